@@ -0,0 +1,298 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command gorest calls go-rest APIs from the command line. It understands the
+// response envelope, printing results and reporting error responses.
+//
+// Usage:
+//
+//	gorest [flags] get <resource> <id>
+//	gorest [flags] list <resource>
+//	gorest [flags] create <resource> [body]
+//	gorest [flags] update <resource> <id> [body]
+//	gorest [flags] delete <resource> <id>
+//
+// Request bodies are JSON, read from the argument or, if it's omitted or "-", from
+// standard input. The base URL and bearer token default to the GOREST_URL and
+// GOREST_TOKEN environment variables.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Workiva/go-rest/rest/client"
+)
+
+// headerFlags collects repeated -H flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q must be of the form \"Key: Value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// queryFlags collects repeated -q flags.
+type queryFlags url.Values
+
+func (q queryFlags) String() string { return url.Values(q).Encode() }
+
+func (q queryFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("query %q must be of the form key=value", value)
+	}
+	url.Values(q).Add(kv[0], kv[1])
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command, returning the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gorest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		baseURL = flags.String("url", os.Getenv("GOREST_URL"),
+			"base URL of the API, including the version, e.g. https://example.com/api/v1")
+		token   = flags.String("token", os.Getenv("GOREST_TOKEN"), "bearer token sent in the Authorization header")
+		output  = flags.String("o", "json", "output format: json, compact or raw")
+		timeout = flags.Duration("timeout", 30*time.Second, "request timeout")
+		limit   = flags.Int("limit", 0, "maximum number of results per page when listing")
+		cursor  = flags.String("cursor", "", "cursor of the page to list")
+		all     = flags.Bool("all", false, "list all pages")
+		headers headerFlags
+		query   = queryFlags{}
+	)
+	flags.Var(&headers, "H", "header to send, e.g. \"X-Tenant: acme\" (repeatable)")
+	flags.Var(query, "q", "query parameter to send when listing, e.g. status=active (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "Usage: gorest [flags] get|list|create|update|delete <resource> [id] [body]\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cmd := command{
+		stdin:  stdin,
+		stdout: stdout,
+		output: *output,
+	}
+	if *baseURL == "" {
+		fmt.Fprintln(stderr, "gorest: -url or GOREST_URL is required")
+		return 2
+	}
+	options := []client.Option{}
+	if *token != "" {
+		options = append(options, client.WithHeader("Authorization", "Bearer "+*token))
+	}
+	for _, header := range headers {
+		kv := strings.SplitN(header, ":", 2)
+		options = append(options, client.WithHeader(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])))
+	}
+	cmd.client = client.New(*baseURL, options...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	listOptions := &client.ListOptions{Limit: *limit, Cursor: *cursor, Query: url.Values(query)}
+	err := cmd.run(ctx, flags.Args(), listOptions, *all)
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(stderr, "gorest: %s\n", err)
+		flags.Usage()
+		return 2
+	default:
+		fmt.Fprintf(stderr, "gorest: %s\n", describe(err))
+		return 1
+	}
+}
+
+// usageError indicates the command was invoked incorrectly.
+type usageError string
+
+func (u usageError) Error() string { return string(u) }
+
+// command performs a gorest subcommand.
+type command struct {
+	client *client.Client
+	stdin  io.Reader
+	stdout io.Writer
+	output string
+}
+
+// run performs the subcommand given by the args.
+func (c *command) run(ctx context.Context, args []string, options *client.ListOptions, all bool) error {
+	if len(args) < 2 {
+		return usageError("a subcommand and resource are required")
+	}
+	sub, resource, params := args[0], args[1], args[2:]
+
+	var result json.RawMessage
+	switch sub {
+	case "get":
+		if len(params) != 1 {
+			return usageError("get requires an id")
+		}
+		if err := c.client.Read(ctx, resource, params[0], &result); err != nil {
+			return err
+		}
+	case "list":
+		return c.list(ctx, resource, options, all)
+	case "create":
+		body, err := c.body(params)
+		if err != nil {
+			return err
+		}
+		if err := c.client.Create(ctx, resource, body, &result); err != nil {
+			return err
+		}
+	case "update":
+		if len(params) < 1 {
+			return usageError("update requires an id")
+		}
+		body, err := c.body(params[1:])
+		if err != nil {
+			return err
+		}
+		if err := c.client.Update(ctx, resource, params[0], body, &result); err != nil {
+			return err
+		}
+	case "delete":
+		if len(params) != 1 {
+			return usageError("delete requires an id")
+		}
+		if err := c.client.Delete(ctx, resource, params[0], &result); err != nil {
+			return err
+		}
+	default:
+		return usageError(fmt.Sprintf("unknown subcommand %q", sub))
+	}
+	return c.print(result)
+}
+
+// list prints the results of the page, or of all pages if all is set.
+func (c *command) list(ctx context.Context, resource string, options *client.ListOptions, all bool) error {
+	it := c.client.Iterate(resource, options)
+	results := []json.RawMessage{}
+	for {
+		var page []json.RawMessage
+		if !it.Next(ctx, &page) {
+			break
+		}
+		results = append(results, page...)
+		if !all {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	if err := c.print(data); err != nil {
+		return err
+	}
+	if page := it.Page(); !all && page != nil && page.Cursor != "" {
+		fmt.Fprintf(c.stdout, "# next cursor: %s\n", page.Cursor)
+	}
+	return nil
+}
+
+// body returns the JSON request body from the args or standard input.
+func (c *command) body(args []string) (json.RawMessage, error) {
+	var data []byte
+	switch {
+	case len(args) > 1:
+		return nil, usageError("too many arguments")
+	case len(args) == 1 && args[0] != "-":
+		data = []byte(args[0])
+	default:
+		var err error
+		if data, err = ioutil.ReadAll(c.stdin); err != nil {
+			return nil, err
+		}
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("request body is not valid JSON")
+	}
+	return json.RawMessage(data), nil
+}
+
+// print writes the JSON value to standard output in the output format.
+func (c *command) print(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	switch c.output {
+	case "raw":
+		buf.Write(data)
+	case "compact":
+		if err := json.Compact(&buf, data); err != nil {
+			return err
+		}
+	case "json":
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+	default:
+		return usageError(fmt.Sprintf("unknown output format %q", c.output))
+	}
+	buf.WriteByte('\n')
+	_, err := c.stdout.Write(buf.Bytes())
+	return err
+}
+
+// describe returns a human-readable description of the error, including any field
+// errors of validation failures.
+func describe(err error) string {
+	var restErr *client.Error
+	if !errors.As(err, &restErr) {
+		return err.Error()
+	}
+	lines := []string{restErr.Error()}
+	if restErr.Code != "" {
+		lines = append(lines, "  code: "+restErr.Code)
+	}
+	for _, field := range restErr.Fields {
+		lines = append(lines, fmt.Sprintf("  %s: %s (%s)", field.Field, field.Message, field.Code))
+	}
+	return strings.Join(lines, "\n")
+}
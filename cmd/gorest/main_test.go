@@ -0,0 +1,163 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+type widgetHandler struct {
+	rest.BaseResourceHandler
+}
+
+func (w widgetHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (w widgetHandler) Authenticate(r *http.Request) error {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		return rest.UnauthorizedRequest("Invalid token")
+	}
+	return nil
+}
+
+func (w widgetHandler) ReadResourceList(ctx rest.RequestContext, limit int,
+	cursor string, version string) ([]rest.Resource, string, error) {
+	start, _ := strconv.Atoi(cursor)
+	resources := []rest.Resource{}
+	for i := start + 1; i <= start+limit && i <= 3; i++ {
+		resources = append(resources, map[string]interface{}{"id": strconv.Itoa(i)})
+	}
+	next := ""
+	if start+limit < 3 {
+		next = strconv.Itoa(start + limit)
+	}
+	return resources, next, nil
+}
+
+func (w widgetHandler) ReadResource(ctx rest.RequestContext, id string,
+	version string) (rest.Resource, error) {
+	if id != "1" {
+		return nil, rest.NotFound("No widget " + id)
+	}
+	return map[string]interface{}{"id": id, "tenant": ctx.Header().Get("X-Tenant")}, nil
+}
+
+func (w widgetHandler) CreateResource(ctx rest.RequestContext, data rest.Payload,
+	version string) (rest.Resource, error) {
+	if data["name"] == nil {
+		return nil, rest.NewValidationError("Invalid widget",
+			rest.FieldError{Field: "name", Code: "required", Message: "Name is required"})
+	}
+	data["id"] = "4"
+	return data, nil
+}
+
+// runCommand runs gorest against a test API, returning the exit code and output.
+func runCommand(stdin string, args ...string) (int, string, string) {
+	api := rest.NewAPI(&rest.Configuration{})
+	api.RegisterResourceHandler(widgetHandler{})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-url", server.URL + "/api/v1", "-token", "secret"}, args...)
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// Ensures that get pretty-prints the result and sends headers.
+func TestGet(t *testing.T) {
+	assert := assert.New(t)
+
+	code, stdout, _ := runCommand("", "-H", "X-Tenant: acme", "get", "widgets", "1")
+	assert.Equal(0, code)
+	assert.Equal("{\n  \"id\": \"1\",\n  \"tenant\": \"acme\"\n}\n", stdout)
+
+	code, stdout, _ = runCommand("", "-o", "compact", "get", "widgets", "1")
+	assert.Equal(0, code)
+	assert.Equal("{\"id\":\"1\",\"tenant\":\"\"}\n", stdout)
+}
+
+// Ensures that error responses are reported with a non-zero exit code.
+func TestGetError(t *testing.T) {
+	assert := assert.New(t)
+
+	code, stdout, stderr := runCommand("", "get", "widgets", "2")
+	assert.Equal(1, code)
+	assert.Equal("", stdout)
+	assert.Equal("gorest: 404 Not Found: No widget 2\n", stderr)
+
+	var out, errOut bytes.Buffer
+	code = run([]string{"-url", "http://127.0.0.1:1", "-timeout", "1s", "get", "widgets"},
+		nil, &out, &errOut)
+	assert.Equal(2, code)
+	assert.Contains(errOut.String(), "gorest: get requires an id")
+}
+
+// Ensures that list prints a page with its next cursor, or all pages.
+func TestList(t *testing.T) {
+	assert := assert.New(t)
+
+	code, stdout, _ := runCommand("", "-o", "compact", "-limit", "2", "list", "widgets")
+	assert.Equal(0, code)
+	assert.Equal("[{\"id\":\"1\"},{\"id\":\"2\"}]\n# next cursor: 2\n", stdout)
+
+	code, stdout, _ = runCommand("", "-o", "compact", "-limit", "2", "-all", "list", "widgets")
+	assert.Equal(0, code)
+	assert.Equal("[{\"id\":\"1\"},{\"id\":\"2\"},{\"id\":\"3\"}]\n", stdout)
+}
+
+// Ensures that create reads the body from the argument or stdin and describes
+// validation errors.
+func TestCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	code, stdout, _ := runCommand("", "-o", "compact", "create", "widgets", `{"name":"cog"}`)
+	assert.Equal(0, code)
+	assert.Equal("{\"id\":\"4\",\"name\":\"cog\"}\n", stdout)
+
+	code, stdout, _ = runCommand(`{"name":"sprocket"}`, "-o", "compact", "create", "widgets")
+	assert.Equal(0, code)
+	assert.Equal("{\"id\":\"4\",\"name\":\"sprocket\"}\n", stdout)
+
+	code, _, stderr := runCommand("", "create", "widgets", `{}`)
+	assert.Equal(1, code)
+	assert.Equal("gorest: 422 Unprocessable Entity: Invalid widget\n  name: Name is required (required)\n", stderr)
+
+	code, _, stderr = runCommand("", "create", "widgets", `{`)
+	assert.Equal(1, code)
+	assert.Equal("gorest: request body is not valid JSON\n", stderr)
+}
+
+// Ensures that an invalid token is reported.
+func TestUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+
+	code, _, stderr := runCommand("", "-token", "wrong", "get", "widgets", "1")
+	assert.Equal(1, code)
+	assert.Equal("gorest: 401 Unauthorized: Invalid token\n", stderr)
+}
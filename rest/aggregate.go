@@ -0,0 +1,361 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// groupByKey is the name of the query string variable giving the fields
+	// aggregations group resources by.
+	groupByKey = "group_by"
+
+	// metricKey is the name of the query string variable giving the metrics computed
+	// by aggregations.
+	metricKey = "metric"
+)
+
+// MetricFunction is the function a Metric computes over the resources of a Bucket.
+type MetricFunction string
+
+// MetricFunction constants are the functions supported by aggregations.
+const (
+	MetricCount MetricFunction = "count"
+	MetricSum   MetricFunction = "sum"
+	MetricAvg   MetricFunction = "avg"
+	MetricMin   MetricFunction = "min"
+	MetricMax   MetricFunction = "max"
+)
+
+// metricFunctions are the MetricFunctions, in the order they're listed in errors.
+var metricFunctions = []MetricFunction{MetricCount, MetricSum, MetricAvg, MetricMin, MetricMax}
+
+// Metric is a value computed over the resources of each Bucket of an aggregation,
+// parsed from the metric query string variable, e.g. ?metric=count,avg:size. Except
+// for MetricCount, metrics are computed over a numeric field.
+type Metric struct {
+	// Function is the function computing the metric.
+	Function MetricFunction
+
+	// Field is the name of the field the metric is computed over, as given by its
+	// Rule's Name. It's empty for MetricCount.
+	Field string
+}
+
+// String returns the metric in the syntax of the metric query string variable, which
+// is also its key in the Metrics of Buckets.
+func (m Metric) String() string {
+	if m.Field == "" {
+		return string(m.Function)
+	}
+	return string(m.Function) + ":" + m.Field
+}
+
+// Aggregation describes an aggregation request, grouping resources into Buckets by
+// the values of fields and computing metrics over each Bucket.
+type Aggregation struct {
+	// GroupBy are the names of the fields resources are grouped by, as given by their
+	// Rules' Names. Resources are aggregated into a single Bucket if it's empty.
+	GroupBy []string
+
+	// Metrics are the metrics computed over each Bucket.
+	Metrics []Metric
+}
+
+// Bucket is a group of resources in the results of an aggregation.
+type Bucket struct {
+	// Key holds the values of the GroupBy fields shared by the Bucket's resources.
+	Key map[string]interface{} `json:"key"`
+
+	// Metrics holds the values of the Aggregation's Metrics, keyed by their String.
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// Aggregator is implemented by ResourceHandlers supporting aggregations of their
+// resources. GET requests to /api/v{version}/{resource}/aggregate, e.g.
+// ?group_by=status&metric=count, are passed to Aggregate once the grouping fields and
+// metrics are validated against the Rules. The list filter and time range are
+// available from the RequestContext. The Buckets are sorted by their keys and sent as
+// the results.
+type Aggregator interface {
+	// Aggregate returns the Buckets of the resources grouped and measured as
+	// described by the Aggregation.
+	Aggregate(ctx RequestContext, aggregation Aggregation, version string) ([]Bucket, error)
+}
+
+// aggregator returns the Aggregator implemented by the ResourceHandler, if any.
+func aggregator(handler ResourceHandler) (Aggregator, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	a, ok := handler.(Aggregator)
+	return a, ok
+}
+
+// aggregateURI returns the URI of the ResourceHandler's aggregations.
+func aggregateURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/aggregate"
+}
+
+// Compute aggregates resources in memory, for ResourceHandlers which don't aggregate
+// in their data stores. Resources missing a field they're grouped by have a nil value
+// for it in their Bucket's key, and fields which aren't numbers are ignored by metrics.
+func (a Aggregation) Compute(resources []map[string]interface{}) []Bucket {
+	type group struct {
+		bucket  Bucket
+		count   float64
+		counted map[string]float64
+	}
+	groups := map[string]*group{}
+	order := []string{}
+	for _, resource := range resources {
+		key := map[string]interface{}{}
+		values := make([]interface{}, len(a.GroupBy))
+		for i, field := range a.GroupBy {
+			key[field], values[i] = resource[field], resource[field]
+		}
+		encoded, _ := json.Marshal(values)
+		g, ok := groups[string(encoded)]
+		if !ok {
+			g = &group{
+				bucket:  Bucket{Key: key, Metrics: map[string]float64{}},
+				counted: map[string]float64{},
+			}
+			groups[string(encoded)] = g
+			order = append(order, string(encoded))
+		}
+		g.count++
+
+		for _, metric := range a.Metrics {
+			name := metric.String()
+			if metric.Function == MetricCount {
+				g.bucket.Metrics[name] = g.count
+				continue
+			}
+			value, ok := filterNumber(reflect.ValueOf(resource[metric.Field]))
+			if !ok {
+				continue
+			}
+			current, seen := g.bucket.Metrics[name]
+			switch metric.Function {
+			case MetricSum, MetricAvg:
+				value += current
+			case MetricMin:
+				if seen {
+					value = math.Min(value, current)
+				}
+			case MetricMax:
+				if seen {
+					value = math.Max(value, current)
+				}
+			}
+			g.bucket.Metrics[name] = value
+			g.counted[name]++
+		}
+	}
+
+	buckets := make([]Bucket, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		for _, metric := range a.Metrics {
+			if name := metric.String(); metric.Function == MetricAvg && g.counted[name] > 0 {
+				g.bucket.Metrics[name] /= g.counted[name]
+			}
+		}
+		buckets = append(buckets, g.bucket)
+	}
+	return buckets
+}
+
+// sort orders the Buckets by the values of their keys, in the order of the GroupBy
+// fields. Keys with nil values sort last.
+func (a Aggregation) sort(buckets []Bucket) {
+	sort.SliceStable(buckets, func(i, j int) bool {
+		for _, field := range a.GroupBy {
+			x, y := buckets[i].Key[field], buckets[j].Key[field]
+			if order, ok := compareFilterValues(x, y); ok && order != 0 {
+				return order < 0
+			} else if !ok && (x == nil) != (y == nil) {
+				return y == nil
+			}
+		}
+		return false
+	})
+}
+
+// groupableFields returns the names of the fields the Rules mark Groupable for the
+// version, in order.
+func groupableFields(rules Rules, version string) []string {
+	fields := []string{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		if rule.Groupable {
+			fields = append(fields, rule.Name())
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// numericFields returns the names of the fields the Rules give numeric Types for the
+// version, in order.
+func numericFields(rules Rules, version string) []string {
+	fields := []string{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		if rule.Type >= Int && rule.Type <= Float64 {
+			fields = append(fields, rule.Name())
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ParseAggregation parses the comma-separated fields of a group_by expression and the
+// comma-separated metrics of a metric expression, each a MetricFunction optionally
+// followed by a colon and field, e.g. sum:size. Metrics default to count. It returns
+// an error if either is malformed or uses fields other than those given.
+func ParseAggregation(groupBy, metrics string, groupable, numeric []string) (Aggregation, error) {
+	aggregation := Aggregation{GroupBy: []string{}, Metrics: []Metric{}}
+	if strings.TrimSpace(groupBy) != "" {
+		for _, part := range strings.Split(groupBy, ",") {
+			field := strings.TrimSpace(part)
+			if field == "" {
+				return Aggregation{}, fmt.Errorf("Invalid group_by: empty field in %q", groupBy)
+			}
+			if !contains(groupable, field) {
+				return Aggregation{}, fmt.Errorf(
+					"Invalid group_by: %s can't be grouped by; groupable fields are %s",
+					field, fieldNames(groupable))
+			}
+			if contains(aggregation.GroupBy, field) {
+				return Aggregation{}, fmt.Errorf("Invalid group_by: %s is given more than once", field)
+			}
+			aggregation.GroupBy = append(aggregation.GroupBy, field)
+		}
+	}
+
+	if strings.TrimSpace(metrics) == "" {
+		metrics = string(MetricCount)
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(metrics, ",") {
+		function, field := strings.TrimSpace(part), ""
+		if i := strings.Index(function, ":"); i >= 0 {
+			function, field = function[:i], function[i+1:]
+		}
+		metric := Metric{Function: MetricFunction(function), Field: field}
+		switch {
+		case !containsMetricFunction(metric.Function):
+			names := make([]string, len(metricFunctions))
+			for i, f := range metricFunctions {
+				names[i] = string(f)
+			}
+			return Aggregation{}, fmt.Errorf("Invalid metric: unknown function %q; functions are %s",
+				function, strings.Join(names, ", "))
+		case metric.Function == MetricCount && field != "":
+			return Aggregation{}, fmt.Errorf("Invalid metric: %s doesn't take a field", MetricCount)
+		case metric.Function != MetricCount && !contains(numeric, field):
+			return Aggregation{}, fmt.Errorf(
+				"Invalid metric: %s can't be computed over %q; numeric fields are %s",
+				metric.Function, field, fieldNames(numeric))
+		case seen[metric.String()]:
+			return Aggregation{}, fmt.Errorf("Invalid metric: %s is given more than once", metric)
+		}
+		seen[metric.String()] = true
+		aggregation.Metrics = append(aggregation.Metrics, metric)
+	}
+	return aggregation, nil
+}
+
+// containsMetricFunction indicates if the MetricFunction is supported.
+func containsMetricFunction(function MetricFunction) bool {
+	for _, f := range metricFunctions {
+		if f == function {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldNames joins the names of fields for error messages.
+func fieldNames(fields []string) string {
+	if len(fields) == 0 {
+		return "none"
+	}
+	return strings.Join(fields, ", ")
+}
+
+// handleAggregate returns a Handler passing aggregation requests to the Aggregator
+// and then serializing and dispatching the sorted Buckets.
+func (h requestHandler) handleAggregate(handler ResourceHandler, a Aggregator) http.Handler {
+	rules := handler.Rules()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		version := ctx.Version()
+
+		groupBy, _ := ctx.Value(groupByKey).(string)
+		metrics, _ := ctx.Value(metricKey).(string)
+		aggregation, err := ParseAggregation(groupBy, metrics,
+			groupableFields(rules, version), numericFields(rules, version))
+		if err != nil {
+			h.sendResponse(ctx.setError(BadRequest(err.Error())))
+			return
+		}
+		ctx, err = listContext(ctx, rules, version)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		buckets, err := a.Aggregate(ctx, aggregation, version)
+		if buckets == nil {
+			buckets = []Bucket{}
+		}
+		for idx, bucket := range buckets {
+			key := make(map[string]interface{}, len(aggregation.GroupBy))
+			for _, field := range aggregation.GroupBy {
+				key[field] = bucket.Key[field]
+			}
+			buckets[idx].Key = key
+			if bucket.Metrics == nil {
+				buckets[idx].Metrics = map[string]float64{}
+			}
+		}
+		aggregation.sort(buckets)
+
+		ctx = ctx.setResult(buckets)
+		ctx = ctx.setError(err)
+		ctx = ctx.setStatus(http.StatusOK)
+
+		h.sendResponse(ctx)
+	})
+}
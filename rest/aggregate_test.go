@@ -0,0 +1,133 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// aggregatableTasksResourceHandler is a tasksResourceHandler aggregating tasks in
+// memory.
+type aggregatableTasksResourceHandler struct {
+	tasksResourceHandler
+}
+
+func (a aggregatableTasksResourceHandler) Rules() Rules {
+	return NewRules((*Payload)(nil),
+		&Rule{Field: "name", Type: String},
+		&Rule{Field: "status", Type: String, Filterable: true, Groupable: true},
+		&Rule{Field: "size", Type: Int, Filterable: true},
+	)
+}
+
+func (a aggregatableTasksResourceHandler) Aggregate(ctx RequestContext,
+	aggregation Aggregation, version string) ([]Bucket, error) {
+	tasks, _, err := a.ReadResourceList(ctx, 0, "", version)
+	resources := []map[string]interface{}{}
+	for _, task := range tasks {
+		resources = append(resources, task.(Payload))
+	}
+	return aggregation.Compute(resources), err
+}
+
+// Ensures that aggregations group the filtered resources and compute metrics over
+// each bucket, sorted by their keys.
+func TestAggregate(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(aggregatableTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET",
+		"/api/v1/tasks/aggregate?group_by=status&metric=count,sum:size,avg:size,max:size&filter=size>1", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{
+				"key":     map[string]interface{}{"status": "active"},
+				"metrics": map[string]interface{}{"count": 1.0, "sum:size": 5.0, "avg:size": 5.0, "max:size": 5.0},
+			},
+			map[string]interface{}{
+				"key":     map[string]interface{}{"status": "done"},
+				"metrics": map[string]interface{}{"count": 2.0, "sum:size": 13.0, "avg:size": 6.5, "max:size": 8.0},
+			},
+		}, envelope["results"])
+	}
+}
+
+// Ensures that aggregations without grouping fields count all resources in a single
+// bucket.
+func TestAggregateWithoutGroupBy(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(aggregatableTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks/aggregate", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{
+				"key":     map[string]interface{}{},
+				"metrics": map[string]interface{}{"count": 4.0},
+			},
+		}, envelope["results"])
+	}
+}
+
+// Ensures that aggregations by fields which aren't groupable, or with invalid metrics,
+// are rejected.
+func TestAggregateInvalid(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(aggregatableTasksResourceHandler{})
+
+	for _, query := range []string{
+		"group_by=name",
+		"group_by=status,status",
+		"metric=median:size",
+		"metric=sum:name",
+		"metric=sum",
+		"metric=count:size",
+		"metric=count,count",
+	} {
+		code, _ := serveJSON(api, "GET", "/api/v1/tasks/aggregate?"+query, "")
+		assert.Equal(http.StatusBadRequest, code, query)
+	}
+}
+
+// Ensures that ParseAggregation parses grouping fields and metrics, defaulting to
+// count.
+func TestParseAggregation(t *testing.T) {
+	assert := assert.New(t)
+
+	aggregation, err := ParseAggregation(" status , size ", "", []string{"size", "status"}, nil)
+
+	if assert.Nil(err) {
+		assert.Equal([]string{"status", "size"}, aggregation.GroupBy)
+		assert.Equal([]Metric{{Function: MetricCount}}, aggregation.Metrics)
+	}
+
+	aggregation, err = ParseAggregation("", "min:size, count", nil, []string{"size"})
+
+	if assert.Nil(err) {
+		assert.Equal([]string{}, aggregation.GroupBy)
+		assert.Equal([]Metric{{Function: MetricMin, Field: "size"}, {Function: MetricCount}},
+			aggregation.Metrics)
+	}
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package rest
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -42,7 +46,9 @@ const (
 	HandleUpdateList              = "updateList"
 )
 
-// Address is the address and port to bind to (e.g. ":8080").
+// Address is the address and port to bind to (e.g. ":8080") or, prefixed with "unix:",
+// the path of a Unix domain socket (e.g. "unix:/var/run/api.sock"). TCP addresses listen
+// on both IPv4 and IPv6 unless prefixed with "tcp4:" or "tcp6:" (e.g. "tcp6:[::1]:8080").
 type Address string
 
 // FilePath represents a file path.
@@ -69,6 +75,183 @@ type Configuration struct {
 	Logger        StdLogger
 	GenerateDocs  bool
 	DocsDirectory string
+
+	// TrustedProxies are the IP addresses and CIDR ranges of proxies whose forwarding
+	// headers are honored when resolving the client IP of a request.
+	TrustedProxies []string
+
+	// RequestTimeout is the maximum duration a ResourceHandler has to handle a request.
+	// Once it elapses, the RequestContext is canceled and a 503 response is sent. If
+	// zero, there is no timeout.
+	RequestTimeout time.Duration
+
+	// ProblemDetails indicates if error responses should be RFC 7807 Problem Details
+	// objects (application/problem+json) rather than the standard response envelope.
+	ProblemDetails bool
+
+	// ErrorMapper, if set, translates errors returned by ResourceHandlers into HTTP
+	// status codes and messages before the response is sent.
+	ErrorMapper ErrorMapper
+
+	// MessageCatalog, if set, translates the messages of LocalizedErrors into the
+	// caller's preferred language for error responses.
+	MessageCatalog MessageCatalog
+
+	// ErrorReporter, if set, is invoked when a ResourceHandler panics. By default,
+	// panics are logged along with their stack trace.
+	ErrorReporter ErrorReporter
+
+	// HypermediaLinks indicates if read and list responses should include "self",
+	// "collection" and "next" links built from the router, alongside any links added
+	// by ResourceHandlers.
+	HypermediaLinks bool
+
+	// ETags indicates if an entity tag should be generated, by hashing the serialized
+	// response, for successful GET responses whose ResourceHandler didn't provide one
+	// via RequestContext.CheckPreconditions. Requests whose If-None-Match header matches
+	// it are answered with a 304 Not Modified response without a body.
+	ETags bool
+
+	// MaxResponseSize, if set, is the maximum size in bytes of a serialized response
+	// body. Serialization is aborted once it's exceeded and a 507 Insufficient Storage
+	// response is sent instead, so a handler accidentally returning millions of
+	// results doesn't exhaust the process's memory.
+	MaxResponseSize int
+
+	// MaxExpandDepth is the longest path of fields, e.g. 2 for items.product, which
+	// read and list requests can expand with the expand query string variable.
+	// Defaults to 3.
+	MaxExpandDepth int
+
+	// StrictQueryParameters indicates if requests to ResourceHandlers with query string
+	// variables which are neither handled by the framework nor declared by the
+	// handler's QueryParameters, e.g. a misspelled ?limt=10, are rejected with a 400
+	// Bad Request response rather than the variables being ignored. The framework's
+	// typed variables, e.g. limit, are validated too.
+	StrictQueryParameters bool
+
+	// MaxHeapBytes, if set, is the heap size in bytes above which requests are
+	// rejected with a 503 Service Unavailable response until it shrinks. The heap is
+	// sampled at most every 100 milliseconds.
+	MaxHeapBytes uint64
+
+	// Instrumentation enables lightweight counters of the requests handled by each
+	// route, exposed by Stats and served at AdminPath/stats, and labels the goroutines
+	// handling requests with their route so CPU profiles can be broken down by route.
+	Instrumentation bool
+
+	// Info describes the API in its OpenAPI specification.
+	Info APIInfo
+
+	// ReadinessPath, if set, is the path of an unauthenticated endpoint for readiness
+	// probes, e.g. "/ready". It responds 200 OK until Shutdown is called and 503
+	// Service Unavailable afterwards.
+	ReadinessPath string
+
+	// ExplorerPath, if set, is the path at which an interactive API explorer driven by
+	// the OpenAPI specification is served, e.g. "/api/explorer". Like the
+	// specification, it's public unless Authenticate is set.
+	ExplorerPath string
+
+	// AdminPath, if set, is the path under which administrative endpoints are served,
+	// e.g. "/admin". GET AdminPath/routes lists the routes registered with the API
+	// and GET AdminPath/postman.json downloads a Postman collection of its endpoints.
+	// Since they disclose the API's routes and traffic, they're only served if
+	// Authenticate is set.
+	AdminPath string
+
+	// MaintenanceRetryAfter is how long clients are told to wait before retrying
+	// requests rejected while the API is in maintenance mode. Defaults to one minute.
+	MaintenanceRetryAfter time.Duration
+
+	// Events, if set, publishes an Event for each successful create, update and delete
+	// request to a ResourceHandler, streamed to clients with Server-Sent Events at
+	// GET /api/v{version}/{resource}/events. Event streams aren't subject to the
+	// RequestTimeout, but are closed by the WriteTimeout of the ServerConfig.
+	Events *EventConfig
+
+	// Uploads, if set, enables resumable uploads to the ResourceHandlers implementing
+	// ResumableUploadHandler.
+	Uploads *UploadConfig
+
+	// Views, if set, enables clients to save named views of the list requests of the
+	// ResourceHandlers at /api/v{version}/{resource}/views, executed with
+	// GET /api/v{version}/{resource}?view={name}.
+	Views *ViewConfig
+
+	// ChangeFeed, if set, appends each successful create, update and delete request to
+	// a ResourceHandler to the change log of its resource, read by downstream consumers
+	// at GET /api/v{version}/{resource}/changes?since={seq}.
+	ChangeFeed *ChangeFeedConfig
+
+	// Operations, if set, enables ResourceHandlers to return an AsyncResult to run a
+	// long-running job in the background, tracked by an Operation served at
+	// /api/v{version}/operations/{id}.
+	Operations *OperationConfig
+
+	// Scheduler, if set, enables scheduling create, update and delete requests to
+	// ResourceHandlers for a later time with an execute_at query parameter.
+	Scheduler *SchedulerConfig
+
+	// Outbox, if set, configures the relay of the Outboxes of OutboxHandlers, whose
+	// Events are written in the same database transactions as their mutations.
+	Outbox *OutboxConfig
+
+	// ResponseCache, if set, caches the responses of read and list requests.
+	ResponseCache *ResponseCache
+
+	// Server, if set, configures the timeouts, header limit, keep-alives and TLS
+	// certificates of the servers run by Start and StartTLS.
+	Server *ServerConfig
+
+	// HTTP2, if set, configures HTTP/2 for the servers run by Start and StartTLS,
+	// including h2c for cleartext connections. StartTLS negotiates HTTP/2 regardless,
+	// with default settings.
+	HTTP2 *HTTP2Config
+
+	// MethodOverride enables overriding the method of POST requests with the
+	// X-HTTP-Method-Override, X-HTTP-Method or X-Method-Override header or, for
+	// URL-encoded forms, the _method field, for clients behind proxies which only
+	// allow GET and POST. The method can be overridden with GET, PUT, PATCH or DELETE.
+	// The routes of ResourceHandlers accept X-HTTP-Method-Override regardless.
+	MethodOverride bool
+
+	// TrailingSlash configures whether requests whose paths only differ from a route's
+	// by a trailing slash, e.g. /widgets/ instead of /widgets, are redirected to or
+	// served by the route. By default, they aren't matched.
+	TrailingSlash TrailingSlash
+
+	// CaseInsensitivePaths matches the literal segments of route paths, e.g. the
+	// resource names, case-insensitively, so /API/v1/Widgets is served by the route of
+	// /api/v{version}/widgets. Path variables, such as resource IDs, are unchanged.
+	CaseInsensitivePaths bool
+
+	// NotFound, if set, returns the error responded with, in the standard envelope, to
+	// requests which don't match any route, e.g. a NotFound error with a custom
+	// message. If it returns nil, a default NotFound error is used.
+	NotFound func(RequestContext) error
+
+	// MethodNotAllowed, if set, returns the error responded with, in the standard
+	// envelope, to requests matching the paths of routes but not their methods, which
+	// are given. If it returns nil, a default MethodNotAllowed error is used.
+	MethodNotAllowed func(ctx RequestContext, allowed []string) error
+
+	// Fallback, if set, handles requests which don't match any route or static file,
+	// e.g. a httputil.ReverseProxy to a legacy service the API is gradually replacing.
+	Fallback http.Handler
+
+	// Router, if set, dispatches requests to the routes registered with the API. By
+	// default, gorilla/mux is used.
+	Router Router
+
+	// Authenticate, if set, authenticates requests to the endpoints served by the API
+	// itself rather than a ResourceHandler, such as the OpenAPI specification, the API
+	// explorer and the administrative endpoints. Requests for which it returns an
+	// error are rejected with a 401. If it isn't set, the OpenAPI specification at
+	// /api/openapi.json, the discovery root of each version at /api/v{version}/ and the
+	// explorer are public, disclosing the API's resources and fields to anyone, while
+	// the administrative endpoints and webhooks aren't served.
+	Authenticate func(*http.Request) error
 }
 
 // Debugf prints the formatted string to the Configuration Logger if Debug is enabled.
@@ -135,26 +318,85 @@ func wrapMiddleware(handler http.Handler, middleware ...Middleware) http.Handler
 type API interface {
 	http.Handler
 
-	// Start begins serving requests. This will block unless it fails, in which case an
-	// error will be returned. This will validate any defined Rules. If any Rules are
-	// invalid, it will panic. Any provided Middleware will be invoked for every request
-	// handled by the API.
+	// Start begins serving requests. This will block until it fails, in which case an
+	// error will be returned, or the API is stopped, in which case nil is returned.
+	// This will validate any defined Rules. If any Rules are invalid, it will panic.
+	// Any provided Middleware will be invoked for every request handled by the API.
 	Start(Address, ...Middleware) error
 
 	// StartTLS begins serving requests received over HTTPS connections. This will block
-	// unless it fails, in which case an error will be returned. Files containing a
-	// certificate and matching private key for the server must be provided. If the
-	// certificate is signed by a certificate authority, the certFile should be the
-	// concatenation of the server's certificate followed by the CA's certificate. This
+	// until it fails, in which case an error will be returned, or the API is stopped,
+	// in which case nil is returned. Files containing a certificate and matching
+	// private key for the server must be provided unless ServerConfig.TLS or its
+	// CertManager provides certificates. If the certificate is signed by a certificate authority, the
+	// certFile should be the concatenation of the server's certificate followed by the
+	// CA's certificate. The files are reloaded as configured by the ServerConfig. This
 	// will validate any defined Rules. If any Rules are invalid, it will panic. Any
 	// provided Middleware will be invoked for every request handled by the API.
 	StartTLS(Address, FilePath, FilePath, ...Middleware) error
 
+	// Serve begins serving requests received by the listener. This will block until it
+	// fails, in which case an error will be returned, or the API is stopped, in which
+	// case nil is returned. An API can serve several listeners concurrently, e.g. a
+	// public listener and a local administrative listener, each with its own
+	// Middleware.
+	Serve(net.Listener, ...Middleware) error
+
+	// Stop immediately closes the listeners and connections of the servers run by
+	// Start and StartTLS, causing them to return.
+	Stop() error
+
+	// Shutdown gracefully stops the servers run by Start and StartTLS. It marks the
+	// API as not ready, ends event streams, stops accepting connections and waits for
+	// in-flight requests to complete until the context is done, then runs the
+	// functions registered with OnShutdown. It returns the first error encountered.
+	Shutdown(context.Context) error
+
+	// OnShutdown registers a function to be run by Shutdown once the servers have
+	// stopped, e.g. to flush buffers or close database connections.
+	OnShutdown(func(context.Context) error)
+
+	// OnListen registers a function to be called with the address each server is
+	// bound to before it begins serving requests, e.g. to learn the port chosen by the
+	// system when listening on ":0".
+	OnListen(func(net.Addr))
+
+	// Addrs returns the addresses the running servers are bound to.
+	Addrs() []net.Addr
+
+	// OnStart registers a function to be run before the API first begins serving
+	// requests, e.g. to open database connections. If it fails, the API isn't started
+	// and the error is returned.
+	OnStart(func(context.Context) error)
+
+	// SetMaintenance switches maintenance mode on or off at runtime, e.g. for planned
+	// migrations. While it's on, requests to any route other than the readiness and
+	// administrative endpoints are responded to with a 503 Service Unavailable error
+	// with the message, or a default one if it's empty, and a Retry-After header.
+	SetMaintenance(on bool, message string)
+
+	// Run begins serving requests like Start and gracefully shuts the API down with
+	// Shutdown when the process receives SIGINT or SIGTERM, waiting at most the
+	// ServerConfig's ShutdownTimeout. It blocks until the API has stopped.
+	Run(Address, ...Middleware) error
+
 	// RegisterResourceHandler binds the provided ResourceHandler to the appropriate REST
 	// endpoints and applies any specified middleware. Endpoints will have the following
-	// base URL: /api/:version/resourceName.
+	// base URL: /api/:version/resourceName. It may be called while the API is serving
+	// requests, e.g. to roll out a resource behind a feature flag.
 	RegisterResourceHandler(ResourceHandler, ...RequestMiddleware)
 
+	// UnregisterResourceHandler removes the ResourceHandler of the named resource while
+	// the API is serving requests, responding to requests for its endpoints with a 404.
+	// It returns false if no ResourceHandler is registered for the resource.
+	UnregisterResourceHandler(string) bool
+
+	// ReplaceResourceHandler swaps the ResourceHandler registered for its resource with
+	// the provided one, with the same URIs, and applies any specified middleware,
+	// without dropping requests. It returns an error if no ResourceHandler is
+	// registered for the resource or their URIs differ.
+	ReplaceResourceHandler(ResourceHandler, ...RequestMiddleware) error
+
 	// RegisterHandlerFunc binds the http.HandlerFunc to the provided URI and applies any
 	// specified middleware.
 	RegisterHandlerFunc(string, http.HandlerFunc, ...RequestMiddleware)
@@ -167,6 +409,16 @@ type API interface {
 	// prefix and applies any specified middleware.
 	RegisterPathPrefix(string, http.HandlerFunc, ...RequestMiddleware)
 
+	// RegisterHost dispatches requests for the host, e.g. "admin.example.com" or
+	// "*.example.com" for any of its subdomains, to the API instead, so APIs with their
+	// own resources, middleware and versions can be served by one server.
+	RegisterHost(string, API)
+
+	// RegisterStatic serves the files of the http.FileSystem at paths beginning with
+	// the prefix which aren't claimed by any route. If an index file is given, it's
+	// served for requests for HTML with no matching file, for single-page applications.
+	RegisterStatic(string, http.FileSystem, string)
+
 	// RegisterResponseSerializer registers the provided ResponseSerializer with the given
 	// format. If the format has already been registered, it will be overwritten.
 	RegisterResponseSerializer(string, ResponseSerializer)
@@ -185,6 +437,9 @@ type API interface {
 	// ResourceHandlers returns a slice containing the registered ResourceHandlers.
 	ResourceHandlers() []ResourceHandler
 
+	// Routes returns the routes registered with the API in the order they're matched.
+	Routes() []Route
+
 	// Validate will validate the Rules configured for this API. It returns nil
 	// if all Rules are valid, otherwise returns the first encountered
 	// validation error.
@@ -217,7 +472,7 @@ func newAuthMiddleware(authenticate func(*http.Request) error) RequestMiddleware
 func newVersionMiddleware(validVersions []string) RequestMiddleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			requestVersion := mux.Vars(r)["version"]
+			requestVersion := PathVars(r)["version"]
 
 			for _, v := range validVersions {
 				if requestVersion == v {
@@ -232,45 +487,189 @@ func newVersionMiddleware(validVersions []string) RequestMiddleware {
 	}
 }
 
-// muxAPI is an implementation of the API interface which relies on the gorilla/mux
-// package to handle request dispatching (see http://www.gorillatoolkit.org/pkg/mux).
+// muxAPI is an implementation of the API interface which dispatches requests with a
+// Router, by default backed by the gorilla/mux package (see
+// http://www.gorillatoolkit.org/pkg/mux).
 type muxAPI struct {
 	config             *Configuration
-	router             *mux.Router
+	router             Router
+	routerMu           sync.RWMutex
 	mu                 sync.RWMutex
 	handler            *requestHandler
 	serializerRegistry map[string]ResponseSerializer
 	resourceHandlers   []ResourceHandler
+	routes             []Route
+	templates          map[string]*routeTemplate
+	stats              map[string]*routeStats
+	servers            map[*http.Server]net.Addr
+	listenHooks        []func(net.Addr)
+	matchers           routeGroup
+	claimed            map[string]struct{}
+	static             []*staticHandler
+	hosts              map[string]API
+	resources          map[string]*liveResource
+	outboxes           map[Outbox]*outboxRelay
+	routed             http.Handler
+	notFound           http.Handler
+	methodNotAllowed   http.Handler
+	maintenance        http.Handler
+	startHooks         []func(context.Context) error
+	startMu            sync.Mutex
+	started            bool
+	shutdownHooks      []func(context.Context) error
+	shuttingDown       int32
 }
 
 // NewAPI returns a newly allocated API instance.
 func NewAPI(config *Configuration) API {
-	r := mux.NewRouter()
+	router := config.Router
+	if router == nil {
+		router = NewMuxRouter(mux.NewRouter())
+	}
 	restAPI := &muxAPI{
 		config:             config,
-		router:             r,
+		router:             router,
 		serializerRegistry: map[string]ResponseSerializer{"json": &jsonSerializer{}},
 		resourceHandlers:   make([]ResourceHandler, 0),
+		templates:          map[string]*routeTemplate{},
+		stats:              map[string]*routeStats{},
+		servers:            map[*http.Server]net.Addr{},
+		claimed:            map[string]struct{}{},
+		hosts:              map[string]API{},
+		resources:          map[string]*liveResource{},
+		outboxes:           map[Outbox]*outboxRelay{},
+	}
+	trustedProxies, err := ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		log.Printf("Ignoring trusted proxies: %v", err)
+	}
+	restAPI.handler = &requestHandler{
+		API:            restAPI,
+		urls:           restAPI,
+		trustedProxies: trustedProxies,
+		muted:          map[string]struct{}{},
+	}
+	if config.Events != nil {
+		restAPI.handler.events = newEventBus(config.Events)
+	}
+	restAPI.routed = routerHandler{restAPI}
+	restAPI.notFound = restAPI.unmatchedRoute("notFound", restAPI.handleUnmatched(http.StatusNotFound))
+	restAPI.methodNotAllowed = restAPI.unmatchedRoute("methodNotAllowed",
+		restAPI.handleUnmatched(http.StatusMethodNotAllowed))
+	auth := []RequestMiddleware{restAPI.authenticator()}
+	restAPI.handle(newRouterRoute("openapi", "GET", openAPIURI, false,
+		applyMiddleware(openAPIHandler(restAPI), auth)), "rest.OpenAPI", auth, 0)
+	restAPI.handle(newRouterRoute("discovery", "GET", discoveryURI, false,
+		applyMiddleware(restAPI.handler.handleDiscovery(), auth)),
+		"rest.discovery", auth, config.RequestTimeout)
+	if config.Uploads != nil {
+		restAPI.handler.uploads = newUploadManager(*config.Uploads)
+	}
+	if config.Views != nil {
+		restAPI.handler.views = config.Views.Store
+		if restAPI.handler.views == nil {
+			restAPI.handler.views = NewMemoryViewStore()
+		}
+	}
+	if config.ChangeFeed != nil {
+		restAPI.handler.changes = config.ChangeFeed.Store
+		if restAPI.handler.changes == nil {
+			restAPI.handler.changes = NewMemoryChangeStore()
+		}
+	}
+	if config.Operations != nil {
+		restAPI.registerOperations(config.Operations)
+	}
+	if config.Scheduler != nil {
+		restAPI.registerScheduler(*config.Scheduler)
+	}
+	if config.Events != nil && config.Events.Publisher != nil {
+		restAPI.startPublisher(config.Events.Publisher)
+	}
+	if config.Events != nil && config.Events.Webhooks != nil {
+		restAPI.registerWebhooks(*config.Events.Webhooks)
+	}
+	if config.Events != nil && config.Events.WebSocketPath != "" {
+		restAPI.handle(newRouterRoute("events:websocket", "GET", config.Events.WebSocketPath, false,
+			applyMiddleware(restAPI.handler.handleEventSocket(), auth)), "rest.EventSocket", auth, 0)
+	}
+	if config.ReadinessPath != "" {
+		restAPI.handle(newRouterRoute("readiness", "GET", config.ReadinessPath, false,
+			restAPI.handleReadiness()), "rest.readiness", nil, 0)
+	}
+	if path := strings.TrimRight(config.ExplorerPath, "/"); path != "" {
+		restAPI.handle(newRouterRoute("explorer", "GET", path, true,
+			applyMiddleware(explorerHandler(restAPI, path), auth)), "rest.explorer", auth, 0)
+	}
+	if path := strings.TrimRight(config.AdminPath, "/"); path != "" {
+		restAPI.registerAdmin(path)
 	}
-	restAPI.handler = &requestHandler{restAPI, r}
 	return restAPI
 }
 
-// Start begins serving requests. This will block unless it fails, in which case an error will be
-// returned.
+// registerAdmin registers the administrative endpoints under the path, authenticated
+// by the Configuration's Authenticate function. They aren't registered without one,
+// since they'd disclose the API's routes and traffic to anyone.
+func (r *muxAPI) registerAdmin(path string) {
+	if r.config.Authenticate == nil {
+		log.Printf("Administrative endpoints aren't registered without the Configuration's Authenticate function")
+		return
+	}
+	auth := []RequestMiddleware{r.authenticator()}
+	r.handle(newRouterRoute("admin:routes", "GET", path+"/routes", false,
+		applyMiddleware(r.handler.handleRoutes(), auth)), "rest.routes", auth, 0)
+	r.handle(newRouterRoute("admin:postman", "GET", path+"/postman.json", false,
+		applyMiddleware(postmanHandler(r), auth)), "rest.PostmanCollection", auth, 0)
+	if r.config.Instrumentation {
+		r.handle(newRouterRoute("admin:stats", "GET", path+"/stats", false,
+			applyMiddleware(r.handler.handleStats(), auth)), "rest.Stats", auth, 0)
+	}
+}
+
+// Start begins serving requests. This will block until it fails, in which case an error will
+// be returned, or the API is stopped, in which case nil is returned.
 func (r *muxAPI) Start(addr Address, middleware ...Middleware) error {
 	r.preprocess()
-	return http.ListenAndServe(string(addr), wrapMiddleware(r.router, middleware...))
+	ln, err := r.listen(addr, ":http")
+	if err != nil {
+		return err
+	}
+	return r.serveListener(ln, nil, middleware)
 }
 
-// StartTLS begins serving requests received over HTTPS connections. This will block unless it
-// fails, in which case an error will be returned. Files containing a certificate and matching
-// private key for the server must be provided. If the certificate is signed by a certificate
-// authority, the certFile should be the concatenation of the server's certificate followed by
-// the CA's certificate.
+// Serve begins serving requests received by the listener, e.g. one returned by
+// net.Listen or inherited from a parent process. This will block until it fails, in which
+// case an error will be returned, or the API is stopped, in which case nil is returned. An
+// API can serve several listeners concurrently, each with its own Middleware.
+func (r *muxAPI) Serve(ln net.Listener, middleware ...Middleware) error {
+	r.preprocess()
+	return r.serveListener(ln, nil, middleware)
+}
+
+// StartTLS begins serving requests received over HTTPS connections. This will block until it
+// fails, in which case an error will be returned, or the API is stopped, in which case nil is
+// returned. Files containing a certificate and matching private key for the server must be
+// provided unless ServerConfig.TLS or its CertManager provides certificates. If the certificate
+// is signed by a certificate authority, the certFile should be the concatenation of the server's
+// certificate followed by the CA's certificate. The files are reloaded as configured by the
+// ServerConfig.
 func (r *muxAPI) StartTLS(addr Address, certFile, keyFile FilePath, middleware ...Middleware) error {
 	r.preprocess()
-	return http.ListenAndServeTLS(string(addr), string(certFile), string(keyFile), wrapMiddleware(r.router, middleware...))
+	var certs *certReloader
+	if certFile != "" || keyFile != "" {
+		var err error
+		if certs, err = newCertReloader(string(certFile), string(keyFile)); err != nil {
+			return err
+		}
+		if c := r.config.Server; c != nil {
+			defer certs.watch(c.CertReloadInterval, c.ReloadCertOnSIGHUP)()
+		}
+	}
+	ln, err := r.listen(addr, ":https")
+	if err != nil {
+		return err
+	}
+	return r.serveListener(ln, r.tlsConfig(certs), middleware)
 }
 
 // preprocess performs any necessary preprocessing before the server can be started, including
@@ -284,10 +683,8 @@ func (r *muxAPI) preprocess() {
 	}
 }
 
-// Check the route for an error and log the error if it exists.
-func (r *muxAPI) checkRoute(handler, method, uri string, route *mux.Route) {
-	err := route.GetError()
-
+// Check the route registration error and log it if it exists.
+func (r *muxAPI) checkRoute(handler, method, uri string, err error) {
 	if err != nil {
 		log.Printf("Failed to setup route %s with %v", uri, err)
 	} else {
@@ -295,104 +692,251 @@ func (r *muxAPI) checkRoute(handler, method, uri string, route *mux.Route) {
 	}
 }
 
+// authenticator returns RequestMiddleware authenticating requests to the endpoints
+// served by the API itself with the authenticator of the Configuration, if any.
+func (r *muxAPI) authenticator() RequestMiddleware {
+	return newAuthMiddleware(func(req *http.Request) error {
+		if r.config.Authenticate == nil {
+			return nil
+		}
+		return r.config.Authenticate(req)
+	})
+}
+
 // RegisterResourceHandler binds the provided ResourceHandler to the appropriate REST endpoints and
 // applies any specified middleware. Endpoints will have the following base URL:
-// /api/:version/resourceName.
+// /api/:version/resourceName. It may be called while the API is serving requests.
 func (r *muxAPI) RegisterResourceHandler(h ResourceHandler, middleware ...RequestMiddleware) {
 	h = resourceHandlerProxy{h}
+	if outboxed, ok := outboxHandler(h); ok {
+		r.relayOutbox(h, outboxed.Outbox())
+	}
+	routes, middleware := r.resourceRoutes(h, middleware)
+	if resource := r.unregisteredResource(h.ResourceName(), routes); resource != nil {
+		// The routes of a previously unregistered handler with the same URIs are
+		// still registered with the Router, so they're reused.
+		r.activate(resource, h, routes, middleware)
+		return
+	}
+
+	resource := &liveResource{}
+	resource.set(h, routes)
+	for _, route := range routes {
+		err := r.handle(newRouterRoute(route.name, route.method, route.uri, false,
+			r.liveRoute(resource, route.name), route.headers...),
+			route.handlerName, middleware, r.config.RequestTimeout)
+		r.checkRoute(route.label, route.uri, route.logMethod, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources[h.ResourceName()] = resource
+	r.resourceHandlers = append(r.resourceHandlers, h)
+}
+
+// resourceRoutes returns the routes of the ResourceHandler and the middleware applied
+// to them.
+func (r *muxAPI) resourceRoutes(h ResourceHandler,
+	middleware []RequestMiddleware) ([]resourceRoute, []RequestMiddleware) {
+
 	resource := h.ResourceName()
+	handlerName := fmt.Sprintf("%T", h.(resourceHandlerProxy).ResourceHandler)
+	// Middleware is applied in reverse, so query string variables are validated once
+	// requests are authenticated.
+	if _, ok := queryParameterHandler(h); ok || r.config.StrictQueryParameters {
+		middleware = append(middleware, r.handler.newQueryParameterMiddleware(h))
+	}
 	middleware = append(middleware, newAuthMiddleware(h.Authenticate))
 	if validVersions := h.ValidVersions(); validVersions != nil {
 		middleware = append(middleware, newVersionMiddleware(validVersions))
 	}
 
-	// Some browsers don't support PUT and DELETE, so allow method overriding.
-	// POST requests with X-HTTP-Method-Override=PUT/DELETE will route to the
-	// respective handlers.
-
-	route := r.router.Handle(
-		h.ReadListURI(), applyMiddleware(r.handler.handleReadList(h), middleware),
-	).Methods("POST").Headers("X-HTTP-Method-Override", "GET").Name(resource + ":readListOverride")
-	r.checkRoute("read list override", h.ReadListURI(), "OVERRIDE-GET", route)
-
-	route = r.router.Handle(
-		h.ReadURI(), applyMiddleware(r.handler.handleRead(h), middleware),
-	).Methods("POST").Headers("X-HTTP-Method-Override", "GET").Name(resource + ":readOverride")
-	r.checkRoute("read override", h.ReadURI(), "OVERRIDE-GET", route)
-
-	route = r.router.Handle(
-		h.UpdateListURI(), applyMiddleware(r.handler.handleUpdateList(h), middleware),
-	).Methods("POST").Headers("X-HTTP-Method-Override", "PUT").Name(resource + ":updateListOverride")
-	r.checkRoute("update list override", h.UpdateListURI(), "OVERRIDE-PUT", route)
-
-	route = r.router.Handle(
-		h.UpdateURI(), applyMiddleware(r.handler.handleUpdate(h), middleware),
-	).Methods("POST").Headers("X-HTTP-Method-Override", "PUT").Name(resource + ":updateOverride")
-	r.checkRoute("update override", h.UpdateURI(), "OVERRIDE-PUT", route)
-
-	route = r.router.Handle(
-		h.DeleteURI(), applyMiddleware(r.handler.handleDelete(h), middleware),
-	).Methods("POST").Headers("X-HTTP-Method-Override", "DELETE").Name(resource + ":deleteOverride")
-	r.checkRoute("delete override", h.DeleteURI(), "OVERRIDE-DELETE", route)
-
-	// These return a Route which has a GetError command. Probably should check
-	// that and log it if it fails :)
-	r.router.Handle(
-		h.CreateURI(), applyMiddleware(r.handler.handleCreate(h), middleware),
-	).Methods("POST").Name(resource + ":" + string(HandleCreate))
-	r.checkRoute("create", h.CreateURI(), "POST", route)
-
-	r.router.Handle(
-		h.ReadListURI(), applyMiddleware(r.handler.handleReadList(h), middleware),
-	).Methods("GET").Name(resource + ":" + string(HandleReadList))
-	r.checkRoute("read list", h.ReadListURI(), "GET", route)
-
-	r.router.Handle(
-		h.ReadURI(), applyMiddleware(r.handler.handleRead(h), middleware),
-	).Methods("GET").Name(resource + ":" + string(HandleRead))
-	r.checkRoute("read", h.ReadURI(), "GET", route)
-
-	r.router.Handle(
-		h.UpdateListURI(), applyMiddleware(r.handler.handleUpdateList(h), middleware),
-	).Methods("PUT").Name(resource + ":" + string(HandleUpdateList))
-	r.checkRoute("update list", h.UpdateListURI(), "PUT", route)
-
-	r.router.Handle(
-		h.UpdateURI(), applyMiddleware(r.handler.handleUpdate(h), middleware),
-	).Methods("PUT").Name(resource + ":" + string(HandleUpdate))
-	r.checkRoute("update", h.UpdateURI(), "PUT", route)
-
-	r.router.Handle(
-		h.DeleteURI(), applyMiddleware(r.handler.handleDelete(h), middleware),
-	).Methods("DELETE").Name(resource + ":" + string(HandleDelete))
-	r.checkRoute("delete", h.DeleteURI(), "DELETE", route)
+	// The framework's routes under the resource, e.g. schema and events, are
+	// registered first so they aren't matched as reads of resources with IDs such as
+	// "schema" and "events".
+	routes := []resourceRoute{{
+		name: resource + ":schema", method: "GET", uri: schemaURI(h),
+		label: "schema", logMethod: "GET", handlerName: "rest.JSONSchema",
+		handler: applyMiddleware(r.handler.handleSchema(h), middleware),
+	}}
+	if r.handler.changes != nil {
+		routes = append(routes, resourceRoute{
+			name: resource + ":changes", method: "GET", uri: changesURI(h),
+			label: "changes", logMethod: "GET", handlerName: "rest.Changes",
+			handler: applyMiddleware(r.handler.handleChanges(h), middleware),
+		})
+	}
+	routes = append(routes, r.handler.uploadRoutes(h, middleware)...)
+	routes = append(routes, r.handler.viewRoutes(h, middleware)...)
+	if r.handler.events != nil {
+		routes = append(routes, resourceRoute{
+			name: resource + ":events", method: "GET", uri: eventsURI(h),
+			label: "events", logMethod: "GET", handlerName: "rest.Events",
+			handler: applyMiddleware(r.handler.handleEvents(h), middleware),
+		})
+	}
+	if s, ok := searcher(h); ok {
+		routes = append(routes, resourceRoute{
+			name: resource + ":search", method: "GET", uri: searchURI(h),
+			label: "search", logMethod: "GET", handlerName: handlerName + ".Search",
+			handler: applyMiddleware(r.handler.handleSearch(h, s), middleware),
+		})
+	}
+	if a, ok := aggregator(h); ok {
+		routes = append(routes, resourceRoute{
+			name: resource + ":aggregate", method: "GET", uri: aggregateURI(h),
+			label: "aggregate", logMethod: "GET", handlerName: handlerName + ".Aggregate",
+			handler: applyMiddleware(r.handler.handleAggregate(h, a), middleware),
+		})
+	}
+	if d, ok := distinctValuer(h); ok {
+		routes = append(routes, resourceRoute{
+			name: resource + ":distinct", method: "GET", uri: distinctURI(h),
+			label: "distinct", logMethod: "GET", handlerName: handlerName + ".DistinctValues",
+			handler: applyMiddleware(r.handler.handleDistinct(h, d), middleware),
+		})
+	}
 
-	r.resourceHandlers = append(r.resourceHandlers, h)
+	return append(routes, []resourceRoute{
+
+		// Some browsers don't support PUT and DELETE, so allow method overriding.
+		// POST requests with X-HTTP-Method-Override=PUT/DELETE will route to the
+		// respective handlers.
+		{
+			name: resource + ":readListOverride", method: "POST", uri: h.ReadListURI(),
+			headers: []string{"X-HTTP-Method-Override", "GET"},
+			label:   "read list override", logMethod: "OVERRIDE-GET",
+			handlerName: handlerName + ".ReadResourceList",
+			handler:     applyMiddleware(r.handler.handleReadList(h), middleware),
+		},
+		{
+			name: resource + ":readOverride", method: "POST", uri: h.ReadURI(),
+			headers: []string{"X-HTTP-Method-Override", "GET"},
+			label:   "read override", logMethod: "OVERRIDE-GET",
+			handlerName: handlerName + ".ReadResource",
+			handler:     applyMiddleware(r.handler.handleRead(h), middleware),
+		},
+		{
+			name: resource + ":updateListOverride", method: "POST", uri: h.UpdateListURI(),
+			headers: []string{"X-HTTP-Method-Override", "PUT"},
+			label:   "update list override", logMethod: "OVERRIDE-PUT",
+			handlerName: handlerName + ".UpdateResourceList",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleUpdateList,
+				r.handler.invalidatingWrite(h, r.handler.handleUpdateList(h))), middleware),
+		},
+		{
+			name: resource + ":updateOverride", method: "POST", uri: h.UpdateURI(),
+			headers: []string{"X-HTTP-Method-Override", "PUT"},
+			label:   "update override", logMethod: "OVERRIDE-PUT",
+			handlerName: handlerName + ".UpdateResource",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleUpdate,
+				r.handler.invalidatingWrite(h, r.handler.handleUpdate(h))), middleware),
+		},
+		{
+			name: resource + ":deleteOverride", method: "POST", uri: h.DeleteURI(),
+			headers: []string{"X-HTTP-Method-Override", "DELETE"},
+			label:   "delete override", logMethod: "OVERRIDE-DELETE",
+			handlerName: handlerName + ".DeleteResource",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleDelete,
+				r.handler.invalidatingWrite(h, r.handler.handleDelete(h))), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleCreate), method: "POST", uri: h.CreateURI(),
+			label: "create", logMethod: "POST", handlerName: handlerName + ".CreateResource",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleCreate,
+				r.handler.invalidatingWrite(h, r.handler.handleCreate(h))), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleReadList), method: "GET", uri: h.ReadListURI(),
+			label: "read list", logMethod: "GET", handlerName: handlerName + ".ReadResourceList",
+			handler: applyMiddleware(r.handler.cachedRead(h, resource+":"+string(HandleReadList),
+				r.handler.handleReadList(h)), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleRead), method: "GET", uri: h.ReadURI(),
+			label: "read", logMethod: "GET", handlerName: handlerName + ".ReadResource",
+			handler: applyMiddleware(r.handler.cachedRead(h, resource+":"+string(HandleRead),
+				r.handler.handleRead(h)), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleUpdateList), method: "PUT", uri: h.UpdateListURI(),
+			label: "update list", logMethod: "PUT", handlerName: handlerName + ".UpdateResourceList",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleUpdateList,
+				r.handler.invalidatingWrite(h, r.handler.handleUpdateList(h))), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleUpdate), method: "PUT", uri: h.UpdateURI(),
+			label: "update", logMethod: "PUT", handlerName: handlerName + ".UpdateResource",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleUpdate,
+				r.handler.invalidatingWrite(h, r.handler.handleUpdate(h))), middleware),
+		},
+		{
+			name: resource + ":" + string(HandleDelete), method: "DELETE", uri: h.DeleteURI(),
+			label: "delete", logMethod: "DELETE", handlerName: handlerName + ".DeleteResource",
+			handler: applyMiddleware(r.handler.schedulable(h, HandleDelete,
+				r.handler.invalidatingWrite(h, r.handler.handleDelete(h))), middleware),
+		},
+	}...), middleware
 }
 
 // RegisterHandlerFunc binds the http.HandlerFunc to the provided URI and applies any
 // specified middleware.
 func (r *muxAPI) RegisterHandlerFunc(uri string, handlerfunc http.HandlerFunc,
 	middleware ...RequestMiddleware) {
-	r.router.Handle(uri, applyMiddleware(http.HandlerFunc(handlerfunc), middleware))
+	err := r.handle(newRouterRoute("", "", uri, false, applyMiddleware(http.HandlerFunc(handlerfunc), middleware)),
+		funcName(handlerfunc), middleware, 0)
+	if err != nil {
+		log.Printf("Failed to setup route %s with %v", uri, err)
+	}
 }
 
 // RegisterHandler binds the http.Handler to the provided URI and applies any specified
 // middleware.
 func (r *muxAPI) RegisterHandler(uri string, handler http.Handler, middleware ...RequestMiddleware) {
-	r.router.Handle(uri, applyMiddleware(handler, middleware))
+	err := r.handle(newRouterRoute("", "", uri, false, applyMiddleware(handler, middleware)),
+		fmt.Sprintf("%T", handler), middleware, 0)
+	if err != nil {
+		log.Printf("Failed to setup route %s with %v", uri, err)
+	}
 }
 
 // RegisterPathPrefix binds the http.HandlerFunc to URIs matched by the given path
 // prefix and applies any specified middleware.
 func (r *muxAPI) RegisterPathPrefix(uri string, handler http.HandlerFunc,
 	middleware ...RequestMiddleware) {
-	r.router.PathPrefix(uri).Handler(applyMiddleware(handler, middleware))
+	err := r.handle(newRouterRoute("", "", uri, true, applyMiddleware(handler, middleware)),
+		funcName(handler), middleware, 0)
+	if err != nil {
+		log.Printf("Failed to setup route %s with %v", uri, err)
+	}
 }
 
-// ServeHTTP handles an HTTP request.
+// ServeHTTP handles an HTTP request, overriding its method if configured, and dispatches
+// it to the API registered for its host or, if it doesn't match any route, to a route
+// matching it leniently, static files or the Fallback.
 func (r *muxAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.router.ServeHTTP(w, req)
+	handler, req := r.handlerFor(r.overrideMethod(req))
+	handler.ServeHTTP(w, req)
+}
+
+// handlerFor returns the Handler dispatching the request and the request to pass it,
+// whose path is rewritten if it matches a route leniently.
+func (r *muxAPI) handlerFor(req *http.Request) (http.Handler, *http.Request) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if api := r.hostAPI(req.Host); api != nil {
+		return api, req
+	}
+	if r.maintenance != nil && !r.maintenanceExempt(req.URL.Path) {
+		return r.maintenance, req
+	}
+	if handler, lenient := r.lenientHandler(req); handler != nil {
+		return handler, lenient
+	}
+	if handler := r.unmatchedHandler(req); handler != nil {
+		return handler, req
+	}
+	return r.routed, req
 }
 
 // RegisterResponseSerializer registers the provided ResponseSerializer with the given format. If the
@@ -426,7 +970,9 @@ func (r *muxAPI) AvailableFormats() []string {
 
 // ResourceHandlers returns a slice containing the registered ResourceHandlers.
 func (r *muxAPI) ResourceHandlers() []ResourceHandler {
-	return r.resourceHandlers
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]ResourceHandler(nil), r.resourceHandlers...)
 }
 
 // Configuration returns the API Configuration.
@@ -438,7 +984,7 @@ func (r *muxAPI) Configuration() *Configuration {
 // all Rules are valid, otherwise returns the first encountered validation
 // error.
 func (r *muxAPI) Validate() error {
-	for _, handler := range r.resourceHandlers {
+	for _, handler := range r.ResourceHandlers() {
 		rules := handler.Rules()
 		if rules == nil || rules.Size() == 0 {
 			continue
@@ -468,7 +1014,7 @@ func (r *muxAPI) responseSerializer(format string) (ResponseSerializer, error) {
 	if serializer, ok := r.serializerRegistry[format]; ok {
 		return serializer, nil
 	}
-	return nil, fmt.Errorf("Format not implemented: %s", format)
+	return nil, fmt.Errorf("%w: %s", ErrFormatNotImplemented, format)
 }
 
 // applyMiddleware wraps the Handler with the provided RequestMiddleware and returns another Handler.
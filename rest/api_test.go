@@ -18,13 +18,17 @@ package rest
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
 )
 
 type TestResource struct {
@@ -131,7 +135,7 @@ func (m *MockResourceHandler) Rules() Rules {
 // getRouteHandler returns the http.Handler for the API route with the given name.
 // This is purely for testing purposes and shouldn't be used elsewhere.
 func (r *muxAPI) getRouteHandler(name string) (http.Handler, error) {
-	route := r.router.Get(name)
+	route := r.router.(*muxRouter).router.Get(name)
 	if route == nil {
 		return nil, fmt.Errorf("No API route with name %s", name)
 	}
@@ -277,6 +281,7 @@ func TestHandleCreateNotAuthorized(t *testing.T) {
 	handler.On("ResourceName").Return("foo")
 	handler.On("Authenticate").Return(fmt.Errorf("Not authorized"))
 	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
 
 	api.RegisterResourceHandler(handler)
 	createHandler, _ := api.(*muxAPI).getRouteHandler("foo:create")
@@ -911,8 +916,9 @@ func TestOutboundRulesDontApplyOnNilResource(t *testing.T) {
 	readHandler.ServeHTTP(resp, req)
 
 	handler.Mock.AssertExpectations(t)
+	assert.Equal(http.StatusNotFound, resp.Code, "Incorrect response code")
 	assert.Equal(
-		`{"messages":[],"reason":"OK","result":null,"status":200}`,
+		`{"messages":["Resource not found"],"reason":"Not Found","status":404}`,
 		resp.Body.String(),
 		"Incorrect response string",
 	)
@@ -1150,3 +1156,401 @@ func TestVersionMiddleware(t *testing.T) {
 	assert.Equal(w.Code, http.StatusBadRequest)
 	assert.NotContains(w.Body.String(), "foo")
 }
+
+type acceptedResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (a acceptedResourceHandler) ResourceName() string {
+	return "jobs"
+}
+
+func (a acceptedResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	ctx.SetResponseStatus(http.StatusAccepted)
+	ctx.ResponseHeader().Set("Location", "http://foo.com/api/v1/jobs/1")
+	ctx.ResponseHeader().Set("X-Job-Id", "1")
+	return &TestResource{Foo: "pending"}, nil
+}
+
+// Ensures that handlers can override the success status code and set response
+// headers.
+func TestHandleCreateResponseStatusAndHeaders(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(acceptedResourceHandler{})
+	createHandler, _ := api.(*muxAPI).getRouteHandler("jobs:create")
+
+	req, _ := http.NewRequest("POST", "http://foo.com/api/v1/jobs", bytes.NewReader([]byte(`{}`)))
+	resp := httptest.NewRecorder()
+
+	createHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusAccepted, resp.Code, "Incorrect response code")
+	assert.Equal("http://foo.com/api/v1/jobs/1", resp.Header().Get("Location"))
+	assert.Equal("1", resp.Header().Get("X-Job-Id"))
+	assert.Equal("application/json", resp.Header().Get("Content-Type"))
+	assert.Equal(
+		`{"messages":[],"reason":"Accepted","result":{"foo":"pending"},"status":202}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+type pagedResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (p pagedResourceHandler) ResourceName() string {
+	return "pages"
+}
+
+func (p pagedResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	ctx.SetTotal(3)
+	ctx.SetNextCursor(fmt.Sprintf("%d", ctx.Offset()+limit))
+	return []Resource{&TestResource{Foo: "hello"}}, "", nil
+}
+
+// Ensures that the read list handler includes pagination metadata set by the
+// ResourceHandler.
+func TestHandleReadListPagination(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(pagedResourceHandler{})
+	readListHandler, _ := api.(*muxAPI).getRouteHandler("pages:readList")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/pages?limit=1&offset=1", nil)
+	req.RequestURI = "/api/v1/pages?limit=1&offset=1"
+	resp := httptest.NewRecorder()
+
+	readListHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusOK, resp.Code, "Incorrect response code")
+	assert.Equal(
+		`{"messages":[],"next":"http://foo.com/api/v1/pages?limit=1\u0026next=2\u0026offset=1","reason":"OK","results":[{"foo":"hello"}],"status":200,"total":3}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+type deprecatedResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (d deprecatedResourceHandler) ResourceName() string {
+	return "legacy"
+}
+
+func (d deprecatedResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	ctx.AddWarning("field 'foo' is deprecated")
+	return &TestResource{Foo: "bar"}, nil
+}
+
+// Ensures that warnings are included in the response messages and Warning header.
+func TestHandleReadWarnings(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(deprecatedResourceHandler{})
+	readHandler, _ := api.(*muxAPI).getRouteHandler("legacy:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/legacy/1", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusOK, resp.Code, "Incorrect response code")
+	assert.Equal(`299 - "field 'foo' is deprecated"`, resp.Header().Get("Warning"))
+	assert.Equal(
+		`{"messages":["field 'foo' is deprecated"],"reason":"OK","result":{"foo":"bar"},"status":200}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+type slowResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (s slowResourceHandler) ResourceName() string {
+	return "slow"
+}
+
+func (s slowResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	if _, ok := ctx.RemainingTime(); !ok {
+		return nil, fmt.Errorf("no deadline")
+	}
+	<-ctx.Done()
+	return &TestResource{Foo: "late"}, nil
+}
+
+// Ensures that the RequestContext is canceled once the configured RequestTimeout
+// elapses and a 503 is returned.
+func TestHandleReadTimeout(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{RequestTimeout: time.Millisecond})
+	api.RegisterResourceHandler(slowResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/slow/1", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(
+		`{"messages":["Request timed out"],"reason":"Service Unavailable","status":503}`,
+		resp.Body.String(),
+	)
+}
+
+// Ensures that nothing is written once the client has disconnected.
+func TestHandleReadClientDisconnected(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(slowResourceHandler{})
+
+	parent, cancel := context.WithTimeout(context.Background(), time.Hour)
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/slow/1", nil)
+	req = req.WithContext(parent)
+	cancel()
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.False(resp.Flushed)
+	assert.Equal("", resp.Body.String())
+	assert.Equal("", resp.Header().Get("Content-Type"))
+}
+
+// Ensures that the read handler responds with the status of a StatusError returned
+// by the ResourceHandler.
+func TestHandleReadStatusError(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+	handler.On("ReadResource").Return(nil, NotFound("no such foo"))
+
+	api.RegisterResourceHandler(handler)
+	readHandler, _ := api.(*muxAPI).getRouteHandler("foo:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v0.1/foo/1", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code, "Incorrect response code")
+	assert.Equal(
+		`{"messages":["no such foo"],"reason":"Not Found","status":404}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+// Ensures that the configured ErrorMapper translates errors returned by the
+// ResourceHandler.
+func TestHandleReadErrorMapper(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{ErrorMapper: noRowsMapper})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+	handler.On("ReadResource").Return(nil, errNoRows)
+
+	api.RegisterResourceHandler(handler)
+	readHandler, _ := api.(*muxAPI).getRouteHandler("foo:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v0.1/foo/1", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code, "Incorrect response code")
+	assert.Equal(
+		`{"messages":["Resource does not exist"],"reason":"Not Found","status":404}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+// Ensures that the create handler returns a Bad Request with the offset of the error
+// if the request body is malformed JSON.
+func TestHandleCreateMalformedBody(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+
+	api.RegisterResourceHandler(handler)
+	createHandler, _ := api.(*muxAPI).getRouteHandler("foo:create")
+
+	req, _ := http.NewRequest("POST", "http://foo.com/api/v0.1/foo",
+		bytes.NewReader([]byte(`{"foo": bar}`)))
+	resp := httptest.NewRecorder()
+
+	createHandler.ServeHTTP(resp, req)
+
+	handler.AssertNotCalled(t, "CreateResource")
+	assert.Equal(http.StatusBadRequest, resp.Code, "Incorrect response code")
+	assert.Equal(
+		`{"messages":["Malformed JSON at offset 9: invalid character 'b' looking for beginning of value"],"reason":"Bad Request","status":400}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+// Ensures that the create and update handlers return a Bad Request if the request
+// body is empty.
+func TestHandleCreateUpdateEmptyBody(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+
+	api.RegisterResourceHandler(handler)
+
+	for _, route := range []string{"foo:create", "foo:update", "foo:updateList"} {
+		routeHandler, _ := api.(*muxAPI).getRouteHandler(route)
+		req, _ := http.NewRequest("PUT", "http://foo.com/api/v0.1/foo/1", nil)
+		resp := httptest.NewRecorder()
+
+		routeHandler.ServeHTTP(resp, req)
+
+		assert.Equal(http.StatusBadRequest, resp.Code, "Incorrect response code")
+		assert.Equal(
+			`{"messages":["Request body is empty"],"reason":"Bad Request","status":400}`,
+			resp.Body.String(),
+			"Incorrect response string",
+		)
+	}
+}
+
+// Ensures that the read handler sets the Retry-After header for retryable errors.
+func TestHandleReadRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+	handler.On("ReadResource").Return(nil, Unavailable(30*time.Second))
+
+	api.RegisterResourceHandler(handler)
+	readHandler, _ := api.(*muxAPI).getRouteHandler("foo:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v0.1/foo/1", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusServiceUnavailable, resp.Code, "Incorrect response code")
+	assert.Equal("30", resp.Header().Get("Retry-After"))
+	assert.Equal(
+		`{"messages":["Service temporarily unavailable"],"reason":"Service Unavailable","status":503}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
+
+// statusSerializer is a ResponseSerializer which renders errors as a status message
+// in the style of google.rpc.Status.
+type statusSerializer struct{}
+
+func (s statusSerializer) Serialize(p Payload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (s statusSerializer) SerializeError(err error, p Payload) ([]byte, error) {
+	return []byte(fmt.Sprintf("code: %d\nmessage: %q\n", p["status"], err.Error())), nil
+}
+
+func (s statusSerializer) ContentType() string {
+	return "application/x-status"
+}
+
+// Ensures that error responses are rendered using ErrorSerializer when the negotiated
+// serializer implements it.
+func TestHandleReadErrorSerializer(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+	api.RegisterResponseSerializer("status", statusSerializer{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+	handler.On("ReadResource").Return(nil, NotFound("no such foo"))
+
+	api.RegisterResourceHandler(handler)
+	readHandler, _ := api.(*muxAPI).getRouteHandler("foo:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v0.1/foo/1?format=status", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code, "Incorrect response code")
+	assert.Equal("application/x-status", resp.Header().Get("Content-Type"))
+	assert.Equal("code: 404\nmessage: \"no such foo\"\n", resp.Body.String())
+}
+
+// Ensures that an unknown response format results in an error matching
+// ErrFormatNotImplemented.
+func TestResponseSerializerFormatNotImplemented(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+
+	_, err := api.responseSerializer("blah")
+	assert.True(errors.Is(err, ErrFormatNotImplemented))
+	assert.Equal("Format not implemented: blah", err.Error())
+	assert.Equal(http.StatusBadRequest, errorStatus(err))
+}
+
+// Ensures that wrapped ValidationErrors returned by a ResourceHandler still render
+// their field errors.
+func TestHandleReadWrappedValidationError(t *testing.T) {
+	assert := assert.New(t)
+	handler := new(MockResourceHandler)
+	api := NewAPI(&Configuration{})
+
+	handler.On("ResourceName").Return("foo")
+	handler.On("Authenticate").Return(nil)
+	handler.On("ValidVersions").Return(nil)
+	handler.On("Rules").Return(&rules{})
+	handler.On("ReadResource").Return(nil, fmt.Errorf("checking foo: %w",
+		NewValidationError("Invalid foo", FieldError{Field: "id", Code: "invalid", Message: "Bad ID"})))
+
+	api.RegisterResourceHandler(handler)
+	readHandler, _ := api.(*muxAPI).getRouteHandler("foo:read")
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v0.1/foo/1", nil)
+	resp := httptest.NewRecorder()
+
+	readHandler.ServeHTTP(resp, req)
+
+	assert.Equal(statusUnprocessableEntity, resp.Code, "Incorrect response code")
+	assert.Equal(
+		`{"errors":[{"code":"invalid","field":"id","message":"Bad ID"}],"messages":["checking foo: Invalid foo"],`+
+			`"reason":"Unprocessable Entity","status":422}`,
+		resp.Body.String(),
+		"Incorrect response string",
+	)
+}
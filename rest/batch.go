@@ -0,0 +1,90 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"log"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Batch executes the independent sub-operations of a bulk request, such as the items
+// of an UpdateResourceList call, concurrently so the latency of the request approaches
+// that of its slowest item rather than the sum of them.
+type Batch struct {
+	// Workers is the maximum number of sub-operations executed at once. Defaults to
+	// GOMAXPROCS.
+	Workers int
+}
+
+// Run executes the operation for each of the n items, passing the item's index, and
+// returns the error of each item in order, which is nil if it succeeded. Items fail
+// independently: an error or panic in one doesn't affect the others, and a panic is
+// logged and returned as a 500 Error. Once ctx is done, items which haven't started
+// fail with its error.
+func (b Batch) Run(ctx context.Context, n int, op func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = runBatchItem(ctx, i, op)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() == nil {
+			select {
+			case indexes <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		for ; i < n; i++ {
+			errs[i] = ctx.Err()
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
+
+// runBatchItem executes the operation for the item, recovering from any panic.
+func runBatchItem(ctx context.Context, i int, op func(ctx context.Context, i int) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Recovered from panic in batch item %d: %v\n%s", i, recovered, debug.Stack())
+			err = InternalServerError("Internal server error")
+		}
+	}()
+	return op(ctx, i)
+}
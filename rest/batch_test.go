@@ -0,0 +1,93 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// Ensures that the items of a Batch are executed concurrently up to the worker limit
+// and that their errors are returned in order.
+func TestBatchRun(t *testing.T) {
+	assert := assert.New(t)
+	var running, maxRunning int32
+	errs := Batch{Workers: 3}.Run(context.Background(), 10, func(ctx context.Context, i int) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if i%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+
+	assert.Len(errs, 10)
+	for i, err := range errs {
+		if i%2 == 1 {
+			assert.EqualError(err, "odd")
+		} else {
+			assert.Nil(err)
+		}
+	}
+	assert.Equal(int32(3), maxRunning)
+}
+
+// Ensures that a panicking item of a Batch fails without affecting the others.
+func TestBatchRunPanic(t *testing.T) {
+	assert := assert.New(t)
+	errs := Batch{}.Run(context.Background(), 3, func(ctx context.Context, i int) error {
+		if i == 1 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	assert.Nil(errs[0])
+	assert.Equal(500, errs[1].(Error).Status())
+	assert.Nil(errs[2])
+}
+
+// Ensures that items of a Batch which haven't started when the context is done fail
+// with its error.
+func TestBatchRunCanceled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := Batch{Workers: 1}.Run(ctx, 5, func(ctx context.Context, i int) error {
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.Nil(errs[0])
+	assert.Nil(errs[1])
+	assert.Equal(context.Canceled, errs[3])
+	assert.Equal(context.Canceled, errs[4])
+	assert.Len(Batch{}.Run(ctx, 0, nil), 0)
+}
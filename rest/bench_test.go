@@ -0,0 +1,116 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// benchWidget is the resource served by the benchmark ResourceHandler.
+type benchWidget struct {
+	ID    string
+	Name  string
+	Count int
+	Tags  []string
+}
+
+// benchResourceHandler serves fixed benchWidgets with Rules, so the benchmarks exercise
+// decoding, Rule application and serialization without any storage.
+type benchResourceHandler struct {
+	BaseResourceHandler
+	widgets []Resource
+}
+
+func newBenchResourceHandler(n int) *benchResourceHandler {
+	widgets := make([]Resource, n)
+	for i := range widgets {
+		widgets[i] = &benchWidget{
+			ID:    strconv.Itoa(i),
+			Name:  "widget " + strconv.Itoa(i),
+			Count: i,
+			Tags:  []string{"a", "b"},
+		}
+	}
+	return &benchResourceHandler{widgets: widgets}
+}
+
+func (b *benchResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (b *benchResourceHandler) Rules() Rules {
+	return NewRules((*benchWidget)(nil),
+		&Rule{Field: "ID", FieldAlias: "id", OutputOnly: true},
+		&Rule{Field: "Name", FieldAlias: "name", Type: String, Required: true},
+		&Rule{Field: "Count", FieldAlias: "count", Type: Int},
+		&Rule{Field: "Tags", FieldAlias: "tags"},
+	)
+}
+
+func (b *benchResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	return b.widgets[0], nil
+}
+
+func (b *benchResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return b.widgets[0], nil
+}
+
+func (b *benchResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	return b.widgets, "", nil
+}
+
+// benchmarkRequest benchmarks serving the request to an API with the Configuration.
+func benchmarkRequest(b *testing.B, config *Configuration, method, url string, body []byte) {
+	api := NewAPI(config)
+	api.RegisterResourceHandler(newBenchResourceHandler(100))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.ServeHTTP(resp, req)
+		if resp.Code >= 300 {
+			b.Fatalf("unexpected status %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	benchmarkRequest(b, &Configuration{}, "POST", "http://example.com/api/v1/widgets",
+		[]byte(`{"name": "widget", "count": "3", "tags": ["a", "b"]}`))
+}
+
+func BenchmarkRead(b *testing.B) {
+	benchmarkRequest(b, &Configuration{}, "GET", "http://example.com/api/v1/widgets/1", nil)
+}
+
+func BenchmarkReadList(b *testing.B) {
+	benchmarkRequest(b, &Configuration{}, "GET", "http://example.com/api/v1/widgets", nil)
+}
+
+// BenchmarkReadInstrumented measures the overhead of Instrumentation on reads.
+func BenchmarkReadInstrumented(b *testing.B) {
+	benchmarkRequest(b, &Configuration{Instrumentation: true}, "GET",
+		"http://example.com/api/v1/widgets/1", nil)
+}
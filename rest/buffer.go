@@ -0,0 +1,247 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// errInvalidRawMessage is returned when serializing a json.RawMessage which isn't
+// valid JSON.
+var errInvalidRawMessage = errors.New("Result is not valid JSON")
+
+// maxPooledBufferSize is the capacity above which response buffers are dropped rather
+// than returned to the pool, so an occasional large response doesn't pin its memory.
+const maxPooledBufferSize = 64 << 10
+
+// responseBufferPool pools the buffers responses are serialized into.
+var responseBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := &responseBuffer{}
+		b.encoder = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// responseBuffer is a pooled buffer along with a JSON encoder writing to it.
+type responseBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+
+	// limit, if positive, is the size the buffer may not exceed. Encoding fails with
+	// errResponseTooLarge once it does.
+	limit int
+}
+
+// getResponseBuffer returns an empty responseBuffer from the pool.
+func getResponseBuffer() *responseBuffer {
+	return responseBufferPool.Get().(*responseBuffer)
+}
+
+// putResponseBuffer resets the responseBuffer and returns it to the pool. The buffer's
+// contents must not be used afterwards.
+func putResponseBuffer(b *responseBuffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	b.Reset()
+	b.limit = 0
+	responseBufferPool.Put(b)
+}
+
+// encodeJSON appends the JSON encoding of v to the buffer, as produced by
+// json.Marshal.
+func (b *responseBuffer) encodeJSON(v interface{}) error {
+	start := b.Len()
+	if err := b.encoder.Encode(v); err != nil {
+		b.Truncate(start)
+		return err
+	}
+	// Encode terminates each value with a newline which Marshal doesn't.
+	b.Truncate(b.Len() - 1)
+	return b.checkLimit()
+}
+
+// checkLimit returns errResponseTooLarge if the buffer exceeds its limit.
+func (b *responseBuffer) checkLimit() error {
+	if b.limit > 0 && b.Len() > b.limit {
+		return errResponseTooLarge
+	}
+	return nil
+}
+
+// encodePayload appends the JSON encoding of the Payload to the buffer, as produced by
+// json.Marshal. Payloads built by the framework, such as the response envelope and
+// resources with Rules applied, are written directly so each value is encoded once
+// without reflecting over the maps holding them.
+func (b *responseBuffer) encodePayload(p Payload) error {
+	if p == nil {
+		b.WriteString("null")
+		return nil
+	}
+
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start := b.Len()
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.encodeString(key)
+		b.WriteByte(':')
+		if err := b.encodeValue(p[key]); err != nil {
+			b.Truncate(start)
+			return err
+		}
+		if err := b.checkLimit(); err != nil {
+			b.Truncate(start)
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// encodeValue appends the JSON encoding of the value to the buffer, writing Payloads
+// and slices of them with encodePayload and json.RawMessages verbatim.
+func (b *responseBuffer) encodeValue(v interface{}) error {
+	var scratch [24]byte
+	switch value := v.(type) {
+	case nil:
+		b.WriteString("null")
+		return nil
+	case string:
+		b.encodeString(value)
+		return nil
+	case bool:
+		b.Write(strconv.AppendBool(scratch[:0], value))
+		return nil
+	case int:
+		b.Write(strconv.AppendInt(scratch[:0], int64(value), 10))
+		return nil
+	case int64:
+		b.Write(strconv.AppendInt(scratch[:0], value, 10))
+		return nil
+	case json.RawMessage:
+		return b.encodeRaw(value)
+	case Payload:
+		return b.encodePayload(value)
+	case map[string]interface{}:
+		return b.encodePayload(value)
+	case []Resource:
+		if value == nil {
+			break
+		}
+		return b.encodeSlice(len(value), func(i int) interface{} { return value[i] })
+	case []Payload:
+		if value == nil {
+			break
+		}
+		return b.encodeSlice(len(value), func(i int) interface{} { return value[i] })
+	}
+	if b.limit > 0 {
+		// Encode other slices an element at a time so the limit is enforced before
+		// the whole slice is encoded.
+		if slice := reflect.ValueOf(v); isPlainSlice(slice) {
+			return b.encodeSlice(slice.Len(), func(i int) interface{} { return slice.Index(i).Interface() })
+		}
+	}
+	return b.encodeJSON(v)
+}
+
+// isPlainSlice returns true if the value is a non-nil slice, other than a []byte, which
+// json.Marshal encodes as an array of its elements.
+func isPlainSlice(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice || v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	_, marshaler := v.Interface().(json.Marshaler)
+	_, textMarshaler := v.Interface().(encoding.TextMarshaler)
+	return !marshaler && !textMarshaler
+}
+
+// encodeRaw appends the pre-serialized JSON to the buffer verbatim, without the
+// compaction and HTML escaping applied by json.Marshal, so results which already exist
+// as JSON, e.g. documents read from a store, aren't decoded and encoded again. It
+// returns an error if the JSON is invalid.
+func (b *responseBuffer) encodeRaw(raw json.RawMessage) error {
+	if raw == nil {
+		b.WriteString("null")
+		return nil
+	}
+	if !json.Valid(raw) {
+		return errInvalidRawMessage
+	}
+	b.Write(raw)
+	return b.checkLimit()
+}
+
+// encodeString appends the JSON encoding of the string to the buffer. Strings without
+// characters needing escaping are written as-is.
+func (b *responseBuffer) encodeString(s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' ||
+			c == '<' || c == '>' || c == '&' {
+			// The error is always nil when encoding a string.
+			b.encodeJSON(s)
+			return
+		}
+	}
+	b.WriteByte('"')
+	b.WriteString(s)
+	b.WriteByte('"')
+}
+
+// encodeSlice appends the JSON array of n values returned by item to the buffer.
+func (b *responseBuffer) encodeSlice(n int, item func(int) interface{}) error {
+	start := b.Len()
+	b.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := b.encodeValue(item(i)); err != nil {
+			b.Truncate(start)
+			return err
+		}
+		if err := b.checkLimit(); err != nil {
+			b.Truncate(start)
+			return err
+		}
+	}
+	b.WriteByte(']')
+	return nil
+}
+
+// bufferSerializer is implemented by ResponseSerializers which can serialize into a
+// pooled responseBuffer rather than allocating a byte slice per response.
+type bufferSerializer interface {
+	serializeTo(*responseBuffer, Payload) error
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// marshalSerializer is a ResponseSerializer which always allocates a byte slice per
+// response.
+type marshalSerializer struct{}
+
+func (m marshalSerializer) Serialize(p Payload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (m marshalSerializer) ContentType() string {
+	return "application/json"
+}
+
+// benchmarkResponse returns a response with a moderately sized result.
+func benchmarkResponse() response {
+	items := make([]Resource, 50)
+	for i := range items {
+		items[i] = Payload{"id": i, "name": "widget", "tags": []string{"a", "b"}}
+	}
+	return response{
+		Status:  http.StatusOK,
+		Payload: Payload{status: 200, reason: "OK", messages: []string{}, results: items},
+	}
+}
+
+// Ensures that encodeJSON produces the same output as json.Marshal.
+func TestResponseBufferEncodeJSON(t *testing.T) {
+	assert := assert.New(t)
+	payload := Payload{"html": "<b>&</b>", "n": 1, "list": []int{1, 2}}
+	expected, _ := json.Marshal(payload)
+
+	buf := getResponseBuffer()
+	defer putResponseBuffer(buf)
+	assert.Nil(buf.encodeJSON(payload))
+	assert.Equal(string(expected), buf.String())
+}
+
+// Ensures that encodeJSON leaves the buffer unchanged when encoding fails.
+func TestResponseBufferEncodeJSONError(t *testing.T) {
+	assert := assert.New(t)
+	buf := getResponseBuffer()
+	defer putResponseBuffer(buf)
+	buf.WriteString("prefix")
+
+	assert.NotNil(buf.encodeJSON(Payload{"bad": make(chan int)}))
+	assert.Equal("prefix", buf.String())
+}
+
+// Ensures that encodePayload produces the same output as json.Marshal for nested
+// Payloads, maps, and slices.
+func TestResponseBufferEncodePayload(t *testing.T) {
+	assert := assert.New(t)
+	payload := Payload{
+		"result": Payload{"name": "<widget>", "parts": []Resource{Payload{"id": 1}, "bolt"}},
+		"map":    map[string]interface{}{"b": 2, "a": []Payload{{"x": nil}}},
+		"raw":    json.RawMessage(`{"pre":"encoded"}`),
+		"nil":    []Resource(nil),
+		"empty":  Payload(nil),
+		"list":   []int{3, 2, 1},
+		"scalar": []Resource{true, -7, int64(1 << 40), 1.5, "tab\t\"quote\" \u2028 é"},
+		"<key>":  "&",
+	}
+	expected, err := json.Marshal(payload)
+	assert.Nil(err)
+
+	buf := getResponseBuffer()
+	defer putResponseBuffer(buf)
+	assert.Nil(buf.encodePayload(payload))
+	assert.Equal(string(expected), buf.String())
+}
+
+// Ensures that encodePayload leaves the buffer unchanged when a nested value fails to
+// encode.
+func TestResponseBufferEncodePayloadError(t *testing.T) {
+	assert := assert.New(t)
+	buf := getResponseBuffer()
+	defer putResponseBuffer(buf)
+
+	assert.NotNil(buf.encodePayload(Payload{"result": []Resource{Payload{"bad": make(chan int)}}}))
+	assert.Equal(0, buf.Len())
+}
+
+// Ensures that encodePayload embeds json.RawMessages verbatim and rejects invalid
+// ones.
+func TestResponseBufferEncodeRaw(t *testing.T) {
+	assert := assert.New(t)
+	buf := getResponseBuffer()
+	defer putResponseBuffer(buf)
+
+	assert.Nil(buf.encodePayload(Payload{
+		"result": json.RawMessage(`{"name": "<widget>", "parts": [1, 2]}`),
+		"nil":    json.RawMessage(nil),
+	}))
+	assert.Equal(`{"nil":null,"result":{"name": "<widget>", "parts": [1, 2]}}`, buf.String())
+
+	buf.Reset()
+	assert.Equal(errInvalidRawMessage, buf.encodePayload(Payload{"result": json.RawMessage(`{"name":`)}))
+	assert.Equal(0, buf.Len())
+}
+
+// rawResourceHandler serves resources which are already serialized as JSON.
+type rawResourceHandler struct {
+	BaseResourceHandler
+	resource json.RawMessage
+}
+
+func (r *rawResourceHandler) ResourceName() string {
+	return "docs"
+}
+
+func (r *rawResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return r.resource, nil
+}
+
+func (r *rawResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	return []Resource{r.resource, r.resource}, "", nil
+}
+
+// Ensures that pre-serialized results returned by ResourceHandlers are embedded in
+// the response envelope verbatim and that invalid ones result in an error response.
+func TestPreSerializedResults(t *testing.T) {
+	assert := assert.New(t)
+	handler := &rawResourceHandler{resource: json.RawMessage(`{"id": "1", "body": "<p>"}`)}
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(handler)
+
+	req, _ := http.NewRequest("GET", "http://example.com/api/v1/docs/1", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Contains(resp.Body.String(), `"result":{"id": "1", "body": "<p>"}`)
+
+	req, _ = http.NewRequest("GET", "http://example.com/api/v1/docs", nil)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Contains(resp.Body.String(), `"results":[{"id": "1", "body": "<p>"},{"id": "1", "body": "<p>"}]`)
+
+	handler.resource = json.RawMessage(`{"id":`)
+	req, _ = http.NewRequest("GET", "http://example.com/api/v1/docs/1", nil)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusInternalServerError, resp.Code)
+}
+
+// Ensures that buffers are returned to the pool empty and that large buffers are
+// dropped.
+func TestPutResponseBuffer(t *testing.T) {
+	assert := assert.New(t)
+	buf := getResponseBuffer()
+	buf.WriteString("data")
+	putResponseBuffer(buf)
+	assert.Equal(0, buf.Len())
+
+	large := getResponseBuffer()
+	large.WriteString(strings.Repeat("x", maxPooledBufferSize+1))
+	putResponseBuffer(large)
+	assert.NotEqual(0, large.Len())
+}
+
+// Ensures that sendResponse writes the same body through pooled buffers as through
+// Serialize.
+func TestSendResponsePooled(t *testing.T) {
+	assert := assert.New(t)
+	pooled := httptest.NewRecorder()
+	sendResponse(pooled, benchmarkResponse(), jsonSerializer{})
+	marshaled := httptest.NewRecorder()
+	sendResponse(marshaled, benchmarkResponse(), marshalSerializer{})
+
+	assert.Equal(http.StatusOK, pooled.Code)
+	assert.Equal("application/json", pooled.Header().Get("Content-Type"))
+	assert.Equal(marshaled.Body.String(), pooled.Body.String())
+}
+
+// discardResponseWriter is an http.ResponseWriter which discards the response.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d discardResponseWriter) Header() http.Header         { return d.header }
+func (d discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d discardResponseWriter) WriteHeader(int)             {}
+
+func benchmarkSendResponse(b *testing.B, serializer ResponseSerializer) {
+	resp := benchmarkResponse()
+	w := discardResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sendResponse(w, resp, serializer)
+	}
+}
+
+// BenchmarkSendResponsePooled measures serializing responses into pooled buffers.
+func BenchmarkSendResponsePooled(b *testing.B) {
+	benchmarkSendResponse(b, jsonSerializer{})
+}
+
+// BenchmarkSendResponseMarshal measures serializing responses into a new byte slice
+// per response.
+func BenchmarkSendResponseMarshal(b *testing.B) {
+	benchmarkSendResponse(b, marshalSerializer{})
+}
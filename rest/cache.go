@@ -0,0 +1,306 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cacheKeyPrefix is the prefix of the keys of cached responses.
+const cacheKeyPrefix = "rest:cache:"
+
+// ResponseCache caches the responses of read and list requests, keyed by the route,
+// path, query, negotiated format and Principal. Responses to requests without a
+// Principal, e.g. those of ResourceHandlers authenticating without calling
+// SetRequestPrincipal, are only cached for SharedCacheResourceHandlers. Responses with
+// Cache-Control private or no-store, or varying by headers the key doesn't cover,
+// aren't cached. Entries for a resource are invalidated when a create, update or
+// delete request for it succeeds.
+type ResponseCache struct {
+	// Store holds the cached responses, e.g. one returned by NewMemoryCacheStore or
+	// NewRedisCacheStore.
+	Store CacheStore
+
+	// TTL is how long responses are cached for, unless the ResourceHandler is a
+	// CacheableResourceHandler.
+	TTL time.Duration
+}
+
+// CacheStore stores cached responses.
+type CacheStore interface {
+	// Get returns the value stored under the key and true, or false if there is none
+	// or it has expired.
+	Get(key string) ([]byte, bool, error)
+
+	// Set stores the value under the key for the duration of the TTL.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Invalidate removes the values stored under keys beginning with the prefix.
+	Invalidate(prefix string) error
+}
+
+// CacheableResourceHandler is an optional interface implemented by ResourceHandlers to
+// control how long their responses are cached for when a ResponseCache is configured.
+type CacheableResourceHandler interface {
+	// CacheTTL returns how long responses of read and list requests for the version
+	// are cached for. Responses aren't cached if it's zero.
+	CacheTTL(version string) time.Duration
+}
+
+// SharedCacheResourceHandler is an optional interface implemented by ResourceHandlers
+// whose responses to requests without a Principal may be cached. Those responses are
+// keyed by the requests' Authorization and Cookie headers, so they're only shared by
+// callers presenting the same credentials, e.g. anonymous ones.
+type SharedCacheResourceHandler interface {
+	// SharedCache indicates if responses to requests for the version without a
+	// Principal are cached.
+	SharedCache(version string) bool
+}
+
+// cacheKeyHeaders are the request headers hashed into the keys of cached responses,
+// and so which the responses can vary by.
+var cacheKeyHeaders = []string{"Accept", "Accept-Language", "Authorization", "Cookie"}
+
+// cachedResponse is a response held in a CacheStore.
+type cachedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cacheTTL returns how long the handler's responses for the version are cached for.
+func (c *ResponseCache) cacheTTL(handler ResourceHandler, version string) time.Duration {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	if cacheable, ok := handler.(CacheableResourceHandler); ok {
+		return cacheable.CacheTTL(version)
+	}
+	return c.TTL
+}
+
+// cachePrefix returns the prefix of the keys of the resource's cached responses.
+func cachePrefix(resource string) string {
+	return cacheKeyPrefix + resource + ":"
+}
+
+// sharedCache indicates if the handler's responses for the version to requests
+// without a Principal are cached.
+func sharedCache(handler ResourceHandler, version string) bool {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	shared, ok := handler.(SharedCacheResourceHandler)
+	return ok && shared.SharedCache(version)
+}
+
+// cacheKey returns the key of the cached response of the request to the route.
+func cacheKey(resource, route string, r *http.Request) string {
+	hash := sha256.New()
+	parts := []string{route, r.URL.Path, r.URL.Query().Encode()}
+	for _, name := range cacheKeyHeaders {
+		parts = append(parts, strings.Join(r.Header[name], ","))
+	}
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	if principal, ok := RequestPrincipal(r); ok {
+		hash.Write([]byte(principal.ID))
+	}
+	return cachePrefix(resource) + hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheableResponse indicates if the response may be stored, i.e. it isn't marked
+// private or no-store and only varies by headers hashed into its key.
+func cacheableResponse(header http.Header) bool {
+	for _, directive := range strings.Split(strings.ToLower(header.Get("Cache-Control")), ",") {
+		switch strings.TrimSpace(strings.SplitN(directive, "=", 2)[0]) {
+		case "private", "no-store":
+			return false
+		}
+	}
+	for _, value := range header["Vary"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			covered := false
+			for _, keyed := range cacheKeyHeaders {
+				covered = covered || strings.EqualFold(name, keyed)
+			}
+			if !covered {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cachedRead returns a Handler serving the read or list route of the resource from the
+// ResponseCache of the Configuration, caching the successful responses of the next
+// Handler.
+func (h requestHandler) cachedRead(handler ResourceHandler, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := h.Configuration()
+		if config == nil || config.ResponseCache == nil || config.ResponseCache.Store == nil ||
+			r.URL.Query().Get(waitKey) != "" {
+			// Long polls wait for changes, so they aren't served from the cache.
+			next.ServeHTTP(w, r)
+			return
+		}
+		cache := config.ResponseCache
+		version := PathVars(r)[versionKey]
+		ttl := cache.cacheTTL(handler, version)
+		if _, ok := RequestPrincipal(r); !ok && !sharedCache(handler, version) {
+			ttl = 0
+		}
+		if ttl <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(handler.ResourceName(), route, r)
+		if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+			if data, ok, err := cache.Store.Get(key); err != nil {
+				log.Printf("Response cache lookup failed: %s", err)
+			} else if ok {
+				var cached cachedResponse
+				if err := json.Unmarshal(data, &cached); err == nil {
+					serveCachedResponse(w, r, cached)
+					return
+				}
+			}
+		}
+
+		recorder := &cacheRecorder{ResponseWriter: w}
+		recorder.Header().Set("X-Cache", "MISS")
+		next.ServeHTTP(recorder, r)
+		if recorder.status != http.StatusOK || !cacheableResponse(w.Header()) {
+			return
+		}
+
+		header := http.Header{}
+		for name, values := range w.Header() {
+			if name != requestIDHeader && name != "X-Cache" && name != "Set-Cookie" {
+				header[name] = values
+			}
+		}
+		data, err := json.Marshal(cachedResponse{Header: header, Body: recorder.body.Bytes()})
+		if err == nil {
+			err = cache.Store.Set(key, data, ttl)
+		}
+		if err != nil {
+			log.Printf("Response caching failed: %s", err)
+		}
+	})
+}
+
+// serveCachedResponse writes the cached response, or a 304 if the request's
+// conditional headers match its entity tag or modification time.
+func serveCachedResponse(w http.ResponseWriter, r *http.Request, cached cachedResponse) {
+	for name, values := range cached.Header {
+		w.Header()[name] = values
+	}
+	id := r.Header.Get(requestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	w.Header().Set(requestIDHeader, id)
+	w.Header().Set("X-Cache", "HIT")
+
+	lastModified, _ := parseHTTPDate(cached.Header.Get("Last-Modified"))
+	if evaluatePreconditions(r, cached.Header.Get("ETag"), lastModified) == http.StatusNotModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(cached.Body)
+}
+
+// invalidatingWrite returns a Handler invalidating the cached responses of the
+// resource if the write request handled by the next Handler succeeds.
+func (h requestHandler) invalidatingWrite(handler ResourceHandler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := h.Configuration()
+		if config == nil || config.ResponseCache == nil || config.ResponseCache.Store == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status >= 200 && recorder.status < 300 {
+			if err := config.ResponseCache.Store.Invalidate(cachePrefix(handler.ResourceName())); err != nil {
+				log.Printf("Response cache invalidation failed: %s", err)
+			}
+		}
+	})
+}
+
+// statusRecorder is an http.ResponseWriter recording the response status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status and writes it.
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, recording a 200 status if none was written.
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// cacheRecorder is an http.ResponseWriter recording the response status and body.
+type cacheRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status and writes it.
+func (c *cacheRecorder) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// Write records and writes the body, recording a 200 status if none was written.
+func (c *cacheRecorder) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryCacheStore is a CacheStore holding a bounded number of entries in memory,
+// evicting the least recently used entry when full.
+type memoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	now        func() time.Time
+}
+
+// memoryCacheEntry is an entry of a memoryCacheStore.
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns a CacheStore holding up to maxEntries values in memory,
+// evicting the least recently used when full. If maxEntries isn't positive, the
+// number of entries isn't limited.
+func NewMemoryCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the value stored under the key and true, or false if there is none or
+// it has expired.
+func (m *memoryCacheStore) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !m.now().Before(entry.expires) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores the value under the key for the duration of the TTL.
+func (m *memoryCacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &memoryCacheEntry{key: key, value: value, expires: m.now().Add(ttl)}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.lru.MoveToFront(element)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(entry)
+	if m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+// Invalidate removes the values stored under keys beginning with the prefix.
+func (m *memoryCacheStore) Invalidate(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, element := range m.entries {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(element)
+		}
+	}
+	return nil
+}
+
+// remove removes the entry from the store.
+func (m *memoryCacheStore) remove(element *list.Element) {
+	m.lru.Remove(element)
+	delete(m.entries, element.Value.(*memoryCacheEntry).key)
+}
+
+// RedisClient sends commands to a Redis server. It's implemented by the connections of
+// redigo (github.com/gomodule/redigo) and can wrap other clients, e.g. the Do method of
+// go-redis.
+type RedisClient interface {
+	// Do sends the command and its arguments and returns the reply.
+	Do(command string, args ...interface{}) (interface{}, error)
+}
+
+// redisCacheStore is a CacheStore holding values in Redis.
+type redisCacheStore struct {
+	client RedisClient
+}
+
+// NewRedisCacheStore returns a CacheStore holding values in Redis, which allows cached
+// responses to be shared by every instance of an API. The client must be safe for
+// concurrent use.
+func NewRedisCacheStore(client RedisClient) CacheStore {
+	return &redisCacheStore{client}
+}
+
+// Get returns the value stored under the key and true, or false if there is none.
+func (r *redisCacheStore) Get(key string) ([]byte, bool, error) {
+	reply, err := r.client.Do("GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	switch value := reply.(type) {
+	case []byte:
+		return value, true, nil
+	case string:
+		return []byte(value), true, nil
+	}
+	return nil, false, fmt.Errorf("unexpected Redis reply %T", reply)
+}
+
+// Set stores the value under the key, which Redis expires after the TTL.
+func (r *redisCacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	ms := int64(ttl / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := r.client.Do("SET", key, value, "PX", ms)
+	return err
+}
+
+// Invalidate removes the values stored under keys beginning with the prefix, scanning
+// for them so the server isn't blocked.
+func (r *redisCacheStore) Invalidate(prefix string) error {
+	pattern := redisGlobEscaper.Replace(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := r.client.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100)
+		if err != nil {
+			return err
+		}
+		next, keys, err := parseRedisScan(reply)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			args := make([]interface{}, len(keys))
+			for i, key := range keys {
+				args[i] = key
+			}
+			if _, err := r.client.Do("DEL", args...); err != nil {
+				return err
+			}
+		}
+		if next == "0" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// redisGlobEscaper escapes the special characters of Redis glob patterns.
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// parseRedisScan returns the cursor and keys of a SCAN reply.
+func parseRedisScan(reply interface{}) (string, []string, error) {
+	parts, ok := reply.([]interface{})
+	if !ok || len(parts) != 2 {
+		return "", nil, fmt.Errorf("unexpected Redis SCAN reply %v", reply)
+	}
+	cursor, ok := redisString(parts[0])
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected Redis SCAN cursor %v", parts[0])
+	}
+	items, ok := parts[1].([]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected Redis SCAN keys %v", parts[1])
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if key, ok := redisString(item); ok {
+			keys = append(keys, key)
+		}
+	}
+	return cursor, keys, nil
+}
+
+// redisString returns the Redis bulk string reply as a string.
+func redisString(reply interface{}) (string, bool) {
+	switch value := reply.(type) {
+	case []byte:
+		return string(value), true
+	case string:
+		return value, true
+	}
+	return "", false
+}
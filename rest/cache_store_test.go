@@ -0,0 +1,159 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the memory CacheStore evicts the least recently used entry when full.
+func TestMemoryCacheStoreEviction(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryCacheStore(2)
+
+	assert.Nil(store.Set("a", []byte("1"), time.Minute))
+	assert.Nil(store.Set("b", []byte("2"), time.Minute))
+	_, ok, _ := store.Get("a")
+	assert.True(ok)
+	assert.Nil(store.Set("c", []byte("3"), time.Minute))
+
+	_, ok, _ = store.Get("b")
+	assert.False(ok)
+	value, ok, err := store.Get("a")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal([]byte("1"), value)
+	_, ok, _ = store.Get("c")
+	assert.True(ok)
+}
+
+// Ensures that the memory CacheStore expires entries after their TTL.
+func TestMemoryCacheStoreExpiry(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryCacheStore(0).(*memoryCacheStore)
+	now := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Set("a", []byte("1"), time.Second)
+	_, ok, _ := store.Get("a")
+	assert.True(ok)
+
+	now = now.Add(time.Second)
+	_, ok, _ = store.Get("a")
+	assert.False(ok)
+	assert.Len(store.entries, 0)
+}
+
+// Ensures that the memory CacheStore invalidates the entries with the prefix.
+func TestMemoryCacheStoreInvalidate(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryCacheStore(10)
+	store.Set("foo:1", []byte("1"), time.Minute)
+	store.Set("foo:2", []byte("2"), time.Minute)
+	store.Set("bar:1", []byte("3"), time.Minute)
+
+	assert.Nil(store.Invalidate("foo:"))
+	_, ok, _ := store.Get("foo:1")
+	assert.False(ok)
+	_, ok, _ = store.Get("foo:2")
+	assert.False(ok)
+	_, ok, _ = store.Get("bar:1")
+	assert.True(ok)
+}
+
+// fakeRedisClient is a RedisClient implementing the commands used by the Redis
+// CacheStore in memory.
+type fakeRedisClient struct {
+	values   map[string][]byte
+	commands []string
+	err      error
+}
+
+func (f *fakeRedisClient) Do(command string, args ...interface{}) (interface{}, error) {
+	f.commands = append(f.commands, command)
+	if f.err != nil {
+		return nil, f.err
+	}
+	switch command {
+	case "GET":
+		value, ok := f.values[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return value, nil
+	case "SET":
+		f.values[args[0].(string)] = args[1].([]byte)
+		return "OK", nil
+	case "SCAN":
+		// Return one key per call to exercise the cursor.
+		keys := []string{}
+		for key := range f.values {
+			if ok, _ := path.Match(args[2].(string), key); ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return []interface{}{[]byte("0"), []interface{}{}}, nil
+		}
+		return []interface{}{[]byte("1"), []interface{}{[]byte(keys[0])}}, nil
+	case "DEL":
+		for _, key := range args {
+			delete(f.values, key.(string))
+		}
+		return int64(len(args)), nil
+	}
+	return nil, errors.New("unknown command " + command)
+}
+
+// Ensures that the Redis CacheStore gets, sets and invalidates values.
+func TestRedisCacheStore(t *testing.T) {
+	assert := assert.New(t)
+	client := &fakeRedisClient{values: map[string][]byte{}}
+	store := NewRedisCacheStore(client)
+
+	_, ok, err := store.Get("foo:1")
+	assert.Nil(err)
+	assert.False(ok)
+
+	assert.Nil(store.Set("foo:1", []byte("1"), time.Minute))
+	assert.Nil(store.Set("foo:2", []byte("2"), time.Minute))
+	assert.Nil(store.Set("bar:1", []byte("3"), time.Minute))
+	value, ok, err := store.Get("foo:1")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal([]byte("1"), value)
+
+	assert.Nil(store.Invalidate("foo:"))
+	assert.Equal(map[string][]byte{"bar:1": []byte("3")}, client.values)
+
+	client.err = errors.New("connection refused")
+	_, _, err = store.Get("bar:1")
+	assert.Equal(client.err, err)
+	assert.Equal(client.err, store.Invalidate("bar:"))
+}
+
+// Ensures that the special characters of invalidated prefixes are escaped.
+func TestRedisGlobEscaper(t *testing.T) {
+	assert.Equal(t, `rest:cache:a\*b\?\[c\]:`, redisGlobEscaper.Replace("rest:cache:a*b?[c]:"))
+}
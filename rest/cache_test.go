@@ -0,0 +1,259 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cachingResourceHandler struct {
+	BaseResourceHandler
+	reads    int
+	ttl      time.Duration
+	unshared bool
+}
+
+func (c *cachingResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (c *cachingResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	return &TestResource{Foo: "created"}, nil
+}
+
+func (c *cachingResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	c.reads++
+	switch id {
+	case "private":
+		ctx.ResponseHeader().Set("Cache-Control", "private, max-age=60")
+	case "vary":
+		ctx.ResponseHeader().Set("Vary", "Accept, X-Tenant")
+	}
+	if err := ctx.CheckPreconditions("v1", time.Time{}); err != nil {
+		return nil, err
+	}
+	return &TestResource{Foo: id}, nil
+}
+
+func (c *cachingResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	c.reads++
+	return []Resource{&TestResource{Foo: "a"}}, "", nil
+}
+
+func (c *cachingResourceHandler) Authenticate(r *http.Request) error {
+	if user := r.Header.Get("X-User"); user != "" {
+		SetRequestPrincipal(r, &Principal{ID: user})
+	}
+	return nil
+}
+
+func (c *cachingResourceHandler) CacheTTL(version string) time.Duration {
+	return c.ttl
+}
+
+func (c *cachingResourceHandler) SharedCache(version string) bool {
+	return !c.unshared
+}
+
+// serveCacheRequest serves a request to the API, applying the headers.
+func serveCacheRequest(api API, method, url string, headers map[string]string) *httptest.ResponseRecorder {
+	var body *bytes.Buffer
+	if method == "POST" {
+		body = bytes.NewBufferString(`{"foo": "bar"}`)
+	} else {
+		body = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, url, body)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	return resp
+}
+
+// Ensures that read and list responses are served from the ResponseCache after the
+// first request.
+func TestResponseCacheHit(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	first := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	assert.Equal(http.StatusOK, first.Code)
+	assert.Equal("MISS", first.Header().Get("X-Cache"))
+
+	second := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	assert.Equal(http.StatusOK, second.Code)
+	assert.Equal("HIT", second.Header().Get("X-Cache"))
+	assert.Equal(first.Body.String(), second.Body.String())
+	assert.Equal(first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.NotEqual("", second.Header().Get(requestIDHeader))
+	assert.NotEqual(first.Header().Get(requestIDHeader), second.Header().Get(requestIDHeader))
+	assert.Equal(1, handler.reads)
+
+	// The query is part of the key.
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1?fields=foo", nil)
+	assert.Equal(2, handler.reads)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets", nil)
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets", nil)
+	assert.Equal("HIT", resp.Header().Get("X-Cache"))
+	assert.Equal(3, handler.reads)
+}
+
+// Ensures that responses are cached separately for each Principal.
+func TestResponseCachePrincipal(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", map[string]string{"X-User": "alice"})
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", map[string]string{"X-User": "bob"})
+	assert.Equal("MISS", resp.Header().Get("X-Cache"))
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", map[string]string{"X-User": "alice"})
+	assert.Equal("HIT", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+}
+
+// Ensures that requests with Cache-Control: no-cache bypass cached responses and
+// refresh them.
+func TestResponseCacheNoCache(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"Cache-Control": "no-cache"})
+	assert.Equal("MISS", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+}
+
+// Ensures that a cached response is revalidated against the request's conditional
+// headers.
+func TestResponseCacheNotModified(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"If-None-Match": `"v1"`})
+	assert.Equal(http.StatusNotModified, resp.Code)
+	assert.Equal("HIT", resp.Header().Get("X-Cache"))
+	assert.Equal(`"v1"`, resp.Header().Get("ETag"))
+	assert.Equal("", resp.Body.String())
+	assert.Equal(1, handler.reads)
+}
+
+// Ensures that successful writes invalidate the resource's cached responses.
+func TestResponseCacheInvalidation(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets", nil)
+	resp := serveCacheRequest(api, "POST", "http://foo.com/api/v1/widgets", nil)
+	assert.Equal(http.StatusCreated, resp.Code)
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets", nil)
+	assert.Equal("MISS", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+}
+
+// Ensures that responses aren't cached if the CacheTTL is zero or no ResponseCache is
+// configured.
+func TestResponseCacheDisabled(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10), TTL: time.Minute}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	assert.Equal("", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+
+	handler = &cachingResourceHandler{ttl: time.Minute}
+	api = NewAPI(&Configuration{})
+	api.RegisterResourceHandler(handler)
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	assert.Equal("", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+}
+
+// Ensures that responses to requests without a Principal aren't cached unless the
+// handler shares them, and then only with callers presenting the same credentials.
+func TestResponseCacheWithoutPrincipal(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute, unshared: true}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1", nil)
+	assert.Equal("", resp.Header().Get("X-Cache"))
+	assert.Equal(2, handler.reads)
+
+	handler = &cachingResourceHandler{ttl: time.Minute}
+	api = NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"Authorization": "Bearer alice"})
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"Authorization": "Bearer bob"})
+	assert.Equal("MISS", resp.Header().Get("X-Cache"))
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"Cookie": "session=alice"})
+	assert.Equal("MISS", resp.Header().Get("X-Cache"))
+	resp = serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/1",
+		map[string]string{"Authorization": "Bearer alice"})
+	assert.Equal("HIT", resp.Header().Get("X-Cache"))
+	assert.Equal(3, handler.reads)
+}
+
+// Ensures that responses marked private, or varying by headers the key doesn't cover,
+// aren't cached.
+func TestResponseCacheUncacheableResponses(t *testing.T) {
+	assert := assert.New(t)
+	handler := &cachingResourceHandler{ttl: time.Minute}
+	api := NewAPI(&Configuration{ResponseCache: &ResponseCache{Store: NewMemoryCacheStore(10)}})
+	api.RegisterResourceHandler(handler)
+
+	for _, id := range []string{"private", "vary"} {
+		serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/"+id, nil)
+		resp := serveCacheRequest(api, "GET", "http://foo.com/api/v1/widgets/"+id, nil)
+		assert.Equal("MISS", resp.Header().Get("X-Cache"), id)
+	}
+	assert.Equal(4, handler.reads)
+}
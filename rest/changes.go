@@ -0,0 +1,178 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Change is an entry of the change log of a resource, recording a successful mutation.
+type Change struct {
+	// Seq is the sequence number of the Change in the change log of its resource,
+	// starting at 1 and increasing by one with each Change.
+	Seq uint64 `json:"seq"`
+
+	// Type is the kind of mutation.
+	Type EventType `json:"type"`
+
+	// Resource is the name of the resource mutated.
+	Resource string `json:"resource"`
+
+	// ResourceID is the ID of the resource mutated, if the request included one.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Version is the API version of the request.
+	Version string `json:"version"`
+
+	// Payload is the response of the mutation with the outbound Rules applied.
+	Payload Resource `json:"payload,omitempty"`
+
+	// Time is when the Change was recorded.
+	Time time.Time `json:"time"`
+}
+
+// ChangeStore stores append-only change logs of resources. Implementations must be
+// safe for concurrent use.
+type ChangeStore interface {
+	// Append appends the Change to the change log of its resource, assigning it the
+	// next sequence number, which is returned.
+	Append(change Change) (uint64, error)
+
+	// Changes returns up to limit Changes of the resource with sequence numbers greater
+	// than since, in order.
+	Changes(resource string, since uint64, limit int) ([]Change, error)
+}
+
+// ChangeFeedConfig configures the change logs of resources.
+type ChangeFeedConfig struct {
+	// Store, if set, stores the change logs, e.g. in a database shared by the instances
+	// of a service. By default, they're kept in memory.
+	Store ChangeStore
+}
+
+// memoryChangeStore is a ChangeStore keeping change logs in memory.
+type memoryChangeStore struct {
+	mu   sync.RWMutex
+	logs map[string][]Change
+}
+
+// NewMemoryChangeStore returns a ChangeStore keeping the change logs in memory. The
+// change logs grow without bound and are lost when the process exits.
+func NewMemoryChangeStore() ChangeStore {
+	return &memoryChangeStore{logs: map[string][]Change{}}
+}
+
+// Append appends the Change to the change log of its resource.
+func (m *memoryChangeStore) Append(change Change) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changes := m.logs[change.Resource]
+	change.Seq = uint64(len(changes)) + 1
+	m.logs[change.Resource] = append(changes, change)
+	return change.Seq, nil
+}
+
+// Changes returns up to limit Changes of the resource after since.
+func (m *memoryChangeStore) Changes(resource string, since uint64, limit int) ([]Change, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	changes := m.logs[resource]
+	if since >= uint64(len(changes)) {
+		return []Change{}, nil
+	}
+	changes = changes[since:]
+	if limit > 0 && len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return append([]Change{}, changes...), nil
+}
+
+// recordChange appends the mutation of the resource to its change log, if the change
+// feed is enabled. The mutation has already been made, so failures are logged.
+func (h requestHandler) recordChange(kind EventType, resource, resourceID, version string,
+	payload Resource) {
+
+	if h.changes == nil {
+		return
+	}
+	_, err := h.changes.Append(Change{
+		Type:       kind,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Version:    version,
+		Payload:    payload,
+		Time:       time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to record %s of %s %s: %v", kind, resource, resourceID, err)
+	}
+}
+
+// changesURI returns the URI of the ResourceHandler's change log.
+func changesURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/changes"
+}
+
+// handleChanges returns a Handler responding with the Changes of the resource after
+// the sequence number given by the since query parameter, up to the limit. The next
+// link of the response reads the Changes after those returned, so consumers follow it
+// to stay in sync.
+func (h requestHandler) handleChanges(handler ResourceHandler) http.Handler {
+	resource := handler.ResourceName()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, resource)
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		since := uint64(0)
+		if value := r.URL.Query().Get(sinceKey); value != "" {
+			var err error
+			if since, err = strconv.ParseUint(value, 10, 64); err != nil {
+				h.sendResponse(ctx.setError(BadRequest(fmt.Sprintf("Invalid %s: %s", sinceKey, value))))
+				return
+			}
+		}
+
+		changes, err := h.changes.Changes(resource, since, ctx.Limit())
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+		if len(changes) > 0 {
+			since = changes[len(changes)-1].Seq
+		}
+		next := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+		if r.TLS != nil {
+			next.Scheme = "https"
+		}
+		query := r.URL.Query()
+		query.Set(sinceKey, strconv.FormatUint(since, 10))
+		next.RawQuery = query.Encode()
+		ctx.AddLink("next", next.String())
+
+		ctx = ctx.setResult(changes)
+		ctx = ctx.setStatus(http.StatusOK)
+		h.sendResponse(ctx)
+	})
+}
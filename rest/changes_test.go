@@ -0,0 +1,111 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingChangeStore is a ChangeStore which fails to read change logs.
+type failingChangeStore struct {
+	ChangeStore
+}
+
+func (f failingChangeStore) Changes(resource string, since uint64, limit int) ([]Change, error) {
+	return nil, errors.New("Store unavailable")
+}
+
+// Ensures that mutations are appended to the change logs of their resources, which are
+// read after a sequence number with a next link to the following Changes.
+func TestChangeFeed(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ChangeFeed: &ChangeFeedConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+
+	serveJSON(api, "POST", "/api/v1/widgets", `{"name": "sprocket"}`)
+	serveJSON(api, "PUT", "/api/v1/widgets/42", `{"name": "cog"}`)
+	serveJSON(api, "DELETE", "/api/v1/widgets/42", "")
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/widgets/changes?limit=2", "")
+	assert.Equal(http.StatusOK, code)
+	changes := envelope["results"].([]interface{})
+	if assert.Len(changes, 2) {
+		first := changes[0].(map[string]interface{})
+		assert.Equal(float64(1), first["seq"])
+		assert.Equal("create", first["type"])
+		assert.Equal(map[string]interface{}{"name": "sprocket"}, first["payload"])
+		assert.Equal("42", changes[1].(map[string]interface{})["resource_id"])
+	}
+	assert.Equal("http://example.com/api/v1/widgets/changes?limit=2&since=2",
+		envelope["links"].(map[string]interface{})["next"])
+
+	_, envelope = serveJSON(api, "GET", "/api/v1/widgets/changes?since=2", "")
+	changes = envelope["results"].([]interface{})
+	if assert.Len(changes, 1) {
+		assert.Equal("delete", changes[0].(map[string]interface{})["type"])
+	}
+	_, envelope = serveJSON(api, "GET", "/api/v1/widgets/changes?since=3", "")
+	assert.Len(envelope["results"], 0)
+	assert.Equal("http://example.com/api/v1/widgets/changes?since=3",
+		envelope["links"].(map[string]interface{})["next"])
+
+	code, _ = serveJSON(api, "GET", "/api/v1/widgets/changes?since=latest", "")
+	assert.Equal(http.StatusBadRequest, code)
+}
+
+// Ensures that errors of the ChangeStore are responded with and that the changes route
+// isn't registered unless the change feed is configured.
+func TestChangeFeedErrors(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ChangeFeed: &ChangeFeedConfig{Store: failingChangeStore{}}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	code, _ := serveJSON(api, "GET", "/api/v1/widgets/changes", "")
+	assert.Equal(http.StatusInternalServerError, code)
+
+	api = NewAPI(&Configuration{})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	_, envelope := serveJSON(api, "GET", "/api/v1/widgets/changes", "")
+	assert.Nil(envelope["results"])
+}
+
+// Ensures that the memory ChangeStore keeps a change log for each resource.
+func TestMemoryChangeStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryChangeStore()
+
+	seq, err := store.Append(Change{Resource: "widgets"})
+	assert.Equal(uint64(1), seq)
+	assert.Nil(err)
+	seq, _ = store.Append(Change{Resource: "gadgets"})
+	assert.Equal(uint64(1), seq)
+	seq, _ = store.Append(Change{Resource: "widgets"})
+	assert.Equal(uint64(2), seq)
+
+	changes, err := store.Changes("widgets", 0, 0)
+	assert.Nil(err)
+	assert.Len(changes, 2)
+	changes, _ = store.Changes("widgets", 1, 10)
+	if assert.Len(changes, 1) {
+		assert.Equal(uint64(2), changes[0].Seq)
+	}
+	changes, _ = store.Changes("sprockets", 0, 10)
+	assert.Equal([]Change{}, changes)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials attach credentials to requests. Implementations must be safe for
+// concurrent use.
+type Credentials interface {
+	// Authorize adds credentials to the request.
+	Authorize(req *http.Request) error
+}
+
+// CredentialsFunc is an adapter which allows using a function as Credentials.
+type CredentialsFunc func(*http.Request) error
+
+// Authorize calls the function.
+func (c CredentialsFunc) Authorize(req *http.Request) error {
+	return c(req)
+}
+
+// WithCredentials authorizes every request attempt with the Credentials, so expiring
+// credentials are refreshed and signatures are recomputed on retries.
+func WithCredentials(credentials Credentials) Option {
+	return WithMiddleware(RequestHook(credentials.Authorize))
+}
+
+// APIKey returns Credentials which set the header to the static key, e.g.
+// APIKey("X-API-Key", key).
+func APIKey(header, key string) Credentials {
+	return CredentialsFunc(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
+
+// BearerToken returns Credentials which send the static token in the Authorization
+// header.
+func BearerToken(token string) Credentials {
+	return APIKey("Authorization", "Bearer "+token)
+}
+
+// tokenExpiryMargin is how long before expiry cached tokens are refreshed.
+const tokenExpiryMargin = 30 * time.Second
+
+// ClientCredentials are Credentials which obtain bearer tokens using the OAuth2
+// client credentials grant (RFC 6749, section 4.4). Tokens are cached and
+// refreshed shortly before they expire.
+type ClientCredentials struct {
+	// TokenURL is the URL of the token endpoint.
+	TokenURL string
+
+	// ClientID and ClientSecret authenticate the client with the token endpoint.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes are the scopes requested, if any.
+	Scopes []string
+
+	// HTTPClient performs requests to the token endpoint. Defaults to
+	// http.DefaultClient.
+	HTTPClient Doer
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// tokenResponse is a successful response from an OAuth2 token endpoint.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Authorize adds a bearer token to the request, obtaining a new one if the cached
+// token is missing or about to expire.
+func (c *ClientCredentials) Authorize(req *http.Request) error {
+	token, err := c.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns a valid access token, obtaining a new one if necessary.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expires.IsZero() || time.Now().Add(tokenExpiryMargin).Before(c.expires)) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	doer := c.HTTPClient
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+	resp, err := doer.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, data)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", &DecodeError{Status: resp.StatusCode, Body: data, Err: err}
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	c.token = token.AccessToken
+	c.expires = time.Time{}
+	if token.ExpiresIn > 0 {
+		c.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return c.token, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that APIKey and BearerToken set their headers on each request.
+func TestClientStaticCredentials(t *testing.T) {
+	assert := assert.New(t)
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[]}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithCredentials(APIKey("X-API-Key", "secret")))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal("secret", headers.Get("X-API-Key"))
+
+	c = New(server.URL, WithCredentials(BearerToken("token")))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal("Bearer token", headers.Get("Authorization"))
+}
+
+// Ensures that ClientCredentials obtains a token with the client credentials grant,
+// caches it and refreshes it once it's about to expire.
+func TestClientCredentials(t *testing.T) {
+	assert := assert.New(t)
+	issued := 0
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		id, secret, _ := r.BasicAuth()
+		assert.Equal("id", id)
+		assert.Equal("secret", secret)
+		assert.Nil(r.ParseForm())
+		assert.Equal("client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal("read write", r.PostForm.Get("scope"))
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, issued)
+	}))
+	defer tokens.Close()
+
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[]}`)
+	}))
+	defer server.Close()
+
+	credentials := &ClientCredentials{
+		TokenURL:     tokens.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+	c := New(server.URL, WithCredentials(credentials))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal("Bearer token-1", auth)
+	assert.Equal(1, issued)
+
+	credentials.expires = credentials.expires.Add(-3590 * time.Second)
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal("Bearer token-2", auth)
+	assert.Equal(2, issued)
+}
+
+// Ensures that ClientCredentials returns an error and the request isn't sent when
+// the token endpoint rejects the client.
+func TestClientCredentialsError(t *testing.T) {
+	assert := assert.New(t)
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid_client"}`)
+	}))
+	defer tokens.Close()
+
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithCredentials(&ClientCredentials{TokenURL: tokens.URL}))
+	err := c.Read(context.Background(), "widgets", "1", nil)
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "status 401")
+	}
+	assert.False(called)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// CachedResponse is a response stored in a Cache along with the validators used to
+// make conditional requests for it.
+type CachedResponse struct {
+	// Status is the HTTP status code of the response.
+	Status int
+
+	// Header is the response header.
+	Header http.Header
+
+	// Body is the response body.
+	Body []byte
+
+	// ETag is the entity tag of the response, if any.
+	ETag string
+
+	// LastModified is the Last-Modified header of the response, if any.
+	LastModified string
+}
+
+// response returns a copy of the 304 Not Modified response with the cached status
+// and header, updated with any headers sent along with the 304.
+func (c *CachedResponse) response(notModified *http.Response) *http.Response {
+	resp := *notModified
+	resp.StatusCode = c.Status
+	resp.Status = http.StatusText(c.Status)
+	resp.Header = c.Header.Clone()
+	for key, values := range notModified.Header {
+		resp.Header[key] = values
+	}
+	return &resp
+}
+
+// Cache stores responses for conditional GET requests. Implementations must be safe
+// for concurrent use.
+type Cache interface {
+	// Get returns the response cached for the key, if any.
+	Get(key string) (*CachedResponse, bool)
+
+	// Set caches the response for the key.
+	Set(key string, resp *CachedResponse)
+}
+
+// WithCache sets the Cache used to store responses to GET requests which have an
+// ETag or Last-Modified header. Subsequent requests for the same URL are made
+// conditional, and the cached body is used when the server responds 304 Not
+// Modified. Since responses are cached by URL, a Cache shouldn't be shared by
+// Clients with different credentials.
+func WithCache(cache Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// cachedResponse returns the cached response for the request, if any, adding its
+// validators to the request.
+func (c *Client) cachedResponse(req *http.Request) *CachedResponse {
+	if c.cache == nil || req.Method != http.MethodGet {
+		return nil
+	}
+	cached, ok := c.cache.Get(req.URL.String())
+	if !ok {
+		return nil
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+	return cached
+}
+
+// cacheResponse caches the response to the request if it has validators.
+func (c *Client) cacheResponse(req *http.Request, resp *http.Response, body []byte) {
+	if c.cache == nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	c.cache.Set(req.URL.String(), &CachedResponse{
+		Status:       resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ETag:         etag,
+		LastModified: lastModified,
+	})
+}
+
+// memoryCache is a Cache which holds a bounded number of responses in memory,
+// evicting the least recently used.
+type memoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+// memoryCacheEntry is an element of the memoryCache LRU list.
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryCache returns a Cache which holds up to capacity responses in memory,
+// evicting the least recently used. A capacity of zero or less is unbounded.
+func NewMemoryCache(capacity int) Cache {
+	return &memoryCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Get returns the response cached for the key, if any.
+func (m *memoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(elem)
+	return elem.Value.(*memoryCacheEntry).resp, true
+}
+
+// Set caches the response for the key.
+func (m *memoryCache) Set(key string, resp *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		elem.Value.(*memoryCacheEntry).resp = resp
+		m.lru.MoveToFront(elem)
+		return
+	}
+
+	m.entries[key] = m.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	if m.capacity > 0 && m.lru.Len() > m.capacity {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the client makes conditional requests and serves cached bodies on 304.
+func TestClientCache(t *testing.T) {
+	assert := assert.New(t)
+	calls, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[],"result":{"id":"1","name":"cog"}}`)
+	}))
+	defer server.Close()
+	c := New(server.URL, WithCache(NewMemoryCache(10)))
+
+	for i := 0; i < 3; i++ {
+		var result widget
+		assert.Nil(c.Read(context.Background(), "widgets", "1", &result))
+		assert.Equal(widget{ID: "1", Name: "cog"}, result)
+	}
+	assert.Equal(3, calls)
+	assert.Equal(2, notModified)
+}
+
+// Ensures that responses without validators or to other methods aren't cached.
+func TestClientCacheSkipped(t *testing.T) {
+	assert := assert.New(t)
+	cache := NewMemoryCache(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("", r.Header.Get("If-None-Match"))
+		if r.Method == http.MethodPut {
+			w.Header().Set("ETag", `"v2"`)
+		}
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[],"result":{"id":"1"}}`)
+	}))
+	defer server.Close()
+	c := New(server.URL, WithCache(cache))
+
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Nil(c.Update(context.Background(), "widgets", "1", &widget{}, nil))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+
+	_, ok := cache.Get(server.URL + "/widgets/1")
+	assert.False(ok)
+}
+
+// Ensures that the memory cache evicts the least recently used response.
+func TestMemoryCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := NewMemoryCache(2)
+
+	cache.Set("a", &CachedResponse{ETag: "a"})
+	cache.Set("b", &CachedResponse{ETag: "b"})
+	_, ok := cache.Get("a")
+	assert.True(ok)
+	cache.Set("c", &CachedResponse{ETag: "c"})
+
+	_, ok = cache.Get("b")
+	assert.False(ok)
+	resp, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal("a", resp.ETag)
+
+	cache.Set("a", &CachedResponse{ETag: "a2"})
+	resp, _ = cache.Get("a")
+	assert.Equal("a2", resp.ETag)
+}
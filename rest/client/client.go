@@ -0,0 +1,347 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client provides a typed Go client for APIs built with go-rest. It
+// understands the response envelope, decoding results into Go values and error
+// responses into *Error values.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Doer performs HTTP requests. It's satisfied by *http.Client.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the Doer used to perform requests. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(doer Doer) Option {
+	return func(c *Client) {
+		c.doer = doer
+	}
+}
+
+// WithHeader sets a header which is sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Set(key, value)
+	}
+}
+
+// Client performs CRUD operations against resources of a go-rest API. It's safe for
+// concurrent use.
+type Client struct {
+	baseURL    string
+	doer       Doer
+	header     http.Header
+	retry      *RetryPolicy
+	cache      Cache
+	middleware []Middleware
+}
+
+// New returns a Client for the API at the given base URL, which includes the
+// version, e.g. "https://example.com/api/v1".
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		doer:    http.DefaultClient,
+		header:  http.Header{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	for _, m := range c.middleware {
+		c.doer = m(c.doer)
+	}
+	return c
+}
+
+// ListOptions configure a List request.
+type ListOptions struct {
+	// Limit is the maximum number of results to return. If zero, the server default
+	// is used.
+	Limit int
+
+	// Cursor is the cursor of the page to return, taken from Page.Cursor of the
+	// previous page.
+	Cursor string
+
+	// Query contains additional query parameters, such as filters.
+	Query url.Values
+}
+
+// Page describes a page of results returned by List.
+type Page struct {
+	// Next is the URL of the next page of results, if any.
+	Next string
+
+	// Cursor is the cursor of the next page of results, if any.
+	Cursor string
+
+	// Total is the total number of results, if the server reported it.
+	Total *int
+
+	// Messages are any messages included in the response.
+	Messages []string
+}
+
+// HasNext indicates if there is another page of results.
+func (p *Page) HasNext() bool {
+	return p.Cursor != "" || p.Next != ""
+}
+
+// envelope is the response envelope sent by go-rest APIs.
+type envelope struct {
+	Status   int             `json:"status"`
+	Reason   string          `json:"reason"`
+	Messages []string        `json:"messages"`
+	Result   json.RawMessage `json:"result"`
+	Results  json.RawMessage `json:"results"`
+	Next     string          `json:"next"`
+	Total    *int            `json:"total"`
+	Code     string          `json:"code"`
+	Errors   []FieldError    `json:"errors"`
+
+	// Header is the response header.
+	Header http.Header `json:"-"`
+}
+
+// Create creates a resource from body, decoding the created resource into result if
+// it's not nil.
+func (c *Client) Create(ctx context.Context, resource string, body, result interface{}) error {
+	ctx = withResource(ctx, resource)
+	_, err := c.do(ctx, http.MethodPost, c.resourceURL(resource, ""), body, result)
+	return err
+}
+
+// Read reads the resource with the given ID into result.
+func (c *Client) Read(ctx context.Context, resource, id string, result interface{}) error {
+	ctx = withResource(ctx, resource)
+	_, err := c.do(ctx, http.MethodGet, c.resourceURL(resource, id), nil, result)
+	return err
+}
+
+// Update updates the resource with the given ID from body, decoding the updated
+// resource into result if it's not nil.
+func (c *Client) Update(ctx context.Context, resource, id string, body, result interface{}) error {
+	ctx = withResource(ctx, resource)
+	_, err := c.do(ctx, http.MethodPut, c.resourceURL(resource, id), body, result)
+	return err
+}
+
+// Delete deletes the resource with the given ID, decoding the deleted resource into
+// result if it's not nil.
+func (c *Client) Delete(ctx context.Context, resource, id string, result interface{}) error {
+	ctx = withResource(ctx, resource)
+	_, err := c.do(ctx, http.MethodDelete, c.resourceURL(resource, id), nil, result)
+	return err
+}
+
+// List reads a page of resources into results, which should be a pointer to a slice.
+func (c *Client) List(ctx context.Context, resource string, options *ListOptions,
+	results interface{}) (*Page, error) {
+
+	ctx = withResource(ctx, resource)
+	u := c.resourceURL(resource, "")
+	if query := listQuery(options).Encode(); query != "" {
+		u += "?" + query
+	}
+	return c.listURL(ctx, u, results)
+}
+
+// listURL reads the page of resources at the URL into results. The next page is
+// taken from the envelope or, failing that, a Link header with rel="next".
+func (c *Client) listURL(ctx context.Context, u string, results interface{}) (*Page, error) {
+	env, err := c.do(ctx, http.MethodGet, u, nil, results)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Next: env.Next, Total: env.Total, Messages: env.Messages}
+	if page.Next == "" {
+		page.Next = parseLinks(env.Header.Values("Link"))["next"]
+	}
+	if page.Next != "" {
+		if next, err := url.Parse(page.Next); err == nil {
+			if base, err := url.Parse(u); err == nil {
+				page.Next = base.ResolveReference(next).String()
+			}
+			page.Cursor = next.Query().Get("next")
+		}
+	}
+	return page, nil
+}
+
+// listQuery returns the query parameters for the ListOptions.
+func listQuery(options *ListOptions) url.Values {
+	query := url.Values{}
+	if options == nil {
+		return query
+	}
+	for key, values := range options.Query {
+		query[key] = append([]string(nil), values...)
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Cursor != "" {
+		query.Set("next", options.Cursor)
+	}
+	return query
+}
+
+// resourceURL returns the URL of the resource collection or, if id isn't empty, the
+// resource with that ID.
+func (c *Client) resourceURL(resource, id string) string {
+	u := c.baseURL + "/" + url.PathEscape(resource)
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
+
+// do performs the request, decoding the result or results of the response envelope
+// into out. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, u string, body, out interface{}) (*envelope, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, data, err := c.send(ctx, method, u, payload)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEnvelope(resp, data, out)
+}
+
+// send performs the request, retrying it according to the RetryPolicy, and returns
+// the final response along with its body.
+func (c *Client) send(ctx context.Context, method, u string, payload []byte) (*http.Response, []byte, error) {
+	for attempt := 1; ; attempt++ {
+		resp, data, err := c.attempt(ctx, method, u, payload)
+		delay, retry := c.retryDelay(ctx, method, attempt, resp, err)
+		if !retry {
+			return resp, data, err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// newRequest returns a request with the Client's headers.
+func (c *Client) newRequest(ctx context.Context, method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for key, values := range c.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	return req, nil
+}
+
+// attempt performs a single attempt of the request.
+func (c *Client) attempt(ctx context.Context, method, u string, payload []byte) (*http.Response, []byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := c.newRequest(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key, ok := ctx.Value(idempotencyKeyKey).(string); ok {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+
+	cached := c.cachedResponse(req)
+
+	resp, err := c.doer.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		return cached.response(resp), cached.Body, nil
+	}
+	c.cacheResponse(req, resp, data)
+	return resp, data, nil
+}
+
+// decodeEnvelope decodes the response envelope, unmarshaling its result into out or
+// returning an *Error for error responses.
+func decodeEnvelope(resp *http.Response, data []byte, out interface{}) (*envelope, error) {
+	env := &envelope{Status: resp.StatusCode, Header: resp.Header}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, env); err != nil {
+			if resp.StatusCode >= http.StatusBadRequest {
+				// Not an envelope, e.g. an error from a proxy or middleware.
+				return nil, newError(resp, &envelope{Messages: []string{string(data)}})
+			}
+			return nil, &DecodeError{Status: resp.StatusCode, Body: data, Err: err}
+		}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, newError(resp, env)
+	}
+
+	if out == nil {
+		return env, nil
+	}
+
+	raw := env.Result
+	if len(raw) == 0 {
+		raw = env.Results
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return env, nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return nil, &DecodeError{Status: resp.StatusCode, Body: data, Err: err}
+	}
+	return env, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+type widget struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// widgetHandler is a ResourceHandler backed by an in-memory map of widgets.
+type widgetHandler struct {
+	rest.BaseResourceHandler
+	widgets map[string]*widget
+}
+
+func (w *widgetHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (w *widgetHandler) CreateResource(ctx rest.RequestContext, data rest.Payload,
+	version string) (rest.Resource, error) {
+	name, _ := data["name"].(string)
+	if name == "" {
+		return nil, rest.NewValidationError("Invalid widget",
+			rest.FieldError{Field: "name", Code: "required", Message: "Name is required"})
+	}
+	created := &widget{ID: strconv.Itoa(len(w.widgets) + 1), Name: name}
+	w.widgets[created.ID] = created
+	return created, nil
+}
+
+func (w *widgetHandler) ReadResourceList(ctx rest.RequestContext, limit int,
+	cursor string, version string) ([]rest.Resource, string, error) {
+	ids := make([]string, 0, len(w.widgets))
+	for id := range w.widgets {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	next := ""
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[limit-1]
+	}
+	resources := make([]rest.Resource, 0, len(ids))
+	for _, id := range ids {
+		resources = append(resources, w.widgets[id])
+	}
+	ctx.SetTotal(len(w.widgets))
+	return resources, next, nil
+}
+
+func (w *widgetHandler) ReadResource(ctx rest.RequestContext, id string,
+	version string) (rest.Resource, error) {
+	if found, ok := w.widgets[id]; ok {
+		return found, nil
+	}
+	return nil, rest.NotFound("No widget " + id)
+}
+
+func (w *widgetHandler) UpdateResource(ctx rest.RequestContext, id string,
+	data rest.Payload, version string) (rest.Resource, error) {
+	found, ok := w.widgets[id]
+	if !ok {
+		return nil, rest.NotFound("No widget " + id)
+	}
+	found.Name, _ = data["name"].(string)
+	return found, nil
+}
+
+func (w *widgetHandler) DeleteResource(ctx rest.RequestContext, id string,
+	version string) (rest.Resource, error) {
+	found, ok := w.widgets[id]
+	if !ok {
+		return nil, rest.NotFound("No widget " + id)
+	}
+	delete(w.widgets, id)
+	return found, nil
+}
+
+// newTestServer returns a server for an API exposing the widgets resource.
+func newTestServer() *httptest.Server {
+	api := rest.NewAPI(&rest.Configuration{})
+	api.RegisterResourceHandler(&widgetHandler{widgets: map[string]*widget{}})
+	return httptest.NewServer(api)
+}
+
+// Ensures that the Client performs CRUD operations and decodes the envelope.
+func TestClientCRUD(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	c := New(server.URL+"/api/v1", WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	var created widget
+	assert.Nil(c.Create(ctx, "widgets", &widget{Name: "sprocket"}, &created))
+	assert.Equal(widget{ID: "1", Name: "sprocket"}, created)
+
+	var read widget
+	assert.Nil(c.Read(ctx, "widgets", "1", &read))
+	assert.Equal(created, read)
+
+	var updated widget
+	assert.Nil(c.Update(ctx, "widgets", "1", &widget{Name: "cog"}, &updated))
+	assert.Equal(widget{ID: "1", Name: "cog"}, updated)
+
+	assert.Nil(c.Delete(ctx, "widgets", "1", nil))
+
+	err := c.Read(ctx, "widgets", "1", &read)
+	assert.True(errors.Is(err, rest.ErrNotFound))
+	var restErr *Error
+	assert.True(errors.As(err, &restErr))
+	assert.Equal(http.StatusNotFound, restErr.StatusCode())
+	assert.Equal([]string{"No widget 1"}, restErr.Messages)
+	assert.Equal("404 Not Found: No widget 1", err.Error())
+}
+
+// Ensures that validation errors are returned with their field errors.
+func TestClientValidationError(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	c := New(server.URL+"/api/v1", WithHTTPClient(server.Client()))
+
+	err := c.Create(context.Background(), "widgets", map[string]string{}, nil)
+	var restErr *Error
+	assert.True(errors.As(err, &restErr))
+	assert.Equal(422, restErr.Status)
+	assert.Equal([]FieldError{{Field: "name", Code: "required", Message: "Name is required"}},
+		restErr.Fields)
+}
+
+// Ensures that List decodes results and pagination details.
+func TestClientList(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	c := New(server.URL+"/api/v1", WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Nil(c.Create(ctx, "widgets", &widget{Name: name}, nil))
+	}
+
+	var widgets []widget
+	page, err := c.List(ctx, "widgets", &ListOptions{Limit: 2}, &widgets)
+	assert.Nil(err)
+	assert.Equal([]widget{{"1", "a"}, {"2", "b"}}, widgets)
+	assert.True(page.HasNext())
+	assert.Equal("2", page.Cursor)
+	assert.Equal(3, *page.Total)
+
+	widgets = nil
+	page, err = c.List(ctx, "widgets", &ListOptions{Limit: 2, Cursor: page.Cursor}, &widgets)
+	assert.Nil(err)
+	assert.Equal([]widget{{"3", "c"}}, widgets)
+	assert.False(page.HasNext())
+}
+
+// Ensures that non-envelope error responses are still returned as *Error.
+func TestClientNonEnvelopeError(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("secret", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Invalid token"))
+	}))
+	defer server.Close()
+	c := New(server.URL, WithHeader("Authorization", "secret"))
+
+	err := c.Read(context.Background(), "widgets", "1", nil)
+	assert.True(errors.Is(err, rest.ErrUnauthorized))
+	assert.Equal("401 Unauthorized: Invalid token", err.Error())
+}
+
+// Ensures that malformed success responses result in a DecodeError.
+func TestClientDecodeError(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	err := c.Read(context.Background(), "widgets", "1", nil)
+	var decodeErr *DecodeError
+	assert.True(errors.As(err, &decodeErr))
+	assert.Equal(http.StatusOK, decodeErr.Status)
+	assert.Equal([]byte("not json"), decodeErr.Body)
+}
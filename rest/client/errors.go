@@ -0,0 +1,116 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// errInvalidNDJSON is the decoding error for an NDJSON line which isn't valid JSON.
+var errInvalidNDJSON = errors.New("invalid JSON in NDJSON stream")
+
+// FieldError describes a problem with a single field of a request, as reported by a
+// rest.ValidationError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is returned for error responses. It can be compared against the rest
+// package sentinel errors, e.g. errors.Is(err, rest.ErrNotFound).
+type Error struct {
+	// Status is the HTTP status code.
+	Status int
+
+	// Reason is the status text.
+	Reason string
+
+	// Messages are the messages included in the response.
+	Messages []string
+
+	// Code is the machine-readable error code, if any.
+	Code string
+
+	// Fields are the field errors of a validation error, if any.
+	Fields []FieldError
+
+	// Header is the response header.
+	Header http.Header
+}
+
+// newError returns an Error for the response and its decoded envelope.
+func newError(resp *http.Response, env *envelope) *Error {
+	reason := env.Reason
+	if reason == "" {
+		reason = http.StatusText(resp.StatusCode)
+	}
+	return &Error{
+		Status:   resp.StatusCode,
+		Reason:   reason,
+		Messages: env.Messages,
+		Code:     env.Code,
+		Fields:   env.Errors,
+		Header:   resp.Header,
+	}
+}
+
+// Error returns the status and messages of the error response.
+func (e *Error) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("%d %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Reason, strings.Join(e.Messages, "; "))
+}
+
+// StatusCode returns the HTTP status code.
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// Is reports whether the target is the rest package sentinel error for the status
+// code.
+func (e *Error) Is(target error) bool {
+	return rest.CustomError(e.Reason, e.Status).Is(target)
+}
+
+// DecodeError is returned when a response can't be decoded.
+type DecodeError struct {
+	// Status is the HTTP status code.
+	Status int
+
+	// Body is the raw response body.
+	Body []byte
+
+	// Err is the decoding error.
+	Err error
+}
+
+// Error returns the decoding error along with the status code.
+func (d *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %d response: %s", d.Status, d.Err)
+}
+
+// Unwrap returns the decoding error.
+func (d *DecodeError) Unwrap() error {
+	return d.Err
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// Ensures that Error matches the rest sentinel errors for its status code.
+func TestErrorIs(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &Error{Status: http.StatusConflict, Reason: "Conflict"}
+	assert.True(errors.Is(err, rest.ErrConflict))
+	assert.False(errors.Is(err, rest.ErrNotFound))
+	assert.Equal("409 Conflict", err.Error())
+}
+
+// Ensures that DecodeError unwraps to the decoding error.
+func TestDecodeErrorUnwrap(t *testing.T) {
+	assert := assert.New(t)
+	cause := errors.New("unexpected EOF")
+
+	err := &DecodeError{Status: http.StatusOK, Err: cause}
+	assert.Equal(cause, errors.Unwrap(err))
+	assert.Equal("decoding 200 response: unexpected EOF", err.Error())
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// withResource returns a context recording the resource a request is made against.
+func withResource(ctx context.Context, resource string) context.Context {
+	return context.WithValue(ctx, resourceKey, resource)
+}
+
+// ResourceFromContext returns the name of the resource a request is made against,
+// which is available to Middleware through the request's context.
+func ResourceFromContext(ctx context.Context) string {
+	resource, _ := ctx.Value(resourceKey).(string)
+	return resource
+}
+
+// RequestMetrics describes a completed request attempt.
+type RequestMetrics struct {
+	// Resource is the name of the resource requested.
+	Resource string
+
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Status is the response status code, or zero if no response was received.
+	Status int
+
+	// Duration is how long the attempt took, until the response header was received.
+	Duration time.Duration
+
+	// Err is the error which prevented a response from being received, if any.
+	Err error
+}
+
+// Metrics records request metrics, e.g. into a latency histogram tagged by
+// resource, method and status.
+type Metrics interface {
+	// ObserveRequest records a completed request attempt.
+	ObserveRequest(metrics RequestMetrics)
+}
+
+// MetricsFunc is an adapter which allows using a function as Metrics.
+type MetricsFunc func(RequestMetrics)
+
+// ObserveRequest calls the function.
+func (m MetricsFunc) ObserveRequest(metrics RequestMetrics) {
+	m(metrics)
+}
+
+// WithMetrics records metrics for every request attempt made by the Client.
+func WithMetrics(metrics Metrics) Option {
+	return WithMiddleware(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			observed := RequestMetrics{
+				Resource: ResourceFromContext(req.Context()),
+				Method:   req.Method,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			if resp != nil {
+				observed.Status = resp.StatusCode
+			}
+			metrics.ObserveRequest(observed)
+			return resp, err
+		})
+	})
+}
+
+// Span is a unit of work in a trace. It's satisfied by a thin adapter around an
+// OpenTelemetry span, without this package depending on OpenTelemetry.
+type Span interface {
+	// SetAttribute sets an attribute on the span.
+	SetAttribute(key string, value interface{})
+
+	// RecordError marks the span as failed with the error.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// Tracer starts client spans.
+type Tracer interface {
+	// Start starts a client span, returning a context carrying it. The request is
+	// sent with the returned context, so propagation Middleware added before
+	// WithTracer can inject the span's trace context into the request headers.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// WithTracer starts a client span for every request attempt made by the Client.
+// Spans are named after the method and resource, e.g. "GET widgets", and carry
+// attributes following the OpenTelemetry HTTP client semantic conventions.
+func WithTracer(tracer Tracer) Option {
+	return WithMiddleware(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resource := ResourceFromContext(req.Context())
+			name := req.Method
+			if resource != "" {
+				name += " " + resource
+			}
+			ctx, span := tracer.Start(req.Context(), name)
+			defer span.End()
+
+			span.SetAttribute("http.request.method", req.Method)
+			span.SetAttribute("url.full", req.URL.String())
+			span.SetAttribute("server.address", req.URL.Hostname())
+			if resource != "" {
+				span.SetAttribute("rest.resource", resource)
+			}
+
+			resp, err := next.Do(req.WithContext(ctx))
+			if err != nil {
+				span.RecordError(err)
+				return resp, err
+			}
+			span.SetAttribute("http.response.status_code", resp.StatusCode)
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetAttribute("error.type", http.StatusText(resp.StatusCode))
+			}
+			return resp, err
+		})
+	})
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSpanKey is the context key of spans started by testTracer.
+type testSpanKey struct{}
+
+// testSpan is a Span recording its attributes.
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.err = err }
+func (s *testSpan) End()                                       { s.ended = true }
+
+// testTracer is a Tracer recording the spans it starts.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &testSpan{name: name, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+// Ensures that WithMetrics records an observation per request attempt, tagged by
+// resource, method and status.
+func TestClientMetrics(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"status":404,"reason":"Not Found","messages":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[],"results":[]}`)
+	}))
+	defer server.Close()
+
+	var observed []RequestMetrics
+	c := New(server.URL, WithMetrics(MetricsFunc(func(m RequestMetrics) {
+		observed = append(observed, m)
+	})))
+	_, err := c.List(context.Background(), "widgets", nil, &[]widget{})
+	assert.Nil(err)
+	assert.NotNil(c.Delete(context.Background(), "gadgets", "1", nil))
+
+	if assert.Len(observed, 2) {
+		assert.Equal("widgets", observed[0].Resource)
+		assert.Equal(http.MethodGet, observed[0].Method)
+		assert.Equal(http.StatusOK, observed[0].Status)
+		assert.True(observed[0].Duration > 0)
+		assert.Equal("gadgets", observed[1].Resource)
+		assert.Equal(http.MethodDelete, observed[1].Method)
+		assert.Equal(http.StatusNotFound, observed[1].Status)
+	}
+}
+
+// Ensures that WithMetrics records transport errors without a status.
+func TestClientMetricsError(t *testing.T) {
+	assert := assert.New(t)
+	var observed RequestMetrics
+	c := New("http://127.0.0.1:0", WithMetrics(MetricsFunc(func(m RequestMetrics) {
+		observed = m
+	})))
+	assert.NotNil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal(0, observed.Status)
+	assert.NotNil(observed.Err)
+}
+
+// Ensures that WithTracer starts a span per request attempt with HTTP client
+// attributes, and that the span's context reaches inner Middleware.
+func TestClientTracer(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("GET widgets", r.Header.Get("X-Span"))
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[]}`)
+	}))
+	defer server.Close()
+
+	propagate := RequestHook(func(req *http.Request) error {
+		if span, ok := req.Context().Value(testSpanKey{}).(*testSpan); ok {
+			req.Header.Set("X-Span", span.name)
+		}
+		return nil
+	})
+	tracer := &testTracer{}
+	c := New(server.URL, WithMiddleware(propagate), WithTracer(tracer))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+
+	if assert.Len(tracer.spans, 1) {
+		span := tracer.spans[0]
+		assert.Equal("GET widgets", span.name)
+		assert.True(span.ended)
+		assert.Nil(span.err)
+		assert.Equal(http.MethodGet, span.attributes["http.request.method"])
+		assert.Equal(server.URL+"/widgets/1", span.attributes["url.full"])
+		assert.Equal("widgets", span.attributes["rest.resource"])
+		assert.Equal(http.StatusOK, span.attributes["http.response.status_code"])
+	}
+}
+
+// Ensures that ResourceFromContext returns the resource of iterated pages.
+func TestResourceFromContextIterate(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	var resources []string
+	c := New(server.URL+"/api/v1", WithMiddleware(RequestHook(func(req *http.Request) error {
+		resources = append(resources, ResourceFromContext(req.Context()))
+		return nil
+	})))
+	for i := 0; i < 3; i++ {
+		assert.Nil(c.Create(context.Background(), "widgets", &widget{Name: "w"}, nil))
+	}
+	resources = nil
+
+	it := c.Iterate("widgets", &ListOptions{Limit: 2})
+	var page []widget
+	for it.Next(context.Background(), &page) {
+	}
+	assert.Nil(it.Err())
+	assert.True(len(resources) >= 2)
+	for _, resource := range resources {
+		assert.Equal("widgets", resource)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"strings"
+)
+
+// Iterator iterates over the pages of a collection, following next cursors and Link
+// headers. An Iterator isn't safe for concurrent use.
+//
+//	it := c.Iterate("widgets", nil)
+//	var widgets []Widget
+//	for it.Next(ctx, &widgets) {
+//		// Handle the page of widgets.
+//	}
+//	if err := it.Err(); err != nil {
+//		// Handle the error.
+//	}
+type Iterator struct {
+	c        *Client
+	resource string
+	options  *ListOptions
+	next     string
+	started  bool
+	page     *Page
+	err      error
+}
+
+// Iterate returns an Iterator over the pages of the resource collection, starting
+// with the page described by the ListOptions.
+func (c *Client) Iterate(resource string, options *ListOptions) *Iterator {
+	return &Iterator{c: c, resource: resource, options: options}
+}
+
+// Next reads the next page into results, which should be a pointer to a slice. It
+// returns false once there are no more pages or an error occurs, which is available
+// from Err.
+func (it *Iterator) Next(ctx context.Context, results interface{}) bool {
+	if it.err != nil || (it.started && it.next == "") {
+		return false
+	}
+
+	var page *Page
+	if it.started {
+		page, it.err = it.c.listURL(withResource(ctx, it.resource), it.next, results)
+	} else {
+		page, it.err = it.c.List(ctx, it.resource, it.options, results)
+	}
+	it.started = true
+	if it.err != nil {
+		return false
+	}
+
+	it.page = page
+	it.next = page.Next
+	return true
+}
+
+// Page returns the most recently read page.
+func (it *Iterator) Page() *Page {
+	return it.page
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// parseLinks parses Link header values (RFC 8288), returning the target URL of each
+// link keyed by its relation type.
+func parseLinks(values []string) map[string]string {
+	links := map[string]string{}
+	for _, value := range values {
+		for {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+
+			// Link params run until the next link.
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params = value[:next]
+			}
+			for _, param := range strings.Split(params, ";") {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimRight(kv[1], ", "), `"`)) {
+					if _, ok := links[strings.ToLower(rel)]; !ok {
+						links[strings.ToLower(rel)] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that Iterator follows next cursors across all pages.
+func TestIteratorCursor(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	c := New(server.URL+"/api/v1", WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		assert.Nil(c.Create(ctx, "widgets", &widget{Name: name}, nil))
+	}
+
+	var names []string
+	pages := 0
+	it := c.Iterate("widgets", &ListOptions{Limit: 2})
+	var widgets []widget
+	for it.Next(ctx, &widgets) {
+		pages++
+		for _, w := range widgets {
+			names = append(names, w.Name)
+		}
+		widgets = nil
+	}
+
+	assert.Nil(it.Err())
+	assert.Equal(3, pages)
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, names)
+	assert.Equal(5, *it.Page().Total)
+}
+
+// Ensures that Iterator follows Link headers and stops on errors.
+func TestIteratorLinkHeader(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</widgets?page=2>; rel="next", </widgets?page=3>; rel="last"`)
+			fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[],"results":[{"id":"1"}]}`)
+		case "2":
+			w.Header().Set("Link", `</widgets?page=3>; rel="next"`)
+			fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[],"results":[{"id":"2"}]}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"status":500,"reason":"Internal Server Error","messages":["boom"]}`)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	var ids []string
+	it := c.Iterate("widgets", nil)
+	var widgets []widget
+	for it.Next(context.Background(), &widgets) {
+		for _, w := range widgets {
+			ids = append(ids, w.ID)
+		}
+		widgets = nil
+	}
+
+	assert.Equal([]string{"1", "2"}, ids)
+	assert.Equal(http.StatusInternalServerError, it.Err().(*Error).Status)
+	assert.False(it.Next(context.Background(), &widgets))
+}
+
+// Ensures that parseLinks extracts link targets by relation type.
+func TestParseLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	links := parseLinks([]string{
+		`<https://example.com/a?x=1,2>; rel="next prefetch"; title="Next", <https://example.com/z>; rel=last`,
+		`<https://example.com/b>; rel="next"`,
+	})
+	assert.Equal(map[string]string{
+		"next":     "https://example.com/a?x=1,2",
+		"prefetch": "https://example.com/a?x=1,2",
+		"last":     "https://example.com/z",
+	}, links)
+	assert.Empty(parseLinks([]string{"garbage"}))
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWTSigner are Credentials which send a self-signed JSON Web Token (RFC 7519) as
+// a bearer token. Tokens are signed with HS256 when Key is a []byte or RS256 when
+// it's an *rsa.PrivateKey, and are reused until shortly before they expire.
+type JWTSigner struct {
+	// Key is the signing key.
+	Key interface{}
+
+	// KeyID, if set, is included in the token header as "kid".
+	KeyID string
+
+	// Issuer, Subject and Audience set the "iss", "sub" and "aud" claims.
+	Issuer   string
+	Subject  string
+	Audience string
+
+	// TTL is the lifetime of each token. Defaults to 5 minutes.
+	TTL time.Duration
+
+	// Claims are additional claims included in each token.
+	Claims map[string]interface{}
+
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Authorize adds a signed token to the request.
+func (j *JWTSigner) Authorize(req *http.Request) error {
+	token, err := j.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns a signed token, signing a new one if the previous one is about to
+// expire.
+func (j *JWTSigner) Token() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now
+	if j.now != nil {
+		now = j.now
+	}
+	issued := now()
+	if j.token != "" && issued.Add(tokenExpiryMargin).Before(j.expires) {
+		return j.token, nil
+	}
+
+	ttl := j.TTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	claims := map[string]interface{}{}
+	for key, value := range j.Claims {
+		claims[key] = value
+	}
+	for key, value := range map[string]string{"iss": j.Issuer, "sub": j.Subject, "aud": j.Audience} {
+		if value != "" {
+			claims[key] = value
+		}
+	}
+	expires := issued.Add(ttl)
+	claims["iat"] = issued.Unix()
+	claims["exp"] = expires.Unix()
+
+	token, err := signJWT(j.Key, j.KeyID, claims)
+	if err != nil {
+		return "", err
+	}
+	j.token, j.expires = token, expires
+	return token, nil
+}
+
+// signJWT returns the compact serialization of a JWT with the claims, signed with
+// the key.
+func signJWT(key interface{}, keyID string, claims map[string]interface{}) (string, error) {
+	header := map[string]string{"typ": "JWT"}
+	switch key.(type) {
+	case []byte:
+		header["alg"] = "HS256"
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	default:
+		return "", fmt.Errorf("unsupported JWT signing key type %T", key)
+	}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	encodedHeader, err := encodeJWTSegment(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeJWTSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encodeJWTSegment returns the base64url encoding of the JSON value.
+func encodeJWTSegment(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that JWTSigner signs HS256 tokens with the configured claims and reuses
+// them until they're about to expire.
+func TestJWTSignerHS256(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1500000000, 0)
+	signer := &JWTSigner{
+		Key:      []byte("secret"),
+		KeyID:    "key-1",
+		Issuer:   "issuer",
+		Audience: "api",
+		TTL:      time.Minute,
+		Claims:   map[string]interface{}{"scope": "read"},
+		now:      func() time.Time { return now },
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.Nil(signer.Authorize(req))
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if !assert.Len(parts, 3) {
+		return
+	}
+
+	var header map[string]string
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.Nil(json.Unmarshal(data, &header))
+	assert.Equal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": "key-1"}, header)
+
+	var claims map[string]interface{}
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	assert.Nil(json.Unmarshal(data, &claims))
+	assert.Equal(map[string]interface{}{
+		"iss": "issuer", "aud": "api", "scope": "read",
+		"iat": float64(1500000000), "exp": float64(1500000060),
+	}, claims)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	assert.Equal(base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2])
+
+	now = now.Add(20 * time.Second)
+	reused, err := signer.Token()
+	assert.Nil(err)
+	assert.Equal(token, reused)
+
+	now = now.Add(20 * time.Second)
+	renewed, err := signer.Token()
+	assert.Nil(err)
+	assert.NotEqual(token, renewed)
+}
+
+// Ensures that JWTSigner signs RS256 tokens verifiable with the public key.
+func TestJWTSignerRS256(t *testing.T) {
+	assert := assert.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(err) {
+		return
+	}
+	token, err := (&JWTSigner{Key: key, Subject: "client"}).Token()
+	assert.Nil(err)
+
+	parts := strings.Split(token, ".")
+	if !assert.Len(parts, 3) {
+		return
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.Nil(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+// Ensures that JWTSigner returns an error for unsupported key types.
+func TestJWTSignerUnsupportedKey(t *testing.T) {
+	_, err := (&JWTSigner{Key: "secret"}).Token()
+	assert.NotNil(t, err)
+}
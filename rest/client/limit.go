@@ -0,0 +1,229 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for requests rejected by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RateLimiter is a token bucket limiting the rate of outbound requests. Requests
+// wait for a token rather than failing, up to the deadline of their context.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests per second on average
+// and bursts of up to burst requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// Wait blocks until a token is available or the context is done, returning the
+// context's error in the latter case.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	delay := r.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.now().Add(delay)) {
+		r.cancel()
+		return context.DeadlineExceeded
+	}
+	if err := sleep(ctx, delay); err != nil {
+		r.cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token, possibly leaving the bucket in debt, and returns how long
+// to wait before it becomes available.
+func (r *RateLimiter) reserve() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	r.tokens--
+	if r.tokens >= 0 || r.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
+
+// cancel returns a token reserved by a request which gave up waiting for it.
+func (r *RateLimiter) cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens++
+}
+
+// WithRateLimit limits the rate of requests made by the Client, including retries.
+// A RateLimiter may be shared by several Clients to limit their combined rate.
+func WithRateLimit(limiter *RateLimiter) Option {
+	return WithMiddleware(RequestHook(func(req *http.Request) error {
+		return limiter.Wait(req.Context())
+	}))
+}
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed allows requests.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects requests with ErrCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen allows a single trial request, whose outcome closes or reopens
+	// the circuit.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops sending requests to an API which is failing. After a number
+// of consecutive failures, network errors or 429, 500, 502, 503 and 504 responses,
+// the circuit opens and requests fail fast with ErrCircuitOpen. Once the cooldown
+// elapses, a trial request is let through to probe whether the API has recovered.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	opened   time.Time
+	now      func() time.Time
+}
+
+// NewCircuitBreaker returns a CircuitBreaker which opens after threshold consecutive
+// failures and stays open for the cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// State returns the current state of the CircuitBreaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.now().Sub(b.opened) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may be sent, moving an open circuit whose cooldown
+// has elapsed to half-open.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.opened) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		// A trial request is already in flight.
+		return false
+	}
+	return true
+}
+
+// record updates the CircuitBreaker with the outcome of a request.
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.opened = b.now()
+	}
+}
+
+// release abandons a trial request without an outcome, leaving the circuit open so
+// the next request becomes the trial.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.state = CircuitOpen
+	}
+}
+
+// WithCircuitBreaker guards the requests of the Client with the CircuitBreaker.
+// Requests rejected by an open circuit aren't retried.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return WithMiddleware(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if !breaker.allow() {
+				return nil, ErrCircuitOpen
+			}
+			resp, err := next.Do(req)
+			if err != nil && req.Context().Err() != nil {
+				// The caller gave up, which says nothing about the API's health.
+				breaker.release()
+				return resp, err
+			}
+			breaker.record(err != nil || retryableStatuses[resp.StatusCode])
+			return resp, err
+		})
+	})
+}
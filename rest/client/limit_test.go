@@ -0,0 +1,128 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that RateLimiter allows bursts and then spaces requests at its rate.
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(10, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.Equal(time.Duration(0), limiter.reserve())
+	assert.Equal(time.Duration(0), limiter.reserve())
+	assert.Equal(100*time.Millisecond, limiter.reserve())
+	assert.Equal(200*time.Millisecond, limiter.reserve())
+
+	now = now.Add(time.Second)
+	assert.Equal(time.Duration(0), limiter.reserve())
+}
+
+// Ensures that RateLimiter.Wait fails fast when the token won't be available before
+// the context's deadline.
+func TestRateLimiterDeadline(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewRateLimiter(0.001, 1)
+	assert.Nil(limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, limiter.Wait(ctx))
+}
+
+// Ensures that WithRateLimit delays requests beyond the burst.
+func TestClientRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[]}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRateLimit(NewRateLimiter(20, 1)))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	}
+	assert.True(time.Since(start) >= 90*time.Millisecond)
+}
+
+// Ensures that CircuitBreaker opens after consecutive failures, probes with a single
+// trial request after the cooldown and closes once it succeeds.
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(0, 0)
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	assert.True(breaker.allow())
+	breaker.record(true)
+	assert.Equal(CircuitClosed, breaker.State())
+	assert.True(breaker.allow())
+	breaker.record(true)
+	assert.Equal(CircuitOpen, breaker.State())
+	assert.False(breaker.allow())
+
+	now = now.Add(time.Minute)
+	assert.Equal(CircuitHalfOpen, breaker.State())
+	assert.True(breaker.allow())
+	assert.False(breaker.allow())
+	breaker.record(true)
+	assert.Equal(CircuitOpen, breaker.State())
+
+	now = now.Add(time.Minute)
+	assert.True(breaker.allow())
+	breaker.release()
+	assert.True(breaker.allow())
+	breaker.record(false)
+	assert.Equal(CircuitClosed, breaker.State())
+	assert.Equal("closed", CircuitClosed.String())
+	assert.Equal("half-open", CircuitHalfOpen.String())
+}
+
+// Ensures that WithCircuitBreaker fails requests fast with ErrCircuitOpen once the
+// API keeps failing, without retrying them.
+func TestClientCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"status":503,"reason":"Service Unavailable","messages":[]}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL,
+		WithCircuitBreaker(NewCircuitBreaker(3, time.Minute)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	err := c.Read(context.Background(), "widgets", "1", nil)
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(3, calls)
+
+	assert.Equal(ErrCircuitOpen, c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal(3, calls)
+}
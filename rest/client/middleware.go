@@ -0,0 +1,67 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "net/http"
+
+// DoerFunc is an adapter which allows using a function as a Doer.
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+// Do calls the function.
+func (d DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return d(req)
+}
+
+// Middleware returns a Doer wrapping the provided Doer, allowing requests and
+// responses to be inspected or modified, e.g. to sign requests, propagate tracing
+// headers, log or record metrics. Middleware is invoked for every attempt of a
+// request, so signatures are computed afresh on retries.
+type Middleware func(Doer) Doer
+
+// WithMiddleware adds Middleware to the Client. Like server RequestMiddleware, each
+// Middleware wraps the previous, so the last one added is invoked first.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// RequestHook returns Middleware which invokes the hook before each request is
+// sent. If the hook returns an error, the request isn't sent and the error is
+// returned.
+func RequestHook(hook func(*http.Request) error) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// ResponseHook returns Middleware which invokes the hook after each request
+// completes, with the response or the error which occurred.
+func ResponseHook(hook func(*http.Request, *http.Response, error)) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			hook(req, resp, err)
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that Middleware wraps requests in order, with the last added invoked
+// first.
+func TestClientMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(r.Method + " " + r.URL.Path))
+		assert.Equal(hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signature"))
+		assert.Equal("trace-1", r.Header.Get("Traceparent"))
+		fmt.Fprint(w, `{"status":200,"reason":"OK","messages":[]}`)
+	}))
+	defer server.Close()
+
+	sign := RequestHook(func(req *http.Request) error {
+		order = append(order, "sign")
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(req.Method + " " + req.URL.Path))
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	})
+	trace := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "trace")
+			req.Header.Set("Traceparent", "trace-1")
+			return next.Do(req)
+		})
+	}
+	var status int
+	record := ResponseHook(func(req *http.Request, resp *http.Response, err error) {
+		order = append(order, "record")
+		status = resp.StatusCode
+	})
+
+	c := New(server.URL, WithMiddleware(sign, trace), WithMiddleware(record))
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	assert.Equal([]string{"trace", "sign", "record"}, order)
+	assert.Equal(http.StatusOK, status)
+}
+
+// Ensures that a RequestHook error prevents the request from being sent.
+func TestClientRequestHookError(t *testing.T) {
+	assert := assert.New(t)
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	hookErr := errors.New("no credentials")
+
+	c := New(server.URL, WithMiddleware(RequestHook(func(*http.Request) error { return hookErr })))
+	err := c.Read(context.Background(), "widgets", "1", nil)
+	assert.Equal(hookErr, err)
+	assert.False(called)
+}
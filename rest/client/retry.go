@@ -0,0 +1,171 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// idempotencyKeyHeader is the header carrying the idempotency key of a request.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// contextKey is the type of keys for values stored in a context.Context by this
+// package.
+type contextKey int
+
+const (
+	idempotencyKeyKey contextKey = iota
+	resourceKey
+)
+
+// ContextWithIdempotencyKey returns a context which causes requests made with it to
+// carry the idempotency key. Requests with an idempotency key are retried regardless
+// of their method, since the server can deduplicate them.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey, key)
+}
+
+// RetryPolicy configures how failed requests are retried. Requests are retried on
+// network errors and 429, 500, 502, 503 and 504 responses, but only if they're safe
+// or idempotent (GET, HEAD, OPTIONS, PUT and DELETE) or carry an idempotency key.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first. Values
+	// below 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the base delay between attempts, which doubles with each attempt.
+	// Defaults to 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, including any delay requested by a
+	// Retry-After header. Defaults to 30s.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a RetryPolicy making up to three attempts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// WithRetryPolicy sets the RetryPolicy of the Client. By default, requests aren't
+// retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = &policy
+	}
+}
+
+// retryableStatuses are the response status codes which are retried.
+var retryableStatuses = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// idempotentMethods are the methods which are safe to retry.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// retryDelay returns how long to wait before retrying the request, or false if it
+// shouldn't be retried.
+func (c *Client) retryDelay(ctx context.Context, method string, attempt int,
+	resp *http.Response, err error) (time.Duration, bool) {
+
+	policy := c.retry
+	if policy == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+		return 0, false
+	}
+	if _, ok := ctx.Value(idempotencyKeyKey).(string); !ok && !idempotentMethods[method] {
+		return 0, false
+	}
+	if err == nil && !retryableStatuses[resp.StatusCode] {
+		return 0, false
+	}
+
+	minBackoff, maxBackoff := policy.MinBackoff, policy.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultRetryPolicy.MinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if after > maxBackoff {
+				after = maxBackoff
+			}
+			return after, true
+		}
+	}
+
+	// Exponential backoff with full jitter.
+	backoff := minBackoff << uint(attempt-1)
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1), true
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		after := date.Sub(now)
+		if after < 0 {
+			after = 0
+		}
+		return after, true
+	}
+	return 0, false
+}
+
+// sleep waits for the duration or until the context is done, returning the
+// context's error in the latter case.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
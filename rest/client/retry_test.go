@@ -0,0 +1,172 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetries = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  5 * time.Millisecond,
+}
+
+// flakyServer returns a server which responds with the status until it has been
+// called failures times, after which it responds with an empty result.
+func flakyServer(status, failures int, calls *int32, header http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		if int(atomic.AddInt32(calls, 1)) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"status":503,"reason":"Service Unavailable","messages":["busy"]}`))
+			return
+		}
+		w.Write([]byte(`{"status":200,"reason":"OK","messages":[],"result":{"id":"1"}}`))
+	}))
+}
+
+// Ensures that idempotent requests are retried on retryable responses.
+func TestClientRetry(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusServiceUnavailable, 2, &calls, nil)
+	defer server.Close()
+	c := New(server.URL, WithRetryPolicy(fastRetries))
+
+	var result widget
+	assert.Nil(c.Read(context.Background(), "widgets", "1", &result))
+	assert.Equal("1", result.ID)
+	assert.Equal(int32(3), calls)
+}
+
+// Ensures that requests give up after MaxAttempts and return the last error.
+func TestClientRetryExhausted(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusBadGateway, 5, &calls, nil)
+	defer server.Close()
+	c := New(server.URL, WithRetryPolicy(fastRetries))
+
+	err := c.Update(context.Background(), "widgets", "1", &widget{}, nil)
+	assert.Equal(http.StatusBadGateway, err.(*Error).Status)
+	assert.Equal(int32(3), calls)
+}
+
+// Ensures that non-idempotent requests are only retried with an idempotency key.
+func TestClientRetryIdempotency(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusServiceUnavailable, 1, &calls, nil)
+	defer server.Close()
+	c := New(server.URL, WithRetryPolicy(fastRetries))
+
+	assert.NotNil(c.Create(context.Background(), "widgets", &widget{}, nil))
+	assert.Equal(int32(1), calls)
+
+	atomic.StoreInt32(&calls, 0)
+	ctx := ContextWithIdempotencyKey(context.Background(), "abc")
+	assert.Nil(c.Create(ctx, "widgets", &widget{}, nil))
+	assert.Equal(int32(2), calls)
+}
+
+// Ensures that non-retryable responses and clients without a RetryPolicy aren't
+// retried.
+func TestClientNoRetry(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusNotFound, 1, &calls, nil)
+	defer server.Close()
+
+	assert.NotNil(New(server.URL, WithRetryPolicy(fastRetries)).Read(
+		context.Background(), "widgets", "1", nil))
+	assert.Equal(int32(1), calls)
+
+	server = flakyServer(http.StatusServiceUnavailable, 1, &calls, nil)
+	defer server.Close()
+	atomic.StoreInt32(&calls, 0)
+	assert.NotNil(New(server.URL).Read(context.Background(), "widgets", "1", nil))
+	assert.Equal(int32(1), calls)
+}
+
+// Ensures that Retry-After is honored, capped to MaxBackoff.
+func TestClientRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusTooManyRequests, 1, &calls, http.Header{"Retry-After": {"1"}})
+	defer server.Close()
+	c := New(server.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+	}))
+
+	start := time.Now()
+	assert.Nil(c.Read(context.Background(), "widgets", "1", nil))
+	elapsed := time.Since(start)
+	assert.True(elapsed >= 20*time.Millisecond, elapsed.String())
+	assert.True(elapsed < time.Second, elapsed.String())
+}
+
+// Ensures that waiting between retries stops when the context is canceled.
+func TestClientRetryCanceled(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	server := flakyServer(http.StatusServiceUnavailable, 5, &calls, http.Header{"Retry-After": {"10"}})
+	defer server.Close()
+	c := New(server.URL, WithRetryPolicy(DefaultRetryPolicy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Read(ctx, "widgets", "1", nil)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(int32(1), calls)
+}
+
+// Ensures that parseRetryAfter handles seconds and HTTP dates.
+func TestParseRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	after, ok := parseRetryAfter("120", now)
+	assert.True(ok)
+	assert.Equal(2*time.Minute, after)
+
+	after, ok = parseRetryAfter("Wed, 21 Oct 2015 07:29:00 GMT", now)
+	assert.True(ok)
+	assert.Equal(time.Minute, after)
+
+	after, ok = parseRetryAfter("Wed, 21 Oct 2015 07:27:00 GMT", now)
+	assert.True(ok)
+	assert.Equal(time.Duration(0), after)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(ok)
+	_, ok = parseRetryAfter("-1", now)
+	assert.False(ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(ok)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4DateFormat = "20060102T150405Z"
+)
+
+// SigV4Signer are Credentials which sign requests using AWS Signature Version 4,
+// for APIs behind gateways which verify SigV4 signatures.
+type SigV4Signer struct {
+	// AccessKeyID and SecretAccessKey are the signing credentials.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken, if set, is sent in the X-Amz-Security-Token header.
+	SessionToken string
+
+	// Region and Service scope the signing key, e.g. "us-east-1" and "execute-api".
+	Region  string
+	Service string
+
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+}
+
+// Authorize signs the request, setting the X-Amz-Date and Authorization headers.
+func (s *SigV4Signer) Authorize(req *http.Request) error {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	date := t.Format(sigV4DateFormat)
+
+	payloadHash, err := hashRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Amz-Date", date)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalSigV4Headers(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalSigV4Path(req.URL),
+		canonicalSigV4Query(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{t.Format("20060102"), s.Region, s.Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm, date, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// hashRequestBody returns the hex-encoded SHA-256 hash of the request body, leaving
+// the body readable.
+func hashRequestBody(req *http.Request) (string, error) {
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		if body, err = ioutil.ReadAll(reader); err != nil {
+			return "", err
+		}
+	} else if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// canonicalSigV4Headers returns the signed header names and canonical headers of the
+// request. The host, content type and X-Amz-* headers are signed.
+func canonicalSigV4Headers(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.Host}
+	if headers["host"] == "" {
+		headers["host"] = req.URL.Host
+	}
+	for key, values := range req.Header {
+		name := strings.ToLower(key)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, value := range values {
+				trimmed[i] = strings.Join(strings.Fields(value), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalSigV4Path returns the URI-encoded path of the URL.
+func canonicalSigV4Path(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalSigV4Query returns the query of the URL with parameters sorted and
+// encoded as required by SigV4.
+func canonicalSigV4Query(u *url.URL) string {
+	query := u.Query()
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes the string, leaving only unreserved characters.
+func sigV4Escape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
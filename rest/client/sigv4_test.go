@@ -0,0 +1,82 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSigV4Signer returns a SigV4Signer using the credentials of the AWS
+// Signature Version 4 test suite.
+func newTestSigV4Signer() *SigV4Signer {
+	return &SigV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+}
+
+// Ensures that SigV4Signer produces the signature of the "get-vanilla" test case
+// from the AWS Signature Version 4 test suite.
+func TestSigV4SignerGetVanilla(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	assert.Nil(newTestSigV4Signer().Authorize(req))
+	assert.Equal("20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+// Ensures that SigV4Signer signs the request body and leaves it readable.
+func TestSigV4SignerBody(t *testing.T) {
+	assert := assert.New(t)
+	signer := newTestSigV4Signer()
+	signer.SessionToken = "session"
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.amazonaws.com/a b?z=1&a=x y",
+		ioutil.NopCloser(strings.NewReader(`{"name":"widget"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	assert.Nil(signer.Authorize(req))
+	assert.Equal("session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(req.Header.Get("Authorization"),
+		"SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, ")
+
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(`{"name":"widget"}`, string(body))
+
+	other, _ := http.NewRequest(http.MethodPost, "https://example.amazonaws.com/a b?z=1&a=x y",
+		strings.NewReader(`{"name":"gadget"}`))
+	other.Header.Set("Content-Type", "application/json")
+	assert.Nil(signer.Authorize(other))
+	assert.NotEqual(req.Header.Get("Authorization"), other.Header.Get("Authorization"))
+}
+
+// Ensures that canonicalSigV4Query sorts parameters and percent-encodes spaces.
+func TestCanonicalSigV4Query(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/?b=2&a=x+y&a=1", nil)
+	assert.Equal(t, "a=1&a=x%20y&b=2", canonicalSigV4Query(req.URL))
+}
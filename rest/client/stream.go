@@ -0,0 +1,100 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+// ndjsonContentType is the MIME type of newline-delimited JSON.
+const ndjsonContentType = "application/x-ndjson"
+
+// maxStreamLine is the maximum size of a single NDJSON item.
+const maxStreamLine = 16 << 20
+
+// Stream reads the resource collection, invoking fn with each item as it's decoded
+// rather than buffering the entire response. The server is asked for
+// newline-delimited JSON; if it responds with a standard envelope instead, fn is
+// invoked with each of its results. Iteration stops at the first error returned by
+// fn, which Stream returns. Streamed requests aren't retried or cached.
+func (c *Client) Stream(ctx context.Context, resource string, options *ListOptions,
+	fn func(item json.RawMessage) error) error {
+
+	ctx = withResource(ctx, resource)
+	u := c.resourceURL(resource, "")
+	if query := listQuery(options).Encode(); query != "" {
+		u += "?" + query
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", ndjsonContentType+", application/json;q=0.9")
+
+	resp, err := c.doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode >= http.StatusBadRequest || mediaType != ndjsonContentType {
+		return streamEnvelope(resp, fn)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return &DecodeError{Status: resp.StatusCode, Body: line, Err: errInvalidNDJSON}
+		}
+		// The scanner reuses its buffer, so copy the item.
+		if err := fn(append(json.RawMessage(nil), line...)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// streamEnvelope decodes a buffered envelope response, invoking fn with each result.
+func streamEnvelope(resp *http.Response, fn func(item json.RawMessage) error) error {
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if _, err := decodeEnvelope(resp, data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
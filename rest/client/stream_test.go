@@ -0,0 +1,111 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that Stream decodes NDJSON items as they arrive.
+func TestClientStreamNDJSON(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+		assert.Equal("100", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "{\"id\":\"%d\"}\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	var ids []string
+	err := c.Stream(context.Background(), "widgets", &ListOptions{Limit: 100},
+		func(item json.RawMessage) error {
+			var w widget
+			if err := json.Unmarshal(item, &w); err != nil {
+				return err
+			}
+			ids = append(ids, w.ID)
+			return nil
+		})
+	assert.Nil(err)
+	assert.Equal([]string{"1", "2", "3"}, ids)
+}
+
+// Ensures that Stream stops at the first callback error and reports malformed lines.
+func TestClientStreamErrors(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{oops\n")
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := c.Stream(context.Background(), "widgets", nil, func(json.RawMessage) error {
+		calls++
+		return stop
+	})
+	assert.Equal(stop, err)
+	assert.Equal(1, calls)
+
+	calls = 0
+	err = c.Stream(context.Background(), "widgets", nil, func(json.RawMessage) error {
+		calls++
+		return nil
+	})
+	var decodeErr *DecodeError
+	assert.True(errors.As(err, &decodeErr))
+	assert.Equal([]byte("{oops"), decodeErr.Body)
+	assert.Equal(2, calls)
+}
+
+// Ensures that Stream falls back to envelope results and returns error responses.
+func TestClientStreamEnvelope(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	c := New(server.URL+"/api/v1", WithHTTPClient(server.Client()))
+	ctx := context.Background()
+	for _, name := range []string{"a", "b"} {
+		assert.Nil(c.Create(ctx, "widgets", &widget{Name: name}, nil))
+	}
+
+	var items []string
+	err := c.Stream(ctx, "widgets", nil, func(item json.RawMessage) error {
+		items = append(items, string(item))
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal([]string{`{"id":"1","name":"a"}`, `{"id":"2","name":"b"}`}, items)
+
+	err = c.Stream(ctx, "gadgets", nil, func(json.RawMessage) error { return nil })
+	assert.Equal(http.StatusNotFound, err.(*Error).Status)
+}
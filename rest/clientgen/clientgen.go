@@ -0,0 +1,282 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clientgen generates strongly typed Go clients for the resources registered
+// with a rest.API. Since resources are registered at runtime, generation is driven by
+// a small program which builds the API and calls Generate, typically invoked with go
+// generate:
+//
+//	//go:generate go run ./internal/genclient -out client_gen.go
+//
+// The generated code uses the rest/client package to perform requests.
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// Config configures the generated client.
+type Config struct {
+	// Package is the package name of the generated code. Defaults to "client".
+	Package string
+
+	// Version is the API version whose Rules determine the generated types. If empty,
+	// Rules for all versions are included.
+	Version string
+}
+
+// resource describes a generated resource client.
+type resource struct {
+	Name   string
+	Type   string
+	Client string
+}
+
+// structType describes a generated struct type.
+type structType struct {
+	Name     string
+	Resource string
+	Fields   []structField
+}
+
+// structField describes a field of a generated struct type.
+type structField struct {
+	Name string
+	Type string
+	Tag  string
+	Doc  string
+}
+
+// generator accumulates the types and resources to generate.
+type generator struct {
+	version   string
+	resources []resource
+	types     []structType
+	names     map[string]bool
+	imports   map[string]bool
+}
+
+// Generate returns gofmt'd Go source for a client of the API's registered resources.
+// Each resource gets a struct type derived from its Rules and a client type with
+// Get, List, Create, Update and Delete methods.
+func Generate(api rest.API, config Config) ([]byte, error) {
+	pkg := config.Package
+	if pkg == "" {
+		pkg = "client"
+	}
+
+	handlers := append([]rest.ResourceHandler(nil), api.ResourceHandlers()...)
+	sort.Sort(byResourceName(handlers))
+
+	g := &generator{
+		version: config.Version,
+		names:   map[string]bool{},
+		imports: map[string]bool{},
+	}
+	for _, handler := range handlers {
+		g.addResource(handler)
+	}
+
+	imports := []string{"context", "github.com/Workiva/go-rest/rest/client"}
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	var buf bytes.Buffer
+	err := clientTemplate.Execute(&buf, map[string]interface{}{
+		"Package":   pkg,
+		"Imports":   imports,
+		"Types":     g.types,
+		"Resources": g.resources,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated client: %s", err)
+	}
+	return src, nil
+}
+
+// addResource adds the struct and client types for the ResourceHandler.
+func (g *generator) addResource(handler rest.ResourceHandler) {
+	name := handler.ResourceName()
+	typeName := ""
+	if rules := handler.Rules(); rules != nil && rules.ResourceType() != nil {
+		typeName = exportedName(rules.ResourceType().Name())
+	}
+	if typeName == "" {
+		typeName = exportedName(singular(name))
+	}
+	typeName = g.uniqueName(typeName)
+
+	g.addStruct(typeName, name, handler.Rules())
+	g.resources = append(g.resources, resource{
+		Name:   name,
+		Type:   typeName,
+		Client: typeName + "Client",
+	})
+}
+
+// addStruct adds a struct type with fields for the Rules, recursively adding types
+// for nested Rules.
+func (g *generator) addStruct(name, resourceName string, rules rest.Rules) {
+	// Reserve the type's position so it precedes its nested types.
+	idx := len(g.types)
+	g.types = append(g.types, structType{Name: name, Resource: resourceName})
+	if rules == nil {
+		return
+	}
+	if g.version != "" {
+		rules = rules.ForVersion(g.version)
+	}
+
+	fields := make([]structField, 0, rules.Size())
+	for _, rule := range rules.Contents() {
+		fields = append(fields, g.field(name, rules.ResourceType(), rule))
+	}
+	g.types[idx].Fields = fields
+}
+
+// field returns the struct field for the Rule.
+func (g *generator) field(parent string, resourceType reflect.Type, rule *rest.Rule) structField {
+	tag := rule.Name()
+	if !rule.Required {
+		tag += ",omitempty"
+	}
+	field := structField{
+		Name: exportedName(rule.Field),
+		Tag:  fmt.Sprintf("`json:%q`", tag),
+		Doc:  strings.TrimSpace(rule.DocString),
+	}
+
+	switch {
+	case rule.Rules != nil:
+		nested := g.uniqueName(parent + exportedName(rule.Field))
+		g.addStruct(nested, "", rule.Rules)
+		field.Type = "*" + nested
+		if rule.Type == rest.Slice {
+			field.Type = "[]*" + nested
+		}
+	case rule.Type != rest.Unspecified:
+		field.Type = rule.Type.String()
+	default:
+		field.Type = fieldType(resourceType, rule.Field)
+	}
+
+	if strings.Contains(field.Type, "time.") {
+		g.imports["time"] = true
+	}
+	return field
+}
+
+// fieldType returns the Go type of the resource field if it can be referenced
+// without importing the resource's package, otherwise interface{}.
+func fieldType(resourceType reflect.Type, name string) string {
+	if resourceType == nil {
+		return "interface{}"
+	}
+	for resourceType.Kind() == reflect.Ptr {
+		resourceType = resourceType.Elem()
+	}
+	if resourceType.Kind() != reflect.Struct {
+		return "interface{}"
+	}
+	f, ok := resourceType.FieldByName(name)
+	if !ok || !builtinType(f.Type) {
+		return "interface{}"
+	}
+	return f.Type.String()
+}
+
+// builtinType indicates if the type is composed only of predeclared types.
+func builtinType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return builtinType(t.Elem())
+	case reflect.Map:
+		return builtinType(t.Key()) && builtinType(t.Elem())
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.Struct, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return false
+	}
+	return t.PkgPath() == ""
+}
+
+// uniqueName returns the name, suffixed with a number if it's already in use.
+func (g *generator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// exportedName converts the name to an exported Go identifier, e.g. "line_items"
+// becomes "LineItems".
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	exported := b.String()
+	if exported != "" && unicode.IsDigit(rune(exported[0])) {
+		exported = "X" + exported
+	}
+	return exported
+}
+
+// singular returns a naive singular form of the resource name.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// byResourceName implements sort.Interface for sorting ResourceHandlers by name.
+type byResourceName []rest.ResourceHandler
+
+func (b byResourceName) Len() int           { return len(b) }
+func (b byResourceName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byResourceName) Less(i, j int) bool { return b[i].ResourceName() < b[j].ResourceName() }
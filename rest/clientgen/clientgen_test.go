@@ -0,0 +1,124 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientgen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+type Order struct {
+	ID        string
+	Total     float64
+	Tags      []string
+	Placed    time.Time
+	LineItems []LineItem
+}
+
+type LineItem struct {
+	SKU      string
+	Quantity int
+}
+
+type orderHandler struct {
+	rest.BaseResourceHandler
+}
+
+func (o orderHandler) ResourceName() string {
+	return "orders"
+}
+
+func (o orderHandler) Rules() rest.Rules {
+	return rest.NewRules((*Order)(nil),
+		&rest.Rule{Field: "ID", FieldAlias: "id", Required: true, DocString: "ID of the order."},
+		&rest.Rule{Field: "Total", FieldAlias: "total", Type: rest.Float64},
+		&rest.Rule{Field: "Tags", FieldAlias: "tags"},
+		&rest.Rule{Field: "Placed", FieldAlias: "placed", Type: rest.Time, Versions: []string{"2"}},
+		&rest.Rule{Field: "LineItems", FieldAlias: "line_items", Type: rest.Slice,
+			Rules: rest.NewRules((*LineItem)(nil),
+				&rest.Rule{Field: "SKU", FieldAlias: "sku", Type: rest.String},
+				&rest.Rule{Field: "Quantity", FieldAlias: "quantity", Type: rest.Int},
+			)},
+	)
+}
+
+type categoryHandler struct {
+	rest.BaseResourceHandler
+}
+
+func (c categoryHandler) ResourceName() string {
+	return "categories"
+}
+
+// Ensures that Generate emits types and clients for the registered resources.
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	api := rest.NewAPI(&rest.Configuration{})
+	api.RegisterResourceHandler(orderHandler{})
+	api.RegisterResourceHandler(categoryHandler{})
+
+	src, err := Generate(api, Config{Package: "orders", Version: "1"})
+	assert.Nil(err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "client_gen.go", src, 0)
+	assert.Nil(err)
+
+	code := string(src)
+	assert.Contains(code, "// Code generated by clientgen. DO NOT EDIT.\n\npackage orders\n")
+	assert.Contains(code, "type Category struct {\n}")
+	assert.Contains(code, `type Order struct {
+	// ID of the order.
+	ID        string            `+"`json:\"id\"`"+`
+	Total     float64           `+"`json:\"total,omitempty\"`"+`
+	Tags      []string          `+"`json:\"tags,omitempty\"`"+`
+	LineItems []*OrderLineItems `)
+	assert.True(strings.Index(code, "type Order struct") < strings.Index(code, "type OrderLineItems struct"))
+	assert.Contains(code, "type OrderLineItems struct {\n\tSKU      string `json:\"sku,omitempty\"`")
+	assert.Contains(code,
+		"func (r *OrderClient) Get(ctx context.Context, id string) (*Order, error) {")
+	assert.Contains(code, `r.c.List(ctx, "orders", options, &results)`)
+	assert.Contains(code, "func NewCategoryClient(c *client.Client) *CategoryClient {")
+	assert.NotContains(code, "Placed")
+	assert.NotContains(code, `"time"`)
+
+	src, err = Generate(api, Config{})
+	assert.Nil(err)
+	code = string(src)
+	assert.Contains(code, "package client\n")
+	assert.Contains(code, "Placed    time.Time")
+	assert.Contains(code, "\t\"time\"\n")
+}
+
+// Ensures that exportedName and singular derive Go identifiers from resource names.
+func TestNames(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("LineItems", exportedName("line_items"))
+	assert.Equal("ID", exportedName("ID"))
+	assert.Equal("X2fa", exportedName("2fa"))
+	assert.Equal("category", singular("categories"))
+	assert.Equal("address", singular("addresses"))
+	assert.Equal("order", singular("orders"))
+	assert.Equal("class", singular("class"))
+}
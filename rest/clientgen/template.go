@@ -0,0 +1,89 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientgen
+
+import "text/template"
+
+// clientTemplate is the template of the generated client source.
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by clientgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{range .Imports}}	"{{.}}"
+{{end}})
+{{range .Types}}
+{{if .Resource}}// {{.Name}} is a resource of the {{.Resource}} collection.{{else}}// {{.Name}} is a nested value.{{end}}
+type {{.Name}} struct {
+{{range .Fields}}{{if .Doc}}	// {{.Doc}}
+{{end}}	{{.Name}} {{.Type}} {{.Tag}}
+{{end}}}
+{{end}}
+{{range .Resources}}
+// {{.Client}} performs operations on the {{.Name}} resource.
+type {{.Client}} struct {
+	c *client.Client
+}
+
+// New{{.Client}} returns a client for the {{.Name}} resource which performs
+// requests using c.
+func New{{.Client}}(c *client.Client) *{{.Client}} {
+	return &{{.Client}}{c: c}
+}
+
+// Get reads the {{.Type}} with the given ID.
+func (r *{{.Client}}) Get(ctx context.Context, id string) (*{{.Type}}, error) {
+	result := new({{.Type}})
+	if err := r.c.Read(ctx, "{{.Name}}", id, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// List reads a page of the {{.Name}} resource.
+func (r *{{.Client}}) List(ctx context.Context, options *client.ListOptions) ([]*{{.Type}}, *client.Page, error) {
+	var results []*{{.Type}}
+	page, err := r.c.List(ctx, "{{.Name}}", options, &results)
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, page, nil
+}
+
+// Create creates the {{.Type}}, returning the created {{.Type}}.
+func (r *{{.Client}}) Create(ctx context.Context, resource *{{.Type}}) (*{{.Type}}, error) {
+	result := new({{.Type}})
+	if err := r.c.Create(ctx, "{{.Name}}", resource, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Update updates the {{.Type}} with the given ID, returning the updated {{.Type}}.
+func (r *{{.Client}}) Update(ctx context.Context, id string, resource *{{.Type}}) (*{{.Type}}, error) {
+	result := new({{.Type}})
+	if err := r.c.Update(ctx, "{{.Name}}", id, resource, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete deletes the {{.Type}} with the given ID.
+func (r *{{.Client}}) Delete(ctx context.Context, id string) error {
+	return r.c.Delete(ctx, "{{.Name}}", id, nil)
+}
+{{end}}`))
@@ -0,0 +1,144 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses the given IP addresses and CIDR ranges (e.g. "10.0.0.0/8"
+// or "192.168.1.1") into networks for use with ClientIP. If any are invalid, nil is
+// returned with an error.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid trusted proxy: %s", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted proxy: %s", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ClientIP returns the IP address of the client which made the request. The
+// Forwarded, X-Forwarded-For and X-Real-IP headers, in that order of precedence, are
+// only honored when the request was received from one of the trusted proxies, since
+// they can otherwise be spoofed by the client. Repeated Forwarded and X-Forwarded-For
+// headers form a single chain, and forwarding chains are walked from the nearest hop
+// backwards, and the first address which isn't a trusted proxy is
+// returned. If the remote address can't be parsed, an empty string is returned.
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remote := parseHostIP(r.RemoteAddr)
+	if remote == nil {
+		return ""
+	}
+
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote.String()
+	}
+
+	if forwarded := strings.Join(r.Header["Forwarded"], ","); forwarded != "" {
+		if ip := clientFromChain(parseForwardedFor(forwarded), trustedProxies); ip != nil {
+			return ip.String()
+		}
+	}
+
+	if xff := strings.Join(r.Header["X-Forwarded-For"], ","); xff != "" {
+		if ip := clientFromChain(strings.Split(xff, ","), trustedProxies); ip != nil {
+			return ip.String()
+		}
+	}
+
+	if ip := parseHostIP(r.Header.Get("X-Real-IP")); ip != nil {
+		return ip.String()
+	}
+
+	return remote.String()
+}
+
+// clientFromChain walks the chain of forwarded addresses from right (nearest hop) to
+// left and returns the first one which isn't a trusted proxy. If every address is
+// trusted, the leftmost address is returned. If any address is malformed, nil is
+// returned since the chain can't be relied upon.
+func clientFromChain(chain []string, trustedProxies []*net.IPNet) net.IP {
+	var ip net.IP
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip = parseHostIP(chain[i])
+		if ip == nil {
+			return nil
+		}
+		if !isTrustedProxy(ip, trustedProxies) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// parseForwardedFor returns the "for" parameter values of a Forwarded header
+// (RFC 7239) in the order they appear.
+func parseForwardedFor(header string) []string {
+	addrs := []string{}
+	for _, element := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			pair = strings.TrimSpace(pair)
+			if len(pair) < 4 || !strings.EqualFold(pair[:4], "for=") {
+				continue
+			}
+			addrs = append(addrs, strings.Trim(pair[4:], `"`))
+		}
+	}
+	return addrs
+}
+
+// parseHostIP parses an IP address which may include a port and, for IPv6
+// addresses, brackets (e.g. "[2001:db8::1]:8080"). Returns nil if it's invalid.
+func parseHostIP(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
+
+// isTrustedProxy returns true if the IP address is contained in any of the trusted
+// proxy networks.
+func isTrustedProxy(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that ParseTrustedProxies accepts IP addresses and CIDR ranges and rejects
+// invalid values.
+func TestParseTrustedProxies(t *testing.T) {
+	assert := assert.New(t)
+
+	networks, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	if assert.Nil(err) && assert.Len(networks, 3) {
+		assert.Equal("10.0.0.0/8", networks[0].String())
+		assert.Equal("192.168.1.1/32", networks[1].String())
+		assert.Equal("::1/128", networks[2].String())
+	}
+
+	networks, err = ParseTrustedProxies([]string{"10.0.0.0/8", "foo"})
+	assert.Nil(networks)
+	assert.NotNil(err)
+}
+
+// Ensures that forwarding headers are ignored for requests from untrusted addresses.
+func TestClientIPUntrustedRemote(t *testing.T) {
+	assert := assert.New(t)
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("X-Real-IP", "1.2.3.4")
+
+	assert.Equal("203.0.113.7", ClientIP(req, trusted))
+	assert.Equal("203.0.113.7", ClientIP(req, nil))
+}
+
+// Ensures that X-Forwarded-For chains are walked from the nearest hop, skipping
+// trusted proxies, so that spoofed leading entries are ignored.
+func TestClientIPForwardedFor(t *testing.T) {
+	assert := assert.New(t)
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.2, 10.0.0.2")
+
+	assert.Equal("198.51.100.2", ClientIP(req, trusted))
+
+	req.Header.Set("X-Forwarded-For", "10.0.0.3, 10.0.0.2")
+	assert.Equal("10.0.0.3", ClientIP(req, trusted))
+
+	req.Header.Set("X-Forwarded-For", "garbage")
+	req.Header.Set("X-Real-IP", "198.51.100.9")
+	assert.Equal("198.51.100.9", ClientIP(req, trusted))
+}
+
+// Ensures that repeated forwarding headers are walked as a single chain, so that the
+// nearest hops of the last header are considered first.
+func TestClientIPMultipleHeaders(t *testing.T) {
+	assert := assert.New(t)
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Add("X-Forwarded-For", "1.2.3.4")
+	req.Header.Add("X-Forwarded-For", "198.51.100.2, 10.0.0.2")
+
+	assert.Equal("198.51.100.2", ClientIP(req, trusted))
+
+	req.Header.Add("Forwarded", "for=1.2.3.4")
+	req.Header.Add("Forwarded", "for=198.51.100.3, for=10.0.0.2")
+	assert.Equal("198.51.100.3", ClientIP(req, trusted))
+}
+
+// Ensures that the Forwarded header takes precedence and supports quoted IPv6
+// addresses with ports.
+func TestClientIPForwarded(t *testing.T) {
+	assert := assert.New(t)
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("Forwarded", `for="[2001:db8::1]:4711";proto=https, for=10.0.0.2`)
+	req.Header.Set("X-Forwarded-For", "198.51.100.2")
+
+	assert.Equal("2001:db8::1", ClientIP(req, trusted))
+}
+
+// Ensures that the RequestContext resolves the client IP using the API's trusted
+// proxies.
+func TestContextClientIP(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{TrustedProxies: []string{"127.0.0.1"}})
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("X-Real-IP", "198.51.100.2")
+
+	ctx, _ := api.(*muxAPI).handler.newContext(req, httptest.NewRecorder(), "")
+
+	assert.Equal("198.51.100.2", ctx.ClientIP())
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CodedError is implemented by errors which carry a stable, machine-readable code.
+// The code is included in the "code" field of error responses so clients can branch
+// on it rather than on message text.
+type CodedError interface {
+	error
+
+	// Code returns the machine-readable error code.
+	Code() string
+}
+
+// ErrorCode is a registered, machine-readable error code along with the HTTP status
+// code of errors which carry it. ErrorCodes should be created using
+// RegisterErrorCode.
+type ErrorCode struct {
+	code        string
+	status      int
+	description string
+}
+
+// String returns the error code.
+func (c ErrorCode) String() string { return c.code }
+
+// Status returns the HTTP status code of errors which carry the code.
+func (c ErrorCode) Status() int { return c.status }
+
+// Description returns the description of the error code.
+func (c ErrorCode) Description() string { return c.description }
+
+// Error returns an Error with the given reason which carries the code.
+func (c ErrorCode) Error(reason string) Error {
+	return Error{reason: reason, status: c.status, code: c.code}
+}
+
+// errorCodes is the registry of error codes, keyed by code.
+var (
+	errorCodes   = map[string]ErrorCode{}
+	errorCodesMu sync.RWMutex
+)
+
+// RegisterErrorCode registers a machine-readable error code for errors with the
+// given HTTP status code. Codes must be unique, so this panics if the code is empty
+// or already registered. It's intended to be called from package-level variable
+// declarations or init functions.
+func RegisterErrorCode(code string, status int, description string) ErrorCode {
+	if code == "" {
+		panic("rest: error code must not be empty")
+	}
+
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+
+	if _, ok := errorCodes[code]; ok {
+		panic(fmt.Sprintf("rest: error code %q already registered", code))
+	}
+
+	errorCode := ErrorCode{code: code, status: status, description: description}
+	errorCodes[code] = errorCode
+	return errorCode
+}
+
+// LookupErrorCode returns the registered ErrorCode for the code, if any.
+func LookupErrorCode(code string) (ErrorCode, bool) {
+	errorCodesMu.RLock()
+	defer errorCodesMu.RUnlock()
+	errorCode, ok := errorCodes[code]
+	return errorCode, ok
+}
+
+// ErrorCodes returns all registered ErrorCodes sorted by code.
+func ErrorCodes() []ErrorCode {
+	errorCodesMu.RLock()
+	defer errorCodesMu.RUnlock()
+
+	codes := make([]ErrorCode, 0, len(errorCodes))
+	for _, errorCode := range errorCodes {
+		codes = append(codes, errorCode)
+	}
+	sort.Sort(byCode(codes))
+	return codes
+}
+
+// byCode implements sort.Interface for sorting ErrorCodes by code.
+type byCode []ErrorCode
+
+func (b byCode) Len() int           { return len(b) }
+func (b byCode) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCode) Less(i, j int) bool { return b[i].code < b[j].code }
+
+// errorCode returns the machine-readable code of the error, if any.
+func errorCode(err error) string {
+	var coded CodedError
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errQuotaExceeded = RegisterErrorCode("quota_exceeded", http.StatusTooManyRequests,
+	"The account has exceeded its request quota.")
+
+// Ensures that RegisterErrorCode registers codes and produces Errors carrying them.
+func TestRegisterErrorCode(t *testing.T) {
+	assert := assert.New(t)
+
+	err := errQuotaExceeded.Error("Too many widgets")
+	assert.Equal("Too many widgets", err.Error())
+	assert.Equal(http.StatusTooManyRequests, err.StatusCode())
+	assert.Equal("quota_exceeded", err.Code())
+	assert.Equal("quota_exceeded", errorCode(err))
+	assert.Equal("", errorCode(NotFound("foo")))
+
+	registered, ok := LookupErrorCode("quota_exceeded")
+	assert.True(ok)
+	assert.Equal(errQuotaExceeded, registered)
+	assert.Equal("The account has exceeded its request quota.", registered.Description())
+	assert.Contains(ErrorCodes(), errQuotaExceeded)
+
+	_, ok = LookupErrorCode("nope")
+	assert.False(ok)
+}
+
+// Ensures that RegisterErrorCode panics for empty or duplicate codes.
+func TestRegisterErrorCodeDuplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Panics(func() { RegisterErrorCode("", http.StatusBadRequest, "") })
+	assert.Panics(func() { RegisterErrorCode("quota_exceeded", http.StatusBadRequest, "") })
+}
+
+type codedResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (c codedResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (c codedResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return nil, errQuotaExceeded.Error("Too many widgets")
+}
+
+// Ensures that error codes are included in the response envelope.
+func TestHandleReadErrorCode(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(codedResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusTooManyRequests, resp.Code)
+	assert.Equal(
+		`{"code":"quota_exceeded","messages":["Too many widgets"],"reason":"Too Many Requests","status":429}`,
+		resp.Body.String(),
+	)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// parseETags parses the entity tags of an If-Match or If-None-Match header. The
+// wildcard "*" is returned as-is. Tags retain their quotes and weak prefix.
+func parseETags(header string) []string {
+	etags := []string{}
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag != "" {
+			etags = append(etags, etag)
+		}
+	}
+	return etags
+}
+
+// formatETag returns the entity tag quoted if it isn't already, e.g. abc becomes "abc"
+// while W/"abc" is left unchanged.
+func formatETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// contentETag returns a strong entity tag derived from a hash of the serialized
+// response body.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches returns true if the entity tag matches any of the tags (or the tags
+// contain the wildcard and the resource exists). Strong comparison (RFC 7232,
+// section 2.3.2) requires that neither tag is weak, while weak comparison ignores the
+// weak prefix.
+func etagMatches(etags []string, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range etags {
+		if candidate == "*" {
+			return true
+		}
+		if strong {
+			if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") &&
+				candidate == etag {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHTTPDate parses the value of a header such as If-Modified-Since. Returns false
+// if the header is missing or malformed, in which case it must be ignored.
+func parseHTTPDate(header string) (time.Time, bool) {
+	if header == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// evaluatePreconditions evaluates the conditional headers of the request against the
+// current entity tag and modification time of the resource, following the order of
+// precedence defined by RFC 7232, section 6. It returns 0 if the request should be
+// performed, otherwise http.StatusNotModified or http.StatusPreconditionFailed. An
+// empty entity tag or zero modification time means the resource doesn't have one.
+func evaluatePreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	etag = formatETag(etag)
+	// HTTP dates have a resolution of one second.
+	lastModified = lastModified.Truncate(time.Second)
+	safe := r.Method == "GET" || r.Method == "HEAD"
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagMatches(parseETags(ifMatch), etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPDate(r.Header.Get("If-Unmodified-Since")); ok &&
+		!lastModified.IsZero() && lastModified.After(since) {
+		return http.StatusPreconditionFailed
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etagMatches(parseETags(ifNoneMatch), etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPDate(r.Header.Get("If-Modified-Since")); ok && safe &&
+		!lastModified.IsZero() && !lastModified.After(since) {
+		return http.StatusNotModified
+	}
+
+	return 0
+}
+
+// ETagResourceHandler is an optional interface implemented by ResourceHandlers
+// supporting optimistic concurrency control. Before an update or delete request with
+// an If-Match or If-None-Match header is passed to the ResourceHandler, the header is
+// evaluated against the current entity tag of the resource and a 412 Precondition
+// Failed response is sent if it doesn't match, preventing lost updates. The verified
+// entity tag is available from RequestContext.ExpectedETag.
+type ETagResourceHandler interface {
+	// ResourceETag returns the current entity tag of the resource with the ID for the
+	// version, or an empty string if the resource doesn't exist.
+	ResourceETag(ctx RequestContext, id, version string) (string, error)
+}
+
+// checkWritePreconditions evaluates the conditional headers of an update or delete
+// request against the current entity tag of the resource if the handler is an
+// ETagResourceHandler, returning a PreconditionFailed Error if they don't match.
+// Otherwise the returned RequestContext holds the expected entity tag.
+func checkWritePreconditions(ctx RequestContext, handler ResourceHandler,
+	version string) (RequestContext, error) {
+
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	etagHandler, ok := handler.(ETagResourceHandler)
+	if !ok {
+		return ctx, nil
+	}
+	ifMatch := ctx.IfMatch()
+	if len(ifMatch) == 0 && len(ctx.IfNoneMatch()) == 0 {
+		return ctx, nil
+	}
+	req, ok := ctx.Request()
+	if !ok {
+		return ctx, nil
+	}
+
+	etag, err := etagHandler.ResourceETag(ctx, ctx.ResourceID(), version)
+	if err != nil {
+		return ctx, err
+	}
+	etag = formatETag(etag)
+	if evaluatePreconditions(req, etag, time.Time{}) != 0 {
+		if etag != "" {
+			ctx.ResponseHeader().Set("ETag", etag)
+		}
+		return ctx, PreconditionFailed("Precondition Failed")
+	}
+	if len(ifMatch) > 0 {
+		ctx = ctx.WithValue(expectedETagKey, etag)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the conditional headers are exposed on the RequestContext.
+func TestContextConditionalHeaders(t *testing.T) {
+	assert := assert.New(t)
+	modified := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("If-Match", `"a", W/"b"`)
+	req.Header.Set("If-None-Match", "*")
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	req.Header.Set("If-Unmodified-Since", "garbage")
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	assert.Equal([]string{`"a"`, `W/"b"`}, ctx.IfMatch())
+	assert.Equal([]string{"*"}, ctx.IfNoneMatch())
+	since, ok := ctx.IfModifiedSince()
+	assert.True(ok)
+	assert.Equal(modified, since)
+	_, ok = ctx.IfUnmodifiedSince()
+	assert.False(ok)
+}
+
+// Ensures that preconditions are evaluated according to RFC 7232.
+func TestEvaluatePreconditions(t *testing.T) {
+	assert := assert.New(t)
+	modified := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+	newRequest := func(method string, headers map[string]string) *http.Request {
+		req, _ := http.NewRequest(method, "http://example.com/foo", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+
+	assert.Equal(0, evaluatePreconditions(newRequest("GET", nil), "abc", modified))
+
+	// If-None-Match uses weak comparison.
+	req := newRequest("GET", map[string]string{"If-None-Match": `W/"abc"`})
+	assert.Equal(http.StatusNotModified, evaluatePreconditions(req, "abc", modified))
+	req = newRequest("PUT", map[string]string{"If-None-Match": "*"})
+	assert.Equal(http.StatusPreconditionFailed, evaluatePreconditions(req, "abc", modified))
+	req = newRequest("PUT", map[string]string{"If-None-Match": "*"})
+	assert.Equal(0, evaluatePreconditions(req, "", time.Time{}))
+
+	// If-Match uses strong comparison.
+	req = newRequest("PUT", map[string]string{"If-Match": `"abc"`})
+	assert.Equal(0, evaluatePreconditions(req, "abc", modified))
+	req = newRequest("PUT", map[string]string{"If-Match": `W/"abc"`})
+	assert.Equal(http.StatusPreconditionFailed, evaluatePreconditions(req, "abc", modified))
+
+	// Dates are only consulted without the corresponding entity tag header.
+	req = newRequest("GET", map[string]string{
+		"If-Modified-Since": modified.Format(http.TimeFormat)})
+	assert.Equal(http.StatusNotModified,
+		evaluatePreconditions(req, "", modified.Add(500*time.Millisecond)))
+	req = newRequest("GET", map[string]string{
+		"If-None-Match":     `"xyz"`,
+		"If-Modified-Since": modified.Format(http.TimeFormat)})
+	assert.Equal(0, evaluatePreconditions(req, "abc", modified))
+	req = newRequest("DELETE", map[string]string{
+		"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.Equal(http.StatusPreconditionFailed, evaluatePreconditions(req, "", modified))
+}
+
+type conditionalResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (c conditionalResourceHandler) ResourceName() string {
+	return "docs"
+}
+
+func (c conditionalResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	if err := ctx.CheckPreconditions("v1", time.Time{}); err != nil {
+		return nil, err
+	}
+	return &TestResource{Foo: "bar"}, nil
+}
+
+// Ensures that a NotModified error returned by a ResourceHandler results in a 304
+// response without a body.
+func TestHandleReadNotModified(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(conditionalResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/docs/1", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotModified, resp.Code)
+	assert.Equal(`"v1"`, resp.Header().Get("ETag"))
+	assert.Equal("", resp.Body.String())
+
+	req, _ = http.NewRequest("GET", "http://foo.com/api/v1/docs/1", nil)
+	req.Header.Set("If-None-Match", `"v0"`)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal(`"v1"`, resp.Header().Get("ETag"))
+}
+
+// Ensures that an entity tag is generated from the serialized response when ETags is
+// enabled and that a matching If-None-Match header results in a 304 response.
+func TestGeneratedETag(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ETags: true})
+	api.RegisterResourceHandler(&cachingResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	etag := resp.Header().Get("ETag")
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal(contentETag(resp.Body.Bytes()), etag)
+
+	req, _ = http.NewRequest("GET", "http://foo.com/api/v1/widgets", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusNotModified, resp.Code)
+	assert.Equal(etag, resp.Header().Get("ETag"))
+	assert.Equal("", resp.Body.String())
+
+	// Entity tags provided by the ResourceHandler take precedence.
+	req, _ = http.NewRequest("GET", "http://foo.com/api/v1/widgets/1", nil)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(`"v1"`, resp.Header().Get("ETag"))
+
+	// Write responses don't get entity tags.
+	req, _ = http.NewRequest("POST", "http://foo.com/api/v1/widgets", bytes.NewBufferString(`{"foo": "bar"}`))
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusCreated, resp.Code)
+	assert.Equal("", resp.Header().Get("ETag"))
+}
+
+// Ensures that entity tags aren't generated unless ETags is enabled.
+func TestGeneratedETagDisabled(t *testing.T) {
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(&cachingResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(t, "", resp.Header().Get("ETag"))
+}
+
+type versionedResourceHandler struct {
+	BaseResourceHandler
+	etag     string
+	expected string
+	writes   int
+}
+
+func (v *versionedResourceHandler) ResourceName() string {
+	return "docs"
+}
+
+func (v *versionedResourceHandler) ResourceETag(ctx RequestContext, id,
+	version string) (string, error) {
+	return v.etag, nil
+}
+
+func (v *versionedResourceHandler) UpdateResource(ctx RequestContext, id string,
+	data Payload, version string) (Resource, error) {
+	v.writes++
+	v.expected = ctx.ExpectedETag()
+	return &TestResource{Foo: "bar"}, nil
+}
+
+func (v *versionedResourceHandler) DeleteResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	v.writes++
+	v.expected = ctx.ExpectedETag()
+	return nil, nil
+}
+
+// Ensures that updates and deletes of an ETagResourceHandler are rejected with a 412
+// if the If-Match header doesn't match the current entity tag.
+func TestWritePreconditions(t *testing.T) {
+	assert := assert.New(t)
+	handler := &versionedResourceHandler{etag: "v2"}
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(handler)
+	serve := func(method, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://foo.com/api/v1/docs/1",
+			bytes.NewBufferString(`{"foo": "bar"}`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp := httptest.NewRecorder()
+		api.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve("PUT", `"v1"`)
+	assert.Equal(http.StatusPreconditionFailed, resp.Code)
+	assert.Equal(`"v2"`, resp.Header().Get("ETag"))
+	resp = serve("DELETE", `"v1"`)
+	assert.Equal(http.StatusPreconditionFailed, resp.Code)
+	assert.Equal(0, handler.writes)
+
+	resp = serve("PUT", `"v2"`)
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal(`"v2"`, handler.expected)
+	resp = serve("DELETE", `"v1", "v2"`)
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal(`"v2"`, handler.expected)
+
+	// Requests without preconditions are passed through.
+	resp = serve("PUT", "")
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal("", handler.expected)
+	assert.Equal(3, handler.writes)
+
+	// A wildcard doesn't match a resource which doesn't exist.
+	handler.etag = ""
+	resp = serve("PUT", "*")
+	assert.Equal(http.StatusPreconditionFailed, resp.Code)
+}
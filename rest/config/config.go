@@ -0,0 +1,389 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the settings of servers built with go-rest from JSON or YAML
+// files and environment variables, so they can be configured without recompiling.
+//
+// Settings are read from the file, if any, and then overridden by the environment
+// variables named by the prefix and the env tags of the fields, e.g.
+// MYAPP_TIMEOUTS_READ for Timeouts.Read with the prefix "MYAPP_". Durations are
+// strings such as "30s" and lists are comma-separated in environment variables.
+//
+//	settings, err := config.Load("server.yaml", "MYAPP_")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	api := rest.NewAPI(settings.Configuration())
+//	api.RegisterResourceHandler(handler)
+//	log.Fatal(settings.Start(api))
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v1"
+
+	"github.com/Workiva/go-rest/rest"
+	"github.com/Workiva/go-rest/rest/middleware"
+)
+
+// Config is the configuration of a server.
+type Config struct {
+	// Address is the address the server listens on, e.g. ":8080" or
+	// "unix:/run/api.sock".
+	Address string `json:"address" env:"ADDRESS"`
+
+	// TLS configures the server's certificate. If unset, the server doesn't use TLS.
+	TLS TLS `json:"tls" env:"TLS_"`
+
+	// Timeouts limits the duration of connections and requests.
+	Timeouts Timeouts `json:"timeouts" env:"TIMEOUTS_"`
+
+	// CORS configures cross-origin requests. If no origins are allowed, cross-origin
+	// requests aren't checked.
+	CORS CORS `json:"cors" env:"CORS_"`
+
+	// RateLimit limits the rate of requests from each client. If its rate is zero,
+	// requests aren't limited.
+	RateLimit RateLimit `json:"rate_limit" env:"RATE_LIMIT_"`
+
+	// TrustedProxies are the IP addresses and CIDR ranges of proxies whose forwarding
+	// headers identify clients.
+	TrustedProxies []string `json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	// Debug enables debug logging.
+	Debug bool `json:"debug" env:"DEBUG"`
+}
+
+// TLS configures the certificate of a server.
+type TLS struct {
+	// CertFile is the path of the PEM-encoded certificate chain.
+	CertFile string `json:"cert_file" env:"CERT_FILE"`
+
+	// KeyFile is the path of the PEM-encoded private key.
+	KeyFile string `json:"key_file" env:"KEY_FILE"`
+}
+
+// Timeouts limits the duration of connections and requests. Zero values leave the
+// defaults in place.
+type Timeouts struct {
+	// Read is the maximum duration for reading an entire request.
+	Read Duration `json:"read" env:"READ"`
+
+	// ReadHeader is the maximum duration for reading the request headers.
+	ReadHeader Duration `json:"read_header" env:"READ_HEADER"`
+
+	// Write is the maximum duration before timing out writes of the response.
+	Write Duration `json:"write" env:"WRITE"`
+
+	// Idle is how long idle keep-alive connections are kept open.
+	Idle Duration `json:"idle" env:"IDLE"`
+
+	// Shutdown is how long in-flight requests may take to complete when shutting
+	// down.
+	Shutdown Duration `json:"shutdown" env:"SHUTDOWN"`
+
+	// Request is the maximum duration a ResourceHandler has to handle a request.
+	Request Duration `json:"request" env:"REQUEST"`
+}
+
+// CORS configures cross-origin requests.
+type CORS struct {
+	// AllowedOrigins are the origins allowed to make requests, which may contain
+	// wildcards, e.g. "*.example.com" or "*".
+	AllowedOrigins []string `json:"allowed_origins" env:"ALLOWED_ORIGINS"`
+}
+
+// RateLimit limits the rate of requests from each client.
+type RateLimit struct {
+	// RequestsPerSecond is the average rate of requests allowed.
+	RequestsPerSecond float64 `json:"requests_per_second" env:"REQUESTS_PER_SECOND"`
+
+	// Burst is the number of requests allowed in bursts. Defaults to 1.
+	Burst int `json:"burst" env:"BURST"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s".
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration from a JSON string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %s", b)
+	}
+	return d.parse(s)
+}
+
+// MarshalJSON writes the duration as a JSON string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// parse sets the duration from the string.
+func (d *Duration) parse(s string) error {
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// durationType is the reflected type of Durations.
+var durationType = reflect.TypeOf(Duration(0))
+
+// Load returns the Config read from the file, if the path isn't empty, overridden by
+// the environment variables with the prefix. Files with the .yaml or .yml extension
+// are read as YAML and others as JSON. Unknown settings and invalid values are
+// reported as errors.
+func Load(path, envPrefix string) (*Config, error) {
+	config := &Config{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = config.decodeYAML(data)
+		default:
+			err = config.decodeJSON(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: %s: %v", path, err)
+		}
+	}
+	if err := config.ApplyEnv(envPrefix); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// decodeJSON decodes the JSON into the Config, rejecting unknown settings.
+func (c *Config) decodeJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(c)
+}
+
+// decodeYAML decodes the YAML into the Config by way of JSON, so the same field names
+// and Duration format apply.
+func (c *Config) decodeYAML(data []byte) error {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(jsonValue(value))
+	if err != nil {
+		return err
+	}
+	return c.decodeJSON(data)
+}
+
+// jsonValue converts the maps decoded from YAML, which may have keys of any type, to
+// maps which can be encoded as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range value {
+			value[i] = jsonValue(v)
+		}
+	}
+	return value
+}
+
+// ApplyEnv overrides the settings of the Config with the environment variables with
+// the prefix which are set.
+func (c *Config) ApplyEnv(prefix string) error {
+	return applyEnv(reflect.ValueOf(c).Elem(), prefix, os.LookupEnv)
+}
+
+// applyEnv sets the fields of the struct from the environment variables named by the
+// prefix and their env tags, descending into nested structs.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		name := prefix + field.Tag.Get("env")
+		if value.Kind() == reflect.Struct {
+			if err := applyEnv(value, name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(value, strings.TrimSpace(s)); err != nil {
+			return fmt.Errorf("config: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue parses the string into the value.
+func setValue(value reflect.Value, s string) error {
+	if value.Type() == durationType {
+		return value.Addr().Interface().(*Duration).parse(s)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// Validate returns an error describing the first invalid setting of the Config, if
+// any.
+func (c *Config) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("config: address is required")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return fmt.Errorf("config: tls.cert_file and tls.key_file must be set together")
+	}
+	for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("config: %v", err)
+		}
+	}
+	timeouts := []struct {
+		name    string
+		timeout Duration
+	}{
+		{"read", c.Timeouts.Read},
+		{"read_header", c.Timeouts.ReadHeader},
+		{"write", c.Timeouts.Write},
+		{"idle", c.Timeouts.Idle},
+		{"shutdown", c.Timeouts.Shutdown},
+		{"request", c.Timeouts.Request},
+	}
+	for _, t := range timeouts {
+		if t.timeout < 0 {
+			return fmt.Errorf("config: timeouts.%s must not be negative", t.name)
+		}
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "" {
+			return fmt.Errorf("config: cors.allowed_origins must not contain empty origins")
+		}
+	}
+	if c.RateLimit.RequestsPerSecond < 0 {
+		return fmt.Errorf("config: rate_limit.requests_per_second must not be negative")
+	}
+	if c.RateLimit.Burst < 0 {
+		return fmt.Errorf("config: rate_limit.burst must not be negative")
+	}
+	if _, err := rest.ParseTrustedProxies(c.TrustedProxies); err != nil {
+		return fmt.Errorf("config: trusted_proxies: %v", err)
+	}
+	return nil
+}
+
+// Configuration returns the rest.Configuration of an API with the settings.
+func (c *Config) Configuration() *rest.Configuration {
+	configuration := rest.NewConfiguration()
+	configuration.Debug = c.Debug
+	configuration.TrustedProxies = c.TrustedProxies
+	configuration.RequestTimeout = time.Duration(c.Timeouts.Request)
+	configuration.Server = &rest.ServerConfig{
+		ReadTimeout:       time.Duration(c.Timeouts.Read),
+		ReadHeaderTimeout: time.Duration(c.Timeouts.ReadHeader),
+		WriteTimeout:      time.Duration(c.Timeouts.Write),
+		IdleTimeout:       time.Duration(c.Timeouts.Idle),
+		ShutdownTimeout:   time.Duration(c.Timeouts.Shutdown),
+	}
+	return configuration
+}
+
+// Middleware returns the Middleware enforcing the CORS and rate limit settings.
+func (c *Config) Middleware() []rest.Middleware {
+	var mw []rest.Middleware
+	if len(c.CORS.AllowedOrigins) > 0 {
+		mw = append(mw, middleware.NewCORSMiddleware(c.CORS.AllowedOrigins))
+	}
+	if c.RateLimit.RequestsPerSecond > 0 {
+		proxies, _ := rest.ParseTrustedProxies(c.TrustedProxies)
+		mw = append(mw, middleware.NewRateLimitMiddleware(c.RateLimit.RequestsPerSecond,
+			c.RateLimit.Burst, proxies))
+	}
+	return mw
+}
+
+// Start begins serving requests with the API at the configured address, using TLS if
+// a certificate is configured, with the Middleware of the settings. Like API#Start,
+// it blocks until the API fails or is stopped.
+func (c *Config) Start(api rest.API) error {
+	address := rest.Address(c.Address)
+	if c.TLS.CertFile != "" {
+		return api.StartTLS(address, rest.FilePath(c.TLS.CertFile),
+			rest.FilePath(c.TLS.KeyFile), c.Middleware()...)
+	}
+	return api.Start(address, c.Middleware()...)
+}
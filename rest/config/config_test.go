@@ -0,0 +1,170 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFile writes the contents to the named file in a temporary directory and
+// returns its path.
+func writeFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Ensures that Load reads the settings from JSON and YAML files.
+func TestLoadFile(t *testing.T) {
+	assert := assert.New(t)
+	expected := &Config{
+		Address:   ":8080",
+		Timeouts:  Timeouts{Read: Duration(5 * time.Second), Shutdown: Duration(time.Minute)},
+		CORS:      CORS{AllowedOrigins: []string{"*.example.com"}},
+		RateLimit: RateLimit{RequestsPerSecond: 2.5, Burst: 10},
+		Debug:     true,
+	}
+
+	config, err := Load(writeFile(t, "server.json", `{
+		"address": ":8080",
+		"timeouts": {"read": "5s", "shutdown": "1m"},
+		"cors": {"allowed_origins": ["*.example.com"]},
+		"rate_limit": {"requests_per_second": 2.5, "burst": 10},
+		"debug": true
+	}`), "")
+	assert.NoError(err)
+	assert.Equal(expected, config)
+
+	config, err = Load(writeFile(t, "server.yaml", `
+address: ":8080"
+timeouts:
+  read: 5s
+  shutdown: 1m
+cors:
+  allowed_origins:
+    - "*.example.com"
+rate_limit:
+  requests_per_second: 2.5
+  burst: 10
+debug: true
+`), "")
+	assert.NoError(err)
+	assert.Equal(expected, config)
+}
+
+// Ensures that Load reports unknown settings and invalid values.
+func TestLoadFileErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Load(writeFile(t, "server.json", `{"address": ":8080", "adress": ":80"}`), "")
+	assert.Error(err)
+
+	_, err = Load(writeFile(t, "server.yml", "address: \":8080\"\ntimeouts:\n  read: 5 seconds\n"), "")
+	assert.Error(err)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"), "")
+	assert.Error(err)
+}
+
+// Ensures that environment variables override the settings of the file.
+func TestLoadEnv(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("TEST_ADDRESS", ":9090")
+	t.Setenv("TEST_TIMEOUTS_WRITE", "10s")
+	t.Setenv("TEST_CORS_ALLOWED_ORIGINS", "a.example.com, b.example.com")
+	t.Setenv("TEST_RATE_LIMIT_BURST", "3")
+	t.Setenv("TEST_DEBUG", "false")
+
+	config, err := Load(writeFile(t, "server.json", `{"address": ":8080", "debug": true}`), "TEST_")
+	assert.NoError(err)
+	assert.Equal(":9090", config.Address)
+	assert.Equal(Duration(10*time.Second), config.Timeouts.Write)
+	assert.Equal([]string{"a.example.com", "b.example.com"}, config.CORS.AllowedOrigins)
+	assert.Equal(3, config.RateLimit.Burst)
+	assert.False(config.Debug)
+
+	t.Setenv("TEST_RATE_LIMIT_BURST", "many")
+	_, err = Load("", "TEST_")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "TEST_RATE_LIMIT_BURST")
+	}
+}
+
+// Ensures that Validate rejects invalid settings.
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&Config{Address: ":8080"}).Validate())
+
+	invalid := map[string]*Config{
+		"address is required":                   {},
+		"tls.cert_file and tls.key_file":        {Address: ":8080", TLS: TLS{CertFile: "cert.pem"}},
+		"no such file":                          {Address: ":8080", TLS: TLS{CertFile: "missing.pem", KeyFile: "missing.key"}},
+		"timeouts.idle must not be negative":    {Address: ":8080", Timeouts: Timeouts{Idle: -1}},
+		"cors.allowed_origins":                  {Address: ":8080", CORS: CORS{AllowedOrigins: []string{""}}},
+		"rate_limit.requests_per_second":        {Address: ":8080", RateLimit: RateLimit{RequestsPerSecond: -1}},
+		"rate_limit.burst must not be negative": {Address: ":8080", RateLimit: RateLimit{Burst: -1}},
+		"trusted_proxies":                       {Address: ":8080", TrustedProxies: []string{"proxy"}},
+	}
+	for message, config := range invalid {
+		err := config.Validate()
+		if assert.Error(err, message) {
+			assert.Contains(err.Error(), message)
+		}
+	}
+}
+
+// Ensures that the Configuration and Middleware of a Config apply its settings.
+func TestConfigurationMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	config := &Config{
+		Address:   ":8080",
+		Timeouts:  Timeouts{Read: Duration(time.Second), Request: Duration(2 * time.Second)},
+		CORS:      CORS{AllowedOrigins: []string{"*"}},
+		RateLimit: RateLimit{RequestsPerSecond: 1},
+	}
+
+	configuration := config.Configuration()
+	assert.False(configuration.Debug)
+	assert.Equal(2*time.Second, configuration.RequestTimeout)
+	assert.Equal(time.Second, configuration.Server.ReadTimeout)
+
+	middleware := config.Middleware()
+	assert.Len(middleware, 2)
+	request := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", "http://example.com")
+		for _, m := range middleware {
+			if err := m(httptest.NewRecorder(), req); err != nil {
+				return err.Code
+			}
+		}
+		return http.StatusOK
+	}
+	assert.Equal(http.StatusOK, request())
+	assert.Equal(http.StatusTooManyRequests, request())
+
+	assert.Empty((&Config{}).Middleware())
+}
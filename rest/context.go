@@ -18,11 +18,15 @@ package rest
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	gcontext "github.com/gorilla/context"
 	"github.com/gorilla/mux"
@@ -45,10 +49,39 @@ const (
 	// limitKey is the name of the query string variable for the results limit.
 	limitKey = "limit"
 
+	// offsetKey is the name of the query string variable for the results offset.
+	offsetKey = "offset"
+
+	// defaultLimit is the results limit used when one isn't specified.
+	defaultLimit = 100
+
+	// maxBodyPrealloc is the largest Content-Length for which the request body buffer is
+	// allocated up front.
+	maxBodyPrealloc = 1 << 20
+
 	requestKey int = iota
 	statusKey
 	errorKey
 	resultKey
+	responseStatusKey
+	nextCursorKey
+	totalKey
+	principalKey
+	localesKey
+	requestIDKey
+	multipartFormKey
+	linksKey
+	typedPayloadKey
+	expectedETagKey
+	routeStatsKey
+	listFilterKey
+	sortFieldsKey
+	metaKey
+	highlightsKey
+	timeRangeKey
+	geoCircleKey
+	geoBoxKey
+	viewFieldsKey
 )
 
 // RequestContext contains the context information for the current HTTP request. It's a wrapper
@@ -93,10 +126,24 @@ type RequestContext interface {
 	// there isn't one.
 	ResourceID() string
 
+	// PathVar returns the value of the named URL path variable, e.g. "company" for a
+	// route defined as /api/v{version}/{company}/resources, defaulting to an empty
+	// string if there isn't one. Unlike Value, query string parameters are never
+	// returned.
+	PathVar(string) string
+
+	// PathVars returns all of the URL path variables for the request.
+	PathVars() RouteVars
+
 	// Version returns the API version for the request, defaulting to an empty string if
 	// one is not specified in the request path.
 	Version() string
 
+	// RemainingTime returns the time left before the request's deadline and true, or 0
+	// and false if the request has no deadline. This can be used to budget calls to
+	// downstream services.
+	RemainingTime() (time.Duration, bool)
+
 	// Status returns the current HTTP status code that will be returned for the request,
 	// defaulting to 200 if one hasn't been set yet.
 	Status() int
@@ -104,6 +151,11 @@ type RequestContext interface {
 	// setStatus sets the HTTP status code to be returned for the request.
 	setStatus(int) RequestContext
 
+	// SetResponseStatus overrides the HTTP status code returned for a successful
+	// request, e.g. 202 Accepted for work which will be completed asynchronously.
+	// It has no effect on error responses.
+	SetResponseStatus(int)
+
 	// Error returns the current error for the request or nil if no errors have been set.
 	Error() error
 
@@ -120,12 +172,62 @@ type RequestContext interface {
 	// string if one hasn't been set.
 	Cursor() string
 
-	// setCursor sets the current result cursor for the request.
-	setCursor(string) RequestContext
+	// NextCursor returns the cursor for the next page of results, defaulting to an
+	// empty string if there isn't one.
+	NextCursor() string
+
+	// SetNextCursor sets the cursor for the next page of results. It's used to build
+	// the next URL included in the response.
+	SetNextCursor(string)
 
 	// Limit returns the maximum number of results that should be fetched.
 	Limit() int
 
+	// Offset returns the number of results to skip, defaulting to 0 if one is not
+	// specified using the "offset" query parameter.
+	Offset() int
+
+	// ListFilter returns the filter parsed from the "filter" query parameter of a list
+	// request, or nil if there isn't one.
+	ListFilter() *FilterNode
+
+	// SortFields returns the fields, in order of precedence, given by the "sort" query
+	// parameter of a list request, or nil if there isn't one.
+	SortFields() []SortField
+
+	// TimeRange returns the range of times given by the "from" and "to" query
+	// parameters of a list request, which is zero if neither is given.
+	TimeRange() TimeRange
+
+	// Near returns the circle given by the "near" and "radius" query parameters of a
+	// list request, or nil if there isn't one.
+	Near() *GeoCircle
+
+	// BoundingBox returns the box given by the "bbox" query parameter of a list
+	// request, or nil if there isn't one.
+	BoundingBox() *GeoBox
+
+	// SearchQuery returns the query given by the "q" query parameter of a search
+	// request, or an empty string if there isn't one.
+	SearchQuery() string
+
+	// Highlights returns the fragments of search results matching the query, keyed by
+	// the result's ID and then field, to be included in the response.
+	Highlights() map[string]map[string][]string
+
+	// AddHighlight adds fragments of the field of the search result with the given ID
+	// which matched the query, e.g. "<em>red</em> shoes", to be included in the
+	// "highlights" of the response.
+	AddHighlight(id, field string, fragments ...string)
+
+	// Total returns the total number of results available and true if the request
+	// handler has set one, otherwise 0 and false.
+	Total() (int, bool)
+
+	// SetTotal sets the total number of results available to be included in the
+	// response.
+	SetTotal(int)
+
 	// Messages returns all of the messages set by the request handler to be included in
 	// the response.
 	Messages() []string
@@ -133,14 +235,133 @@ type RequestContext interface {
 	// AddMessage adds a message to the request messages to be included in the response.
 	AddMessage(string)
 
+	// Warnings returns all of the warnings set by the request handler.
+	Warnings() []string
+
+	// AddWarning adds a non-fatal notice, e.g. "field 'foo' is deprecated", to the
+	// request messages to be included in the response. Warnings are also sent using
+	// the Warning response header.
+	AddWarning(string)
+
+	// Links returns the links to related resources to be included in the response,
+	// keyed by relation type.
+	Links() map[string]string
+
+	// AddLink adds a link to a related resource, identified by its relation type, e.g.
+	// "owner", to be included in the "links" of the response. Adding a link with the
+	// same relation type as an existing one replaces it.
+	AddLink(rel, href string)
+
 	// Header returns the header key-value pairs for the request.
 	Header() http.Header
 
-	// Body returns a buffer containing the raw body of the request.
+	// IfMatch returns the entity tags of the If-Match header, or an empty slice if
+	// there are none.
+	IfMatch() []string
+
+	// IfNoneMatch returns the entity tags of the If-None-Match header, or an empty
+	// slice if there are none.
+	IfNoneMatch() []string
+
+	// IfModifiedSince returns the time of the If-Modified-Since header and true, or
+	// the zero time and false if it's missing or malformed.
+	IfModifiedSince() (time.Time, bool)
+
+	// IfUnmodifiedSince returns the time of the If-Unmodified-Since header and true,
+	// or the zero time and false if it's missing or malformed.
+	IfUnmodifiedSince() (time.Time, bool)
+
+	// CheckPreconditions evaluates the request's conditional headers against the
+	// current entity tag and modification time of the resource, either of which may
+	// be empty. It returns nil if the request should be performed, otherwise a
+	// NotModified or PreconditionFailed Error which should be returned by the
+	// ResourceHandler. The ETag and Last-Modified response headers are set.
+	CheckPreconditions(etag string, lastModified time.Time) error
+
+	// ExpectedETag returns the entity tag of the resource which the If-Match header of
+	// an update or delete request was verified against, if the ResourceHandler is an
+	// ETagResourceHandler. ResourceHandlers should only apply the change if the
+	// resource still has this entity tag, e.g. using a conditional write, since it may
+	// have been modified since. An empty string is returned if there's no If-Match
+	// header.
+	ExpectedETag() string
+
+	// ClientIP returns the IP address of the client which made the request. Forwarding
+	// headers are only honored for requests received from the API's configured
+	// TrustedProxies.
+	ClientIP() string
+
+	// Locales returns the caller's preferred locales parsed from the Accept-Language
+	// header, ordered by preference. An empty slice is returned if none were specified.
+	Locales() []string
+
+	// Locale returns the best match for the caller's preferred locales from the
+	// available locales using RFC 4647 lookup. If none match, the first available
+	// locale is returned, or an empty string if none are provided.
+	Locale(available ...string) string
+
+	// Body returns a buffer containing the raw body of the request. It's empty if the
+	// body is streamed by a StreamingPayloadHandler.
 	Body() *bytes.Buffer
 
+	// PayloadDecoder returns a json.Decoder reading the body of a request streamed by a
+	// StreamingPayloadHandler. Reads fail with a 413 Error once the StreamLimits are
+	// exceeded. An error is returned if the body isn't streamed or has already been
+	// read.
+	PayloadDecoder() (*json.Decoder, error)
+
+	// PayloadItems decodes the body of a request streamed by a StreamingPayloadHandler,
+	// either a JSON array of objects or a single object, sending each item with the
+	// inbound Rules applied on the returned channel. The channel is closed once the body
+	// has been read, after which the error channel receives nil or the Error to
+	// respond with, e.g. if the body is malformed or exceeds the StreamLimits.
+	// Decoding stops if the RequestContext is canceled.
+	PayloadItems() (<-chan Payload, <-chan error)
+
+	// TypedPayload returns the request payload decoded into the type returned by the
+	// NewPayload method of a TypedPayloadHandler, or a []interface{} of them for
+	// list updates. If the ResourceHandler isn't a TypedPayloadHandler, nil is
+	// returned.
+	TypedPayload() interface{}
+
+	// MultipartForm returns the parsed multipart/form-data body of the request. If the
+	// request isn't multipart or the body is malformed, nil is returned with an
+	// error. Any temporary files are removed once the response has been sent.
+	MultipartForm() (*multipart.Form, error)
+
+	// Files returns the files uploaded with a multipart/form-data request ordered by
+	// form field name. If the request isn't multipart, nil is returned with an error.
+	Files() ([]*UploadedFile, error)
+
+	// File returns the first file uploaded in the given form field. If there is no
+	// such file, nil is returned with an error.
+	File(string) (*UploadedFile, error)
+
 	// ResponseWriter Access to Response Writer Interface to allow for setting Response Header values
 	ResponseWriter() http.ResponseWriter
+
+	// ResponseHeader returns the header key-value pairs which will be sent with the
+	// response. Handlers can use this to set headers such as Location.
+	ResponseHeader() http.Header
+
+	// RequestID returns the ID used to correlate the request. The client-provided
+	// X-Request-ID header is used if it's valid, otherwise an ID is generated.
+	RequestID() string
+
+	// ResourceName returns the name of the resource the request is for, defaulting to
+	// an empty string if the request isn't handled by a ResourceHandler.
+	ResourceName() string
+
+	// Logger returns a logger which tags each line with the request ID, resource
+	// name, version and Principal ID so that log lines can be correlated.
+	Logger() StdLogger
+
+	// Principal returns the authenticated Principal for the request and true, or nil
+	// and false if the request hasn't been authenticated.
+	Principal() (*Principal, bool)
+
+	// SetPrincipal sets the authenticated Principal for the request.
+	SetPrincipal(*Principal)
 }
 
 // gorillaRequestContext is an implementation of the RequestContext interface. It wraps
@@ -151,13 +372,27 @@ type gorillaRequestContext struct {
 	req      *http.Request
 	body     *bytes.Buffer
 	writer   http.ResponseWriter
-	router   *mux.Router
+	urls     urlBuilder
+	stream   *payloadStream
 	messages []string
+	warnings []string
+
+	trustedProxies []*net.IPNet
+	resourceName   string
+	logger         StdLogger
 }
 
 // NewContext returns a RequestContext populated with parameters from the request path and
 // query string.
 func NewContext(parent context.Context, req *http.Request, writer http.ResponseWriter) RequestContext {
+	return newRequestContext(parent, req, writer, readRequestBody(req))
+}
+
+// newRequestContext returns a RequestContext populated with parameters from the
+// request path and query string with the given request body.
+func newRequestContext(parent context.Context, req *http.Request, writer http.ResponseWriter,
+	body *bytes.Buffer) RequestContext {
+
 	if parent == nil {
 		parent = context.Background()
 	}
@@ -176,30 +411,49 @@ func NewContext(parent context.Context, req *http.Request, writer http.ResponseW
 		gcontext.Set(req, key, val)
 	}
 
-	for key, value := range mux.Vars(req) {
+	for key, value := range PathVars(req) {
 		gcontext.Set(req, key, value)
 	}
 
-	var body []byte
-	if req.Body != nil {
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err == nil {
-			body = bytes
-		}
-	}
-
 	// TODO: Keys can potentially be overwritten if the request path has
 	// parameters with the same name as query string values. Figure out a
 	// better way to handle this.
 
-	return &gorillaRequestContext{parent, req, bytes.NewBuffer(body), writer, nil, []string{}}
+	return &gorillaRequestContext{
+		Context:  parent,
+		req:      req,
+		body:     body,
+		writer:   writer,
+		messages: []string{},
+		warnings: []string{},
+	}
 }
 
+// readRequestBody reads the body of the request into a buffer. If reading fails, the
+// buffer is empty.
+func readRequestBody(req *http.Request) *bytes.Buffer {
+	body := &bytes.Buffer{}
+	if req.Body != nil {
+		// Size the buffer up front when the length is known to avoid growing it.
+		if req.ContentLength > 0 && req.ContentLength <= maxBodyPrealloc {
+			body.Grow(int(req.ContentLength))
+		}
+		if _, err := body.ReadFrom(req.Body); err != nil {
+			body.Reset()
+		}
+	}
+	return body
+}
+
+// NewContextWithRouter returns a RequestContext populated with parameters from the
+// request path and query string which builds URLs using the gorilla/mux Router.
 func NewContextWithRouter(parent context.Context, req *http.Request, writer http.ResponseWriter,
 	router *mux.Router) RequestContext {
 
 	context := NewContext(parent, req, writer)
-	context.(*gorillaRequestContext).router = router
+	if router != nil {
+		context.(*gorillaRequestContext).urls = &muxRouter{router}
+	}
 	return context
 }
 
@@ -207,7 +461,10 @@ func NewContextWithRouter(parent context.Context, req *http.Request, writer http
 // as the parent.
 func (ctx *gorillaRequestContext) WithValue(key, value interface{}) RequestContext {
 	if r, ok := ctx.Request(); ok {
-		return &gorillaRequestContext{context.WithValue(ctx, key, value), r, ctx.body, ctx.writer, ctx.router, ctx.messages}
+		child := *ctx
+		child.Context = context.WithValue(ctx, key, value)
+		child.req = r
+		return &child
 	}
 
 	// Should not reach this.
@@ -248,18 +505,58 @@ func (ctx *gorillaRequestContext) ResourceID() string {
 	return ctx.ValueWithDefault(resourceIDKey, "").(string)
 }
 
+// PathVar returns the value of the named URL path variable, defaulting to an empty
+// string if there isn't one.
+func (ctx *gorillaRequestContext) PathVar(name string) string {
+	return PathVars(ctx.req)[name]
+}
+
+// PathVars returns all of the URL path variables for the request.
+func (ctx *gorillaRequestContext) PathVars() RouteVars {
+	vars := RouteVars{}
+	for key, value := range PathVars(ctx.req) {
+		vars[key] = value
+	}
+	return vars
+}
+
 // Version returns the API version for the request, defaulting to an empty string
 // if one is not specified in the request path.
 func (ctx *gorillaRequestContext) Version() string {
 	return ctx.ValueWithDefault(versionKey, "").(string)
 }
 
+// RemainingTime returns the time left before the request's deadline and true, or 0 and
+// false if the request has no deadline.
+func (ctx *gorillaRequestContext) RemainingTime() (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	remaining := deadline.Sub(time.Now())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
 // Status returns the current HTTP status code that will be returned for the request,
-// defaulting to 200 if one hasn't been set yet.
+// defaulting to 200 if one hasn't been set yet. A status set by the request handler
+// using SetResponseStatus takes precedence.
 func (ctx *gorillaRequestContext) Status() int {
+	if status, ok := ctx.Value(responseStatusKey).(int); ok {
+		return status
+	}
 	return ctx.ValueWithDefault(statusKey, http.StatusOK).(int)
 }
 
+// SetResponseStatus overrides the HTTP status code returned for a successful request.
+// The status is stored on the request so that it's visible to every context derived
+// from it, including the one used by the framework to build the response.
+func (ctx *gorillaRequestContext) SetResponseStatus(status int) {
+	gcontext.Set(ctx.req, responseStatusKey, status)
+}
+
 // setStatus sets the HTTP status code to be returned for the request.
 func (ctx *gorillaRequestContext) setStatus(status int) RequestContext {
 	return ctx.WithValue(statusKey, status)
@@ -297,9 +594,28 @@ func (ctx *gorillaRequestContext) Cursor() string {
 	return ctx.ValueWithDefault(cursorKey, "").(string)
 }
 
-// setCursor sets the current result cursor for the request.
-func (ctx *gorillaRequestContext) setCursor(cursor string) RequestContext {
-	return ctx.WithValue(cursorKey, cursor)
+// NextCursor returns the cursor for the next page of results, defaulting to an empty
+// string if there isn't one.
+func (ctx *gorillaRequestContext) NextCursor() string {
+	return ctx.ValueWithDefault(nextCursorKey, "").(string)
+}
+
+// SetNextCursor sets the cursor for the next page of results.
+func (ctx *gorillaRequestContext) SetNextCursor(cursor string) {
+	gcontext.Set(ctx.req, nextCursorKey, cursor)
+}
+
+// Total returns the total number of results available and true if the request
+// handler has set one, otherwise 0 and false.
+func (ctx *gorillaRequestContext) Total() (int, bool) {
+	total, ok := ctx.Value(totalKey).(int)
+	return total, ok
+}
+
+// SetTotal sets the total number of results available to be included in the
+// response.
+func (ctx *gorillaRequestContext) SetTotal(total int) {
+	gcontext.Set(ctx.req, totalKey, total)
 }
 
 // Header returns the header key-value pairs for the request.
@@ -312,11 +628,134 @@ func (ctx *gorillaRequestContext) Header() http.Header {
 	return req.Header
 }
 
+// IfMatch returns the entity tags of the If-Match header.
+func (ctx *gorillaRequestContext) IfMatch() []string {
+	return parseETags(ctx.Header().Get("If-Match"))
+}
+
+// IfNoneMatch returns the entity tags of the If-None-Match header.
+func (ctx *gorillaRequestContext) IfNoneMatch() []string {
+	return parseETags(ctx.Header().Get("If-None-Match"))
+}
+
+// IfModifiedSince returns the time of the If-Modified-Since header and true, or the
+// zero time and false if it's missing or malformed.
+func (ctx *gorillaRequestContext) IfModifiedSince() (time.Time, bool) {
+	return parseHTTPDate(ctx.Header().Get("If-Modified-Since"))
+}
+
+// IfUnmodifiedSince returns the time of the If-Unmodified-Since header and true, or
+// the zero time and false if it's missing or malformed.
+func (ctx *gorillaRequestContext) IfUnmodifiedSince() (time.Time, bool) {
+	return parseHTTPDate(ctx.Header().Get("If-Unmodified-Since"))
+}
+
+// CheckPreconditions evaluates the request's conditional headers against the current
+// entity tag and modification time of the resource.
+func (ctx *gorillaRequestContext) CheckPreconditions(etag string, lastModified time.Time) error {
+	if etag != "" {
+		ctx.ResponseHeader().Set("ETag", formatETag(etag))
+	}
+	if !lastModified.IsZero() {
+		ctx.ResponseHeader().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	switch evaluatePreconditions(ctx.req, etag, lastModified) {
+	case http.StatusNotModified:
+		return NotModified("Not Modified")
+	case http.StatusPreconditionFailed:
+		return PreconditionFailed("Precondition Failed")
+	}
+	return nil
+}
+
+// ExpectedETag returns the entity tag which the If-Match header was verified against.
+func (ctx *gorillaRequestContext) ExpectedETag() string {
+	etag, _ := ctx.Value(expectedETagKey).(string)
+	return etag
+}
+
+// ClientIP returns the IP address of the client which made the request.
+func (ctx *gorillaRequestContext) ClientIP() string {
+	return ClientIP(ctx.req, ctx.trustedProxies)
+}
+
+// Locales returns the caller's preferred locales parsed from the Accept-Language
+// header, ordered by preference.
+func (ctx *gorillaRequestContext) Locales() []string {
+	if locales, ok := ctx.Value(localesKey).([]string); ok {
+		return locales
+	}
+
+	locales := parseAcceptLanguage(ctx.Header().Get(acceptLanguageHeader))
+	gcontext.Set(ctx.req, localesKey, locales)
+	return locales
+}
+
+// Locale returns the best match for the caller's preferred locales from the available
+// locales, falling back to the first available locale.
+func (ctx *gorillaRequestContext) Locale(available ...string) string {
+	fallback := ""
+	if len(available) > 0 {
+		fallback = available[0]
+	}
+	return LookupLocale(ctx.Locales(), available, fallback)
+}
+
 // Body returns a buffer containing the raw body of the request.
 func (ctx *gorillaRequestContext) Body() *bytes.Buffer {
 	return ctx.body
 }
 
+// TypedPayload returns the request payload decoded into the type returned by the
+// NewPayload method of a TypedPayloadHandler, or nil if there isn't one.
+func (ctx *gorillaRequestContext) TypedPayload() interface{} {
+	return ctx.Value(typedPayloadKey)
+}
+
+// MultipartForm returns the parsed multipart/form-data body of the request.
+func (ctx *gorillaRequestContext) MultipartForm() (*multipart.Form, error) {
+	if form, ok := ctx.Value(multipartFormKey).(*multipart.Form); ok {
+		return form, nil
+	}
+
+	boundary, ok := isMultipart(ctx.Header().Get("Content-Type"))
+	if !ok {
+		return nil, fmt.Errorf("Request is not multipart/form-data")
+	}
+
+	form, err := parseMultipartForm(ctx.body.Bytes(), boundary)
+	if err != nil {
+		return nil, err
+	}
+	gcontext.Set(ctx.req, multipartFormKey, form)
+	return form, nil
+}
+
+// Files returns the files uploaded with a multipart/form-data request ordered by form
+// field name.
+func (ctx *gorillaRequestContext) Files() ([]*UploadedFile, error) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	return uploadedFiles(form), nil
+}
+
+// File returns the first file uploaded in the given form field.
+func (ctx *gorillaRequestContext) File(field string) (*UploadedFile, error) {
+	files, err := ctx.Files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.Field == field {
+			return file, nil
+		}
+	}
+	return nil, fmt.Errorf("No file with field '%s'", field)
+}
+
 // Request returns the *http.Request associated with context using NewContext, if any.
 func (ctx *gorillaRequestContext) Request() (*http.Request, bool) {
 	// We cannot use ctx.(*gorillaRequestContext).req to get the request because ctx may
@@ -328,19 +767,101 @@ func (ctx *gorillaRequestContext) Request() (*http.Request, bool) {
 
 // Limit returns the maximum number of results that should be fetched.
 func (ctx *gorillaRequestContext) Limit() int {
-	limitStr := ctx.ValueWithDefault(limitKey, "100")
-	limit, err := strconv.Atoi(limitStr.(string))
+	return ctx.intValue(limitKey, defaultLimit)
+}
+
+// Offset returns the number of results to skip, defaulting to 0 if one is not
+// specified using the "offset" query parameter or it's negative.
+func (ctx *gorillaRequestContext) Offset() int {
+	offset := ctx.intValue(offsetKey, 0)
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// ListFilter returns the filter parsed from the "filter" query parameter of a list
+// request, or nil if there isn't one.
+func (ctx *gorillaRequestContext) ListFilter() *FilterNode {
+	filter, _ := ctx.Value(listFilterKey).(*FilterNode)
+	return filter
+}
+
+// SortFields returns the fields, in order of precedence, given by the "sort" query
+// parameter of a list request, or nil if there isn't one.
+func (ctx *gorillaRequestContext) SortFields() []SortField {
+	fields, _ := ctx.Value(sortFieldsKey).([]SortField)
+	return fields
+}
+
+// TimeRange returns the range of times given by the "from" and "to" query
+// parameters of a list request, which is zero if neither is given.
+func (ctx *gorillaRequestContext) TimeRange() TimeRange {
+	r, _ := ctx.Value(timeRangeKey).(TimeRange)
+	return r
+}
+
+// Near returns the circle given by the "near" and "radius" query parameters of a
+// list request, or nil if there isn't one.
+func (ctx *gorillaRequestContext) Near() *GeoCircle {
+	circle, _ := ctx.Value(geoCircleKey).(*GeoCircle)
+	return circle
+}
+
+// BoundingBox returns the box given by the "bbox" query parameter of a list request,
+// or nil if there isn't one.
+func (ctx *gorillaRequestContext) BoundingBox() *GeoBox {
+	box, _ := ctx.Value(geoBoxKey).(*GeoBox)
+	return box
+}
+
+// SearchQuery returns the query given by the "q" query parameter of a search
+// request, or an empty string if there isn't one.
+func (ctx *gorillaRequestContext) SearchQuery() string {
+	query, _ := ctx.Value(searchKey).(string)
+	return strings.TrimSpace(query)
+}
+
+// Highlights returns the fragments of search results matching the query, keyed by
+// the result's ID and then field, to be included in the response.
+func (ctx *gorillaRequestContext) Highlights() map[string]map[string][]string {
+	highlights, _ := ctx.Value(highlightsKey).(map[string]map[string][]string)
+	return highlights
+}
+
+// AddHighlight adds fragments of the field of the search result with the given ID
+// which matched the query to be included in the "highlights" of the response.
+func (ctx *gorillaRequestContext) AddHighlight(id, field string, fragments ...string) {
+	highlights := ctx.Highlights()
+	if highlights == nil {
+		highlights = map[string]map[string][]string{}
+		gcontext.Set(ctx.req, highlightsKey, highlights)
+	}
+	if highlights[id] == nil {
+		highlights[id] = map[string][]string{}
+	}
+	highlights[id][field] = append(highlights[id][field], fragments...)
+}
+
+// intValue returns the context value for the given key parsed as an int. If there's
+// no such value or it's not a valid int, the provided default is returned.
+func (ctx *gorillaRequestContext) intValue(key interface{}, defaultVal int) int {
+	str, ok := ctx.Value(key).(string)
+	if !ok {
+		return defaultVal
+	}
+	val, err := strconv.Atoi(str)
 	if err != nil {
-		limit = 100
+		return defaultVal
 	}
-	return limit
+	return val
 }
 
 // NextURL returns the URL to use to request the next page of results using the current
 // cursor. If there is no cursor for this request or the URL fails to be built, an empty
 // string is returned with the error set.
 func (ctx *gorillaRequestContext) NextURL() (string, error) {
-	cursor := ctx.Cursor()
+	cursor := ctx.NextCursor()
 	if cursor == "" {
 		return "", fmt.Errorf("Unable to build next url: no cursor")
 	}
@@ -363,7 +884,7 @@ func (ctx *gorillaRequestContext) NextURL() (string, error) {
 	}
 
 	q := u.Query()
-	q.Set("next", cursor)
+	q.Set(cursorKey, cursor)
 	u.RawQuery = q.Encode()
 	return u.String(), nil
 }
@@ -392,16 +913,17 @@ func (ctx *gorillaRequestContext) BuildURL(resourceName string,
 			resourceName)
 	}
 
-	routeName := resourceName + ":" + string(method)
-	route := ctx.router.Get(routeName)
+	if ctx.urls == nil {
+		return nil, fmt.Errorf("unable to build URL for resource name %q: no router available",
+			resourceName)
+	}
 
-	// Transform RouteVars map to list of key, val pairs for Gorilla's API
-	pairs := make([]string, (len(vars)*2)+2)
+	routeVars := RouteVars{}
 	for key, val := range vars {
-		pairs = append(pairs, key, val)
+		routeVars[key] = val
 	}
-	pairs = append(pairs, "version", ctx.Version())
-	url, err := route.URL(pairs...)
+	routeVars[versionKey] = ctx.Version()
+	url, err := ctx.urls.buildURL(resourceName+":"+string(method), routeVars)
 	if err != nil {
 		return nil, err
 	}
@@ -430,6 +952,109 @@ func (ctx *gorillaRequestContext) AddMessage(message string) {
 	ctx.messages = append(ctx.messages, message)
 }
 
+// Links returns the links to related resources to be included in the response,
+// keyed by relation type.
+func (ctx *gorillaRequestContext) Links() map[string]string {
+	links, _ := ctx.Value(linksKey).(map[string]string)
+	return links
+}
+
+// AddLink adds a link to a related resource, identified by its relation type, to be
+// included in the "links" of the response.
+func (ctx *gorillaRequestContext) AddLink(rel, href string) {
+	links := ctx.Links()
+	if links == nil {
+		links = map[string]string{}
+		gcontext.Set(ctx.req, linksKey, links)
+	}
+	links[rel] = href
+}
+
+// Warnings returns all of the warnings set by the request handler.
+func (ctx *gorillaRequestContext) Warnings() []string {
+	return ctx.warnings
+}
+
+// AddWarning adds a non-fatal notice to the request messages to be included in the
+// response.
+func (ctx *gorillaRequestContext) AddWarning(warning string) {
+	ctx.warnings = append(ctx.warnings, warning)
+	ctx.AddMessage(warning)
+}
+
 func (ctx *gorillaRequestContext) ResponseWriter() http.ResponseWriter {
 	return ctx.writer
 }
+
+// ResponseHeader returns the header key-value pairs which will be sent with the
+// response.
+func (ctx *gorillaRequestContext) ResponseHeader() http.Header {
+	if ctx.writer == nil {
+		return http.Header{}
+	}
+	return ctx.writer.Header()
+}
+
+// Principal returns the authenticated Principal for the request and true, or nil and
+// false if the request hasn't been authenticated.
+func (ctx *gorillaRequestContext) Principal() (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// SetPrincipal sets the authenticated Principal for the request.
+func (ctx *gorillaRequestContext) SetPrincipal(p *Principal) {
+	SetRequestPrincipal(ctx.req, p)
+}
+
+// RequestID returns the ID used to correlate the request.
+func (ctx *gorillaRequestContext) RequestID() string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+
+	id := ctx.Header().Get(requestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	gcontext.Set(ctx.req, requestIDKey, id)
+	return id
+}
+
+// ResourceName returns the name of the resource the request is for.
+func (ctx *gorillaRequestContext) ResourceName() string {
+	return ctx.resourceName
+}
+
+// Logger returns a logger which tags each line with the request ID, resource name,
+// version and Principal ID.
+func (ctx *gorillaRequestContext) Logger() StdLogger {
+	principal := ""
+	if p, ok := ctx.Principal(); ok {
+		principal = p.ID
+	}
+
+	return newTaggedLogger(ctx.logger,
+		"request_id", ctx.RequestID(),
+		"resource", ctx.resourceName,
+		"version", ctx.Version(),
+		"principal", principal,
+	)
+}
+
+// PayloadDecoder returns a json.Decoder reading the streamed body of the request.
+func (ctx *gorillaRequestContext) PayloadDecoder() (*json.Decoder, error) {
+	return ctx.stream.decoder()
+}
+
+// PayloadItems decodes the streamed body of the request, sending each item on the
+// returned channel.
+func (ctx *gorillaRequestContext) PayloadItems() (<-chan Payload, <-chan error) {
+	if ctx.stream == nil {
+		items, errc := make(chan Payload), make(chan error, 1)
+		close(items)
+		errc <- errNotStreamed
+		return items, errc
+	}
+	return ctx.stream.items(ctx.Done())
+}
@@ -172,7 +172,7 @@ func TestBuildURL(t *testing.T) {
 	gContext.Set(req, "version", "1")
 
 	writer := httptest.NewRecorder()
-	ctx := NewContextWithRouter(nil, req, writer, api.(*muxAPI).router)
+	ctx := NewContextWithRouter(nil, req, writer, api.(*muxAPI).router.(*muxRouter).router)
 
 	url, _ := ctx.BuildURL("widgets", HandleCreate, nil)
 	assert.Equal(url.String(), "http://example.com/api/v1/widgets")
@@ -195,3 +195,126 @@ func TestBuildURL(t *testing.T) {
 		"category": "anvils"})
 	assert.Equal(url.String(), "https://example.com/api/v2/acme/anvils/resources")
 }
+
+// Ensures that a status set with SetResponseStatus takes precedence over the default
+// status and is visible to derived contexts.
+func TestSetResponseStatus(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	assert.Equal(http.StatusOK, ctx.Status())
+
+	ctx.SetResponseStatus(http.StatusAccepted)
+	ctx = ctx.setStatus(http.StatusCreated)
+
+	assert.Equal(http.StatusAccepted, ctx.Status())
+}
+
+// Ensures that ResponseHeader returns the ResponseWriter's Header.
+func TestResponseHeader(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	ctx.ResponseHeader().Set("Location", "http://example.com/foo/1")
+
+	assert.Equal("http://example.com/foo/1", writer.Header().Get("Location"))
+}
+
+// Ensures that Offset returns the offset from the query string, defaulting to 0 if
+// it's missing, invalid or negative.
+func TestOffset(t *testing.T) {
+	assert := assert.New(t)
+	writer := httptest.NewRecorder()
+
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	assert.Equal(0, NewContext(nil, req, writer).Offset())
+
+	req, _ = http.NewRequest("GET", "http://example.com/foo?offset=20", nil)
+	assert.Equal(20, NewContext(nil, req, writer).Offset())
+
+	req, _ = http.NewRequest("GET", "http://example.com/foo?offset=blah", nil)
+	assert.Equal(0, NewContext(nil, req, writer).Offset())
+
+	req, _ = http.NewRequest("GET", "http://example.com/foo?offset=-5", nil)
+	assert.Equal(0, NewContext(nil, req, writer).Offset())
+}
+
+// Ensures that the next cursor is distinct from the request cursor and is used to
+// build the next URL.
+func TestSetNextCursor(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo?next=abc", nil)
+	req.RequestURI = "/foo?next=abc"
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	_, err := ctx.NextURL()
+	assert.NotNil(err)
+
+	ctx.SetNextCursor("def")
+
+	assert.Equal("abc", ctx.Cursor())
+	assert.Equal("def", ctx.NextCursor())
+	nextURL, err := ctx.NextURL()
+	assert.Nil(err)
+	assert.Equal("http://example.com/foo?next=def", nextURL)
+}
+
+// Ensures that Total returns false until a total is set.
+func TestSetTotal(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	_, ok := ctx.Total()
+	assert.False(ok)
+
+	ctx.SetTotal(42)
+
+	total, ok := ctx.Total()
+	assert.True(ok)
+	assert.Equal(42, total)
+}
+
+// Ensures that PathVar and PathVars return the URL path variables and not query
+// string parameters.
+func TestPathVars(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	writer := httptest.NewRecorder()
+
+	var ctx RequestContext
+	api.RegisterHandlerFunc("/api/v{version:[^/]+}/{company}/{category}/things",
+		func(w http.ResponseWriter, r *http.Request) {
+			ctx = NewContext(nil, r, w)
+		})
+	req, _ := http.NewRequest("POST", "http://example.com/api/v1/acme/anvils/things?company=evil", nil)
+	api.ServeHTTP(writer, req)
+
+	if assert.NotNil(ctx) {
+		assert.Equal("acme", ctx.PathVar("company"))
+		assert.Equal("anvils", ctx.PathVar("category"))
+		assert.Equal("", ctx.PathVar("missing"))
+		assert.Equal(RouteVars{"version": "1", "company": "acme", "category": "anvils"},
+			ctx.PathVars())
+	}
+}
+
+// Ensures that warnings are tracked separately and included in the messages.
+func TestAddWarning(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	ctx.AddMessage("foo")
+	ctx.AddWarning("field 'bar' is deprecated")
+
+	assert.Equal([]string{"field 'bar' is deprecated"}, ctx.Warnings())
+	assert.Equal([]string{"foo", "field 'bar' is deprecated"}, ctx.Messages())
+}
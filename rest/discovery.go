@@ -0,0 +1,86 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+)
+
+// discoveryURI is the URI of the API discovery root of each version.
+const discoveryURI = "/api/v{" + versionKey + ":[^/]+}/"
+
+// discoveryOperations are the names of the routes listed for each resource by the
+// discovery endpoint, in order.
+var discoveryOperations = []string{
+	string(HandleCreate),
+	string(HandleReadList),
+	string(HandleRead),
+	string(HandleUpdateList),
+	string(HandleUpdate),
+	string(HandleDelete),
+	"schema",
+}
+
+// handleDiscovery returns a Handler which lists the resources available in the
+// requested version along with their operations and URL templates, as registered
+// with the router. This allows generic clients and tooling to introspect the API.
+func (h requestHandler) handleDiscovery() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, "")
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		version := ctx.Version()
+
+		routes := map[string]Route{}
+		for _, route := range h.Routes() {
+			if route.Name != "" {
+				routes[route.Name] = route
+			}
+		}
+
+		resources := []interface{}{}
+		for _, handler := range h.ResourceHandlers() {
+			if valid := handler.ValidVersions(); valid != nil && !contains(valid, version) {
+				continue
+			}
+
+			operations := []interface{}{}
+			for _, name := range discoveryOperations {
+				route, ok := routes[handler.ResourceName()+":"+name]
+				if !ok {
+					continue
+				}
+				for _, method := range route.Methods {
+					operations = append(operations, map[string]interface{}{
+						"name":   name,
+						"method": method,
+						"href":   openAPIPath(route.Path, version),
+					})
+				}
+			}
+
+			resources = append(resources, map[string]interface{}{
+				"name":       handler.ResourceName(),
+				"operations": operations,
+			})
+		}
+
+		ctx = ctx.setResult(map[string]interface{}{"version": version, "resources": resources})
+		ctx = ctx.setStatus(http.StatusOK)
+		h.sendResponse(ctx)
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type versionedHandler struct {
+	BaseResourceHandler
+}
+
+func (v versionedHandler) ResourceName() string {
+	return "gadgets"
+}
+
+func (v versionedHandler) ValidVersions() []string {
+	return []string{"2"}
+}
+
+// getDiscovery returns the decoded discovery root of the version.
+func getDiscovery(t *testing.T, api API, version string) map[string]interface{} {
+	req, _ := http.NewRequest("GET", "http://example.com/api/v"+version+"/", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var payload map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &payload))
+	return payload["result"].(map[string]interface{})
+}
+
+// Ensures that the discovery root lists the resources of the version with their
+// operations and URL templates.
+func TestDiscovery(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(TestResourceHandler{})
+	api.RegisterResourceHandler(versionedHandler{})
+
+	result := getDiscovery(t, api, "1")
+	assert.Equal("1", result["version"])
+	resources := result["resources"].([]interface{})
+	if !assert.Len(resources, 1) {
+		return
+	}
+	widgets := resources[0].(map[string]interface{})
+	assert.Equal("widgets", widgets["name"])
+	assert.Equal([]interface{}{
+		map[string]interface{}{"name": "create", "method": "POST", "href": "/api/v1/widgets"},
+		map[string]interface{}{"name": "readList", "method": "GET", "href": "/api/v1/widgets"},
+		map[string]interface{}{"name": "read", "method": "GET", "href": "/api/v1/widgets/{resource_id}"},
+		map[string]interface{}{"name": "updateList", "method": "PUT", "href": "/api/v1/widgets"},
+		map[string]interface{}{"name": "update", "method": "PUT", "href": "/api/v1/widgets/{resource_id}"},
+		map[string]interface{}{"name": "delete", "method": "DELETE", "href": "/api/v1/widgets/{resource_id}"},
+		map[string]interface{}{"name": "schema", "method": "GET", "href": "/api/v1/widgets/schema"},
+	}, widgets["operations"])
+
+	resources = getDiscovery(t, api, "2")["resources"].([]interface{})
+	assert.Len(resources, 2)
+}
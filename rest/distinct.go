@@ -0,0 +1,144 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// distinctFieldKey is the name of the URL path variable for the field of a distinct
+// values request.
+const distinctFieldKey = "distinct_field"
+
+// DistinctValue is a unique value of a field and the number of resources having it.
+type DistinctValue struct {
+	// Value is the value of the field.
+	Value interface{} `json:"value"`
+
+	// Count is the number of resources whose field has the value.
+	Count int `json:"count"`
+}
+
+// DistinctValuer is implemented by ResourceHandlers listing the unique values of their
+// fields, e.g. to populate the options of filters. GET requests to
+// /api/v{version}/{resource}/distinct/{field} are passed to DistinctValues for fields
+// the Rules mark Filterable, with the list filter and time range available from the
+// RequestContext. The values are sent as the results, most common first, and then in
+// order of value, up to the limit.
+type DistinctValuer interface {
+	// DistinctValues returns the unique values of the field, as given by its Rule's
+	// Name, across the resources, and their counts.
+	DistinctValues(ctx RequestContext, field string, limit int,
+		version string) ([]DistinctValue, error)
+}
+
+// distinctValuer returns the DistinctValuer implemented by the ResourceHandler, if any.
+func distinctValuer(handler ResourceHandler) (DistinctValuer, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	d, ok := handler.(DistinctValuer)
+	return d, ok
+}
+
+// distinctURI returns the URI of the distinct values of the ResourceHandler's fields.
+func distinctURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/distinct/{" + distinctFieldKey + "}"
+}
+
+// CountDistinct counts the unique values of the field across resources in memory, for
+// DistinctValuers which don't count them in their data stores. Resources missing the
+// field aren't counted.
+func CountDistinct(field string, resources []map[string]interface{}) []DistinctValue {
+	counts := map[string]*DistinctValue{}
+	values := []DistinctValue{}
+	order := []string{}
+	for _, resource := range resources {
+		value, ok := resource[field]
+		if !ok || value == nil {
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		if count, ok := counts[string(encoded)]; ok {
+			count.Count++
+			continue
+		}
+		counts[string(encoded)] = &DistinctValue{Value: value, Count: 1}
+		order = append(order, string(encoded))
+	}
+	for _, key := range order {
+		values = append(values, *counts[key])
+	}
+	return values
+}
+
+// sortDistinctValues orders the values by descending count and then by value.
+func sortDistinctValues(values []DistinctValue) {
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		order, _ := compareFilterValues(values[i].Value, values[j].Value)
+		return order < 0
+	})
+}
+
+// handleDistinct returns a Handler passing distinct values requests for filterable
+// fields to the DistinctValuer and then serializing and dispatching the values.
+func (h requestHandler) handleDistinct(handler ResourceHandler, d DistinctValuer) http.Handler {
+	rules := handler.Rules()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		version := ctx.Version()
+
+		field := ctx.PathVars()[distinctFieldKey]
+		fields := filterableFields(rules, version)
+		if _, ok := fields[field]; !ok {
+			h.sendResponse(ctx.setError(BadRequest(fmt.Sprintf(
+				"Invalid field: %s has no distinct values; filterable fields are %s",
+				field, fieldNames(sortedFilterFields(fields))))))
+			return
+		}
+		ctx, err := listContext(ctx, rules, version)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		limit := ctx.Limit()
+		values, err := d.DistinctValues(ctx, field, limit, version)
+		if values == nil {
+			values = []DistinctValue{}
+		}
+		sortDistinctValues(values)
+		if limit > 0 && len(values) > limit {
+			values = values[:limit]
+		}
+
+		ctx = ctx.setResult(values)
+		ctx = ctx.setError(err)
+		ctx = ctx.setStatus(http.StatusOK)
+
+		h.sendResponse(ctx)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// distinctTasksResourceHandler is a tasksResourceHandler counting the distinct values
+// of tasks' fields in memory.
+type distinctTasksResourceHandler struct {
+	tasksResourceHandler
+}
+
+func (d distinctTasksResourceHandler) DistinctValues(ctx RequestContext, field string,
+	limit int, version string) ([]DistinctValue, error) {
+	tasks, _, err := d.ReadResourceList(ctx, limit, "", version)
+	resources := []map[string]interface{}{}
+	for _, task := range tasks {
+		resources = append(resources, task.(Payload))
+	}
+	return CountDistinct(field, resources), err
+}
+
+// Ensures that the distinct values of filterable fields are sent most common first and
+// then in order of value.
+func TestDistinctValues(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(distinctTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks/distinct/size", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{"value": 5.0, "count": 2.0},
+			map[string]interface{}{"value": 1.0, "count": 1.0},
+			map[string]interface{}{"value": 8.0, "count": 1.0},
+		}, envelope["results"])
+	}
+}
+
+// Ensures that distinct values are counted across the filtered resources, up to the
+// limit.
+func TestDistinctValuesFilterAndLimit(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(distinctTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET",
+		"/api/v1/tasks/distinct/status?filter=size>=5&limit=1", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{"value": "done", "count": 2.0},
+		}, envelope["results"])
+	}
+}
+
+// Ensures that distinct values of fields which aren't filterable are rejected.
+func TestDistinctValuesNotFilterable(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(distinctTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks/distinct/name", "")
+
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(envelope["messages"], "Invalid field: name has no distinct values; "+
+		"filterable fields are size, status")
+}
@@ -16,16 +16,34 @@ limitations under the License.
 
 package rest
 
-import "net/http"
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 // statusUnprocessableEntity indicates the request was well-formed but was
 // unable to be followed due to semantic errors.
 const statusUnprocessableEntity = 422
 
+// StatusError is implemented by errors which map to an HTTP status code. When a
+// ResourceHandler returns a StatusError, the response will have its status code
+// rather than 500 Internal Server Error.
+type StatusError interface {
+	error
+
+	// StatusCode returns the HTTP status code for the error.
+	StatusCode() int
+}
+
 // Error is an implementation of the error interface representing an HTTP error.
 type Error struct {
-	reason string
-	status int
+	reason     string
+	status     int
+	code       string
+	retryAfter time.Duration
 }
 
 // Error returns the Error message.
@@ -34,47 +52,189 @@ func (r Error) Error() string { return r.reason }
 // Status returns the HTTP status code.
 func (r Error) Status() int { return r.status }
 
+// StatusCode returns the HTTP status code. It implements the StatusError interface.
+func (r Error) StatusCode() int { return r.status }
+
+// Code returns the machine-readable error code, if any. It implements the CodedError
+// interface.
+func (r Error) Code() string { return r.code }
+
+// RetryAfter returns how long clients should wait before retrying the request. It
+// implements the RetryableError interface.
+func (r Error) RetryAfter() time.Duration { return r.retryAfter }
+
+// RetryableError is implemented by errors which are transient. When a ResourceHandler
+// returns a RetryableError with a positive RetryAfter, the response includes a
+// Retry-After header so clients know when to retry.
+type RetryableError interface {
+	error
+
+	// RetryAfter returns how long clients should wait before retrying the request.
+	RetryAfter() time.Duration
+}
+
+// retryAfterHeader returns the Retry-After header value, in whole seconds, for the
+// error. It returns false if the error isn't a RetryableError with a positive delay.
+func retryAfterHeader(err error) (string, bool) {
+	var retryable RetryableError
+	if !errors.As(err, &retryable) || retryable.RetryAfter() <= 0 {
+		return "", false
+	}
+	seconds := int64(math.Ceil(retryable.RetryAfter().Seconds()))
+	return strconv.FormatInt(seconds, 10), true
+}
+
+// Is reports whether the target is the sentinel error for the Error's status code,
+// e.g. errors.Is(NotFound("no such widget"), ErrNotFound) is true.
+func (r Error) Is(target error) bool {
+	s, ok := target.(*sentinelError)
+	return ok && s.byStatus && s.status == r.status
+}
+
+// sentinelError is the type of the sentinel errors exposed by the package. They can
+// be returned and wrapped by ResourceHandlers and compared using errors.Is.
+type sentinelError struct {
+	message  string
+	status   int
+	byStatus bool
+}
+
+// Error returns the sentinel error message.
+func (s *sentinelError) Error() string { return s.message }
+
+// StatusCode returns the HTTP status code. It implements the StatusError interface.
+func (s *sentinelError) StatusCode() int { return s.status }
+
+// Sentinel errors which ResourceHandlers can return or wrap, e.g. with
+// fmt.Errorf("user %s: %w", id, rest.ErrNotFound), and which framework errors can be
+// compared against using errors.Is.
+var (
+	ErrNotFound     error = &sentinelError{"Not found", http.StatusNotFound, true}
+	ErrUnauthorized error = &sentinelError{"Unauthorized", http.StatusUnauthorized, true}
+	ErrForbidden    error = &sentinelError{"Forbidden", http.StatusForbidden, true}
+	ErrConflict     error = &sentinelError{"Conflict", http.StatusConflict, true}
+
+	// ErrFormatNotImplemented is returned when a response format is requested for
+	// which no ResponseSerializer is registered.
+	ErrFormatNotImplemented error = &sentinelError{"Format not implemented", http.StatusBadRequest, false}
+)
+
+// errorStatus returns the HTTP status code for the error, using the first
+// StatusError in its chain. Errors which don't wrap a StatusError result in a 500
+// Internal Server Error.
+func errorStatus(err error) int {
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// NotFound returns a Error for a 404 Not Found error.
+func NotFound(reason string) Error {
+	return Error{reason: reason, status: http.StatusNotFound}
+}
+
+// Forbidden returns a Error for a 403 Forbidden error.
+func Forbidden(reason string) Error {
+	return Error{reason: reason, status: http.StatusForbidden}
+}
+
+// Conflict returns a Error for a 409 Conflict error.
+func Conflict(reason string) Error {
+	return Error{reason: reason, status: http.StatusConflict}
+}
+
+// UnprocessableEntity returns a Error for a 422 Unprocessable Entity error.
+func UnprocessableEntity(reason string) Error {
+	return Error{reason: reason, status: statusUnprocessableEntity}
+}
+
+// Unauthorized returns a Error for a 401 Unauthorized error.
+func Unauthorized(reason string) Error {
+	return Error{reason: reason, status: http.StatusUnauthorized}
+}
+
 // ResourceNotFound returns a Error for a 404 Not Found error.
 func ResourceNotFound(reason string) Error {
-	return Error{reason, http.StatusNotFound}
+	return Error{reason: reason, status: http.StatusNotFound}
 }
 
 // ResourceNotPermitted returns a Error for a 403 Forbidden error.
 func ResourceNotPermitted(reason string) Error {
-	return Error{reason, http.StatusForbidden}
+	return Error{reason: reason, status: http.StatusForbidden}
 }
 
 // ResourceConflict returns a Error for a 409 Conflict error.
 func ResourceConflict(reason string) Error {
-	return Error{reason, http.StatusConflict}
+	return Error{reason: reason, status: http.StatusConflict}
 }
 
 // BadRequest returns a Error for a 400 Bad Request error.
 func BadRequest(reason string) Error {
-	return Error{reason, http.StatusBadRequest}
+	return Error{reason: reason, status: http.StatusBadRequest}
 }
 
 // UnprocessableRequest returns a Error for a 422 Unprocessable Entity error.
 func UnprocessableRequest(reason string) Error {
-	return Error{reason, statusUnprocessableEntity}
+	return Error{reason: reason, status: statusUnprocessableEntity}
 }
 
 // UnauthorizedRequest returns a Error for a 401 Unauthorized error.
 func UnauthorizedRequest(reason string) Error {
-	return Error{reason, http.StatusUnauthorized}
+	return Error{reason: reason, status: http.StatusUnauthorized}
 }
 
 // MethodNotAllowed returns a Error for a 405 Method Not Allowed error.
 func MethodNotAllowed(reason string) Error {
-	return Error{reason, http.StatusMethodNotAllowed}
+	return Error{reason: reason, status: http.StatusMethodNotAllowed}
 }
 
 // InternalServerError returns a Error for a 500 Internal Server error.
 func InternalServerError(reason string) Error {
-	return Error{reason, http.StatusInternalServerError}
+	return Error{reason: reason, status: http.StatusInternalServerError}
+}
+
+// NotModified returns a Error for a 304 Not Modified response. No response body is
+// sent.
+func NotModified(reason string) Error {
+	return Error{reason: reason, status: http.StatusNotModified}
+}
+
+// PreconditionFailed returns a Error for a 412 Precondition Failed error.
+func PreconditionFailed(reason string) Error {
+	return Error{reason: reason, status: http.StatusPreconditionFailed}
+}
+
+// Unavailable returns a Error for a 503 Service Unavailable error indicating the
+// request can be retried after the given duration.
+func Unavailable(after time.Duration) Error {
+	return Error{
+		reason:     "Service temporarily unavailable",
+		status:     http.StatusServiceUnavailable,
+		retryAfter: after,
+	}
 }
 
 // CustomError returns an Error for the given HTTP status code.
 func CustomError(reason string, status int) Error {
-	return Error{reason, status}
+	return Error{reason: reason, status: status}
+}
+
+// ErrorMapper translates errors returned by ResourceHandlers, such as sql.ErrNoRows
+// or domain errors, into an HTTP status code and message. It returns false if the
+// error should be left as is.
+type ErrorMapper func(err error) (status int, message string, ok bool)
+
+// mapError applies the ErrorMapper to the error, returning the translated Error if
+// the mapper recognizes it.
+func mapError(mapper ErrorMapper, err error) error {
+	if mapper == nil || err == nil {
+		return err
+	}
+	status, message, ok := mapper(err)
+	if !ok {
+		return err
+	}
+	return CustomError(message, status)
 }
@@ -17,8 +17,11 @@ limitations under the License.
 package rest
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -62,4 +65,119 @@ func TestErrors(t *testing.T) {
 	err = InternalServerError("foo")
 	assert.Equal("foo", err.Error())
 	assert.Equal(http.StatusInternalServerError, err.Status())
+
+	err = NotModified("foo")
+	assert.Equal("foo", err.Error())
+	assert.Equal(http.StatusNotModified, err.Status())
+
+	err = PreconditionFailed("foo")
+	assert.Equal("foo", err.Error())
+	assert.Equal(http.StatusPreconditionFailed, err.Status())
+}
+
+// Ensures that the typed Error constructors map to the expected status codes and
+// implement StatusError.
+func TestTypedErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var err StatusError = NotFound("foo")
+	assert.Equal("foo", err.Error())
+	assert.Equal(http.StatusNotFound, err.StatusCode())
+
+	err = Forbidden("foo")
+	assert.Equal(http.StatusForbidden, err.StatusCode())
+
+	err = Conflict("foo")
+	assert.Equal(http.StatusConflict, err.StatusCode())
+
+	err = UnprocessableEntity("foo")
+	assert.Equal(statusUnprocessableEntity, err.StatusCode())
+
+	err = Unauthorized("foo")
+	assert.Equal(http.StatusUnauthorized, err.StatusCode())
+}
+
+type quotaError struct{}
+
+func (q quotaError) Error() string   { return "quota exceeded" }
+func (q quotaError) StatusCode() int { return http.StatusTooManyRequests }
+
+// Ensures that errorStatus honors any StatusError and defaults to 500.
+func TestErrorStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(http.StatusNotFound, errorStatus(NotFound("foo")))
+	assert.Equal(http.StatusTooManyRequests, errorStatus(quotaError{}))
+	assert.Equal(http.StatusTooManyRequests, errorStatus(&quotaError{}))
+	assert.Equal(http.StatusInternalServerError, errorStatus(fmt.Errorf("foo")))
+}
+
+var errNoRows = errors.New("no rows in result set")
+
+func noRowsMapper(err error) (int, string, bool) {
+	if err == errNoRows {
+		return http.StatusNotFound, "Resource does not exist", true
+	}
+	return 0, "", false
+}
+
+// Ensures that mapError translates errors recognized by the ErrorMapper and leaves
+// others untouched.
+func TestMapError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(CustomError("Resource does not exist", http.StatusNotFound),
+		mapError(noRowsMapper, errNoRows))
+
+	err := fmt.Errorf("foo")
+	assert.Equal(err, mapError(noRowsMapper, err))
+	assert.Equal(err, mapError(nil, err))
+	assert.Nil(mapError(noRowsMapper, nil))
+}
+
+// Ensures that Unavailable produces a retryable 503 and that retryAfterHeader rounds
+// up to whole seconds.
+func TestUnavailable(t *testing.T) {
+	assert := assert.New(t)
+
+	err := Unavailable(1500 * time.Millisecond)
+	assert.Equal(http.StatusServiceUnavailable, err.StatusCode())
+	assert.Equal(1500*time.Millisecond, err.RetryAfter())
+
+	header, ok := retryAfterHeader(err)
+	assert.True(ok)
+	assert.Equal("2", header)
+
+	header, ok = retryAfterHeader(Localize(Unavailable(time.Minute), "busy", nil))
+	assert.True(ok)
+	assert.Equal("60", header)
+
+	_, ok = retryAfterHeader(Unavailable(0))
+	assert.False(ok)
+	_, ok = retryAfterHeader(NotFound("foo"))
+	assert.False(ok)
+	_, ok = retryAfterHeader(nil)
+	assert.False(ok)
+}
+
+// Ensures that Errors match the sentinel for their status code and that wrapped
+// errors are classified using errors.As.
+func TestSentinelErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(errors.Is(NotFound("no such widget"), ErrNotFound))
+	assert.True(errors.Is(ResourceNotFound("no such widget"), ErrNotFound))
+	assert.True(errors.Is(UnauthorizedRequest("who are you"), ErrUnauthorized))
+	assert.True(errors.Is(Forbidden("nope"), ErrForbidden))
+	assert.True(errors.Is(Conflict("taken"), ErrConflict))
+	assert.False(errors.Is(Conflict("taken"), ErrNotFound))
+	assert.False(errors.Is(BadRequest("bad"), ErrFormatNotImplemented))
+
+	wrapped := fmt.Errorf("user 42: %w", ErrNotFound)
+	assert.True(errors.Is(wrapped, ErrNotFound))
+	assert.Equal(http.StatusNotFound, errorStatus(wrapped))
+
+	wrapped = fmt.Errorf("loading widget: %w", errQuotaExceeded.Error("Too many widgets"))
+	assert.Equal(http.StatusTooManyRequests, errorStatus(wrapped))
+	assert.Equal("quota_exceeded", errorCode(wrapped))
 }
@@ -0,0 +1,388 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEventHistory is the default number of recent Events kept for subscribers
+	// resuming a stream.
+	defaultEventHistory = 1000
+
+	// eventBufferSize is the number of Events buffered for each subscriber. Subscribers
+	// falling further behind are disconnected, so they resume with Last-Event-ID rather
+	// than slowing publishers down.
+	eventBufferSize = 64
+
+	// eventKeepAlive is how often comments are sent on idle event streams so proxies
+	// don't close them.
+	eventKeepAlive = 15 * time.Second
+)
+
+// EventType is the kind of mutation described by an Event.
+type EventType string
+
+const (
+	// EventCreate is published when a resource is created.
+	EventCreate EventType = "create"
+
+	// EventUpdate is published when a resource is updated, including by an update of
+	// a list of resources.
+	EventUpdate EventType = "update"
+
+	// EventDelete is published when a resource is deleted.
+	EventDelete EventType = "delete"
+)
+
+// Event describes a successful mutation of a resource made through the API.
+type Event struct {
+	// ID is the sequence number of the Event, increasing with each Event published
+	// by the API.
+	ID uint64 `json:"id"`
+
+	// Type is the kind of mutation.
+	Type EventType `json:"type"`
+
+	// Resource is the name of the mutated resource.
+	Resource string `json:"resource"`
+
+	// ResourceID is the ID of the mutated resource, if it was given in the URI of
+	// the request.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Version is the version of the API the mutation was made with.
+	Version string `json:"version,omitempty"`
+
+	// Payload is the resource returned by the ResourceHandler, with the outbound Rules
+	// of the version applied.
+	Payload Resource `json:"payload"`
+
+	// Time is when the Event was published.
+	Time time.Time `json:"time"`
+}
+
+// EventConfig configures the Events published for the mutations of resources, which
+// are streamed with Server-Sent Events at GET /api/v{version}/{resource}/events.
+// List responses then include an X-Change-Token header, and clients unable to hold
+// streams open can long poll with ?wait=30s&since={token}, which waits until the
+// resource changes after the token or the wait elapses. Subscribers only receive the
+// Events the ResourceHandlers implementing EventAuthorizer authorize them to see.
+type EventConfig struct {
+	// History is the number of recent Events kept in a ring buffer so clients
+	// reconnecting with the Last-Event-ID header, or subscribing over a WebSocket with
+	// a last_event_id, receive the Events they missed before the live ones. Clients
+	// which missed Events no longer kept are sent a reset Event instead, so they know
+	// to read the resources again. Defaults to 1000.
+	History int
+
+	// Retention, if positive, is how long Events are kept for reconnecting clients,
+	// regardless of History.
+	Retention time.Duration
+
+	// WebSocketPath, if set, is the path at which clients can subscribe to the Events
+	// of resources, or of resources with given IDs or field values, over a
+	// WebSocket, e.g. "/api/events". Clients send {"action": "subscribe", "resource":
+	// "widgets", "fields": {"status": "active"}} or {"action": "unsubscribe",
+	// "resource": "widgets", "resource_id": "42"} and receive {"type": "event",
+	// "event": {...}} messages.
+	WebSocketPath string
+
+	// Publisher, if set, publishes the Events to another system, such as a message
+	// broker, in the background.
+	Publisher EventPublisher
+
+	// Webhooks, if set, serves the webhooks resource with which callers register URLs
+	// to which the Events are delivered. It requires the Configuration's Authenticate
+	// function.
+	Webhooks *WebhookConfig
+}
+
+// eventBus publishes Events to subscribers, keeping the most recent ones so
+// subscribers can resume after reconnecting.
+type eventBus struct {
+	mu          sync.Mutex
+	seq         uint64
+	history     *eventRing
+	subscribers map[*eventSubscription]struct{}
+	closing     chan struct{}
+	closeOnce   sync.Once
+}
+
+// eventSubscription receives the published Events accepted by its filter.
+type eventSubscription struct {
+	events chan Event
+	filter func(Event) bool
+}
+
+// newEventBus returns an eventBus keeping the configured number of recent Events.
+func newEventBus(config *EventConfig) *eventBus {
+	size := config.History
+	if size <= 0 {
+		size = defaultEventHistory
+	}
+	return &eventBus{
+		history:     newEventRing(size, config.Retention),
+		subscribers: map[*eventSubscription]struct{}{},
+		closing:     make(chan struct{}),
+	}
+}
+
+// close signals the requests waiting on the bus, such as event streams and long
+// polls, to return so the API can shut down. Events can still be published.
+func (b *eventBus) close() {
+	b.closeOnce.Do(func() { close(b.closing) })
+}
+
+// done returns a channel which is closed once the bus is closed.
+func (b *eventBus) done() <-chan struct{} {
+	return b.closing
+}
+
+// publish assigns the Event an ID and time, records it and sends it to the matching
+// subscribers. Subscribers whose buffers are full are disconnected.
+func (b *eventBus) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event.ID = b.seq
+	event.Time = time.Now().UTC()
+	b.history.push(event)
+
+	for sub := range b.subscribers {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// subscribe returns a subscription to the Events accepted by the filter published from
+// now on.
+func (b *eventBus) subscribe(filter func(Event) bool) *eventSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.add(filter)
+}
+
+// resume returns a subscription to the Events accepted by the filter and the recorded
+// Events after the given ID which it accepts, and false if Events after the ID are no
+// longer recorded.
+func (b *eventBus) resume(after uint64, filter func(Event) bool) (*eventSubscription, []Event,
+	bool) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	missed, complete := b.history.after(after, filter)
+	return b.add(filter), missed, complete
+}
+
+// add adds a subscription to the Events accepted by the filter. The lock must be held.
+func (b *eventBus) add(filter func(Event) bool) *eventSubscription {
+	sub := &eventSubscription{events: make(chan Event, eventBufferSize), filter: filter}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe stops sending Events to the subscription.
+func (b *eventBus) unsubscribe(sub *eventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// consume calls handle with each Event of the subscription in order until the context
+// is done, then with the Events still buffered. If the subscriber falls behind and is
+// disconnected, it resumes after the last Event handled, so only the Events no longer
+// recorded are missed.
+func (b *eventBus) consume(ctx context.Context, sub *eventSubscription, filter func(Event) bool,
+	handle func(Event)) {
+
+	var last uint64
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				var missed []Event
+				sub, missed, _ = b.resume(last, filter)
+				for _, event := range missed {
+					handle(event)
+					last = event.ID
+				}
+				continue
+			}
+			handle(event)
+			last = event.ID
+		case <-ctx.Done():
+			b.unsubscribe(sub)
+			for event := range sub.events {
+				handle(event)
+			}
+			return
+		}
+	}
+}
+
+// publish publishes an Event of the mutation of the ResourceHandler's resource for
+// each of the payloads, if Events are enabled.
+func (h requestHandler) publish(ctx RequestContext, kind EventType, handler ResourceHandler,
+	payloads ...Resource) {
+
+	h.publishEvent(kind, handler, ctx.ResourceID(), ctx.Version(), payloads...)
+}
+
+// publishEvent publishes an Event of the mutation of the ResourceHandler's resource
+// with the ID and version for each of the payloads. The Events of OutboxHandlers are
+// instead written to their Outbox by the handlers and relayed from there.
+func (h requestHandler) publishEvent(kind EventType, handler ResourceHandler,
+	resourceID, version string, payloads ...Resource) {
+
+	if _, ok := outboxHandler(handler); ok {
+		return
+	}
+	for _, payload := range payloads {
+		h.emit(Event{
+			Type:       kind,
+			Resource:   handler.ResourceName(),
+			ResourceID: resourceID,
+			Version:    version,
+			Payload:    payload,
+		})
+	}
+}
+
+// emit publishes the Event, if Events are enabled, and appends it to the change feed,
+// if enabled. The mutations of muted resources aren't published.
+func (h requestHandler) emit(event Event) {
+	if _, ok := h.muted[event.Resource]; ok {
+		return
+	}
+	h.recordChange(event.Type, event.Resource, event.ResourceID, event.Version, event.Payload)
+	if h.events != nil {
+		h.events.publish(event)
+	}
+}
+
+// eventsURI returns the URI of the ResourceHandler's stream of Events.
+func eventsURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/events"
+}
+
+// handleEvents returns a Handler streaming the Events of the resource's mutations with
+// Server-Sent Events, starting with the recorded Events after the one identified by
+// the Last-Event-ID header, if any, preceded by a reset Event if some are no longer
+// recorded. The Events can be restricted to those of a resource with the resource_id
+// query string variable and to those whose payloads have fields with values with
+// field.{name} variables. Streams end when the client disconnects or the API shuts
+// down.
+func (h requestHandler) handleEvents(handler ResourceHandler) http.Handler {
+	resource := handler.ResourceName()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		lastID := r.Header.Get("Last-Event-ID")
+		var after uint64
+		var err error
+		if lastID != "" {
+			if after, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+				err = BadRequest(fmt.Sprintf("Invalid Last-Event-ID: %s", lastID))
+			}
+		}
+		if !ok {
+			err = InternalServerError("Streaming isn't supported")
+		}
+		if err != nil {
+			ctx, cancel := h.newContext(r, w, resource)
+			defer cancel()
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		filter := h.newEventSubscriber(r, eventQueryFilter(resource, r.URL.Query())).accepts
+		var sub *eventSubscription
+		var missed []Event
+		complete := true
+		if lastID != "" {
+			sub, missed, complete = h.events.resume(after, filter)
+		} else {
+			sub = h.events.subscribe(filter)
+		}
+		defer h.events.unsubscribe(sub)
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if !complete {
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", resetEvent); err != nil {
+				return
+			}
+		}
+		for _, event := range missed {
+			if writeEvent(w, event) != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event, ok := <-sub.events:
+				if !ok || writeEvent(w, event) != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-h.events.done():
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// writeEvent writes the Event in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
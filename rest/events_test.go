@@ -0,0 +1,214 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// widgetsResourceHandler is a ResourceHandler echoing the payloads of mutations.
+type widgetsResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (w widgetsResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (w widgetsResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	return []Resource{}, "", nil
+}
+
+func (w widgetsResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	return data, nil
+}
+
+func (w widgetsResourceHandler) UpdateResource(ctx RequestContext, id string,
+	data Payload, version string) (Resource, error) {
+	data["id"] = id
+	return data, nil
+}
+
+func (w widgetsResourceHandler) DeleteResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return Payload{"id": id}, nil
+}
+
+// readEvent reads the next Event of the Server-Sent Events stream.
+func readEvent(t *testing.T, stream *bufio.Reader) (string, Event) {
+	id, kind := "", ""
+	var event Event
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return id + " " + kind, event
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			kind = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// Ensures that the eventBus sends published Events to matching subscribers, replays
+// recorded Events after an ID and disconnects subscribers which fall behind.
+func TestEventBus(t *testing.T) {
+	assert := assert.New(t)
+	bus := newEventBus(&EventConfig{History: 2})
+	bus.publish(Event{Resource: "widgets"})
+	sub := bus.subscribe(func(e Event) bool { return e.Resource == "widgets" })
+
+	bus.publish(Event{Resource: "gadgets"})
+	bus.publish(Event{Resource: "widgets", Type: EventDelete})
+	event := <-sub.events
+	assert.Equal(uint64(3), event.ID)
+	assert.Equal(EventDelete, event.Type)
+	assert.False(event.Time.IsZero())
+
+	_, missed, _ := bus.resume(0, func(Event) bool { return true })
+	assert.Len(missed, 2)
+	assert.Equal(uint64(2), missed[0].ID)
+	_, missed, _ = bus.resume(2, func(Event) bool { return true })
+	assert.Len(missed, 1)
+
+	for i := 0; i <= eventBufferSize; i++ {
+		bus.publish(Event{Resource: "widgets"})
+	}
+	received := 0
+	for range sub.events {
+		received++
+	}
+	assert.Equal(eventBufferSize, received)
+	bus.unsubscribe(sub)
+}
+
+// Ensures that the mutations of resources are streamed with Server-Sent Events and
+// that clients can resume streams with Last-Event-ID.
+func TestEventStream(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	events, err := http.Get(ts.URL + "/api/v1/widgets/events")
+	if !assert.Nil(err) {
+		return
+	}
+	defer events.Body.Close()
+	assert.Equal(http.StatusOK, events.StatusCode)
+	assert.Equal("text/event-stream", events.Header.Get("Content-Type"))
+
+	body := bytes.NewBufferString(`{"name": "sprocket"}`)
+	resp, err := http.Post(ts.URL+"/api/v1/widgets", "application/json", body)
+	if assert.Nil(err) {
+		resp.Body.Close()
+	}
+	req, _ := http.NewRequest("PUT", ts.URL+"/api/v1/widgets/42", strings.NewReader(`{"name": "cog"}`))
+	if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+		resp.Body.Close()
+	}
+	req, _ = http.NewRequest("DELETE", ts.URL+"/api/v1/widgets/42", nil)
+	if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+		resp.Body.Close()
+	}
+
+	stream := bufio.NewReader(events.Body)
+	header, event := readEvent(t, stream)
+	assert.Equal("1 create", header)
+	assert.Equal("widgets", event.Resource)
+	assert.Equal("1", event.Version)
+	assert.Equal(map[string]interface{}{"name": "sprocket"}, event.Payload)
+	header, event = readEvent(t, stream)
+	assert.Equal("2 update", header)
+	assert.Equal("42", event.ResourceID)
+	header, _ = readEvent(t, stream)
+	assert.Equal("3 delete", header)
+
+	req, _ = http.NewRequest("GET", ts.URL+"/api/v1/widgets/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resumed, err := http.DefaultClient.Do(req)
+	if !assert.Nil(err) {
+		return
+	}
+	defer resumed.Body.Close()
+	stream = bufio.NewReader(resumed.Body)
+	header, _ = readEvent(t, stream)
+	assert.Equal("2 update", header)
+	header, _ = readEvent(t, stream)
+	assert.Equal("3 delete", header)
+
+	fresh, err := http.Get(ts.URL + "/api/v1/widgets/events")
+	if !assert.Nil(err) {
+		return
+	}
+	defer fresh.Body.Close()
+	req, _ = http.NewRequest("DELETE", ts.URL+"/api/v1/widgets/7", nil)
+	if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+		resp.Body.Close()
+	}
+	header, _ = readEvent(t, bufio.NewReader(fresh.Body))
+	assert.Equal("4 delete", header)
+
+	req, _ = http.NewRequest("GET", ts.URL+"/api/v1/widgets/events", nil)
+	req.Header.Set("Last-Event-ID", "latest")
+	if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Ensures that the events route isn't registered unless Events are configured.
+func TestEventStreamDisabled(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/widgets/events", nil)
+	done := make(chan struct{})
+	go func() {
+		api.ServeHTTP(w, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+		assert.NotEqual("text/event-stream", w.Header().Get("Content-Type"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("request wasn't handled as a read")
+	}
+}
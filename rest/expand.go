@@ -0,0 +1,244 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// expandKey is the name of the query string variable expanding referenced
+	// resources.
+	expandKey = "expand"
+
+	// defaultMaxExpandDepth is the default longest path of fields which can be
+	// expanded.
+	defaultMaxExpandDepth = 3
+)
+
+// expander embeds the resources referenced by the fields of the resources of a read
+// or list request, given by the expand query string variable, e.g.
+// ?expand=owner,items.product. Each field of a path is either a field whose Rule
+// References a resource, whose IDs are replaced with the resources read from its
+// ResourceHandler, or a field with nested Rules. Referenced resources are read once
+// per request, and only if their ResourceHandlers authenticate the request.
+type expander struct {
+	h       requestHandler
+	ctx     RequestContext
+	r       *http.Request
+	version string
+	read    map[string]Resource
+	authErr map[string]error
+}
+
+// expand replaces the IDs referenced by the fields given by the expand query string
+// variable of the request with the resources they identify. It returns a 400 error if
+// a path is too deep or has fields which can't be expanded.
+func (h requestHandler) expand(ctx RequestContext, handler ResourceHandler,
+	resources ...Resource) error {
+
+	expr, _ := ctx.Value(expandKey).(string)
+	if expr == "" {
+		return nil
+	}
+	maxDepth := defaultMaxExpandDepth
+	if config := h.Configuration(); config != nil && config.MaxExpandDepth > 0 {
+		maxDepth = config.MaxExpandDepth
+	}
+	r, _ := ctx.Request()
+	e := &expander{h: h, ctx: ctx, r: r, version: ctx.Version(),
+		read: map[string]Resource{}, authErr: map[string]error{}}
+
+	for _, path := range strings.Split(expr, ",") {
+		fields := strings.Split(strings.TrimSpace(path), ".")
+		if len(fields) > maxDepth {
+			return BadRequest(fmt.Sprintf("Invalid expand: %s is deeper than %d fields",
+				path, maxDepth))
+		}
+		if err := e.validate(handler.Rules(), fields, path); err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if err := e.expand(resource, handler.Rules(), fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// referencingFields returns the names of the fields the Rules mark as referencing
+// resources for the version, in order.
+func referencingFields(rules Rules, version string) []string {
+	fields := []string{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		if rule.References != "" {
+			fields = append(fields, rule.Name())
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// rule returns the outbound Rule of the field, or nil.
+func (e *expander) rule(rules Rules, field string) *Rule {
+	if rules == nil {
+		return nil
+	}
+	for _, rule := range rulesFor(rules, Outbound, e.version).Contents() {
+		if rule.Name() == field {
+			return rule
+		}
+	}
+	return nil
+}
+
+// validate returns a 400 error if any of the fields of the path can't be expanded.
+func (e *expander) validate(rules Rules, fields []string, path string) error {
+	for _, field := range fields {
+		rule := e.rule(rules, field)
+		switch {
+		case rule != nil && rule.References != "":
+			related := e.h.resourceHandler(rule.References)
+			if related == nil {
+				return BadRequest(fmt.Sprintf("Invalid expand: %s references unknown resource %s",
+					path, rule.References))
+			}
+			rules = related.Rules()
+		case rule != nil && rule.Rules != nil:
+			rules = rule.Rules
+		default:
+			return BadRequest(fmt.Sprintf("Invalid expand: %s can't be expanded in %s", field, path))
+		}
+	}
+	return nil
+}
+
+// expand expands the path of fields of the resource, or of each of the resources if
+// it's a list, with the Rules.
+func (e *expander) expand(resource Resource, rules Rules, fields []string) error {
+	switch value := resource.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if err := e.expand(item, rules, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []Payload:
+		for _, item := range value {
+			if err := e.expand(item, rules, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []Resource:
+		for _, item := range value {
+			if err := e.expand(item, rules, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	payload, ok := resourceFields(resource)
+	if !ok || len(fields) == 0 {
+		return nil
+	}
+	value, ok := payload[fields[0]]
+	if !ok || value == nil {
+		return nil
+	}
+
+	rule := e.rule(rules, fields[0])
+	if rule == nil {
+		return nil
+	}
+	if rule.References == "" {
+		return e.expand(value, rule.Rules, fields[1:])
+	}
+	related := e.h.resourceHandler(rule.References)
+	expanded, err := e.resolve(related, value)
+	if err != nil {
+		return err
+	}
+	payload[fields[0]] = expanded
+	return e.expand(expanded, related.Rules(), fields[1:])
+}
+
+// resolve returns the resource, or list of resources, of the ResourceHandler
+// identified by the ID, or list of IDs. The IDs of resources which don't exist are
+// resolved to nil.
+func (e *expander) resolve(related ResourceHandler, ids interface{}) (Resource, error) {
+	if list, ok := ids.([]interface{}); ok {
+		resolved := make([]interface{}, len(list))
+		for i, id := range list {
+			resource, err := e.resolve(related, id)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resource
+		}
+		return resolved, nil
+	}
+	if _, ok := resourceFields(ids); ok {
+		// The reference was already expanded, e.g. by another path.
+		return ids, nil
+	}
+
+	name := related.ResourceName()
+	if _, ok := e.authErr[name]; !ok {
+		e.authErr[name] = related.Authenticate(e.r)
+	}
+	if err := e.authErr[name]; err != nil {
+		return nil, err
+	}
+
+	id := fmt.Sprint(ids)
+	key := name + "/" + id
+	if resource, ok := e.read[key]; ok {
+		return resource, nil
+	}
+	resource, err := related.ReadResource(e.ctx, id, e.version)
+	if errorStatus(err) == http.StatusNotFound || err == nil && isNilResource(resource) {
+		resource, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resource != nil {
+		resource = applyOutboundRules(resource, related.Rules(), e.version)
+	}
+	e.read[key] = resource
+	return resource, nil
+}
+
+// resourceFields returns the fields of the resource and true if it's a map.
+func resourceFields(resource Resource) (map[string]interface{}, bool) {
+	switch fields := resource.(type) {
+	case Payload:
+		return fields, true
+	case map[string]interface{}:
+		return fields, true
+	}
+	return nil, false
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// usersResourceHandler is a ResourceHandler reading users by ID, counting its reads.
+type usersResourceHandler struct {
+	BaseResourceHandler
+	reads *int32
+}
+
+func (u usersResourceHandler) ResourceName() string {
+	return "users"
+}
+
+func (u usersResourceHandler) Rules() Rules {
+	return NewRules((*Payload)(nil),
+		&Rule{Field: "id", Type: String},
+		&Rule{Field: "name", Type: String},
+		&Rule{Field: "manager", Type: String, References: "users"},
+	)
+}
+
+func (u usersResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	atomic.AddInt32(u.reads, 1)
+	users := map[string]map[string]interface{}{
+		"1": {"id": "1", "name": "Ada", "manager": "2", "password": "secret"},
+		"2": {"id": "2", "name": "Grace"},
+	}
+	if user, ok := users[id]; ok {
+		return user, nil
+	}
+	return nil, ResourceNotFound("No such user")
+}
+
+// ordersResourceHandler is a ResourceHandler of orders referencing their owners and,
+// through their items, secrets.
+type ordersResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (o ordersResourceHandler) ResourceName() string {
+	return "orders"
+}
+
+func (o ordersResourceHandler) Rules() Rules {
+	return NewRules((*Payload)(nil),
+		&Rule{Field: "id", Type: String},
+		&Rule{Field: "owner", Type: String, References: "users"},
+		&Rule{Field: "watchers", Type: Slice, References: "users"},
+		&Rule{Field: "items", Type: Slice, Rules: NewRules((*Payload)(nil),
+			&Rule{Field: "secret", Type: String, References: "secrets"},
+			&Rule{Field: "quantity", Type: Int},
+		)},
+	)
+}
+
+func (o ordersResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return Payload{"id": id, "owner": "1", "watchers": []interface{}{"2", "9"},
+		"items": []interface{}{map[string]interface{}{"secret": "s", "quantity": 2.0}}}, nil
+}
+
+func (o ordersResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	return []Resource{
+		Payload{"id": "a", "owner": "1"},
+		Payload{"id": "b", "owner": "2"},
+		Payload{"id": "c"},
+	}, "", nil
+}
+
+// Ensures that read and list requests embed the resources referenced by the fields
+// given by the expand query string variable, reading each once.
+func TestExpand(t *testing.T) {
+	assert := assert.New(t)
+	var reads int32
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(ordersResourceHandler{})
+	api.RegisterResourceHandler(usersResourceHandler{reads: &reads})
+	api.RegisterResourceHandler(secretsResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/orders/x?expand=owner.manager,watchers", "")
+	assert.Equal(200, code)
+	result := envelope["result"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"id": "1", "name": "Ada",
+		"manager": map[string]interface{}{"id": "2", "name": "Grace"}}, result["owner"])
+	assert.Equal([]interface{}{map[string]interface{}{"id": "2", "name": "Grace"}, nil},
+		result["watchers"])
+	assert.Equal(int32(3), atomic.LoadInt32(&reads))
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/orders?expand=owner", "")
+	assert.Equal(200, code)
+	owners := []interface{}{}
+	for _, order := range envelope["results"].([]interface{}) {
+		owners = append(owners, order.(map[string]interface{})["owner"])
+	}
+	assert.Equal([]interface{}{
+		map[string]interface{}{"id": "1", "name": "Ada", "manager": "2"},
+		map[string]interface{}{"id": "2", "name": "Grace"},
+		nil,
+	}, owners)
+
+	code, _ = serveJSON(api, "GET", "/api/v1/orders/x", "")
+	assert.Equal(200, code)
+	assert.Contains(queryParameters(api, "/api/v{version}/orders"), expandKey)
+}
+
+// Ensures that expansions are rejected if they're too deep, have fields which don't
+// reference resources, or reference resources whose ResourceHandlers don't
+// authenticate the request.
+func TestExpandErrors(t *testing.T) {
+	assert := assert.New(t)
+	var reads int32
+	api := NewAPI(&Configuration{MaxExpandDepth: 2})
+	api.RegisterResourceHandler(ordersResourceHandler{})
+	api.RegisterResourceHandler(usersResourceHandler{reads: &reads})
+	api.RegisterResourceHandler(secretsResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/orders/x?expand=owner.manager.manager", "")
+	assert.Equal(400, code)
+	assert.Equal([]interface{}{"Invalid expand: owner.manager.manager is deeper than 2 fields"},
+		envelope["messages"])
+	code, envelope = serveJSON(api, "GET", "/api/v1/orders/x?expand=items.quantity", "")
+	assert.Equal(400, code)
+	assert.Equal([]interface{}{"Invalid expand: quantity can't be expanded in items.quantity"},
+		envelope["messages"])
+	code, _ = serveJSON(api, "GET", "/api/v1/orders?expand=id", "")
+	assert.Equal(400, code)
+	code, _ = serveJSON(api, "GET", "/api/v1/orders/x?expand=items.secret", "")
+	assert.Equal(401, code)
+}
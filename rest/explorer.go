@@ -0,0 +1,69 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// explorerAssets contains the API explorer page, scripts and styles.
+//
+//go:embed explorer
+var explorerAssets embed.FS
+
+// explorerTemplate renders the API explorer page.
+var explorerTemplate = template.Must(template.ParseFS(explorerAssets, "explorer/index.html"))
+
+// explorerHandler returns a Handler serving the API explorer at the path, which has
+// no trailing slash. The explorer is driven by the OpenAPI specification served at
+// /api/openapi.json.
+func explorerHandler(api API, path string) http.Handler {
+	assets, _ := fs.Sub(explorerAssets, "explorer")
+	files := http.StripPrefix(path, http.FileServer(http.FS(assets)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path && r.URL.Path != path+"/" {
+			if strings.HasSuffix(r.URL.Path, "/index.html") {
+				http.NotFound(w, r)
+				return
+			}
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		title := api.Configuration().Info.Title
+		if title == "" {
+			title = "API Explorer"
+		}
+		var page bytes.Buffer
+		if err := explorerTemplate.Execute(&page, map[string]string{
+			"Title":   title,
+			"Path":    path,
+			"SpecURL": openAPIURI,
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+}
@@ -0,0 +1,165 @@
+// Renders the operations of an OpenAPI specification and lets them be tried out.
+(function () {
+    "use strict";
+
+    var specURL = document.body.getAttribute("data-spec");
+    var spec;
+
+    function element(tag, attributes, children) {
+        var el = document.createElement(tag);
+        Object.keys(attributes || {}).forEach(function (key) {
+            el.setAttribute(key, attributes[key]);
+        });
+        (children || []).forEach(function (child) {
+            el.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+        });
+        return el;
+    }
+
+    function resolve(schema) {
+        while (schema && schema.$ref) {
+            schema = spec.components.schemas[schema.$ref.split("/").pop()];
+        }
+        return schema || {};
+    }
+
+    // example returns an example value for the schema.
+    function example(schema, depth) {
+        schema = resolve(schema);
+        if (schema.example !== undefined) {
+            return schema.example;
+        }
+        if (depth > 4) {
+            return null;
+        }
+        switch (schema.type) {
+        case "object":
+            var value = {};
+            Object.keys(schema.properties || {}).forEach(function (name) {
+                value[name] = example(schema.properties[name], depth + 1);
+            });
+            return value;
+        case "array":
+            return [example(schema.items, depth + 1)];
+        case "integer":
+        case "number":
+            return 0;
+        case "boolean":
+            return true;
+        case "string":
+            return schema.format === "date-time" ? new Date().toISOString() : "";
+        }
+        return null;
+    }
+
+    function send(path, method, operation, form, output) {
+        var url = path;
+        var query = [];
+        (operation.parameters || []).forEach(function (param) {
+            var value = form.elements[param.name].value;
+            if (param.in === "path") {
+                url = url.replace("{" + param.name + "}", encodeURIComponent(value));
+            } else if (value !== "") {
+                query.push(encodeURIComponent(param.name) + "=" + encodeURIComponent(value));
+            }
+        });
+        if (query.length) {
+            url += "?" + query.join("&");
+        }
+
+        var init = {method: method.toUpperCase(), headers: {"Accept": "application/json"}, credentials: "same-origin"};
+        if (form.elements.body) {
+            init.body = form.elements.body.value;
+            init.headers["Content-Type"] = "application/json";
+        }
+
+        output.textContent = init.method + " " + url + "\n\n";
+        fetch(url, init).then(function (resp) {
+            return resp.text().then(function (text) {
+                try {
+                    text = JSON.stringify(JSON.parse(text), null, 2);
+                } catch (e) {
+                    // Not JSON, show as is.
+                }
+                output.textContent += resp.status + " " + resp.statusText + "\n\n" + text;
+            });
+        }).catch(function (err) {
+            output.textContent += err;
+        });
+    }
+
+    function renderOperation(path, method, operation) {
+        var form = element("form");
+        (operation.parameters || []).forEach(function (param) {
+            form.appendChild(element("label", {}, [
+                param.name + " (" + param.in + ")",
+                element("input", {name: param.name, placeholder: param.description || ""})
+            ]));
+        });
+        if (operation.requestBody) {
+            var content = operation.requestBody.content["application/json"];
+            var body = element("textarea", {name: "body"});
+            var value = content.example !== undefined ? content.example : example(content.schema, 0);
+            body.value = JSON.stringify(value, null, 2);
+            form.appendChild(element("label", {}, ["body", body]));
+        }
+        var output = element("pre");
+        form.appendChild(element("button", {type: "submit"}, ["Send"]));
+        form.addEventListener("submit", function (e) {
+            e.preventDefault();
+            send(path, method, operation, form, output);
+        });
+
+        return element("details", {"class": "operation"}, [
+            element("summary", {}, [
+                element("span", {"class": "method " + method}, [method.toUpperCase()]),
+                path + (operation.summary ? " - " + operation.summary : ""),
+                operation.deprecated ? element("span", {"class": "deprecated"}, ["deprecated"]) : ""
+            ]),
+            element("div", {"class": "body"}, [
+                element("p", {}, [operation.description || ""]),
+                form,
+                output
+            ])
+        ]);
+    }
+
+    function render() {
+        document.getElementById("version").textContent = "v" + spec.info.version;
+        var nav = document.getElementById("resources");
+        var section = document.getElementById("operations");
+        section.innerHTML = "";
+
+        var tags = {};
+        Object.keys(spec.paths).sort().forEach(function (path) {
+            ["get", "post", "put", "delete"].forEach(function (method) {
+                var operation = spec.paths[path][method];
+                if (!operation) {
+                    return;
+                }
+                var tag = (operation.tags || ["default"])[0];
+                if (!tags[tag]) {
+                    tags[tag] = element("div", {id: "tag-" + tag}, [element("h2", {}, [tag])]);
+                    nav.appendChild(element("a", {href: "#tag-" + tag}, [tag]));
+                    section.appendChild(tags[tag]);
+                }
+                tags[tag].appendChild(renderOperation(path, method, operation));
+            });
+        });
+        if (!Object.keys(tags).length) {
+            section.appendChild(element("p", {"class": "empty"}, ["No resources are registered."]));
+        }
+    }
+
+    fetch(specURL, {credentials: "same-origin"}).then(function (resp) {
+        if (!resp.ok) {
+            throw new Error("Failed to load specification: " + resp.status);
+        }
+        return resp.json();
+    }).then(function (s) {
+        spec = s;
+        render();
+    }).catch(function (err) {
+        document.getElementById("operations").textContent = err.message;
+    });
+})();
@@ -0,0 +1,86 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveExplorer performs a GET request for the path against the API.
+func serveExplorer(api API, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+// Ensures that the API explorer page and its assets are served at the configured
+// path.
+func TestExplorer(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ExplorerPath: "/docs/explorer/", Info: APIInfo{Title: "Widgets"}})
+
+	for _, path := range []string{"/docs/explorer", "/docs/explorer/"} {
+		w := serveExplorer(api, path)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(w.Body.String(), "<title>Widgets</title>")
+		assert.Contains(w.Body.String(), `data-spec="/api/openapi.json"`)
+		assert.Contains(w.Body.String(), `src="/docs/explorer/explorer.js"`)
+	}
+
+	w := serveExplorer(api, "/docs/explorer/explorer.js")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), "data-spec")
+
+	assert.Equal(http.StatusNotFound, serveExplorer(api, "/docs/explorer/missing.js").Code)
+	assert.Equal(http.StatusNotFound, serveExplorer(api, "/docs/explorer/index.html").Code)
+}
+
+// Ensures that the API explorer isn't served unless a path is configured.
+func TestExplorerDisabled(t *testing.T) {
+	api := NewAPI(&Configuration{})
+	assert.Equal(t, http.StatusNotFound, serveExplorer(api, "/api/explorer").Code)
+}
+
+// Ensures that the API explorer and the OpenAPI specification are gated behind the
+// configured authenticator.
+func TestExplorerAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	config := &Configuration{ExplorerPath: "/api/explorer"}
+	api := NewAPI(config)
+	config.Authenticate = func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "secret" {
+			return errors.New("Not authorized")
+		}
+		return nil
+	}
+
+	assert.Equal(http.StatusUnauthorized, serveExplorer(api, "/api/explorer").Code)
+	assert.Equal(http.StatusUnauthorized, serveExplorer(api, "/api/openapi.json").Code)
+
+	req, _ := http.NewRequest("GET", "http://example.com/api/explorer", nil)
+	req.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+}
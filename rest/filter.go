@@ -0,0 +1,430 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// filterKey is the name of the query string variable filtering lists.
+const filterKey = "filter"
+
+// maxFilterDepth is the deepest filters can nest parenthesized expressions.
+const maxFilterDepth = 8
+
+// FilterOperator compares a field with a value in a filter.
+type FilterOperator string
+
+// FilterOperator constants are the comparisons supported by filters.
+const (
+	FilterEqual          FilterOperator = "=="
+	FilterNotEqual       FilterOperator = "!="
+	FilterGreater        FilterOperator = ">"
+	FilterGreaterOrEqual FilterOperator = ">="
+	FilterLess           FilterOperator = "<"
+	FilterLessOrEqual    FilterOperator = "<="
+)
+
+// filterOperators are the FilterOperators, longest first so they're matched greedily.
+var filterOperators = []FilterOperator{
+	FilterEqual, FilterNotEqual, FilterGreaterOrEqual, FilterLessOrEqual,
+	FilterGreater, FilterLess,
+}
+
+// FilterLogic combines the child nodes of a FilterNode.
+type FilterLogic string
+
+// FilterLogic constants are the ways FilterNodes combine their children.
+const (
+	FilterAnd FilterLogic = "and"
+	FilterOr  FilterLogic = "or"
+)
+
+// FilterNode is a node of the tree parsed from the filter query string variable of a
+// list request, e.g. ?filter=status==active,created_at>=2024-01-01. Conditions are
+// separated by commas or semicolons, all of which must hold, or by pipes, any of which
+// must hold, and grouped with parentheses. Values containing separators or
+// parentheses are double-quoted, escaping quotes and backslashes with backslashes.
+// Only the fields of Rules marked Filterable can be filtered by, and values are
+// coerced to their Types; times are RFC 3339 or dates.
+type FilterNode struct {
+	// Logic combines the Nodes of conjunctions and disjunctions. It's empty for
+	// comparisons.
+	Logic FilterLogic
+
+	// Nodes are the children of conjunctions and disjunctions.
+	Nodes []*FilterNode
+
+	// Field is the name of the field compared, as given by its Rule's Name.
+	Field string
+
+	// Operator is the comparison of the field with the Value.
+	Operator FilterOperator
+
+	// Value is the value compared with, coerced to the Type of the field's Rule.
+	Value interface{}
+}
+
+// Matches evaluates the filter against the fields of a resource, for ResourceHandlers
+// filtering lists in memory. Comparisons of missing fields and of values which can't be
+// ordered don't hold, except FilterNotEqual.
+func (n *FilterNode) Matches(fields map[string]interface{}) bool {
+	switch n.Logic {
+	case FilterAnd:
+		for _, node := range n.Nodes {
+			if !node.Matches(fields) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, node := range n.Nodes {
+			if node.Matches(fields) {
+				return true
+			}
+		}
+		return false
+	}
+
+	order, ok := compareFilterValues(fields[n.Field], n.Value)
+	switch n.Operator {
+	case FilterEqual:
+		return ok && order == 0
+	case FilterNotEqual:
+		return !ok || order != 0
+	case FilterGreater:
+		return ok && order > 0
+	case FilterGreaterOrEqual:
+		return ok && order >= 0
+	case FilterLess:
+		return ok && order < 0
+	case FilterLessOrEqual:
+		return ok && order <= 0
+	}
+	return false
+}
+
+// String returns the filter in the syntax of the filter query string variable.
+func (n *FilterNode) String() string {
+	if n.Logic == "" {
+		value := fmt.Sprint(n.Value)
+		if t, ok := n.Value.(time.Time); ok {
+			value = t.Format(time.RFC3339)
+		}
+		if strings.ContainsAny(value, `,;|()"\`) || value == "" {
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+		return n.Field + string(n.Operator) + value
+	}
+	separator := ","
+	if n.Logic == FilterOr {
+		separator = "|"
+	}
+	parts := make([]string, len(n.Nodes))
+	for i, node := range n.Nodes {
+		parts[i] = node.String()
+		if node.Logic != "" && node.Logic != n.Logic {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+// compareFilterValues returns the order of the values, -1, 0 or 1, and false if they
+// can't be ordered because they're of different kinds.
+func compareFilterValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if af, ok := filterNumber(av); ok {
+		bf, ok := filterNumber(bv)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch av.Kind() {
+	case reflect.String:
+		if bv.Kind() != reflect.String {
+			return 0, false
+		}
+		return strings.Compare(av.String(), bv.String()), true
+	case reflect.Bool:
+		if bv.Kind() != reflect.Bool {
+			return 0, false
+		}
+		switch {
+		case av.Bool() == bv.Bool():
+			return 0, true
+		case bv.Bool():
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// filterNumber returns the numeric value as a float64 and true, or false if it isn't
+// a number.
+func filterNumber(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// filterableFields returns the Types of the fields the Rules mark Filterable for the
+// version, by name.
+func filterableFields(rules Rules, version string) map[string]Type {
+	fields := map[string]Type{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		if rule.Filterable {
+			fields[rule.Name()] = rule.Type
+		}
+	}
+	return fields
+}
+
+// listFilter returns the FilterNode parsed from the filter query string variable of a
+// list request to the ResourceHandler, nil if there isn't one, or a 400 error if it's
+// malformed or filters by fields which aren't filterable.
+func listFilter(ctx RequestContext, rules Rules, version string) (*FilterNode, error) {
+	expr, _ := ctx.Value(filterKey).(string)
+	if expr == "" {
+		return nil, nil
+	}
+	node, err := ParseFilter(expr, filterableFields(rules, version))
+	if err != nil {
+		return nil, BadRequest(err.Error())
+	}
+	return node, nil
+}
+
+// ParseFilter parses the filter expression, in the syntax described by FilterNode,
+// returning an error if it's malformed or compares fields other than those given,
+// whose values are coerced to their Types.
+func ParseFilter(expr string, fields map[string]Type) (*FilterNode, error) {
+	p := &filterParser{expr: expr, fields: fields}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected %q", p.expr[p.pos])
+	}
+	return node, nil
+}
+
+// filterParser is a recursive descent parser of filter expressions.
+type filterParser struct {
+	expr   string
+	pos    int
+	fields map[string]Type
+}
+
+// errorf returns an error describing a syntax error at the current position.
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid filter at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// parseOr parses conditions separated by pipes.
+func (p *filterParser) parseOr(depth int) (*FilterNode, error) {
+	return p.parseList(depth, FilterOr, "|", p.parseAnd)
+}
+
+// parseAnd parses conditions separated by commas or semicolons.
+func (p *filterParser) parseAnd(depth int) (*FilterNode, error) {
+	return p.parseList(depth, FilterAnd, ",;", p.parseTerm)
+}
+
+// parseList parses terms separated by any of the separators, combining them with the
+// logic if there's more than one.
+func (p *filterParser) parseList(depth int, logic FilterLogic, separators string,
+	parse func(int) (*FilterNode, error)) (*FilterNode, error) {
+
+	node, err := parse(depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*FilterNode{node}
+	for p.pos < len(p.expr) && strings.IndexByte(separators, p.expr[p.pos]) >= 0 {
+		p.pos++
+		if node, err = parse(depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &FilterNode{Logic: logic, Nodes: nodes}, nil
+}
+
+// parseTerm parses a parenthesized expression or a comparison.
+func (p *filterParser) parseTerm(depth int) (*FilterNode, error) {
+	if p.pos < len(p.expr) && p.expr[p.pos] == '(' {
+		if depth == maxFilterDepth {
+			return nil, p.errorf("nested too deeply")
+		}
+		p.pos++
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.expr) || p.expr[p.pos] != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses a field, operator and value.
+func (p *filterParser) parseComparison() (*FilterNode, error) {
+	start := p.pos
+	for p.pos < len(p.expr) && isFilterFieldChar(p.expr[p.pos]) {
+		p.pos++
+	}
+	field := p.expr[start:p.pos]
+	if field == "" {
+		return nil, p.errorf("expected a field")
+	}
+	kind, ok := p.fields[field]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("%s can't be filtered by; filterable fields are %s", field,
+			strings.Join(sortedFilterFields(p.fields), ", "))
+	}
+
+	var op FilterOperator
+	for _, candidate := range filterOperators {
+		if strings.HasPrefix(p.expr[p.pos:], string(candidate)) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected an operator after %s", field)
+	}
+	p.pos += len(op)
+
+	raw, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	value, err := coerceFilterValue(raw, kind)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid filter value for %s: %q is not a valid %s", field, raw, kind)
+	}
+	return &FilterNode{Field: field, Operator: op, Value: value}, nil
+}
+
+// parseValue parses a bare or double-quoted value.
+func (p *filterParser) parseValue() (string, error) {
+	if p.pos < len(p.expr) && p.expr[p.pos] == '"' {
+		p.pos++
+		var value []byte
+		for p.pos < len(p.expr) {
+			c := p.expr[p.pos]
+			p.pos++
+			switch c {
+			case '"':
+				return string(value), nil
+			case '\\':
+				if p.pos == len(p.expr) {
+					return "", p.errorf("unterminated escape")
+				}
+				c = p.expr[p.pos]
+				p.pos++
+			}
+			value = append(value, c)
+		}
+		return "", p.errorf("unterminated quoted value")
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && !strings.ContainsRune(`,;|()"`, rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	return p.expr[start:p.pos], nil
+}
+
+// isFilterFieldChar indicates if the character can be part of a field name.
+func isFilterFieldChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9'
+}
+
+// coerceFilterValue coerces the value to the Type. Times are parsed as RFC 3339 or, at
+// midnight UTC, dates.
+func coerceFilterValue(value string, kind Type) (interface{}, error) {
+	switch kind {
+	case Interface:
+		return value, nil
+	case Time:
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", value)
+	}
+	return coerceFromString(value, kind)
+}
+
+// sortedFilterFields returns the names of the fields in order.
+func sortedFilterFields(fields map[string]Type) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tasksResourceHandler is a ResourceHandler filtering and sorting a list of tasks in
+// memory.
+type tasksResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (t tasksResourceHandler) ResourceName() string {
+	return "tasks"
+}
+
+func (t tasksResourceHandler) Rules() Rules {
+	return NewRules((*Payload)(nil),
+		&Rule{Field: "name", Type: String, Sortable: true},
+		&Rule{Field: "status", Type: String, Filterable: true},
+		&Rule{Field: "size", Type: Int, Filterable: true, Sortable: true},
+	)
+}
+
+func (t tasksResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	tasks := []Payload{
+		{"name": "a", "status": "active", "size": 1},
+		{"name": "b", "status": "active", "size": 5},
+		{"name": "c", "status": "done", "size": 8},
+		{"name": "d", "status": "done", "size": 5},
+	}
+	results := []Resource{}
+	for _, task := range tasks {
+		if filter := ctx.ListFilter(); filter == nil || filter.Matches(task) {
+			results = append(results, task)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, field := range ctx.SortFields() {
+			order, _ := compareFilterValues(results[i].(Payload)[field.Field],
+				results[j].(Payload)[field.Field])
+			if order != 0 {
+				return order < 0 != field.Descending
+			}
+		}
+		return false
+	})
+	return results, "", nil
+}
+
+// queryParameters returns the names of the query parameters of the OpenAPI operation
+// listing the resources at the path.
+func queryParameters(api API, path string) []string {
+	paths := newOpenAPIDocument(api)["paths"].(map[string]map[string]interface{})
+	names := []string{}
+	for _, param := range paths[path]["get"].(map[string]interface{})["parameters"].([]interface{}) {
+		if param := param.(map[string]interface{}); param["in"] == "query" {
+			names = append(names, param["name"].(string))
+		}
+	}
+	return names
+}
+
+// Ensures that ParseFilter builds a tree of conjunctions, disjunctions and comparisons,
+// coercing values to the Types of the fields.
+func TestParseFilter(t *testing.T) {
+	assert := assert.New(t)
+	fields := map[string]Type{"status": String, "size": Int, "created_at": Time, "done": Bool}
+
+	node, err := ParseFilter("status==active,created_at>=2024-01-01", fields)
+	if assert.Nil(err) {
+		assert.Equal(&FilterNode{Logic: FilterAnd, Nodes: []*FilterNode{
+			{Field: "status", Operator: FilterEqual, Value: "active"},
+			{Field: "created_at", Operator: FilterGreaterOrEqual,
+				Value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}}, node)
+	}
+
+	node, err = ParseFilter(`size<3|(status!="a,b";done==true)`, fields)
+	if assert.Nil(err) {
+		assert.Equal(&FilterNode{Logic: FilterOr, Nodes: []*FilterNode{
+			{Field: "size", Operator: FilterLess, Value: 3},
+			{Logic: FilterAnd, Nodes: []*FilterNode{
+				{Field: "status", Operator: FilterNotEqual, Value: "a,b"},
+				{Field: "done", Operator: FilterEqual, Value: true},
+			}},
+		}}, node)
+		assert.Equal(`size<3|(status!="a,b",done==true)`, node.String())
+	}
+
+	for _, expr := range []string{
+		"owner==me",
+		"status",
+		"size==big",
+		"(status==a",
+		"status==a)",
+		`status=="a`,
+		"status==a,",
+		"((((((((((status==a))))))))))",
+	} {
+		_, err := ParseFilter(expr, fields)
+		assert.NotNil(err, expr)
+	}
+}
+
+// Ensures that FilterNodes evaluate comparisons of fields of any numeric kind, strings,
+// booleans and times.
+func TestFilterNodeMatches(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	fields := map[string]interface{}{"size": int64(5), "ratio": 0.5, "status": "active",
+		"done": false, "created_at": now}
+
+	assert.True((&FilterNode{Field: "size", Operator: FilterGreaterOrEqual, Value: 5}).Matches(fields))
+	assert.False((&FilterNode{Field: "size", Operator: FilterGreater, Value: 5}).Matches(fields))
+	assert.True((&FilterNode{Field: "ratio", Operator: FilterLess, Value: float32(1)}).Matches(fields))
+	assert.True((&FilterNode{Field: "status", Operator: FilterLess, Value: "b"}).Matches(fields))
+	assert.True((&FilterNode{Field: "done", Operator: FilterEqual, Value: false}).Matches(fields))
+	assert.True((&FilterNode{Field: "created_at", Operator: FilterLess,
+		Value: now.Add(time.Second)}).Matches(fields))
+	assert.False((&FilterNode{Field: "owner", Operator: FilterEqual, Value: "me"}).Matches(fields))
+	assert.True((&FilterNode{Field: "owner", Operator: FilterNotEqual, Value: "me"}).Matches(fields))
+	assert.False((&FilterNode{Field: "status", Operator: FilterGreater, Value: 1}).Matches(fields))
+	assert.True((&FilterNode{Logic: FilterOr, Nodes: []*FilterNode{
+		{Field: "size", Operator: FilterEqual, Value: 1},
+		{Field: "status", Operator: FilterEqual, Value: "active"},
+	}}).Matches(fields))
+}
+
+// Ensures that list requests are filtered with the filter query string variable, only
+// by the fields marked Filterable, and that the filter is documented.
+func TestListFilter(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(tasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks?filter=status==active,size%3E2", "")
+	assert.Equal(200, code)
+	assert.Equal([]interface{}{map[string]interface{}{"name": "b", "status": "active", "size": 5.0}},
+		envelope["results"])
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks?filter=name==a", "")
+	assert.Equal(400, code)
+	assert.Contains(envelope["messages"].([]interface{})[0], "filterable fields are size, status")
+	code, _ = serveJSON(api, "GET", "/api/v1/tasks?filter=size==big", "")
+	assert.Equal(400, code)
+
+	assert.Contains(queryParameters(api, "/api/v{version}/tasks"), filterKey)
+}
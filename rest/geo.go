@@ -0,0 +1,220 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// nearKey is the name of the query string variable giving the center, as lat,lng,
+	// of the circle a list is limited to.
+	nearKey = "near"
+
+	// radiusKey is the name of the query string variable giving the radius of the
+	// circle a list is limited to, e.g. 5km.
+	radiusKey = "radius"
+
+	// bboxKey is the name of the query string variable giving the bounding box, as
+	// minLng,minLat,maxLng,maxLat, a list is limited to.
+	bboxKey = "bbox"
+
+	// earthRadius is the mean radius of the Earth in meters.
+	earthRadius = 6371008.8
+)
+
+// radiusUnits are the lengths in meters of the units of radiuses.
+var radiusUnits = map[string]float64{
+	"":   1,
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.344,
+	"ft": 0.3048,
+}
+
+// GeoPoint is a location given by its latitude and longitude in degrees.
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// GeoCircle is the area parsed from the near and radius query string variables of a
+// list request, e.g. ?near=51.5072,-0.1276&radius=5km. Radiuses are in m, km, mi or
+// ft, defaulting to meters.
+type GeoCircle struct {
+	// Center is the center of the circle.
+	Center GeoPoint
+
+	// Radius is the radius of the circle in meters.
+	Radius float64
+}
+
+// Contains indicates if the point is within the circle, using the great-circle
+// distance, for ResourceHandlers filtering resources in memory.
+func (c GeoCircle) Contains(p GeoPoint) bool {
+	return Distance(c.Center, p) <= c.Radius
+}
+
+// GeoBox is the area parsed from the bbox query string variable of a list request,
+// given as minLng,minLat,maxLng,maxLat like GeoJSON bounding boxes, e.g.
+// ?bbox=-0.51,51.28,0.33,51.69. Boxes whose minimum longitude is greater than their
+// maximum cross the antimeridian.
+type GeoBox struct {
+	// Min is the south-west corner of the box.
+	Min GeoPoint
+
+	// Max is the north-east corner of the box.
+	Max GeoPoint
+}
+
+// Contains indicates if the point is within the box, for ResourceHandlers filtering
+// resources in memory.
+func (b GeoBox) Contains(p GeoPoint) bool {
+	if p.Lat < b.Min.Lat || p.Lat > b.Max.Lat {
+		return false
+	}
+	if b.Min.Lng > b.Max.Lng {
+		return p.Lng >= b.Min.Lng || p.Lng <= b.Max.Lng
+	}
+	return p.Lng >= b.Min.Lng && p.Lng <= b.Max.Lng
+}
+
+// Distance returns the great-circle distance in meters between the points.
+func Distance(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (b.Lng-a.Lng)*math.Pi/180
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// ParseGeoCircle parses the center, as lat,lng, and radius, a non-negative number
+// optionally followed by a unit, of a circle, returning an error if either is
+// malformed or out of range.
+func ParseGeoCircle(near, radius string) (GeoCircle, error) {
+	coords, err := parseCoordinates(near, 2)
+	if err != nil {
+		return GeoCircle{}, fmt.Errorf("Invalid %s: %s", nearKey, err)
+	}
+	center := GeoPoint{Lat: coords[0], Lng: coords[1]}
+	if err := validateGeoPoint(center); err != nil {
+		return GeoCircle{}, fmt.Errorf("Invalid %s: %s", nearKey, err)
+	}
+
+	radius = strings.ToLower(strings.TrimSpace(radius))
+	number := strings.TrimRight(radius, "abcdefghijklmnopqrstuvwxyz")
+	unit, ok := radiusUnits[radius[len(number):]]
+	if !ok {
+		return GeoCircle{}, fmt.Errorf("Invalid %s: unknown unit in %q; units are m, km, mi and ft",
+			radiusKey, radius)
+	}
+	length, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || length < 0 || math.IsInf(length, 0) {
+		return GeoCircle{}, fmt.Errorf("Invalid %s: %q isn't a non-negative length, e.g. 5km",
+			radiusKey, radius)
+	}
+	return GeoCircle{Center: center, Radius: length * unit}, nil
+}
+
+// ParseGeoBox parses a bounding box given as minLng,minLat,maxLng,maxLat, returning an
+// error if it's malformed, out of range or its minimum latitude is greater than its
+// maximum.
+func ParseGeoBox(bbox string) (GeoBox, error) {
+	coords, err := parseCoordinates(bbox, 4)
+	if err != nil {
+		return GeoBox{}, fmt.Errorf("Invalid %s: %s", bboxKey, err)
+	}
+	box := GeoBox{
+		Min: GeoPoint{Lat: coords[1], Lng: coords[0]},
+		Max: GeoPoint{Lat: coords[3], Lng: coords[2]},
+	}
+	for _, p := range []GeoPoint{box.Min, box.Max} {
+		if err := validateGeoPoint(p); err != nil {
+			return GeoBox{}, fmt.Errorf("Invalid %s: %s", bboxKey, err)
+		}
+	}
+	if box.Min.Lat > box.Max.Lat {
+		return GeoBox{}, fmt.Errorf("Invalid %s: minimum latitude %g is greater than maximum %g",
+			bboxKey, box.Min.Lat, box.Max.Lat)
+	}
+	return box, nil
+}
+
+// parseCoordinates parses the comma-separated numbers, returning an error if there
+// aren't the expected number of them.
+func parseCoordinates(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("%q doesn't have %d comma-separated coordinates", value, count)
+	}
+	coords := make([]float64, count)
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a coordinate", part)
+		}
+		coords[i] = coord
+	}
+	return coords, nil
+}
+
+// validateGeoPoint returns an error if the point's latitude or longitude is out of
+// range.
+func validateGeoPoint(p GeoPoint) error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %g isn't between -90 and 90", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("longitude %g isn't between -180 and 180", p.Lng)
+	}
+	return nil
+}
+
+// requestGeoArea returns the GeoCircle and GeoBox parsed from the query string
+// variables of the request, either nil if it's not given, or a 400 error if they're
+// malformed.
+func requestGeoArea(ctx RequestContext) (*GeoCircle, *GeoBox, error) {
+	near, _ := ctx.Value(nearKey).(string)
+	radius, _ := ctx.Value(radiusKey).(string)
+	bbox, _ := ctx.Value(bboxKey).(string)
+
+	var circle *GeoCircle
+	switch {
+	case near != "" && radius == "":
+		return nil, nil, BadRequest(fmt.Sprintf("Invalid %s: %s is required", nearKey, radiusKey))
+	case near == "" && radius != "":
+		return nil, nil, BadRequest(fmt.Sprintf("Invalid %s: %s is required", radiusKey, nearKey))
+	case near != "":
+		c, err := ParseGeoCircle(near, radius)
+		if err != nil {
+			return nil, nil, BadRequest(err.Error())
+		}
+		circle = &c
+	}
+
+	var box *GeoBox
+	if bbox != "" {
+		b, err := ParseGeoBox(bbox)
+		if err != nil {
+			return nil, nil, BadRequest(err.Error())
+		}
+		box = &b
+	}
+	return circle, box, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// placesResourceHandler is a ResourceHandler filtering a list of places by the
+// geographic areas of requests.
+type placesResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (p placesResourceHandler) ResourceName() string {
+	return "places"
+}
+
+func (p placesResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	places := map[string]GeoPoint{
+		"london":  {Lat: 51.5072, Lng: -0.1276},
+		"paris":   {Lat: 48.8566, Lng: 2.3522},
+		"fiji":    {Lat: -17.7134, Lng: 178.065},
+		"samoa":   {Lat: -13.759, Lng: -172.1046},
+		"croydon": {Lat: 51.3762, Lng: -0.0982},
+	}
+	results := []Resource{}
+	for _, name := range []string{"croydon", "fiji", "london", "paris", "samoa"} {
+		place := places[name]
+		if near := ctx.Near(); near != nil && !near.Contains(place) {
+			continue
+		}
+		if box := ctx.BoundingBox(); box != nil && !box.Contains(place) {
+			continue
+		}
+		results = append(results, name)
+	}
+	return results, "", nil
+}
+
+// Ensures that list requests are limited to the circle given by near and radius.
+func TestListNear(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(placesResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/places?near=51.5072,-0.1276&radius=20km", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{"croydon", "london"}, envelope["results"])
+	}
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/places?near=51.5072,-0.1276&radius=300mi", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{"croydon", "london", "paris"}, envelope["results"])
+	}
+}
+
+// Ensures that list requests are limited to the bounding box, including boxes crossing
+// the antimeridian.
+func TestListBoundingBox(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(placesResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/places?bbox=-1,48,3,52", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{"croydon", "london", "paris"}, envelope["results"])
+	}
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/places?bbox=170,-20,-170,-10", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{"fiji", "samoa"}, envelope["results"])
+	}
+}
+
+// Ensures that list requests with malformed geographic areas are rejected.
+func TestListGeoInvalid(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(placesResourceHandler{})
+
+	for _, query := range []string{
+		"near=51.5,-0.1",
+		"radius=5km",
+		"near=51.5&radius=5km",
+		"near=91,0&radius=5km",
+		"near=51.5,-0.1&radius=5parsecs",
+		"near=51.5,-0.1&radius=-5km",
+		"bbox=-1,48,3",
+		"bbox=-1,52,3,48",
+		"bbox=-181,48,3,52",
+	} {
+		code, _ := serveJSON(api, "GET", "/api/v1/places?"+query, "")
+		assert.Equal(http.StatusBadRequest, code, query)
+	}
+}
+
+// Ensures that ParseGeoCircle converts radiuses to meters.
+func TestParseGeoCircle(t *testing.T) {
+	assert := assert.New(t)
+
+	for radius, meters := range map[string]float64{
+		"250": 250, "250m": 250, "1.5km": 1500, "1 mi": 1609.344, "10FT": 3.048,
+	} {
+		circle, err := ParseGeoCircle(" 40.7 , -74 ", radius)
+		if assert.Nil(err, radius) {
+			assert.Equal(GeoPoint{Lat: 40.7, Lng: -74}, circle.Center)
+			assert.InDelta(meters, circle.Radius, 1e-9, radius)
+		}
+	}
+}
+
+// Ensures that Distance returns the great-circle distance between points.
+func TestDistance(t *testing.T) {
+	assert := assert.New(t)
+
+	london, paris := GeoPoint{Lat: 51.5072, Lng: -0.1276}, GeoPoint{Lat: 48.8566, Lng: 2.3522}
+
+	assert.InDelta(343500, Distance(london, paris), 1000)
+	assert.Equal(0.0, Distance(paris, paris))
+}
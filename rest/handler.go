@@ -17,14 +17,25 @@ limitations under the License.
 package rest
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
 
-	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
 )
 
+// errEmptyBody is returned when a create or update request has no body.
+var errEmptyBody = errors.New("Request body is empty")
+
 // Resource represents a domain model.
 type Resource interface{}
 
@@ -88,7 +99,8 @@ type ResourceHandler interface {
 	// ReadResource is the logic that corresponds to reading a single resource by its ID
 	// at GET /api/:version/resourceName/{id}. Typically, this would make some sort of
 	// database query to load the resource. If the resource doesn't exist, nil should be
-	// returned along with an appropriate error.
+	// returned along with an appropriate error. Returning nil without an error results
+	// in a 404 Not Found response.
 	ReadResource(RequestContext, string, string) (Resource, error)
 
 	// UpdateResourceList is the logic that corresponds to updating a collection of
@@ -122,50 +134,120 @@ type ResourceHandler interface {
 
 	// Rules returns the resource rules to apply to incoming requests and outgoing
 	// responses. The default behavior, seen in BaseResourceHandler, is to apply no
-	// rules.
+	// rules. It's called once when the ResourceHandler is registered.
 	Rules() Rules
 }
 
 // requestHandler constructs http.HandlerFuncs responsible for handling HTTP requests.
 type requestHandler struct {
 	API
-	router *mux.Router
+	urls           urlBuilder
+	trustedProxies []*net.IPNet
+	events         *eventBus
+	operations     *operationStore
+	changes        ChangeStore
+	uploads        *uploadManager
+	views          ViewStore
+	scheduler      *scheduler
+	muted          map[string]struct{}
+}
+
+// mute stops the mutations of the resources being published as Events or appended to
+// the change feed. It must be called before requests are served.
+func (h *requestHandler) mute(resources ...string) {
+	for _, resource := range resources {
+		h.muted[resource] = struct{}{}
+	}
+}
+
+// newContext returns a RequestContext for a request to the named resource which is
+// able to build URLs using the API router. The RequestContext is canceled when the
+// client disconnects, the configured RequestTimeout elapses or the returned
+// CancelFunc is called, which must happen once the request has been handled.
+func (h requestHandler) newContext(r *http.Request, w http.ResponseWriter,
+	resourceName string) (RequestContext, context.CancelFunc) {
+
+	return h.newContextWithBody(r, w, resourceName, readRequestBody(r))
+}
+
+// newInputContext returns a RequestContext for a create or update request to the
+// handler. If the handler is a StreamingPayloadHandler streaming the request, its body
+// isn't read up front but available from PayloadDecoder and PayloadItems.
+func (h requestHandler) newInputContext(r *http.Request, w http.ResponseWriter,
+	handler ResourceHandler, resourceName string, rules Rules,
+	method HandleMethod) (RequestContext, context.CancelFunc) {
+
+	version := PathVars(r)[versionKey]
+	limits, ok := streamLimits(handler, method, version)
+	if !ok {
+		return h.newContext(r, w, resourceName)
+	}
+	ctx, cancel := h.newContextWithBody(r, w, resourceName, &bytes.Buffer{})
+	stream := newPayloadStream(r.Body, limits, rules, version)
+	ctx.(*gorillaRequestContext).stream = stream
+	return ctx, func() {
+		stream.close()
+		cancel()
+	}
+}
+
+// newContextWithBody returns a RequestContext for the request with the given body.
+func (h requestHandler) newContextWithBody(r *http.Request, w http.ResponseWriter,
+	resourceName string, body *bytes.Buffer) (RequestContext, context.CancelFunc) {
+
+	var parent context.Context = r.Context()
+	cancel := context.CancelFunc(func() {})
+	config := h.Configuration()
+	if config != nil && config.RequestTimeout > 0 {
+		parent, cancel = context.WithTimeout(parent, config.RequestTimeout)
+	}
+
+	ctx := newRequestContext(parent, r, w, body)
+	gctx := ctx.(*gorillaRequestContext)
+	gctx.urls = h.urls
+	gctx.trustedProxies = h.trustedProxies
+	gctx.resourceName = resourceName
+	if config != nil {
+		gctx.logger = config.Logger
+	}
+	return ctx, cancel
 }
 
 // handleCreate returns a HandlerFunc which will deserialize the request payload, pass
 // it to the provided create function, and then serialize and dispatch the response.
 // The serialization mechanism used is specified by the "format" query parameter.
 func (h requestHandler) handleCreate(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
+		ctx, cancel := h.newInputContext(r, w, handler, resource, rules, HandleCreate)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 		version := ctx.Version()
-		rules := handler.Rules()
 
-		data, err := decodePayload(ctx.Body().Bytes())
+		ctx, data, err := decodeInput(ctx, handler, rules, version)
 		if err != nil {
-			// Payload decoding failed.
-			ctx = ctx.setError(BadRequest(err.Error()))
+			ctx = ctx.setError(err)
 		} else {
-			data, err := applyInboundRules(data, rules, version)
-			if err != nil {
-				// Type coercion failed.
-				ctx = ctx.setError(UnprocessableRequest(err.Error()))
-			} else {
-				resource, err := handler.CreateResource(ctx, data, ctx.Version())
-				if err == nil {
-					resource = applyOutboundRules(resource, rules, version)
-				}
+			resource, err := handler.CreateResource(ctx, data, ctx.Version())
+			if async, ok := resource.(*AsyncResult); ok && err == nil {
+				h.sendResponse(h.startOperation(ctx, handler, EventCreate, async))
+				return
+			}
+			if err == nil {
+				resource = applyOutboundRules(resource, rules, version)
+				h.publish(ctx, EventCreate, handler, resource)
+			}
 
-				if resource != nil {
-					ctx = ctx.setResult(resource)
-					ctx = ctx.setStatus(http.StatusCreated)
-				} else {
-					ctx = ctx.setStatus(http.StatusNoContent)
-				}
+			if resource != nil {
+				ctx = ctx.setResult(resource)
+				ctx = ctx.setStatus(http.StatusCreated)
+			} else {
+				ctx = ctx.setStatus(http.StatusNoContent)
+			}
 
-				if err != nil {
-					ctx = ctx.setError(err)
-				}
+			if err != nil {
+				ctx = ctx.setError(err)
 			}
 		}
 
@@ -177,42 +259,148 @@ func (h requestHandler) handleCreate(handler ResourceHandler) http.Handler {
 // provided read function and then serialize and dispatch the response. The
 // serialization mechanism used is specified by the "format" query parameter.
 func (h requestHandler) handleReadList(handler ResourceHandler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
-		version := ctx.Version()
-		rules := handler.Rules()
+	resource := handler.ResourceName()
 
-		resources, cursor, err := handler.ReadResourceList(
-			ctx, ctx.Limit(), ctx.Cursor(), version)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, resource)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 
-		if err == nil {
-			// Apply rules to results.
-			for idx, resource := range resources {
-				resources[idx] = applyOutboundRules(resource, rules, version)
-			}
+		token, err := h.awaitChanges(ctx, resource)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+		if token != "" {
+			ctx.ResponseHeader().Set(changeTokenHeader, token)
 		}
 
-		ctx = ctx.setResult(resources)
-		ctx = ctx.setCursor(cursor)
-		ctx = ctx.setError(err)
-		ctx = ctx.setStatus(http.StatusOK)
+		ctx, err = h.viewContext(ctx, handler)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
 
-		h.sendResponse(ctx)
+		h.sendResponse(h.readList(ctx, handler, HandleReadList,
+			func(ctx RequestContext) ([]Resource, string, error) {
+				return handler.ReadResourceList(
+					ctx, ctx.Limit(), ctx.Cursor(), ctx.Version())
+			}))
 	})
 }
 
+// listContext returns the context with the filter, time range and geographic areas of
+// a list request to the ResourceHandler, or a 400 error if any are malformed.
+func listContext(ctx RequestContext, rules Rules, version string) (RequestContext, error) {
+	filter, err := listFilter(ctx, rules, version)
+	if err != nil {
+		return ctx, err
+	}
+	if filter != nil {
+		ctx = ctx.WithValue(listFilterKey, filter)
+	}
+	timeRange, err := requestTimeRange(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if !timeRange.IsZero() {
+		ctx = ctx.WithValue(timeRangeKey, timeRange)
+	}
+	circle, box, err := requestGeoArea(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if circle != nil {
+		ctx = ctx.WithValue(geoCircleKey, circle)
+	}
+	if box != nil {
+		ctx = ctx.WithValue(geoBoxKey, box)
+	}
+	return ctx, nil
+}
+
+// readList parses the list filter, time range, geographic area and sort parameters
+// onto the context, calls the provided read function and prepares the list response,
+// applying outbound rules, expansions, pagination metadata and links to the results.
+// It's shared by the list endpoints, e.g. reads of collections and searches.
+func (h requestHandler) readList(ctx RequestContext, handler ResourceHandler,
+	method HandleMethod,
+	read func(RequestContext) ([]Resource, string, error)) RequestContext {
+
+	rules, version := handler.Rules(), ctx.Version()
+
+	ctx, err := listContext(ctx, rules, version)
+	if err != nil {
+		return ctx.setError(err)
+	}
+	sortFields, err := listSort(ctx, rules, version)
+	if err != nil {
+		return ctx.setError(err)
+	}
+	if sortFields != nil {
+		ctx = ctx.WithValue(sortFieldsKey, sortFields)
+	}
+
+	resources, cursor, err := read(ctx)
+
+	if err == nil {
+		// Apply rules to results.
+		for idx, resource := range resources {
+			resources[idx] = applyOutboundRules(resource, rules, version)
+		}
+		err = h.expand(ctx, handler, resources...)
+	}
+	if fields, ok := ctx.Value(viewFieldsKey).([]string); ok && err == nil {
+		for idx, resource := range resources {
+			resources[idx] = projectFields(resource, fields)
+		}
+	}
+
+	if cursor != "" {
+		ctx.SetNextCursor(cursor)
+	}
+	var page Payload
+	if err == nil && offsetPaginated(ctx) {
+		if page, err = pageMeta(ctx, handler); err == nil {
+			ctx = ctx.WithValue(metaKey, page)
+		}
+	}
+	if err == nil {
+		h.addStandardLinks(ctx, handler, method)
+		setLinkHeader(ctx, page, len(resources))
+	}
+
+	ctx = ctx.setResult(resources)
+	ctx = ctx.setError(err)
+	return ctx.setStatus(http.StatusOK)
+}
+
 // handleRead returns a Handler which will pass the resource id to the provided
 // read function and then serialize and dispatch the response. The serialization
 // mechanism used is specified by the "format" query parameter.
 func (h requestHandler) handleRead(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
+		ctx, cancel := h.newContext(r, w, resource)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 		version := ctx.Version()
-		rules := handler.Rules()
 
 		resource, err := handler.ReadResource(ctx, ctx.ResourceID(), version)
+		if async, ok := resource.(*AsyncResult); ok && err == nil {
+			h.sendResponse(h.startOperation(ctx, handler, "", async))
+			return
+		}
+		if err == nil && isNilResource(resource) {
+			// Enforce the ReadResource contract: no resource means it doesn't exist.
+			resource = nil
+			err = NotFound("Resource not found")
+		}
 		if err == nil {
 			resource = applyOutboundRules(resource, rules, version)
+			err = h.expand(ctx, handler, resource)
+			h.addStandardLinks(ctx, handler, HandleRead)
 		}
 
 		ctx = ctx.setResult(resource)
@@ -228,44 +416,30 @@ func (h requestHandler) handleRead(handler ResourceHandler) http.Handler {
 // response. The serialization mechanism used is specified by the "format" query
 // parameter.
 func (h requestHandler) handleUpdateList(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
+		ctx, cancel := h.newInputContext(r, w, handler, resource, rules, HandleUpdateList)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 		version := ctx.Version()
-		rules := handler.Rules()
-
-		payloadStr := ctx.Body().Bytes()
-		var data []Payload
-		var err error
-		data, err = decodePayloadSlice(payloadStr)
-		if err != nil {
-			var p Payload
-			p, err = decodePayload(payloadStr)
-			data = []Payload{p}
-		}
 
+		ctx, data, err := decodeInputList(ctx, handler, rules, version)
 		if err != nil {
-			// Payload decoding failed.
-			ctx = ctx.setError(BadRequest(err.Error()))
+			ctx = ctx.setError(err)
 		} else {
-			for i := range data {
-				data[i], err = applyInboundRules(data[i], rules, version)
-			}
-			if err != nil {
-				// Type coercion failed.
-				ctx = ctx.setError(UnprocessableRequest(err.Error()))
-			} else {
-				resources, err := handler.UpdateResourceList(ctx, data, version)
-				if err == nil {
-					// Apply rules to results.
-					for idx, resource := range resources {
-						resources[idx] = applyOutboundRules(resource, rules, version)
-					}
+			resources, err := handler.UpdateResourceList(ctx, data, version)
+			if err == nil {
+				// Apply rules to results.
+				for idx, resource := range resources {
+					resources[idx] = applyOutboundRules(resource, rules, version)
 				}
-
-				ctx = ctx.setResult(resources)
-				ctx = ctx.setError(err)
-				ctx = ctx.setStatus(http.StatusOK)
+				h.publish(ctx, EventUpdate, handler, resources...)
 			}
+
+			ctx = ctx.setResult(resources)
+			ctx = ctx.setError(err)
+			ctx = ctx.setStatus(http.StatusOK)
 		}
 
 		h.sendResponse(ctx)
@@ -277,31 +451,36 @@ func (h requestHandler) handleUpdateList(handler ResourceHandler) http.Handler {
 // response. The serialization mechanism used is specified by the "format" query
 // parameter.
 func (h requestHandler) handleUpdate(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
+		ctx, cancel := h.newInputContext(r, w, handler, resource, rules, HandleUpdate)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 		version := ctx.Version()
-		rules := handler.Rules()
 
-		data, err := decodePayload(ctx.Body().Bytes())
+		ctx, err := checkWritePreconditions(ctx, handler, version)
+		var data Payload
+		if err == nil {
+			ctx, data, err = decodeInput(ctx, handler, rules, version)
+		}
 		if err != nil {
-			// Payload decoding failed.
-			ctx = ctx.setError(BadRequest(err.Error()))
+			ctx = ctx.setError(err)
 		} else {
-			data, err := applyInboundRules(data, rules, version)
-			if err != nil {
-				// Type coercion failed.
-				ctx = ctx.setError(UnprocessableRequest(err.Error()))
-			} else {
-				resource, err := handler.UpdateResource(
-					ctx, ctx.ResourceID(), data, version)
-				if err == nil {
-					resource = applyOutboundRules(resource, rules, version)
-				}
-
-				ctx = ctx.setResult(resource)
-				ctx = ctx.setError(err)
-				ctx = ctx.setStatus(http.StatusOK)
+			resource, err := handler.UpdateResource(
+				ctx, ctx.ResourceID(), data, version)
+			if async, ok := resource.(*AsyncResult); ok && err == nil {
+				h.sendResponse(h.startOperation(ctx, handler, EventUpdate, async))
+				return
 			}
+			if err == nil {
+				resource = applyOutboundRules(resource, rules, version)
+				h.publish(ctx, EventUpdate, handler, resource)
+			}
+
+			ctx = ctx.setResult(resource)
+			ctx = ctx.setError(err)
+			ctx = ctx.setStatus(http.StatusOK)
 		}
 
 		h.sendResponse(ctx)
@@ -312,19 +491,33 @@ func (h requestHandler) handleUpdate(handler ResourceHandler) http.Handler {
 // delete function and then serialize and dispatch the response. The serialization
 // mechanism used is specified by the "format" query parameter.
 func (h requestHandler) handleDelete(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := NewContextWithRouter(nil, r, w, h.router)
+		ctx, cancel := h.newContext(r, w, resource)
+		defer cancel()
+		defer h.recoverPanic(ctx)
 		version := ctx.Version()
-		rules := handler.Rules()
 
-		resource, err := handler.DeleteResource(ctx, ctx.ResourceID(), version)
-		if err == nil {
-			resource = applyOutboundRules(resource, rules, version)
-		}
+		ctx, err := checkWritePreconditions(ctx, handler, version)
+		if err != nil {
+			ctx = ctx.setError(err)
+		} else {
+			resource, err := handler.DeleteResource(ctx, ctx.ResourceID(), version)
+			if async, ok := resource.(*AsyncResult); ok && err == nil {
+				h.sendResponse(h.startOperation(ctx, handler, EventDelete, async))
+				return
+			}
+			if err == nil {
+				resource = applyOutboundRules(resource, rules, version)
+				h.publish(ctx, EventDelete, handler, resource)
+				h.addStandardLinks(ctx, handler, HandleRead)
+			}
 
-		ctx = ctx.setResult(resource)
-		ctx = ctx.setError(err)
-		ctx = ctx.setStatus(http.StatusOK)
+			ctx = ctx.setResult(resource)
+			ctx = ctx.setError(err)
+			ctx = ctx.setStatus(http.StatusOK)
+		}
 
 		h.sendResponse(ctx)
 	})
@@ -333,39 +526,172 @@ func (h requestHandler) handleDelete(handler ResourceHandler) http.Handler {
 // sendResponse writes a success or error response to the provided http.ResponseWriter
 // based on the contents of the RequestContext.
 func (h requestHandler) sendResponse(ctx RequestContext) {
+	switch ctx.Err() {
+	case context.Canceled:
+		// The client disconnected, so there's nobody to respond to.
+		return
+	case context.DeadlineExceeded:
+		ctx = ctx.setError(CustomError("Request timed out", http.StatusServiceUnavailable))
+	}
+
+	config := h.Configuration()
+	if config != nil && ctx.Error() != nil {
+		err := mapError(config.ErrorMapper, ctx.Error())
+		ctx = ctx.setError(localizeError(ctx, config.MessageCatalog, err))
+	}
+
 	format := ctx.ResponseFormat()
 	serializer, err := h.responseSerializer(format)
 	if err != nil {
 		// Fall back to json serialization.
 		serializer = jsonSerializer{}
-		ctx = ctx.setError(BadRequest(fmt.Sprintf("Format not implemented: %s", format)))
+		ctx = ctx.setError(err)
 	}
 
-	sendResponse(ctx.ResponseWriter(), NewResponse(ctx), serializer)
+	ctx.ResponseHeader().Set(requestIDHeader, ctx.RequestID())
+
+	if retryAfter, ok := retryAfterHeader(ctx.Error()); ok {
+		ctx.ResponseHeader().Set("Retry-After", retryAfter)
+	}
+
+	// Miscellaneous persistent warnings use warn-code 299 (RFC 7234, section 5.5).
+	for _, warning := range ctx.Warnings() {
+		ctx.ResponseHeader().Add("Warning", fmt.Sprintf("299 - %s", strconv.Quote(warning)))
+	}
+
+	resp := NewResponse(ctx)
+	if config != nil && config.ProblemDetails && ctx.Error() != nil {
+		resp = newProblemResponse(ctx)
+		resp.ContentType = problemContentTypes[serializer.ContentType()]
+	}
+	if config != nil {
+		resp.MaxSize = config.MaxResponseSize
+	}
+	if req, ok := ctx.Request(); ok && config != nil && config.ETags && ctx.Error() == nil &&
+		(req.Method == "GET" || req.Method == "HEAD") {
+		resp.Conditional = req
+	}
+
+	if stats, ok := ctx.Value(routeStatsKey).(*routeStats); ok {
+		start := time.Now()
+		sendResponse(ctx.ResponseWriter(), resp, serializer)
+		atomic.AddInt64(&stats.serializeNanos, int64(time.Since(start)))
+	} else {
+		sendResponse(ctx.ResponseWriter(), resp, serializer)
+	}
+
+	// Remove any temporary files created for multipart uploads.
+	if form, ok := ctx.Value(multipartFormKey).(*multipart.Form); ok {
+		form.RemoveAll()
+	}
 }
 
 // sendResponse writes a response to the http.ResponseWriter.
 func sendResponse(w http.ResponseWriter, r response, serializer ResponseSerializer) {
 	status := r.Status
 	contentType := serializer.ContentType()
+	if r.ContentType != "" {
+		contentType = r.ContentType
+	}
 
 	var response []byte
 	if r.Payload != nil {
 		var err error
-		response, err = serializer.Serialize(r.Payload)
-		if err != nil {
+		errSerializer, isErrSerializer := serializer.(ErrorSerializer)
+		bufSerializer, isBufSerializer := serializer.(bufferSerializer)
+		switch {
+		case isErrSerializer && r.Error != nil:
+			response, err = errSerializer.SerializeError(r.Error, r.Payload)
+		case isBufSerializer:
+			buf := getResponseBuffer()
+			defer putResponseBuffer(buf)
+			buf.limit = r.MaxSize
+			err = bufSerializer.serializeTo(buf, r.Payload)
+			response = buf.Bytes()
+		default:
+			response, err = serializer.Serialize(r.Payload)
+		}
+		if err == nil && r.MaxSize > 0 && len(response) > r.MaxSize {
+			err = errResponseTooLarge
+		}
+		if err == errResponseTooLarge {
+			log.Printf("Response serialization aborted: exceeds %d bytes", r.MaxSize)
+			status = http.StatusInsufficientStorage
+			contentType = "text/plain"
+			response = []byte(err.Error())
+		} else if err != nil {
 			log.Printf("Response serialization failed: %s", err)
 			status = http.StatusInternalServerError
 			contentType = "text/plain"
 			response = []byte(err.Error())
+		} else if r.Conditional != nil && status == http.StatusOK && w.Header().Get("ETag") == "" {
+			etag := contentETag(response)
+			w.Header().Set("ETag", etag)
+			if evaluatePreconditions(r.Conditional, etag, time.Time{}) == http.StatusNotModified {
+				status = http.StatusNotModified
+			}
 		}
 	}
 
+	// 304 responses must not contain a body.
+	if status == http.StatusNotModified {
+		w.WriteHeader(status)
+		return
+	}
+
 	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	w.Write(response)
 }
 
+// isNilResource indicates if the Resource is nil or a nil pointer, map, or slice.
+func isNilResource(resource Resource) bool {
+	if resource == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(resource); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// decodeRequestPayload returns the Payload for the request. For multipart/form-data
+// requests, this contains the non-file form values. For all other requests, the body
+// is decoded as JSON using decodePayload.
+func decodeRequestPayload(ctx RequestContext) (Payload, error) {
+	if _, ok := isMultipart(ctx.Header().Get("Content-Type")); ok {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return nil, err
+		}
+		return formPayload(form), nil
+	}
+
+	body := ctx.Body().Bytes()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, errEmptyBody
+	}
+
+	data, err := decodePayload(body)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	return data, nil
+}
+
+// decodeError returns an error describing why the JSON request body could not be
+// decoded, including the byte offset at which decoding failed when known.
+func decodeError(err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("Malformed JSON at offset %d: %s", e.Offset, e)
+	case *json.UnmarshalTypeError:
+		return fmt.Errorf("Unexpected JSON %s at offset %d", e.Value, e.Offset)
+	}
+	return err
+}
+
 // decodePayload unmarshals the JSON payload and returns the resulting map. If the
 // content is empty, an empty map is returned. If decoding fails, nil is returned
 // with an error.
@@ -18,6 +18,7 @@ package rest
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,3 +93,29 @@ func TestDecodePayloadSliceHappyPath(t *testing.T) {
 	assert.Equal([]Payload{Payload{"foo": "bar", "baz": float64(1)}}, decoded)
 	assert.Nil(err)
 }
+
+// Ensures that decodeError includes the offset of JSON syntax and type errors.
+func TestDecodeError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := decodePayload([]byte(`{"foo":`))
+	assert.Equal("Malformed JSON at offset 7: unexpected end of JSON input", decodeError(err).Error())
+
+	_, err = decodePayload([]byte(`[1, 2]`))
+	assert.Equal("Unexpected JSON array at offset 1", decodeError(err).Error())
+
+	err = fmt.Errorf("foo")
+	assert.Equal(err, decodeError(err))
+}
+
+// Ensures that isNilResource detects nil interfaces and nil pointers.
+func TestIsNilResource(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isNilResource(nil))
+	assert.True(isNilResource((*TestResource)(nil)))
+	assert.True(isNilResource(map[string]interface{}(nil)))
+	assert.False(isNilResource(&TestResource{}))
+	assert.False(isNilResource(TestResource{}))
+	assert.False(isNilResource(""))
+}
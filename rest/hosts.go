@@ -0,0 +1,61 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net"
+	"strings"
+)
+
+// RegisterHost dispatches requests for the host to the API instead, so APIs with their
+// own resources, middleware and versions can be served by one server. The host is
+// matched case-insensitively, ignoring the port, and may begin with "*." to match any of
+// its subdomains, e.g. "*.example.com", which exact hosts take precedence over. The API
+// is only used as an http.Handler, so it needn't be started and the lifecycle of the
+// server, e.g. OnShutdown hooks, is managed by this API.
+func (r *muxAPI) RegisterHost(host string, api API) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts[normalizeHost(host)] = api
+}
+
+// hostAPI returns the API registered for the host or nil if there is none. The caller
+// must hold the read lock.
+func (r *muxAPI) hostAPI(host string) API {
+	if len(r.hosts) == 0 {
+		return nil
+	}
+	host = normalizeHost(host)
+	if api, ok := r.hosts[host]; ok {
+		return api
+	}
+	for i := strings.IndexByte(host, '.'); i >= 0; i = strings.IndexByte(host, '.') {
+		host = host[i+1:]
+		if api, ok := r.hosts["*."+host]; ok {
+			return api
+		}
+	}
+	return nil
+}
+
+// normalizeHost returns the host in lower case without its port or a trailing dot.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that requests are dispatched to the API registered for their host, with
+// exact hosts taking precedence over wildcards, and to the API itself otherwise.
+func TestRegisterHost(t *testing.T) {
+	assert := assert.New(t)
+	public := NewAPI(&Configuration{})
+	public.RegisterResourceHandler(largeResourceHandler{count: 1})
+	admin := NewAPI(&Configuration{})
+	admin.RegisterHandlerFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin"))
+	})
+	tenants := NewAPI(&Configuration{})
+	tenants.RegisterHandlerFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tenant"))
+	})
+	public.RegisterHost("Admin.Example.com", admin)
+	public.RegisterHost("*.example.com", tenants)
+
+	serve := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		public.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal("admin", serve("admin.example.com:8443", "/whoami").Body.String())
+	assert.Equal("admin", serve("ADMIN.example.com.", "/whoami").Body.String())
+	assert.Equal("tenant", serve("acme.example.com", "/whoami").Body.String())
+	assert.Equal("tenant", serve("eu.acme.example.com", "/whoami").Body.String())
+	assert.Equal(http.StatusNotFound, serve("admin.example.com", "/api/v1/rows").Code)
+	assert.Equal(http.StatusOK, serve("example.com", "/api/v1/rows").Code)
+	assert.Equal(http.StatusNotFound, serve("example.com", "/whoami").Code)
+}
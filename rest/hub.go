@@ -0,0 +1,199 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// eventFieldPrefix prefixes the query string variables restricting the Events streamed
+// to those whose payloads have fields with given values, e.g. field.status=active.
+const eventFieldPrefix = "field."
+
+// EventFilter selects the Events of a resource delivered to a subscriber.
+type EventFilter struct {
+	// Resource is the name of the resource whose Events are selected.
+	Resource string `json:"resource"`
+
+	// ResourceID, if set, restricts the Events to those of the resource with the ID.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Fields, if set, restricts the Events to those whose payloads have the fields
+	// with the values, compared as strings.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Matches indicates if the filter selects the Event.
+func (f EventFilter) Matches(event Event) bool {
+	if event.Resource != f.Resource {
+		return false
+	}
+	if f.ResourceID != "" && event.ResourceID != f.ResourceID {
+		return false
+	}
+	if len(f.Fields) == 0 {
+		return true
+	}
+	payload := eventPayloadFields(event.Payload)
+	for name, value := range f.Fields {
+		field, ok := payload[name]
+		if !ok || field == nil || fmt.Sprint(field) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// eventPayloadFields returns the fields of the Event payload, encoding payloads which
+// aren't maps as JSON first.
+func eventPayloadFields(payload Resource) map[string]interface{} {
+	switch fields := payload.(type) {
+	case Payload:
+		return fields
+	case map[string]interface{}:
+		return fields
+	}
+	fields := map[string]interface{}{}
+	if data, err := json.Marshal(payload); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// eventQueryFilter returns the EventFilter of the resource given by the query string
+// of a request for its Events: the resource_id variable and the field.{name}
+// variables.
+func eventQueryFilter(resource string, query url.Values) EventFilter {
+	filter := EventFilter{Resource: resource, ResourceID: query.Get(resourceIDKey)}
+	for key, values := range query {
+		if !strings.HasPrefix(key, eventFieldPrefix) || len(values) == 0 {
+			continue
+		}
+		if filter.Fields == nil {
+			filter.Fields = map[string]string{}
+		}
+		filter.Fields[strings.TrimPrefix(key, eventFieldPrefix)] = values[0]
+	}
+	return filter
+}
+
+// EventAuthorizer is an optional interface implemented by ResourceHandlers restricting
+// which subscribers receive the Events of their resources, e.g. to the tenant of the
+// Principal. Without it, every subscriber authenticated by the handler receives them.
+type EventAuthorizer interface {
+	// AuthorizeEvent indicates if the subscriber which made the request, whose
+	// Principal is available from RequestPrincipal, may receive the Event. It's
+	// called for each Event selected by the subscriber's filters, including those
+	// replayed after reconnecting, so it should be fast.
+	AuthorizeEvent(r *http.Request, event Event) bool
+}
+
+// eventAuthorizer returns the ResourceHandler as an EventAuthorizer, if it is one.
+func eventAuthorizer(handler ResourceHandler) (EventAuthorizer, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	authorizer, ok := handler.(EventAuthorizer)
+	return authorizer, ok
+}
+
+// resourceHandler returns the ResourceHandler registered for the resource, or nil.
+func (h requestHandler) resourceHandler(resource string) ResourceHandler {
+	for _, handler := range h.ResourceHandlers() {
+		if handler.ResourceName() == resource {
+			return handler
+		}
+	}
+	return nil
+}
+
+// eventSubscriber is a subscriber to the Events fanned out by the eventBus. It receives
+// the Events selected by any of its EventFilters which the ResourceHandlers of their
+// resources authorize the request it subscribed with to see, so subscribers only
+// receive the Events they're allowed to.
+type eventSubscriber struct {
+	h       requestHandler
+	r       *http.Request
+	mu      sync.RWMutex
+	filters []EventFilter
+}
+
+// newEventSubscriber returns an eventSubscriber for the request with the filters.
+func (h requestHandler) newEventSubscriber(r *http.Request, filters ...EventFilter) *eventSubscriber {
+	return &eventSubscriber{h: h, r: r, filters: filters}
+}
+
+// add adds the filter, unless the subscriber already has it.
+func (s *eventSubscriber) add(filter EventFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index(filter) < 0 {
+		s.filters = append(s.filters, filter)
+	}
+}
+
+// remove removes the filter, if the subscriber has it.
+func (s *eventSubscriber) remove(filter EventFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.index(filter); i >= 0 {
+		s.filters = append(s.filters[:i], s.filters[i+1:]...)
+	}
+}
+
+// index returns the index of the filter, or -1 if the subscriber doesn't have it. The
+// caller must hold the lock.
+func (s *eventSubscriber) index(filter EventFilter) int {
+	for i, f := range s.filters {
+		if f.Resource == filter.Resource && f.ResourceID == filter.ResourceID &&
+			(len(f.Fields) == 0 && len(filter.Fields) == 0 ||
+				reflect.DeepEqual(f.Fields, filter.Fields)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// accepts indicates if the subscriber receives the Event. It's used as the filter of
+// the subscriber's eventSubscription.
+func (s *eventSubscriber) accepts(event Event) bool {
+	s.mu.RLock()
+	matched := false
+	for _, filter := range s.filters {
+		if filter.Matches(event) {
+			matched = true
+			break
+		}
+	}
+	s.mu.RUnlock()
+	return matched && s.authorized(event)
+}
+
+// authorized indicates if the ResourceHandler of the Event's resource authorizes the
+// subscriber to receive it.
+func (s *eventSubscriber) authorized(event Event) bool {
+	if authorizer, ok := eventAuthorizer(s.h.resourceHandler(event.Resource)); ok {
+		return authorizer.AuthorizeEvent(s.r, event)
+	}
+	return true
+}
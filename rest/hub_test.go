@@ -0,0 +1,165 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// tenantsResourceHandler is a ResourceHandler authorizing subscribers to receive only
+// the Events of their tenant, given by the X-Tenant header.
+type tenantsResourceHandler struct {
+	widgetsResourceHandler
+}
+
+func (t tenantsResourceHandler) ResourceName() string {
+	return "tenants"
+}
+
+func (t tenantsResourceHandler) Authenticate(r *http.Request) error {
+	SetRequestPrincipal(r, &Principal{ID: r.Header.Get("X-Tenant")})
+	return nil
+}
+
+func (t tenantsResourceHandler) AuthorizeEvent(r *http.Request, event Event) bool {
+	principal, ok := RequestPrincipal(r)
+	return ok && eventPayloadFields(event.Payload)["tenant"] == principal.ID
+}
+
+// Ensures that EventFilters select Events by resource, ID and payload fields, whether
+// the payloads are maps or structs.
+func TestEventFilter(t *testing.T) {
+	assert := assert.New(t)
+	type widget struct {
+		Status string `json:"status"`
+		Size   int    `json:"size"`
+	}
+	event := Event{Resource: "widgets", ResourceID: "42", Payload: Payload{"status": "active", "size": 3}}
+
+	assert.True(EventFilter{Resource: "widgets"}.Matches(event))
+	assert.False(EventFilter{Resource: "gadgets"}.Matches(event))
+	assert.True(EventFilter{Resource: "widgets", ResourceID: "42"}.Matches(event))
+	assert.False(EventFilter{Resource: "widgets", ResourceID: "7"}.Matches(event))
+	assert.True(EventFilter{Resource: "widgets",
+		Fields: map[string]string{"status": "active", "size": "3"}}.Matches(event))
+	assert.False(EventFilter{Resource: "widgets",
+		Fields: map[string]string{"status": "retired"}}.Matches(event))
+	assert.False(EventFilter{Resource: "widgets",
+		Fields: map[string]string{"color": ""}}.Matches(event))
+
+	event.Payload = widget{Status: "active", Size: 3}
+	assert.True(EventFilter{Resource: "widgets",
+		Fields: map[string]string{"status": "active", "size": "3"}}.Matches(event))
+}
+
+// Ensures that Server-Sent Event subscribers only receive the Events selected by their
+// query string which the ResourceHandler authorizes them to see.
+func TestEventStreamFiltering(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{}})
+	api.RegisterResourceHandler(tenantsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/tenants/events?field.status=active", nil)
+	req.Header.Set("X-Tenant", "acme")
+	events, err := http.DefaultClient.Do(req)
+	if !assert.Nil(err) {
+		return
+	}
+	defer events.Body.Close()
+
+	for _, body := range []string{
+		`{"tenant": "initech", "status": "active"}`,
+		`{"tenant": "acme", "status": "inactive"}`,
+		`{"tenant": "acme", "status": "active"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/api/v1/tenants", "application/json", strings.NewReader(body))
+		if assert.Nil(err) {
+			resp.Body.Close()
+		}
+	}
+
+	header, event := readEvent(t, bufio.NewReader(events.Body))
+	assert.Equal("3 create", header)
+	assert.Equal(map[string]interface{}{"tenant": "acme", "status": "active"}, event.Payload)
+
+	req, _ = http.NewRequest("GET", ts.URL+"/api/v1/tenants/events", nil)
+	req.Header.Set("X-Tenant", "initech")
+	req.Header.Set("Last-Event-ID", "0")
+	resumed, err := http.DefaultClient.Do(req)
+	if !assert.Nil(err) {
+		return
+	}
+	defer resumed.Body.Close()
+	header, _ = readEvent(t, bufio.NewReader(resumed.Body))
+	assert.Equal("1 create", header)
+}
+
+// Ensures that WebSocket subscribers can filter Events by payload fields and only
+// receive the Events the ResourceHandler authorizes them to see.
+func TestEventSocketFiltering(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{WebSocketPath: "/api/events"}})
+	api.RegisterResourceHandler(tenantsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	config, _ := websocket.NewConfig("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/events", ts.URL)
+	config.Header.Set("X-Tenant", "acme")
+	conn, err := websocket.DialConfig(config)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close()
+	websocket.JSON.Send(conn, webSocketRequest{Action: "subscribe", Resource: "tenants",
+		Fields: map[string]string{"status": "active"}})
+	assert.Equal("subscribed", receiveMessage(t, conn).Type)
+
+	for _, body := range []string{
+		`{"tenant": "initech", "status": "active"}`,
+		`{"tenant": "acme", "status": "inactive"}`,
+		`{"tenant": "acme", "status": "active"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/api/v1/tenants", "application/json", strings.NewReader(body))
+		if assert.Nil(err) {
+			resp.Body.Close()
+		}
+	}
+	message := receiveMessage(t, conn)
+	if assert.Equal("event", message.Type) {
+		assert.Equal(uint64(3), message.Event.ID)
+	}
+
+	websocket.JSON.Send(conn, webSocketRequest{Action: "unsubscribe", Resource: "tenants",
+		Fields: map[string]string{"status": "active"}})
+	assert.Equal("unsubscribed", receiveMessage(t, conn).Type)
+	resp, err := http.Post(ts.URL+"/api/v1/tenants", "application/json",
+		strings.NewReader(`{"tenant": "acme", "status": "active"}`))
+	if assert.Nil(err) {
+		resp.Body.Close()
+	}
+	websocket.JSON.Send(conn, webSocketRequest{Action: "ping"})
+	assert.Equal("error", receiveMessage(t, conn).Type)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MessageCatalog provides translations of error messages. It's set on the
+// Configuration to render LocalizedError messages in the caller's language, as
+// determined by the Accept-Language header.
+type MessageCatalog interface {
+	// Locales returns the locales the catalog has translations for. The first is used
+	// when none of the caller's preferred locales are available.
+	Locales() []string
+
+	// Message returns the message for the key in the locale with the params
+	// substituted. It returns false if there is no translation.
+	Message(locale, key string, params map[string]interface{}) (string, bool)
+}
+
+// MapCatalog is a MessageCatalog backed by a map of locale to message key to message
+// template. Templates reference params by name in braces, e.g. "{name}".
+type MapCatalog map[string]map[string]string
+
+// Locales returns the locales in the catalog, sorted.
+func (m MapCatalog) Locales() []string {
+	locales := make([]string, 0, len(m))
+	for locale := range m {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Message returns the translated message for the key in the locale.
+func (m MapCatalog) Message(locale, key string, params map[string]interface{}) (string, bool) {
+	template, ok := m[locale][key]
+	if !ok {
+		return "", false
+	}
+	return formatMessage(template, params), true
+}
+
+// formatMessage substitutes the params into the message template.
+func formatMessage(template string, params map[string]interface{}) string {
+	if len(params) == 0 {
+		return template
+	}
+	replacements := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// LocalizedError is an error whose message can be translated using a MessageCatalog.
+// Error returns the canonical message, so logs are unaffected, while responses use
+// the translation for the caller's locale when one is available.
+type LocalizedError struct {
+	err    error
+	key    string
+	params map[string]interface{}
+}
+
+// Localize returns a LocalizedError which renders err using the message key and
+// params. The status code and error code of err are preserved.
+func Localize(err error, key string, params map[string]interface{}) *LocalizedError {
+	return &LocalizedError{err: err, key: key, params: params}
+}
+
+// Error returns the canonical error message.
+func (l *LocalizedError) Error() string { return l.err.Error() }
+
+// StatusCode returns the HTTP status code of the underlying error.
+func (l *LocalizedError) StatusCode() int { return errorStatus(l.err) }
+
+// Code returns the machine-readable code of the underlying error, if any.
+func (l *LocalizedError) Code() string { return errorCode(l.err) }
+
+// MessageKey returns the catalog key of the message.
+func (l *LocalizedError) MessageKey() string { return l.key }
+
+// MessageParams returns the params substituted into the message.
+func (l *LocalizedError) MessageParams() map[string]interface{} { return l.params }
+
+// Unwrap returns the underlying error.
+func (l *LocalizedError) Unwrap() error { return l.err }
+
+// translatedError is a LocalizedError rendered in a particular locale.
+type translatedError struct {
+	*LocalizedError
+	message string
+}
+
+// Error returns the translated message.
+func (t translatedError) Error() string { return t.message }
+
+// localizeError translates the error for the request if it's a LocalizedError and
+// the catalog has a translation for the caller's locale. Otherwise, the error is
+// returned as is.
+func localizeError(ctx RequestContext, catalog MessageCatalog, err error) error {
+	var localized *LocalizedError
+	if !errors.As(err, &localized) || catalog == nil {
+		return err
+	}
+
+	locale := ctx.Locale(catalog.Locales()...)
+	message, ok := catalog.Message(locale, localized.key, localized.params)
+	if !ok {
+		return err
+	}
+	return translatedError{LocalizedError: localized, message: message}
+}
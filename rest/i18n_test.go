@@ -0,0 +1,103 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalog = MapCatalog{
+	"en": {"widget_missing": "Widget {id} does not exist"},
+	"fr": {"widget_missing": "Le widget {id} n'existe pas"},
+}
+
+// Ensures that MapCatalog returns translations with params substituted.
+func TestMapCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"en", "fr"}, testCatalog.Locales())
+
+	message, ok := testCatalog.Message("fr", "widget_missing", map[string]interface{}{"id": 42})
+	assert.True(ok)
+	assert.Equal("Le widget 42 n'existe pas", message)
+
+	_, ok = testCatalog.Message("de", "widget_missing", nil)
+	assert.False(ok)
+	_, ok = testCatalog.Message("en", "nope", nil)
+	assert.False(ok)
+}
+
+// Ensures that LocalizedError preserves the canonical message, status and code of
+// the underlying error.
+func TestLocalizedError(t *testing.T) {
+	assert := assert.New(t)
+	underlying := NotFound("Widget 42 does not exist")
+
+	err := Localize(underlying, "widget_missing", map[string]interface{}{"id": 42})
+	assert.Equal("Widget 42 does not exist", err.Error())
+	assert.Equal(http.StatusNotFound, err.StatusCode())
+	assert.Equal("widget_missing", err.MessageKey())
+	assert.Equal(map[string]interface{}{"id": 42}, err.MessageParams())
+	assert.Equal(underlying, err.Unwrap())
+}
+
+type localizedResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (l localizedResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (l localizedResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return nil, Localize(NotFound("Widget "+id+" does not exist"), "widget_missing",
+		map[string]interface{}{"id": id})
+}
+
+// Ensures that LocalizedError messages are rendered in the caller's language.
+func TestHandleReadLocalizedError(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{MessageCatalog: testCatalog})
+	api.RegisterResourceHandler(localizedResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets/42", nil)
+	req.Header.Set("Accept-Language", "fr-CA, en;q=0.5")
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code)
+	assert.Equal(
+		`{"messages":["Le widget 42 n'existe pas"],"reason":"Not Found","status":404}`,
+		resp.Body.String(),
+	)
+
+	// Without a catalog, the canonical message is used.
+	api = NewAPI(&Configuration{})
+	api.RegisterResourceHandler(localizedResourceHandler{})
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(
+		`{"messages":["Widget 42 does not exist"],"reason":"Not Found","status":404}`,
+		resp.Body.String(),
+	)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// jsonSchemaDialect is the JSON Schema dialect of resource schemas.
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+	// jsonSchemaContentType is the MIME type of resource schemas.
+	jsonSchemaContentType = "application/schema+json"
+)
+
+// JSONSchema returns the JSON Schema of the resource handled by the ResourceHandler
+// for the version, derived from its Rules or, without Rules, its resource type. The
+// schema covers both requests and responses: fields which are only accepted in
+// requests are marked writeOnly and fields only sent in responses readOnly. The same
+// schema is served at GET /api/:version/resourceName/schema.
+func JSONSchema(handler ResourceHandler, version string) ([]byte, error) {
+	s := resourceSchema(handler.Rules(), version)
+	s["$schema"] = jsonSchemaDialect
+	s["title"] = handlerTypeName(handler)
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// schemaURI returns the URI of the JSON Schema of the ResourceHandler's resource.
+func schemaURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/schema"
+}
+
+// handleSchema returns a Handler which serves the JSON Schema of the
+// ResourceHandler's resource for the requested version.
+func (h requestHandler) handleSchema(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+
+		s, err := JSONSchema(handler, ctx.Version())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", jsonSchemaContentType)
+		w.Write(s)
+	})
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaHandler struct {
+	BaseResourceHandler
+}
+
+func (s schemaHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (s schemaHandler) ReadResource(ctx RequestContext, id string, version string) (Resource, error) {
+	return &schemaResource{ID: 42}, nil
+}
+
+func (s schemaHandler) Rules() Rules {
+	return NewRules((*schemaResource)(nil),
+		&Rule{Field: "ID", FieldAlias: "id", Type: Int64, OutputOnly: true},
+		&Rule{Field: "Street", FieldAlias: "street", Type: String, Required: true,
+			DocString: "Street address"},
+		&Rule{FieldAlias: "token", Type: String, InputOnly: true},
+		&Rule{Field: "Tags", FieldAlias: "tags", Versions: []string{"2"}},
+	)
+}
+
+// Ensures that the JSON Schema of a resource is served per version, marking input
+// and output only fields.
+func TestJSONSchemaEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(schemaHandler{})
+
+	req, _ := http.NewRequest("GET", "http://example.com/api/v1/widgets/schema", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/schema+json", w.Header().Get("Content-Type"))
+
+	var s map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "schemaResource",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"id":     map[string]interface{}{"type": "integer", "format": "int64", "readOnly": true},
+			"street": map[string]interface{}{"type": "string", "description": "Street address"},
+			"token":  map[string]interface{}{"type": "string", "writeOnly": true},
+		},
+		"required": []interface{}{"street"},
+	}, s)
+
+	req, _ = http.NewRequest("GET", "http://example.com/api/v2/widgets/schema", nil)
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Contains(s["properties"], "tags")
+}
+
+// Ensures that the schema route doesn't shadow reads of other resources.
+func TestJSONSchemaEndpointRead(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(schemaHandler{})
+
+	req, _ := http.NewRequest("GET", "http://example.com/api/v1/widgets/42", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `"id":42`)
+}
+
+// Ensures that JSONSchema derives the schema from the resource type without Rules.
+func TestJSONSchemaResourceType(t *testing.T) {
+	assert := assert.New(t)
+	data, err := JSONSchema(TestResourceHandler{}, "1")
+	assert.Nil(err)
+
+	var s map[string]interface{}
+	assert.Nil(json.Unmarshal(data, &s))
+	assert.Equal("widgets", s["title"])
+	assert.Equal("object", s["type"])
+}
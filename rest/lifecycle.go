@@ -0,0 +1,95 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is how long Run waits for in-flight requests by default.
+const defaultShutdownTimeout = 30 * time.Second
+
+// OnStart registers the function to be run before the API first begins serving
+// requests. Functions are run in the order they're registered.
+func (r *muxAPI) OnStart(hook func(context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.startHooks = append(r.startHooks, hook)
+}
+
+// runStartHooks runs the OnStart hooks unless they've already run. It returns the first
+// error encountered, in which case the hooks are run again the next time.
+func (r *muxAPI) runStartHooks() error {
+	r.startMu.Lock()
+	defer r.startMu.Unlock()
+	if r.started {
+		return nil
+	}
+	r.mu.RLock()
+	hooks := append([]func(context.Context) error(nil), r.startHooks...)
+	r.mu.RUnlock()
+	for _, hook := range hooks {
+		if err := hook(context.Background()); err != nil {
+			return err
+		}
+	}
+	r.started = true
+	return nil
+}
+
+// Run begins serving requests like Start and gracefully shuts the API down with Shutdown
+// when the process receives SIGINT or SIGTERM, waiting at most the ServerConfig's
+// ShutdownTimeout. It blocks until the API has stopped, returning the error of Start or
+// Shutdown.
+func (r *muxAPI) Run(addr Address, middleware ...Middleware) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	return r.runUntil(signals, func() error { return r.Start(addr, middleware...) })
+}
+
+// runUntil runs the start function until it returns or a signal is received, in which
+// case the API is shut down.
+func (r *muxAPI) runUntil(signals <-chan os.Signal, start func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- start()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case sig := <-signals:
+		log.Printf("Received %v, shutting down", sig)
+		timeout := defaultShutdownTimeout
+		if r.config.Server != nil && r.config.Server.ShutdownTimeout > 0 {
+			timeout = r.config.Server.ShutdownTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := r.Shutdown(ctx)
+		if startErr := <-done; err == nil {
+			err = startErr
+		}
+		return err
+	}
+}
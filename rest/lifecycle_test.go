@@ -0,0 +1,95 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the OnStart hooks are run once, before the API first serves requests,
+// and that the API isn't started if one fails.
+func TestOnStart(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	fail := errors.New("database unavailable")
+	var calls int32
+	api.OnStart(func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return fail
+		}
+		return nil
+	})
+
+	assert.Equal(fail, api.Start("127.0.0.1:0"))
+	startTestAPI(t, api)
+	startTestAPI(t, api)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+}
+
+// Ensures that runUntil shuts the API down gracefully when a signal is received,
+// running the shutdown hooks.
+func TestRunUntilSignal(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Server: &ServerConfig{ShutdownTimeout: time.Second}})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	stopped := make(chan struct{})
+	api.OnShutdown(func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		assert.True(ok)
+		assert.WithinDuration(time.Now().Add(time.Second), deadline, time.Second)
+		close(stopped)
+		return nil
+	})
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	addr := freeAddress(t)
+	go func() {
+		done <- api.(*muxAPI).runUntil(signals, func() error { return api.Start(Address(addr)) })
+	}()
+	var err error
+	for i := 0; i < 100; i++ {
+		var resp *http.Response
+		if resp, err = http.Get("http://" + addr + "/api/v1/rows"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(err)
+
+	signals <- syscall.SIGTERM
+	assert.Nil(<-done)
+	<-stopped
+}
+
+// Ensures that runUntil returns the error of the start function if it fails.
+func TestRunUntilError(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{}).(*muxAPI)
+	fail := errors.New("address in use")
+	assert.Equal(fail, api.runUntil(make(chan os.Signal), func() error { return fail }))
+}
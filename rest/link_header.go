@@ -0,0 +1,101 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// pageURL returns the URL of the list request with the query string variables set, or
+// removed if their values are empty.
+func pageURL(ctx RequestContext, vars map[string]string) (string, bool) {
+	r, ok := ctx.Request()
+	if !ok {
+		return "", false
+	}
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	u, err := url.Parse(fmt.Sprintf("%s://%s%s", scheme, r.Host, uri))
+	if err != nil {
+		return "", false
+	}
+	q := u.Query()
+	for key, value := range vars {
+		if value == "" {
+			q.Del(key)
+		} else {
+			q.Set(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+// setLinkHeader sets the RFC 5988 Link header of a list response, with the first, prev,
+// next and last pages which can be determined, so clients can paginate without
+// understanding the envelope. Lists paginated with cursors link to their first and
+// next pages, and offset-paginated lists also to their previous and, given a total,
+// last pages.
+func setLinkHeader(ctx RequestContext, page Payload, count int) {
+	rels := map[string]map[string]string{}
+	if page == nil {
+		if cursor := ctx.NextCursor(); cursor != "" {
+			rels["next"] = map[string]string{cursorKey: cursor}
+		}
+		if ctx.Cursor() != "" || rels["next"] != nil {
+			rels["first"] = map[string]string{cursorKey: ""}
+		}
+	} else {
+		limit, offset := page[limitKey].(int), page[offsetKey].(int)
+		t, hasTotal := page[total].(int)
+		rels["first"] = map[string]string{offsetKey: "0"}
+		if offset > 0 {
+			prev := offset - limit
+			if prev < 0 {
+				prev = 0
+			}
+			rels["prev"] = map[string]string{offsetKey: strconv.Itoa(prev)}
+		}
+		if hasTotal && offset+limit < t || !hasTotal && count >= limit {
+			rels["next"] = map[string]string{offsetKey: strconv.Itoa(offset + limit)}
+		}
+		if hasTotal && t > 0 {
+			rels["last"] = map[string]string{offsetKey: strconv.Itoa((t - 1) / limit * limit)}
+		}
+	}
+
+	values := []string{}
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if vars, ok := rels[rel]; ok {
+			if u, ok := pageURL(ctx, vars); ok {
+				values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, u, rel))
+			}
+		}
+	}
+	if len(values) > 0 {
+		ctx.ResponseHeader().Set("Link", strings.Join(values, ", "))
+	}
+}
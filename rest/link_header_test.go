@@ -0,0 +1,68 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listLinkHeader returns the Link header of the list response to the request.
+func listLinkHeader(api API, uri string) string {
+	req, _ := http.NewRequest("GET", uri, nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	return resp.Header().Get("Link")
+}
+
+// Ensures that list responses paginated with offsets link to their first, previous,
+// next and, given a total, last pages with the Link header.
+func TestLinkHeaderOffsets(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(countedResourceHandler{})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+
+	assert.Equal(`<http://example.com/api/v1/counted?limit=10&offset=0>; rel="first", `+
+		`<http://example.com/api/v1/counted?limit=10&offset=15>; rel="prev", `+
+		`<http://example.com/api/v1/counted?limit=10&offset=35>; rel="next", `+
+		`<http://example.com/api/v1/counted?limit=10&offset=40>; rel="last"`,
+		listLinkHeader(api, "http://example.com/api/v1/counted?limit=10&offset=25"))
+	assert.Equal(`<http://example.com/api/v1/counted?limit=50&offset=0>; rel="first", `+
+		`<http://example.com/api/v1/counted?limit=50&offset=0>; rel="last"`,
+		listLinkHeader(api, "http://example.com/api/v1/counted?limit=50&offset=0"))
+	assert.Equal(`<http://example.com/api/v1/widgets?limit=10&offset=0>; rel="first", `+
+		`<http://example.com/api/v1/widgets?limit=10&offset=0>; rel="prev"`,
+		listLinkHeader(api, "http://example.com/api/v1/widgets?limit=10&offset=5"))
+}
+
+// Ensures that list responses paginated with cursors link to their first and next
+// pages with the Link header, and that unpaginated lists have none.
+func TestLinkHeaderCursors(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(pagedResourceHandler{})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+
+	assert.Equal(`<http://example.com/api/v1/pages?limit=1>; rel="first", `+
+		`<http://example.com/api/v1/pages?limit=1&next=1>; rel="next"`,
+		listLinkHeader(api, "http://example.com/api/v1/pages?limit=1"))
+	assert.Equal("", listLinkHeader(api, "http://example.com/api/v1/widgets"))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+// addStandardLinks adds the "self" and "collection" links of a read response or the
+// "self" and "next" links of a list response, built from the router, if
+// HypermediaLinks is enabled. Links added by the ResourceHandler take precedence.
+func (h requestHandler) addStandardLinks(ctx RequestContext, handler ResourceHandler,
+	method HandleMethod) {
+
+	config := h.Configuration()
+	if config == nil || !config.HypermediaLinks {
+		return
+	}
+
+	standard := map[string]string{}
+	resource := handler.ResourceName()
+	vars := ctx.PathVars()
+	switch method {
+	case HandleRead:
+		if u, err := ctx.BuildURL(resource, HandleRead, vars); err == nil {
+			standard["self"] = u.String()
+		}
+		if u, err := ctx.BuildURL(resource, HandleReadList, vars); err == nil {
+			standard["collection"] = u.String()
+		}
+	case HandleReadList:
+		if u, err := ctx.BuildURL(resource, HandleReadList, vars); err == nil {
+			if r, ok := ctx.Request(); ok {
+				u.RawQuery = r.URL.RawQuery
+			}
+			standard["self"] = u.String()
+		}
+		if u, err := ctx.NextURL(); err == nil {
+			standard["next"] = u
+		}
+	case searchMethod:
+		if r, ok := ctx.Request(); ok {
+			standard["self"] = r.URL.String()
+		}
+		if u, err := ctx.NextURL(); err == nil {
+			standard["next"] = u
+		}
+	}
+
+	existing := ctx.Links()
+	for rel, href := range standard {
+		if _, ok := existing[rel]; !ok {
+			ctx.AddLink(rel, href)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type linkHandler struct {
+	BaseResourceHandler
+}
+
+func (l linkHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (l linkHandler) ReadResource(ctx RequestContext, id string, version string) (Resource, error) {
+	ctx.AddLink("owner", "http://example.com/api/v1/users/7")
+	return map[string]interface{}{"id": id}, nil
+}
+
+func (l linkHandler) ReadResourceList(ctx RequestContext, limit int, cursor string,
+	version string) ([]Resource, string, error) {
+	return []Resource{map[string]interface{}{"id": "1"}}, "abc", nil
+}
+
+// serveLinks performs a GET request for the URL against an API serving linkHandler,
+// returning the links of the response.
+func serveLinks(t *testing.T, config *Configuration, url string) map[string]interface{} {
+	api := NewAPI(config)
+	api.RegisterResourceHandler(linkHandler{})
+	req, _ := http.NewRequest("GET", url, nil)
+	req.RequestURI = req.URL.RequestURI()
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var payload map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &payload))
+	links, _ := payload["links"].(map[string]interface{})
+	return links
+}
+
+// Ensures that read responses include self and collection links along with links
+// added by the handler.
+func TestHypermediaLinksRead(t *testing.T) {
+	links := serveLinks(t, &Configuration{HypermediaLinks: true}, "http://example.com/api/v1/widgets/42")
+	assert.Equal(t, map[string]interface{}{
+		"self":       "http://example.com/api/v1/widgets/42",
+		"collection": "http://example.com/api/v1/widgets",
+		"owner":      "http://example.com/api/v1/users/7",
+	}, links)
+}
+
+// Ensures that list responses include self and next links.
+func TestHypermediaLinksList(t *testing.T) {
+	links := serveLinks(t, &Configuration{HypermediaLinks: true}, "http://example.com/api/v1/widgets?limit=1")
+	assert.Equal(t, map[string]interface{}{
+		"self": "http://example.com/api/v1/widgets?limit=1",
+		"next": "http://example.com/api/v1/widgets?limit=1&next=abc",
+	}, links)
+}
+
+// Ensures that only links added by the handler are included unless HypermediaLinks
+// is enabled.
+func TestHypermediaLinksDisabled(t *testing.T) {
+	assert := assert.New(t)
+	links := serveLinks(t, &Configuration{}, "http://example.com/api/v1/widgets/42")
+	assert.Equal(map[string]interface{}{"owner": "http://example.com/api/v1/users/7"}, links)
+
+	assert.Nil(serveLinks(t, &Configuration{}, "http://example.com/api/v1/widgets"))
+}
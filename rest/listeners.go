@@ -0,0 +1,105 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+// InheritedListener is a listener opened by the parent of the process, e.g. systemd or a
+// previous instance handing over its sockets for a zero-downtime restart.
+type InheritedListener struct {
+	net.Listener
+
+	// Name is the name of the listener given by LISTEN_FDNAMES, e.g. the
+	// FileDescriptorName of a systemd socket unit, or empty if it isn't named.
+	Name string
+}
+
+// InheritedListeners returns the listeners passed to the process using the systemd
+// socket activation protocol, which can be served with API.Serve. The file descriptors
+// starting at 3 are listeners if LISTEN_PID is the process's ID, LISTEN_FDS is their
+// count and LISTEN_FDNAMES optionally names them, separated by colons. A parent handing
+// over its sockets should pass them as ExtraFiles of the child and set the variables
+// accordingly, leaving LISTEN_PID unset if it can't know the child's ID. The variables
+// are unset so they aren't inherited by the process's own children. No listeners are
+// returned if none were passed.
+func InheritedListeners() ([]InheritedListener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return inheritListeners(os.Getenv, listenFDsStart)
+}
+
+// inheritListeners returns the listeners described by the environment variables
+// returned by getenv, whose file descriptors start at the first.
+func inheritListeners(getenv func(string) string, first uintptr) ([]InheritedListener, error) {
+	if pid := getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	fds := getenv("LISTEN_FDS")
+	if fds == "" {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("Invalid LISTEN_FDS %q", fds)
+	}
+	var names []string
+	if fdNames := getenv("LISTEN_FDNAMES"); fdNames != "" {
+		names = strings.Split(fdNames, ":")
+	}
+
+	listeners := make([]InheritedListener, 0, count)
+	for i := 0; i < count; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		fd := first + uintptr(i)
+		file := os.NewFile(fd, name)
+		if file == nil {
+			err = fmt.Errorf("Invalid inherited file descriptor %d", fd)
+			break
+		}
+		// FileListener duplicates the file descriptor, so the original is closed.
+		var ln net.Listener
+		ln, err = net.FileListener(file)
+		file.Close()
+		if err != nil {
+			err = fmt.Errorf("Inherited file descriptor %d isn't a listener: %v", fd, err)
+			break
+		}
+		listeners = append(listeners, InheritedListener{Listener: ln, Name: name})
+	}
+	if err != nil {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		return nil, err
+	}
+	return listeners, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dupFD returns a duplicate of the file's descriptor, which is owned by the caller, and
+// closes the file.
+func dupFD(t *testing.T, file *os.File) uintptr {
+	defer file.Close()
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uintptr(fd)
+}
+
+// listenEnv returns a getenv function returning the variables.
+func listenEnv(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+// Ensures that inherited file descriptors are returned as named listeners which can be
+// served.
+func TestInheritListeners(t *testing.T) {
+	assert := assert.New(t)
+	parent, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	defer parent.Close()
+	file, err := parent.(*net.TCPListener).File()
+	if !assert.Nil(err) {
+		return
+	}
+
+	listeners, err := inheritListeners(listenEnv(map[string]string{
+		"LISTEN_PID":     strconv.Itoa(os.Getpid()),
+		"LISTEN_FDS":     "1",
+		"LISTEN_FDNAMES": "public",
+	}), dupFD(t, file))
+	if !assert.Nil(err) || !assert.Len(listeners, 1) {
+		return
+	}
+	assert.Equal("public", listeners[0].Name)
+	assert.Equal(parent.Addr().String(), listeners[0].Addr().String())
+
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	done := make(chan error, 1)
+	go func() { done <- api.Serve(listeners[0]) }()
+	resp, err := http.Get("http://" + parent.Addr().String() + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+	assert.Nil(api.Stop())
+	assert.Nil(<-done)
+}
+
+// Ensures that no listeners are returned when none were passed to the process and
+// that invalid file descriptors result in an error.
+func TestInheritListenersInvalid(t *testing.T) {
+	assert := assert.New(t)
+	listeners, err := inheritListeners(listenEnv(nil), listenFDsStart)
+	assert.Nil(err)
+	assert.Empty(listeners)
+
+	listeners, err = inheritListeners(listenEnv(map[string]string{
+		"LISTEN_PID": strconv.Itoa(os.Getpid() + 1),
+		"LISTEN_FDS": "1",
+	}), listenFDsStart)
+	assert.Nil(err)
+	assert.Empty(listeners)
+
+	_, err = inheritListeners(listenEnv(map[string]string{"LISTEN_FDS": "x"}), listenFDsStart)
+	assert.NotNil(err)
+
+	path := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(path, []byte("data"), 0600)
+	file, err := os.Open(path)
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = inheritListeners(listenEnv(map[string]string{"LISTEN_FDS": "1"}), dupFD(t, file))
+	assert.NotNil(err)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptLanguageHeader is the name of the request header containing the caller's
+// preferred languages.
+const acceptLanguageHeader = "Accept-Language"
+
+// languageRange is a single language range from an Accept-Language header along with
+// its quality value.
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// byQuality implements sort.Interface for ordering language ranges by descending
+// quality value.
+type byQuality []languageRange
+
+func (b byQuality) Len() int           { return len(b) }
+func (b byQuality) Less(i, j int) bool { return b[i].quality > b[j].quality }
+func (b byQuality) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// parseAcceptLanguage parses the value of an Accept-Language header (RFC 7231,
+// section 5.3.5) and returns the language ranges ordered by preference. Ranges with
+// equal quality values retain the order in which they were specified. Ranges with a
+// quality value of 0, which are explicitly not acceptable, and malformed ranges are
+// discarded.
+func parseAcceptLanguage(header string) []string {
+	ranges := []languageRange{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			quality = q
+		}
+
+		if quality == 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag, quality})
+	}
+
+	sort.Stable(byQuality(ranges))
+
+	locales := make([]string, len(ranges))
+	for i, r := range ranges {
+		locales[i] = r.tag
+	}
+	return locales
+}
+
+// LookupLocale returns the best language tag from the available tags for the preferred
+// language ranges using the lookup scheme described by RFC 4647, section 3.4. Each
+// preferred range is progressively truncated (e.g. "zh-Hant-CN" to "zh-Hant" to "zh")
+// until it matches an available tag. Matching is case-insensitive and the available
+// tag is returned as specified. If nothing matches, the fallback is returned.
+func LookupLocale(preferred, available []string, fallback string) string {
+	for _, locale := range preferred {
+		if locale == "*" {
+			continue
+		}
+
+		for locale != "" {
+			for _, tag := range available {
+				if strings.EqualFold(tag, locale) {
+					return tag
+				}
+			}
+			locale = truncateLocale(locale)
+		}
+	}
+
+	return fallback
+}
+
+// truncateLocale removes the last subtag from the language tag. Single-character
+// subtags (e.g. the "x" in "en-x-foo") are removed along with the subtag following
+// them.
+func truncateLocale(locale string) string {
+	idx := strings.LastIndex(locale, "-")
+	if idx < 0 {
+		return ""
+	}
+	locale = locale[:idx]
+
+	if idx = strings.LastIndex(locale, "-"); idx >= 0 && len(locale)-idx == 2 {
+		locale = locale[:idx]
+	}
+	return locale
+}
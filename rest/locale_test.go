@@ -0,0 +1,64 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that parseAcceptLanguage orders ranges by quality and discards
+// unacceptable ranges.
+func TestParseAcceptLanguage(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{}, parseAcceptLanguage(""))
+	assert.Equal([]string{"fr-CH", "fr", "en", "de", "*"},
+		parseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	assert.Equal([]string{"de", "en-US", "en"},
+		parseAcceptLanguage("en-US;q=0.5,de, en;q=0.5, ja;q=0, es;q=foo"))
+}
+
+// Ensures that LookupLocale progressively truncates preferred ranges.
+func TestLookupLocale(t *testing.T) {
+	assert := assert.New(t)
+	available := []string{"en", "zh-Hant", "fr-CA"}
+
+	assert.Equal("zh-Hant", LookupLocale([]string{"zh-hant-CN-x-private"}, available, "en"))
+	assert.Equal("fr-CA", LookupLocale([]string{"de", "fr-ca"}, available, "en"))
+	assert.Equal("en", LookupLocale([]string{"en-GB"}, available, ""))
+	assert.Equal("en", LookupLocale([]string{"fr", "*"}, available, "en"))
+	assert.Equal("", LookupLocale(nil, available, ""))
+}
+
+// Ensures that the RequestContext exposes the preferred locales and matches them
+// against the available locales.
+func TestContextLocale(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("Accept-Language", "de-AT;q=0.8, fr")
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+
+	assert.Equal([]string{"fr", "de-AT"}, ctx.Locales())
+	assert.Equal("de", ctx.Locale("en", "de"))
+	assert.Equal("en", ctx.Locale("en", "es"))
+	assert.Equal("", ctx.Locale())
+}
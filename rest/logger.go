@@ -0,0 +1,135 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	// requestIDHeader is the name of the header used to propagate request IDs.
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength is the maximum length of a request ID provided by a client.
+	maxRequestIDLength = 128
+)
+
+// taggedLogger is an implementation of the StdLogger interface which prefixes every
+// line logged with a set of tags, e.g. "[request_id=abc resource=widgets] ".
+type taggedLogger struct {
+	logger StdLogger
+	prefix string
+}
+
+// newTaggedLogger returns a StdLogger which writes to the provided logger, prefixing
+// each line with the given key-value pairs. Pairs with empty values are omitted. If
+// the logger is nil, a default logger writing to stdout is used.
+func newTaggedLogger(logger StdLogger, pairs ...string) StdLogger {
+	if logger == nil {
+		logger = log.New(os.Stdout, defaultLogPrefix, log.LstdFlags)
+	}
+
+	tags := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			tags = append(tags, pairs[i]+"="+pairs[i+1])
+		}
+	}
+
+	prefix := ""
+	if len(tags) > 0 {
+		prefix = "[" + strings.Join(tags, " ") + "] "
+	}
+	return &taggedLogger{logger: logger, prefix: prefix}
+}
+
+// Print logs the tagged line using fmt.Sprint semantics.
+func (t *taggedLogger) Print(v ...interface{}) {
+	t.logger.Print(t.prefix + fmt.Sprint(v...))
+}
+
+// Printf logs the tagged line using fmt.Sprintf semantics.
+func (t *taggedLogger) Printf(format string, v ...interface{}) {
+	t.logger.Print(t.prefix + fmt.Sprintf(format, v...))
+}
+
+// Println logs the tagged line using fmt.Sprintln semantics.
+func (t *taggedLogger) Println(v ...interface{}) {
+	t.logger.Print(t.prefix + fmt.Sprintln(v...))
+}
+
+// Fatal logs the tagged line and calls os.Exit(1).
+func (t *taggedLogger) Fatal(v ...interface{}) {
+	t.logger.Fatal(t.prefix + fmt.Sprint(v...))
+}
+
+// Fatalf logs the tagged line and calls os.Exit(1).
+func (t *taggedLogger) Fatalf(format string, v ...interface{}) {
+	t.logger.Fatal(t.prefix + fmt.Sprintf(format, v...))
+}
+
+// Fatalln logs the tagged line and calls os.Exit(1).
+func (t *taggedLogger) Fatalln(v ...interface{}) {
+	t.logger.Fatal(t.prefix + fmt.Sprintln(v...))
+}
+
+// Panic logs the tagged line and panics.
+func (t *taggedLogger) Panic(v ...interface{}) {
+	t.logger.Panic(t.prefix + fmt.Sprint(v...))
+}
+
+// Panicf logs the tagged line and panics.
+func (t *taggedLogger) Panicf(format string, v ...interface{}) {
+	t.logger.Panic(t.prefix + fmt.Sprintf(format, v...))
+}
+
+// Panicln logs the tagged line and panics.
+func (t *taggedLogger) Panicln(v ...interface{}) {
+	t.logger.Panic(t.prefix + fmt.Sprintln(v...))
+}
+
+// newRequestID returns a random 128-bit request ID encoded as hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns true if the client-provided request ID is safe to log and
+// echo back, meaning it's reasonably short and contains only alphanumerics and the
+// characters "-", "_", "." and ":".
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the tagged logger prefixes lines with the non-empty tags.
+func TestTaggedLogger(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	logger := newTaggedLogger(log.New(&buf, "", 0), "a", "1", "b", "", "c", "100%")
+
+	logger.Printf("hello %s", "world")
+	logger.Println("foo", 1)
+	logger.Print("bar")
+
+	assert.Equal("[a=1 c=100%] hello world\n[a=1 c=100%] foo 1\n[a=1 c=100%] bar\n",
+		buf.String())
+}
+
+// Ensures that only safe client-provided request IDs are accepted.
+func TestValidRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(validRequestID("abc-123_DEF.4:5"))
+	assert.False(validRequestID(""))
+	assert.False(validRequestID("foo bar"))
+	assert.False(validRequestID("foo\nbar"))
+	assert.False(validRequestID(string(make([]byte, maxRequestIDLength+1))))
+	assert.Len(newRequestID(), 32)
+}
+
+// Ensures that the request ID uses the client-provided header if valid and is
+// otherwise generated once per request.
+func TestRequestID(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+	assert.Equal("abc", ctx.RequestID())
+
+	req, _ = http.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("X-Request-ID", "<script>")
+	ctx = NewContext(nil, req, httptest.NewRecorder())
+	id := ctx.RequestID()
+	assert.Len(id, 32)
+	assert.Equal(id, ctx.WithValue("foo", "bar").RequestID())
+}
+
+type loggingResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (l loggingResourceHandler) ResourceName() string {
+	return "logs"
+}
+
+func (l loggingResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	ctx.SetPrincipal(&Principal{ID: "alice"})
+	ctx.Logger().Printf("reading %s", id)
+	return nil, nil
+}
+
+// Ensures that the RequestContext Logger is tagged with the request details and that
+// the request ID is sent back with the response.
+func TestContextLogger(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	api := NewAPI(&Configuration{Logger: log.New(&buf, "", 0)})
+	api.RegisterResourceHandler(loggingResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/logs/42", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal("req-1", resp.Header().Get("X-Request-ID"))
+	assert.Contains(buf.String(),
+		"[request_id=req-1 resource=logs version=1 principal=alice] reading 42\n")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// waitKey is the name of the query string variable for how long list requests
+	// wait for changes.
+	waitKey = "wait"
+
+	// sinceKey is the name of the query string variable for the change token list
+	// requests wait for changes after.
+	sinceKey = "since"
+
+	// changeTokenHeader is the response header of list requests identifying the
+	// latest change when the list was read.
+	changeTokenHeader = "X-Change-Token"
+
+	// maxWait is the longest list requests wait for changes.
+	maxWait = time.Minute
+)
+
+// changes returns a subscription to the Events accepted by the filter and true if one
+// was published after the given ID, or may have been but is no longer recorded.
+func (b *eventBus) changes(since uint64, filter func(Event) bool) (*eventSubscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	missed, complete := b.history.after(since, filter)
+	return b.add(filter), !complete || len(missed) > 0
+}
+
+// latest returns the ID of the latest Event published.
+func (b *eventBus) latest() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// awaitChanges blocks a list request for the resource with wait and since query
+// parameters until an Event of the resource is published after the change token given
+// by since, or the wait elapses. It returns the change token of the list, identifying
+// the latest Event when it's read. A request without a since parameter doesn't wait,
+// so clients can obtain their first change token. Waits are limited to a minute and,
+// given a RequestTimeout, half its duration so the list can still be read, and end
+// when the API shuts down. If Events aren't enabled, it doesn't wait and returns an
+// empty token.
+func (h requestHandler) awaitChanges(ctx RequestContext, resource string) (string, error) {
+	if h.events == nil {
+		return "", nil
+	}
+	req, ok := ctx.Request()
+	if !ok {
+		return "", nil
+	}
+	query := req.URL.Query()
+	if query.Get(waitKey) == "" || query.Get(sinceKey) == "" {
+		return strconv.FormatUint(h.events.latest(), 10), nil
+	}
+
+	wait, err := time.ParseDuration(query.Get(waitKey))
+	if err != nil || wait < 0 {
+		return "", BadRequest(fmt.Sprintf("Invalid %s: %s", waitKey, query.Get(waitKey)))
+	}
+	since, err := strconv.ParseUint(query.Get(sinceKey), 10, 64)
+	if err != nil {
+		return "", BadRequest(fmt.Sprintf("Invalid %s: %s", sinceKey, query.Get(sinceKey)))
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	if remaining, ok := ctx.RemainingTime(); ok && wait > remaining/2 {
+		wait = remaining / 2
+	}
+
+	sub, changed := h.events.changes(since, func(event Event) bool {
+		return event.Resource == resource
+	})
+	defer h.events.unsubscribe(sub)
+	if !changed {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-sub.events:
+		case <-timer.C:
+		case <-ctx.Done():
+		case <-h.events.done():
+		}
+	}
+	return strconv.FormatUint(h.events.latest(), 10), nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that list requests return change tokens and long polls wait until the
+// resource changes after the given token.
+func TestLongPoll(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/widgets")
+	if !assert.Nil(err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("0", resp.Header.Get(changeTokenHeader))
+
+	start := time.Now()
+	resp, err = http.Get(ts.URL + "/api/v1/widgets?wait=50ms&since=0")
+	if !assert.Nil(err) {
+		return
+	}
+	resp.Body.Close()
+	assert.True(time.Since(start) >= 50*time.Millisecond)
+	assert.Equal("0", resp.Header.Get(changeTokenHeader))
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(ts.URL + "/api/v1/widgets?wait=30s&since=0")
+		if err != nil {
+			close(done)
+			return
+		}
+		resp.Body.Close()
+		done <- resp
+	}()
+	time.Sleep(50 * time.Millisecond)
+	resp, err = http.Post(ts.URL+"/api/v1/widgets", "application/json", strings.NewReader(`{}`))
+	if assert.Nil(err) {
+		resp.Body.Close()
+	}
+	select {
+	case resp := <-done:
+		if assert.NotNil(resp) {
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			assert.Equal("1", resp.Header.Get(changeTokenHeader))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Long poll didn't return after a change")
+	}
+
+	start = time.Now()
+	resp, err = http.Get(ts.URL + "/api/v1/widgets?wait=30s&since=0")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.True(time.Since(start) < 5*time.Second)
+		assert.Equal("1", resp.Header.Get(changeTokenHeader))
+	}
+
+	for _, query := range []string{"wait=forever&since=0", "wait=1s&since=latest"} {
+		resp, err = http.Get(ts.URL + "/api/v1/widgets?" + query)
+		if assert.Nil(err) {
+			resp.Body.Close()
+			assert.Equal(http.StatusBadRequest, resp.StatusCode, query)
+		}
+	}
+}
+
+// Ensures that Shutdown ends pending long polls rather than waiting for their wait to
+// elapse, still responding with the list.
+func TestLongPollShutdown(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	addr, done := startTestAPI(t, api)
+
+	polled := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/api/v1/widgets?wait=30s&since=0")
+		if err != nil {
+			close(polled)
+			return
+		}
+		resp.Body.Close()
+		polled <- resp
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+	assert.Nil(<-done)
+	if resp := <-polled; assert.NotNil(resp) {
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("0", resp.Header.Get(changeTokenHeader))
+	}
+}
+
+// Ensures that changes are only reported for the resource polled, and when the
+// token is older than the retained history.
+func TestEventBusChanges(t *testing.T) {
+	assert := assert.New(t)
+	bus := newEventBus(&EventConfig{History: 2})
+	widgets := func(event Event) bool { return event.Resource == "widgets" }
+
+	bus.publish(Event{Resource: "widgets"})
+	bus.publish(Event{Resource: "gadgets"})
+	bus.publish(Event{Resource: "gadgets"})
+
+	sub, changed := bus.changes(3, widgets)
+	bus.unsubscribe(sub)
+	assert.False(changed)
+	sub, changed = bus.changes(2, widgets)
+	bus.unsubscribe(sub)
+	assert.False(changed)
+	sub, changed = bus.changes(0, widgets)
+	bus.unsubscribe(sub)
+	assert.True(changed)
+	assert.Equal(uint64(3), bus.latest())
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultMaintenanceMessage is the message of the errors responded with in
+	// maintenance mode if SetMaintenance isn't given one.
+	defaultMaintenanceMessage = "Service is down for maintenance"
+
+	// defaultMaintenanceRetryAfter is the default Configuration MaintenanceRetryAfter.
+	defaultMaintenanceRetryAfter = time.Minute
+)
+
+// SetMaintenance switches maintenance mode on or off. While it's on, requests to any
+// route other than the readiness and administrative endpoints are responded to with a
+// 503 Service Unavailable error with the message and a Retry-After header. APIs
+// registered with RegisterHost are switched separately.
+func (r *muxAPI) SetMaintenance(on bool, message string) {
+	var handler http.Handler
+	if on {
+		if message == "" {
+			message = defaultMaintenanceMessage
+		}
+		retryAfter := r.config.MaintenanceRetryAfter
+		if retryAfter <= 0 {
+			retryAfter = defaultMaintenanceRetryAfter
+		}
+		handler = r.handleMaintenance(Error{
+			reason:     message,
+			status:     http.StatusServiceUnavailable,
+			retryAfter: retryAfter,
+		})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maintenance = handler
+}
+
+// handleMaintenance returns a Handler responding to requests with the error in the
+// standard envelope.
+func (r *muxAPI) handleMaintenance(err Error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := r.handler.newContext(req, w, "")
+		defer cancel()
+		ctx = ctx.setError(err)
+		r.handler.sendResponse(ctx)
+	})
+}
+
+// maintenanceExempt indicates if requests for the path are served in maintenance mode,
+// i.e. it's the readiness endpoint or one of the administrative endpoints.
+func (r *muxAPI) maintenanceExempt(path string) bool {
+	if r.config.ReadinessPath != "" && path == r.config.ReadinessPath {
+		return true
+	}
+	admin := strings.TrimRight(r.config.AdminPath, "/")
+	return admin != "" && strings.HasPrefix(path, admin+"/")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that requests are rejected with 503 errors while the API is in maintenance
+// mode, except for the readiness and administrative endpoints.
+func TestSetMaintenance(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{
+		ReadinessPath:         "/ready",
+		AdminPath:             "/admin",
+		Authenticate:          authenticateAll,
+		MaintenanceRetryAfter: 90 * time.Second,
+	})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	assert.Equal(http.StatusOK, serve("/api/v1/rows").Code)
+
+	api.SetMaintenance(true, "Migrating rows")
+	w := serve("/api/v1/rows")
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	assert.Equal("90", w.Header().Get("Retry-After"))
+	assert.Equal([]interface{}{"Migrating rows"}, decodeEnvelope(w)["messages"])
+	assert.Equal(http.StatusServiceUnavailable, serve("/missing").Code)
+	assert.Equal(http.StatusOK, serve("/ready").Code)
+	assert.Equal(http.StatusOK, serve("/admin/routes").Code)
+
+	api.SetMaintenance(true, "")
+	w = serve("/api/v1/rows")
+	assert.Equal([]interface{}{defaultMaintenanceMessage}, decodeEnvelope(w)["messages"])
+
+	api.SetMaintenance(false, "")
+	assert.Equal(http.StatusOK, serve("/api/v1/rows").Code)
+}
+
+// Ensures that maintenance mode defaults to a Retry-After of one minute.
+func TestSetMaintenanceDefaultRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.SetMaintenance(true, "")
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	assert.Equal("60", w.Header().Get("Retry-After"))
+}
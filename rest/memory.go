@@ -0,0 +1,98 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// heapSampleInterval is the minimum interval between samples of the heap size,
+	// since reading it briefly stops the world.
+	heapSampleInterval = 100 * time.Millisecond
+
+	// heapRetryAfter is how long clients are asked to wait before retrying requests
+	// rejected because the heap is too large.
+	heapRetryAfter = time.Second
+)
+
+// errResponseTooLarge is returned when a serialized response exceeds the configured
+// MaxResponseSize.
+var errResponseTooLarge = errors.New("Response too large")
+
+// processHeap samples the heap of the process.
+var processHeap = &heapMonitor{interval: heapSampleInterval, read: readHeapInUse}
+
+// heapMonitor samples the size of the heap in use, at most once per interval.
+type heapMonitor struct {
+	mu       sync.Mutex
+	interval time.Duration
+	read     func() uint64
+
+	// sampled is the time of the last sample in Unix nanoseconds and inUse is the
+	// heap size at the time, both accessed atomically.
+	sampled int64
+	inUse   uint64
+}
+
+// heapInUse returns the size of the heap in use as of the last sample, sampling it
+// again if the interval has elapsed.
+func (m *heapMonitor) heapInUse() uint64 {
+	now := time.Now().UnixNano()
+	if now-atomic.LoadInt64(&m.sampled) < int64(m.interval) {
+		return atomic.LoadUint64(&m.inUse)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now-atomic.LoadInt64(&m.sampled) >= int64(m.interval) {
+		atomic.StoreUint64(&m.inUse, m.read())
+		atomic.StoreInt64(&m.sampled, now)
+	}
+	return atomic.LoadUint64(&m.inUse)
+}
+
+// readHeapInUse returns the size of the heap in use, in bytes.
+func readHeapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// guardHeap returns a Handler which rejects requests with a 503 while the heap
+// exceeds the MaxHeapBytes of the Configuration, otherwise passing them to the next
+// Handler.
+func (h requestHandler) guardHeap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := h.Configuration()
+		if config == nil || config.MaxHeapBytes == 0 || processHeap.heapInUse() <= config.MaxHeapBytes {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The body isn't read since the request is rejected.
+		ctx, cancel := h.newContextWithBody(r, w, "", &bytes.Buffer{})
+		defer cancel()
+		h.sendResponse(ctx.setError(Unavailable(heapRetryAfter)))
+	})
+}
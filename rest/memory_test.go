@@ -0,0 +1,122 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type largeResourceHandler struct {
+	BaseResourceHandler
+	count int
+}
+
+func (l largeResourceHandler) ResourceName() string {
+	return "rows"
+}
+
+func (l largeResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	resources := make([]Resource, l.count)
+	for i := range resources {
+		resources[i] = &TestResource{Foo: "row"}
+	}
+	return resources, "", nil
+}
+
+// Ensures that responses exceeding the MaxResponseSize are replaced with a 507.
+func TestMaxResponseSize(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{MaxResponseSize: 1024})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1000})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/rows", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusInsufficientStorage, resp.Code)
+	assert.Equal(errResponseTooLarge.Error(), resp.Body.String())
+
+	api = NewAPI(&Configuration{MaxResponseSize: 1024})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+}
+
+// Ensures that encoding stops once the limit of the responseBuffer is exceeded and
+// that slices are encoded as json.Marshal does when a limit is set.
+func TestResponseBufferLimit(t *testing.T) {
+	assert := assert.New(t)
+	rows := make([]TestResource, 1000)
+	buf := &responseBuffer{limit: 100}
+	buf.encoder = json.NewEncoder(&buf.Buffer)
+	assert.Equal(errResponseTooLarge, buf.encodePayload(Payload{"results": rows}))
+	assert.Equal(0, buf.Len())
+
+	buf.limit = 1 << 20
+	values := Payload{
+		"rows":  rows[:2],
+		"bytes": []byte("hi"),
+		"times": []time.Time{time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	assert.Nil(buf.encodePayload(values))
+	expected, _ := json.Marshal(values)
+	assert.Equal(string(expected), buf.String())
+}
+
+// Ensures that requests are rejected with a 503 while the heap exceeds MaxHeapBytes.
+func TestGuardHeap(t *testing.T) {
+	assert := assert.New(t)
+	original := processHeap
+	defer func() { processHeap = original }()
+	inUse := uint64(100)
+	processHeap = &heapMonitor{read: func() uint64 { return inUse }}
+
+	api := NewAPI(&Configuration{MaxHeapBytes: 100})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/rows", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+
+	inUse = 101
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusServiceUnavailable, resp.Code)
+	assert.Equal("1", resp.Header().Get("Retry-After"))
+}
+
+// Ensures that the heap is only sampled once per interval.
+func TestHeapMonitorInterval(t *testing.T) {
+	assert := assert.New(t)
+	reads := 0
+	monitor := &heapMonitor{interval: time.Hour, read: func() uint64 {
+		reads++
+		return uint64(reads)
+	}}
+
+	assert.Equal(uint64(1), monitor.heapInUse())
+	assert.Equal(uint64(1), monitor.heapInUse())
+	assert.Equal(1, reads)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+// RouteMetadata documents one of the standard endpoints of a ResourceHandler in the
+// OpenAPI specification, the API explorer and Postman collections.
+type RouteMetadata struct {
+	// Summary is a short summary of what the endpoint does.
+	Summary string
+
+	// Description describes the endpoint, taking precedence over the handler's
+	// documentation method, e.g. CreateDocumentation.
+	Description string
+
+	// Tags group the endpoint in documentation, in addition to the resource name.
+	Tags []string
+
+	// ExampleRequest is an example request payload.
+	ExampleRequest interface{}
+
+	// ExampleResponse is an example result, sent in the response envelope.
+	ExampleResponse interface{}
+
+	// Deprecated indicates if the endpoint is deprecated.
+	Deprecated bool
+}
+
+// RouteMetadataProvider is an optional interface implemented by ResourceHandlers to
+// keep the documentation of their endpoints next to the code serving them.
+type RouteMetadataProvider interface {
+	// RouteMetadata returns the metadata of the handler's endpoints, keyed by the
+	// HandleMethod of each endpoint.
+	RouteMetadata() map[HandleMethod]RouteMetadata
+}
+
+// routeMetadata returns the metadata of the ResourceHandler's endpoint, if it's a
+// RouteMetadataProvider.
+func routeMetadata(handler ResourceHandler, method HandleMethod) RouteMetadata {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	if provider, ok := handler.(RouteMetadataProvider); ok {
+		return provider.RouteMetadata()[method]
+	}
+	return RouteMetadata{}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// documentedResourceHandler is a ResourceHandler providing route metadata.
+type documentedResourceHandler struct {
+	TestResourceHandler
+}
+
+func (d documentedResourceHandler) RouteMetadata() map[HandleMethod]RouteMetadata {
+	return map[HandleMethod]RouteMetadata{
+		HandleCreate: {
+			Summary:         "Create a widget",
+			Description:     "Creates a widget in the catalog",
+			Tags:            []string{"catalog"},
+			ExampleRequest:  map[string]interface{}{"name": "sprocket"},
+			ExampleResponse: map[string]interface{}{"id": "1", "name": "sprocket"},
+		},
+		HandleDelete: {Deprecated: true},
+	}
+}
+
+// Ensures that routeMetadata returns the metadata of registered RouteMetadataProviders
+// and is empty for other ResourceHandlers.
+func TestRouteMetadata(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(documentedResourceHandler{})
+	handler := api.ResourceHandlers()[0]
+
+	assert.Equal("Create a widget", routeMetadata(handler, HandleCreate).Summary)
+	assert.True(routeMetadata(handler, HandleDelete).Deprecated)
+	assert.Equal(RouteMetadata{}, routeMetadata(handler, HandleRead))
+	assert.Equal(RouteMetadata{}, routeMetadata(TestResourceHandler{}, HandleCreate))
+}
+
+// Ensures that route metadata is included in the OpenAPI specification.
+func TestRouteMetadataOpenAPI(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(documentedResourceHandler{})
+	spec := getOpenAPI(t, api)
+
+	paths := spec["paths"].(map[string]interface{})
+	create := paths["/api/v{version}/widgets"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal("Create a widget", create["summary"])
+	assert.Equal("Creates a widget in the catalog", create["description"])
+	assert.Equal([]interface{}{"widgets", "catalog"}, create["tags"])
+
+	body := create["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"name": "sprocket"},
+		body["application/json"].(map[string]interface{})["example"])
+
+	response := create["responses"].(map[string]interface{})["201"].(map[string]interface{})
+	example := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})["example"]
+	assert.Equal(map[string]interface{}{"id": "1", "name": "sprocket"}, example.(map[string]interface{})["result"])
+
+	remove := paths["/api/v{version}/widgets/{resource_id}"].(map[string]interface{})["delete"].(map[string]interface{})
+	assert.Equal(true, remove["deprecated"])
+	assert.NotContains(remove, "summary")
+}
+
+// Ensures that route metadata is used in Postman collections.
+func TestRouteMetadataPostman(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(documentedResourceHandler{})
+
+	data, err := PostmanCollection(api)
+	assert.Nil(err)
+	var collection map[string]interface{}
+	assert.Nil(json.Unmarshal(data, &collection))
+
+	folder := collection["item"].([]interface{})[0].(map[string]interface{})
+	create := folder["item"].([]interface{})[0].(map[string]interface{})
+	request := create["request"].(map[string]interface{})
+	assert.Equal("Creates a widget in the catalog", request["description"])
+
+	var body map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(request["body"].(map[string]interface{})["raw"].(string)), &body))
+	assert.Equal(map[string]interface{}{"name": "sprocket"}, body)
+	assert.Len(create["response"], 1)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// methodOverrideField is the form field overriding the method of POST requests.
+	methodOverrideField = "_method"
+
+	// maxOverrideFormSize is the maximum size of form bodies read for the method
+	// override field, matching the limit of http.Request.ParseForm.
+	maxOverrideFormSize = 10 << 20
+)
+
+// methodOverrideHeaders are the headers overriding the methods of POST requests, in
+// order of precedence.
+var methodOverrideHeaders = []string{"X-HTTP-Method-Override", "X-HTTP-Method", "X-Method-Override"}
+
+// overridableMethods are the methods a POST request can be overridden with.
+var overridableMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// overrideMethod returns the request with its method overridden if the Configuration
+// enables MethodOverride and it's a POST request with a method override header or, if
+// its body is a URL-encoded form, a _method field. The body of the request is left
+// for its handler to read.
+func (r *muxAPI) overrideMethod(req *http.Request) *http.Request {
+	if !r.config.MethodOverride || req.Method != http.MethodPost {
+		return req
+	}
+	method := ""
+	for _, header := range methodOverrideHeaders {
+		if method = req.Header.Get(header); method != "" {
+			break
+		}
+	}
+	if method == "" {
+		method = formMethodOverride(req)
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if !overridableMethods[method] {
+		return req
+	}
+
+	clone := new(http.Request)
+	*clone = *req
+	clone.Method = method
+	return clone
+}
+
+// formMethodOverride returns the _method field of the request's URL-encoded form body
+// or an empty string if there is none. The body is replaced so it can be read again.
+func formMethodOverride(req *http.Request) string {
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != "application/x-www-form-urlencoded" || req.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxOverrideFormSize+1))
+	req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	if err != nil || len(body) > maxOverrideFormSize {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(methodOverrideField)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveOverride returns the response of an API with a custom route echoing the method
+// and body of requests.
+func serveOverride(enabled bool, req *http.Request) string {
+	api := NewAPI(&Configuration{MethodOverride: enabled})
+	api.RegisterHandlerFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + string(body)))
+	})
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+// Ensures that the methods of POST requests are overridden by headers and form fields
+// only when MethodOverride is enabled.
+func TestMethodOverride(t *testing.T) {
+	assert := assert.New(t)
+	header := func(key, value string) *http.Request {
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader("data"))
+		req.Header.Set(key, value)
+		return req
+	}
+	form := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		return req
+	}
+
+	assert.Equal("PATCH data", serveOverride(true, header("X-HTTP-Method-Override", "patch")))
+	assert.Equal("DELETE data", serveOverride(true, header("X-HTTP-Method", "DELETE")))
+	assert.Equal("PUT data", serveOverride(true, header("X-Method-Override", "PUT")))
+	assert.Equal("PUT _method=PUT&a=1", serveOverride(true, form("_method=PUT&a=1")))
+	assert.Equal("POST data", serveOverride(true, header("X-HTTP-Method-Override", "CONNECT")))
+	assert.Equal("POST a=1", serveOverride(true, form("a=1")))
+	assert.Equal("POST data", serveOverride(false, header("X-HTTP-Method-Override", "PATCH")))
+	assert.Equal("POST _method=PUT", serveOverride(false, form("_method=PUT")))
+
+	get := httptest.NewRequest("GET", "/echo", nil)
+	get.Header.Set("X-HTTP-Method-Override", "DELETE")
+	assert.Equal("GET ", serveOverride(true, get))
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// bucketExpiry is how often idle clients are forgotten by a rate limit Middleware.
+const bucketExpiry = time.Minute
+
+// bucket is the token bucket of a client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of requests from each client with token buckets.
+type rateLimiter struct {
+	rate           float64
+	burst          float64
+	trustedProxies []*net.IPNet
+	now            func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewRateLimitMiddleware returns a Middleware which limits each client, identified by
+// its IP address, to rate requests per second on average with bursts of up to burst
+// requests. Forwarding headers are honored for requests from the trusted proxies.
+// Requests exceeding the limit are terminated with a 429 response and a Retry-After
+// header.
+func NewRateLimitMiddleware(rate float64, burst int, trustedProxies []*net.IPNet) rest.Middleware {
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := &rateLimiter{
+		rate:           rate,
+		burst:          float64(burst),
+		trustedProxies: trustedProxies,
+		now:            time.Now,
+		buckets:        map[string]*bucket{},
+	}
+	return limiter.limit
+}
+
+// limit terminates the request with a 429 response if its client has exceeded the
+// limit.
+func (l *rateLimiter) limit(w http.ResponseWriter, r *http.Request) *rest.MiddlewareError {
+	wait := l.take(rest.ClientIP(r, l.trustedProxies))
+	if wait <= 0 {
+		return nil
+	}
+	seconds := int64(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	return &rest.MiddlewareError{
+		Code:     http.StatusTooManyRequests,
+		Response: []byte("Rate limit exceeded"),
+	}
+}
+
+// take takes a token from the client's bucket, returning how long the client has to
+// wait for one if it's empty.
+func (l *rateLimiter) take(client string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.swept) >= bucketExpiry {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if l.rate <= 0 {
+		return bucketExpiry
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep forgets the clients whose buckets have been idle long enough to refill.
+func (l *rateLimiter) sweep(now time.Time) {
+	l.swept = now
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= bucketExpiry && b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the rate limit Middleware allows bursts of requests from each client,
+// rejects further requests with a 429 response until tokens are replenished and
+// forgets idle clients.
+func TestRateLimitMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+	limiter := &rateLimiter{
+		rate:    0.5,
+		burst:   2,
+		now:     func() time.Time { return now },
+		buckets: map[string]*bucket{},
+	}
+	request := func(remote string) (*httptest.ResponseRecorder, int) {
+		req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		if err := limiter.limit(w, req); err != nil {
+			return w, err.Code
+		}
+		return w, http.StatusOK
+	}
+
+	_, code := request("10.0.0.1:1234")
+	assert.Equal(http.StatusOK, code)
+	_, code = request("10.0.0.1:1235")
+	assert.Equal(http.StatusOK, code)
+	w, code := request("10.0.0.1:1236")
+	assert.Equal(http.StatusTooManyRequests, code)
+	assert.Equal("2", w.Header().Get("Retry-After"))
+
+	_, code = request("10.0.0.2:1234")
+	assert.Equal(http.StatusOK, code)
+
+	now = now.Add(2 * time.Second)
+	_, code = request("10.0.0.1:1234")
+	assert.Equal(http.StatusOK, code)
+
+	now = now.Add(time.Hour)
+	_, code = request("10.0.0.3:1234")
+	assert.Equal(http.StatusOK, code)
+	assert.Len(limiter.buckets, 1)
+}
+
+// Ensures that the rate limit Middleware identifies clients behind trusted proxies by
+// their forwarded address.
+func TestRateLimitMiddlewareTrustedProxies(t *testing.T) {
+	assert := assert.New(t)
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	middleware := NewRateLimitMiddleware(1, 1, []*net.IPNet{proxies})
+	request := func(client string) *http.Request {
+		req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", client)
+		return req
+	}
+
+	assert.Nil(middleware(httptest.NewRecorder(), request("203.0.113.1")))
+	assert.Nil(middleware(httptest.NewRecorder(), request("203.0.113.2")))
+	err := middleware(httptest.NewRecorder(), request("203.0.113.1"))
+	assert.NotNil(err)
+	assert.Equal(http.StatusTooManyRequests, err.Code)
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// openAPIVersion is the version of the OpenAPI specification generated.
+	openAPIVersion = "3.0.3"
+
+	// openAPIURI is the URI at which the OpenAPI specification is served.
+	openAPIURI = "/api/openapi.json"
+)
+
+// APIInfo describes an API in generated documentation, such as its OpenAPI
+// specification.
+type APIInfo struct {
+	// Title is the name of the API. Defaults to "API".
+	Title string
+
+	// Description describes the API.
+	Description string
+
+	// Version is the version of the API documentation. Defaults to the latest
+	// version of the registered ResourceHandlers.
+	Version string
+}
+
+// uriParamRegex matches the templated variables of a URI, e.g. {id} or
+// {version:[^/]+}.
+var uriParamRegex = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// OpenAPI returns the OpenAPI 3 specification of the API as JSON. It describes the
+// standard endpoints of every registered ResourceHandler for each of its versions,
+// including the response envelope and payload schemas derived from their Rules or
+// resource types. The same specification is served at /api/openapi.json.
+func OpenAPI(api API) ([]byte, error) {
+	return json.MarshalIndent(newOpenAPIDocument(api), "", "  ")
+}
+
+// openAPIHandler returns a Handler serving the OpenAPI specification of the API.
+func openAPIHandler(api API) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spec, err := OpenAPI(api)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+}
+
+// openAPIOperation describes one of the standard endpoints of a ResourceHandler.
+type openAPIOperation struct {
+	name        HandleMethod
+	method      string
+	uri         string
+	description string
+	input       bool
+	list        bool
+	status      int
+}
+
+// openAPIOperations returns the standard endpoints of the ResourceHandler.
+func openAPIOperations(handler ResourceHandler) []openAPIOperation {
+	return []openAPIOperation{
+		{HandleCreate, "post", handler.CreateURI(), handler.CreateDocumentation(), true, false, http.StatusCreated},
+		{HandleReadList, "get", handler.ReadListURI(), handler.ReadListDocumentation(), false, true, http.StatusOK},
+		{HandleRead, "get", handler.ReadURI(), handler.ReadDocumentation(), false, false, http.StatusOK},
+		{HandleUpdateList, "put", handler.UpdateListURI(), handler.UpdateListDocumentation(), true, true, http.StatusOK},
+		{HandleUpdate, "put", handler.UpdateURI(), handler.UpdateDocumentation(), true, false, http.StatusOK},
+		{HandleDelete, "delete", handler.DeleteURI(), handler.DeleteDocumentation(), false, false, http.StatusOK},
+	}
+}
+
+// newOpenAPIDocument builds the OpenAPI specification of the API.
+func newOpenAPIDocument(api API) map[string]interface{} {
+	config := api.Configuration()
+	handlers := api.ResourceHandlers()
+
+	info := map[string]interface{}{"title": config.Info.Title, "version": config.Info.Version}
+	if config.Info.Title == "" {
+		info["title"] = "API"
+	}
+	if config.Info.Version == "" {
+		info["version"] = "1.0"
+		if all := versions(handlers); len(all) > 0 {
+			info["version"] = all[len(all)-1]
+		}
+	}
+	if config.Info.Description != "" {
+		info["description"] = config.Info.Description
+	}
+
+	schemas := openAPIEnvelopeSchemas()
+	paths := map[string]map[string]interface{}{}
+	for _, handler := range handlers {
+		for _, version := range apiVersions(handler) {
+			output := openAPISchemaName(handler, Outbound, version)
+			input := openAPISchemaName(handler, Inbound, version)
+			schemas[output] = rulesSchema(handler.Rules(), Outbound, version)
+			schemas[input] = rulesSchema(handler.Rules(), Inbound, version)
+
+			for _, op := range openAPIOperations(handler) {
+				path := openAPIPath(op.uri, version)
+				if paths[path] == nil {
+					paths[path] = map[string]interface{}{}
+				}
+				paths[path][op.method] = newOpenAPIOperation(handler, op, path, version, input, output)
+			}
+		}
+	}
+
+	errorContent := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schemaRef("Error")},
+	}
+	if config.ProblemDetails {
+		errorContent = map[string]interface{}{
+			problemContentType: map[string]interface{}{"schema": schemaRef("Problem")},
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info":    info,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error response",
+					"content":     errorContent,
+				},
+			},
+		},
+	}
+}
+
+// newOpenAPIOperation returns the OpenAPI operation object for the endpoint.
+func newOpenAPIOperation(handler ResourceHandler, op openAPIOperation, path, version,
+	input, output string) map[string]interface{} {
+
+	operationID := handler.ResourceName() + "." + string(op.name)
+	if version != "" {
+		operationID += ".v" + version
+	}
+
+	parameters := []interface{}{}
+	for _, match := range uriParamRegex.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema{"type": "string"},
+		})
+	}
+	if op.list && !op.input {
+		parameters = append(parameters,
+			map[string]interface{}{
+				"name":        limitKey,
+				"in":          "query",
+				"description": "Maximum number of results to return",
+				"schema":      schema{"type": "integer", "minimum": 1},
+			},
+			map[string]interface{}{
+				"name":        cursorKey,
+				"in":          "query",
+				"description": "Cursor of the page of results to return",
+				"schema":      schema{"type": "string"},
+			},
+			map[string]interface{}{
+				"name":        offsetKey,
+				"in":          "query",
+				"description": "Number of results to skip, returning a meta block with page counts",
+				"schema":      schema{"type": "integer", "minimum": 0},
+			},
+		)
+		if fields := filterableFields(handler.Rules(), version); len(fields) > 0 {
+			parameters = append(parameters, map[string]interface{}{
+				"name": filterKey,
+				"in":   "query",
+				"description": "Conditions on " + strings.Join(sortedFilterFields(fields), ", ") +
+					", e.g. status==active,size>=3",
+				"schema": schema{"type": "string"},
+			})
+		}
+		if fields := sortableFields(handler.Rules(), version); len(fields) > 0 {
+			parameters = append(parameters, map[string]interface{}{
+				"name": sortKey,
+				"in":   "query",
+				"description": "Fields to sort by, descending if prefixed with -, of " +
+					strings.Join(fields, ", "),
+				"schema": schema{"type": "string"},
+			})
+		}
+	}
+	if op.method == "get" {
+		if fields := referencingFields(handler.Rules(), version); len(fields) > 0 {
+			parameters = append(parameters, map[string]interface{}{
+				"name": expandKey,
+				"in":   "query",
+				"description": "Fields whose referenced resources are embedded, of " +
+					strings.Join(fields, ", "),
+				"schema": schema{"type": "string"},
+			})
+		}
+	}
+
+	for _, param := range declaredQueryParameters(handler, version) {
+		paramSchema := ruleTypeSchema(param.Type)
+		if len(param.Values) > 0 {
+			paramSchema["enum"] = param.Values
+		}
+		parameter := map[string]interface{}{
+			"name":   param.Name,
+			"in":     "query",
+			"schema": paramSchema,
+		}
+		if param.DocString != "" {
+			parameter["description"] = param.DocString
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	resultKey, resultSchema := result, schema(schemaRef(output))
+	if op.list {
+		resultKey, resultSchema = results, schema{"type": "array", "items": schemaRef(output)}
+	}
+	properties := schema{resultKey: resultSchema}
+	if op.list {
+		properties[next] = schema{"type": "string", "description": "URL of the next page of results"}
+		properties[total] = schema{"type": "integer", "description": "Total number of results"}
+		properties[meta] = schema{
+			"type":        "object",
+			"description": "Page of offset-paginated results",
+			"properties": schema{
+				limitKey:  schema{"type": "integer"},
+				offsetKey: schema{"type": "integer"},
+				"page":    schema{"type": "integer"},
+				total:     schema{"type": "integer"},
+				"pages":   schema{"type": "integer"},
+			},
+		}
+	}
+
+	metadata := routeMetadata(handler, op.name)
+	successContent := map[string]interface{}{
+		"schema": schema{"allOf": []interface{}{
+			schemaRef("Envelope"),
+			schema{"type": "object", "properties": properties},
+		}},
+	}
+	if metadata.ExampleResponse != nil {
+		successContent["example"] = map[string]interface{}{
+			status:    op.status,
+			reason:    http.StatusText(op.status),
+			messages:  []string{},
+			resultKey: metadata.ExampleResponse,
+		}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": operationID,
+		"tags":        append([]string{handler.ResourceName()}, metadata.Tags...),
+		"responses": map[string]interface{}{
+			strconv.Itoa(op.status): map[string]interface{}{
+				"description": http.StatusText(op.status),
+				"content": map[string]interface{}{
+					"application/json": successContent,
+				},
+			},
+			"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+		},
+	}
+	if metadata.Description != "" {
+		operation["description"] = metadata.Description
+	} else if op.description != "" {
+		operation["description"] = op.description
+	}
+	if metadata.Summary != "" {
+		operation["summary"] = metadata.Summary
+	}
+	if metadata.Deprecated {
+		operation["deprecated"] = true
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if op.input {
+		body := schema(schemaRef(input))
+		if op.list {
+			body = schema{"type": "array", "items": schemaRef(input)}
+		}
+		content := map[string]interface{}{"schema": body}
+		if metadata.ExampleRequest != nil {
+			content["example"] = metadata.ExampleRequest
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": content},
+		}
+	}
+	return operation
+}
+
+// openAPIEnvelopeSchemas returns the schemas of the response envelope and error
+// responses.
+func openAPIEnvelopeSchemas() map[string]interface{} {
+	fieldError := schema{
+		"type": "object",
+		"properties": schema{
+			"field":   schema{"type": "string"},
+			"code":    schema{"type": "string"},
+			"message": schema{"type": "string"},
+		},
+	}
+	return map[string]interface{}{
+		"Envelope": schema{
+			"type":     "object",
+			"required": []string{status, reason, messages},
+			"properties": schema{
+				status:   schema{"type": "integer"},
+				reason:   schema{"type": "string"},
+				messages: schema{"type": "array", "items": schema{"type": "string"}},
+				links:    schema{"type": "object", "additionalProperties": schema{"type": "string"}},
+			},
+		},
+		"FieldError": fieldError,
+		"Error": schema{"allOf": []interface{}{
+			schemaRef("Envelope"),
+			schema{
+				"type": "object",
+				"properties": schema{
+					errs:      schema{"type": "array", "items": schemaRef("FieldError")},
+					code:      schema{"type": "string"},
+					requestID: schema{"type": "string"},
+					incident:  schema{"type": "string"},
+				},
+			},
+		}},
+		"Problem": schema{
+			"type": "object",
+			"properties": schema{
+				"type":     schema{"type": "string"},
+				"title":    schema{"type": "string"},
+				"status":   schema{"type": "integer"},
+				"detail":   schema{"type": "string"},
+				"instance": schema{"type": "string"},
+				errs:       schema{"type": "array", "items": schemaRef("FieldError")},
+				code:       schema{"type": "string"},
+			},
+		},
+	}
+}
+
+// apiVersions returns the versions served by the ResourceHandler: its ValidVersions
+// or, if they aren't restricted, the versions specified by its Rules. If neither
+// specify versions, a single empty version is returned, meaning any version.
+func apiVersions(handler ResourceHandler) []string {
+	if valid := handler.ValidVersions(); len(valid) > 0 {
+		return valid
+	}
+	if versions := handlerVersions(handler); len(versions) > 0 {
+		return versions
+	}
+	return []string{""}
+}
+
+// openAPIPath returns the URI as an OpenAPI path template, substituting the version
+// if it isn't empty and removing the patterns of templated variables.
+func openAPIPath(uri, version string) string {
+	if version != "" {
+		uri = strings.Replace(uri, "{"+versionKey+":[^/]+}", version, -1)
+	}
+	return uriParamRegex.ReplaceAllString(uri, "{$1}")
+}
+
+// openAPISchemaName returns the name of the component schema for the payloads of
+// the ResourceHandler in the direction given by the Filter for the version.
+func openAPISchemaName(handler ResourceHandler, filter Filter, version string) string {
+	name := []rune(handlerTypeName(handler))
+	if len(name) > 0 {
+		name[0] = unicode.ToUpper(name[0])
+	}
+	s := string(name)
+	if filter == Inbound {
+		s += "Input"
+	}
+	if version != "" {
+		s += "V" + strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, version)
+	}
+	return s
+}
+
+// schemaRef returns a reference to the named component schema.
+func schemaRef(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
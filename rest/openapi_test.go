@@ -0,0 +1,132 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getOpenAPI returns the decoded OpenAPI specification served by the API.
+func getOpenAPI(t *testing.T, api API) map[string]interface{} {
+	req, _ := http.NewRequest("GET", "http://example.com/api/openapi.json", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var spec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	return spec
+}
+
+// Ensures that the OpenAPI specification describes the endpoints of each version of
+// the registered ResourceHandlers.
+func TestOpenAPIPaths(t *testing.T) {
+	assert := assert.New(t)
+	api := setupAPI()
+	api.Configuration().Info = APIInfo{Title: "Widgets", Description: "Widget API"}
+	spec := getOpenAPI(t, api)
+
+	assert.Equal("3.0.3", spec["openapi"])
+	assert.Equal(map[string]interface{}{
+		"title": "Widgets", "description": "Widget API", "version": "2",
+	}, spec["info"])
+
+	paths := spec["paths"].(map[string]interface{})
+	assert.Contains(paths, "/api/v1/foo")
+	assert.Contains(paths, "/api/v1/foo/{resource_id}")
+	assert.Contains(paths, "/api/v1/bar")
+	assert.Contains(paths, "/api/v2/bar/{resource_id}")
+	assert.NotContains(paths, "/api/v2/foo")
+
+	collection := paths["/api/v1/foo"].(map[string]interface{})
+	assert.Contains(collection, "get")
+	assert.Contains(collection, "post")
+	assert.Contains(collection, "put")
+
+	create := collection["post"].(map[string]interface{})
+	assert.Equal("foo.create.v1", create["operationId"])
+	assert.Equal("Creates a new foo", create["description"])
+	body := create["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"$ref": "#/components/schemas/FooResourceInputV1"},
+		body["application/json"].(map[string]interface{})["schema"])
+	responses := create["responses"].(map[string]interface{})
+	assert.Contains(responses, "201")
+	assert.Equal(map[string]interface{}{"$ref": "#/components/responses/Error"}, responses["default"])
+
+	read := paths["/api/v1/foo/{resource_id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal([]interface{}{map[string]interface{}{
+		"name": "resource_id", "in": "path", "required": true,
+		"schema": map[string]interface{}{"type": "string"},
+	}}, read["parameters"])
+
+	list := collection["get"].(map[string]interface{})
+	assert.Len(list["parameters"], 3)
+}
+
+// Ensures that the OpenAPI specification includes payload schemas derived from the
+// Rules.
+func TestOpenAPISchemas(t *testing.T) {
+	assert := assert.New(t)
+	spec := getOpenAPI(t, setupAPI())
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	assert.Contains(schemas, "Envelope")
+	assert.Contains(schemas, "Error")
+
+	input := schemas["FooResourceInputV1"].(map[string]interface{})
+	assert.Equal([]interface{}{"foo", "baz", "qux"}, input["required"])
+	properties := input["properties"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"type": "string", "description": "foo"}, properties["foo"])
+	assert.Equal(map[string]interface{}{"type": "string", "format": "date-time", "description": "qux"},
+		properties["qux"])
+
+	output := schemas["BarResourceV2"].(map[string]interface{})
+	assert.NotContains(output, "required")
+	qux := output["properties"].(map[string]interface{})["qux"].(map[string]interface{})
+	assert.Equal("array", qux["type"])
+	assert.Contains(qux["items"].(map[string]interface{})["properties"], "bar")
+}
+
+// Ensures that the OpenAPI specification keeps the version path parameter for
+// unversioned resources and describes problem details errors when enabled.
+func TestOpenAPIUnversioned(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ProblemDetails: true})
+	api.RegisterResourceHandler(&TestResourceHandler{})
+
+	spec, err := OpenAPI(api)
+	assert.Nil(err)
+	var decoded map[string]interface{}
+	assert.Nil(json.Unmarshal(spec, &decoded))
+
+	paths := decoded["paths"].(map[string]interface{})
+	assert.Contains(paths, "/api/v{version}/widgets")
+	errorResponse := decoded["components"].(map[string]interface{})["responses"].(map[string]interface{})["Error"]
+	assert.Contains(errorResponse.(map[string]interface{})["content"], "application/problem+json")
+}
+
+// Ensures that openAPIPath substitutes the version and strips variable patterns.
+func TestOpenAPIPath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("/api/v1/foo/{resource_id}", openAPIPath("/api/v{version:[^/]+}/foo/{resource_id}", "1"))
+	assert.Equal("/api/v{version}/foo/{id}", openAPIPath("/api/v{version:[^/]+}/foo/{id:[0-9]+}", ""))
+}
@@ -0,0 +1,471 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// operationsResource is the name of the resource serving asynchronous operations.
+	operationsResource = "operations"
+
+	// defaultOperationRetention is the default duration finished operations are kept.
+	defaultOperationRetention = time.Hour
+)
+
+// OperationStatus is the status of an asynchronous Operation.
+type OperationStatus string
+
+const (
+	// OperationPending is the status of an Operation which hasn't started running.
+	OperationPending OperationStatus = "pending"
+
+	// OperationRunning is the status of a running Operation.
+	OperationRunning OperationStatus = "running"
+
+	// OperationSucceeded is the status of an Operation which returned a result.
+	OperationSucceeded OperationStatus = "succeeded"
+
+	// OperationFailed is the status of an Operation which returned an error, or was
+	// canceled.
+	OperationFailed OperationStatus = "failed"
+)
+
+// OperationConfig configures the asynchronous operations started by ResourceHandlers
+// returning an AsyncResult. Operations are kept in memory and served at
+// /api/v{version}/operations/{id}, where DELETE cancels them. Requests for an
+// Operation are authenticated by the Configuration's Authenticate function and the
+// Authenticate method of the ResourceHandler which started it, and Operations are only
+// served to the Principal which started them.
+type OperationConfig struct {
+	// Retention is how long finished Operations are kept. Defaults to one hour.
+	Retention time.Duration
+}
+
+// AsyncResult is returned by the CreateResource, ReadResource, UpdateResource and
+// DeleteResource methods of ResourceHandlers in place of a Resource to perform a
+// long-running job, such as an export or provisioning, in the background. The request
+// is responded to with a 202 Accepted and a Location header of the Operation tracking
+// the job, whose result is the Resource returned by the job with the outbound Rules of
+// the ResourceHandler applied. The Configuration's Operations must be set.
+type AsyncResult struct {
+	run func(context.Context, *Operation) (Resource, error)
+}
+
+// NewAsyncResult returns an AsyncResult running the job, which reports its progress to
+// the Operation. The job's context is canceled when the Operation is canceled or the
+// API is shut down.
+func NewAsyncResult(run func(ctx context.Context, op *Operation) (Resource, error)) *AsyncResult {
+	return &AsyncResult{run: run}
+}
+
+// Operation tracks the status of the job of an AsyncResult.
+type Operation struct {
+	mu         sync.Mutex
+	id         string
+	handler    ResourceHandler
+	owner      string
+	resourceID string
+	status     OperationStatus
+	progress   float64
+	message    string
+	result     Resource
+	err        string
+	executeAt  time.Time
+	created    time.Time
+	updated    time.Time
+	cancel     func()
+	canceled   bool
+}
+
+// operationView is the representation of an Operation served to clients.
+type operationView struct {
+	ID         string          `json:"id"`
+	Status     OperationStatus `json:"status"`
+	Resource   string          `json:"resource"`
+	ResourceID string          `json:"resource_id,omitempty"`
+	Progress   float64         `json:"progress"`
+	Message    string          `json:"message,omitempty"`
+	Result     Resource        `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	ExecuteAt  *time.Time      `json:"execute_at,omitempty"`
+	Created    time.Time       `json:"created"`
+	Updated    time.Time       `json:"updated"`
+}
+
+// ID returns the ID of the Operation.
+func (o *Operation) ID() string {
+	return o.id
+}
+
+// SetProgress reports the fraction of the job completed, between 0 and 1, and
+// optionally a message describing what it's doing.
+func (o *Operation) SetProgress(progress float64, message string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.progress = progress
+	o.message = message
+	o.updated = time.Now().UTC()
+}
+
+// view returns the representation of the Operation served to clients.
+func (o *Operation) view() *operationView {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	view := &operationView{
+		ID:         o.id,
+		Status:     o.status,
+		Resource:   o.handler.ResourceName(),
+		ResourceID: o.resourceID,
+		Progress:   o.progress,
+		Message:    o.message,
+		Result:     o.result,
+		Error:      o.err,
+		Created:    o.created,
+		Updated:    o.updated,
+	}
+	if !o.executeAt.IsZero() {
+		executeAt := o.executeAt
+		view.ExecuteAt = &executeAt
+	}
+	return view
+}
+
+// begin marks the Operation running, canceled by calling cancel from then on, and
+// returns true unless it's already been canceled.
+func (o *Operation) begin(cancel func()) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.canceled {
+		return false
+	}
+	o.status = OperationRunning
+	o.updated = time.Now().UTC()
+	o.cancel = cancel
+	return true
+}
+
+// abort cancels the Operation's job, or the job it's waiting to run.
+func (o *Operation) abort() {
+	o.mu.Lock()
+	o.canceled = true
+	cancel := o.cancel
+	o.mu.Unlock()
+	cancel()
+}
+
+// finish records the outcome of the Operation's job.
+func (o *Operation) finish(result Resource, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.updated = time.Now().UTC()
+	if err != nil {
+		o.status = OperationFailed
+		o.err = err.Error()
+		return
+	}
+	o.status = OperationSucceeded
+	o.progress = 1
+	o.result = result
+}
+
+// finished returns true if the Operation finished before the given time.
+func (o *Operation) finished(before time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return (o.status == OperationSucceeded || o.status == OperationFailed) &&
+		o.updated.Before(before)
+}
+
+// operationStore keeps the Operations started by the API.
+type operationStore struct {
+	retention  time.Duration
+	mu         sync.Mutex
+	operations map[string]*Operation
+	ctx        context.Context
+	cancel     context.CancelFunc
+	running    sync.WaitGroup
+}
+
+// newOperationStore returns an operationStore with the defaults applied to the
+// configuration.
+func newOperationStore(config *OperationConfig) *operationStore {
+	retention := config.Retention
+	if retention <= 0 {
+		retention = defaultOperationRetention
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &operationStore{
+		retention:  retention,
+		operations: map[string]*Operation{},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// start starts an Operation owned by the Principal with the ID running the job,
+// calling done with its result once it succeeds.
+func (s *operationStore) start(handler ResourceHandler, owner, resourceID string,
+	job func(context.Context, *Operation) (Resource, error), done func(Resource) Resource) *Operation {
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	op := s.track(newRequestID(), handler, owner, resourceID, time.Time{}, cancel)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer cancel()
+		if !op.begin(cancel) {
+			op.finish(nil, context.Canceled)
+			return
+		}
+		op.finish(runJob(ctx, op, job, done))
+	}()
+	return op
+}
+
+// track returns the Operation with the ID, adding a pending Operation owned by the
+// Principal with the owner ID and canceled by calling cancel if there's none.
+func (s *operationStore) track(id string, handler ResourceHandler, owner, resourceID string,
+	executeAt time.Time, cancel func()) *Operation {
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	if op, ok := s.operations[id]; ok {
+		return op
+	}
+	op := &Operation{
+		id:         id,
+		handler:    handler,
+		owner:      owner,
+		resourceID: resourceID,
+		status:     OperationPending,
+		executeAt:  executeAt,
+		created:    now,
+		updated:    now,
+		cancel:     cancel,
+	}
+	s.operations[id] = op
+	return op
+}
+
+// runJob runs the job of the Operation, returning the result passed through done. A
+// panicking job fails the Operation.
+func runJob(ctx context.Context, op *Operation, job func(context.Context, *Operation) (Resource, error),
+	done func(Resource) Resource) (result Resource, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Operation %s panicked: %v", op.id, recovered)
+			result, err = nil, fmt.Errorf("Operation failed")
+		}
+	}()
+	if result, err = job(ctx, op); err != nil {
+		return nil, err
+	}
+	return done(result), nil
+}
+
+// prune removes the Operations finished longer ago than the retention. The lock must be
+// held.
+func (s *operationStore) prune(now time.Time) {
+	for id, op := range s.operations {
+		if op.finished(now.Add(-s.retention)) {
+			delete(s.operations, id)
+		}
+	}
+}
+
+// get returns the Operation with the ID, if any.
+func (s *operationStore) get(id string) (*Operation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	op, ok := s.operations[id]
+	return op, ok
+}
+
+// stop cancels the running Operations and waits for them to finish or the context to
+// be done.
+func (s *operationStore) stop(ctx context.Context) error {
+	s.cancel()
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startOperation starts an Operation running the AsyncResult returned by the
+// ResourceHandler for the request, publishing an Event of the kind once it succeeds,
+// and sets the 202 Accepted response.
+func (h requestHandler) startOperation(ctx RequestContext, handler ResourceHandler,
+	kind EventType, async *AsyncResult) RequestContext {
+
+	if h.operations == nil {
+		return ctx.setError(InternalServerError("Asynchronous operations aren't enabled"))
+	}
+	resourceID, version := ctx.ResourceID(), ctx.Version()
+	op := h.operations.start(handler, principalID(ctx), resourceID, async.run, func(result Resource) Resource {
+		result = applyOutboundRules(result, handler.Rules(), version)
+		if kind != "" {
+			h.publishEvent(kind, handler, resourceID, version, result)
+		}
+		return result
+	})
+
+	if url, err := ctx.BuildURL(operationsResource, HandleRead,
+		RouteVars{resourceIDKey: op.id}); err == nil {
+		ctx.ResponseHeader().Set("Location", url.String())
+	}
+	ctx = ctx.setResult(op.view())
+	return ctx.setStatus(http.StatusAccepted)
+}
+
+// operationsResourceHandler is the ResourceHandler serving asynchronous Operations.
+type operationsResourceHandler struct {
+	BaseResourceHandler
+	operations   *operationStore
+	authenticate func(*http.Request) error
+}
+
+// ResourceName returns "operations".
+func (o operationsResourceHandler) ResourceName() string {
+	return operationsResource
+}
+
+// ReadResourceList returns the Operations of the request's Principal which the request
+// is authorized for, oldest first.
+func (o operationsResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+
+	o.operations.mu.Lock()
+	o.operations.prune(time.Now())
+	ops := make([]*Operation, 0, len(o.operations.operations))
+	for _, op := range o.operations.operations {
+		ops = append(ops, op)
+	}
+	o.operations.mu.Unlock()
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].created.Equal(ops[j].created) {
+			return ops[i].id < ops[j].id
+		}
+		return ops[i].created.Before(ops[j].created)
+	})
+
+	resources := []Resource{}
+	for _, op := range ops {
+		if authorizeOperation(ctx, op) == nil && ownsOperation(ctx, op) {
+			resources = append(resources, op.view())
+		}
+	}
+	return paginate(resources, limit, cursor)
+}
+
+// ReadResource returns the request Principal's Operation with the ID.
+func (o operationsResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	op, err := o.operation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return op.view(), nil
+}
+
+// DeleteResource cancels the request Principal's Operation with the ID, if it's pending
+// or running, and removes it.
+func (o operationsResourceHandler) DeleteResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	op, err := o.operation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	op.abort()
+	o.operations.mu.Lock()
+	delete(o.operations.operations, id)
+	o.operations.mu.Unlock()
+	return op.view(), nil
+}
+
+// operation returns the Operation with the ID if the request is authorized for it.
+func (o operationsResourceHandler) operation(ctx RequestContext, id string) (*Operation, error) {
+	op, ok := o.operations.get(id)
+	if !ok {
+		return nil, ResourceNotFound(fmt.Sprintf("No operation with id %s", id))
+	}
+	if err := authorizeOperation(ctx, op); err != nil {
+		return nil, err
+	}
+	if !ownsOperation(ctx, op) {
+		return nil, ResourceNotFound(fmt.Sprintf("No operation with id %s", id))
+	}
+	return op, nil
+}
+
+// Authenticate authenticates requests with the Configuration's Authenticate function.
+func (o operationsResourceHandler) Authenticate(r *http.Request) error {
+	if o.authenticate == nil {
+		return nil
+	}
+	return o.authenticate(r)
+}
+
+// ownsOperation returns true if the Operation was started by the request's Principal,
+// or by a request without one if it has none. The request must have been authorized
+// for the Operation, which may set its Principal.
+func ownsOperation(ctx RequestContext, op *Operation) bool {
+	return op.owner == principalID(ctx)
+}
+
+// authorizeOperation returns an error unless the request is authenticated by the
+// ResourceHandler which started the Operation.
+func authorizeOperation(ctx RequestContext, op *Operation) error {
+	req, ok := ctx.Request()
+	if !ok {
+		return UnauthorizedRequest("Unauthorized")
+	}
+	if err := op.handler.Authenticate(req); err != nil {
+		return UnauthorizedRequest(err.Error())
+	}
+	return nil
+}
+
+// registerOperations registers the operations resource and cancels running Operations
+// when the API is shut down.
+func (r *muxAPI) registerOperations(config *OperationConfig) {
+	r.handler.operations = newOperationStore(config)
+	r.handler.mute(operationsResource)
+	r.RegisterResourceHandler(operationsResourceHandler{
+		operations: r.handler.operations, authenticate: r.config.Authenticate})
+	r.OnShutdown(r.handler.operations.stop)
+}
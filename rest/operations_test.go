@@ -0,0 +1,223 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exportsResourceHandler is a ResourceHandler creating exports asynchronously, which
+// wait until released.
+type exportsResourceHandler struct {
+	BaseResourceHandler
+	release chan error
+}
+
+func (e exportsResourceHandler) ResourceName() string {
+	return "exports"
+}
+
+func (e exportsResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	return NewAsyncResult(func(ctx context.Context, op *Operation) (Resource, error) {
+		op.SetProgress(0.5, "Exporting")
+		select {
+		case err := <-e.release:
+			if err != nil {
+				return nil, err
+			}
+			return Payload{"url": "https://example.com/export.csv"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}), nil
+}
+
+func (e exportsResourceHandler) Authenticate(r *http.Request) error {
+	if r.Header.Get("Authorization") == "" {
+		return errors.New("Not authorized")
+	}
+	return nil
+}
+
+// awaitOperation polls the Operation until its status isn't running and returns it.
+func awaitOperation(api API, id string) map[string]interface{} {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		op := readOperation(api, id)
+		if op["status"] != string(OperationRunning) && op["status"] != string(OperationPending) {
+			return op
+		}
+	}
+	return nil
+}
+
+// readOperation returns the Operation with the ID.
+func readOperation(api API, id string) map[string]interface{} {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/operations/"+id, nil)
+	req.Header.Set("Authorization", "token")
+	api.ServeHTTP(w, req)
+	op, _ := decodeEnvelope(w)["result"].(map[string]interface{})
+	return op
+}
+
+// createExport creates an export and returns the response.
+func createExport(api API) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/exports", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "token")
+	api.ServeHTTP(w, req)
+	return w
+}
+
+// Ensures that AsyncResults are responded to with a 202 Accepted locating an Operation
+// which reports the progress and result of the job.
+func TestAsyncOperation(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Operations: &OperationConfig{}})
+	handler := exportsResourceHandler{release: make(chan error)}
+	api.RegisterResourceHandler(handler)
+	defer api.Shutdown(context.Background())
+
+	w := createExport(api)
+	if !assert.Equal(http.StatusAccepted, w.Code) {
+		return
+	}
+	op := decodeEnvelope(w)["result"].(map[string]interface{})
+	id := op["id"].(string)
+	assert.Equal("http://example.com/api/v1/operations/"+id, w.Header().Get("Location"))
+	assert.Equal("exports", op["resource"])
+
+	for start := time.Now(); op["message"] != "Exporting" && time.Since(start) < 5*time.Second; {
+		op = readOperation(api, id)
+	}
+	assert.Equal(string(OperationRunning), op["status"])
+	assert.Equal(0.5, op["progress"])
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/operations/"+id, nil))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	handler.release <- nil
+	op = awaitOperation(api, id)
+	assert.Equal(string(OperationSucceeded), op["status"])
+	assert.Equal(float64(1), op["progress"])
+	assert.Equal(map[string]interface{}{"url": "https://example.com/export.csv"}, op["result"])
+
+	createExport(api)
+	handler.release <- errors.New("Disk full")
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/operations", nil)
+	req.Header.Set("Authorization", "token")
+	api.ServeHTTP(w, req)
+	ops := decodeEnvelope(w)["results"].([]interface{})
+	if assert.Len(ops, 2) {
+		op = awaitOperation(api, ops[1].(map[string]interface{})["id"].(string))
+		assert.Equal(string(OperationFailed), op["status"])
+		assert.Equal("Disk full", op["error"])
+	}
+}
+
+// Ensures that deleting an Operation cancels its job.
+func TestAsyncOperationCancel(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Operations: &OperationConfig{}})
+	api.RegisterResourceHandler(exportsResourceHandler{release: make(chan error)})
+	defer api.Shutdown(context.Background())
+
+	id := decodeEnvelope(createExport(api))["result"].(map[string]interface{})["id"].(string)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/v1/operations/"+id, nil)
+	req.Header.Set("Authorization", "token")
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Nil(readOperation(api, id))
+}
+
+// callerExportsResourceHandler is an exportsResourceHandler authenticating the caller of
+// the X-Caller header as the request's Principal.
+type callerExportsResourceHandler struct {
+	exportsResourceHandler
+}
+
+func (c callerExportsResourceHandler) Authenticate(r *http.Request) error {
+	return authenticateCaller(r)
+}
+
+// Ensures that Operations are only listed, read and canceled by the Principal which
+// started them.
+func TestAsyncOperationOwner(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Operations: &OperationConfig{}, Authenticate: authenticateCaller})
+	handler := callerExportsResourceHandler{exportsResourceHandler{release: make(chan error, 1)}}
+	api.RegisterResourceHandler(handler)
+	defer api.Shutdown(context.Background())
+
+	code, envelope := serveJSONAs(api, "alice", "POST", "/api/v1/exports", `{}`)
+	if !assert.Equal(http.StatusAccepted, code) {
+		return
+	}
+	id := envelope["result"].(map[string]interface{})["id"].(string)
+
+	_, envelope = serveJSONAs(api, "bob", "GET", "/api/v1/operations", "")
+	assert.Len(envelope["results"], 0)
+	code, _ = serveJSONAs(api, "bob", "GET", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "bob", "DELETE", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+
+	_, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/operations", "")
+	assert.Len(envelope["results"], 1)
+	code, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusOK, code)
+	assert.NotEqual(string(OperationFailed), envelope["result"].(map[string]interface{})["status"])
+	code, _ = serveJSONAs(api, "alice", "DELETE", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusOK, code)
+}
+
+// Ensures that AsyncResults fail unless Operations are configured.
+func TestAsyncOperationDisabled(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(exportsResourceHandler{})
+
+	assert.Equal(http.StatusInternalServerError, createExport(api).Code)
+}
+
+// Ensures that finished Operations are removed once the retention elapses.
+func TestOperationRetention(t *testing.T) {
+	assert := assert.New(t)
+	store := newOperationStore(&OperationConfig{Retention: time.Millisecond})
+	op := store.start(exportsResourceHandler{}, "", "", func(context.Context, *Operation) (Resource, error) {
+		return "done", nil
+	}, func(result Resource) Resource { return result })
+	store.stop(context.Background())
+
+	_, ok := store.get(op.ID())
+	time.Sleep(2 * time.Millisecond)
+	_, retained := store.get(op.ID())
+	assert.True(ok)
+	assert.False(retained)
+}
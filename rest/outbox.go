@@ -0,0 +1,288 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultOutboxPollInterval is the default interval at which outboxes are relayed.
+	defaultOutboxPollInterval = time.Second
+
+	// defaultOutboxBatchSize is the default number of Events read from an outbox at a
+	// time.
+	defaultOutboxBatchSize = 100
+)
+
+// Outbox holds the Events of the mutations of OutboxHandlers until they're relayed.
+// ResourceHandlers write an Event to their Outbox in the same database transaction as
+// the mutation, so the Event is recorded if and only if the mutation is committed, and
+// the API relays it in the background. Events are relayed at least once: an Event may
+// be relayed again if the process exits before it's marked as relayed. Outboxes are
+// compared with ==, so handlers sharing one are relayed together, and implementations
+// are typically pointers. Implementations must be safe for concurrent use.
+type Outbox interface {
+	// Pending returns up to limit of the Events not yet relayed, oldest first.
+	Pending(ctx context.Context, limit int) ([]OutboxEntry, error)
+
+	// Relayed marks the entries with the IDs as relayed, e.g. by deleting them.
+	Relayed(ctx context.Context, ids []string) error
+}
+
+// OutboxEntry is an Event written to an Outbox.
+type OutboxEntry struct {
+	// ID identifies the entry in the Outbox.
+	ID string `json:"id"`
+
+	// Event is the Event of the mutation. Its ID and Time are assigned when it's
+	// relayed.
+	Event Event `json:"event"`
+}
+
+// OutboxHandler is an optional interface implemented by ResourceHandlers whose
+// mutations participate in database transactions. The API doesn't publish the Events
+// of their mutations itself, since they may be rolled back or lost if the process
+// exits. Instead, the handlers write them to their Outbox with the mutation, e.g. with
+// NewOutboxEvent, and the API relays them to the Event stream, change feed, publisher
+// and webhooks.
+type OutboxHandler interface {
+	// Outbox returns the Outbox the handler writes the Events of its mutations to.
+	Outbox() Outbox
+}
+
+// OutboxConfig configures the relay of the Outboxes of OutboxHandlers. They're relayed
+// with the defaults if it's not set.
+type OutboxConfig struct {
+	// PollInterval is the interval at which Outboxes are relayed. Defaults to one
+	// second.
+	PollInterval time.Duration
+
+	// BatchSize is the number of Events read from an Outbox at a time. Defaults to 100.
+	BatchSize int
+}
+
+// NewOutboxEvent returns the Event of a mutation of the kind made by the request, to
+// be written to the ResourceHandler's Outbox. The payload is the resource as returned
+// by the handler, to which its outbound Rules are applied when the Event is relayed.
+func NewOutboxEvent(ctx RequestContext, kind EventType, payload Resource) Event {
+	return Event{
+		Type:       kind,
+		Resource:   ctx.ResourceName(),
+		ResourceID: ctx.ResourceID(),
+		Version:    ctx.Version(),
+		Payload:    payload,
+	}
+}
+
+// outboxHandler returns the ResourceHandler as an OutboxHandler, if it is one.
+func outboxHandler(handler ResourceHandler) (OutboxHandler, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	outboxed, ok := handler.(OutboxHandler)
+	return outboxed, ok
+}
+
+// MemoryOutbox is an Outbox keeping Events in memory, for ResourceHandlers without a
+// database and tests.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	seq     uint64
+	entries map[string]OutboxEntry
+}
+
+// NewMemoryOutbox returns an empty MemoryOutbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: map[string]OutboxEntry{}}
+}
+
+// Write adds the Event to the Outbox.
+func (m *MemoryOutbox) Write(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	id := strconv.FormatUint(m.seq, 10)
+	m.entries[id] = OutboxEntry{ID: id, Event: event}
+}
+
+// Pending returns up to limit of the Events not yet relayed, oldest first.
+func (m *MemoryOutbox) Pending(ctx context.Context, limit int) ([]OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := make([]OutboxEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		pending = append(pending, entry)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, _ := strconv.ParseUint(pending[i].ID, 10, 64)
+		b, _ := strconv.ParseUint(pending[j].ID, 10, 64)
+		return a < b
+	})
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// Relayed removes the entries with the IDs.
+func (m *MemoryOutbox) Relayed(ctx context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.entries, id)
+	}
+	return nil
+}
+
+// outboxRelay relays the Events of an Outbox shared by one or more OutboxHandlers.
+type outboxRelay struct {
+	config   OutboxConfig
+	outbox   Outbox
+	handler  *requestHandler
+	mu       sync.RWMutex
+	handlers map[string]ResourceHandler
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// relayOutbox relays the Outbox of the OutboxHandler in the background until the API
+// is shut down, starting a relay for the Outbox unless another handler shares it.
+// Relays are stopped by Shutdown before the OnShutdown functions are run, so the Events
+// they relay last reach the publisher and webhooks before those are drained.
+func (r *muxAPI) relayOutbox(h ResourceHandler, outbox Outbox) {
+	r.mu.Lock()
+	relay, ok := r.outboxes[outbox]
+	if !ok {
+		config := OutboxConfig{}
+		if r.config.Outbox != nil {
+			config = *r.config.Outbox
+		}
+		if config.PollInterval <= 0 {
+			config.PollInterval = defaultOutboxPollInterval
+		}
+		if config.BatchSize <= 0 {
+			config.BatchSize = defaultOutboxBatchSize
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		relay = &outboxRelay{
+			config:   config,
+			outbox:   outbox,
+			handler:  r.handler,
+			handlers: map[string]ResourceHandler{},
+			cancel:   cancel,
+			done:     make(chan struct{}),
+		}
+		r.outboxes[outbox] = relay
+		go relay.run(ctx)
+	}
+	r.mu.Unlock()
+
+	relay.mu.Lock()
+	relay.handlers[h.ResourceName()] = h
+	relay.mu.Unlock()
+}
+
+// stopOutboxes stops the relays of the Outboxes, returning the first error
+// encountered.
+func (r *muxAPI) stopOutboxes(ctx context.Context) error {
+	r.mu.RLock()
+	relays := make([]*outboxRelay, 0, len(r.outboxes))
+	for _, relay := range r.outboxes {
+		relays = append(relays, relay)
+	}
+	r.mu.RUnlock()
+
+	var first error
+	for _, relay := range relays {
+		if err := relay.stop(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// run relays the Outbox at the poll interval until the context is done.
+func (o *outboxRelay) run(ctx context.Context) {
+	defer close(o.done)
+	ticker := time.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.relay(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// relay publishes the pending Events of the Outbox, a batch at a time, marking each
+// batch as relayed once it's published.
+func (o *outboxRelay) relay(ctx context.Context) {
+	for {
+		entries, err := o.outbox.Pending(ctx, o.config.BatchSize)
+		if err != nil {
+			log.Printf("Failed to read outbox: %v", err)
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			o.emit(entry.Event)
+			ids[i] = entry.ID
+		}
+		if err := o.outbox.Relayed(ctx, ids); err != nil {
+			log.Printf("Failed to mark outbox entries as relayed: %v", err)
+			return
+		}
+		if len(entries) < o.config.BatchSize {
+			return
+		}
+	}
+}
+
+// emit publishes the Event, applying the outbound Rules of its ResourceHandler.
+func (o *outboxRelay) emit(event Event) {
+	o.mu.RLock()
+	handler, ok := o.handlers[event.Resource]
+	o.mu.RUnlock()
+	if ok {
+		event.Payload = applyOutboundRules(event.Payload, handler.Rules(), event.Version)
+	}
+	o.handler.emit(event)
+}
+
+// stop stops relaying the Outbox, then relays the Events still pending so those
+// written by in-flight requests are published before Shutdown returns.
+func (o *outboxRelay) stop(ctx context.Context) error {
+	o.cancel()
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	o.relay(ctx)
+	return ctx.Err()
+}
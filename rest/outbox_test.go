@@ -0,0 +1,152 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ledgerResourceHandler is an OutboxHandler writing the Events of the entries it
+// creates to its Outbox, failing, as though rolled back, for entries without a name.
+type ledgerResourceHandler struct {
+	BaseResourceHandler
+	outbox *MemoryOutbox
+}
+
+func (l ledgerResourceHandler) ResourceName() string {
+	return "ledger"
+}
+
+func (l ledgerResourceHandler) Outbox() Outbox {
+	return l.outbox
+}
+
+func (l ledgerResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	if data["name"] == nil {
+		return nil, errors.New("rolled back")
+	}
+	l.outbox.Write(NewOutboxEvent(ctx, EventCreate, data))
+	return data, nil
+}
+
+// Ensures that the Events of OutboxHandlers aren't published with their responses but
+// relayed from their Outbox, so those of failed mutations are never published, and
+// that the pending Events are relayed on Shutdown before the publisher is drained.
+func TestOutboxRelay(t *testing.T) {
+	assert := assert.New(t)
+	var mu sync.Mutex
+	var published []Event
+	publisher := EventPublisherFunc(func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, event)
+		return nil
+	})
+	outbox := NewMemoryOutbox()
+	api := NewAPI(&Configuration{
+		Events: &EventConfig{Publisher: publisher},
+		Outbox: &OutboxConfig{PollInterval: time.Hour},
+	})
+	api.RegisterResourceHandler(ledgerResourceHandler{outbox: outbox})
+	events := api.(*muxAPI).handler.events
+
+	code, _ := serveJSON(api, "POST", "/api/v1/ledger", `{"name": "deposit"}`)
+	assert.Equal(201, code)
+	code, _ = serveJSON(api, "POST", "/api/v1/ledger", `{}`)
+	assert.Equal(500, code)
+	assert.Equal(uint64(0), events.latest())
+	pending, _ := outbox.Pending(context.Background(), 0)
+	assert.Len(pending, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(published, 1) {
+		assert.Equal(EventCreate, published[0].Type)
+		assert.Equal("ledger", published[0].Resource)
+		assert.Equal("1", published[0].Version)
+		assert.Equal(Payload{"name": "deposit"}, published[0].Payload)
+	}
+	pending, _ = outbox.Pending(context.Background(), 0)
+	assert.Empty(pending)
+}
+
+// Ensures that Outboxes are relayed in the background at the poll interval, in
+// batches, and that handlers sharing an Outbox are relayed together.
+func TestOutboxRelayPolling(t *testing.T) {
+	assert := assert.New(t)
+	outbox := NewMemoryOutbox()
+	api := NewAPI(&Configuration{
+		ChangeFeed: &ChangeFeedConfig{},
+		Outbox:     &OutboxConfig{PollInterval: 10 * time.Millisecond, BatchSize: 2},
+	})
+	api.RegisterResourceHandler(ledgerResourceHandler{outbox: outbox})
+	assert.Len(api.(*muxAPI).outboxes, 1)
+
+	for i := 0; i < 5; i++ {
+		outbox.Write(Event{Type: EventCreate, Resource: "ledger", Payload: Payload{"n": i}})
+	}
+	changes := api.(*muxAPI).handler.changes
+	deadline := time.Now().Add(5 * time.Second)
+	var recorded []Change
+	for time.Now().Before(deadline) {
+		recorded, _ = changes.Changes("ledger", 0, 0)
+		if len(recorded) == 5 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if assert.Len(recorded, 5) {
+		for i, change := range recorded {
+			assert.Equal(Payload{"n": i}, change.Payload)
+		}
+	}
+	assert.Nil(api.Shutdown(context.Background()))
+}
+
+// Ensures that the MemoryOutbox returns pending entries oldest first, up to the limit,
+// and removes relayed entries.
+func TestMemoryOutbox(t *testing.T) {
+	assert := assert.New(t)
+	outbox := NewMemoryOutbox()
+	for _, id := range []string{"a", "b", "c"} {
+		outbox.Write(Event{ResourceID: id})
+	}
+
+	pending, err := outbox.Pending(context.Background(), 2)
+	assert.Nil(err)
+	if assert.Len(pending, 2) {
+		assert.Equal("a", pending[0].Event.ResourceID)
+		assert.Equal("b", pending[1].Event.ResourceID)
+	}
+
+	assert.Nil(outbox.Relayed(context.Background(), []string{pending[0].ID}))
+	pending, _ = outbox.Pending(context.Background(), 0)
+	if assert.Len(pending, 2) {
+		assert.Equal("b", pending[0].Event.ResourceID)
+		assert.Equal("c", pending[1].Event.ResourceID)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+// ResourceCounter is an optional interface implemented by ResourceHandlers which count
+// their resources, so offset-paginated list responses include the total and number of
+// pages when ReadResourceList doesn't set the total itself.
+type ResourceCounter interface {
+	// CountResources returns the number of resources matching the request's
+	// ListFilter, if any.
+	CountResources(ctx RequestContext, version string) (int, error)
+}
+
+// resourceCounter returns the ResourceHandler as a ResourceCounter, if it is one.
+func resourceCounter(handler ResourceHandler) (ResourceCounter, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	counter, ok := handler.(ResourceCounter)
+	return counter, ok
+}
+
+// offsetPaginated indicates if the list request is paginated with the offset query
+// string variable rather than a cursor: it has an offset and ReadResourceList didn't
+// return a cursor for the next page.
+func offsetPaginated(ctx RequestContext) bool {
+	_, ok := ctx.Value(offsetKey).(string)
+	return ok && ctx.NextCursor() == ""
+}
+
+// pageMeta returns the meta block of an offset-paginated list response, with the limit,
+// offset and page number and, if the total is known, the total and number of pages.
+// The total is counted with the ResourceHandler if it's a ResourceCounter and
+// ReadResourceList didn't set it.
+func pageMeta(ctx RequestContext, handler ResourceHandler) (Payload, error) {
+	limit, offset := ctx.Limit(), ctx.Offset()
+	if limit <= 0 {
+		return nil, BadRequest("Invalid limit: must be positive")
+	}
+	meta := Payload{limitKey: limit, offsetKey: offset, "page": offset/limit + 1}
+
+	t, ok := ctx.Total()
+	if counter, isCounter := resourceCounter(handler); !ok && isCounter {
+		count, err := counter.CountResources(ctx, ctx.Version())
+		if err != nil {
+			return nil, err
+		}
+		ctx.SetTotal(count)
+		t, ok = count, true
+	}
+	if ok {
+		meta[total] = t
+		meta["pages"] = (t + limit - 1) / limit
+	}
+	return meta, nil
+}
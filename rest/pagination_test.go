@@ -0,0 +1,81 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countedResourceHandler is a ResourceCounter paginating 45 numbered resources with
+// offsets.
+type countedResourceHandler struct {
+	BaseResourceHandler
+	err error
+}
+
+func (c countedResourceHandler) ResourceName() string {
+	return "counted"
+}
+
+func (c countedResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	results := []Resource{}
+	for i := ctx.Offset(); i < ctx.Offset()+limit && i < 45; i++ {
+		results = append(results, Payload{"n": i})
+	}
+	return results, "", nil
+}
+
+func (c countedResourceHandler) CountResources(ctx RequestContext, version string) (int, error) {
+	return 45, c.err
+}
+
+// Ensures that offset-paginated list responses include a meta block with the limit,
+// offset, page and, given by SetTotal or a ResourceCounter, the total and page count.
+func TestOffsetPagination(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(countedResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/counted?limit=10&offset=20", "")
+	assert.Equal(200, code)
+	assert.Equal(map[string]interface{}{
+		"limit": 10.0, "offset": 20.0, "page": 3.0, "total": 45.0, "pages": 5.0,
+	}, envelope["meta"])
+	assert.Equal(45.0, envelope["total"])
+	assert.Len(envelope["results"], 10)
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/counted?limit=10", "")
+	assert.Equal(200, code)
+	assert.NotContains(envelope, "meta")
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/counted?limit=0&offset=0", "")
+	assert.Equal(400, code)
+
+	api = NewAPI(&Configuration{})
+	api.RegisterResourceHandler(countedResourceHandler{err: errors.New("no count")})
+	code, _ = serveJSON(api, "GET", "/api/v1/counted?offset=0", "")
+	assert.Equal(500, code)
+
+	api = NewAPI(&Configuration{})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	_, envelope = serveJSON(api, "GET", "/api/v1/widgets?offset=5", "")
+	assert.Equal(map[string]interface{}{"limit": 100.0, "offset": 5.0, "page": 1.0}, envelope["meta"])
+}
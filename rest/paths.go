@@ -0,0 +1,123 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TrailingSlash configures how requests whose paths only differ from a route's by a
+// trailing slash are handled.
+type TrailingSlash int
+
+const (
+	// TrailingSlashStrict doesn't match requests to routes whose paths differ by a
+	// trailing slash.
+	TrailingSlashStrict TrailingSlash = iota
+
+	// TrailingSlashRedirect redirects requests to the path of the route, with a 301
+	// Moved Permanently response to GET and HEAD requests and a 308 Permanent Redirect
+	// response otherwise, so the method and body are preserved.
+	TrailingSlashRedirect
+
+	// TrailingSlashMatch serves requests by the route as if they had its path.
+	TrailingSlashMatch
+)
+
+// lenientHandler returns the Handler of a request which doesn't match any route but
+// does once its trailing slash is toggled or the case of its path's literal segments
+// is changed, as configured, along with the request to pass it. It returns nil if
+// there is no such route. The caller must hold the read lock.
+func (r *muxAPI) lenientHandler(req *http.Request) (http.Handler, *http.Request) {
+	slash, fold := r.config.TrailingSlash != TrailingSlashStrict, r.config.CaseInsensitivePaths
+	if (!slash && !fold) || r.matchers.match(req) {
+		return nil, nil
+	}
+
+	paths := []string{}
+	if fold {
+		paths = append(paths, req.URL.Path)
+	}
+	if slash && req.URL.Path != "/" {
+		if strings.HasSuffix(req.URL.Path, "/") {
+			paths = append(paths, strings.TrimSuffix(req.URL.Path, "/"))
+		} else {
+			paths = append(paths, req.URL.Path+"/")
+		}
+	}
+
+	for _, path := range paths {
+		for _, route := range r.matchers.routes {
+			candidate := path
+			if fold {
+				candidate = route.template.canonicalCase(path)
+			}
+			if candidate == req.URL.Path {
+				continue
+			}
+			lenient := withPath(req, candidate)
+			if _, ok := route.Match(lenient); !ok {
+				continue
+			}
+			if r.config.TrailingSlash == TrailingSlashRedirect &&
+				strings.HasSuffix(candidate, "/") != strings.HasSuffix(req.URL.Path, "/") {
+				return redirectHandler(lenient.URL), req
+			}
+			return r.routed, lenient
+		}
+	}
+	return nil, nil
+}
+
+// withPath returns a shallow copy of the request with the path.
+func withPath(req *http.Request, path string) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	u := *req.URL
+	u.Path, u.RawPath = path, ""
+	clone.URL = &u
+	return clone
+}
+
+// redirectHandler returns a Handler permanently redirecting requests to the URL,
+// preserving the methods and bodies of requests other than GET and HEAD.
+func redirectHandler(u *url.URL) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		code := http.StatusPermanentRedirect
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, req, u.RequestURI(), code)
+	})
+}
+
+// canonicalCase returns the path with its segments matching the literal segments of
+// the template case-insensitively spelled as in the template.
+func (t *routeTemplate) canonicalCase(path string) string {
+	parts := strings.Split(path, "/")
+	for i, segment := range t.segments {
+		if i+1 >= len(parts) || segment.slash {
+			break
+		}
+		if !segment.variable && strings.EqualFold(parts[i+1], segment.literal) {
+			parts[i+1] = segment.literal
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveLenient returns the response of an API with the Configuration to the request.
+func serveLenient(config *Configuration, method, path string) *httptest.ResponseRecorder {
+	api := NewAPI(config)
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	api.RegisterHandlerFunc("/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+// Ensures that paths differing from a route's by a trailing slash aren't matched by
+// default.
+func TestTrailingSlashStrict(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(http.StatusNotFound, serveLenient(&Configuration{}, "GET", "/api/v1/rows/").Code)
+	assert.Equal(http.StatusNotFound, serveLenient(&Configuration{}, "GET", "/tags").Code)
+}
+
+// Ensures that requests with paths differing from a route's by a trailing slash are
+// redirected to it, preserving the query and, with a 308, the method.
+func TestTrailingSlashRedirect(t *testing.T) {
+	assert := assert.New(t)
+	config := &Configuration{TrailingSlash: TrailingSlashRedirect}
+
+	w := serveLenient(config, "GET", "/api/v1/rows/?limit=5")
+	assert.Equal(http.StatusMovedPermanently, w.Code)
+	assert.Equal("/api/v1/rows?limit=5", w.Header().Get("Location"))
+
+	w = serveLenient(config, "POST", "/tags")
+	assert.Equal(http.StatusPermanentRedirect, w.Code)
+	assert.Equal("/tags/", w.Header().Get("Location"))
+
+	assert.Equal(http.StatusNotFound, serveLenient(config, "GET", "/missing/").Code)
+}
+
+// Ensures that requests with paths differing from a route's by a trailing slash are
+// served by it when TrailingSlashMatch is configured.
+func TestTrailingSlashMatch(t *testing.T) {
+	assert := assert.New(t)
+	config := &Configuration{TrailingSlash: TrailingSlashMatch}
+
+	w := serveLenient(config, "GET", "/api/v1/rows/")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `"results"`)
+	assert.Equal("/tags/", serveLenient(config, "GET", "/tags").Body.String())
+}
+
+// Ensures that the literal segments of paths are matched case-insensitively when
+// configured, leaving path variables unchanged.
+func TestCaseInsensitivePaths(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(http.StatusNotFound, serveLenient(&Configuration{}, "GET", "/API/v1/ROWS").Code)
+
+	config := &Configuration{CaseInsensitivePaths: true}
+	assert.Equal(http.StatusOK, serveLenient(config, "GET", "/API/v1/ROWS").Code)
+	assert.Equal("/tags/", serveLenient(config, "GET", "/Tags/").Body.String())
+
+	template, _ := parseRouteTemplate("/api/v{version:[^/]+}/widgets/{id}", false)
+	assert.Equal("/api/V1/widgets/AbC", template.canonicalCase("/API/V1/Widgets/AbC"))
+
+	config.TrailingSlash = TrailingSlashMatch
+	assert.Equal("/tags/", serveLenient(config, "GET", "/TAGS").Body.String())
+}
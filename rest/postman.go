@@ -0,0 +1,213 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// postmanSchema is the schema of the Postman collections generated.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanRequestNames are the names of the requests for each standard endpoint.
+var postmanRequestNames = map[HandleMethod]string{
+	HandleCreate:     "Create",
+	HandleReadList:   "Read list",
+	HandleRead:       "Read",
+	HandleUpdateList: "Update list",
+	HandleUpdate:     "Update",
+	HandleDelete:     "Delete",
+}
+
+// PostmanCollection returns a Postman collection (v2.1) as JSON, containing a
+// request for every standard endpoint of each registered ResourceHandler version,
+// grouped in a folder per resource and version. Request bodies and example
+// responses are built from the Rules. Requests are made against the baseUrl
+// collection variable, and the version variable for unversioned resources. The same
+// collection is served at AdminPath/postman.json when AdminPath is configured.
+func PostmanCollection(api API) ([]byte, error) {
+	config := api.Configuration()
+	name := config.Info.Title
+	if name == "" {
+		name = "API"
+	}
+
+	folders := []interface{}{}
+	unversioned := false
+	for _, handler := range api.ResourceHandlers() {
+		for _, version := range apiVersions(handler) {
+			folder := handler.ResourceName()
+			if version != "" {
+				folder += " v" + version
+			} else {
+				unversioned = true
+			}
+
+			items := []interface{}{}
+			for _, op := range openAPIOperations(handler) {
+				items = append(items, newPostmanItem(handler, op, version))
+			}
+			folders = append(folders, map[string]interface{}{"name": folder, "item": items})
+		}
+	}
+
+	variables := []interface{}{
+		map[string]interface{}{"key": "baseUrl", "value": "http://localhost:8080"},
+	}
+	if unversioned {
+		variables = append(variables, map[string]interface{}{"key": versionKey, "value": "1"})
+	}
+
+	info := map[string]interface{}{"name": name, "schema": postmanSchema}
+	if config.Info.Description != "" {
+		info["description"] = config.Info.Description
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"info":     info,
+		"variable": variables,
+		"item":     folders,
+	}, "", "  ")
+}
+
+// newPostmanItem returns the Postman collection item for the endpoint.
+func newPostmanItem(handler ResourceHandler, op openAPIOperation, version string) map[string]interface{} {
+	path := openAPIPath(op.uri, version)
+	pathVariables := []interface{}{}
+	path = uriParamRegex.ReplaceAllStringFunc(path, func(param string) string {
+		name := uriParamRegex.FindStringSubmatch(param)[1]
+		if name == versionKey {
+			return "{{" + versionKey + "}}"
+		}
+		pathVariables = append(pathVariables, map[string]interface{}{"key": name, "value": ""})
+		return ":" + name
+	})
+
+	u := map[string]interface{}{
+		"raw":  "{{baseUrl}}" + path,
+		"host": []string{"{{baseUrl}}"},
+		"path": strings.Split(strings.TrimPrefix(path, "/"), "/"),
+	}
+	if len(pathVariables) > 0 {
+		u["variable"] = pathVariables
+	}
+	if op.list && !op.input {
+		u["query"] = []interface{}{
+			map[string]interface{}{"key": limitKey, "value": "", "disabled": true},
+			map[string]interface{}{"key": cursorKey, "value": "", "disabled": true},
+		}
+	}
+
+	request := map[string]interface{}{
+		"method": strings.ToUpper(op.method),
+		"header": []interface{}{
+			map[string]interface{}{"key": "Accept", "value": "application/json"},
+		},
+		"url": u,
+	}
+	metadata := routeMetadata(handler, op.name)
+	if metadata.Description != "" {
+		request["description"] = metadata.Description
+	} else if op.description != "" {
+		request["description"] = op.description
+	}
+	if op.input {
+		body := buildExampleRequest(handler.Rules(), op.list, version)
+		if metadata.ExampleRequest != nil {
+			if example, err := json.MarshalIndent(metadata.ExampleRequest, "", "    "); err == nil {
+				body = string(example)
+			}
+		}
+		if body == "" {
+			body = "{}"
+			if op.list {
+				body = "[{}]"
+			}
+		}
+		request["header"] = append(request["header"].([]interface{}),
+			map[string]interface{}{"key": "Content-Type", "value": "application/json"})
+		request["body"] = map[string]interface{}{
+			"mode":    "raw",
+			"raw":     body,
+			"options": map[string]interface{}{"raw": map[string]interface{}{"language": "json"}},
+		}
+	}
+
+	item := map[string]interface{}{
+		"name":    postmanRequestNames[op.name] + " " + handler.ResourceName(),
+		"request": request,
+	}
+	if example := examplePostmanResponse(handler, op, version); example != nil {
+		item["response"] = []interface{}{example}
+	}
+	return item
+}
+
+// examplePostmanResponse returns an example response for the endpoint, or nil if the
+// Rules don't describe its result.
+func examplePostmanResponse(handler ResourceHandler, op openAPIOperation, version string) map[string]interface{} {
+	example := buildExampleResponse(handler.Rules(), op.list, version)
+	if metadata := routeMetadata(handler, op.name); metadata.ExampleResponse != nil {
+		if body, err := json.Marshal(metadata.ExampleResponse); err == nil {
+			example = string(body)
+		}
+	}
+	if example == "" {
+		return nil
+	}
+
+	resultKey := result
+	if op.list {
+		resultKey = results
+	}
+	body, err := json.MarshalIndent(map[string]interface{}{
+		status:    op.status,
+		reason:    http.StatusText(op.status),
+		messages:  []string{},
+		resultKey: json.RawMessage(example),
+	}, "", "    ")
+	if err != nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"name":                     "Example response",
+		"status":                   http.StatusText(op.status),
+		"code":                     op.status,
+		"_postman_previewlanguage": "json",
+		"header": []interface{}{
+			map[string]interface{}{"key": "Content-Type", "value": "application/json"},
+		},
+		"body": string(body),
+	}
+}
+
+// postmanHandler returns a Handler serving the Postman collection of the API as a
+// download.
+func postmanHandler(api API) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		collection, err := PostmanCollection(api)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="postman_collection.json"`)
+		w.Write(collection)
+	})
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that PostmanCollection contains a folder of requests per resource version
+// with example bodies and responses built from the Rules.
+func TestPostmanCollection(t *testing.T) {
+	assert := assert.New(t)
+	api := setupAPI()
+	api.Configuration().Info = APIInfo{Title: "Widgets"}
+
+	data, err := PostmanCollection(api)
+	assert.Nil(err)
+	var collection map[string]interface{}
+	assert.Nil(json.Unmarshal(data, &collection))
+
+	info := collection["info"].(map[string]interface{})
+	assert.Equal("Widgets", info["name"])
+	assert.Equal(postmanSchema, info["schema"])
+	assert.Len(collection["variable"], 1)
+
+	folders := collection["item"].([]interface{})
+	names := []interface{}{}
+	for _, folder := range folders {
+		names = append(names, folder.(map[string]interface{})["name"])
+	}
+	assert.Equal([]interface{}{"foo v1", "bar v1", "bar v2"}, names)
+
+	items := folders[0].(map[string]interface{})["item"].([]interface{})
+	assert.Len(items, 6)
+
+	create := items[0].(map[string]interface{})
+	assert.Equal("Create foo", create["name"])
+	request := create["request"].(map[string]interface{})
+	assert.Equal("POST", request["method"])
+	assert.Equal("Creates a new foo", request["description"])
+	assert.Equal("{{baseUrl}}/api/v1/foo", request["url"].(map[string]interface{})["raw"])
+	body := request["body"].(map[string]interface{})["raw"].(string)
+	var example map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(body), &example))
+	assert.Equal("foo", example["foo"])
+	assert.Len(create["response"], 1)
+
+	read := items[2].(map[string]interface{})["request"].(map[string]interface{})
+	u := read["url"].(map[string]interface{})
+	assert.Equal("{{baseUrl}}/api/v1/foo/:resource_id", u["raw"])
+	assert.Equal([]interface{}{"api", "v1", "foo", ":resource_id"}, u["path"])
+	assert.Equal([]interface{}{map[string]interface{}{"key": "resource_id", "value": ""}}, u["variable"])
+}
+
+// Ensures that the Postman collection of unversioned resources uses the version
+// variable and is downloadable from the admin endpoint.
+func TestPostmanCollectionEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{AdminPath: "/admin", Authenticate: authenticateAll})
+	api.RegisterResourceHandler(TestResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://example.com/admin/postman.json", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Header().Get("Content-Disposition"), "attachment")
+
+	var collection map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Len(collection["variable"], 2)
+
+	folder := collection["item"].([]interface{})[0].(map[string]interface{})
+	assert.Equal("widgets", folder["name"])
+	create := folder["item"].([]interface{})[0].(map[string]interface{})
+	request := create["request"].(map[string]interface{})
+	assert.Equal("{{baseUrl}}/api/v{{version}}/widgets", request["url"].(map[string]interface{})["raw"])
+	assert.Equal("{}", request["body"].(map[string]interface{})["raw"])
+	assert.NotContains(create, "response")
+}
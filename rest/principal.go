@@ -0,0 +1,85 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+
+	gcontext "github.com/gorilla/context"
+)
+
+// Principal is the authenticated identity making a request. It's typically set by an
+// authenticator and consumed by authorizers and ResourceHandlers.
+type Principal struct {
+	// ID uniquely identifies the authenticated caller, e.g. a user or service ID.
+	ID string
+
+	// Roles are the roles granted to the caller.
+	Roles []string
+
+	// Scopes are the scopes granted to the caller, e.g. by an OAuth2 token.
+	Scopes []string
+
+	// AuthMethod describes how the caller was authenticated, e.g. "basic" or "bearer".
+	AuthMethod string
+
+	// Attributes contains any additional identity information, such as token claims.
+	Attributes map[string]interface{}
+}
+
+// HasRole returns true if the Principal has been granted the given role.
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope returns true if the Principal has been granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// SetRequestPrincipal associates the Principal with the request. This allows an
+// Authenticate implementation, which only has access to the *http.Request, to share
+// the authenticated identity with the RequestContext passed to ResourceHandlers.
+func SetRequestPrincipal(r *http.Request, p *Principal) {
+	gcontext.Set(r, principalKey, p)
+}
+
+// RequestPrincipal returns the Principal associated with the request and true, or
+// nil and false if there isn't one.
+func RequestPrincipal(r *http.Request) (*Principal, bool) {
+	p, ok := gcontext.Get(r, principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// principalID returns the ID of the Principal of the request, or an empty string if
+// there isn't one.
+func principalID(ctx RequestContext) string {
+	if p, ok := ctx.Principal(); ok {
+		return p.ID
+	}
+	return ""
+}
+
+// contains returns true if the slice contains the given string.
+func contains(s []string, str string) bool {
+	for _, item := range s {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
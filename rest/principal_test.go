@@ -0,0 +1,74 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that HasRole and HasScope check the granted roles and scopes.
+func TestPrincipalHasRoleAndScope(t *testing.T) {
+	assert := assert.New(t)
+	p := &Principal{ID: "alice", Roles: []string{"admin"}, Scopes: []string{"read"}}
+
+	assert.True(p.HasRole("admin"))
+	assert.False(p.HasRole("owner"))
+	assert.True(p.HasScope("read"))
+	assert.False(p.HasScope("write"))
+}
+
+// Ensures that a Principal set on the request by an authenticator is available on
+// the RequestContext.
+func TestRequestPrincipal(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+
+	_, ok := RequestPrincipal(req)
+	assert.False(ok)
+
+	p := &Principal{ID: "alice", AuthMethod: "bearer"}
+	SetRequestPrincipal(req, p)
+	ctx := NewContext(nil, req, writer)
+
+	actual, ok := ctx.Principal()
+	assert.True(ok)
+	assert.Equal(p, actual)
+}
+
+// Ensures that SetPrincipal is visible to contexts derived from the RequestContext.
+func TestSetPrincipal(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	writer := httptest.NewRecorder()
+	ctx := NewContext(nil, req, writer)
+	derived := ctx.WithValue("foo", "bar")
+
+	_, ok := derived.Principal()
+	assert.False(ok)
+
+	p := &Principal{ID: "bob"}
+	ctx.SetPrincipal(p)
+
+	actual, ok := derived.Principal()
+	assert.True(ok)
+	assert.Equal(p, actual)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	// problemContentType is the MIME type of RFC 7807 Problem Details responses.
+	problemContentType = "application/problem+json"
+
+	// problemXMLContentType is the MIME type of RFC 7807 Problem Details responses
+	// serialized as XML.
+	problemXMLContentType = "application/problem+xml"
+
+	// problemTypeBlank is the default problem type, indicating the problem has no
+	// semantics beyond the HTTP status code.
+	problemTypeBlank = "about:blank"
+)
+
+// problemMembers are the members of Problem Details objects defined by RFC 7807, which
+// Extensions can't override.
+var problemMembers = map[string]bool{
+	"type": true, "title": true, "status": true, "detail": true, "instance": true,
+}
+
+// Problem is an error describing an RFC 7807 Problem Details object. ResourceHandlers
+// can return a Problem to control the type, title and extension members of the
+// response when the API is configured to emit Problem Details. Otherwise, it's
+// rendered like any other StatusError.
+type Problem struct {
+	// Type is a URI reference identifying the problem type. Defaults to
+	// "about:blank".
+	Type string
+
+	// Title is a short, human-readable summary of the problem type. Defaults to the
+	// status text.
+	Title string
+
+	// Status is the HTTP status code. Defaults to 500.
+	Status int
+
+	// Detail is a human-readable explanation specific to this occurrence.
+	Detail string
+
+	// Instance is a URI reference identifying this occurrence. Defaults to the
+	// request path.
+	Instance string
+
+	// Extensions are additional members included in the Problem Details object.
+	// Those named like the members above are ignored.
+	Extensions map[string]interface{}
+}
+
+// Error returns the Problem detail, falling back to the title.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// StatusCode returns the HTTP status code. It implements the StatusError interface.
+func (p *Problem) StatusCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+// problemPayload returns the Problem Details object for the error set on the
+// RequestContext.
+func problemPayload(ctx RequestContext) Payload {
+	err := ctx.Error()
+	s := errorStatus(err)
+	payload := Payload{
+		"type":   problemTypeBlank,
+		"title":  http.StatusText(s),
+		"status": s,
+		"detail": err.Error(),
+	}
+	if r, ok := ctx.Request(); ok {
+		payload["instance"] = r.URL.Path
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && validationErr.HasErrors() {
+		payload[errs] = fieldErrorsPayload(validationErr.FieldErrors())
+	}
+
+	if c := errorCode(err); c != "" {
+		payload[code] = c
+	}
+
+	var panicErr panicError
+	if errors.As(err, &panicErr) {
+		payload[requestID] = ctx.RequestID()
+		payload[incident] = panicErr.incident
+	}
+
+	var problem *Problem
+	if errors.As(err, &problem) {
+		for key, value := range problem.Extensions {
+			if !problemMembers[key] {
+				payload[key] = value
+			}
+		}
+		if problem.Type != "" {
+			payload["type"] = problem.Type
+		}
+		if problem.Title != "" {
+			payload["title"] = problem.Title
+		}
+		if problem.Instance != "" {
+			payload["instance"] = problem.Instance
+		}
+	}
+
+	return payload
+}
+
+// problemContentTypes maps serializer MIME types to their Problem Details equivalents.
+var problemContentTypes = map[string]string{
+	"application/json": problemContentType,
+	"application/xml":  problemXMLContentType,
+	"text/xml":         problemXMLContentType,
+}
+
+// newProblemResponse constructs a new response struct containing an RFC 7807
+// Problem Details object for the error set on the RequestContext.
+func newProblemResponse(ctx RequestContext) response {
+	err := ctx.Error()
+	return response{Payload: problemPayload(ctx), Status: errorStatus(err), Error: err}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that a Problem implements StatusError with sensible defaults.
+func TestProblem(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Problem{Title: "Out of credit"}
+	assert.Equal("Out of credit", p.Error())
+	assert.Equal(http.StatusInternalServerError, p.StatusCode())
+
+	p = &Problem{Title: "Out of credit", Detail: "Balance is 30", Status: http.StatusForbidden}
+	assert.Equal("Balance is 30", p.Error())
+	assert.Equal(http.StatusForbidden, p.StatusCode())
+}
+
+type problemResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (p problemResourceHandler) ResourceName() string {
+	return "accounts"
+}
+
+func (p problemResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	if id == "1" {
+		return nil, NotFound("No account 1")
+	}
+	if id == "3" {
+		return nil, &Problem{
+			Status: http.StatusConflict,
+			Extensions: map[string]interface{}{
+				"status": 409.0, "title": "Overridden", "detail": "Overridden", "balance": 30,
+			},
+		}
+	}
+	return nil, &Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Extensions: map[string]interface{}{"balance": 30},
+	}
+}
+
+// Ensures that errors are rendered as Problem Details when configured.
+func TestHandleReadProblemDetails(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ProblemDetails: true})
+	api.RegisterResourceHandler(problemResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/accounts/1", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code)
+	assert.Equal("application/problem+json", resp.Header().Get("Content-Type"))
+	assert.Equal(
+		`{"detail":"No account 1","instance":"/api/v1/accounts/1","status":404,"title":"Not Found","type":"about:blank"}`,
+		resp.Body.String(),
+	)
+
+	req, _ = http.NewRequest("GET", "http://foo.com/api/v1/accounts/2", nil)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusForbidden, resp.Code)
+	assert.Equal(
+		`{"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/api/v1/accounts/2",`+
+			`"status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`,
+		resp.Body.String(),
+	)
+}
+
+// Ensures that Extensions don't override the members of Problem Details, so the
+// status in the body matches the response's.
+func TestHandleReadProblemDetailsReservedExtensions(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ProblemDetails: true})
+	api.RegisterResourceHandler(problemResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/accounts/3", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusConflict, resp.Code)
+	assert.Equal(
+		`{"balance":30,"detail":"","instance":"/api/v1/accounts/3","status":409,"title":"Conflict","type":"about:blank"}`,
+		resp.Body.String(),
+	)
+}
+
+// Ensures that the standard envelope is used unless Problem Details are configured.
+func TestHandleReadProblemDetailsDisabled(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(problemResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/accounts/2", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusForbidden, resp.Code)
+	assert.Equal("application/json", resp.Header().Get("Content-Type"))
+	assert.Equal(
+		`{"messages":["Your current balance is 30, but that costs 50."],"reason":"Forbidden","status":403}`,
+		resp.Body.String(),
+	)
+}
+
+// Ensures that Problem Details use the XML media type for XML serializers.
+func TestHandleReadProblemDetailsXML(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{ProblemDetails: true})
+	api.RegisterResponseSerializer("xml", xmlContentTypeSerializer{})
+	api.RegisterResourceHandler(problemResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/accounts/1?format=xml", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusNotFound, resp.Code)
+	assert.Equal("application/problem+xml", resp.Header().Get("Content-Type"))
+}
+
+type xmlContentTypeSerializer struct {
+	jsonSerializer
+}
+
+func (x xmlContentTypeSerializer) ContentType() string {
+	return "application/xml"
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"log"
+)
+
+// EventPublisher publishes the Events of successful mutations to another system, such
+// as a message broker, so other services can react to changes of resources. See the
+// publishers package for NATS and Kafka adapters.
+type EventPublisher interface {
+	// Publish publishes the Event. It's called with one Event at a time, in the order
+	// they were published. Errors are logged and the Event isn't retried.
+	Publish(ctx context.Context, event Event) error
+}
+
+// EventPublisherFunc is an EventPublisher calling the function.
+type EventPublisherFunc func(ctx context.Context, event Event) error
+
+// Publish calls the function with the Event.
+func (f EventPublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// startPublisher publishes the Events with the EventPublisher in the background until
+// the API is shut down, at which point the Events queued are published before Shutdown
+// returns, unless its context is done first. Publishing doesn't delay the responses
+// to mutations, but an EventPublisher falling far enough behind misses the Events no
+// longer recorded by the API.
+func (r *muxAPI) startPublisher(publisher EventPublisher) {
+	events := r.handler.events
+	sub := events.subscribe(allEvents)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		events.consume(ctx, sub, allEvents, func(event Event) {
+			if err := publisher.Publish(context.Background(), event); err != nil {
+				log.Printf("Failed to publish event %d: %v", event.ID, err)
+			}
+		})
+	}()
+
+	r.OnShutdown(func(ctx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
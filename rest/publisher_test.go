@@ -0,0 +1,56 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that the EventPublisher is called with the Events of mutations in order, and
+// that the queued Events are published before Shutdown returns.
+func TestEventPublisher(t *testing.T) {
+	assert := assert.New(t)
+	var mu sync.Mutex
+	var published []string
+	release := make(chan struct{})
+	publisher := EventPublisherFunc(func(ctx context.Context, event Event) error {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, string(event.Type)+" "+event.ResourceID)
+		return nil
+	})
+	api := NewAPI(&Configuration{Events: &EventConfig{Publisher: publisher}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+
+	serveJSON(api, "POST", "/api/v1/widgets", `{"name": "sprocket"}`)
+	serveJSON(api, "PUT", "/api/v1/widgets/42", `{"name": "cog"}`)
+	serveJSON(api, "DELETE", "/api/v1/widgets/42", "")
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal([]string{"create ", "update 42", "delete 42"}, published)
+}
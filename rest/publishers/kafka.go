@@ -0,0 +1,61 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publishers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// KafkaProducer writes messages to Kafka topics. Clients such as sarama's
+// SyncProducer or kafka-go's Writer are adapted with a few lines, e.g.
+//
+//	type saramaProducer struct{ sarama.SyncProducer }
+//
+//	func (p saramaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+//		_, _, err := p.SendMessage(&sarama.ProducerMessage{
+//			Topic: topic, Key: sarama.ByteEncoder(key), Value: sarama.ByteEncoder(value)})
+//		return err
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// kafkaPublisher publishes Events to Kafka.
+type kafkaPublisher struct {
+	producer KafkaProducer
+	topic    string
+}
+
+// NewKafkaPublisher returns a rest.EventPublisher writing each Event as JSON to the
+// topic. Messages are keyed by {resource}/{resource ID}, so the Events of a resource
+// are written to the same partition and consumed in order.
+func NewKafkaPublisher(producer KafkaProducer, topic string) rest.EventPublisher {
+	return &kafkaPublisher{producer: producer, topic: topic}
+}
+
+// Publish writes the Event to the topic.
+func (k *kafkaPublisher) Publish(ctx context.Context, event rest.Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key := event.Resource + "/" + event.ResourceID
+	return k.producer.Produce(ctx, k.topic, []byte(key), value)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publishers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Workiva/go-rest/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+// kafkaProducer records the messages produced.
+type kafkaProducer struct {
+	topic string
+	key   string
+	value []byte
+	err   error
+}
+
+func (k *kafkaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+	k.topic, k.key, k.value = topic, string(key), value
+	return k.err
+}
+
+// Ensures that Events are written as JSON to the topic, keyed by their resource and
+// resource ID, and that errors of the producer are returned.
+func TestKafkaPublisher(t *testing.T) {
+	assert := assert.New(t)
+	producer := &kafkaProducer{}
+	publisher := NewKafkaPublisher(producer, "mutations")
+
+	assert.Nil(publisher.Publish(context.Background(),
+		rest.Event{ID: 3, Type: rest.EventUpdate, Resource: "widgets", ResourceID: "42"}))
+	assert.Equal("mutations", producer.topic)
+	assert.Equal("widgets/42", producer.key)
+	var event rest.Event
+	if assert.Nil(json.Unmarshal(producer.value, &event)) {
+		assert.Equal(rest.EventUpdate, event.Type)
+	}
+
+	producer.err = errors.New("broker unavailable")
+	assert.Equal(producer.err, publisher.Publish(context.Background(), rest.Event{}))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package publishers provides rest.EventPublishers which publish the Events of
+// mutations to message brokers. The adapters depend on small interfaces rather than
+// the brokers' client libraries, so services only link the client they use.
+//
+//	conn, err := nats.Connect(nats.DefaultURL)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	config := rest.NewConfiguration()
+//	config.Events = &rest.EventConfig{Publisher: publishers.NewNATSPublisher(conn, "myapp")}
+package publishers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// NATSConn publishes messages to NATS subjects. It's satisfied by *nats.Conn.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+}
+
+// natsPublisher publishes Events to NATS.
+type natsPublisher struct {
+	conn   NATSConn
+	prefix string
+}
+
+// NewNATSPublisher returns a rest.EventPublisher publishing each Event as JSON to the
+// subject {prefix}.{resource}.{type}, e.g. myapp.widgets.create, so subscribers can
+// use wildcards such as myapp.widgets.> or myapp.*.delete. An empty prefix omits it.
+func NewNATSPublisher(conn NATSConn, prefix string) rest.EventPublisher {
+	return &natsPublisher{conn: conn, prefix: prefix}
+}
+
+// Publish publishes the Event to its subject.
+func (n *natsPublisher) Publish(ctx context.Context, event rest.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := event.Resource + "." + string(event.Type)
+	if n.prefix != "" {
+		subject = n.prefix + "." + subject
+	}
+	return n.conn.Publish(subject, data)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publishers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Workiva/go-rest/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+// natsConn records the messages published.
+type natsConn struct {
+	subjects []string
+	data     [][]byte
+}
+
+func (n *natsConn) Publish(subject string, data []byte) error {
+	n.subjects = append(n.subjects, subject)
+	n.data = append(n.data, data)
+	return nil
+}
+
+// Ensures that Events are published as JSON to subjects named by their resource and
+// type.
+func TestNATSPublisher(t *testing.T) {
+	assert := assert.New(t)
+	conn := &natsConn{}
+
+	publisher := NewNATSPublisher(conn, "myapp")
+	assert.Nil(publisher.Publish(context.Background(),
+		rest.Event{ID: 1, Type: rest.EventCreate, Resource: "widgets", ResourceID: "42"}))
+	assert.Nil(NewNATSPublisher(conn, "").Publish(context.Background(),
+		rest.Event{ID: 2, Type: rest.EventDelete, Resource: "widgets"}))
+
+	assert.Equal([]string{"myapp.widgets.create", "widgets.delete"}, conn.subjects)
+	var event rest.Event
+	if assert.Nil(json.Unmarshal(conn.data[0], &event)) {
+		assert.Equal(uint64(1), event.ID)
+		assert.Equal("42", event.ResourceID)
+	}
+}
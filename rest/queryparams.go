@@ -0,0 +1,158 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// QueryParameter describes a query string variable accepted by a ResourceHandler's
+// endpoints.
+type QueryParameter struct {
+	// Name is the name of the query string variable.
+	Name string
+
+	// Type is the Type values are coerced to to be validated. Values of Unspecified
+	// parameters aren't validated.
+	Type Type
+
+	// Values, if set, are the values which are allowed.
+	Values []string
+
+	// Description used in documentation.
+	DocString string
+}
+
+// QueryParameterHandler is implemented by ResourceHandlers declaring the query string
+// variables accepted by their endpoints in addition to the framework's, e.g. limit and
+// filter. Requests with declared variables whose values are malformed or not allowed
+// are rejected with a 400 Bad Request response, and, if StrictQueryParameters is
+// configured, so are requests with variables which aren't declared.
+type QueryParameterHandler interface {
+	// QueryParameters returns the query string variables accepted for the version.
+	QueryParameters(version string) []QueryParameter
+}
+
+// standardQueryParameters are the query string variables handled by the framework.
+// Those with a Type are validated in strict mode.
+var standardQueryParameters = []QueryParameter{
+	{Name: formatKey},
+	{Name: cursorKey},
+	{Name: limitKey, Type: Int},
+	{Name: offsetKey, Type: Int},
+	{Name: filterKey},
+	{Name: sortKey},
+	{Name: expandKey},
+	{Name: searchKey},
+	{Name: fromKey},
+	{Name: toKey},
+	{Name: timezoneKey},
+	{Name: nearKey},
+	{Name: radiusKey},
+	{Name: bboxKey},
+	{Name: groupByKey},
+	{Name: metricKey},
+	{Name: waitKey, Type: Duration},
+	{Name: sinceKey, Type: Uint64},
+	{Name: executeAtKey, Type: Time},
+	{Name: resourceIDKey},
+	{Name: viewKey},
+}
+
+// queryParameterHandler returns the QueryParameterHandler implemented by the
+// ResourceHandler, if any.
+func queryParameterHandler(handler ResourceHandler) (QueryParameterHandler, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	declaring, ok := handler.(QueryParameterHandler)
+	return declaring, ok
+}
+
+// declaredQueryParameters returns the query string variables declared by the
+// ResourceHandler for the version, if it's a QueryParameterHandler.
+func declaredQueryParameters(handler ResourceHandler, version string) []QueryParameter {
+	if declaring, ok := queryParameterHandler(handler); ok {
+		return declaring.QueryParameters(version)
+	}
+	return nil
+}
+
+// validateQueryParameters returns an error describing the first query string variable
+// of the request which is malformed, not allowed or, if strict, unknown.
+func validateQueryParameters(query map[string][]string, declared []QueryParameter,
+	strict bool) error {
+
+	params := map[string]QueryParameter{}
+	if strict {
+		for _, param := range standardQueryParameters {
+			params[param.Name] = param
+		}
+	}
+	for _, param := range declared {
+		params[param.Name] = param
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		param, ok := params[name]
+		if !ok {
+			if strict && !strings.HasPrefix(name, eventFieldPrefix) {
+				return fmt.Errorf("Unknown query parameter: %s", name)
+			}
+			continue
+		}
+		for _, value := range query[name] {
+			if param.Type != Unspecified {
+				if _, err := coerceFilterValue(value, param.Type); err != nil {
+					return fmt.Errorf("Invalid %s: %q isn't a valid %s", name, value, param.Type)
+				}
+			}
+			if len(param.Values) > 0 && !contains(param.Values, value) {
+				return fmt.Errorf("Invalid %s: %q isn't one of %s", name, value,
+					strings.Join(param.Values, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// newQueryParameterMiddleware returns a RequestMiddleware rejecting requests to the
+// ResourceHandler whose query string variables are invalid.
+func (h requestHandler) newQueryParameterMiddleware(handler ResourceHandler) RequestMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			config := h.Configuration()
+			strict := config != nil && config.StrictQueryParameters
+			declared := declaredQueryParameters(handler, PathVars(r)[versionKey])
+			if err := validateQueryParameters(r.URL.Query(), declared, strict); err != nil {
+				ctx, cancel := h.newContext(r, w, handler.ResourceName())
+				defer cancel()
+				h.sendResponse(ctx.setError(BadRequest(err.Error())))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
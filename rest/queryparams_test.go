@@ -0,0 +1,101 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// declaringTasksResourceHandler is a tasksResourceHandler declaring query parameters.
+type declaringTasksResourceHandler struct {
+	tasksResourceHandler
+}
+
+func (d declaringTasksResourceHandler) QueryParameters(version string) []QueryParameter {
+	return []QueryParameter{
+		{Name: "view", Type: String, Values: []string{"compact", "full"}},
+		{Name: "min_size", Type: Int, DocString: "Smallest size of tasks"},
+	}
+}
+
+// Ensures that requests with declared query parameters whose values are malformed or
+// not allowed are rejected, while unknown ones are ignored outside strict mode.
+func TestDeclaredQueryParameters(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(declaringTasksResourceHandler{})
+
+	code, _ := serveJSON(api, "GET", "/api/v1/tasks?view=full&min_size=3&limt=10", "")
+	assert.Equal(http.StatusOK, code)
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks?view=summary", "")
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(envelope["messages"], `Invalid view: "summary" isn't one of compact, full`)
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks?min_size=3&min_size=big", "")
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(envelope["messages"], `Invalid min_size: "big" isn't a valid int`)
+}
+
+// Ensures that, in strict mode, requests with unknown query parameters or malformed
+// standard ones are rejected.
+func TestStrictQueryParameters(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfiguration()
+	config.StrictQueryParameters = true
+	api := NewAPI(config)
+	api.RegisterResourceHandler(declaringTasksResourceHandler{})
+	api.RegisterResourceHandler(timeRangeResourceHandler{})
+
+	code, _ := serveJSON(api, "GET", "/api/v1/tasks?limit=10&sort=name&view=compact", "")
+	assert.Equal(http.StatusOK, code)
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks?limt=10", "")
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(envelope["messages"], "Unknown query parameter: limt")
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/ranges?from=now-1h&limit=ten", "")
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(envelope["messages"], `Invalid limit: "ten" isn't a valid int`)
+}
+
+// Ensures that unknown query parameters are ignored outside strict mode when the
+// handler doesn't declare any.
+func TestQueryParametersNotDeclared(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(tasksResourceHandler{})
+
+	code, _ := serveJSON(api, "GET", "/api/v1/tasks?limt=10&limit=ten", "")
+
+	assert.Equal(http.StatusOK, code)
+}
+
+// Ensures that declared query parameters are documented in the OpenAPI specification.
+func TestOpenAPIDeclaredQueryParameters(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(declaringTasksResourceHandler{})
+
+	names := queryParameters(api, "/api/v{version}/tasks")
+
+	assert.Contains(names, "view")
+	assert.Contains(names, "min_size")
+}
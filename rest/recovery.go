@@ -0,0 +1,67 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// ErrorReporter is invoked with the recovered value and stack trace when a
+// ResourceHandler panics. The incident is a unique reference included in the error
+// response so reports can be correlated with the client's request.
+type ErrorReporter func(ctx RequestContext, incident string, recovered interface{}, stack []byte)
+
+// panicError is the error set on the RequestContext when a ResourceHandler panics.
+type panicError struct {
+	incident string
+}
+
+// Error returns a generic message so internal details aren't leaked to clients.
+func (p panicError) Error() string { return "Internal server error" }
+
+// StatusCode returns 500. It implements the StatusError interface.
+func (p panicError) StatusCode() int { return http.StatusInternalServerError }
+
+// recoverPanic recovers from a panic in a ResourceHandler, reports it, and responds
+// with a 500 containing the request ID and an incident reference. It must be
+// deferred.
+func (h requestHandler) recoverPanic(ctx RequestContext) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if recovered == http.ErrAbortHandler {
+		// Let net/http abort the response as intended.
+		panic(recovered)
+	}
+
+	incident := newRequestID()
+	stack := debug.Stack()
+	if config := h.Configuration(); config != nil && config.ErrorReporter != nil {
+		config.ErrorReporter(ctx, incident, recovered, stack)
+	} else {
+		reportPanic(ctx, incident, recovered, stack)
+	}
+
+	h.sendResponse(ctx.setError(panicError{incident: incident}))
+}
+
+// reportPanic is the default ErrorReporter, which logs the panic and stack trace.
+func reportPanic(ctx RequestContext, incident string, recovered interface{}, stack []byte) {
+	ctx.Logger().Printf("Recovered from panic (incident %s): %v\n%s", incident, recovered, stack)
+}
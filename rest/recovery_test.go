@@ -0,0 +1,92 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type panickingResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (p panickingResourceHandler) ResourceName() string {
+	return "widgets"
+}
+
+func (p panickingResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	panic("boom")
+}
+
+// Ensures that panics in a ResourceHandler are reported and result in a 500 containing
+// the request ID and incident reference.
+func TestHandleReadPanic(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		reportedIncident  string
+		reportedRecovered interface{}
+		reportedStack     []byte
+	)
+	api := NewAPI(&Configuration{
+		ErrorReporter: func(ctx RequestContext, incident string, recovered interface{}, stack []byte) {
+			reportedIncident = incident
+			reportedRecovered = recovered
+			reportedStack = stack
+		},
+	})
+	api.RegisterResourceHandler(panickingResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets/1", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusInternalServerError, resp.Code)
+	assert.Equal("abc-123", resp.Header().Get("X-Request-ID"))
+	assert.Equal("boom", reportedRecovered)
+	assert.Contains(string(reportedStack), "panickingResourceHandler")
+	assert.Len(reportedIncident, 32)
+
+	var payload map[string]interface{}
+	assert.Nil(json.Unmarshal(resp.Body.Bytes(), &payload))
+	assert.Equal(map[string]interface{}{
+		"incident":   reportedIncident,
+		"messages":   []interface{}{"Internal server error"},
+		"reason":     "Internal Server Error",
+		"request_id": "abc-123",
+		"status":     float64(500),
+	}, payload)
+}
+
+// Ensures that http.ErrAbortHandler panics are not recovered.
+func TestRecoverPanicAbortHandler(t *testing.T) {
+	assert := assert.New(t)
+	h := requestHandler{API: NewAPI(&Configuration{})}
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/widgets/1", nil)
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	assert.Panics(func() {
+		defer h.recoverPanic(ctx)
+		panic(http.ErrAbortHandler)
+	})
+}
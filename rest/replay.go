@@ -0,0 +1,95 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import "time"
+
+// resetEvent is the type of the Server-Sent Event, and of the WebSocket message, sent
+// to clients resuming after Events which are no longer recorded, so they know to read
+// the resources again rather than silently missing updates.
+const resetEvent = "reset"
+
+// eventRing is a bounded ring buffer of the most recent Events, dropping the oldest
+// once full and, given a retention, those published longer ago.
+type eventRing struct {
+	events    []Event
+	start     int
+	count     int
+	retention time.Duration
+
+	// dropped is the ID of the latest Event dropped. Since IDs are sequential, the
+	// Events after any ID at least as large are all recorded.
+	dropped uint64
+}
+
+// newEventRing returns an eventRing holding up to size Events for the retention.
+func newEventRing(size int, retention time.Duration) *eventRing {
+	return &eventRing{events: make([]Event, size), retention: retention}
+}
+
+// push records the Event, dropping the oldest if the buffer is full.
+func (r *eventRing) push(event Event) {
+	r.prune(event.Time)
+	if r.count == len(r.events) {
+		r.drop()
+	}
+	r.events[(r.start+r.count)%len(r.events)] = event
+	r.count++
+}
+
+// after returns the recorded Events after the ID accepted by the filter, and false if
+// Events after the ID are no longer recorded.
+func (r *eventRing) after(id uint64, filter func(Event) bool) ([]Event, bool) {
+	r.prune(time.Now().UTC())
+	var events []Event
+	for i := 0; i < r.count; i++ {
+		event := r.events[(r.start+i)%len(r.events)]
+		if event.ID > id && filter(event) {
+			events = append(events, event)
+		}
+	}
+	return events, id >= r.dropped
+}
+
+// prune drops the Events older than the retention at the time.
+func (r *eventRing) prune(now time.Time) {
+	if r.retention <= 0 {
+		return
+	}
+	for r.count > 0 && now.Sub(r.events[r.start].Time) > r.retention {
+		r.drop()
+	}
+}
+
+// drop drops the oldest Event.
+func (r *eventRing) drop() {
+	r.dropped = r.events[r.start].ID
+	r.events[r.start] = Event{}
+	r.start = (r.start + 1) % len(r.events)
+	r.count--
+}
+
+// replay calls apply, e.g. to add a filter to a subscription, and returns the recorded
+// Events after the ID accepted by the filter, and false if Events after the ID are no
+// longer recorded. Since no Events are published in between, those published
+// afterwards are the ones sent to the subscription.
+func (b *eventBus) replay(after uint64, filter func(Event) bool, apply func()) ([]Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	apply()
+	return b.history.after(after, filter)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// Ensures that the eventRing keeps the most recent Events up to its size and
+// retention, and reports whether the Events after an ID are all recorded.
+func TestEventRing(t *testing.T) {
+	assert := assert.New(t)
+	all := func(Event) bool { return true }
+	ring := newEventRing(3, 0)
+	now := time.Now().UTC()
+	for id := uint64(1); id <= 5; id++ {
+		ring.push(Event{ID: id, Time: now})
+	}
+
+	events, complete := ring.after(0, all)
+	assert.False(complete)
+	if assert.Len(events, 3) {
+		assert.Equal(uint64(3), events[0].ID)
+		assert.Equal(uint64(5), events[2].ID)
+	}
+	events, complete = ring.after(2, all)
+	assert.True(complete)
+	assert.Len(events, 3)
+	events, complete = ring.after(4, func(e Event) bool { return e.ID != 5 })
+	assert.True(complete)
+	assert.Empty(events)
+
+	ring = newEventRing(10, time.Minute)
+	ring.push(Event{ID: 1, Time: now.Add(-2 * time.Minute)})
+	ring.push(Event{ID: 2, Time: now})
+	events, complete = ring.after(0, all)
+	assert.False(complete)
+	if assert.Len(events, 1) {
+		assert.Equal(uint64(2), events[0].ID)
+	}
+	_, complete = ring.after(1, all)
+	assert.True(complete)
+}
+
+// Ensures that Server-Sent Event streams resumed after Events which are no longer
+// recorded start with a reset Event, followed by those still recorded.
+func TestEventStreamReset(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{History: 2}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+	for i := 0; i < 4; i++ {
+		resp, err := http.Post(ts.URL+"/api/v1/widgets", "application/json", strings.NewReader(`{}`))
+		if assert.Nil(err) {
+			resp.Body.Close()
+		}
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/widgets/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(err) {
+		return
+	}
+	defer resp.Body.Close()
+	stream := bufio.NewReader(resp.Body)
+	header, _ := readEvent(t, stream)
+	assert.Equal(" reset", header)
+	header, _ = readEvent(t, stream)
+	assert.Equal("3 create", header)
+	header, _ = readEvent(t, stream)
+	assert.Equal("4 create", header)
+}
+
+// Ensures that WebSocket subscriptions with a last_event_id receive the recorded Events
+// of the subscription after it before the live ones, preceded by a reset message if
+// some are no longer recorded.
+func TestEventSocketReplay(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{History: 3, WebSocketPath: "/api/events"}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+	mutate := func(id string) {
+		req, _ := http.NewRequest("PUT", ts.URL+"/api/v1/widgets/"+id, strings.NewReader(`{}`))
+		if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+			resp.Body.Close()
+		}
+	}
+	for _, id := range []string{"1", "2", "1", "1"} {
+		mutate(id)
+	}
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/events", "", ts.URL)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close()
+	eventID := func() uint64 {
+		message := receiveMessage(t, conn)
+		if !assert.Equal("event", message.Type) {
+			return 0
+		}
+		return message.Event.ID
+	}
+
+	last := uint64(2)
+	websocket.JSON.Send(conn, webSocketRequest{Action: "subscribe", Resource: "widgets",
+		ResourceID: "1", LastEventID: &last})
+	assert.Equal("subscribed", receiveMessage(t, conn).Type)
+	assert.Equal(uint64(3), eventID())
+	assert.Equal(uint64(4), eventID())
+	mutate("1")
+	assert.Equal(uint64(5), eventID())
+
+	last = 0
+	websocket.JSON.Send(conn, webSocketRequest{Action: "subscribe", Resource: "widgets",
+		ResourceID: "2", LastEventID: &last})
+	assert.Equal("subscribed", receiveMessage(t, conn).Type)
+	assert.Equal(webSocketMessage{Type: resetEvent, Resource: "widgets", ResourceID: "2"},
+		receiveMessage(t, conn))
+	mutate("2")
+	assert.Equal(uint64(6), eventID())
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// resourceRoute is one of the routes of a ResourceHandler.
+type resourceRoute struct {
+	name        string
+	method      string
+	uri         string
+	headers     []string
+	label       string
+	logMethod   string
+	handlerName string
+	handler     http.Handler
+}
+
+// key identifies the route by its name, method, path and headers.
+func (r resourceRoute) key() string {
+	return strings.Join(append([]string{r.name, r.method, r.uri}, r.headers...), " ")
+}
+
+// liveResource holds the Handlers of the routes of a resource, which are replaced when
+// its ResourceHandler is replaced or unregistered while the API is serving requests.
+type liveResource struct {
+	mu       sync.RWMutex
+	handler  ResourceHandler
+	routes   []string
+	handlers map[string]http.Handler
+}
+
+// set replaces the ResourceHandler and the Handlers of its routes. If the
+// ResourceHandler is nil, the resource is unregistered.
+func (l *liveResource) set(h ResourceHandler, routes []resourceRoute) {
+	handlers := map[string]http.Handler{}
+	keys := make([]string, 0, len(routes))
+	for _, route := range routes {
+		handlers[route.name] = route.handler
+		keys = append(keys, route.key())
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handler = h
+	if h == nil {
+		l.handlers = nil
+		return
+	}
+	l.handlers = handlers
+	l.routes = keys
+}
+
+// active indicates if a ResourceHandler is registered for the resource.
+func (l *liveResource) active() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.handler != nil
+}
+
+// matches indicates if the routes are those registered for the resource.
+func (l *liveResource) matches(routes []resourceRoute) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(routes) != len(l.routes) {
+		return false
+	}
+	for i, route := range routes {
+		if route.key() != l.routes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// liveRoute returns the Handler registered with the Router for the named route of the
+// resource, dispatching requests to the Handler of its current ResourceHandler or
+// responding with a 404 if it's been unregistered.
+func (r *muxAPI) liveRoute(resource *liveResource, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resource.mu.RLock()
+		handler := resource.handlers[name]
+		resource.mu.RUnlock()
+		if handler == nil {
+			r.notFound.ServeHTTP(w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// UnregisterResourceHandler removes the ResourceHandler most recently registered for
+// the resource, responding to requests for its routes with a 404 from then on. It
+// returns false if no ResourceHandler is registered for the resource. It may be called
+// while the API is serving requests. In-flight requests are completed by the removed
+// ResourceHandler.
+func (r *muxAPI) UnregisterResourceHandler(resource string) bool {
+	r.mu.Lock()
+	live, ok := r.resources[resource]
+	if !ok || !live.active() {
+		r.mu.Unlock()
+		return false
+	}
+	live.set(nil, nil)
+	if i := r.resourceHandlerIndex(resource); i >= 0 {
+		r.resourceHandlers = append(r.resourceHandlers[:i:i], r.resourceHandlers[i+1:]...)
+	}
+	r.mu.Unlock()
+
+	r.invalidateResource(resource)
+	r.config.Debugf("Unregistered handler of resource %s", resource)
+	return true
+}
+
+// ReplaceResourceHandler swaps the ResourceHandler registered for the resource of the
+// given one, e.g. a new version of it, and applies any specified middleware. Requests
+// are handled by the new ResourceHandler as soon as it returns, without being dropped.
+// It returns an error if no ResourceHandler is registered for the resource or if their
+// URIs differ, since routes can't be removed from the Router.
+func (r *muxAPI) ReplaceResourceHandler(h ResourceHandler, middleware ...RequestMiddleware) error {
+	h = resourceHandlerProxy{h}
+	resource := h.ResourceName()
+	routes, middleware := r.resourceRoutes(h, middleware)
+
+	r.mu.Lock()
+	live, ok := r.resources[resource]
+	if !ok || !live.active() {
+		r.mu.Unlock()
+		return fmt.Errorf("no ResourceHandler is registered for resource %s", resource)
+	}
+	if !live.matches(routes) {
+		r.mu.Unlock()
+		return fmt.Errorf("ResourceHandler of resource %s has different URIs than the one it replaces",
+			resource)
+	}
+	live.set(h, routes)
+	if i := r.resourceHandlerIndex(resource); i >= 0 {
+		r.resourceHandlers[i] = h
+	}
+	r.updateRoutes(routes, middleware)
+	r.mu.Unlock()
+
+	r.invalidateResource(resource)
+	r.config.Debugf("Replaced handler of resource %s", resource)
+	return nil
+}
+
+// unregisteredResource returns the resource whose ResourceHandler was unregistered, if
+// its routes are the given ones.
+func (r *muxAPI) unregisteredResource(resource string, routes []resourceRoute) *liveResource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	live, ok := r.resources[resource]
+	if !ok || live.active() || !live.matches(routes) {
+		return nil
+	}
+	return live
+}
+
+// activate registers the ResourceHandler for the resource whose previous one was
+// unregistered.
+func (r *muxAPI) activate(live *liveResource, h ResourceHandler, routes []resourceRoute,
+	middleware []RequestMiddleware) {
+
+	r.mu.Lock()
+	live.set(h, routes)
+	r.resourceHandlers = append(r.resourceHandlers, h)
+	r.updateRoutes(routes, middleware)
+	r.mu.Unlock()
+	r.config.Debugf("Registered handler of resource %s", h.ResourceName())
+}
+
+// resourceHandlerIndex returns the index of the ResourceHandler most recently
+// registered for the resource, or -1 if there isn't one. The lock must be held.
+func (r *muxAPI) resourceHandlerIndex(resource string) int {
+	for i := len(r.resourceHandlers) - 1; i >= 0; i-- {
+		if r.resourceHandlers[i].ResourceName() == resource {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateRoutes updates the handlers and middleware listed by Routes for the routes of
+// a resource. The lock must be held.
+func (r *muxAPI) updateRoutes(routes []resourceRoute, middleware []RequestMiddleware) {
+	names := make([]string, len(middleware))
+	for i, m := range middleware {
+		names[i] = funcName(m)
+	}
+	handlers := map[string]string{}
+	for _, route := range routes {
+		handlers[route.name] = route.handlerName
+	}
+	for i, info := range r.routes {
+		if handler, ok := handlers[info.Name]; ok {
+			r.routes[i].Handler = handler
+			r.routes[i].Middleware = names
+		}
+	}
+}
+
+// unregisteredRoute indicates if the named route belongs to a resource whose
+// ResourceHandler was unregistered. The lock must be held.
+func (r *muxAPI) unregisteredRoute(name string) bool {
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return false
+	}
+	live, ok := r.resources[name[:i]]
+	return ok && !live.active()
+}
+
+// invalidateResource invalidates the cached responses of the resource.
+func (r *muxAPI) invalidateResource(resource string) {
+	cache := r.config.ResponseCache
+	if cache == nil || cache.Store == nil {
+		return
+	}
+	if err := cache.Store.Invalidate(cachePrefix(resource)); err != nil {
+		log.Printf("Response cache invalidation failed: %s", err)
+	}
+}
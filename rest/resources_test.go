@@ -0,0 +1,122 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// movedRowsHandler serves rows at a different URI than largeResourceHandler.
+type movedRowsHandler struct {
+	largeResourceHandler
+}
+
+func (m movedRowsHandler) ReadListURI() string {
+	return "/api/v{version:[^/]+}/table"
+}
+
+// namedResourceHandler is a ResourceHandler of the named resource.
+type namedResourceHandler struct {
+	BaseResourceHandler
+	name string
+}
+
+func (n namedResourceHandler) ResourceName() string {
+	return n.name
+}
+
+func (n namedResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	return []Resource{}, "", nil
+}
+
+// serveRows returns the status and number of results of a list request for rows.
+func serveRows(api API) (int, int) {
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/rows", nil))
+	results, _ := decodeEnvelope(w)["results"].([]interface{})
+	return w.Code, len(results)
+}
+
+// Ensures that ResourceHandlers can be replaced, unregistered and registered again
+// while the API is serving requests.
+func TestReplaceUnregisterResourceHandler(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	routes := len(api.Routes())
+	status, results := serveRows(api)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(1, results)
+
+	assert.NoError(api.ReplaceResourceHandler(largeResourceHandler{count: 3}))
+	status, results = serveRows(api)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(3, results)
+	assert.Len(api.ResourceHandlers(), 1)
+	assert.Len(api.Routes(), routes)
+	assert.Error(api.ReplaceResourceHandler(movedRowsHandler{}))
+
+	assert.True(api.UnregisterResourceHandler("rows"))
+	assert.False(api.UnregisterResourceHandler("rows"))
+	status, _ = serveRows(api)
+	assert.Equal(http.StatusNotFound, status)
+	assert.Empty(api.ResourceHandlers())
+	assert.Len(api.Routes(), routes-12)
+	assert.Error(api.ReplaceResourceHandler(largeResourceHandler{count: 1}))
+
+	api.RegisterResourceHandler(largeResourceHandler{count: 2})
+	status, results = serveRows(api)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(2, results)
+	assert.Len(api.ResourceHandlers(), 1)
+	assert.Len(api.Routes(), routes)
+}
+
+// Ensures that ResourceHandlers can be registered concurrently with requests.
+func TestRegisterResourceHandlerConcurrently(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				status, _ := serveRows(api)
+				assert.Equal(http.StatusOK, status)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		api.RegisterResourceHandler(namedResourceHandler{name: fmt.Sprintf("items%d", i)})
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/items19", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Len(api.ResourceHandlers(), 21)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resttest
+
+import (
+	"strconv"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// fixtureHandler is a rest.ResourceHandler which serves the fixtures of a Resource.
+type fixtureHandler struct {
+	rest.BaseResourceHandler
+	resource *Resource
+}
+
+// ResourceName returns the name of the Resource.
+func (f *fixtureHandler) ResourceName() string {
+	return f.resource.name
+}
+
+// CreateResource adds a fixture from the payload, using its "id" field if set or
+// otherwise assigning the next numeric ID.
+func (f *fixtureHandler) CreateResource(ctx rest.RequestContext, data rest.Payload,
+	version string) (rest.Resource, error) {
+	r := f.resource
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.failure(); err != nil {
+		return nil, err
+	}
+
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		for {
+			r.nextID++
+			id = strconv.Itoa(r.nextID)
+			if _, exists := r.items[id]; !exists {
+				break
+			}
+		}
+		data["id"] = id
+	}
+	if _, exists := r.items[id]; exists {
+		return nil, rest.Conflict("Resource " + id + " already exists")
+	}
+	r.items[id] = data
+	return data, nil
+}
+
+// ReadResourceList returns a page of fixtures in ID order. The cursor is the ID of
+// the last fixture of the previous page.
+func (f *fixtureHandler) ReadResourceList(ctx rest.RequestContext, limit int,
+	cursor string, version string) ([]rest.Resource, string, error) {
+	r := f.resource
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.failure(); err != nil {
+		return nil, "", err
+	}
+
+	ids := r.sortedIDs()
+	start := 0
+	if cursor != "" {
+		for i, id := range ids {
+			if id == cursor {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	resources := []rest.Resource{}
+	next := ""
+	for i := start; i < len(ids); i++ {
+		if len(resources) == limit {
+			next = ids[i-1]
+			break
+		}
+		resources = append(resources, r.items[ids[i]])
+	}
+	ctx.SetTotal(len(ids))
+	return resources, next, nil
+}
+
+// ReadResource returns the fixture with the ID.
+func (f *fixtureHandler) ReadResource(ctx rest.RequestContext, id string,
+	version string) (rest.Resource, error) {
+	r := f.resource
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.failure(); err != nil {
+		return nil, err
+	}
+
+	fixture, ok := r.items[id]
+	if !ok {
+		return nil, rest.NotFound("Resource " + id + " not found")
+	}
+	return fixture, nil
+}
+
+// UpdateResource replaces the fixture with the ID with the payload.
+func (f *fixtureHandler) UpdateResource(ctx rest.RequestContext, id string,
+	data rest.Payload, version string) (rest.Resource, error) {
+	r := f.resource
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.failure(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := r.items[id]; !ok {
+		return nil, rest.NotFound("Resource " + id + " not found")
+	}
+	data["id"] = id
+	r.items[id] = data
+	return data, nil
+}
+
+// DeleteResource removes the fixture with the ID, returning it.
+func (f *fixtureHandler) DeleteResource(ctx rest.RequestContext, id string,
+	version string) (rest.Resource, error) {
+	r := f.resource
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.failure(); err != nil {
+		return nil, err
+	}
+
+	fixture, ok := r.items[id]
+	if !ok {
+		return nil, rest.NotFound("Resource " + id + " not found")
+	}
+	delete(r.items, id)
+	return fixture, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resttest provides a stub go-rest server for testing clients. It serves
+// resource fixtures using the go-rest response envelope, can be programmed to fail
+// requests, and records the requests it receives for assertions.
+package resttest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Workiva/go-rest/rest"
+)
+
+// Request is a request received by a Server.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Server is an httptest.Server which emulates a go-rest API. Resources should be
+// added with Resource before requests are made, and the Server should be closed
+// when the test completes.
+type Server struct {
+	*httptest.Server
+
+	api       rest.API
+	mu        sync.Mutex
+	resources map[string]*Resource
+	requests  []*Request
+}
+
+// NewServer starts and returns a new Server.
+func NewServer() *Server {
+	s := &Server{
+		api:       rest.NewAPI(&rest.Configuration{}),
+		resources: map[string]*Resource{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the base URL of the API, including the version, suitable for
+// passing to client.New.
+func (s *Server) BaseURL() string {
+	return s.URL + "/api/v1"
+}
+
+// Resource returns the named resource, registering it if it doesn't exist.
+func (s *Server) Resource(name string) *Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.resources[name]; ok {
+		return r
+	}
+	r := &Resource{name: name, items: map[string]interface{}{}}
+	s.resources[name] = r
+	s.api.RegisterResourceHandler(&fixtureHandler{resource: r})
+	return r
+}
+
+// Requests returns the requests received by the Server, in order.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// LastRequest returns the most recent request received by the Server, or nil if
+// there have been none.
+func (s *Server) LastRequest() *Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// serveHTTP records the request and passes it to the API.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, &Request{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	s.mu.Unlock()
+
+	s.api.ServeHTTP(w, r)
+}
+
+// Resource is a collection of fixtures served by a Server. It's safe for concurrent
+// use.
+type Resource struct {
+	mu       sync.Mutex
+	name     string
+	items    map[string]interface{}
+	failures []error
+	nextID   int
+}
+
+// Add adds a fixture with the given ID. Fixtures are serialized as JSON, so any
+// value which can be marshaled can be used. It returns the Resource to allow
+// chaining.
+func (r *Resource) Add(id string, fixture interface{}) *Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[id] = fixture
+	return r
+}
+
+// Get returns the fixture with the given ID, including those created by requests.
+func (r *Resource) Get(id string) (interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fixture, ok := r.items[id]
+	return fixture, ok
+}
+
+// Len returns the number of fixtures.
+func (r *Resource) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.items)
+}
+
+// FailNext causes the next request to the resource to fail with the error. Errors
+// implementing rest.StatusError determine the response status. Calls queue up, so
+// FailNext can be called repeatedly to fail several requests. It returns the
+// Resource to allow chaining.
+func (r *Resource) FailNext(err error) *Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, err)
+	return r
+}
+
+// failure returns the next queued failure, if any.
+func (r *Resource) failure() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+	err := r.failures[0]
+	r.failures = r.failures[1:]
+	return err
+}
+
+// sortedIDs returns the fixture IDs in order, numerically if possible.
+func (r *Resource) sortedIDs() []string {
+	ids := make([]string, 0, len(r.items))
+	for id := range r.items {
+		ids = append(ids, id)
+	}
+	sort.Sort(byID(ids))
+	return ids
+}
+
+// byID implements sort.Interface for sorting IDs numerically, falling back to
+// lexically for non-numeric IDs.
+type byID []string
+
+func (b byID) Len() int      { return len(b) }
+func (b byID) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byID) Less(i, j int) bool {
+	x, errX := strconv.Atoi(b[i])
+	y, errY := strconv.Atoi(b[j])
+	if errX == nil && errY == nil {
+		return x < y
+	}
+	return b[i] < b[j]
+}
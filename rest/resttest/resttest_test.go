@@ -0,0 +1,132 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resttest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Workiva/go-rest/rest"
+	"github.com/Workiva/go-rest/rest/client"
+)
+
+type widget struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Ensures that the Server serves fixtures and emulates CRUD operations.
+func TestServerCRUD(t *testing.T) {
+	assert := assert.New(t)
+	server := NewServer()
+	defer server.Close()
+	server.Resource("widgets").
+		Add("1", widget{ID: "1", Name: "cog"}).
+		Add("2", widget{ID: "2", Name: "sprocket"})
+	c := client.New(server.BaseURL())
+	ctx := context.Background()
+
+	var read widget
+	assert.Nil(c.Read(ctx, "widgets", "2", &read))
+	assert.Equal(widget{ID: "2", Name: "sprocket"}, read)
+
+	var created widget
+	assert.Nil(c.Create(ctx, "widgets", widget{Name: "gear"}, &created))
+	assert.Equal("gear", created.Name)
+	assert.Equal("3", created.ID)
+	assert.Equal(3, server.Resource("widgets").Len())
+
+	var updated widget
+	assert.Nil(c.Update(ctx, "widgets", "3", widget{Name: "pinion"}, &updated))
+	assert.Equal(widget{ID: "3", Name: "pinion"}, updated)
+
+	assert.Nil(c.Delete(ctx, "widgets", "1", nil))
+	_, ok := server.Resource("widgets").Get("1")
+	assert.False(ok)
+
+	err := c.Read(ctx, "widgets", "1", nil)
+	assert.True(errors.Is(err, rest.ErrNotFound))
+}
+
+// Ensures that the Server paginates fixtures in ID order.
+func TestServerList(t *testing.T) {
+	assert := assert.New(t)
+	server := NewServer()
+	defer server.Close()
+	resource := server.Resource("widgets")
+	for _, id := range []string{"10", "2", "1"} {
+		resource.Add(id, widget{ID: id})
+	}
+	c := client.New(server.BaseURL())
+
+	var widgets []widget
+	page, err := c.List(context.Background(), "widgets", &client.ListOptions{Limit: 2}, &widgets)
+	assert.Nil(err)
+	assert.Equal([]widget{{ID: "1"}, {ID: "2"}}, widgets)
+	assert.Equal("2", page.Cursor)
+	assert.Equal(3, *page.Total)
+
+	page, err = c.List(context.Background(), "widgets",
+		&client.ListOptions{Limit: 2, Cursor: page.Cursor}, &widgets)
+	assert.Nil(err)
+	assert.Equal([]widget{{ID: "10"}}, widgets)
+	assert.False(page.HasNext())
+}
+
+// Ensures that FailNext fails queued requests with the programmed errors.
+func TestServerFailNext(t *testing.T) {
+	assert := assert.New(t)
+	server := NewServer()
+	defer server.Close()
+	server.Resource("widgets").
+		Add("1", widget{ID: "1"}).
+		FailNext(rest.Unavailable(0)).
+		FailNext(errors.New("boom"))
+	c := client.New(server.BaseURL())
+	ctx := context.Background()
+
+	err := c.Read(ctx, "widgets", "1", nil)
+	assert.Equal(http.StatusServiceUnavailable, err.(*client.Error).Status)
+	err = c.Read(ctx, "widgets", "1", nil)
+	assert.Equal(http.StatusInternalServerError, err.(*client.Error).Status)
+	assert.Nil(c.Read(ctx, "widgets", "1", nil))
+}
+
+// Ensures that the Server records requests.
+func TestServerRequests(t *testing.T) {
+	assert := assert.New(t)
+	server := NewServer()
+	defer server.Close()
+	assert.Nil(server.LastRequest())
+	server.Resource("widgets")
+	c := client.New(server.BaseURL(), client.WithHeader("Authorization", "Bearer secret"))
+
+	assert.Nil(c.Create(context.Background(), "widgets", widget{ID: "a", Name: "cog"}, nil))
+	assert.NotNil(c.Create(context.Background(), "widgets", widget{ID: "a"}, nil))
+
+	requests := server.Requests()
+	assert.Len(requests, 2)
+	last := server.LastRequest()
+	assert.Equal(http.MethodPost, last.Method)
+	assert.Equal("/api/v1/widgets", last.URL.Path)
+	assert.Equal("Bearer secret", last.Header.Get("Authorization"))
+	assert.JSONEq(`{"id":"a","name":""}`, string(last.Body))
+}
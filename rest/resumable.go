@@ -0,0 +1,550 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tusVersion is the version of the tus resumable upload protocol implemented.
+	tusVersion = "1.0.0"
+
+	// uploadIDKey is the name of the URL path variable for a resumable upload ID.
+	uploadIDKey = "upload_id"
+
+	// uploadContentType is the Content-Type of the chunks of resumable uploads.
+	uploadContentType = "application/offset+octet-stream"
+
+	// defaultUploadExpiry is the default duration after which unfinished uploads are
+	// discarded.
+	defaultUploadExpiry = 24 * time.Hour
+)
+
+// ResumableUploadHandler is an optional interface implemented by ResourceHandlers which
+// create resources from large files uploaded in chunks with the tus protocol
+// (https://tus.io), so clients on unreliable networks can resume interrupted uploads.
+// If the Configuration's Uploads are set:
+//
+//   - POST /api/v{version}/{resource}/uploads with an Upload-Length header, and
+//     optionally a tus Upload-Metadata header, starts an upload, responding 201
+//     Created with its Location.
+//   - HEAD on the Location responds with the Upload-Offset received so far.
+//   - PATCH on the Location with an Upload-Offset header matching the offset received
+//     appends the application/offset+octet-stream body to the upload.
+//   - DELETE on the Location discards the upload.
+//
+// Once the Upload-Length has been received, CompleteUpload creates the resource and
+// the final PATCH is responded to with it and a 201 Created. Uploads are only served
+// through the routes of the resource they were started for, to the Principal which
+// started them.
+type ResumableUploadHandler interface {
+	// CompleteUpload creates the resource from the finished upload, which is deleted
+	// afterwards.
+	CompleteUpload(ctx RequestContext, upload *ResumableUpload, version string) (Resource, error)
+}
+
+// UploadConfig configures resumable uploads.
+type UploadConfig struct {
+	// Store, if set, stores the chunks of uploads. By default, they're stored in files
+	// in the temporary directory.
+	Store UploadStore
+
+	// MaxSize, if positive, is the largest Upload-Length accepted.
+	MaxSize int64
+
+	// Expiry is how long unfinished uploads are kept after they're started. Defaults
+	// to 24 hours.
+	Expiry time.Duration
+}
+
+// UploadStore stores the data of resumable uploads. Implementations must be safe for
+// concurrent use, but the calls for an upload aren't concurrent.
+type UploadStore interface {
+	// Create creates an empty upload with the ID.
+	Create(id string) error
+
+	// Append appends the chunk to the upload, returning the number of bytes appended,
+	// which are kept even if an error is returned.
+	Append(id string, chunk io.Reader) (int64, error)
+
+	// Open returns a reader of the upload's data. The caller must close it.
+	Open(id string) (io.ReadCloser, error)
+
+	// Delete deletes the upload.
+	Delete(id string) error
+}
+
+// ResumableUpload is an upload started by a client, whose data is appended in chunks.
+type ResumableUpload struct {
+	// ID identifies the upload.
+	ID string `json:"id"`
+
+	// Length is the size of the upload in bytes.
+	Length int64 `json:"length"`
+
+	// Offset is the number of bytes received.
+	Offset int64 `json:"offset"`
+
+	// Metadata are the key-value pairs of the Upload-Metadata header, e.g. the
+	// filename.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Created is when the upload was started.
+	Created time.Time `json:"created"`
+
+	store      UploadStore
+	resource   string
+	owner      string
+	busy       bool
+	completing bool
+}
+
+// Open returns a reader of the upload's data. The caller must close it.
+func (u *ResumableUpload) Open() (io.ReadCloser, error) {
+	return u.store.Open(u.ID)
+}
+
+// resumableUploadHandler returns the ResourceHandler as a ResumableUploadHandler if it
+// is one.
+func resumableUploadHandler(handler ResourceHandler) (ResumableUploadHandler, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	resumable, ok := handler.(ResumableUploadHandler)
+	return resumable, ok
+}
+
+// fileUploadStore is an UploadStore keeping each upload in a file of a directory.
+type fileUploadStore struct {
+	dir string
+}
+
+// NewFileUploadStore returns an UploadStore keeping each upload in a file of the
+// directory, which is created if needed.
+func NewFileUploadStore(dir string) UploadStore {
+	return &fileUploadStore{dir: dir}
+}
+
+// Create creates an empty file for the upload.
+func (f *fileUploadStore) Create(id string) error {
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Append appends the chunk to the file of the upload.
+func (f *fileUploadStore) Append(id string, chunk io.Reader) (int64, error) {
+	file, err := os.OpenFile(f.path(id), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(file, chunk)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// Open opens the file of the upload.
+func (f *fileUploadStore) Open(id string) (io.ReadCloser, error) {
+	return os.Open(f.path(id))
+}
+
+// Delete removes the file of the upload.
+func (f *fileUploadStore) Delete(id string) error {
+	return os.Remove(f.path(id))
+}
+
+// path returns the path of the file of the upload.
+func (f *fileUploadStore) path(id string) string {
+	return filepath.Join(f.dir, filepath.Base(id))
+}
+
+// uploadManager keeps the resumable uploads in progress.
+type uploadManager struct {
+	config  UploadConfig
+	mu      sync.Mutex
+	uploads map[string]*ResumableUpload
+}
+
+// newUploadManager returns an uploadManager with the defaults applied to the
+// configuration.
+func newUploadManager(config UploadConfig) *uploadManager {
+	if config.Store == nil {
+		config.Store = NewFileUploadStore(filepath.Join(os.TempDir(), "go-rest-uploads"))
+	}
+	if config.Expiry <= 0 {
+		config.Expiry = defaultUploadExpiry
+	}
+	return &uploadManager{config: config, uploads: map[string]*ResumableUpload{}}
+}
+
+// start starts an upload of the resource owned by the Principal with the owner ID, of
+// the length with the metadata.
+func (m *uploadManager) start(resource, owner string, length int64,
+	metadata map[string]string) (*ResumableUpload, error) {
+
+	upload := &ResumableUpload{
+		ID:       newRequestID(),
+		Length:   length,
+		Metadata: metadata,
+		Created:  time.Now().UTC(),
+		store:    m.config.Store,
+		resource: resource,
+		owner:    owner,
+	}
+	if err := m.config.Store.Create(upload.ID); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	m.uploads[upload.ID] = upload
+	return upload, nil
+}
+
+// expire discards the unfinished uploads started longer ago than the expiry. The lock
+// must be held.
+func (m *uploadManager) expire() {
+	expired := time.Now().Add(-m.config.Expiry)
+	for id, upload := range m.uploads {
+		if !upload.busy && !upload.completing && upload.Created.Before(expired) {
+			delete(m.uploads, id)
+			m.config.Store.Delete(id)
+		}
+	}
+}
+
+// lookup returns the upload with the ID of the resource owned by the Principal with the
+// owner ID. The lock must be held.
+func (m *uploadManager) lookup(resource, owner, id string) (*ResumableUpload, error) {
+	upload, ok := m.uploads[id]
+	if !ok || upload.resource != resource || upload.owner != owner {
+		return nil, ResourceNotFound(fmt.Sprintf("No upload with id %s", id))
+	}
+	return upload, nil
+}
+
+// get returns a copy of the upload with the ID of the resource owned by the Principal
+// with the owner ID.
+func (m *uploadManager) get(resource, owner, id string) (ResumableUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, err := m.lookup(resource, owner, id)
+	if err != nil {
+		return ResumableUpload{}, err
+	}
+	return *upload, nil
+}
+
+// acquire returns the upload with the ID of the resource owned by the Principal with
+// the owner ID for appending a chunk at the offset, which must be the one received so
+// far. The upload must be released afterwards.
+func (m *uploadManager) acquire(resource, owner, id string, offset int64) (*ResumableUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, err := m.lookup(resource, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.completing {
+		return nil, Conflict("The upload is being completed")
+	}
+	if upload.busy {
+		return nil, Conflict("A chunk of the upload is already being received")
+	}
+	if offset != upload.Offset {
+		return nil, Conflict(fmt.Sprintf("Upload-Offset %d doesn't match the offset %d received",
+			offset, upload.Offset))
+	}
+	upload.busy = true
+	return upload, nil
+}
+
+// release records the bytes received for the upload. If complete is true and the
+// upload is finished, it's marked completing, so no more chunks are accepted and it
+// can't be removed, and true is returned: the caller must then complete it and discard
+// it.
+func (m *uploadManager) release(upload *ResumableUpload, received int64, complete bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload.Offset += received
+	upload.busy = false
+	if complete && upload.Offset == upload.Length {
+		upload.completing = true
+	}
+	return upload.completing
+}
+
+// remove discards the upload with the ID of the resource owned by the Principal with
+// the owner ID, returning an error if there's no such upload or a chunk is being
+// received.
+func (m *uploadManager) remove(resource, owner, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, err := m.lookup(resource, owner, id)
+	if err != nil {
+		return err
+	}
+	if upload.busy || upload.completing {
+		return Conflict("A chunk of the upload is being received")
+	}
+	delete(m.uploads, id)
+	return m.config.Store.Delete(id)
+}
+
+// discard discards the completed upload with the ID.
+func (m *uploadManager) discard(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.uploads, id)
+	return m.config.Store.Delete(id)
+}
+
+// uploadsURI returns the URI at which the ResourceHandler's resumable uploads are
+// started.
+func uploadsURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/uploads"
+}
+
+// uploadURI returns the URI of the ResourceHandler's resumable uploads.
+func uploadURI(handler ResourceHandler) string {
+	return uploadsURI(handler) + "/{" + uploadIDKey + "}"
+}
+
+// uploadRoutes returns the routes of the resumable uploads of the ResourceHandler, if
+// it's a ResumableUploadHandler and uploads are enabled.
+func (h requestHandler) uploadRoutes(handler ResourceHandler,
+	middleware []RequestMiddleware) []resourceRoute {
+
+	resumable, ok := resumableUploadHandler(handler)
+	if !ok || h.uploads == nil {
+		return nil
+	}
+	resource := handler.ResourceName()
+	return []resourceRoute{
+		{
+			name: resource + ":uploadCreate", method: "POST", uri: uploadsURI(handler),
+			label: "upload create", logMethod: "POST", handlerName: "rest.ResumableUpload",
+			handler: applyMiddleware(h.handleUploadCreate(handler), middleware),
+		},
+		{
+			name: resource + ":upload", method: "HEAD", uri: uploadURI(handler),
+			label: "upload status", logMethod: "HEAD", handlerName: "rest.ResumableUpload",
+			handler: applyMiddleware(h.handleUploadStatus(handler), middleware),
+		},
+		{
+			name: resource + ":uploadChunk", method: "PATCH", uri: uploadURI(handler),
+			label: "upload chunk", logMethod: "PATCH", handlerName: "rest.ResumableUpload",
+			handler: applyMiddleware(h.handleUploadChunk(handler, resumable), middleware),
+		},
+		{
+			name: resource + ":uploadDelete", method: "DELETE", uri: uploadURI(handler),
+			label: "upload delete", logMethod: "DELETE", handlerName: "rest.ResumableUpload",
+			handler: applyMiddleware(h.handleUploadDelete(handler), middleware),
+		},
+	}
+}
+
+// handleUploadCreate returns a Handler starting resumable uploads.
+func (h requestHandler) handleUploadCreate(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		ctx.ResponseHeader().Set("Tus-Resumable", tusVersion)
+
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			h.sendResponse(ctx.setError(BadRequest("Invalid Upload-Length")))
+			return
+		}
+		if max := h.uploads.config.MaxSize; max > 0 && length > max {
+			h.sendResponse(ctx.setError(CustomError(
+				fmt.Sprintf("Upload-Length exceeds the maximum of %d bytes", max),
+				http.StatusRequestEntityTooLarge)))
+			return
+		}
+		metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		upload, err := h.uploads.start(handler.ResourceName(), principalID(ctx), length, metadata)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+		vars := ctx.PathVars()
+		vars[uploadIDKey] = upload.ID
+		if u, err := ctx.BuildURL(handler.ResourceName(), "upload", vars); err == nil {
+			ctx.ResponseHeader().Set("Location", u.String())
+		}
+		ctx.ResponseHeader().Set("Upload-Offset", "0")
+		ctx = ctx.setResult(upload)
+		ctx = ctx.setStatus(http.StatusCreated)
+		h.sendResponse(ctx)
+	})
+}
+
+// handleUploadStatus returns a Handler responding with the offset and length of
+// resumable uploads.
+func (h requestHandler) handleUploadStatus(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		w.Header().Set("Cache-Control", "no-store")
+		owner := ""
+		if p, ok := RequestPrincipal(r); ok {
+			owner = p.ID
+		}
+		upload, err := h.uploads.get(handler.ResourceName(), owner, PathVars(r)[uploadIDKey])
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// handleUploadChunk returns a Handler appending chunks to resumable uploads and
+// creating the resource once the upload is finished.
+func (h requestHandler) handleUploadChunk(handler ResourceHandler,
+	resumable ResumableUploadHandler) http.Handler {
+
+	resource, rules := handler.ResourceName(), handler.Rules()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The chunk is appended to the upload as it's read rather than buffered.
+		ctx, cancel := h.newContextWithBody(r, w, resource, &bytes.Buffer{})
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		ctx.ResponseHeader().Set("Tus-Resumable", tusVersion)
+		version := ctx.Version()
+
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), uploadContentType) {
+			h.sendResponse(ctx.setError(CustomError(
+				"Chunks must have the Content-Type "+uploadContentType,
+				http.StatusUnsupportedMediaType)))
+			return
+		}
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil {
+			h.sendResponse(ctx.setError(BadRequest("Invalid Upload-Offset")))
+			return
+		}
+		upload, err := h.uploads.acquire(resource, principalID(ctx), ctx.PathVar(uploadIDKey), offset)
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		remaining := upload.Length - upload.Offset
+		if r.ContentLength > remaining {
+			h.uploads.release(upload, 0, false)
+			h.sendResponse(ctx.setError(CustomError("Chunk exceeds the Upload-Length",
+				http.StatusRequestEntityTooLarge)))
+			return
+		}
+		received, err := upload.store.Append(upload.ID, io.LimitReader(r.Body, remaining))
+		// Only the request finishing the upload marks it completing, while the lock is
+		// held, so it's completed once.
+		completing := h.uploads.release(upload, received, err == nil)
+		ctx.ResponseHeader().Set("Upload-Offset", strconv.FormatInt(offset+received, 10))
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+		if !completing {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		finished := *upload
+		result, err := resumable.CompleteUpload(ctx, &finished, version)
+		h.uploads.discard(upload.ID)
+		if err == nil {
+			result = applyOutboundRules(result, rules, version)
+			h.publish(ctx, EventCreate, handler, result)
+		}
+		ctx = ctx.setResult(result)
+		ctx = ctx.setError(err)
+		ctx = ctx.setStatus(http.StatusCreated)
+		h.sendResponse(ctx)
+	})
+}
+
+// handleUploadDelete returns a Handler discarding resumable uploads.
+func (h requestHandler) handleUploadDelete(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		ctx.ResponseHeader().Set("Tus-Resumable", tusVersion)
+
+		err := h.uploads.remove(handler.ResourceName(), principalID(ctx), ctx.PathVar(uploadIDKey))
+		if err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// parseUploadMetadata parses the tus Upload-Metadata header: comma-separated keys,
+// each followed by a space and its base64-encoded value, if any.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, BadRequest("Invalid Upload-Metadata")
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, BadRequest("Invalid Upload-Metadata")
+			}
+			value = string(decoded)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata, nil
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// filesResourceHandler is a ResumableUploadHandler creating files from uploads.
+type filesResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (f filesResourceHandler) ResourceName() string {
+	return "files"
+}
+
+func (f filesResourceHandler) CompleteUpload(ctx RequestContext, upload *ResumableUpload,
+	version string) (Resource, error) {
+	data, err := upload.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	return Payload{"name": upload.Metadata["filename"], "content": string(content)}, nil
+}
+
+// serveUpload serves the resumable upload request with the headers.
+func serveUpload(api API, method, uri, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, uri, strings.NewReader(body))
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+// Ensures that uploads are started, resumed at the offset received and finished into
+// a resource.
+func TestResumableUpload(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Uploads: &UploadConfig{Store: NewFileUploadStore(t.TempDir())}})
+	api.RegisterResourceHandler(filesResourceHandler{})
+
+	w := serveUpload(api, "POST", "/api/v1/files/uploads", "",
+		"Upload-Length", "11", "Upload-Metadata", "filename aGVsbG8udHh0,private")
+	if !assert.Equal(http.StatusCreated, w.Code) {
+		return
+	}
+	assert.Equal(tusVersion, w.Header().Get("Tus-Resumable"))
+	upload := decodeEnvelope(w)["result"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"filename": "hello.txt", "private": ""}, upload["metadata"])
+	location := w.Header().Get("Location")
+	assert.Equal("http://example.com/api/v1/files/uploads/"+upload["id"].(string), location)
+
+	w = serveUpload(api, "PATCH", location, "hello ",
+		"Content-Type", uploadContentType, "Upload-Offset", "0")
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("6", w.Header().Get("Upload-Offset"))
+
+	w = serveUpload(api, "HEAD", location, "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("6", w.Header().Get("Upload-Offset"))
+	assert.Equal("11", w.Header().Get("Upload-Length"))
+
+	w = serveUpload(api, "PATCH", location, "world",
+		"Content-Type", uploadContentType, "Upload-Offset", "0")
+	assert.Equal(http.StatusConflict, w.Code)
+	w = serveUpload(api, "PATCH", location, "world", "Upload-Offset", "6")
+	assert.Equal(http.StatusUnsupportedMediaType, w.Code)
+	w = serveUpload(api, "PATCH", location, "world!",
+		"Content-Type", uploadContentType, "Upload-Offset", "6")
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+	w = serveUpload(api, "PATCH", location, "world",
+		"Content-Type", uploadContentType, "Upload-Offset", "6")
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal(map[string]interface{}{"name": "hello.txt", "content": "hello world"},
+		decodeEnvelope(w)["result"])
+
+	w = serveUpload(api, "HEAD", location, "")
+	assert.Equal(http.StatusNotFound, w.Code)
+}
+
+// Ensures that invalid uploads are rejected and that uploads can be discarded.
+func TestResumableUploadErrors(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Uploads: &UploadConfig{
+		Store: NewFileUploadStore(t.TempDir()), MaxSize: 100}})
+	api.RegisterResourceHandler(filesResourceHandler{})
+
+	w := serveUpload(api, "POST", "/api/v1/files/uploads", "")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	w = serveUpload(api, "POST", "/api/v1/files/uploads", "", "Upload-Length", "101")
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	w = serveUpload(api, "POST", "/api/v1/files/uploads", "",
+		"Upload-Length", "10", "Upload-Metadata", "filename not-base64!")
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	w = serveUpload(api, "POST", "/api/v1/files/uploads", "", "Upload-Length", "10")
+	location := w.Header().Get("Location")
+	w = serveUpload(api, "DELETE", location, "")
+	assert.Equal(http.StatusNoContent, w.Code)
+	w = serveUpload(api, "PATCH", location, "data",
+		"Content-Type", uploadContentType, "Upload-Offset", "0")
+	assert.Equal(http.StatusNotFound, w.Code)
+}
+
+// callerFilesResourceHandler is a filesResourceHandler for the resource authenticating
+// the caller of the X-Caller header as the request's Principal.
+type callerFilesResourceHandler struct {
+	filesResourceHandler
+	resource string
+}
+
+func (c callerFilesResourceHandler) ResourceName() string {
+	return c.resource
+}
+
+func (c callerFilesResourceHandler) Authenticate(r *http.Request) error {
+	return authenticateCaller(r)
+}
+
+// Ensures that uploads are only served to the Principal which started them, through
+// the routes of their resource.
+func TestResumableUploadOwner(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Uploads: &UploadConfig{Store: NewFileUploadStore(t.TempDir())}})
+	api.RegisterResourceHandler(callerFilesResourceHandler{resource: "files"})
+	api.RegisterResourceHandler(callerFilesResourceHandler{resource: "images"})
+
+	w := serveUpload(api, "POST", "/api/v1/files/uploads", "",
+		"Upload-Length", "5", "X-Caller", "alice")
+	if !assert.Equal(http.StatusCreated, w.Code) {
+		return
+	}
+	location := w.Header().Get("Location")
+	other := strings.Replace(location, "/files/", "/images/", 1)
+
+	for _, uri := range []string{location, other} {
+		caller := "bob"
+		if uri == other {
+			caller = "alice"
+		}
+		w = serveUpload(api, "HEAD", uri, "", "X-Caller", caller)
+		assert.Equal(http.StatusNotFound, w.Code, uri)
+		w = serveUpload(api, "PATCH", uri, "hello", "X-Caller", caller,
+			"Content-Type", uploadContentType, "Upload-Offset", "0")
+		assert.Equal(http.StatusNotFound, w.Code, uri)
+		w = serveUpload(api, "DELETE", uri, "", "X-Caller", caller)
+		assert.Equal(http.StatusNotFound, w.Code, uri)
+	}
+
+	w = serveUpload(api, "HEAD", location, "", "X-Caller", "alice")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("0", w.Header().Get("Upload-Offset"))
+	w = serveUpload(api, "PATCH", location, "hello", "X-Caller", "alice",
+		"Content-Type", uploadContentType, "Upload-Offset", "0")
+	assert.Equal(http.StatusCreated, w.Code)
+}
+
+// blockingFilesResourceHandler is a filesResourceHandler whose CompleteUpload counts
+// its calls and waits until released.
+type blockingFilesResourceHandler struct {
+	filesResourceHandler
+	completed *int32
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func (b blockingFilesResourceHandler) CompleteUpload(ctx RequestContext, upload *ResumableUpload,
+	version string) (Resource, error) {
+	atomic.AddInt32(b.completed, 1)
+	b.started <- struct{}{}
+	<-b.release
+	return b.filesResourceHandler.CompleteUpload(ctx, upload, version)
+}
+
+// Ensures that an upload is completed once, even if another request is made for it
+// while it's being completed.
+func TestResumableUploadConcurrentCompletion(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Uploads: &UploadConfig{Store: NewFileUploadStore(t.TempDir())}})
+	handler := blockingFilesResourceHandler{completed: new(int32),
+		started: make(chan struct{}, 2), release: make(chan struct{})}
+	api.RegisterResourceHandler(handler)
+
+	w := serveUpload(api, "POST", "/api/v1/files/uploads", "", "Upload-Length", "5")
+	location := w.Header().Get("Location")
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		first <- serveUpload(api, "PATCH", location, "hello",
+			"Content-Type", uploadContentType, "Upload-Offset", "0")
+	}()
+	select {
+	case <-handler.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Upload wasn't completed")
+	}
+
+	second := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		second <- serveUpload(api, "PATCH", location, "",
+			"Content-Type", uploadContentType, "Upload-Offset", "5")
+	}()
+	select {
+	case w := <-second:
+		assert.Equal(http.StatusConflict, w.Code)
+	case <-handler.started:
+		assert.Fail("Upload was completed twice")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request wasn't responded to")
+	}
+	w = serveUpload(api, "DELETE", location, "")
+	assert.Equal(http.StatusConflict, w.Code)
+
+	close(handler.release)
+	assert.Equal(http.StatusCreated, (<-first).Code)
+	assert.Equal(int32(1), atomic.LoadInt32(handler.completed))
+}
+
+// Ensures that upload routes are only registered for ResumableUploadHandlers when
+// uploads are configured.
+func TestResumableUploadRoutes(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(filesResourceHandler{})
+	w := serveUpload(api, "POST", "/api/v1/files/uploads", "", "Upload-Length", "10")
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	api = NewAPI(&Configuration{Uploads: &UploadConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	w = serveUpload(api, "POST", "/api/v1/widgets/uploads", "", "Upload-Length", "10")
+	assert.NotEqual(http.StatusCreated, w.Code)
+}
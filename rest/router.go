@@ -0,0 +1,367 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultVarPattern is the pattern matched by path variables which don't specify one.
+const defaultVarPattern = "[^/]+"
+
+// routerContextKey is the type of the keys of values the framework stores in request
+// contexts.
+type routerContextKey int
+
+// pathVarsKey is the request context key of the path variables of the matched route.
+const pathVarsKey routerContextKey = iota
+
+// Router dispatches requests to the handlers of the routes registered with an API. By
+// default, gorilla/mux is used, but any Router can be set in the Configuration, such
+// as one returned by NewServeMuxRouter, NewChiRouter or NewHTTPRouter, to use a
+// faster router or to integrate with an existing one.
+type Router interface {
+	http.Handler
+
+	// Handle registers the route, returning an error if the Router can't serve it.
+	// Routers whose patterns are less specific than the route's path template, for
+	// example because they don't support variable patterns, must only serve requests
+	// for which the route's Match method succeeds, using the returned request.
+	Handle(RouterRoute) error
+}
+
+// RouterRoute is a route registered with a Router.
+type RouterRoute struct {
+	// Name is the name of the route, e.g. "widgets:read", or empty if it's unnamed.
+	Name string
+
+	// Method is the HTTP method matched by the route. If empty, any method is
+	// matched.
+	Method string
+
+	// Path is the path template matched by the route, in the syntax used by the URI
+	// methods of ResourceHandlers, e.g. /api/v{version:[^/]+}/widgets/{resource_id}.
+	Path string
+
+	// PathPrefix indicates if the route matches any path beginning with Path.
+	PathPrefix bool
+
+	// Headers are the header values the route requires requests to have. An empty
+	// value requires the header to be present with any value.
+	Headers map[string]string
+
+	// Handler handles the requests matched by the route.
+	Handler http.Handler
+
+	template *routeTemplate
+}
+
+// newRouterRoute returns a RouterRoute requiring the header values given as pairs of
+// keys and values.
+func newRouterRoute(name, method, path string, prefix bool, handler http.Handler,
+	headers ...string) RouterRoute {
+
+	route := RouterRoute{
+		Name:       name,
+		Method:     method,
+		Path:       path,
+		PathPrefix: prefix,
+		Handler:    handler,
+	}
+	if len(headers) > 0 {
+		route.Headers = map[string]string{}
+		for i := 0; i+1 < len(headers); i += 2 {
+			route.Headers[headers[i]] = headers[i+1]
+		}
+	}
+	return route
+}
+
+// Match reports whether the request matches the route's method, headers and path
+// template, including the patterns of its variables. If so, the request is returned
+// with the route's path variables available through PathVars.
+func (r RouterRoute) Match(req *http.Request) (*http.Request, bool) {
+	if r.Method != "" && req.Method != r.Method {
+		return nil, false
+	}
+	for key, value := range r.Headers {
+		values, ok := req.Header[textproto.CanonicalMIMEHeaderKey(key)]
+		if !ok || (value != "" && !contains(values, value)) {
+			return nil, false
+		}
+	}
+	template, err := r.parsedTemplate()
+	if err != nil {
+		return nil, false
+	}
+	vars, ok := template.match(req.URL.Path)
+	if !ok {
+		return nil, false
+	}
+	return req.WithContext(context.WithValue(req.Context(), pathVarsKey, vars)), true
+}
+
+// parsedTemplate returns the route's parsed path template.
+func (r RouterRoute) parsedTemplate() (*routeTemplate, error) {
+	if r.template != nil {
+		return r.template, nil
+	}
+	return parseRouteTemplate(r.Path, r.PathPrefix)
+}
+
+// PathVars returns the path variables of the route matched for the request.
+func PathVars(r *http.Request) RouteVars {
+	if vars, ok := r.Context().Value(pathVarsKey).(RouteVars); ok {
+		return vars
+	}
+	return mux.Vars(r)
+}
+
+// routeTemplate is a parsed path template.
+type routeTemplate struct {
+	path     string
+	prefix   bool
+	regexp   *regexp.Regexp
+	vars     []string
+	patterns []*regexp.Regexp
+	segments []templateSegment
+}
+
+// templateSegment is a /-separated segment of a path template.
+type templateSegment struct {
+	// literal is the segment's text if it has no variables.
+	literal string
+
+	// variable indicates if the segment contains variables.
+	variable bool
+
+	// slash indicates if the segment's variables can match slashes, in which case it
+	// can span multiple segments of a request path.
+	slash bool
+}
+
+// parseRouteTemplate parses the path template, which is matched against entire
+// request paths unless prefix is true.
+func parseRouteTemplate(path string, prefix bool) (*routeTemplate, error) {
+	t := &routeTemplate{path: path, prefix: prefix}
+	expr := &strings.Builder{}
+	expr.WriteString("^")
+	segment := templateSegment{}
+	text := &strings.Builder{}
+
+	endSegment := func() {
+		if !segment.variable {
+			segment.literal = text.String()
+		}
+		t.segments = append(t.segments, segment)
+		segment = templateSegment{}
+		text.Reset()
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '{':
+			end, depth := i, 0
+			for ; end < len(path); end++ {
+				if path[end] == '{' {
+					depth++
+				} else if path[end] == '}' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if end == len(path) {
+				return nil, fmt.Errorf("unbalanced braces in route %q", path)
+			}
+			name, pattern := path[i+1:end], defaultVarPattern
+			if colon := strings.Index(name, ":"); colon >= 0 {
+				name, pattern = name[:colon], name[colon+1:]
+			}
+			if name == "" || pattern == "" {
+				return nil, fmt.Errorf("missing name or pattern in route %q", path)
+			}
+			anchored, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern in route %q: %s", path, err)
+			}
+			if anchored.NumSubexp() > 0 {
+				return nil, fmt.Errorf("route %q contains capture groups in its patterns; "+
+					"only non-capturing groups are accepted", path)
+			}
+			t.vars = append(t.vars, name)
+			t.patterns = append(t.patterns, anchored)
+			segment.variable = true
+			segment.slash = segment.slash || anchored.MatchString("/") || anchored.MatchString("a/b")
+			expr.WriteString("(" + pattern + ")")
+			i = end
+		case '}':
+			return nil, fmt.Errorf("unbalanced braces in route %q", path)
+		case '/':
+			if i > 0 {
+				endSegment()
+			}
+			expr.WriteByte('/')
+		default:
+			text.WriteByte(c)
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if len(path) > 0 {
+		endSegment()
+	}
+	if !prefix {
+		expr.WriteString("$")
+	}
+
+	compiled, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid route %q: %s", path, err)
+	}
+	t.regexp = compiled
+	return t, nil
+}
+
+// match returns the path variables if the path matches the template.
+func (t *routeTemplate) match(path string) (RouteVars, bool) {
+	matches := t.regexp.FindStringSubmatch(path)
+	if matches == nil {
+		return nil, false
+	}
+	vars := make(RouteVars, len(t.vars))
+	for i, name := range t.vars {
+		vars[name] = matches[i+1]
+	}
+	return vars, true
+}
+
+// url returns the path with the template's variables substituted, returning an error
+// if a variable is missing or its value doesn't match the variable's pattern.
+func (t *routeTemplate) url(vars RouteVars) (string, error) {
+	path := &strings.Builder{}
+	index := 0
+	for i := 0; i < len(t.path); i++ {
+		if t.path[i] != '{' {
+			path.WriteByte(t.path[i])
+			continue
+		}
+		for depth := 0; i < len(t.path); i++ {
+			if t.path[i] == '{' {
+				depth++
+			} else if t.path[i] == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		name := t.vars[index]
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("missing route variable %q", name)
+		}
+		if !t.patterns[index].MatchString(value) {
+			return "", fmt.Errorf("variable %q doesn't match, expected %q", value,
+				t.patterns[index].String())
+		}
+		path.WriteString(value)
+		index++
+	}
+	return path.String(), nil
+}
+
+// pattern returns a pattern matching the paths matched by the template for Routers
+// which only support variables spanning entire segments, using wildcard to name the
+// variable of the segment at a given index. If the template matches paths beginning
+// with it, or a segment's variables can span multiple segments, the pattern ends with
+// the result of rest and true is returned. If the template is a prefix without a
+// trailing slash, exact is the pattern matching the prefix itself.
+func (t *routeTemplate) pattern(wildcard, rest func(int) string) (pattern, exact string, catchAll bool) {
+	b := &strings.Builder{}
+	for i, segment := range t.segments {
+		b.WriteByte('/')
+		switch {
+		case segment.slash:
+			b.WriteString(rest(i))
+			return b.String(), "", true
+		case segment.variable:
+			b.WriteString(wildcard(i))
+		default:
+			b.WriteString(segment.literal)
+		}
+	}
+	if !t.prefix {
+		return b.String(), "", false
+	}
+	if !strings.HasSuffix(t.path, "/") {
+		exact = b.String()
+		b.WriteByte('/')
+	}
+	b.WriteString(rest(len(t.segments)))
+	return b.String(), exact, true
+}
+
+// routeGroup is an http.Handler dispatching requests to the first of its routes
+// which matches. It serves the routes registered with Routers under the same, less
+// specific, pattern.
+type routeGroup struct {
+	routes []RouterRoute
+}
+
+// ServeHTTP dispatches the request to the first matching route, responding with a 404
+// if there is none.
+func (g *routeGroup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.serve(w, r) {
+		http.NotFound(w, r)
+	}
+}
+
+// serve dispatches the request to the first matching route and reports whether there
+// was one.
+func (g *routeGroup) serve(w http.ResponseWriter, r *http.Request) bool {
+	for _, route := range g.routes {
+		if req, ok := route.Match(r); ok {
+			route.Handler.ServeHTTP(w, req)
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the request matches any of the routes.
+func (g *routeGroup) match(r *http.Request) bool {
+	for _, route := range g.routes {
+		if _, ok := route.Match(r); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// urlBuilder builds the URLs of named routes.
+type urlBuilder interface {
+	// buildURL returns the URL of the named route with its path variables
+	// substituted.
+	buildURL(name string, vars RouteVars) (*url.URL, error)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// routerMethods are the methods for which routes matching any method are registered
+// with Routers requiring one.
+var routerMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// muxRouter is a Router backed by a gorilla/mux Router.
+type muxRouter struct {
+	router *mux.Router
+}
+
+// NewMuxRouter returns a Router registering routes with the gorilla/mux Router. This
+// is the Router used when none is configured.
+func NewMuxRouter(router *mux.Router) Router {
+	return &muxRouter{router}
+}
+
+// Handle registers the route with the gorilla/mux Router.
+func (m *muxRouter) Handle(route RouterRoute) error {
+	r := m.router.NewRoute()
+	if route.PathPrefix {
+		r = r.PathPrefix(route.Path)
+	} else {
+		r = r.Path(route.Path)
+	}
+	if route.Method != "" {
+		r = r.Methods(route.Method)
+	}
+	for key, value := range route.Headers {
+		r = r.Headers(key, value)
+	}
+	if route.Name != "" {
+		r = r.Name(route.Name)
+	}
+	r.Handler(route.Handler)
+	return r.GetError()
+}
+
+// ServeHTTP dispatches the request using the gorilla/mux Router.
+func (m *muxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.router.ServeHTTP(w, r)
+}
+
+// buildURL returns the URL of the named gorilla/mux route with its path variables
+// substituted.
+func (m *muxRouter) buildURL(name string, vars RouteVars) (*url.URL, error) {
+	route := m.router.Get(name)
+	if route == nil {
+		return nil, fmt.Errorf("no route named %q", name)
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for key, val := range vars {
+		pairs = append(pairs, key, val)
+	}
+	return route.URL(pairs...)
+}
+
+// groupedRouter registers routes with a router only supporting variables spanning
+// entire path segments. Routes are registered under less specific patterns and
+// requests are dispatched to the first route under the pattern they match.
+type groupedRouter struct {
+	groups   map[string]*routeGroup
+	register func(key string, group *routeGroup)
+}
+
+// handle adds the route to the group of each pattern key, registering new groups.
+// Routers panic on invalid or conflicting patterns, which is returned as an error.
+func (g *groupedRouter) handle(route RouterRoute, keys ...string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid route %q: %v", route.Path, r)
+		}
+	}()
+	for _, key := range keys {
+		group, ok := g.groups[key]
+		if !ok {
+			group = &routeGroup{}
+			g.register(key, group)
+			g.groups[key] = group
+		}
+		group.routes = append(group.routes, route)
+	}
+	return nil
+}
+
+// methodKeys returns the keys of the pattern for the route's method, using key to
+// combine them.
+func methodKeys(route RouterRoute, key func(method string) string) []string {
+	if route.Method != "" {
+		return []string{key(route.Method)}
+	}
+	keys := make([]string, len(routerMethods))
+	for i, method := range routerMethods {
+		keys[i] = key(method)
+	}
+	return keys
+}
+
+// serveMuxRouter is a Router backed by a net/http ServeMux.
+type serveMuxRouter struct {
+	*groupedRouter
+	mux *http.ServeMux
+}
+
+// NewServeMuxRouter returns a Router registering routes with the ServeMux using the
+// method and wildcard patterns supported since Go 1.22.
+func NewServeMuxRouter(mux *http.ServeMux) Router {
+	s := &serveMuxRouter{mux: mux}
+	s.groupedRouter = &groupedRouter{
+		groups: map[string]*routeGroup{},
+		register: func(pattern string, group *routeGroup) {
+			mux.Handle(pattern, group)
+		},
+	}
+	return s
+}
+
+// Handle registers the route with the ServeMux.
+func (s *serveMuxRouter) Handle(route RouterRoute) error {
+	template, err := route.parsedTemplate()
+	if err != nil {
+		return err
+	}
+	pattern, exact, catchAll := template.pattern(
+		func(i int) string { return fmt.Sprintf("{p%d}", i) },
+		func(i int) string { return fmt.Sprintf("{p%d...}", i) },
+	)
+	if !catchAll && strings.HasSuffix(pattern, "/") {
+		// Patterns ending in a slash otherwise match any path beginning with them.
+		pattern += "{$}"
+	}
+	patterns := []string{pattern}
+	if exact != "" {
+		patterns = append(patterns, exact)
+	}
+
+	keys := []string{}
+	for _, pattern := range patterns {
+		if route.Method != "" {
+			pattern = route.Method + " " + pattern
+		}
+		keys = append(keys, pattern)
+	}
+	return s.handle(route, keys...)
+}
+
+// ServeHTTP dispatches the request using the ServeMux.
+func (s *serveMuxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ChiMux is the subset of the chi.Router interface used by NewChiRouter, which is
+// implemented by *chi.Mux.
+type ChiMux interface {
+	http.Handler
+
+	// Handle registers the handler for requests with any method matching the pattern.
+	Handle(pattern string, h http.Handler)
+
+	// Method registers the handler for requests with the method matching the pattern.
+	Method(method, pattern string, h http.Handler)
+}
+
+// chiRouter is a Router backed by a chi router.
+type chiRouter struct {
+	*groupedRouter
+	router ChiMux
+}
+
+// NewChiRouter returns a Router registering routes with the chi router, e.g.
+// NewChiRouter(chi.NewRouter()).
+func NewChiRouter(router ChiMux) Router {
+	c := &chiRouter{router: router}
+	c.groupedRouter = &groupedRouter{
+		groups: map[string]*routeGroup{},
+		register: func(key string, group *routeGroup) {
+			parts := strings.SplitN(key, " ", 2)
+			if parts[0] == "" {
+				router.Handle(parts[1], group)
+			} else {
+				router.Method(parts[0], parts[1], group)
+			}
+		},
+	}
+	return c
+}
+
+// Handle registers the route with the chi router.
+func (c *chiRouter) Handle(route RouterRoute) error {
+	template, err := route.parsedTemplate()
+	if err != nil {
+		return err
+	}
+	pattern, exact, _ := template.pattern(
+		func(i int) string { return fmt.Sprintf("{p%d}", i) },
+		func(int) string { return "*" },
+	)
+	keys := []string{route.Method + " " + pattern}
+	if exact != "" {
+		keys = append(keys, route.Method+" "+exact)
+	}
+	return c.handle(route, keys...)
+}
+
+// ServeHTTP dispatches the request using the chi router.
+func (c *chiRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.router.ServeHTTP(w, r)
+}
+
+// HTTPRouterMux is the subset of the methods of *httprouter.Router used by
+// NewHTTPRouter.
+type HTTPRouterMux interface {
+	http.Handler
+
+	// Handler registers the handler for requests with the method matching the path.
+	Handler(method, path string, handler http.Handler)
+}
+
+// httpRouter is a Router backed by an httprouter Router.
+type httpRouter struct {
+	*groupedRouter
+	router   HTTPRouterMux
+	prefixes *routeGroup
+}
+
+// NewHTTPRouter returns a Router registering routes with the httprouter Router, e.g.
+// NewHTTPRouter(httprouter.New()). As httprouter rejects a literal path segment and
+// a variable in the same position, routes are registered by the number of path
+// segments they match, with every segment a variable. Routes matching path prefixes
+// are matched before the httprouter Router.
+func NewHTTPRouter(router HTTPRouterMux) Router {
+	h := &httpRouter{router: router, prefixes: &routeGroup{}}
+	h.groupedRouter = &groupedRouter{
+		groups: map[string]*routeGroup{},
+		register: func(key string, group *routeGroup) {
+			parts := strings.SplitN(key, " ", 2)
+			router.Handler(parts[0], parts[1], group)
+		},
+	}
+	return h
+}
+
+// Handle registers the route with the httprouter Router.
+func (h *httpRouter) Handle(route RouterRoute) error {
+	template, err := route.parsedTemplate()
+	if err != nil {
+		return err
+	}
+	if template.prefix {
+		h.prefixes.routes = append(h.prefixes.routes, route)
+		return nil
+	}
+
+	path := &strings.Builder{}
+	for i, segment := range template.segments {
+		path.WriteByte('/')
+		if segment.slash {
+			h.prefixes.routes = append(h.prefixes.routes, route)
+			return nil
+		}
+		if segment.variable || segment.literal != "" {
+			fmt.Fprintf(path, ":p%d", i)
+		}
+	}
+	return h.handle(route, methodKeys(route, func(method string) string {
+		return method + " " + path.String()
+	})...)
+}
+
+// ServeHTTP dispatches the request to the first matching route with a path prefix,
+// or using the httprouter Router if there is none.
+func (h *httpRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.prefixes.serve(w, r) {
+		h.router.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSegmentRouter is a minimal router matching patterns segment by segment, where
+// segments beginning with ':' or '{' are variables and '*' matches the rest of the
+// path, standing in for chi and httprouter.
+type fakeSegmentRouter struct {
+	methods  []string
+	patterns []string
+	handlers []http.Handler
+}
+
+func (f *fakeSegmentRouter) Handle(pattern string, h http.Handler) {
+	f.Method("", pattern, h)
+}
+
+func (f *fakeSegmentRouter) Method(method, pattern string, h http.Handler) {
+	for i := range f.patterns {
+		if f.methods[i] == method && f.patterns[i] == pattern {
+			panic("duplicate pattern " + pattern)
+		}
+	}
+	f.methods = append(f.methods, method)
+	f.patterns = append(f.patterns, pattern)
+	f.handlers = append(f.handlers, h)
+}
+
+func (f *fakeSegmentRouter) Handler(method, path string, h http.Handler) {
+	f.Method(method, path, h)
+}
+
+func (f *fakeSegmentRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for i, pattern := range f.patterns {
+		if f.methods[i] != "" && f.methods[i] != r.Method {
+			continue
+		}
+		if segmentsMatch(strings.Split(pattern, "/"), strings.Split(r.URL.Path, "/")) {
+			f.handlers[i].ServeHTTP(w, r)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func segmentsMatch(pattern, path []string) bool {
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		variable := strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "{")
+		if variable && path[i] == "" || !variable && segment != path[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// routerBackends returns a Router of each kind supported.
+func routerBackends() map[string]func() Router {
+	return map[string]func() Router{
+		"mux":        func() Router { return NewMuxRouter(mux.NewRouter()) },
+		"servemux":   func() Router { return NewServeMuxRouter(http.NewServeMux()) },
+		"chi":        func() Router { return NewChiRouter(&fakeSegmentRouter{}) },
+		"httprouter": func() Router { return NewHTTPRouter(&fakeSegmentRouter{}) },
+	}
+}
+
+// serveRouterRequest sends a request to the API and returns the response.
+func serveRouterRequest(api API, method, path string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "http://example.com"+path, strings.NewReader("{}"))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+// Ensures that APIs serve resource, method override, discovery, prefix and handler
+// routes with each Router.
+func TestRouterBackends(t *testing.T) {
+	for name, newRouter := range routerBackends() {
+		assert := assert.New(t)
+		api := NewAPI(&Configuration{
+			Router:          newRouter(),
+			ExplorerPath:    "/explorer",
+			HypermediaLinks: true,
+		})
+		api.RegisterResourceHandler(TestResourceHandler{})
+		api.RegisterHandlerFunc("/ping/{name}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("pong " + PathVars(r)["name"]))
+		})
+
+		w := serveRouterRequest(api, "GET", "/api/v1/widgets/42")
+		assert.Equal(http.StatusOK, w.Code, name)
+		var payload map[string]interface{}
+		assert.Nil(json.Unmarshal(w.Body.Bytes(), &payload), name)
+		assert.Equal(map[string]interface{}{
+			"self":       "http://example.com/api/v1/widgets/42",
+			"collection": "http://example.com/api/v1/widgets",
+		}, payload["links"], name)
+
+		w = serveRouterRequest(api, "GET", "/api/v1/widgets/schema")
+		assert.Equal("application/schema+json", w.Header().Get("Content-Type"), name)
+
+		w = serveRouterRequest(api, "POST", "/api/v1/widgets")
+		assert.Equal(http.StatusCreated, w.Code, name)
+
+		w = serveRouterRequest(api, "POST", "/api/v1/widgets/42", "X-HTTP-Method-Override", "GET")
+		assert.Equal(http.StatusOK, w.Code, name)
+
+		w = serveRouterRequest(api, "GET", "/api/v1/")
+		assert.Equal(http.StatusOK, w.Code, name)
+		assert.Contains(w.Body.String(), `"href":"/api/v1/widgets/{resource_id}"`, name)
+
+		w = serveRouterRequest(api, "GET", "/explorer")
+		assert.Equal(http.StatusOK, w.Code, name)
+		w = serveRouterRequest(api, "GET", "/explorer/explorer.js")
+		assert.Equal(http.StatusOK, w.Code, name)
+
+		w = serveRouterRequest(api, "GET", "/ping/bob")
+		assert.Equal("pong bob", w.Body.String(), name)
+
+		w = serveRouterRequest(api, "GET", "/api/v1/gadgets")
+		assert.Equal(http.StatusNotFound, w.Code, name)
+	}
+}
+
+// Ensures that routes are registered with chi and httprouter under patterns of whole
+// segment variables.
+func TestRouterBackendPatterns(t *testing.T) {
+	assert := assert.New(t)
+	route := newRouterRoute("", "GET", "/api/v{version:[^/]+}/widgets/{id}", false, http.NotFoundHandler())
+
+	chi := &fakeSegmentRouter{}
+	assert.Nil(NewChiRouter(chi).Handle(route))
+	assert.Equal([]string{"/api/{p1}/widgets/{p3}"}, chi.patterns)
+
+	router := &fakeSegmentRouter{}
+	assert.Nil(NewHTTPRouter(router).Handle(route))
+	assert.Equal([]string{"/:p0/:p1/:p2/:p3"}, router.patterns)
+
+	any := newRouterRoute("", "", "/ping", false, http.NotFoundHandler())
+	router = &fakeSegmentRouter{}
+	assert.Nil(NewHTTPRouter(router).Handle(any))
+	assert.Equal(routerMethods, router.methods)
+}
+
+// Ensures that Routers return errors for routes they can't serve.
+func TestRouterBackendErrors(t *testing.T) {
+	assert := assert.New(t)
+	servemux := NewServeMuxRouter(http.NewServeMux())
+	assert.Nil(servemux.Handle(newRouterRoute("", "GET", "/a/{x}", false, http.NotFoundHandler())))
+	assert.NotNil(servemux.Handle(newRouterRoute("", "GET", "/{y}/b", false, http.NotFoundHandler())))
+
+	api := NewAPI(&Configuration{Router: NewServeMuxRouter(http.NewServeMux())})
+	api.RegisterHandlerFunc("/{id", http.NotFound)
+	assert.Len(api.Routes(), 2)
+}
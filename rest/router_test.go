@@ -0,0 +1,156 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that path templates match paths and extract their variables.
+func TestRouteTemplateMatch(t *testing.T) {
+	assert := assert.New(t)
+	template, err := parseRouteTemplate("/api/v{version:[^/]+}/widgets/{id:[0-9]{2,}}", false)
+	assert.Nil(err)
+
+	vars, ok := template.match("/api/v1/widgets/42")
+	assert.True(ok)
+	assert.Equal(RouteVars{"version": "1", "id": "42"}, vars)
+
+	_, ok = template.match("/api/v1/widgets/4")
+	assert.False(ok)
+	_, ok = template.match("/api/v1/widgets/42/parts")
+	assert.False(ok)
+
+	prefix, err := parseRouteTemplate("/static/{dir}", true)
+	assert.Nil(err)
+	vars, ok = prefix.match("/static/css/site.css")
+	assert.True(ok)
+	assert.Equal(RouteVars{"dir": "css"}, vars)
+}
+
+// Ensures that invalid path templates are rejected.
+func TestRouteTemplateInvalid(t *testing.T) {
+	assert := assert.New(t)
+	for _, path := range []string{"/{id", "/id}", "/{}", "/{id:}", "/{id:(a|b)}", "/{id:[}"} {
+		_, err := parseRouteTemplate(path, false)
+		assert.NotNil(err, path)
+	}
+	_, err := parseRouteTemplate("/{id:(?:a|b)}", false)
+	assert.Nil(err)
+}
+
+// Ensures that URLs are built from path templates, validating variables.
+func TestRouteTemplateURL(t *testing.T) {
+	assert := assert.New(t)
+	template, _ := parseRouteTemplate("/api/v{version:[^/]+}/widgets/{id:[0-9]+}", false)
+
+	url, err := template.url(RouteVars{"version": "2", "id": "7", "extra": "x"})
+	assert.Nil(err)
+	assert.Equal("/api/v2/widgets/7", url)
+
+	_, err = template.url(RouteVars{"version": "2"})
+	assert.NotNil(err)
+	_, err = template.url(RouteVars{"version": "2", "id": "seven"})
+	assert.NotNil(err)
+}
+
+// Ensures that path templates are converted to patterns of whole segment variables.
+func TestRouteTemplatePattern(t *testing.T) {
+	assert := assert.New(t)
+	wildcard := func(i int) string { return "{w}" }
+	rest := func(i int) string { return "*" }
+
+	for _, test := range []struct {
+		path     string
+		prefix   bool
+		pattern  string
+		exact    string
+		catchAll bool
+	}{
+		{"/api/v{version:[^/]+}/widgets/{id}", false, "/api/{w}/widgets/{w}", "", false},
+		{"/api/v{version:[^/]+}/", false, "/api/{w}/", "", false},
+		{"/files/{path:.*}", false, "/files/*", "", true},
+		{"/explorer", true, "/explorer/*", "/explorer", true},
+		{"/static/", true, "/static/*", "", true},
+	} {
+		template, err := parseRouteTemplate(test.path, test.prefix)
+		assert.Nil(err)
+		pattern, exact, catchAll := template.pattern(wildcard, rest)
+		assert.Equal(test.pattern, pattern, test.path)
+		assert.Equal(test.exact, exact, test.path)
+		assert.Equal(test.catchAll, catchAll, test.path)
+	}
+}
+
+// Ensures that RouterRoute.Match checks the method, headers and path and sets the path
+// variables of the request.
+func TestRouterRouteMatch(t *testing.T) {
+	assert := assert.New(t)
+	route := newRouterRoute("", "POST", "/widgets/{id}", false, nil, "X-HTTP-Method-Override", "GET")
+
+	req, _ := http.NewRequest("POST", "http://example.com/widgets/1", nil)
+	_, ok := route.Match(req)
+	assert.False(ok)
+
+	req.Header.Set("X-Http-Method-Override", "GET")
+	matched, ok := route.Match(req)
+	assert.True(ok)
+	assert.Equal(RouteVars{"id": "1"}, PathVars(matched))
+
+	req.Method = "PUT"
+	_, ok = route.Match(req)
+	assert.False(ok)
+}
+
+// Ensures that PathVars falls back to the variables of gorilla/mux routes.
+func TestPathVarsMux(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/widgets/1", nil)
+	assert.Len(PathVars(req), 0)
+	assert.Equal(RouteVars{"id": "1"}, PathVars(mux.SetURLVars(req, map[string]string{"id": "1"})))
+}
+
+// Ensures that routeGroup dispatches requests to the first matching route.
+func TestRouteGroup(t *testing.T) {
+	assert := assert.New(t)
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + ":" + PathVars(r)["id"]))
+		})
+	}
+	group := &routeGroup{routes: []RouterRoute{
+		newRouterRoute("", "GET", "/widgets/schema", false, handler("schema")),
+		newRouterRoute("", "GET", "/widgets/{id}", false, handler("read")),
+	}}
+
+	for path, expected := range map[string]string{"/widgets/schema": "schema:", "/widgets/1": "read:1"} {
+		req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+		w := httptest.NewRecorder()
+		group.ServeHTTP(w, req)
+		assert.Equal(expected, w.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com/gadgets/1", nil)
+	w := httptest.NewRecorder()
+	group.ServeHTTP(w, req)
+	assert.Equal(http.StatusNotFound, w.Code)
+}
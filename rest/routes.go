@@ -0,0 +1,198 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Route describes a route registered with an API, for introspection and debugging.
+type Route struct {
+	// Name is the name of the route, e.g. "widgets:read", or empty if it's unnamed.
+	Name string
+
+	// Methods are the HTTP methods matched by the route. If empty, any method is
+	// matched.
+	Methods []string
+
+	// Path is the path template matched by the route.
+	Path string
+
+	// PathPrefix indicates if the route matches any path beginning with Path.
+	PathPrefix bool
+
+	// Handler names the function handling requests, e.g. "*main.widgetHandler.ReadResource".
+	Handler string
+
+	// Middleware names the RequestMiddleware applied to requests, in the order applied.
+	Middleware []string
+
+	// Timeout is the maximum duration handlers have to handle requests, or zero if
+	// there is none.
+	Timeout time.Duration
+}
+
+// funcSuffixRegex matches the suffixes of the names of closures and method values.
+var funcSuffixRegex = regexp.MustCompile(`(\.func\d+|-fm)+$`)
+
+// funcName returns the name of the function, qualified by its package name, e.g.
+// "rest.newAuthMiddleware" for a closure returned by newAuthMiddleware.
+func funcName(fn interface{}) string {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(value.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return funcSuffixRegex.ReplaceAllString(name, "")
+}
+
+// handle registers the route with the Router and records it for introspection through
+// Routes and for building URLs, returning an error if it couldn't be registered.
+func (r *muxAPI) handle(route RouterRoute, handler string, middleware []RequestMiddleware,
+	timeout time.Duration) error {
+
+	template, err := parseRouteTemplate(route.Path, route.PathPrefix)
+	if err != nil {
+		return err
+	}
+	route.template = template
+	if r.config.Instrumentation && route.Name != "" {
+		stats := &routeStats{}
+		route.Handler = instrument(route.Name, stats, route.Handler)
+		r.mu.Lock()
+		r.stats[route.Name] = stats
+		r.mu.Unlock()
+	}
+	route.Handler = markMatched(r.handler.guardHeap(route.Handler))
+	r.routerMu.Lock()
+	err = r.router.Handle(route)
+	r.routerMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	info := Route{
+		Name:       route.Name,
+		Path:       route.Path,
+		PathPrefix: route.PathPrefix,
+		Handler:    handler,
+		Timeout:    timeout,
+	}
+	if route.Method != "" {
+		info.Methods = []string{route.Method}
+	}
+	for _, m := range middleware {
+		info.Middleware = append(info.Middleware, funcName(m))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, info)
+	r.matchers.routes = append(r.matchers.routes, route)
+	if first := template.segments[0]; !first.variable && first.literal != "" {
+		r.claimed[first.literal] = struct{}{}
+	}
+	if route.Name != "" {
+		r.templates[route.Name] = template
+	}
+	return nil
+}
+
+// unmatchedRoute returns the Handler of requests which don't match any route, counted
+// in the Stats under the name if Instrumentation is enabled.
+func (r *muxAPI) unmatchedRoute(name string, handler http.Handler) http.Handler {
+	if r.config.Instrumentation {
+		stats := &routeStats{}
+		handler = instrument(name, stats, handler)
+		r.mu.Lock()
+		r.stats[name] = stats
+		r.mu.Unlock()
+	}
+	return r.handler.guardHeap(handler)
+}
+
+// buildURL returns the URL of the named route with its path variables substituted.
+func (r *muxAPI) buildURL(name string, vars RouteVars) (*url.URL, error) {
+	r.mu.RLock()
+	template, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no route named %q", name)
+	}
+	path, err := template.url(vars)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Path: path}, nil
+}
+
+// Routes returns the routes registered with the API in the order they're matched.
+func (r *muxAPI) Routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		if !r.unregisteredRoute(route.Name) {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// handleRoutes returns a Handler which lists the routes registered with the API, in
+// the order they're matched, to help debug why requests aren't routed as expected.
+func (h requestHandler) handleRoutes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, "")
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		routes := []interface{}{}
+		for _, route := range h.Routes() {
+			info := map[string]interface{}{
+				"name":        route.Name,
+				"methods":     route.Methods,
+				"path":        route.Path,
+				"path_prefix": route.PathPrefix,
+				"handler":     route.Handler,
+				"middleware":  route.Middleware,
+			}
+			if route.Timeout > 0 {
+				info["timeout"] = route.Timeout.String()
+			}
+			routes = append(routes, info)
+		}
+
+		ctx = ctx.setResult(routes)
+		ctx = ctx.setStatus(http.StatusOK)
+		h.sendResponse(ctx)
+	})
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tracingMiddleware is RequestMiddleware used to test middleware names.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return next
+}
+
+// Ensures that Routes lists registered routes in the order they're matched, with
+// their handlers, middleware and timeouts.
+func TestRoutes(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{RequestTimeout: time.Second})
+	api.RegisterResourceHandler(TestResourceHandler{}, tracingMiddleware)
+	api.RegisterPathPrefix("/static/", http.NotFound)
+
+	routes := api.Routes()
+	assert.Equal("openapi", routes[0].Name)
+	assert.Equal("discovery", routes[1].Name)
+
+	var read, static *Route
+	for i, route := range routes {
+		switch route.Name {
+		case "widgets:read":
+			read = &routes[i]
+		case "":
+			static = &routes[i]
+		}
+	}
+	if assert.NotNil(read) {
+		assert.Equal([]string{"GET"}, read.Methods)
+		assert.Equal("/api/v{version:[^/]+}/widgets/{resource_id}", read.Path)
+		assert.False(read.PathPrefix)
+		assert.Equal("rest.TestResourceHandler.ReadResource", read.Handler)
+		assert.Equal([]string{"rest.tracingMiddleware", "rest.newAuthMiddleware"}, read.Middleware)
+		assert.Equal(time.Second, read.Timeout)
+	}
+	if assert.NotNil(static) {
+		assert.Equal("/static/", static.Path)
+		assert.True(static.PathPrefix)
+		assert.Equal("http.NotFound", static.Handler)
+		assert.Equal(time.Duration(0), static.Timeout)
+	}
+}
+
+// authenticateAll is an Authenticate function accepting every request.
+func authenticateAll(*http.Request) error {
+	return nil
+}
+
+// Ensures that the admin endpoint lists the registered routes.
+func TestRoutesAdminEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{AdminPath: "/admin/", RequestTimeout: time.Second,
+		Authenticate: authenticateAll})
+	api.RegisterResourceHandler(TestResourceHandler{})
+
+	req, _ := http.NewRequest("GET", "http://example.com/admin/routes", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+
+	var payload map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &payload))
+	routes := payload["results"].([]interface{})
+	assert.Len(routes, len(api.Routes()))
+	assert.Equal("admin:routes", routes[2].(map[string]interface{})["name"])
+
+	last := routes[len(routes)-1].(map[string]interface{})
+	assert.Equal("widgets:delete", last["name"])
+	assert.Equal("1s", last["timeout"])
+	assert.Equal([]interface{}{"DELETE"}, last["methods"])
+}
+
+// Ensures that the admin endpoints are authenticated and aren't registered without an
+// Authenticate function.
+func TestAdminEndpointsAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	for _, path := range []string{"/admin/routes", "/admin/postman.json", "/admin/stats"} {
+		api := NewAPI(&Configuration{AdminPath: "/admin", Instrumentation: true})
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(http.StatusNotFound, w.Code, path)
+
+		api = NewAPI(&Configuration{AdminPath: "/admin", Instrumentation: true,
+			Authenticate: authenticateCaller})
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(http.StatusUnauthorized, w.Code, path)
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Caller", "admin")
+		api.ServeHTTP(w, req)
+		assert.Equal(http.StatusOK, w.Code, path)
+	}
+}
+
+// Ensures that funcName returns package-qualified names without closure suffixes.
+func TestFuncName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("rest.tracingMiddleware", funcName(RequestMiddleware(tracingMiddleware)))
+	assert.Equal("rest.newAuthMiddleware", funcName(newAuthMiddleware(nil)))
+	assert.Equal("", funcName(nil))
+}
@@ -67,6 +67,21 @@ type Rules interface {
 type rules struct {
 	contents     []*Rule
 	resourceType reflect.Type
+
+	// compiled holds the Rules which apply in each direction for each version
+	// specified by the contained Rules, computed by NewRules so they aren't filtered
+	// for every request.
+	compiled map[compiledRulesKey]Rules
+
+	// unversioned holds the Rules which apply in each direction for versions which
+	// aren't specified by any of the contained Rules.
+	unversioned map[Filter]Rules
+}
+
+// compiledRulesKey identifies the Rules which apply in a direction for a version.
+type compiledRulesKey struct {
+	filter  Filter
+	version string
 }
 
 // Contents returns the contained Rules.
@@ -171,10 +186,43 @@ func NewRules(ptr interface{}, r ...*Rule) Rules {
 			resourceType.Kind()))
 	}
 
-	return &rules{
+	compiled := &rules{
 		resourceType: resourceType.Elem(),
 		contents:     r,
+		compiled:     map[compiledRulesKey]Rules{},
+		unversioned:  map[Filter]Rules{},
+	}
+	for _, filter := range []Filter{Inbound, Outbound} {
+		filtered := compiled.Filter(filter)
+		for _, rule := range r {
+			for _, version := range rule.Versions {
+				key := compiledRulesKey{filter, version}
+				if _, ok := compiled.compiled[key]; !ok {
+					compiled.compiled[key] = filtered.ForVersion(version)
+				}
+			}
+		}
+		unversioned := make([]*Rule, 0, filtered.Size())
+		for _, rule := range filtered.Contents() {
+			if rule.Versions == nil {
+				unversioned = append(unversioned, rule)
+			}
+		}
+		compiled.unversioned[filter] = &rules{contents: unversioned, resourceType: compiled.resourceType}
+	}
+	return compiled
+}
+
+// rulesFor returns the Rules which apply in the direction given by the Filter for the
+// version, using those compiled by NewRules if possible.
+func rulesFor(r Rules, filter Filter, version string) Rules {
+	if compiled, ok := r.(*rules); ok && compiled.unversioned != nil {
+		if rules, ok := compiled.compiled[compiledRulesKey{filter, version}]; ok {
+			return rules
+		}
+		return compiled.unversioned[filter]
 	}
+	return r.Filter(filter).ForVersion(version)
 }
 
 // Rule provides schema validation and type coercion for request input and fine-grained
@@ -219,6 +267,23 @@ type Rule struct {
 	// Nested Rules to apply to field value.
 	Rules Rules
 
+	// Indicates if lists can be filtered by the field with the filter query string
+	// variable. Values are coerced to the Rule's Type to be compared.
+	Filterable bool
+
+	// References, if set, is the name of the resource whose ID, or list of IDs, is the
+	// field's value. Read and list requests can then embed the referenced resources
+	// in place of their IDs with the expand query string variable.
+	References string
+
+	// Indicates if lists can be sorted by the field with the sort query string
+	// variable.
+	Sortable bool
+
+	// Indicates if aggregations can group resources by the field with the group_by
+	// query string variable.
+	Groupable bool
+
 	// Description used in documentation.
 	DocString string
 
@@ -281,7 +346,7 @@ func applyInboundRules(payload Payload, rules Rules, version string) (Payload, e
 	}
 
 	// Apply only inbound Rules.
-	rules = rules.Filter(true).ForVersion(version)
+	rules = rulesFor(rules, Inbound, version)
 
 	if rules.Size() == 0 {
 		return payload, nil
@@ -401,7 +466,7 @@ func nestedInboundRulesApply(value interface{}, rules Rules, version string) boo
 		return false
 	}
 
-	return rules.Filter(Inbound).ForVersion(version).Size() > 0
+	return rulesFor(rules, Inbound, version).Size() > 0
 }
 
 // applyOutboundRules applies Rules which are not specified as input only to the
@@ -413,7 +478,7 @@ func nestedInboundRulesApply(value interface{}, rules Rules, version string) boo
 // applied to field values.
 func applyOutboundRules(resource Resource, rules Rules, version string) Resource {
 	// Apply only outbound Rules.
-	rules = rules.Filter(false).ForVersion(version)
+	rules = rulesFor(rules, Outbound, version)
 
 	if isNil(resource) || rules.Size() == 0 {
 		// Return resource as-is if no Rules are provided.
@@ -1535,3 +1535,56 @@ func TestRulesValidateNonResourceRule(t *testing.T) {
 
 	assert.Nil(rules.Validate())
 }
+
+// Ensures that Type String returns the Go name of the type.
+func TestTypeString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("int64", Int64.String())
+	assert.Equal("time.Time", Time.String())
+	assert.Equal("interface{}", Unspecified.String())
+}
+
+// Ensures that the Rules compiled by NewRules match those filtered for each direction
+// and version, including versions not specified by any Rule.
+func TestRulesFor(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRules((*TestResource)(nil),
+		&Rule{Field: "Foo", FieldAlias: "foo"},
+		&Rule{Field: "Foo", FieldAlias: "foo1", Versions: []string{"1"}},
+		&Rule{Field: "Foo", FieldAlias: "foo2", Versions: []string{"2"}, OutputOnly: true},
+		&Rule{Field: "Foo", FieldAlias: "never", Versions: []string{}},
+		&Rule{FieldAlias: "input", InputOnly: true},
+	)
+
+	for _, filter := range []Filter{Inbound, Outbound} {
+		for _, version := range []string{"", "1", "2", "3"} {
+			assert.Equal(r.Filter(filter).ForVersion(version).Contents(),
+				rulesFor(r, filter, version).Contents(), "%v %s", filter, version)
+		}
+	}
+
+	// Rules not created by NewRules are filtered on demand.
+	assert.Equal(0, rulesFor(&rules{}, Outbound, "1").Size())
+}
+
+// benchmarkRulesResource is the resource used by the Rules benchmarks.
+type benchmarkRulesResource struct {
+	Foo string
+	Bar int
+	Baz []string
+}
+
+func BenchmarkApplyOutboundRules(b *testing.B) {
+	r := NewRules((*benchmarkRulesResource)(nil),
+		&Rule{Field: "Foo", FieldAlias: "foo"},
+		&Rule{Field: "Bar", FieldAlias: "bar", Versions: []string{"1"}},
+		&Rule{Field: "Bar", FieldAlias: "count", Versions: []string{"2"}},
+		&Rule{Field: "Baz", FieldAlias: "baz"},
+	)
+	resource := &benchmarkRulesResource{Foo: "hello", Bar: 42, Baz: []string{"a", "b"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		applyOutboundRules(resource, r, "2")
+	}
+}
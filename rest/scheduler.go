@@ -0,0 +1,406 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	gcontext "github.com/gorilla/context"
+)
+
+const (
+	// executeAtKey is the name of the query string variable scheduling a mutation.
+	executeAtKey = "execute_at"
+
+	// defaultSchedulePollInterval is the default interval at which due mutations are
+	// executed.
+	defaultSchedulePollInterval = time.Second
+)
+
+// scheduleStrippedHeaders are the request headers not stored with scheduled mutations,
+// since they carry credentials.
+var scheduleStrippedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// SchedulerConfig configures scheduled mutations. Create, update and delete requests
+// to ResourceHandlers with an execute_at query parameter, an RFC 3339 time, are
+// authenticated and then stored rather than handled. They're responded to with a 202
+// Accepted and the Location of an Operation, and the ResourceHandler is invoked with
+// the request once the time has come, recording the outcome on the Operation. Deleting
+// the Operation before then cancels the mutation, or the execution in progress. The
+// Authenticate method of the ResourceHandler isn't called again and the credentials of
+// the request aren't stored, so they aren't available to the ResourceHandler, but its
+// Principal is, and the ResourceHandler is invoked with it. Mutations are executed at
+// most once. Operations are enabled with their defaults if not configured.
+type SchedulerConfig struct {
+	// Store, if set, stores the scheduled mutations, e.g. in a database so they survive
+	// restarts. By default, they're kept in memory.
+	Store ScheduleStore
+
+	// PollInterval is the interval at which due mutations are executed. Defaults to
+	// one second.
+	PollInterval time.Duration
+
+	// MaxDelay, if positive, is the furthest in the future mutations can be scheduled.
+	MaxDelay time.Duration
+}
+
+// ScheduledMutation is a request to a ResourceHandler to be handled at a later time.
+type ScheduledMutation struct {
+	// ID identifies the mutation and the Operation recording its outcome.
+	ID string `json:"id"`
+
+	// Route is the name of the route handling the mutation, e.g. "widgets:update".
+	Route string `json:"route"`
+
+	// Method, URL, Header and Body are those of the request, without the execute_at
+	// query parameter and credentials.
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+
+	// Vars are the path variables of the request.
+	Vars map[string]string `json:"vars"`
+
+	// Principal, if set, is the Principal of the request, with which the mutation is
+	// executed.
+	Principal *Principal `json:"principal,omitempty"`
+
+	// ExecuteAt is when the mutation is executed.
+	ExecuteAt time.Time `json:"execute_at"`
+}
+
+// ScheduleStore stores the scheduled mutations. Implementations must be safe for
+// concurrent use.
+type ScheduleStore interface {
+	// Add stores the mutation.
+	Add(mutation ScheduledMutation) error
+
+	// Due removes and returns the mutations to be executed at or before the time,
+	// ordered by their ExecuteAt.
+	Due(now time.Time) ([]ScheduledMutation, error)
+
+	// Remove removes the mutation with the ID, returning false if there's no such
+	// mutation.
+	Remove(id string) (bool, error)
+}
+
+// memoryScheduleStore is a ScheduleStore keeping mutations in memory.
+type memoryScheduleStore struct {
+	mu        sync.Mutex
+	mutations map[string]ScheduledMutation
+}
+
+// NewMemoryScheduleStore returns a ScheduleStore keeping the mutations in memory, so
+// they're lost when the process exits.
+func NewMemoryScheduleStore() ScheduleStore {
+	return &memoryScheduleStore{mutations: map[string]ScheduledMutation{}}
+}
+
+// Add stores the mutation.
+func (m *memoryScheduleStore) Add(mutation ScheduledMutation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mutations[mutation.ID] = mutation
+	return nil
+}
+
+// Due removes and returns the mutations due at the time.
+func (m *memoryScheduleStore) Due(now time.Time) ([]ScheduledMutation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	due := []ScheduledMutation{}
+	for id, mutation := range m.mutations {
+		if !mutation.ExecuteAt.After(now) {
+			due = append(due, mutation)
+			delete(m.mutations, id)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ExecuteAt.Before(due[j].ExecuteAt) })
+	return due, nil
+}
+
+// Remove removes the mutation with the ID.
+func (m *memoryScheduleStore) Remove(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.mutations[id]
+	delete(m.mutations, id)
+	return ok, nil
+}
+
+// scheduledRoute is a route whose mutations can be scheduled.
+type scheduledRoute struct {
+	handler ResourceHandler
+	next    http.Handler
+}
+
+// scheduler stores scheduled mutations and executes them when they're due.
+type scheduler struct {
+	config     SchedulerConfig
+	operations *operationStore
+	mu         sync.RWMutex
+	routes     map[string]scheduledRoute
+	ctx        context.Context
+	cancel     context.CancelFunc
+	executing  sync.WaitGroup
+	done       chan struct{}
+}
+
+// newScheduler returns a scheduler recording the outcomes of mutations on Operations,
+// with the defaults applied to the configuration.
+func newScheduler(config SchedulerConfig, operations *operationStore) *scheduler {
+	if config.Store == nil {
+		config.Store = NewMemoryScheduleStore()
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultSchedulePollInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &scheduler{
+		config:     config,
+		operations: operations,
+		routes:     map[string]scheduledRoute{},
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// run executes the due mutations at the poll interval until the scheduler is stopped.
+func (s *scheduler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			due, err := s.config.Store.Due(now)
+			if err != nil {
+				log.Printf("Failed to read scheduled mutations: %v", err)
+				continue
+			}
+			for _, mutation := range due {
+				s.executing.Add(1)
+				go s.execute(mutation)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// execute handles the mutation with its route, recording the outcome on its Operation.
+func (s *scheduler) execute(mutation ScheduledMutation) {
+	defer s.executing.Done()
+	s.mu.RLock()
+	route, ok := s.routes[mutation.Route]
+	s.mu.RUnlock()
+	if !ok {
+		log.Printf("Dropping scheduled mutation %s of unknown route %s", mutation.ID, mutation.Route)
+		return
+	}
+
+	owner := ""
+	if mutation.Principal != nil {
+		owner = mutation.Principal.ID
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	op := s.operations.track(mutation.ID, route.handler, owner, mutation.Vars[resourceIDKey],
+		mutation.ExecuteAt, cancel)
+	if !op.begin(cancel) {
+		op.finish(nil, context.Canceled)
+		return
+	}
+
+	req, err := http.NewRequest(mutation.Method, mutation.URL, bytes.NewReader(mutation.Body))
+	if err != nil {
+		op.finish(nil, err)
+		return
+	}
+	req.Header = mutation.Header
+	vars := RouteVars{}
+	for key, value := range mutation.Vars {
+		vars[key] = value
+	}
+	req = req.WithContext(context.WithValue(ctx, pathVarsKey, vars))
+	defer gcontext.Clear(req)
+	if mutation.Principal != nil {
+		SetRequestPrincipal(req, mutation.Principal)
+	}
+
+	recorder := &mutationRecorder{header: http.Header{}}
+	route.next.ServeHTTP(recorder, req)
+	op.finish(recorder.outcome())
+}
+
+// stop stops executing mutations and waits for those executing to finish or the
+// context to be done. Mutations which aren't due yet remain stored.
+func (s *scheduler) stop(ctx context.Context) error {
+	s.cancel()
+	finished := make(chan struct{})
+	go func() {
+		<-s.done
+		s.executing.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// schedulable returns a Handler storing the mutations handled by the next Handler
+// with an execute_at query parameter, if the scheduler is enabled, and otherwise
+// handling requests with the next Handler.
+func (h requestHandler) schedulable(handler ResourceHandler, method HandleMethod,
+	next http.Handler) http.Handler {
+
+	if h.scheduler == nil {
+		return next
+	}
+	name := handler.ResourceName() + ":" + string(method)
+	h.scheduler.mu.Lock()
+	h.scheduler.routes[name] = scheduledRoute{handler: handler, next: next}
+	h.scheduler.mu.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if _, ok := query[executeAtKey]; !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body := readRequestBody(r)
+		ctx, cancel := h.newContextWithBody(r, w, handler.ResourceName(), body)
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		executeAt, err := time.Parse(time.RFC3339, query.Get(executeAtKey))
+		if err != nil {
+			h.sendResponse(ctx.setError(BadRequest(
+				fmt.Sprintf("Invalid %s: %s", executeAtKey, query.Get(executeAtKey)))))
+			return
+		}
+		if max := h.scheduler.config.MaxDelay; max > 0 && time.Until(executeAt) > max {
+			h.sendResponse(ctx.setError(BadRequest(
+				fmt.Sprintf("Mutations can't be scheduled more than %s ahead", max))))
+			return
+		}
+
+		query.Del(executeAtKey)
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		header := r.Header.Clone()
+		for _, key := range scheduleStrippedHeaders {
+			header.Del(key)
+		}
+		mutation := ScheduledMutation{
+			ID:        newRequestID(),
+			Route:     name,
+			Method:    r.Method,
+			URL:       target.String(),
+			Header:    header,
+			Body:      body.Bytes(),
+			Vars:      PathVars(r),
+			ExecuteAt: executeAt.UTC(),
+		}
+		if p, ok := ctx.Principal(); ok {
+			mutation.Principal = p
+		}
+		if err := h.scheduler.config.Store.Add(mutation); err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		store := h.scheduler.config.Store
+		op := h.operations.track(mutation.ID, handler, principalID(ctx), ctx.ResourceID(),
+			mutation.ExecuteAt, func() { store.Remove(mutation.ID) })
+		if u, err := ctx.BuildURL(operationsResource, HandleRead,
+			RouteVars{resourceIDKey: op.id}); err == nil {
+			ctx.ResponseHeader().Set("Location", u.String())
+		}
+		ctx = ctx.setResult(op.view())
+		ctx = ctx.setStatus(http.StatusAccepted)
+		h.sendResponse(ctx)
+	})
+}
+
+// mutationRecorder is an http.ResponseWriter recording the response to a scheduled
+// mutation.
+type mutationRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header returns the response headers.
+func (m *mutationRecorder) Header() http.Header {
+	return m.header
+}
+
+// WriteHeader records the status.
+func (m *mutationRecorder) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+}
+
+// Write records the body, recording a 200 status if none was written.
+func (m *mutationRecorder) Write(b []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	return m.body.Write(b)
+}
+
+// outcome returns the result of the mutation from the response envelope or, if it
+// failed, an error with the reason.
+func (m *mutationRecorder) outcome() (Resource, error) {
+	var envelope map[string]interface{}
+	json.Unmarshal(m.body.Bytes(), &envelope)
+	if m.status < 200 || m.status > 299 {
+		if msg, ok := envelope[reason].(string); ok && msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, fmt.Errorf("%s", http.StatusText(m.status))
+	}
+	if list, ok := envelope[results]; ok {
+		return list, nil
+	}
+	return envelope[result], nil
+}
+
+// registerScheduler starts executing scheduled mutations until the API is shut down.
+func (r *muxAPI) registerScheduler(config SchedulerConfig) {
+	if r.handler.operations == nil {
+		r.registerOperations(&OperationConfig{})
+	}
+	r.handler.scheduler = newScheduler(config, r.handler.operations)
+	go r.handler.scheduler.run()
+	r.OnShutdown(r.handler.scheduler.stop)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that mutations with an execute_at time are handled once it's due, with the
+// outcome recorded on an Operation, and that the credentials of the request aren't
+// stored.
+func TestScheduledMutation(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryScheduleStore()
+	api := NewAPI(&Configuration{Scheduler: &SchedulerConfig{
+		Store: store, PollInterval: 5 * time.Millisecond}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	defer api.Shutdown(context.Background())
+
+	executeAt := time.Now().Add(50 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	req := httptest.NewRequest("PUT", "/api/v1/widgets/42?execute_at="+executeAt,
+		strings.NewReader(`{"name": "cog"}`))
+	req.Header.Set("Authorization", "token")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	if !assert.Equal(http.StatusAccepted, w.Code) {
+		return
+	}
+	op := decodeEnvelope(w)["result"].(map[string]interface{})
+	assert.Equal(string(OperationPending), op["status"])
+	assert.Equal("42", op["resource_id"])
+	assert.NotNil(op["execute_at"])
+	id := op["id"].(string)
+	assert.Equal("http://example.com/api/v1/operations/"+id, w.Header().Get("Location"))
+
+	mutations := store.(*memoryScheduleStore).mutations
+	store.(*memoryScheduleStore).mu.Lock()
+	if mutation, ok := mutations[id]; assert.True(ok) {
+		assert.Equal("/api/v1/widgets/42", mutation.URL)
+		assert.Equal("", mutation.Header.Get("Authorization"))
+		assert.Equal(map[string]string{"version": "1", "resource_id": "42"}, mutation.Vars)
+	}
+	store.(*memoryScheduleStore).mu.Unlock()
+
+	op = awaitOperation(api, id)
+	assert.Equal(string(OperationSucceeded), op["status"])
+	assert.Equal(map[string]interface{}{"id": "42", "name": "cog"}, op["result"])
+}
+
+// Ensures that deleting the Operation of a scheduled mutation cancels it and that
+// invalid times are rejected.
+func TestScheduledMutationCancel(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryScheduleStore()
+	api := NewAPI(&Configuration{Scheduler: &SchedulerConfig{Store: store, MaxDelay: time.Hour}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	defer api.Shutdown(context.Background())
+
+	executeAt := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	code, envelope := serveJSON(api, "DELETE", "/api/v1/widgets/7?execute_at="+executeAt, "")
+	if !assert.Equal(http.StatusAccepted, code) {
+		return
+	}
+	id := envelope["result"].(map[string]interface{})["id"].(string)
+	code, _ = serveJSON(api, "DELETE", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusOK, code)
+	due, _ := store.Due(time.Now().Add(time.Hour))
+	assert.Len(due, 0)
+
+	code, _ = serveJSON(api, "POST", "/api/v1/widgets?execute_at=tomorrow", `{}`)
+	assert.Equal(http.StatusBadRequest, code)
+	executeAt = time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	code, _ = serveJSON(api, "POST", "/api/v1/widgets?execute_at="+executeAt, `{}`)
+	assert.Equal(http.StatusBadRequest, code)
+}
+
+// jobsResourceHandler is a ResourceHandler authenticating the caller of the X-Caller
+// header whose updates report the caller's Principal ID and run until canceled.
+type jobsResourceHandler struct {
+	BaseResourceHandler
+	callers  chan string
+	canceled chan struct{}
+}
+
+func (j jobsResourceHandler) ResourceName() string {
+	return "jobs"
+}
+
+func (j jobsResourceHandler) Authenticate(r *http.Request) error {
+	return authenticateCaller(r)
+}
+
+func (j jobsResourceHandler) UpdateResource(ctx RequestContext, id string, data Payload,
+	version string) (Resource, error) {
+	caller := ""
+	if p, ok := ctx.Principal(); ok {
+		caller = p.ID
+	}
+	j.callers <- caller
+	<-ctx.Done()
+	close(j.canceled)
+	return nil, ctx.Err()
+}
+
+// Ensures that scheduled mutations are executed with the Principal which scheduled
+// them and that deleting the Operation of a mutation being executed cancels it.
+func TestScheduledMutationPrincipal(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Scheduler: &SchedulerConfig{PollInterval: 5 * time.Millisecond}})
+	handler := jobsResourceHandler{callers: make(chan string, 1), canceled: make(chan struct{})}
+	api.RegisterResourceHandler(handler)
+	defer api.Shutdown(context.Background())
+
+	executeAt := time.Now().Add(20 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	code, envelope := serveJSONAs(api, "alice", "PUT", "/api/v1/jobs/1?execute_at="+executeAt, `{}`)
+	if !assert.Equal(http.StatusAccepted, code) {
+		return
+	}
+	id := envelope["result"].(map[string]interface{})["id"].(string)
+	select {
+	case caller := <-handler.callers:
+		assert.Equal("alice", caller)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Mutation wasn't executed")
+	}
+
+	code, _ = serveJSONAs(api, "bob", "DELETE", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "alice", "DELETE", "/api/v1/operations/"+id, "")
+	assert.Equal(http.StatusOK, code)
+	select {
+	case <-handler.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Mutation wasn't canceled")
+	}
+}
+
+// Ensures that the memory ScheduleStore returns the due mutations in order and removes
+// them.
+func TestMemoryScheduleStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryScheduleStore()
+	now := time.Now()
+	store.Add(ScheduledMutation{ID: "b", ExecuteAt: now.Add(-time.Second)})
+	store.Add(ScheduledMutation{ID: "a", ExecuteAt: now.Add(-time.Minute)})
+	store.Add(ScheduledMutation{ID: "c", ExecuteAt: now.Add(time.Minute)})
+
+	due, err := store.Due(now)
+	assert.Nil(err)
+	if assert.Len(due, 2) {
+		assert.Equal("a", due[0].ID)
+		assert.Equal("b", due[1].ID)
+	}
+	due, _ = store.Due(now)
+	assert.Len(due, 0)
+	removed, _ := store.Remove("c")
+	assert.True(removed)
+	removed, _ = store.Remove("c")
+	assert.False(removed)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schema is a JSON Schema object, as used by OpenAPI specifications.
+type schema map[string]interface{}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// rulesSchema returns the JSON Schema of the payloads described by the Rules for the
+// version, in the direction given by the Filter. If there are no Rules, the schema is
+// derived from the resource type, if any, since resources are then serialized as is.
+func rulesSchema(rules Rules, filter Filter, version string) schema {
+	if rules == nil || rules.Size() == 0 {
+		if rules != nil && rules.ResourceType() != nil {
+			return typeSchema(rules.ResourceType(), map[reflect.Type]bool{})
+		}
+		return schema{"type": "object"}
+	}
+
+	properties := schema{}
+	required := []string{}
+	nested := func(nested Rules) schema {
+		return rulesSchema(nested, filter, version)
+	}
+	for _, rule := range rules.ForVersion(version).Filter(filter).Contents() {
+		properties[rule.Name()] = ruleSchema(rule, rules.ResourceType(), nested)
+		if rule.Required && filter == Inbound {
+			required = append(required, rule.Name())
+		}
+	}
+
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// resourceSchema returns the JSON Schema of the resource described by the Rules for
+// the version, covering both requests and responses. Fields which are only accepted
+// in requests are marked writeOnly and fields which are only sent in responses are
+// marked readOnly.
+func resourceSchema(rules Rules, version string) schema {
+	if rules == nil || rules.Size() == 0 {
+		return rulesSchema(rules, Outbound, version)
+	}
+
+	properties := schema{}
+	required := []string{}
+	nested := func(nested Rules) schema {
+		return resourceSchema(nested, version)
+	}
+	for _, rule := range rules.ForVersion(version).Contents() {
+		s := ruleSchema(rule, rules.ResourceType(), nested)
+		if rule.InputOnly {
+			s["writeOnly"] = true
+		}
+		if rule.OutputOnly {
+			s["readOnly"] = true
+		} else if rule.Required {
+			required = append(required, rule.Name())
+		}
+		properties[rule.Name()] = s
+	}
+
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// ruleSchema returns the JSON Schema of the field described by the Rule, using the
+// function to describe nested Rules. Unspecified types are derived from the
+// corresponding field of the resource type, if any.
+func ruleSchema(rule *Rule, resourceType reflect.Type, nested func(Rules) schema) schema {
+	var s schema
+	switch {
+	case rule.Rules != nil && rule.Rules.Size() > 0:
+		s = nested(rule.Rules)
+		if rule.Type == Slice {
+			s = schema{"type": "array", "items": s}
+		}
+	case rule.Type == Unspecified:
+		s = schema{}
+		if resourceType != nil && resourceType.Kind() == reflect.Struct && rule.isResourceRule() {
+			if field, ok := resourceType.FieldByName(rule.Field); ok {
+				s = typeSchema(field.Type, map[reflect.Type]bool{})
+			}
+		}
+	default:
+		s = ruleTypeSchema(rule.Type)
+	}
+
+	if rule.DocString != "" {
+		s["description"] = rule.DocString
+	}
+	if rule.DocExample != nil {
+		s["example"] = rule.DocExample
+	}
+	return s
+}
+
+// ruleTypeSchema returns the JSON Schema of values coerced to the Type.
+func ruleTypeSchema(t Type) schema {
+	switch t {
+	case Int8, Int16, Int32, Uint8, Uint16:
+		return schema{"type": "integer", "format": "int32"}
+	case Int, Int64, Uint, Uint32, Uint64, Duration:
+		return schema{"type": "integer", "format": "int64"}
+	case Float32:
+		return schema{"type": "number", "format": "float"}
+	case Float64:
+		return schema{"type": "number", "format": "double"}
+	case String:
+		return schema{"type": "string"}
+	case Bool:
+		return schema{"type": "boolean"}
+	case Slice:
+		return schema{"type": "array", "items": schema{}}
+	case Map:
+		return schema{"type": "object"}
+	case Time:
+		return schema{"type": "string", "format": "date-time"}
+	}
+	return schema{}
+}
+
+// typeSchema returns the JSON Schema of values of the Go type when encoded as JSON.
+// Types already being described are given a generic object schema to break cycles.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case durationType:
+		return schema{"type": "integer", "format": "int64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return schema{"type": "number", "format": "double"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schema{"type": "string", "format": "byte"}
+		}
+		return schema{"type": "array", "items": typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return schema{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := schema{}
+		addStructProperties(properties, t, seen)
+		return schema{"type": "object", "properties": properties}
+	}
+	return schema{}
+}
+
+// addStructProperties adds the JSON Schemas of the encoded fields of the struct type
+// to the properties, flattening embedded structs like encoding/json.
+func addStructProperties(properties schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addStructProperties(properties, fieldType, seen)
+			continue
+		}
+		if field.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type, seen)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaAddress struct {
+	Street string `json:"street"`
+}
+
+type schemaResource struct {
+	schemaAddress
+	ID       int64             `json:"id"`
+	Tags     []string          `json:"tags,omitempty"`
+	Data     []byte            `json:"data"`
+	Created  *time.Time        `json:"created"`
+	Labels   map[string]string `json:"labels"`
+	Parent   *schemaResource   `json:"parent"`
+	Secret   string            `json:"-"`
+	Untagged bool
+	internal string
+}
+
+// Ensures that typeSchema describes structs as encoded by encoding/json, breaking
+// cycles.
+func TestTypeSchema(t *testing.T) {
+	s := typeSchema(reflect.TypeOf(schemaResource{}), map[reflect.Type]bool{})
+	assert.Equal(t, schema{
+		"type": "object",
+		"properties": schema{
+			"street":   schema{"type": "string"},
+			"id":       schema{"type": "integer", "format": "int64"},
+			"tags":     schema{"type": "array", "items": schema{"type": "string"}},
+			"data":     schema{"type": "string", "format": "byte"},
+			"created":  schema{"type": "string", "format": "date-time"},
+			"labels":   schema{"type": "object", "additionalProperties": schema{"type": "string"}},
+			"parent":   schema{"type": "object"},
+			"Untagged": schema{"type": "boolean"},
+		},
+	}, s)
+}
+
+// Ensures that rulesSchema derives the schema from the resource type when there are
+// no Rules, and field types from the resource type for unspecified Rule types.
+func TestRulesSchemaResourceType(t *testing.T) {
+	assert := assert.New(t)
+	s := rulesSchema(NewRules((*schemaResource)(nil)), Outbound, "1")
+	assert.Contains(s["properties"], "street")
+
+	s = rulesSchema(NewRules((*schemaResource)(nil),
+		&Rule{Field: "Tags", FieldAlias: "tags", DocExample: []string{"a"}},
+		&Rule{Field: "ID", FieldAlias: "id", Type: Int64, Required: true, OutputOnly: true},
+	), Inbound, "1")
+	assert.Equal(schema{
+		"type": "object",
+		"properties": schema{
+			"tags": schema{"type": "array", "items": schema{"type": "string"}, "example": []string{"a"}},
+		},
+	}, s)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import "net/http"
+
+const (
+	// searchKey is the query parameter holding the query of a search request.
+	searchKey = "q"
+
+	// searchMethod identifies search requests, e.g. in their standard links.
+	searchMethod HandleMethod = "search"
+)
+
+// Searcher is implemented by ResourceHandlers supporting full-text searches of their
+// resources. GET requests to /api/v{version}/{resource}/search?q={query} are passed
+// to Search, with the usual list parameters available from the RequestContext, i.e.
+// the ListFilter, TimeRange, SortFields and Offset, and results are sent like those
+// of ReadResourceList. Search can describe why results matched using AddHighlight.
+type Searcher interface {
+	// Search returns the resources matching the query, and the cursor of the next
+	// page of results if there is one.
+	Search(ctx RequestContext, query string, limit int, cursor string,
+		version string) ([]Resource, string, error)
+}
+
+// searcher returns the Searcher implemented by the ResourceHandler, if any.
+func searcher(handler ResourceHandler) (Searcher, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	s, ok := handler.(Searcher)
+	return s, ok
+}
+
+// searchURI returns the URI of the ResourceHandler's searches.
+func searchURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/search"
+}
+
+// handleSearch returns a Handler passing the query of search requests to the
+// Searcher and then serializing and dispatching the results.
+func (h requestHandler) handleSearch(handler ResourceHandler, s Searcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		query := ctx.SearchQuery()
+		if query == "" {
+			h.sendResponse(ctx.setError(BadRequest("Missing search query parameter: " + searchKey)))
+			return
+		}
+
+		h.sendResponse(h.readList(ctx, handler, searchMethod,
+			func(ctx RequestContext) ([]Resource, string, error) {
+				return s.Search(ctx, query, ctx.Limit(), ctx.Cursor(), ctx.Version())
+			}))
+	})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchableTasksResourceHandler is a tasksResourceHandler searching tasks by name.
+type searchableTasksResourceHandler struct {
+	tasksResourceHandler
+}
+
+func (s searchableTasksResourceHandler) Search(ctx RequestContext, query string, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	tasks, _, err := s.ReadResourceList(ctx, limit, cursor, version)
+	results := []Resource{}
+	for _, task := range tasks {
+		name := task.(Payload)["name"].(string)
+		if strings.Contains(query, name) {
+			ctx.AddHighlight(name, "name", "<em>"+name+"</em>")
+			results = append(results, task)
+		}
+	}
+	return results, "", err
+}
+
+// Ensures that searches are passed to the Searcher along with the list filter and
+// sort fields, and that highlights are included in the response.
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(searchableTasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET",
+		"/api/v1/tasks/search?q=a+b+d&filter=size==5&sort=-name", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		results := envelope["results"].([]interface{})
+		if assert.Len(results, 2) {
+			assert.Equal("d", results[0].(map[string]interface{})["name"])
+			assert.Equal("b", results[1].(map[string]interface{})["name"])
+		}
+		assert.Equal(map[string]interface{}{
+			"b": map[string]interface{}{"name": []interface{}{"<em>b</em>"}},
+			"d": map[string]interface{}{"name": []interface{}{"<em>d</em>"}},
+		}, envelope["highlights"])
+	}
+}
+
+// Ensures that searches without a query are rejected.
+func TestSearchMissingQuery(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(searchableTasksResourceHandler{})
+
+	code, _ := serveJSON(api, "GET", "/api/v1/tasks/search?q=+", "")
+
+	assert.Equal(http.StatusBadRequest, code)
+}
+
+// Ensures that the search route is only registered for Searchers, so searches of
+// other resources are reads of the resource with the ID "search".
+func TestSearchNotSupported(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(tasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks/search?q=a", "")
+
+	assert.NotEqual(http.StatusOK, code)
+	assert.Nil(envelope["highlights"])
+}
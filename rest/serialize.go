@@ -17,25 +17,45 @@ limitations under the License.
 package rest
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"reflect"
 )
 
 const (
-	status   = "status"
-	reason   = "reason"
-	messages = "messages"
-	result   = "result"
-	results  = "results"
-	next     = "next"
+	status    = "status"
+	reason    = "reason"
+	messages  = "messages"
+	result    = "result"
+	results   = "results"
+	next      = "next"
+	total     = "total"
+	errs      = "errors"
+	code      = "code"
+	requestID = "request_id"
+	incident  = "incident"
+	links     = "links"
+	meta      = "meta"
+	highlight = "highlights"
 )
 
 // response is a data structure holding the serializable response body for a request and
 // HTTP status code. It should be created using NewResponse.
 type response struct {
-	Payload Payload
-	Status  int
+	Payload     Payload
+	Status      int
+	ContentType string
+	Error       error
+
+	// Conditional, if set, is the request whose If-None-Match header is evaluated
+	// against an entity tag generated from the serialized Payload, for responses
+	// without one.
+	Conditional *http.Request
+
+	// MaxSize, if positive, is the size the serialized Payload may not exceed.
+	MaxSize int
 }
 
 // ResponseSerializer is responsible for serializing REST responses and sending
@@ -49,6 +69,15 @@ type ResponseSerializer interface {
 	ContentType() string
 }
 
+// ErrorSerializer is an optional interface implemented by ResponseSerializers which
+// render error responses in a format-specific shape, such as a protobuf Status,
+// rather than serializing the error payload like any other response.
+type ErrorSerializer interface {
+	// SerializeError marshals the error and its response payload into a byte slice to
+	// be sent over the wire.
+	SerializeError(error, Payload) ([]byte, error)
+}
+
 // jsonSerializer is an implementation of ResponseSerializer which serializes responses
 // as JSON.
 type jsonSerializer struct{}
@@ -58,6 +87,11 @@ func (j jsonSerializer) Serialize(p Payload) ([]byte, error) {
 	return json.Marshal(p)
 }
 
+// serializeTo marshals a response payload as JSON into the buffer.
+func (j jsonSerializer) serializeTo(b *responseBuffer, p Payload) error {
+	return b.encodePayload(p)
+}
+
 // ContentType returns the JSON MIME type of the response.
 func (j jsonSerializer) ContentType() string {
 	return "application/json"
@@ -78,7 +112,7 @@ func NewResponse(ctx RequestContext) response {
 func newSuccessResponse(ctx RequestContext) response {
 	r := ctx.Result()
 	resultKey := result
-	if r != nil && reflect.TypeOf(r).Kind() == reflect.Slice {
+	if isResultList(r) {
 		resultKey = results
 	}
 
@@ -97,19 +131,41 @@ func newSuccessResponse(ctx RequestContext) response {
 			payload[next] = nextURL
 		}
 
+		if t, ok := ctx.Total(); ok {
+			payload[total] = t
+		}
+
+		if l := ctx.Links(); len(l) > 0 {
+			payload[links] = l
+		}
+
+		if m, ok := ctx.Value(metaKey).(Payload); ok {
+			payload[meta] = m
+		}
+
+		if hl := ctx.Highlights(); len(hl) > 0 {
+			payload[highlight] = hl
+		}
+
 		response.Payload = payload
 	}
 
 	return response
 }
 
+// isResultList indicates if the result is a list of resources. Pre-encoded JSON
+// results are lists only if they hold an array.
+func isResultList(r Resource) bool {
+	if raw, ok := r.(json.RawMessage); ok {
+		trimmed := bytes.TrimSpace(raw)
+		return len(trimmed) > 0 && trimmed[0] == '['
+	}
+	return r != nil && reflect.TypeOf(r).Kind() == reflect.Slice
+}
+
 // newErrorResponse constructs a new response struct containing an error message.
 func newErrorResponse(ctx RequestContext) response {
-	err := ctx.Error()
-	s := http.StatusInternalServerError
-	if restError, ok := err.(Error); ok {
-		s = restError.Status()
-	}
+	s := errorStatus(ctx.Error())
 
 	payload := Payload{
 		status:   s,
@@ -117,9 +173,25 @@ func newErrorResponse(ctx RequestContext) response {
 		messages: ctx.Messages(),
 	}
 
+	var validationErr *ValidationError
+	if errors.As(ctx.Error(), &validationErr) && validationErr.HasErrors() {
+		payload[errs] = fieldErrorsPayload(validationErr.FieldErrors())
+	}
+
+	if c := errorCode(ctx.Error()); c != "" {
+		payload[code] = c
+	}
+
+	var panicErr panicError
+	if errors.As(ctx.Error(), &panicErr) {
+		payload[requestID] = ctx.RequestID()
+		payload[incident] = panicErr.incident
+	}
+
 	response := response{
 		Payload: payload,
 		Status:  s,
+		Error:   ctx.Error(),
 	}
 
 	return response
@@ -0,0 +1,35 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that isResultList identifies slices and pre-encoded JSON arrays as lists.
+func TestIsResultList(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isResultList([]Resource{}))
+	assert.True(isResultList(json.RawMessage(` [{"id": 1}]`)))
+	assert.False(isResultList(json.RawMessage(`{"id": 1}`)))
+	assert.False(isResultList(json.RawMessage(nil)))
+	assert.False(isResultList(Payload{}))
+	assert.False(isResultList(nil))
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// unixAddressPrefix prefixes the Addresses of Unix domain sockets.
+const unixAddressPrefix = "unix:"
+
+// ServerConfig configures the connections of the servers run by Start and StartTLS.
+// Zero values leave the corresponding net/http defaults in place.
+type ServerConfig struct {
+	// ReadTimeout is the maximum duration for reading an entire request, including
+	// the body.
+	ReadTimeout time.Duration
+
+	// ReadHeaderTimeout is the maximum duration for reading the request headers. If
+	// zero, ReadTimeout is used.
+	ReadHeaderTimeout time.Duration
+
+	// WriteTimeout is the maximum duration before timing out writes of the response.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is how long idle keep-alive connections are kept open. If zero,
+	// ReadTimeout is used. It also applies to HTTP/2 connections unless
+	// HTTP2Config.IdleTimeout is set.
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes is the maximum size in bytes of the request headers. Defaults to
+	// 1MB.
+	MaxHeaderBytes int
+
+	// DisableKeepAlives closes connections after each request instead of reusing
+	// them.
+	DisableKeepAlives bool
+
+	// TLS, if set, configures the connections of the servers run by StartTLS. It
+	// defaults to requiring TLS 1.2 or later with forward-secret AEAD cipher suites.
+	// Its certificates are replaced by those loaded from the files passed to StartTLS
+	// unless they're empty, e.g. if it provides GetCertificate.
+	TLS *tls.Config
+
+	// CertReloadInterval, if positive, is how often StartTLS checks its certificate
+	// and key files for changes, reloading them without a restart.
+	CertReloadInterval time.Duration
+
+	// ReloadCertOnSIGHUP reloads the certificate and key files of StartTLS whenever
+	// the process receives SIGHUP.
+	ReloadCertOnSIGHUP bool
+
+	// ShutdownTimeout is how long Run waits for in-flight requests to complete when
+	// shutting down. Defaults to 30 seconds.
+	ShutdownTimeout time.Duration
+
+	// CertManager, if set, obtains the certificates of StartTLS automatically when it
+	// isn't given certificate and key files. Start then also responds to the HTTP-01
+	// challenges of the certificate authority, so it should listen on port 80.
+	CertManager CertificateManager
+
+	// Interface, if set, is the name of the network interface, e.g. "eth0", whose
+	// address TCP listeners are bound to when the address passed to Start or StartTLS
+	// has no host, e.g. "tcp6::8080" for its IPv6 address.
+	Interface string
+}
+
+// configure applies the settings to the server.
+func (c *ServerConfig) configure(server *http.Server) {
+	server.ReadTimeout = c.ReadTimeout
+	server.ReadHeaderTimeout = c.ReadHeaderTimeout
+	server.WriteTimeout = c.WriteTimeout
+	server.IdleTimeout = c.IdleTimeout
+	server.MaxHeaderBytes = c.MaxHeaderBytes
+	server.SetKeepAlivesEnabled(!c.DisableKeepAlives)
+}
+
+// HTTP2Config configures HTTP/2 for the servers run by Start and StartTLS.
+type HTTP2Config struct {
+	// H2C enables HTTP/2 over cleartext TCP connections ("h2c") for Start, so internal
+	// traffic, e.g. from a load balancer or sidecar proxy, is multiplexed without TLS.
+	// Clients may use prior knowledge or the HTTP/1.1 Upgrade header.
+	H2C bool
+
+	// MaxConcurrentStreams is the number of concurrent streams each client may have
+	// open. Defaults to 250.
+	MaxConcurrentStreams uint32
+
+	// MaxReadFrameSize is the largest frame size in bytes the server reads. Defaults
+	// to 1MB.
+	MaxReadFrameSize uint32
+
+	// IdleTimeout is how long idle connections are kept open. If zero, they are kept
+	// open until the client closes them.
+	IdleTimeout time.Duration
+}
+
+// http2Server returns the HTTP/2 server with the configured settings.
+func (c *HTTP2Config) http2Server() *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams: c.MaxConcurrentStreams,
+		MaxReadFrameSize:     c.MaxReadFrameSize,
+		IdleTimeout:          c.IdleTimeout,
+	}
+}
+
+// newServer returns the http.Server serving the handler at the address, configured with
+// the Configuration's connection settings and for HTTP/2 if it requests it. The TLS
+// config, if given, is used for TLS connections. Otherwise ACME HTTP-01 challenges are
+// answered if there's a CertificateManager.
+func (r *muxAPI) newServer(addr Address, handler http.Handler,
+	tlsConfig *tls.Config) (*http.Server, error) {
+
+	if manager := r.certManager(); manager != nil && tlsConfig == nil {
+		handler = manager.HTTPHandler(handler)
+	}
+	server := &http.Server{Addr: string(addr), Handler: handler, TLSConfig: tlsConfig}
+	if r.config.Server != nil {
+		r.config.Server.configure(server)
+	}
+	if r.config.HTTP2 == nil {
+		return server, nil
+	}
+
+	h2 := r.config.HTTP2.http2Server()
+	if r.config.HTTP2.H2C {
+		server.Handler = h2c.NewHandler(handler, h2)
+	}
+	if err := http2.ConfigureServer(server, h2); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// network returns the network of the address, given by its "unix:", "tcp4:", "tcp6:" or
+// "tcp:" prefix and otherwise "tcp", and the address without the prefix.
+func (a Address) network() (string, string) {
+	for _, network := range []string{"unix", "tcp4", "tcp6", "tcp"} {
+		if addr := strings.TrimPrefix(string(a), network+":"); addr != string(a) {
+			return network, addr
+		}
+	}
+	return "tcp", string(a)
+}
+
+// listen returns a listener on the Unix domain socket or TCP address, or the default
+// address if it's empty. TCP addresses without a host are bound to the address of the
+// ServerConfig's Interface, if any.
+func (r *muxAPI) listen(addr Address, defaultAddr string) (net.Listener, error) {
+	network, address := addr.network()
+	if network == "unix" {
+		// Remove a socket left behind by a previous process.
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+		return net.Listen(network, address)
+	}
+	if address == "" {
+		address = defaultAddr
+	}
+	if c := r.config.Server; c != nil && c.Interface != "" {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if host == "" {
+			if host, err = interfaceHost(c.Interface, network); err != nil {
+				return nil, err
+			}
+			address = net.JoinHostPort(host, port)
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// interfaceHost returns the first address of the named network interface usable by
+// the TCP network, with the interface as the zone of IPv6 link-local addresses.
+func interfaceHost(name, network string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+			continue
+		}
+		if ip.To4() == nil && ip.IsLinkLocalUnicast() {
+			return ip.String() + "%" + name, nil
+		}
+		return ip.String(), nil
+	}
+	return "", fmt.Errorf("interface %s has no %s address", name, network)
+}
+
+// serveListener serves requests received by the listener with the middleware, over TLS
+// if the TLS config is given, until it fails or the API is stopped. The OnStart hooks are
+// run first if they haven't been.
+func (r *muxAPI) serveListener(ln net.Listener, tlsConfig *tls.Config,
+	middleware []Middleware) error {
+
+	if err := r.runStartHooks(); err != nil {
+		ln.Close()
+		return err
+	}
+	server, err := r.newServer(Address(ln.Addr().String()), wrapMiddleware(r, middleware...),
+		tlsConfig)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		return r.serve(server, ln.Addr(), func() error { return server.ServeTLS(ln, "", "") })
+	}
+	return r.serve(server, ln.Addr(), func() error { return server.Serve(ln) })
+}
+
+// serve runs the server with the listen function, tracking it and the address it's
+// bound to so it can be stopped. The OnListen hooks are run with the address first. It
+// returns nil if the server was stopped.
+func (r *muxAPI) serve(server *http.Server, addr net.Addr, listen func() error) error {
+	r.mu.Lock()
+	r.servers[server] = addr
+	hooks := append([]func(net.Addr){}, r.listenHooks...)
+	r.mu.Unlock()
+	for _, hook := range hooks {
+		hook(addr)
+	}
+	defer func() {
+		r.mu.Lock()
+		delete(r.servers, server)
+		r.mu.Unlock()
+	}()
+
+	if err := listen(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// OnListen registers the function to be called with the address each server run by
+// Start, StartTLS or Serve is bound to before it begins serving requests, e.g. the
+// port chosen by the system for ":0".
+func (r *muxAPI) OnListen(hook func(net.Addr)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listenHooks = append(r.listenHooks, hook)
+}
+
+// Addrs returns the addresses the servers currently run by Start, StartTLS and Serve
+// are bound to.
+func (r *muxAPI) Addrs() []net.Addr {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	addrs := make([]net.Addr, 0, len(r.servers))
+	for _, addr := range r.servers {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Stop immediately closes the listeners and connections of the servers run by Start and
+// StartTLS, causing them to return. It returns the first error encountered closing them.
+func (r *muxAPI) Stop() error {
+	var err error
+	for _, server := range r.runningServers() {
+		if closeErr := server.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// runningServers returns the servers currently run by Start and StartTLS.
+func (r *muxAPI) runningServers() []*http.Server {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	servers := make([]*http.Server, 0, len(r.servers))
+	for server := range r.servers {
+		servers = append(servers, server)
+	}
+	return servers
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+// Ensures that the server serves HTTP/2 over cleartext connections when H2C is
+// enabled.
+func TestNewServerH2C(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{HTTP2: &HTTP2Config{H2C: true, MaxConcurrentStreams: 10}})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	server, err := api.(*muxAPI).newServer(":0", api, nil)
+	assert.Nil(err)
+
+	ts := httptest.NewUnstartedServer(server.Handler)
+	ts.Start()
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get(ts.URL + "/api/v1/rows")
+	if assert.Nil(err) {
+		defer resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(2, resp.ProtoMajor)
+	}
+}
+
+// Ensures that the server negotiates HTTP/2 over TLS when HTTP2 is configured and
+// is a plain HTTP/1.1 server otherwise.
+func TestNewServerHTTP2(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{HTTP2: &HTTP2Config{}})
+	server, err := api.(*muxAPI).newServer(":8443", api, nil)
+	assert.Nil(err)
+	assert.Equal(":8443", server.Addr)
+	assert.Contains(server.TLSConfig.NextProtos, "h2")
+	assert.NotNil(server.TLSNextProto["h2"])
+
+	api = NewAPI(&Configuration{})
+	server, err = api.(*muxAPI).newServer(":8080", api, nil)
+	assert.Nil(err)
+	assert.Nil(server.TLSConfig)
+	assert.Equal(api, server.Handler)
+}
+
+// Ensures that the server is configured with the Configuration's connection
+// settings.
+func TestNewServerConfig(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{
+		Server: &ServerConfig{
+			ReadTimeout:       time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+			WriteTimeout:      3 * time.Second,
+			IdleTimeout:       4 * time.Second,
+			MaxHeaderBytes:    4096,
+			DisableKeepAlives: true,
+		},
+	})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	server, err := api.(*muxAPI).newServer(":0", api, nil)
+	assert.Nil(err)
+	assert.Equal(time.Second, server.ReadTimeout)
+	assert.Equal(2*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(3*time.Second, server.WriteTimeout)
+	assert.Equal(4*time.Second, server.IdleTimeout)
+	assert.Equal(4096, server.MaxHeaderBytes)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	go server.Serve(ln)
+	defer server.Close()
+	resp, err := http.Get("http://" + ln.Addr().String() + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.True(resp.Close)
+	}
+}
+
+// freeAddress returns a local address with a free port.
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// startTestAPI runs the API on a free local port until the test ends, returning its
+// address once it's serving requests and a channel receiving the result of Start.
+func startTestAPI(t *testing.T, api API) (string, <-chan error) {
+	return startTestServer(t, api, func(addr Address) error { return api.Start(addr) })
+}
+
+// startTestServer runs the API on a free local port with the start function until the
+// test ends, returning its address once it's accepting connections and a channel
+// receiving the result of the start function.
+func startTestServer(t *testing.T, api API, start func(Address) error) (string, <-chan error) {
+	addr := freeAddress(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- start(Address(addr))
+	}()
+	t.Cleanup(func() { api.Stop() })
+
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return addr, done
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("API didn't start at %s", addr)
+	return "", nil
+}
+
+// Ensures that Stop closes the servers run by Start, which then return nil.
+func TestStartStop(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	addr, done := startTestAPI(t, api)
+
+	resp, err := http.Get("http://" + addr + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	assert.Nil(api.Stop())
+	select {
+	case err := <-done:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		assert.Fail("Start didn't return after Stop")
+	}
+}
+
+// Ensures that Start listens on a Unix domain socket when the address is prefixed with
+// "unix:", replacing a stale socket.
+func TestStartUnixSocket(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "api.sock")
+	stale, err := net.Listen("unix", path)
+	if !assert.Nil(err) {
+		return
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	done := make(chan error, 1)
+	go func() {
+		done <- api.Start(Address("unix:" + path))
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = client.Get("http://api/api/v1/rows"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	assert.Nil(api.Stop())
+	assert.Nil(<-done)
+}
+
+// Ensures that an API serves several listeners concurrently, each with its own
+// Middleware.
+func TestServeListeners(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	deny := func(w http.ResponseWriter, r *http.Request) *MiddlewareError {
+		return &MiddlewareError{Code: http.StatusForbidden, Response: []byte("public")}
+	}
+
+	public, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	done := make(chan error, 2)
+	go func() { done <- api.Serve(public, deny) }()
+	go func() { done <- api.Serve(admin) }()
+	defer api.Stop()
+
+	resp, err := http.Get("http://" + admin.Addr().String() + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+	resp, err = http.Get("http://" + public.Addr().String() + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusForbidden, resp.StatusCode)
+	}
+
+	assert.Nil(api.Stop())
+	assert.Nil(<-done)
+	assert.Nil(<-done)
+}
+
+// startListening starts the API at the address, returning the address reported to
+// its OnListen hooks.
+func startListening(t *testing.T, api API, addr Address) net.Addr {
+	listening := make(chan net.Addr, 1)
+	api.OnListen(func(addr net.Addr) { listening <- addr })
+	done := make(chan error, 1)
+	go func() { done <- api.Start(addr) }()
+	t.Cleanup(func() { api.Stop() })
+
+	select {
+	case addr := <-listening:
+		return addr
+	case err := <-done:
+		t.Fatalf("API didn't start at %s: %v", addr, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("API didn't start at %s", addr)
+	}
+	return nil
+}
+
+// Ensures that Start listens on the network given by the address's prefix and reports
+// the address it's bound to.
+func TestStartNetworks(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+
+	addr := startListening(t, api, "tcp4:127.0.0.1:0")
+	assert.Equal("tcp", addr.Network())
+	assert.NotEqual("127.0.0.1:0", addr.String())
+	assert.Equal([]net.Addr{addr}, api.Addrs())
+	resp, err := http.Get("http://" + addr.String() + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		ln.Close()
+		api := NewAPI(&Configuration{})
+		addr := startListening(t, api, "tcp6:[::1]:0")
+		assert.Equal("::1", addr.(*net.TCPAddr).IP.String())
+	}
+
+	assert.Error(NewAPI(&Configuration{}).Start("tcp4:[::1]:0"))
+	assert.Empty(NewAPI(&Configuration{}).Addrs())
+}
+
+// Ensures that TCP listeners are bound to the address of the configured interface.
+func TestStartInterface(t *testing.T) {
+	assert := assert.New(t)
+	interfaces, _ := net.Interfaces()
+	loopback := ""
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			loopback = iface.Name
+			break
+		}
+	}
+	if loopback == "" {
+		t.Skip("no loopback interface")
+	}
+
+	api := NewAPI(&Configuration{Server: &ServerConfig{Interface: loopback}})
+	addr := startListening(t, api, "tcp4::0")
+	assert.True(addr.(*net.TCPAddr).IP.IsLoopback())
+
+	api = NewAPI(&Configuration{Server: &ServerConfig{Interface: "no-such-interface"}})
+	assert.Error(api.Start(":0"))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// handleReadiness returns a Handler which responds 200 OK while the API is serving
+// requests and 503 Service Unavailable once it's shutting down, so load balancers stop
+// routing requests to it.
+func (r *muxAPI) handleReadiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if atomic.LoadInt32(&r.shuttingDown) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("shutting down"))
+			return
+		}
+		w.Write([]byte("ready"))
+	})
+}
+
+// OnShutdown registers the function to be run by Shutdown once the servers have
+// stopped. Functions are run in the order they're registered.
+func (r *muxAPI) OnShutdown(hook func(context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shutdownHooks = append(r.shutdownHooks, hook)
+}
+
+// Shutdown gracefully stops the servers run by Start and StartTLS. It marks the API as
+// not ready, ends event streams, stops accepting connections and waits for in-flight
+// requests to complete until the context is done, at which point the remaining connections are closed. The
+// pending Events of Outboxes are then relayed and the functions registered with
+// OnShutdown run with the context. Start and StartTLS return as soon as Shutdown is
+// called, so callers should wait for Shutdown to return before exiting. It returns the first error encountered.
+func (r *muxAPI) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&r.shuttingDown, 1)
+	if r.handler.events != nil {
+		r.handler.events.close()
+	}
+	servers := r.runningServers()
+	r.mu.RLock()
+	hooks := append([]func(context.Context) error(nil), r.shutdownHooks...)
+	r.mu.RUnlock()
+
+	errs := make([]error, len(servers), len(servers)+len(hooks))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server *http.Server) {
+			defer wg.Done()
+			if errs[i] = server.Shutdown(ctx); errs[i] != nil {
+				server.Close()
+			}
+		}(i, server)
+	}
+	wg.Wait()
+
+	errs = append(errs, r.stopOutboxes(ctx))
+	for _, hook := range hooks {
+		errs = append(errs, hook(ctx))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
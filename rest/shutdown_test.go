@@ -0,0 +1,150 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSlowAPI returns an API with a ReadinessPath and a /slow endpoint which signals
+// started when a request arrives and responds once release is closed.
+func newSlowAPI(started chan<- struct{}, release <-chan struct{}) API {
+	api := NewAPI(&Configuration{ReadinessPath: "/ready"})
+	api.RegisterHandlerFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte("done"))
+	})
+	return api
+}
+
+// Ensures that Shutdown flips readiness, waits for in-flight requests to complete and
+// then runs the shutdown hooks.
+func TestShutdown(t *testing.T) {
+	assert := assert.New(t)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	api := newSlowAPI(started, release)
+	hooks := []string{}
+	api.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "first")
+		return nil
+	})
+	api.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "second")
+		return nil
+	})
+	addr, done := startTestAPI(t, api)
+
+	ready := httptest.NewRecorder()
+	api.ServeHTTP(ready, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(http.StatusOK, ready.Code)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- api.Shutdown(context.Background())
+	}()
+	assert.Nil(<-done)
+	select {
+	case <-shutdown:
+		assert.Fail("Shutdown returned with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ready = httptest.NewRecorder()
+	api.ServeHTTP(ready, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(http.StatusServiceUnavailable, ready.Code)
+	assert.Empty(hooks)
+
+	close(release)
+	assert.Equal("done", <-body)
+	assert.Nil(<-shutdown)
+	assert.Equal([]string{"first", "second"}, hooks)
+}
+
+// Ensures that Shutdown closes the remaining connections and returns the context's
+// error once its deadline passes, still running the shutdown hooks.
+func TestShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	api := newSlowAPI(started, release)
+	ran := false
+	api.OnShutdown(func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	addr, _ := startTestAPI(t, api)
+
+	failed := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		failed <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, api.Shutdown(ctx))
+	assert.True(ran)
+	assert.NotNil(<-failed)
+}
+
+// Ensures that Shutdown ends the event streams of connected clients rather than
+// waiting for them to disconnect.
+func TestShutdownEventStream(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	addr, done := startTestAPI(t, api)
+
+	events, err := http.Get("http://" + addr + "/api/v1/widgets/events")
+	if !assert.Nil(err) {
+		return
+	}
+	defer events.Body.Close()
+	assert.Equal(http.StatusOK, events.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+	assert.Nil(<-done)
+	_, err = ioutil.ReadAll(events.Body)
+	assert.Nil(err)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortKey is the name of the query string variable sorting lists.
+const sortKey = "sort"
+
+// SortField is a field a list is sorted by, parsed from the sort query string variable
+// of a list request, e.g. ?sort=-created_at,name sorts by created_at, descending, then
+// by name. Only the fields of Rules marked Sortable can be sorted by.
+type SortField struct {
+	// Field is the name of the field, as given by its Rule's Name.
+	Field string
+
+	// Descending indicates if the list is sorted in descending order of the field.
+	Descending bool
+}
+
+// String returns the field in the syntax of the sort query string variable.
+func (f SortField) String() string {
+	if f.Descending {
+		return "-" + f.Field
+	}
+	return f.Field
+}
+
+// sortableFields returns the names of the fields the Rules mark Sortable for the
+// version, in order.
+func sortableFields(rules Rules, version string) []string {
+	fields := []string{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		if rule.Sortable {
+			fields = append(fields, rule.Name())
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// listSort returns the SortFields parsed from the sort query string variable of a list
+// request to the ResourceHandler, nil if there isn't one, or a 400 error if it's
+// malformed or sorts by fields which aren't sortable.
+func listSort(ctx RequestContext, rules Rules, version string) ([]SortField, error) {
+	expr, _ := ctx.Value(sortKey).(string)
+	if expr == "" {
+		return nil, nil
+	}
+	fields, err := ParseSort(expr, sortableFields(rules, version))
+	if err != nil {
+		return nil, BadRequest(err.Error())
+	}
+	return fields, nil
+}
+
+// ParseSort parses the comma-separated fields of a sort expression, each descending if
+// prefixed with a hyphen or ascending, optionally prefixed with a plus, returning an
+// error if it's malformed or sorts by fields other than those given or by the same
+// field twice.
+func ParseSort(expr string, sortable []string) ([]SortField, error) {
+	fields := []SortField{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(expr, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		switch {
+		case strings.HasPrefix(field.Field, "-"):
+			field.Field, field.Descending = field.Field[1:], true
+		case strings.HasPrefix(field.Field, "+"):
+			field.Field = field.Field[1:]
+		}
+		if field.Field == "" {
+			return nil, fmt.Errorf("Invalid sort: empty field in %q", expr)
+		}
+		if !contains(sortable, field.Field) {
+			names := strings.Join(sortable, ", ")
+			if names == "" {
+				names = "none"
+			}
+			return nil, fmt.Errorf("Invalid sort: %s can't be sorted by; sortable fields are %s",
+				field.Field, names)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("Invalid sort: %s is given more than once", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that ParseSort parses ascending and descending fields in order, rejecting
+// fields which aren't sortable, empty fields and fields given twice.
+func TestParseSort(t *testing.T) {
+	assert := assert.New(t)
+	sortable := []string{"created_at", "name"}
+
+	fields, err := ParseSort("-created_at,name", sortable)
+	assert.Nil(err)
+	assert.Equal([]SortField{{Field: "created_at", Descending: true}, {Field: "name"}}, fields)
+	fields, err = ParseSort("+name", sortable)
+	assert.Nil(err)
+	assert.Equal([]SortField{{Field: "name"}}, fields)
+	assert.Equal("-created_at", SortField{Field: "created_at", Descending: true}.String())
+
+	for _, expr := range []string{"size", "name,", "-", "name,-name"} {
+		_, err := ParseSort(expr, sortable)
+		assert.NotNil(err, expr)
+	}
+	_, err = ParseSort("name", nil)
+	assert.EqualError(err, "Invalid sort: name can't be sorted by; sortable fields are none")
+}
+
+// Ensures that list requests are sorted with the sort query string variable, only by
+// the fields marked Sortable, and that the sort is documented.
+func TestListSort(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(tasksResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks?sort=-size,name", "")
+	assert.Equal(200, code)
+	names := []interface{}{}
+	for _, result := range envelope["results"].([]interface{}) {
+		names = append(names, result.(map[string]interface{})["name"])
+	}
+	assert.Equal([]interface{}{"c", "b", "d", "a"}, names)
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks?sort=status", "")
+	assert.Equal(400, code)
+	assert.Equal([]interface{}{"Invalid sort: status can't be sorted by; sortable fields are name, size"},
+		envelope["messages"])
+
+	assert.Contains(queryParameters(api, "/api/v{version}/tasks"), sortKey)
+}
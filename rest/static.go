@@ -0,0 +1,130 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// staticHandler serves the files of a file system mounted at a path prefix.
+type staticHandler struct {
+	prefix   string
+	root     http.FileSystem
+	index    string
+	notFound http.Handler
+}
+
+// RegisterStatic serves the files of the root, e.g. http.Dir("public") or
+// http.FS(assets) for embedded files, at paths beginning with the prefix which aren't
+// claimed by any route, so a UI can be served alongside the API. Paths beginning with
+// the first segment of a route, e.g. /api, are claimed by the API. Directories are served
+// their index.html file. If index is set, GET requests for HTML with no matching file
+// are served the index file instead, e.g. "/index.html", so single-page applications
+// using the history API can handle them.
+func (r *muxAPI) RegisterStatic(prefix string, root http.FileSystem, index string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.static = append(r.static, &staticHandler{
+		prefix:   "/" + strings.Trim(prefix, "/"),
+		root:     root,
+		index:    index,
+		notFound: r.fallback(),
+	})
+	sort.SliceStable(r.static, func(i, j int) bool {
+		return len(r.static[i].prefix) > len(r.static[j].prefix)
+	})
+}
+
+// unmatchedHandler returns the Handler serving the request if it doesn't match any
+// route: the static files mounted at its path or the Configuration's Fallback. It
+// returns nil if the request matches a route or there is no such Handler. The caller
+// must hold the read lock.
+func (r *muxAPI) unmatchedHandler(req *http.Request) http.Handler {
+	if (r.config.Fallback == nil && len(r.static) == 0) || r.matchers.match(req) {
+		return nil
+	}
+	if (req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		!r.claimsPath(req.URL.Path) {
+		for _, static := range r.static {
+			if static.matches(req.URL.Path) {
+				return static
+			}
+		}
+	}
+	return r.config.Fallback
+}
+
+// fallback returns the Handler of requests which don't match any route or static file.
+func (r *muxAPI) fallback() http.Handler {
+	if r.config.Fallback != nil {
+		return r.config.Fallback
+	}
+	return r.notFound
+}
+
+// claimsPath indicates if the path begins with the first segment of a route, e.g. /api,
+// in which case it's left to the API even if no route matches.
+func (r *muxAPI) claimsPath(urlPath string) bool {
+	segment := strings.SplitN(strings.TrimPrefix(urlPath, "/"), "/", 2)[0]
+	_, ok := r.claimed[segment]
+	return ok
+}
+
+// matches indicates if the path begins with the prefix.
+func (s *staticHandler) matches(urlPath string) bool {
+	if s.prefix == "/" {
+		return true
+	}
+	return urlPath == s.prefix || strings.HasPrefix(urlPath, s.prefix+"/")
+}
+
+// ServeHTTP serves the requested file, falling back to the index file for requests for
+// HTML. If there is neither, the request is passed to the Configuration's Fallback or
+// responded to with a 404.
+func (s *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + strings.TrimPrefix(r.URL.Path, s.prefix))
+	if s.serveFile(w, r, name) {
+		return
+	}
+	if s.index != "" && strings.Contains(r.Header.Get("Accept"), "text/html") &&
+		s.serveFile(w, r, s.index) {
+		return
+	}
+	s.notFound.ServeHTTP(w, r)
+}
+
+// serveFile serves the named file or, if it's a directory, its index.html file and
+// reports whether it exists.
+func (s *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) bool {
+	f, err := s.root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return s.serveFile(w, r, path.Join(name, "index.html"))
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	return true
+}
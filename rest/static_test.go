@@ -0,0 +1,105 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStaticAPI returns an API serving a single-page application at / alongside a
+// resource.
+func newStaticAPI() API {
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	api.RegisterStatic("/", http.FS(fstest.MapFS{
+		"index.html":      {Data: []byte("<html>app</html>")},
+		"assets/app.js":   {Data: []byte("run()")},
+		"docs/index.html": {Data: []byte("<html>docs</html>")},
+	}), "/index.html")
+	api.RegisterStatic("/files", http.FS(fstest.MapFS{
+		"report.txt": {Data: []byte("report")},
+	}), "")
+	return api
+}
+
+// Ensures that static files are served for paths which don't match a route, with
+// requests for HTML falling back to the index file.
+func TestRegisterStatic(t *testing.T) {
+	assert := assert.New(t)
+	api := newStaticAPI()
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/assets/app.js", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("run()", w.Body.String())
+	assert.Contains(w.Header().Get("Content-Type"), "javascript")
+
+	assert.Equal("<html>app</html>", get("/", "text/html").Body.String())
+	assert.Equal("<html>docs</html>", get("/docs/", "").Body.String())
+	assert.Equal("<html>app</html>", get("/settings/profile", "text/html,*/*").Body.String())
+	assert.Equal(http.StatusNotFound, get("/settings/profile", "application/json").Code)
+	assert.Equal(http.StatusNotFound, get("/../index.html/x", "").Code)
+
+	assert.Equal("report", get("/files/report.txt", "").Body.String())
+	assert.Equal(http.StatusNotFound, get("/files/missing.txt", "text/html").Code)
+
+	w = get("/api/v1/rows", "text/html")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `"results"`)
+	assert.NotContains(get("/api/v1/missing", "text/html").Body.String(), "app")
+}
+
+// Ensures that requests which don't match any route or static file are passed to the
+// Fallback, e.g. a reverse proxy to a legacy service.
+func TestFallback(t *testing.T) {
+	assert := assert.New(t)
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("legacy " + r.Method + " " + r.URL.Path))
+	}))
+	defer legacy.Close()
+	target, _ := url.Parse(legacy.URL)
+
+	api := NewAPI(&Configuration{Fallback: httputil.NewSingleHostReverseProxy(target)})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	api.RegisterStatic("/static", http.FS(fstest.MapFS{"app.js": {Data: []byte("run()")}}), "")
+	serve := func(method, path string) string {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Body.String()
+	}
+
+	assert.Contains(serve("GET", "/api/v1/rows"), `"results"`)
+	assert.Equal("legacy PATCH /api/v1/rows", serve("PATCH", "/api/v1/rows"))
+	assert.Equal("legacy GET /api/v2/orders/1", serve("GET", "/api/v2/orders/1"))
+	assert.Equal("run()", serve("GET", "/static/app.js"))
+	assert.Equal("legacy GET /static/missing.js", serve("GET", "/static/missing.js"))
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/pprof"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// RouteStats are the counters of the requests handled by a route, collected when the
+// Configuration enables Instrumentation.
+type RouteStats struct {
+	// Requests is the number of requests handled.
+	Requests uint64
+
+	// ServerErrors is the number of requests responded to with a 5xx status.
+	ServerErrors uint64
+
+	// ResponseBytes is the total size of the response bodies written.
+	ResponseBytes uint64
+
+	// HandlingTime is the total time spent handling requests, including
+	// serialization.
+	HandlingTime time.Duration
+
+	// SerializationTime is the total time spent serializing and writing responses.
+	SerializationTime time.Duration
+}
+
+// routeStats holds the counters of a route, which are accessed atomically.
+type routeStats struct {
+	requests       uint64
+	serverErrors   uint64
+	responseBytes  uint64
+	handlingNanos  int64
+	serializeNanos int64
+}
+
+// snapshot returns the current values of the counters.
+func (s *routeStats) snapshot() RouteStats {
+	return RouteStats{
+		Requests:          atomic.LoadUint64(&s.requests),
+		ServerErrors:      atomic.LoadUint64(&s.serverErrors),
+		ResponseBytes:     atomic.LoadUint64(&s.responseBytes),
+		HandlingTime:      time.Duration(atomic.LoadInt64(&s.handlingNanos)),
+		SerializationTime: time.Duration(atomic.LoadInt64(&s.serializeNanos)),
+	}
+}
+
+// statsProvider is implemented by APIs collecting RouteStats.
+type statsProvider interface {
+	routeStats() map[string]RouteStats
+}
+
+// Stats returns the RouteStats of each named route of the API, keyed by route name,
+// or nil if the API's Configuration doesn't enable Instrumentation.
+func Stats(api API) map[string]RouteStats {
+	provider, ok := api.(statsProvider)
+	if !ok || !api.Configuration().Instrumentation {
+		return nil
+	}
+	return provider.routeStats()
+}
+
+// routeStats returns the RouteStats of each named route.
+func (r *muxAPI) routeStats() map[string]RouteStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := make(map[string]RouteStats, len(r.stats))
+	for name, s := range r.stats {
+		stats[name] = s.snapshot()
+	}
+	return stats
+}
+
+// instrument returns a Handler counting the requests handled by the named route in
+// the stats and labeling the goroutine handling them with the route for profiling.
+func instrument(name string, stats *routeStats, next http.Handler) http.Handler {
+	labels := pprof.Labels("route", name)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statsRecorder{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), routeStatsKey, stats))
+		pprof.Do(r.Context(), labels, func(context.Context) {
+			next.ServeHTTP(recorder, r)
+		})
+
+		atomic.AddUint64(&stats.requests, 1)
+		atomic.AddInt64(&stats.handlingNanos, int64(time.Since(start)))
+		atomic.AddUint64(&stats.responseBytes, recorder.bytes)
+		if recorder.status >= http.StatusInternalServerError {
+			atomic.AddUint64(&stats.serverErrors, 1)
+		}
+	})
+}
+
+// statsRecorder is an http.ResponseWriter recording the response status and size.
+type statsRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  uint64
+}
+
+// WriteHeader records the status and writes it.
+func (s *statsRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, recording its size and a 200 status if none was written.
+func (s *statsRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += uint64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the ResponseWriter supports it.
+func (s *statsRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, e.g. for WebSockets, if the
+// ResponseWriter supports it. The bytes written afterwards aren't recorded.
+func (s *statsRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter doesn't support hijacking")
+	}
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// handleStats returns a Handler serving the RouteStats of each route, ordered by
+// route name.
+func (h requestHandler) handleStats() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, "")
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		stats := Stats(h.API)
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		routes := []interface{}{}
+		for _, name := range names {
+			s := stats[name]
+			routes = append(routes, map[string]interface{}{
+				"route":              name,
+				"requests":           s.Requests,
+				"server_errors":      s.ServerErrors,
+				"response_bytes":     s.ResponseBytes,
+				"handling_time":      s.HandlingTime.String(),
+				"serialization_time": s.SerializationTime.String(),
+			})
+		}
+
+		ctx = ctx.setResult(routes)
+		ctx = ctx.setStatus(http.StatusOK)
+		h.sendResponse(ctx)
+	})
+}
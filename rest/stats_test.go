@@ -0,0 +1,93 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReadResourceHandler struct {
+	largeResourceHandler
+}
+
+func (f failingReadResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+	return nil, InternalServerError("boom")
+}
+
+// Ensures that the requests handled by each route are counted when Instrumentation is
+// enabled and served at AdminPath/stats.
+func TestStats(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Instrumentation: true, AdminPath: "/admin",
+		Authenticate: authenticateAll})
+	api.RegisterResourceHandler(failingReadResourceHandler{largeResourceHandler{count: 2}})
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://foo.com/api/v1/rows", nil)
+		api.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req, _ := http.NewRequest("GET", "http://foo.com/api/v1/rows/1", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	stats := Stats(api)
+	list := stats["rows:"+string(HandleReadList)]
+	assert.Equal(uint64(3), list.Requests)
+	assert.Equal(uint64(0), list.ServerErrors)
+	assert.True(list.ResponseBytes > 0)
+	assert.True(list.HandlingTime >= list.SerializationTime)
+	assert.True(list.SerializationTime > 0)
+	read := stats["rows:"+string(HandleRead)]
+	assert.Equal(uint64(1), read.Requests)
+	assert.Equal(uint64(1), read.ServerErrors)
+	assert.Equal(uint64(resp.Body.Len()), read.ResponseBytes)
+
+	req, _ = http.NewRequest("GET", "http://foo.com/admin/stats", nil)
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+	var body map[string]interface{}
+	assert.Nil(json.Unmarshal(resp.Body.Bytes(), &body))
+	found := false
+	for _, route := range body["results"].([]interface{}) {
+		route := route.(map[string]interface{})
+		if route["route"] == "rows:"+string(HandleReadList) {
+			found = true
+			assert.Equal(float64(3), route["requests"])
+		}
+	}
+	assert.True(found)
+}
+
+// Ensures that Stats returns nil unless Instrumentation is enabled.
+func TestStatsDisabled(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{AdminPath: "/admin", Authenticate: authenticateAll})
+	api.RegisterResourceHandler(largeResourceHandler{count: 2})
+	assert.Nil(Stats(api))
+
+	req, _ := http.NewRequest("GET", "http://foo.com/admin/stats", nil)
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	assert.Equal(http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	// errNotStreamed is returned when reading the streamed body of a request which
+	// isn't streamed.
+	errNotStreamed = errors.New("Request body isn't streamed")
+
+	// errStreamRead is returned when reading the streamed body of a request more than
+	// once.
+	errStreamRead = errors.New("Request body has already been read")
+)
+
+// StreamingPayloadHandler is an optional interface implemented by ResourceHandlers to
+// decode large create and update request bodies incrementally rather than having the
+// whole body read into memory first. For streamed requests, the Payload passed to the
+// ResourceHandler is nil and the body is instead read using the PayloadDecoder or
+// PayloadItems of the RequestContext.
+type StreamingPayloadHandler interface {
+	// StreamPayload returns the limits applied to the streamed body of requests to the
+	// HandleMethod for the version and true, or false if the body should be read up
+	// front as usual. Only HandleCreate, HandleUpdate and HandleUpdateList requests can
+	// be streamed.
+	StreamPayload(method HandleMethod, version string) (StreamLimits, bool)
+}
+
+// StreamLimits bound the size of a streamed request body.
+type StreamLimits struct {
+	// MaxBytes is the maximum size of the body in bytes. If zero, the size isn't
+	// limited.
+	MaxBytes int64
+
+	// MaxItems is the maximum number of items decoded by PayloadItems. If zero, the
+	// number of items isn't limited.
+	MaxItems int
+}
+
+// streamLimits returns the StreamLimits for requests to the handler's HandleMethod for
+// the version and true if the handler is a StreamingPayloadHandler streaming them.
+func streamLimits(handler ResourceHandler, method HandleMethod, version string) (StreamLimits, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	streaming, ok := handler.(StreamingPayloadHandler)
+	if !ok {
+		return StreamLimits{}, false
+	}
+	return streaming.StreamPayload(method, version)
+}
+
+// isStreamed returns true if the body of the request is streamed.
+func isStreamed(ctx RequestContext) bool {
+	gctx, ok := ctx.(*gorillaRequestContext)
+	return ok && gctx.stream != nil
+}
+
+// payloadStream is the streamed body of a request.
+type payloadStream struct {
+	mu      sync.Mutex
+	body    io.Reader
+	limits  StreamLimits
+	rules   Rules
+	version string
+	read    bool
+
+	// closed is closed once the request has been handled, stopping PayloadItems.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newPayloadStream returns a payloadStream reading the body within the limits and
+// applying the inbound Rules for the version to the items decoded by PayloadItems.
+func newPayloadStream(body io.Reader, limits StreamLimits, rules Rules, version string) *payloadStream {
+	if body == nil {
+		body = http.NoBody
+	}
+	if limits.MaxBytes > 0 {
+		body = &limitedBody{body: body, remaining: limits.MaxBytes}
+	}
+	return &payloadStream{
+		body:    body,
+		limits:  limits,
+		rules:   rules,
+		version: version,
+		closed:  make(chan struct{}),
+	}
+}
+
+// close stops any items being decoded once the request has been handled.
+func (p *payloadStream) close() {
+	p.closeOnce.Do(func() { close(p.closed) })
+}
+
+// open returns the body, which can only be read once.
+func (p *payloadStream) open() (io.Reader, error) {
+	if p == nil {
+		return nil, errNotStreamed
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.read {
+		return nil, errStreamRead
+	}
+	p.read = true
+	return p.body, nil
+}
+
+// decoder returns a json.Decoder reading the body.
+func (p *payloadStream) decoder() (*json.Decoder, error) {
+	body, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	return json.NewDecoder(body), nil
+}
+
+// items decodes the body, sending each item on the returned channel until the body
+// has been read, done is closed or the stream is closed.
+func (p *payloadStream) items(done <-chan struct{}) (<-chan Payload, <-chan error) {
+	items := make(chan Payload)
+	errc := make(chan error, 1)
+	body, err := p.open()
+	if err != nil {
+		close(items)
+		errc <- err
+		return items, errc
+	}
+
+	go func() {
+		defer close(items)
+		errc <- p.decodeItems(bufio.NewReader(body), func(item Payload) bool {
+			select {
+			case items <- item:
+				return true
+			case <-done:
+				return false
+			case <-p.closed:
+				return false
+			}
+		})
+	}()
+	return items, errc
+}
+
+// decodeItems decodes a JSON array of objects or a single object from the body,
+// passing each item to send until it returns false.
+func (p *payloadStream) decodeItems(body *bufio.Reader, send func(Payload) bool) error {
+	first, err := peekNonSpace(body)
+	if err == io.EOF {
+		return BadRequest(errEmptyBody.Error())
+	}
+	if err != nil {
+		return streamError(err)
+	}
+
+	count := 0
+	emit := func(item Payload) error {
+		count++
+		if p.limits.MaxItems > 0 && count > p.limits.MaxItems {
+			return CustomError(fmt.Sprintf("Request body exceeds %d items", p.limits.MaxItems),
+				http.StatusRequestEntityTooLarge)
+		}
+		item, err := applyInboundRules(item, p.rules, p.version)
+		if err != nil {
+			// Type coercion failed.
+			return UnprocessableRequest(err.Error())
+		}
+		if !send(item) {
+			return context.Canceled
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(body)
+	switch first {
+	case '[':
+		if _, err := decoder.Token(); err != nil {
+			return streamError(err)
+		}
+		for decoder.More() {
+			var item Payload
+			if err := decoder.Decode(&item); err != nil {
+				return streamError(err)
+			}
+			if err := emit(item); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return streamError(err)
+		}
+		return nil
+	case '{':
+		var item Payload
+		if err := decoder.Decode(&item); err != nil {
+			return streamError(err)
+		}
+		return emit(item)
+	}
+	return BadRequest("Request body must be a JSON object or array of objects")
+}
+
+// peekNonSpace returns the first byte of the reader which isn't JSON whitespace,
+// discarding any whitespace before it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.Discard(1)
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// streamError returns the Error to respond with for an error decoding a streamed body.
+func streamError(err error) error {
+	if _, ok := err.(Error); ok {
+		return err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return BadRequest("Unexpected end of JSON input")
+	}
+	return BadRequest(decodeError(err).Error())
+}
+
+// limitedBody reads from the body until the remaining bytes are exhausted, after which
+// reads fail with a 413 Error.
+type limitedBody struct {
+	body      io.Reader
+	remaining int64
+}
+
+// Read reads from the body, failing if it exceeds the limit.
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, CustomError("Request body too large", http.StatusRequestEntityTooLarge)
+	}
+	// Read one byte past the limit to detect bodies exceeding it.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		n += int(l.remaining)
+		return n, CustomError("Request body too large", http.StatusRequestEntityTooLarge)
+	}
+	return n, err
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type streamingResourceHandler struct {
+	BaseResourceHandler
+	limits StreamLimits
+	items  []Payload
+	body   string
+}
+
+func (s *streamingResourceHandler) ResourceName() string {
+	return "events"
+}
+
+func (s *streamingResourceHandler) Rules() Rules {
+	return NewRules((*TestResource)(nil), &Rule{Field: "Foo", FieldAlias: "foo", Type: String})
+}
+
+func (s *streamingResourceHandler) StreamPayload(method HandleMethod, version string) (StreamLimits, bool) {
+	return s.limits, method != HandleUpdate
+}
+
+func (s *streamingResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	s.body = ctx.Body().String()
+	items, errc := ctx.PayloadItems()
+	for item := range items {
+		s.items = append(s.items, item)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return &TestResource{Foo: "created"}, nil
+}
+
+func (s *streamingResourceHandler) UpdateResource(ctx RequestContext, id string,
+	data Payload, version string) (Resource, error) {
+	// Updates aren't streamed.
+	if _, err := ctx.PayloadDecoder(); err == nil {
+		return nil, InternalServerError("Update unexpectedly streamed")
+	}
+	return &TestResource{Foo: data["foo"].(string)}, nil
+}
+
+func (s *streamingResourceHandler) UpdateResourceList(ctx RequestContext, data []Payload,
+	version string) ([]Resource, error) {
+	decoder, err := ctx.PayloadDecoder()
+	if err != nil {
+		return nil, err
+	}
+	var items []TestResource
+	if err := decoder.Decode(&items); err != nil {
+		return nil, err
+	}
+	if _, err := ctx.PayloadDecoder(); err != errStreamRead {
+		return nil, InternalServerError("Body read twice")
+	}
+	resources := make([]Resource, len(items))
+	for i := range items {
+		resources[i] = &items[i]
+	}
+	return resources, nil
+}
+
+// serveStreamingRequest serves a request with the body to the API.
+func serveStreamingRequest(api API, method, url, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+	return resp
+}
+
+// Ensures that the items of a streamed request body are decoded with the inbound Rules
+// applied and that the body isn't buffered.
+func TestStreamingPayloadItems(t *testing.T) {
+	assert := assert.New(t)
+	handler := &streamingResourceHandler{}
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(handler)
+
+	resp := serveStreamingRequest(api, "POST", "http://foo.com/api/v1/events",
+		` [{"foo": "a", "bar": 1}, {"foo": "b"}] `)
+	assert.Equal(http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal([]Payload{{"foo": "a"}, {"foo": "b"}}, handler.items)
+	assert.Equal("", handler.body)
+
+	handler.items = nil
+	resp = serveStreamingRequest(api, "POST", "http://foo.com/api/v1/events", `{"foo": "c"}`)
+	assert.Equal(http.StatusCreated, resp.Code)
+	assert.Equal([]Payload{{"foo": "c"}}, handler.items)
+}
+
+// Ensures that malformed or invalid streamed request bodies are rejected.
+func TestStreamingPayloadItemsInvalid(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(&streamingResourceHandler{})
+
+	for body, status := range map[string]int{
+		``:                      http.StatusBadRequest,
+		`"foo"`:                 http.StatusBadRequest,
+		`[{"foo": "a"}, {"foo"`: http.StatusBadRequest,
+		`[{"foo": {"a": 1}}]`:   statusUnprocessableEntity,
+	} {
+		resp := serveStreamingRequest(api, "POST", "http://foo.com/api/v1/events", body)
+		assert.Equal(status, resp.Code, body)
+	}
+}
+
+// Ensures that the StreamLimits are enforced with a 413 response.
+func TestStreamingPayloadLimits(t *testing.T) {
+	assert := assert.New(t)
+	handler := &streamingResourceHandler{limits: StreamLimits{MaxItems: 2}}
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(handler)
+
+	resp := serveStreamingRequest(api, "POST", "http://foo.com/api/v1/events",
+		`[{"foo": "a"}, {"foo": "b"}, {"foo": "c"}]`)
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Len(handler.items, 2)
+
+	handler.limits = StreamLimits{MaxBytes: 20}
+	resp = serveStreamingRequest(api, "POST", "http://foo.com/api/v1/events",
+		`[{"foo": "a"}, {"foo": "b"}, {"foo": "c"}]`)
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.Code)
+
+	resp = serveStreamingRequest(api, "PUT", "http://foo.com/api/v1/events",
+		`[{"foo": "a"}, {"foo": "b"}, {"foo": "c"}]`)
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.Code)
+}
+
+// Ensures that a streamed request body can be decoded with the PayloadDecoder and that
+// requests which aren't streamed are read up front.
+func TestStreamingPayloadDecoder(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(&streamingResourceHandler{})
+
+	resp := serveStreamingRequest(api, "PUT", "http://foo.com/api/v1/events",
+		`[{"Foo": "a"}, {"Foo": "b"}]`)
+	assert.Equal(http.StatusOK, resp.Code, resp.Body.String())
+	assert.Contains(resp.Body.String(), `"foo":"b"`)
+
+	resp = serveStreamingRequest(api, "PUT", "http://foo.com/api/v1/events/1", `{"foo": "c"}`)
+	assert.Equal(http.StatusOK, resp.Code, resp.Body.String())
+}
+
+// Ensures that limitedBody fails once the limit is exceeded.
+func TestLimitedBody(t *testing.T) {
+	assert := assert.New(t)
+	body, err := ioutil.ReadAll(&limitedBody{body: bytes.NewBufferString("hello"), remaining: 5})
+	assert.Nil(err)
+	assert.Equal("hello", string(body))
+
+	body, err = ioutil.ReadAll(&limitedBody{body: bytes.NewBufferString("hello!"), remaining: 5})
+	assert.Equal("hello", string(body))
+	assert.Equal(http.StatusRequestEntityTooLarge, err.(Error).Status())
+}
+
+// Ensures that decoding the items of a streamed body stops once the stream is closed,
+// even if they aren't received.
+func TestPayloadStreamClose(t *testing.T) {
+	stream := newPayloadStream(strings.NewReader(`[{"foo": "a"}, {"foo": "b"}]`),
+		StreamLimits{}, &rules{}, "1")
+	items, errc := stream.items(nil)
+	<-items
+	stream.close()
+	assert.Equal(t, context.Canceled, <-errc)
+}
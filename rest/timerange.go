@@ -0,0 +1,162 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// fromKey is the name of the query string variable giving the start of a time
+	// range.
+	fromKey = "from"
+
+	// toKey is the name of the query string variable giving the end of a time range.
+	toKey = "to"
+
+	// timezoneKey is the name of the query string variable giving the IANA time zone,
+	// e.g. Europe/Paris, of times in a time range without an offset.
+	timezoneKey = "tz"
+)
+
+// relativeTimeRegex matches the offsets of relative times, e.g. the -1d and +6h of
+// now-1d+6h.
+var relativeTimeRegex = regexp.MustCompile(`([+-])(\d+)(ms|s|m|h|d|w)`)
+
+// TimeRange is the range of times parsed from the from and to query string variables
+// of a request, e.g. ?from=now-24h&to=now or ?from=2024-01-01&tz=Europe/Paris. It
+// includes From and excludes To, and either is zero if it's not given, leaving the
+// range unbounded on that side.
+type TimeRange struct {
+	// From is the start of the range, inclusive.
+	From time.Time
+
+	// To is the end of the range, exclusive.
+	To time.Time
+}
+
+// IsZero indicates if the range is unbounded on both sides, i.e. if neither from nor
+// to was given.
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains indicates if the time is within the range, for ResourceHandlers filtering
+// resources in memory.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// ParseTimeRange parses the from and to times of a range, either of which may be
+// empty. Times are RFC 3339, e.g. 2024-01-01T12:00:00Z, dates, e.g. 2024-01-01, which
+// are midnight, local times without an offset, e.g. 2024-01-01T12:00:00, or relative
+// to now, e.g. now, now-24h or now-1w+3d. Relative offsets are numbers of ms, s, m, h,
+// d or w, with days and weeks being calendar days. Times without an offset, and
+// calendar days, are in the location, and the range is returned in it. It returns an
+// error if either time is malformed or from is after to.
+func ParseTimeRange(from, to string, now time.Time, loc *time.Location) (TimeRange, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	var r TimeRange
+	var err error
+	if r.From, err = parseRangeTime(from, now, loc); err != nil {
+		return TimeRange{}, fmt.Errorf("Invalid %s: %s", fromKey, err)
+	}
+	if r.To, err = parseRangeTime(to, now, loc); err != nil {
+		return TimeRange{}, fmt.Errorf("Invalid %s: %s", toKey, err)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return TimeRange{}, fmt.Errorf("Invalid time range: %s %s is after %s %s",
+			fromKey, from, toKey, to)
+	}
+	return r, nil
+}
+
+// parseRangeTime parses a time of a range in the location, returning the zero time if
+// it's empty.
+func parseRangeTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case strings.HasPrefix(value, "now"):
+		offsets := value[len("now"):]
+		if relativeTimeRegex.ReplaceAllString(offsets, "") != "" {
+			return time.Time{}, fmt.Errorf("%q isn't a relative time, e.g. now-24h", value)
+		}
+		t := now
+		for _, match := range relativeTimeRegex.FindAllStringSubmatch(offsets, -1) {
+			n, err := strconv.Atoi(match[2])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%q isn't a relative time, e.g. now-24h", value)
+			}
+			if match[1] == "-" {
+				n = -n
+			}
+			switch match[3] {
+			case "w":
+				t = t.AddDate(0, 0, 7*n)
+			case "d":
+				t = t.AddDate(0, 0, n)
+			default:
+				unit, _ := time.ParseDuration("1" + match[3])
+				t = t.Add(time.Duration(n) * unit)
+			}
+		}
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q isn't an RFC 3339 time, date or relative time", value)
+}
+
+// requestTimeRange returns the TimeRange parsed from the query string variables of the
+// request, or a 400 error if they're malformed.
+func requestTimeRange(ctx RequestContext) (TimeRange, error) {
+	from, _ := ctx.Value(fromKey).(string)
+	to, _ := ctx.Value(toKey).(string)
+	tz, _ := ctx.Value(timezoneKey).(string)
+	if from == "" && to == "" {
+		return TimeRange{}, nil
+	}
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return TimeRange{}, BadRequest(fmt.Sprintf("Invalid %s: unknown time zone %q",
+				timezoneKey, tz))
+		}
+	}
+	r, err := ParseTimeRange(from, to, time.Now(), loc)
+	if err != nil {
+		return TimeRange{}, BadRequest(err.Error())
+	}
+	return r, nil
+}
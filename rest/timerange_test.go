@@ -0,0 +1,127 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timeRangeResourceHandler is a ResourceHandler listing the time range of requests.
+type timeRangeResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (t timeRangeResourceHandler) ResourceName() string {
+	return "ranges"
+}
+
+func (t timeRangeResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+	r := ctx.TimeRange()
+	return []Resource{Payload{"from": r.From, "to": r.To}}, "", nil
+}
+
+// Ensures that ParseTimeRange parses RFC 3339 times, dates, local times and relative
+// times in the location.
+func TestParseTimeRange(t *testing.T) {
+	assert := assert.New(t)
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	now := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2024-01-01T00:00:00Z", time.Date(2024, 1, 1, 2, 0, 0, 0, loc)},
+		{"2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, loc)},
+		{"2024-01-01T08:15:00", time.Date(2024, 1, 1, 8, 15, 0, 0, loc)},
+		{"now", time.Date(2024, 3, 10, 14, 30, 0, 0, loc)},
+		{"now-24h", time.Date(2024, 3, 9, 14, 30, 0, 0, loc)},
+		{"now-1w+3d-30m", time.Date(2024, 3, 6, 14, 0, 0, 0, loc)},
+	} {
+		r, err := ParseTimeRange(test.value, "", now, loc)
+		if assert.Nil(err, test.value) {
+			assert.True(test.expected.Equal(r.From), test.value)
+			assert.Equal(loc, r.From.Location(), test.value)
+			assert.True(r.To.IsZero(), test.value)
+		}
+	}
+}
+
+// Ensures that ParseTimeRange rejects malformed times and ranges ending before they
+// start.
+func TestParseTimeRangeInvalid(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	for _, test := range [][2]string{
+		{"yesterday", ""},
+		{"now-24x", ""},
+		{"now24h", ""},
+		{"", "2024-13-01"},
+		{"now", "now-1h"},
+	} {
+		_, err := ParseTimeRange(test[0], test[1], now, time.UTC)
+		assert.Error(err, test[0]+" "+test[1])
+	}
+}
+
+// Ensures that TimeRange contains times from its start up to, excluding, its end, and
+// is unbounded on sides which aren't given.
+func TestTimeRangeContains(t *testing.T) {
+	assert := assert.New(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	assert.True(TimeRange{From: from, To: to}.Contains(from))
+	assert.False(TimeRange{From: from, To: to}.Contains(to))
+	assert.False(TimeRange{From: from}.Contains(from.Add(-time.Second)))
+	assert.True(TimeRange{To: to}.Contains(from.AddDate(-1, 0, 0)))
+	assert.True(TimeRange{}.IsZero())
+}
+
+// Ensures that the time range of list requests is available from the context.
+func TestListTimeRange(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(timeRangeResourceHandler{})
+
+	code, envelope := serveJSON(api, "GET",
+		"/api/v1/ranges?from=2024-01-01&to=2024-01-02T00:00:00%2B01:00", "")
+
+	if assert.Equal(http.StatusOK, code) {
+		r := envelope["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal("2024-01-01T00:00:00Z", r["from"])
+		assert.Equal("2024-01-01T23:00:00Z", r["to"])
+	}
+}
+
+// Ensures that list requests with malformed time ranges or time zones are rejected.
+func TestListTimeRangeInvalid(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(timeRangeResourceHandler{})
+
+	for _, query := range []string{"from=soon", "from=now&to=now-1d", "from=now&tz=Mars/Olympus"} {
+		code, _ := serveJSON(api, "GET", "/api/v1/ranges?"+query, "")
+		assert.Equal(http.StatusBadRequest, code, query)
+	}
+}
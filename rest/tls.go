@@ -0,0 +1,203 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// acmeTLSProtocol is the ALPN protocol of the ACME TLS-ALPN-01 challenge.
+const acmeTLSProtocol = "acme-tls/1"
+
+// CertificateManager obtains and renews certificates automatically, e.g. from Let's
+// Encrypt. It's implemented by *autocert.Manager (golang.org/x/crypto/acme/autocert),
+// whose HostPolicy restricts the domains certificates are obtained for:
+//
+//	manager := &autocert.Manager{
+//		Prompt:     autocert.AcceptTOS,
+//		HostPolicy: autocert.HostWhitelist("api.example.com"),
+//		Cache:      autocert.DirCache("certs"),
+//	}
+type CertificateManager interface {
+	// GetCertificate returns the certificate for the TLS handshake, obtaining or
+	// renewing it if necessary.
+	GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// HTTPHandler returns a Handler responding to the HTTP-01 challenges of the
+	// certificate authority and passing other requests to the fallback.
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// defaultTLSConfig returns the tls.Config used by StartTLS when ServerConfig.TLS isn't
+// set. It requires TLS 1.2 or later and, for TLS 1.2, only forward-secret AEAD cipher
+// suites.
+func defaultTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
+
+// certReloader serves a certificate loaded from files, reloading it when they change
+// so certificates can be rotated without restarting the server.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTime  time.Time
+}
+
+// newCertReloader returns a certReloader serving the certificate and matching private
+// key in the files, or an error if they can't be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload loads the certificate from the files. The current certificate continues to
+// be served if they can't be loaded.
+func (c *certReloader) reload() error {
+	modTime := c.filesModTime()
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+// filesModTime returns the latest modification time of the files.
+func (c *certReloader) filesModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// changed indicates if the files have been modified since they were last loaded.
+func (c *certReloader) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.filesModTime().Equal(c.modTime)
+}
+
+// getCertificate returns the current certificate. It's used as
+// tls.Config.GetCertificate.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// watch reloads the certificate whenever the files are found to have changed, checking
+// at the interval if it's positive, and whenever the process receives SIGHUP if sighup
+// is set. Failed reloads are logged. The returned function stops watching.
+func (c *certReloader) watch(interval time.Duration, sighup bool) func() {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	var hup chan os.Signal
+	if sighup {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick:
+				if !c.changed() {
+					continue
+				}
+			case <-hup:
+			}
+			if err := c.reload(); err != nil {
+				log.Printf("Failed to reload TLS certificate: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		if hup != nil {
+			signal.Stop(hup)
+		}
+	}
+}
+
+// tlsConfig returns the tls.Config of the servers run by StartTLS: a copy of
+// ServerConfig.TLS or, if it isn't set, the defaults. Certificates are served by the
+// certReloader if it's given, otherwise by the ServerConfig's CertificateManager if
+// it's set.
+func (r *muxAPI) tlsConfig(certs *certReloader) *tls.Config {
+	config := defaultTLSConfig()
+	if r.config.Server != nil && r.config.Server.TLS != nil {
+		config = r.config.Server.TLS.Clone()
+	}
+	if certs != nil {
+		config.Certificates = nil
+		config.GetCertificate = certs.getCertificate
+	} else if manager := r.certManager(); manager != nil {
+		config.Certificates = nil
+		config.GetCertificate = manager.GetCertificate
+		config.NextProtos = append(config.NextProtos, acmeTLSProtocol)
+	}
+	return config
+}
+
+// certManager returns the CertificateManager of the ServerConfig or nil if there is
+// none.
+func (r *muxAPI) certManager() CertificateManager {
+	if r.config.Server == nil {
+		return nil
+	}
+	return r.config.Server.CertManager
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCert writes a self-signed certificate for the common name and its private
+// key to cert.pem and key.pem in the directory, returning their paths.
+func writeTestCert(t *testing.T, dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// commonName returns the common name of the certificate served by the reloader.
+func commonName(c *certReloader) string {
+	cert, _ := c.getCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return ""
+	}
+	return leaf.Subject.CommonName
+}
+
+// Ensures that the certReloader reloads the certificate once its files change and
+// keeps serving the current one if they can't be loaded.
+func TestCertReloader(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "first")
+	certs, err := newCertReloader(certFile, keyFile)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("first", commonName(certs))
+	assert.False(certs.changed())
+
+	stop := certs.watch(10*time.Millisecond, false)
+	defer stop()
+	writeTestCert(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	for i := 0; i < 100 && commonName(certs) != "second"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal("second", commonName(certs))
+
+	ioutil.WriteFile(keyFile, []byte("invalid"), 0600)
+	assert.NotNil(certs.reload())
+	assert.Equal("second", commonName(certs))
+
+	_, err = newCertReloader(filepath.Join(dir, "missing.pem"), keyFile)
+	assert.NotNil(err)
+}
+
+// Ensures that servers run by StartTLS use modern defaults or a copy of the configured
+// tls.Config, with certificates served by the certReloader.
+func TestTLSConfig(t *testing.T) {
+	assert := assert.New(t)
+	certFile, keyFile := writeTestCert(t, t.TempDir(), "localhost")
+	certs, err := newCertReloader(certFile, keyFile)
+	if !assert.Nil(err) {
+		return
+	}
+
+	config := NewAPI(&Configuration{}).(*muxAPI).tlsConfig(certs)
+	assert.Equal(uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Contains(config.CipherSuites, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+	assert.NotContains(config.CipherSuites, tls.TLS_RSA_WITH_AES_128_CBC_SHA)
+	assert.NotNil(config.GetCertificate)
+
+	custom := &tls.Config{MinVersion: tls.VersionTLS13, Certificates: []tls.Certificate{{}}}
+	api := NewAPI(&Configuration{Server: &ServerConfig{TLS: custom}}).(*muxAPI)
+	config = api.tlsConfig(certs)
+	assert.Equal(uint16(tls.VersionTLS13), config.MinVersion)
+	assert.Nil(config.Certificates)
+	assert.Len(custom.Certificates, 1)
+	assert.Len(api.tlsConfig(nil).Certificates, 1)
+}
+
+// Ensures that StartTLS serves requests with the certificate from the files.
+func TestStartTLS(t *testing.T) {
+	assert := assert.New(t)
+	certFile, keyFile := writeTestCert(t, t.TempDir(), "localhost")
+	api := NewAPI(&Configuration{Server: &ServerConfig{CertReloadInterval: time.Minute}})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	addr, done := startTestServer(t, api, func(addr Address) error {
+		return api.StartTLS(addr, FilePath(certFile), FilePath(keyFile))
+	})
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Get("https://" + addr + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("localhost", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	}
+
+	assert.Nil(api.Stop())
+	assert.Nil(<-done)
+}
+
+// fakeCertManager is a CertificateManager serving a fixed certificate and responding
+// to a single HTTP-01 challenge.
+type fakeCertManager struct {
+	cert *tls.Certificate
+}
+
+func (f *fakeCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return f.cert, nil
+}
+
+func (f *fakeCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/acme-challenge/token" {
+			w.Write([]byte("token.thumbprint"))
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// Ensures that StartTLS serves certificates from the CertManager when it isn't given
+// files and that Start responds to its HTTP-01 challenges alongside the API routes.
+func TestCertManager(t *testing.T) {
+	assert := assert.New(t)
+	certFile, keyFile := writeTestCert(t, t.TempDir(), "managed")
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if !assert.Nil(err) {
+		return
+	}
+	api := NewAPI(&Configuration{Server: &ServerConfig{CertManager: &fakeCertManager{&cert}}})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+
+	config := api.(*muxAPI).tlsConfig(nil)
+	assert.Contains(config.NextProtos, acmeTLSProtocol)
+	tlsAddr, _ := startTestServer(t, api, func(addr Address) error {
+		return api.StartTLS(addr, "", "")
+	})
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Get("https://" + tlsAddr + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("managed", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	}
+
+	addr, _ := startTestAPI(t, api)
+	resp, err = http.Get("http://" + addr + "/.well-known/acme-challenge/token")
+	if assert.Nil(err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal("token.thumbprint", string(body))
+	}
+	resp, err = http.Get("http://" + addr + "/api/v1/rows")
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+}
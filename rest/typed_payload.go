@@ -0,0 +1,146 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// TypedPayloadHandler is an optional interface implemented by ResourceHandlers which
+// decode the JSON payloads of create and update requests into structs rather than
+// Payloads. The decoded value is available through RequestContext.TypedPayload, the
+// Payloads passed to CreateResource, UpdateResource and UpdateResourceList are nil and
+// inbound Rules aren't applied. Multipart requests are still decoded into Payloads.
+type TypedPayloadHandler interface {
+	// NewPayload returns a pointer to a new value to decode the payload of a request
+	// for the version into, e.g. &Widget{}.
+	NewPayload(version string) interface{}
+}
+
+// typedPayloadHandler returns the ResourceHandler as a TypedPayloadHandler if it is
+// one.
+func typedPayloadHandler(handler ResourceHandler) (TypedPayloadHandler, bool) {
+	if proxy, ok := handler.(resourceHandlerProxy); ok {
+		handler = proxy.ResourceHandler
+	}
+	typed, ok := handler.(TypedPayloadHandler)
+	return typed, ok
+}
+
+// decodeInput returns the request payload with the inbound Rules applied. If the
+// handler is a TypedPayloadHandler, the payload is instead decoded into its payload
+// type and set as the TypedPayload of the returned RequestContext. If the body is
+// streamed, no payload is returned. The error returned is the one to respond with if
+// the payload is invalid.
+func decodeInput(ctx RequestContext, handler ResourceHandler, rules Rules, version string) (
+	RequestContext, Payload, error) {
+
+	if isStreamed(ctx) {
+		return ctx, nil, nil
+	}
+	if typed, ok := typedPayloadHandler(handler); ok && !isMultipartRequest(ctx) {
+		payload := typed.NewPayload(version)
+		if err := decodeTypedPayload(ctx.Body().Bytes(), payload); err != nil {
+			return ctx, nil, err
+		}
+		return ctx.WithValue(typedPayloadKey, payload), nil, nil
+	}
+
+	data, err := decodeRequestPayload(ctx)
+	if err != nil {
+		// Payload decoding failed.
+		return ctx, nil, BadRequest(err.Error())
+	}
+	data, err = applyInboundRules(data, rules, version)
+	if err != nil {
+		// Type coercion failed.
+		return ctx, nil, UnprocessableRequest(err.Error())
+	}
+	return ctx, data, nil
+}
+
+// decodeInputList returns the list of request payloads with the inbound Rules
+// applied, accepting a single payload as a list of one. If the handler is a
+// TypedPayloadHandler, the payloads are instead decoded into its payload type and set
+// as the TypedPayload of the returned RequestContext as a []interface{}. If the body
+// is streamed, no payloads are returned.
+func decodeInputList(ctx RequestContext, handler ResourceHandler, rules Rules,
+	version string) (
+	RequestContext, []Payload, error) {
+
+	if isStreamed(ctx) {
+		return ctx, nil, nil
+	}
+	body := ctx.Body().Bytes()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ctx, nil, BadRequest(errEmptyBody.Error())
+	}
+
+	if typed, ok := typedPayloadHandler(handler); ok {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			items = []json.RawMessage{body}
+		}
+		payloads := make([]interface{}, len(items))
+		for i, item := range items {
+			payloads[i] = typed.NewPayload(version)
+			if err := decodeTypedPayload(item, payloads[i]); err != nil {
+				return ctx, nil, err
+			}
+		}
+		return ctx.WithValue(typedPayloadKey, payloads), nil, nil
+	}
+
+	data, err := decodePayloadSlice(body)
+	if err != nil {
+		var p Payload
+		if p, err = decodePayload(body); err != nil {
+			return ctx, nil, BadRequest(decodeError(err).Error())
+		}
+		data = []Payload{p}
+	}
+	for i := range data {
+		if data[i], err = applyInboundRules(data[i], rules, version); err != nil {
+			// Type coercion failed.
+			return ctx, nil, UnprocessableRequest(err.Error())
+		}
+	}
+	return ctx, data, nil
+}
+
+// decodeTypedPayload decodes the JSON body into the value, returning a 400 error if it
+// is malformed or a 422 error if a field has the wrong type.
+func decodeTypedPayload(body []byte, value interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return BadRequest(errEmptyBody.Error())
+	}
+	err := json.Unmarshal(body, value)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*json.UnmarshalTypeError); ok {
+		return UnprocessableRequest(decodeError(err).Error())
+	}
+	return BadRequest(decodeError(err).Error())
+}
+
+// isMultipartRequest indicates if the request body is multipart/form-data.
+func isMultipartRequest(ctx RequestContext) bool {
+	_, ok := isMultipart(ctx.Header().Get("Content-Type"))
+	return ok
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// typedWidget is a payload type decoded by typedResourceHandler.
+type typedWidget struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// typedResourceHandler is a TypedPayloadHandler echoing decoded payloads.
+type typedResourceHandler struct {
+	TestResourceHandler
+}
+
+func (t typedResourceHandler) NewPayload(version string) interface{} {
+	return &typedWidget{}
+}
+
+func (t typedResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+
+	if data != nil {
+		return nil, InternalServerError("unexpected payload")
+	}
+	return ctx.TypedPayload(), nil
+}
+
+func (t typedResourceHandler) UpdateResourceList(ctx RequestContext, data []Payload,
+	version string) ([]Resource, error) {
+
+	resources := []Resource{}
+	for _, widget := range ctx.TypedPayload().([]interface{}) {
+		resources = append(resources, widget)
+	}
+	return resources, nil
+}
+
+// sendTypedRequest sends a request with the JSON body to an API serving
+// typedResourceHandler.
+func sendTypedRequest(method, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(typedResourceHandler{})
+	req, _ := http.NewRequest(method, "http://example.com/api/v1/widgets", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+
+	var payload map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &payload)
+	return w, payload
+}
+
+// Ensures that TypedPayloadHandlers receive payloads decoded into their type.
+func TestTypedPayloadCreate(t *testing.T) {
+	assert := assert.New(t)
+	w, payload := sendTypedRequest("POST", `{"name": "sprocket", "count": 3}`)
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal(map[string]interface{}{"name": "sprocket", "count": float64(3)}, payload["result"])
+}
+
+// Ensures that malformed typed payloads are rejected with a 400 and payloads with
+// fields of the wrong type with a 422.
+func TestTypedPayloadErrors(t *testing.T) {
+	assert := assert.New(t)
+	w, _ := sendTypedRequest("POST", `{"name": `)
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	w, _ = sendTypedRequest("POST", ``)
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	w, payload := sendTypedRequest("POST", `{"count": "three"}`)
+	assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(payload["messages"].([]interface{})[0], "offset")
+}
+
+// Ensures that list updates decode each payload, accepting a single payload.
+func TestTypedPayloadUpdateList(t *testing.T) {
+	assert := assert.New(t)
+	w, payload := sendTypedRequest("PUT", `[{"name": "a"}, {"name": "b", "count": 1}]`)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"name": "a", "count": float64(0)},
+		map[string]interface{}{"name": "b", "count": float64(1)},
+	}, payload["results"])
+
+	w, payload = sendTypedRequest("PUT", `{"name": "c"}`)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Len(payload["results"], 1)
+
+	w, _ = sendTypedRequest("PUT", `[{"count": true}]`)
+	assert.Equal(http.StatusUnprocessableEntity, w.Code)
+}
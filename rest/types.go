@@ -74,6 +74,11 @@ var typeToName = map[Type]string{
 	Time:      "time.Time",
 }
 
+// String returns the Go name of the Type, e.g. "int64" or "time.Time".
+func (t Type) String() string {
+	return typeToName[t]
+}
+
 // typeToKind maps Types to their reflect Kind.
 var typeToKind = map[Type]reflect.Kind{
 	Interface: reflect.Interface,
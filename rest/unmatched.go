@@ -0,0 +1,145 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// routerHandler dispatches requests with the Router of an API, responding to requests
+// which don't match any route in the standard envelope rather than with the Router's
+// 404 and 405 responses.
+type routerHandler struct {
+	api *muxAPI
+}
+
+// ServeHTTP dispatches the request with the Router, serving the API's NotFound or
+// MethodNotAllowed handler if it isn't handled by a route. Routes can't be registered
+// while the Router matches the request, which is released once a route handles it.
+func (h routerHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.api.routerMu.RLock()
+	var once sync.Once
+	release := func() { once.Do(h.api.routerMu.RUnlock) }
+	unmatched := &unmatchedWriter{ResponseWriter: w, release: release}
+	h.api.router.ServeHTTP(unmatched, req)
+	release()
+	switch unmatched.status {
+	case http.StatusNotFound:
+		h.api.notFound.ServeHTTP(w, req)
+	case http.StatusMethodNotAllowed:
+		h.api.methodNotAllowed.ServeHTTP(w, req)
+	}
+}
+
+// unmatchedWriter is an http.ResponseWriter discarding 404 and 405 responses written by
+// a Router for requests which aren't handled by a route, recording their status.
+// Routes are passed the underlying http.ResponseWriter by markMatched.
+type unmatchedWriter struct {
+	http.ResponseWriter
+	status  int
+	release func()
+}
+
+// WriteHeader records the status if it's a 404 or 405 and writes it otherwise.
+func (u *unmatchedWriter) WriteHeader(status int) {
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		u.status = status
+		return
+	}
+	u.ResponseWriter.WriteHeader(status)
+}
+
+// Write discards the body of 404 and 405 responses.
+func (u *unmatchedWriter) Write(b []byte) (int, error) {
+	if u.status != 0 {
+		return len(b), nil
+	}
+	return u.ResponseWriter.Write(b)
+}
+
+// markMatched returns a Handler passing requests dispatched by a Router to the route's
+// Handler with the underlying http.ResponseWriter, so its responses aren't mistaken for
+// the Router's, once routes can be registered again.
+func markMatched(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if unmatched, ok := w.(*unmatchedWriter); ok {
+			unmatched.release()
+			w = unmatched.ResponseWriter
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// handleUnmatched returns a Handler responding to requests which don't match any route
+// with the error returned by the Configuration's NotFound or MethodNotAllowed function
+// for the status, or a default error, rendered in the standard envelope.
+func (r *muxAPI) handleUnmatched(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := r.handler.newContext(req, w, "")
+		defer cancel()
+		defer r.handler.recoverPanic(ctx)
+
+		var err error
+		if status == http.StatusMethodNotAllowed {
+			allowed := r.allowedMethods(req)
+			ctx.ResponseHeader().Set("Allow", strings.Join(allowed, ", "))
+			if r.config.MethodNotAllowed != nil {
+				err = r.config.MethodNotAllowed(ctx, allowed)
+			}
+			if err == nil {
+				err = MethodNotAllowed(fmt.Sprintf("Method %s isn't allowed for %s", req.Method,
+					req.URL.Path))
+			}
+		} else {
+			if r.config.NotFound != nil {
+				err = r.config.NotFound(ctx)
+			}
+			if err == nil {
+				err = NotFound(fmt.Sprintf("No route matches %s", req.URL.Path))
+			}
+		}
+
+		ctx = ctx.setError(err)
+		r.handler.sendResponse(ctx)
+	})
+}
+
+// allowedMethods returns the methods of the routes matching the request's path,
+// ignoring those requiring headers or of unregistered ResourceHandlers.
+func (r *muxAPI) allowedMethods(req *http.Request) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	allowed := map[string]bool{}
+	for _, route := range r.matchers.routes {
+		if route.Method == "" || len(route.Headers) > 0 || r.unregisteredRoute(route.Name) {
+			continue
+		}
+		if _, ok := route.template.match(req.URL.Path); ok {
+			allowed[route.Method] = true
+		}
+	}
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeEnvelope returns the decoded body of the response.
+func decodeEnvelope(w *httptest.ResponseRecorder) map[string]interface{} {
+	var envelope map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &envelope)
+	return envelope
+}
+
+// Ensures that requests which don't match any route are responded to in the standard
+// envelope, with the allowed methods of 405 responses.
+func TestUnmatchedDefaults(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.Equal([]interface{}{"No route matches /missing"}, decodeEnvelope(w)["messages"])
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("PATCH", "/api/v1/rows", nil))
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+	assert.Equal("GET, POST, PUT", w.Header().Get("Allow"))
+	assert.Equal(float64(http.StatusMethodNotAllowed), decodeEnvelope(w)["status"])
+}
+
+// Ensures that the Configuration's NotFound and MethodNotAllowed functions provide the
+// errors of unmatched requests, which pass through the Middleware given to Start and
+// are counted by Instrumentation.
+func TestUnmatchedCustom(t *testing.T) {
+	assert := assert.New(t)
+	var allowedMethods []string
+	api := NewAPI(&Configuration{
+		Instrumentation: true,
+		NotFound: func(ctx RequestContext) error {
+			req, _ := ctx.Request()
+			return NotFound("Nothing at " + req.URL.Path)
+		},
+		MethodNotAllowed: func(ctx RequestContext, allowed []string) error {
+			allowedMethods = allowed
+			return nil
+		},
+	})
+	api.RegisterResourceHandler(largeResourceHandler{count: 1})
+	logged := []string{}
+	handler := wrapMiddleware(api, func(w http.ResponseWriter, r *http.Request) *MiddlewareError {
+		logged = append(logged, r.Method+" "+r.URL.Path)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.Equal([]interface{}{"Nothing at /missing"}, decodeEnvelope(w)["messages"])
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/v1/rows", nil))
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+	assert.Equal([]string{"GET", "POST", "PUT"}, allowedMethods)
+
+	assert.Equal([]string{"GET /missing", "DELETE /api/v1/rows"}, logged)
+	assert.Equal(uint64(1), Stats(api)["notFound"].Requests)
+	assert.Equal(uint64(1), Stats(api)["methodNotAllowed"].Requests)
+}
+
+// Ensures that routes are passed the underlying http.ResponseWriter and that their 404
+// responses aren't replaced.
+func TestUnmatchedRouteResponses(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterHandlerFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(ok)
+		http.NotFound(w, r)
+	})
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/flush", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.Equal("404 page not found\n", w.Body.String())
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// multipartMemory is the maximum number of bytes of a multipart form's files which
+// are kept in memory. The remainder is stored on disk in temporary files which are
+// removed once the response has been sent.
+const multipartMemory = 32 << 20
+
+// UploadedFile describes a file included in a multipart/form-data request.
+type UploadedFile struct {
+	// Field is the name of the form field containing the file.
+	Field string
+
+	// Filename is the name of the file as provided by the client. It should not be
+	// trusted as a path on the local file system.
+	Filename string
+
+	// ContentType is the MIME type of the file as provided by the client.
+	ContentType string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	header *multipart.FileHeader
+}
+
+// Open returns a reader for streaming the file contents. The caller must close it.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	return f.header.Open()
+}
+
+// SaveTemp persists the file into a new temporary file in the given directory (or
+// the default temporary directory if empty) and returns its path. If maxSize is
+// positive and the file is larger than maxSize bytes, the temporary file is removed
+// and an error is returned. The caller is responsible for removing the file.
+func (f *UploadedFile) SaveTemp(dir string, maxSize int64) (string, error) {
+	if maxSize > 0 && f.Size > maxSize {
+		return "", fmt.Errorf("File '%s' exceeds maximum size of %d bytes", f.Filename, maxSize)
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := ioutil.TempFile(dir, "upload-*"+filepath.Ext(filepath.Base(f.Filename)))
+	if err != nil {
+		return "", err
+	}
+
+	var reader io.Reader = src
+	if maxSize > 0 {
+		reader = io.LimitReader(src, maxSize+1)
+	}
+	n, err := io.Copy(dst, reader)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && maxSize > 0 && n > maxSize {
+		err = fmt.Errorf("File '%s' exceeds maximum size of %d bytes", f.Filename, maxSize)
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
+
+// isMultipart returns true if the Content-Type is multipart/form-data and returns
+// the multipart boundary.
+func isMultipart(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// parseMultipartForm parses the multipart/form-data body using the given boundary.
+func parseMultipartForm(body []byte, boundary string) (*multipart.Form, error) {
+	return multipart.NewReader(bytes.NewReader(body), boundary).ReadForm(multipartMemory)
+}
+
+// formPayload returns a Payload containing the non-file values of the multipart
+// form. Fields with a single value are unboxed, consistent with query string
+// parameters.
+func formPayload(form *multipart.Form) Payload {
+	payload := Payload{}
+	for key, values := range form.Value {
+		if len(values) == 1 {
+			payload[key] = values[0]
+			continue
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = value
+		}
+		payload[key] = items
+	}
+	return payload
+}
+
+// uploadedFiles returns the files in the multipart form ordered by field name.
+func uploadedFiles(form *multipart.Form) []*UploadedFile {
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	files := []*UploadedFile{}
+	for _, field := range fields {
+		for _, header := range form.File[field] {
+			files = append(files, &UploadedFile{
+				Field:       field,
+				Filename:    header.Filename,
+				ContentType: header.Header.Get("Content-Type"),
+				Size:        header.Size,
+				header:      header,
+			})
+		}
+	}
+	return files
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newMultipartRequest returns a multipart/form-data request with the given form
+// values and a file in the "avatar" field.
+func newMultipartRequest(values map[string]string, contents string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range values {
+		writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("avatar", "me.png")
+	part.Write([]byte(contents))
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "http://example.com/api/v1/users", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// Ensures that uploaded files are exposed on the RequestContext.
+func TestContextFiles(t *testing.T) {
+	assert := assert.New(t)
+	req := newMultipartRequest(nil, "png data")
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	files, err := ctx.Files()
+	if assert.Nil(err) && assert.Len(files, 1) {
+		assert.Equal("avatar", files[0].Field)
+		assert.Equal("me.png", files[0].Filename)
+		assert.Equal(int64(8), files[0].Size)
+
+		reader, err := files[0].Open()
+		if assert.Nil(err) {
+			contents, _ := ioutil.ReadAll(reader)
+			reader.Close()
+			assert.Equal("png data", string(contents))
+		}
+	}
+
+	file, err := ctx.File("avatar")
+	assert.Nil(err)
+	assert.Equal("me.png", file.Filename)
+
+	_, err = ctx.File("missing")
+	assert.NotNil(err)
+}
+
+// Ensures that Files returns an error for requests which aren't multipart.
+func TestContextFilesNotMultipart(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("POST", "http://example.com/foo", bytes.NewBufferString("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	files, err := ctx.Files()
+	assert.Nil(files)
+	assert.NotNil(err)
+}
+
+// Ensures that SaveTemp persists the file and enforces the size cap.
+func TestUploadedFileSaveTemp(t *testing.T) {
+	assert := assert.New(t)
+	req := newMultipartRequest(nil, "png data")
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+	file, _ := ctx.File("avatar")
+	dir, _ := ioutil.TempDir("", "uploads")
+	defer os.RemoveAll(dir)
+
+	path, err := file.SaveTemp(dir, 100)
+	if assert.Nil(err) {
+		contents, _ := ioutil.ReadFile(path)
+		assert.Equal("png data", string(contents))
+	}
+
+	path, err = file.SaveTemp(dir, 4)
+	assert.Equal("", path)
+	assert.NotNil(err)
+	entries, _ := ioutil.ReadDir(dir)
+	assert.Len(entries, 1)
+}
+
+type uploadResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (u uploadResourceHandler) ResourceName() string {
+	return "users"
+}
+
+func (u uploadResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	file, err := ctx.File("avatar")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"name": data["name"], "avatar": file.Filename}, nil
+}
+
+// Ensures that the create handler decodes multipart form values into the Payload.
+func TestHandleCreateMultipart(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(uploadResourceHandler{})
+	resp := httptest.NewRecorder()
+
+	api.ServeHTTP(resp, newMultipartRequest(map[string]string{"name": "alice"}, "png data"))
+
+	assert.Equal(http.StatusCreated, resp.Code)
+	assert.Equal(
+		`{"messages":[],"reason":"Created","result":{"avatar":"me.png","name":"alice"},"status":201}`,
+		resp.Body.String(),
+	)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+// FieldError describes a problem with a single input field.
+type FieldError struct {
+	// Field is the name of the input field, using dots to separate nested fields
+	// (e.g. "address.zip").
+	Field string
+
+	// Code is a stable, machine-readable identifier for the problem, e.g. "required".
+	Code string
+
+	// Message is a human-readable description of the problem.
+	Message string
+}
+
+// ValidationError is an error carrying per-field problems with the request input.
+// When returned by a ResourceHandler, the response will be a 422 Unprocessable
+// Entity containing an "errors" array with the field, code and message of each
+// problem so that clients can highlight the offending fields.
+type ValidationError struct {
+	reason string
+	fields []FieldError
+}
+
+// NewValidationError returns a ValidationError with the given reason and field
+// problems.
+func NewValidationError(reason string, fields ...FieldError) *ValidationError {
+	return &ValidationError{reason: reason, fields: fields}
+}
+
+// Add adds a problem with the given field to the ValidationError and returns it to
+// allow chaining.
+func (v *ValidationError) Add(field, code, message string) *ValidationError {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+	return v
+}
+
+// Error returns the ValidationError reason.
+func (v *ValidationError) Error() string { return v.reason }
+
+// StatusCode returns 422 Unprocessable Entity. It implements the StatusError
+// interface.
+func (v *ValidationError) StatusCode() int { return statusUnprocessableEntity }
+
+// FieldErrors returns the problems with each field.
+func (v *ValidationError) FieldErrors() []FieldError { return v.fields }
+
+// HasErrors returns true if any field problems have been added.
+func (v *ValidationError) HasErrors() bool { return len(v.fields) > 0 }
+
+// fieldErrorsPayload returns the serializable representation of the field problems.
+func fieldErrorsPayload(fields []FieldError) []map[string]string {
+	payload := make([]map[string]string, len(fields))
+	for i, field := range fields {
+		payload[i] = map[string]string{
+			"field":   field.Field,
+			"code":    field.Code,
+			"message": field.Message,
+		}
+	}
+	return payload
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures that ValidationError accumulates field problems and maps to a 422.
+func TestValidationError(t *testing.T) {
+	assert := assert.New(t)
+	err := NewValidationError("Invalid user")
+
+	assert.False(err.HasErrors())
+
+	err.Add("email", "invalid_format", "Email is malformed").
+		Add("address.zip", "required", "Zip code is required")
+
+	assert.True(err.HasErrors())
+	assert.Equal("Invalid user", err.Error())
+	assert.Equal(statusUnprocessableEntity, errorStatus(err))
+	assert.Equal([]FieldError{
+		{Field: "email", Code: "invalid_format", Message: "Email is malformed"},
+		{Field: "address.zip", Code: "required", Message: "Zip code is required"},
+	}, err.FieldErrors())
+}
+
+type validatingResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (v validatingResourceHandler) ResourceName() string {
+	return "users"
+}
+
+func (v validatingResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+	return nil, NewValidationError("Invalid user",
+		FieldError{Field: "email", Code: "invalid_format", Message: "Email is malformed"})
+}
+
+// Ensures that a ValidationError returned by a ResourceHandler is rendered as a 422
+// with an errors array.
+func TestHandleCreateValidationError(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{})
+	api.RegisterResourceHandler(validatingResourceHandler{})
+
+	req, _ := http.NewRequest("POST", "http://foo.com/api/v1/users", bytes.NewBufferString("{}"))
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, req)
+
+	assert.Equal(statusUnprocessableEntity, resp.Code)
+	assert.Equal(
+		`{"errors":[{"code":"invalid_format","field":"email","message":"Email is malformed"}],`+
+			`"messages":["Invalid user"],"reason":"Unprocessable Entity","status":422}`,
+		resp.Body.String(),
+	)
+}
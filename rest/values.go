@@ -0,0 +1,87 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+
+	gcontext "github.com/gorilla/context"
+	"golang.org/x/net/context"
+)
+
+// ContextKey is a typed key for stashing per-request values, allowing middleware and
+// ResourceHandlers to share data without type assertions on interface{} values. Keys
+// are compared by identity, so each call to NewContextKey returns a distinct key even
+// if the names are the same.
+//
+//	var tenantKey = rest.NewContextKey[*Tenant]("tenant")
+//
+//	// In middleware:
+//	tenantKey.Set(r, tenant)
+//
+//	// In a ResourceHandler:
+//	tenant, ok := tenantKey.Get(ctx)
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey returns a new ContextKey for values of type T. The name is only used
+// for debugging.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// String returns the name of the ContextKey.
+func (k *ContextKey[T]) String() string {
+	return k.name
+}
+
+// Set associates the value with the request. It's visible to any RequestContext
+// subsequently created for the request.
+func (k *ContextKey[T]) Set(r *http.Request, value T) {
+	gcontext.Set(r, k, value)
+}
+
+// Get returns the value associated with the key from the Context and true, or the
+// zero value and false if there is no such value.
+func (k *ContextKey[T]) Get(ctx context.Context) (T, bool) {
+	return Value[T](ctx, k)
+}
+
+// GetRequest returns the value associated with the key from the request and true, or
+// the zero value and false if there is no such value.
+func (k *ContextKey[T]) GetRequest(r *http.Request) (T, bool) {
+	value, ok := gcontext.Get(r, k).(T)
+	return value, ok
+}
+
+// Value returns the Context value for the given key as a T and true. If there's no
+// such value or it isn't a T, the zero value and false are returned rather than
+// panicking.
+func Value[T any](ctx context.Context, key interface{}) (T, bool) {
+	value, ok := ctx.Value(key).(T)
+	return value, ok
+}
+
+// ValueWithDefault returns the Context value for the given key as a T. If there's no
+// such value or it isn't a T, the provided default is returned.
+func ValueWithDefault[T any](ctx context.Context, key interface{}, defaultVal T) T {
+	if value, ok := Value[T](ctx, key); ok {
+		return value
+	}
+	return defaultVal
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenant struct {
+	Name string
+}
+
+// Ensures that values set with a ContextKey in middleware are available from the
+// RequestContext with their static type.
+func TestContextKey(t *testing.T) {
+	assert := assert.New(t)
+	key := NewContextKey[*tenant]("tenant")
+	other := NewContextKey[*tenant]("tenant")
+	req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+
+	key.Set(req, &tenant{Name: "acme"})
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	value, ok := key.Get(ctx)
+	if assert.True(ok) {
+		assert.Equal("acme", value.Name)
+	}
+	value, ok = key.GetRequest(req)
+	if assert.True(ok) {
+		assert.Equal("acme", value.Name)
+	}
+	_, ok = other.Get(ctx)
+	assert.False(ok)
+	assert.Equal("tenant", key.String())
+}
+
+// Ensures that Value and ValueWithDefault don't panic on mismatched types.
+func TestValue(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://example.com/foo?limit=5", nil)
+	ctx := NewContext(nil, req, httptest.NewRecorder())
+
+	limit, ok := Value[string](ctx, "limit")
+	assert.True(ok)
+	assert.Equal("5", limit)
+
+	_, ok = Value[int](ctx, "limit")
+	assert.False(ok)
+
+	assert.Equal(10, ValueWithDefault(ctx, "limit", 10))
+	assert.Equal("bar", ValueWithDefault(ctx, "foo", "bar"))
+}
@@ -0,0 +1,376 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// viewKey is the name of the query string variable giving the saved view a list
+	// request executes.
+	viewKey = "view"
+
+	// viewNameKey is the name of the URL path variable for the name of a saved view.
+	viewNameKey = "view_name"
+)
+
+// viewNameRegex matches valid names of saved views.
+var viewNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SavedView is a named combination of the filter, sort, expand and fields of list
+// requests to a resource, created with POST /api/v{version}/{resource}/views and
+// executed with GET /api/v{version}/{resource}?view={name}, so clients don't repeat
+// long query strings.
+type SavedView struct {
+	// Name identifies the view among those of its resource.
+	Name string `json:"name"`
+
+	// Resource is the name of the resource the view lists.
+	Resource string `json:"resource"`
+
+	// Filter is the filter query string variable of the view.
+	Filter string `json:"filter,omitempty"`
+
+	// Sort is the sort query string variable of the view.
+	Sort string `json:"sort,omitempty"`
+
+	// Expand is the expand query string variable of the view.
+	Expand string `json:"expand,omitempty"`
+
+	// Fields, if set, are the only fields included in the resources listed.
+	Fields []string `json:"fields,omitempty"`
+
+	// Created is when the view was saved.
+	Created time.Time `json:"created"`
+}
+
+// ViewConfig configures saved views of the resources of every ResourceHandler, at
+// /api/v{version}/{resource}/views. Views are listed by GET requests there, created by
+// POST requests with a JSON body of their name, filter, sort, expand and fields, and
+// deleted by DELETE requests to /api/v{version}/{resource}/views/{name}, subject to
+// the ResourceHandler's authentication. Views belong to the Principal which saved
+// them and are only listed, executed and deleted by its requests. The query string
+// variables of list requests executing a view take precedence over the view's.
+type ViewConfig struct {
+	// Store, if set, stores the views, e.g. in a database shared by the instances of a
+	// service. By default, they're kept in memory.
+	Store ViewStore
+}
+
+// ViewStore stores SavedViews. Views are owned by the Principal which saved them,
+// identified by its ID, or the empty string for requests without one, and each owner
+// has its own views. Implementations must be safe for concurrent use.
+type ViewStore interface {
+	// SaveView stores the view of the owner, returning an error if the owner already
+	// has a view of the resource with its name.
+	SaveView(owner string, view SavedView) error
+
+	// View returns the owner's view of the resource with the name, or false if there
+	// isn't one.
+	View(owner, resource, name string) (SavedView, bool, error)
+
+	// Views returns the owner's views of the resource in order of name.
+	Views(owner, resource string) ([]SavedView, error)
+
+	// DeleteView deletes the owner's view of the resource with the name, returning
+	// false if there isn't one.
+	DeleteView(owner, resource, name string) (bool, error)
+}
+
+// viewScope identifies the views of a resource owned by a Principal.
+type viewScope struct {
+	owner    string
+	resource string
+}
+
+// memoryViewStore is a ViewStore keeping views in memory.
+type memoryViewStore struct {
+	mu    sync.RWMutex
+	views map[viewScope]map[string]SavedView
+}
+
+// NewMemoryViewStore returns a ViewStore keeping the views in memory. Views are lost
+// when the process exits.
+func NewMemoryViewStore() ViewStore {
+	return &memoryViewStore{views: map[viewScope]map[string]SavedView{}}
+}
+
+// SaveView stores the view unless the owner already has one of its resource with its
+// name.
+func (m *memoryViewStore) SaveView(owner string, view SavedView) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scope := viewScope{owner: owner, resource: view.Resource}
+	views := m.views[scope]
+	if views == nil {
+		views = map[string]SavedView{}
+		m.views[scope] = views
+	}
+	if _, ok := views[view.Name]; ok {
+		return ResourceConflict(fmt.Sprintf("View %s already exists", view.Name))
+	}
+	views[view.Name] = view
+	return nil
+}
+
+// View returns the owner's view of the resource with the name.
+func (m *memoryViewStore) View(owner, resource, name string) (SavedView, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	view, ok := m.views[viewScope{owner: owner, resource: resource}][name]
+	return view, ok, nil
+}
+
+// Views returns the owner's views of the resource in order of name.
+func (m *memoryViewStore) Views(owner, resource string) ([]SavedView, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scoped := m.views[viewScope{owner: owner, resource: resource}]
+	views := make([]SavedView, 0, len(scoped))
+	for _, view := range scoped {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
+
+// DeleteView deletes the owner's view of the resource with the name.
+func (m *memoryViewStore) DeleteView(owner, resource, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scope := viewScope{owner: owner, resource: resource}
+	if _, ok := m.views[scope][name]; !ok {
+		return false, nil
+	}
+	delete(m.views[scope], name)
+	return true, nil
+}
+
+// viewsURI returns the URI of the ResourceHandler's saved views.
+func viewsURI(handler ResourceHandler) string {
+	return handler.ReadListURI() + "/views"
+}
+
+// viewURI returns the URI of a saved view of the ResourceHandler.
+func viewURI(handler ResourceHandler) string {
+	return viewsURI(handler) + "/{" + viewNameKey + "}"
+}
+
+// viewRoutes returns the routes of the ResourceHandler's saved views, if they're
+// enabled.
+func (h requestHandler) viewRoutes(handler ResourceHandler,
+	middleware []RequestMiddleware) []resourceRoute {
+
+	if h.views == nil {
+		return nil
+	}
+	resource := handler.ResourceName()
+	return []resourceRoute{
+		{
+			name: resource + ":viewCreate", method: "POST", uri: viewsURI(handler),
+			label: "view create", logMethod: "POST", handlerName: "rest.SavedViews",
+			handler: applyMiddleware(h.handleViewCreate(handler), middleware),
+		},
+		{
+			name: resource + ":views", method: "GET", uri: viewsURI(handler),
+			label: "views", logMethod: "GET", handlerName: "rest.SavedViews",
+			handler: applyMiddleware(h.handleViews(handler), middleware),
+		},
+		{
+			name: resource + ":viewDelete", method: "DELETE", uri: viewURI(handler),
+			label: "view delete", logMethod: "DELETE", handlerName: "rest.SavedViews",
+			handler: applyMiddleware(h.handleViewDelete(handler), middleware),
+		},
+	}
+}
+
+// handleViewCreate returns a Handler saving views of the ResourceHandler's resources
+// once their name, filter, sort, expand and fields are validated.
+func (h requestHandler) handleViewCreate(handler ResourceHandler) http.Handler {
+	resource, rules := handler.ResourceName(), handler.Rules()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, resource)
+		defer cancel()
+		defer h.recoverPanic(ctx)
+		version := ctx.Version()
+
+		var view SavedView
+		if err := json.Unmarshal(ctx.Body().Bytes(), &view); err != nil {
+			h.sendResponse(ctx.setError(BadRequest("Invalid view: " + err.Error())))
+			return
+		}
+		view.Resource, view.Created = resource, time.Now().UTC()
+		if err := h.validateView(view, rules, version); err != nil {
+			h.sendResponse(ctx.setError(BadRequest(err.Error())))
+			return
+		}
+		if err := h.views.SaveView(principalID(ctx), view); err != nil {
+			h.sendResponse(ctx.setError(err))
+			return
+		}
+
+		ctx = ctx.setResult(view)
+		ctx = ctx.setStatus(http.StatusCreated)
+		h.sendResponse(ctx)
+	})
+}
+
+// handleViews returns a Handler listing the views of the ResourceHandler's resources.
+func (h requestHandler) handleViews(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		views, err := h.views.Views(principalID(ctx), handler.ResourceName())
+		if views == nil {
+			views = []SavedView{}
+		}
+		ctx = ctx.setResult(views)
+		ctx = ctx.setError(err)
+		ctx = ctx.setStatus(http.StatusOK)
+		h.sendResponse(ctx)
+	})
+}
+
+// handleViewDelete returns a Handler deleting views of the ResourceHandler's
+// resources.
+func (h requestHandler) handleViewDelete(handler ResourceHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := h.newContext(r, w, handler.ResourceName())
+		defer cancel()
+		defer h.recoverPanic(ctx)
+
+		name := ctx.PathVars()[viewNameKey]
+		ok, err := h.views.DeleteView(principalID(ctx), handler.ResourceName(), name)
+		if err == nil && !ok {
+			err = ResourceNotFound(fmt.Sprintf("No view with name %s", name))
+		}
+		ctx = ctx.setError(err)
+		ctx = ctx.setStatus(http.StatusNoContent)
+		h.sendResponse(ctx)
+	})
+}
+
+// validateView returns an error if the view's name is malformed or its filter, sort,
+// expand or fields aren't valid for the Rules.
+func (h requestHandler) validateView(view SavedView, rules Rules, version string) error {
+	if !viewNameRegex.MatchString(view.Name) {
+		return fmt.Errorf("Invalid view: name %q must be letters, digits, - or _", view.Name)
+	}
+	if view.Filter != "" {
+		if _, err := ParseFilter(view.Filter, filterableFields(rules, version)); err != nil {
+			return err
+		}
+	}
+	if view.Sort != "" {
+		if _, err := ParseSort(view.Sort, sortableFields(rules, version)); err != nil {
+			return err
+		}
+	}
+	if view.Expand != "" {
+		e := &expander{h: h, version: version}
+		for _, path := range strings.Split(view.Expand, ",") {
+			fields := strings.Split(strings.TrimSpace(path), ".")
+			if err := e.validate(rules, fields, path); err != nil {
+				return err
+			}
+		}
+	}
+	if fields := outboundFields(rules, version); len(fields) > 0 {
+		for _, field := range view.Fields {
+			if !contains(fields, field) {
+				return fmt.Errorf("Invalid fields: %s isn't a field; fields are %s",
+					field, strings.Join(fields, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// outboundFields returns the names of the fields of the Rules for responses of the
+// version, in order.
+func outboundFields(rules Rules, version string) []string {
+	fields := []string{}
+	if rules == nil {
+		return fields
+	}
+	for _, rule := range rulesFor(rules, Outbound, version).Contents() {
+		fields = append(fields, rule.Name())
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// viewContext returns the context of a list request with the filter, sort and expand
+// of the view given by its view query string variable, if any, where the request
+// doesn't give its own, or a 400 error if there's no such view.
+func (h requestHandler) viewContext(ctx RequestContext, handler ResourceHandler) (RequestContext, error) {
+	name, _ := ctx.Value(viewKey).(string)
+	if name == "" || h.views == nil {
+		return ctx, nil
+	}
+	view, ok, err := h.views.View(principalID(ctx), handler.ResourceName(), name)
+	if err != nil {
+		return ctx, err
+	}
+	if !ok {
+		return ctx, BadRequest(fmt.Sprintf("Invalid %s: no view with name %s", viewKey, name))
+	}
+
+	// Query string variables are held by the request, so they take precedence over
+	// the context values of the view.
+	for key, value := range map[string]string{
+		filterKey: view.Filter, sortKey: view.Sort, expandKey: view.Expand,
+	} {
+		if value != "" {
+			ctx = ctx.WithValue(key, value)
+		}
+	}
+	if len(view.Fields) > 0 {
+		ctx = ctx.WithValue(viewFieldsKey, view.Fields)
+	}
+	return ctx, nil
+}
+
+// projectFields returns the resource with only the fields. Resources which aren't
+// maps are converted to them through JSON.
+func projectFields(resource Resource, fields []string) Resource {
+	values, ok := resourceFields(resource)
+	if !ok {
+		encoded, err := json.Marshal(resource)
+		if err != nil || json.Unmarshal(encoded, &values) != nil {
+			return resource
+		}
+	}
+	projected := Payload{}
+	for _, field := range fields {
+		if value, ok := values[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newViewsAPI returns an API with saved views listing tasks.
+func newViewsAPI() API {
+	config := NewConfiguration()
+	config.Views = &ViewConfig{}
+	api := NewAPI(config)
+	api.RegisterResourceHandler(tasksResourceHandler{})
+	return api
+}
+
+// Ensures that saved views are executed by list requests, with the request's query
+// string variables taking precedence over the view's.
+func TestSavedViews(t *testing.T) {
+	assert := assert.New(t)
+	api := newViewsAPI()
+
+	code, envelope := serveJSON(api, "POST", "/api/v1/tasks/views",
+		`{"name": "big", "filter": "size>=5", "sort": "-name", "fields": ["name"]}`)
+	if !assert.Equal(http.StatusCreated, code) {
+		return
+	}
+	view := envelope["result"].(map[string]interface{})
+	assert.Equal("big", view["name"])
+	assert.Equal("tasks", view["resource"])
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks?view=big", "")
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{"name": "d"},
+			map[string]interface{}{"name": "c"},
+			map[string]interface{}{"name": "b"},
+		}, envelope["results"])
+	}
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks?view=big&sort=name", "")
+	if assert.Equal(http.StatusOK, code) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{"name": "b"},
+			map[string]interface{}{"name": "c"},
+			map[string]interface{}{"name": "d"},
+		}, envelope["results"])
+	}
+
+	code, envelope = serveJSON(api, "GET", "/api/v1/tasks/views", "")
+	if assert.Equal(http.StatusOK, code) && assert.Len(envelope["results"], 1) {
+		assert.Equal("big", envelope["results"].([]interface{})[0].(map[string]interface{})["name"])
+	}
+
+	code, _ = serveJSON(api, "DELETE", "/api/v1/tasks/views/big", "")
+	assert.Equal(http.StatusNoContent, code)
+
+	code, _ = serveJSON(api, "GET", "/api/v1/tasks?view=big", "")
+	assert.Equal(http.StatusBadRequest, code)
+
+	code, _ = serveJSON(api, "DELETE", "/api/v1/tasks/views/big", "")
+	assert.Equal(http.StatusNotFound, code)
+}
+
+// callerTasksResourceHandler is a tasksResourceHandler authenticating the caller of the
+// X-Caller header as the request's Principal.
+type callerTasksResourceHandler struct {
+	tasksResourceHandler
+}
+
+func (c callerTasksResourceHandler) Authenticate(r *http.Request) error {
+	return authenticateCaller(r)
+}
+
+// Ensures that saved views are only listed, executed and deleted by the Principal
+// which saved them, and that Principals can save views with the same names.
+func TestSavedViewsOwner(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfiguration()
+	config.Views = &ViewConfig{}
+	api := NewAPI(config)
+	api.RegisterResourceHandler(callerTasksResourceHandler{})
+
+	code, _ := serveJSONAs(api, "alice", "POST", "/api/v1/tasks/views",
+		`{"name": "big", "filter": "size>=5"}`)
+	assert.Equal(http.StatusCreated, code)
+
+	_, envelope := serveJSONAs(api, "bob", "GET", "/api/v1/tasks/views", "")
+	assert.Len(envelope["results"], 0)
+	code, _ = serveJSONAs(api, "bob", "GET", "/api/v1/tasks?view=big", "")
+	assert.Equal(http.StatusBadRequest, code)
+	code, _ = serveJSONAs(api, "bob", "DELETE", "/api/v1/tasks/views/big", "")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "bob", "POST", "/api/v1/tasks/views", `{"name": "big"}`)
+	assert.Equal(http.StatusCreated, code)
+
+	code, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/tasks?view=big", "")
+	if assert.Equal(http.StatusOK, code) {
+		assert.Len(envelope["results"], 3)
+	}
+	code, _ = serveJSONAs(api, "alice", "DELETE", "/api/v1/tasks/views/big", "")
+	assert.Equal(http.StatusNoContent, code)
+	code, envelope = serveJSONAs(api, "bob", "GET", "/api/v1/tasks?view=big", "")
+	if assert.Equal(http.StatusOK, code) {
+		assert.Len(envelope["results"], 4)
+	}
+}
+
+// Ensures that views with malformed names, filters, sorts or fields, or the name of an
+// existing view, aren't saved.
+func TestSavedViewsInvalid(t *testing.T) {
+	assert := assert.New(t)
+	api := newViewsAPI()
+
+	code, _ := serveJSON(api, "POST", "/api/v1/tasks/views", `{"name": "active", "filter": "status==active"}`)
+	assert.Equal(http.StatusCreated, code)
+
+	for body, status := range map[string]int{
+		`{"name": "active"}`:                       http.StatusConflict,
+		`{"name": "two words"}`:                    http.StatusBadRequest,
+		`{"name": "named", "filter": "name==a"}`:   http.StatusBadRequest,
+		`{"name": "sorted", "sort": "status"}`:     http.StatusBadRequest,
+		`{"name": "fields", "fields": ["owner"]}`:  http.StatusBadRequest,
+		`{"name": "expanded", "expand": "status"}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		code, _ := serveJSON(api, "POST", "/api/v1/tasks/views", body)
+		assert.Equal(status, code, body)
+	}
+}
+
+// Ensures that the views routes aren't registered unless views are configured.
+func TestSavedViewsNotConfigured(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(NewConfiguration())
+	api.RegisterResourceHandler(tasksResourceHandler{})
+
+	code, _ := serveJSON(api, "POST", "/api/v1/tasks/views", `{"name": "all"}`)
+	assert.NotEqual(http.StatusCreated, code)
+
+	code, envelope := serveJSON(api, "GET", "/api/v1/tasks?view=all", "")
+	if assert.Equal(http.StatusOK, code) {
+		assert.Len(envelope["results"], 4)
+	}
+}
@@ -0,0 +1,825 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	gcontext "github.com/gorilla/context"
+)
+
+const (
+	// webhooksResource is the name of the resource with which webhooks are registered.
+	webhooksResource = "webhooks"
+
+	// deadLettersResource is the name of the resource recording the deliveries which
+	// failed every attempt.
+	deadLettersResource = "webhook_dead_letters"
+
+	// defaultWebhookAttempts is the default number of attempts made to deliver an Event.
+	defaultWebhookAttempts = 5
+
+	// defaultWebhookBackoff is the default delay before the first retry of a delivery.
+	defaultWebhookBackoff = time.Second
+
+	// defaultWebhookMaxBackoff is the default longest delay between retries.
+	defaultWebhookMaxBackoff = 5 * time.Minute
+
+	// defaultWebhookTimeout is the default timeout of each delivery attempt.
+	defaultWebhookTimeout = 10 * time.Second
+
+	// defaultDeadLetters is the default number of dead letters kept.
+	defaultDeadLetters = 1000
+
+	// webhookIDHeader, webhookEventHeader, webhookTimestampHeader and
+	// webhookSignatureHeader are the headers of deliveries identifying the webhook and
+	// Event and signing the delivery.
+	webhookIDHeader        = "X-Webhook-ID"
+	webhookEventHeader     = "X-Webhook-Event-ID"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookConfig configures the webhooks resource, at /api/v{version}/webhooks, with
+// which callers register URLs to which the Events matching their filters are POSTed.
+// Each delivery is signed with the webhook's secret: the X-Webhook-Signature header is
+// "sha256=" followed by the hex-encoded HMAC-SHA256 of the X-Webhook-Timestamp header,
+// a period and the body, which receivers check with VerifyWebhookSignature. Failed
+// deliveries are retried with exponential backoff and, once the attempts are
+// exhausted, recorded at /api/v{version}/webhook_dead_letters. Webhooks are kept in
+// memory and the requests to both resources are authenticated by the Configuration's
+// Authenticate function, which must set their Principal with SetRequestPrincipal. The
+// resources aren't registered without one. Callers only see and manage their own
+// webhooks and dead letters, and registering a webhook for a resource requires the
+// request to pass the Authenticate method of its ResourceHandler.
+type WebhookConfig struct {
+	// MaxAttempts is the number of attempts made to deliver an Event. Defaults to 5.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry of a delivery, doubled for each
+	// subsequent retry. Defaults to one second.
+	Backoff time.Duration
+
+	// MaxBackoff is the longest delay between retries. Defaults to five minutes.
+	MaxBackoff time.Duration
+
+	// Timeout is the timeout of each delivery attempt. Defaults to ten seconds.
+	Timeout time.Duration
+
+	// DeadLetters is the number of failed deliveries recorded. Defaults to 1000.
+	DeadLetters int
+
+	// Client, if set, is the client with which Events are delivered. Its connections
+	// aren't checked with AllowDestination.
+	Client *http.Client
+
+	// AllowDestination, if set, returns true if Events may be delivered to the IP
+	// address. By default only public addresses are allowed, so that webhooks can't
+	// reach internal services: loopback, link-local, private, unspecified and
+	// multicast addresses, such as 169.254.169.254, are rejected. Addresses are
+	// checked when webhooks are registered and when deliveries connect.
+	AllowDestination func(net.IP) bool
+}
+
+// Webhook is a URL registered to receive the Events of mutations.
+type Webhook struct {
+	// ID identifies the webhook.
+	ID string `json:"id"`
+
+	// URL is the http or https URL to which Events are POSTed.
+	URL string `json:"url"`
+
+	// Resources are the resources whose Events are delivered. Events are only
+	// delivered if the EventAuthorizer of their ResourceHandler, if it's one,
+	// authorizes them for the request delivering them, whose Principal is the one
+	// which registered the webhook.
+	Resources []string `json:"resources"`
+
+	// Events, if not empty, are the types of the Events delivered.
+	Events []EventType `json:"events,omitempty"`
+
+	// Secret is the key with which deliveries are signed. It's generated unless given
+	// when the webhook is created, and only returned then.
+	Secret string `json:"secret,omitempty"`
+
+	// Created is when the webhook was registered.
+	Created time.Time `json:"created"`
+
+	// owner is the Principal which registered the webhook.
+	owner *Principal
+}
+
+// matches returns true if the webhook's filters accept the Event.
+func (w *Webhook) matches(event Event) bool {
+	return contains(w.Resources, event.Resource) &&
+		(len(w.Events) == 0 || containsEventType(w.Events, event.Type))
+}
+
+// DeadLetter records a delivery of an Event to a webhook which failed every attempt.
+type DeadLetter struct {
+	// ID identifies the dead letter.
+	ID string `json:"id"`
+
+	// Webhook is the ID of the webhook the Event was delivered to.
+	Webhook string `json:"webhook"`
+
+	// URL is the URL the Event was delivered to.
+	URL string `json:"url"`
+
+	// Event is the Event delivered.
+	Event Event `json:"event"`
+
+	// Attempts is the number of attempts made.
+	Attempts int `json:"attempts"`
+
+	// Error describes the failure of the last attempt.
+	Error string `json:"error"`
+
+	// Time is when the last attempt failed.
+	Time time.Time `json:"time"`
+
+	// owner is the ID of the Principal which registered the webhook.
+	owner string
+}
+
+// SignWebhook returns the X-Webhook-Signature of a delivery with the timestamp and
+// body, signed with the secret.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, timestamp+".")
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature returns true if the signature of a delivery with the
+// timestamp and body was made with the secret. Receivers should also reject deliveries
+// with old timestamps, which may be replayed.
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
+
+// allEvents is an Event filter accepting every Event.
+func allEvents(Event) bool {
+	return true
+}
+
+// publicAddress returns true if the IP address is neither loopback, link-local,
+// private, unspecified nor multicast.
+func publicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// newWebhookClient returns a client which only connects to the IP addresses allowed
+// by the function. It doesn't use proxies, which would bypass the check.
+func newWebhookClient(allow func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return fmt.Errorf("Webhook address %s isn't allowed", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{Transport: &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}}
+}
+
+// webhookDispatcher stores webhooks and delivers the published Events to them.
+type webhookDispatcher struct {
+	config      WebhookConfig
+	client      *http.Client
+	events      *eventBus
+	handler     *requestHandler
+	mu          sync.RWMutex
+	hooks       map[string]*Webhook
+	deadLetters []*DeadLetter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	deliveries  sync.WaitGroup
+	done        chan struct{}
+}
+
+// newWebhookDispatcher returns a webhookDispatcher delivering the Events published to
+// the requestHandler's eventBus, with the defaults applied to the configuration.
+func newWebhookDispatcher(config WebhookConfig, handler *requestHandler) *webhookDispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultWebhookAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultWebhookBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	if config.DeadLetters <= 0 {
+		config.DeadLetters = defaultDeadLetters
+	}
+	if config.AllowDestination == nil {
+		config.AllowDestination = publicAddress
+	}
+	client := config.Client
+	if client == nil {
+		client = newWebhookClient(config.AllowDestination)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &webhookDispatcher{
+		config:  config,
+		client:  client,
+		events:  handler.events,
+		handler: handler,
+		hooks:   map[string]*Webhook{},
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go d.run(d.events.subscribe(allEvents))
+	return d
+}
+
+// run delivers the Events of the subscription until the dispatcher is stopped.
+func (d *webhookDispatcher) run(sub *eventSubscription) {
+	defer close(d.done)
+	d.events.consume(d.ctx, sub, allEvents, d.dispatch)
+}
+
+// dispatch starts delivering the Event to each webhook whose filters accept it and
+// whose owner is authorized to receive it.
+func (d *webhookDispatcher) dispatch(event Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, hook := range d.hooks {
+		if hook.matches(event) && d.authorized(hook, event) {
+			d.deliveries.Add(1)
+			go d.deliver(*hook, event)
+		}
+	}
+}
+
+// authorized returns true unless the ResourceHandler of the Event's resource is an
+// EventAuthorizer which rejects it for a request to the webhook's URL made by its
+// owner.
+func (d *webhookDispatcher) authorized(hook *Webhook, event Event) bool {
+	authorizer, ok := eventAuthorizer(d.handler.resourceHandler(event.Resource))
+	if !ok {
+		return true
+	}
+	req, err := http.NewRequest("POST", hook.URL, nil)
+	if err != nil {
+		return false
+	}
+	defer gcontext.Clear(req)
+	SetRequestPrincipal(req, hook.owner)
+	return authorizer.AuthorizeEvent(req, event)
+}
+
+// checkDestination returns an error unless the host of the URL only resolves to IP
+// addresses allowed by the configuration.
+func (d *webhookDispatcher) checkDestination(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return UnprocessableRequest(fmt.Sprintf("Invalid webhook url: %s", rawURL))
+	}
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			return UnprocessableRequest(fmt.Sprintf("Invalid webhook url: %s", rawURL))
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !d.config.AllowDestination(ip) {
+			return UnprocessableRequest(fmt.Sprintf("Webhook url %s isn't a public address", rawURL))
+		}
+	}
+	return nil
+}
+
+// deliver POSTs the Event to the webhook, retrying with exponential backoff until it's
+// accepted with a 2xx response or the attempts are exhausted, in which case a dead
+// letter is recorded. Deliveries waiting to be retried when the dispatcher is stopped
+// are recorded as dead letters.
+func (d *webhookDispatcher) deliver(hook Webhook, event Event) {
+	defer d.deliveries.Done()
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.deadLetter(hook, event, 0, err)
+		return
+	}
+
+	backoff := d.config.Backoff
+	for attempt := 1; ; attempt++ {
+		err := d.attempt(hook, event, body)
+		if err == nil {
+			return
+		}
+		if attempt == d.config.MaxAttempts {
+			d.deadLetter(hook, event, attempt, err)
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.deadLetter(hook, event, attempt, err)
+			return
+		}
+		if backoff *= 2; backoff > d.config.MaxBackoff {
+			backoff = d.config.MaxBackoff
+		}
+	}
+}
+
+// attempt makes one attempt to deliver the Event to the webhook, returning an error
+// unless it's accepted with a 2xx response.
+func (d *webhookDispatcher) attempt(hook Webhook, event Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookIDHeader, hook.ID)
+	req.Header.Set(webhookEventHeader, strconv.FormatUint(event.ID, 10))
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, SignWebhook(hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// deadLetter records the failed delivery of the Event to the webhook, discarding the
+// oldest dead letter if the configured number are recorded.
+func (d *webhookDispatcher) deadLetter(hook Webhook, event Event, attempts int, err error) {
+	log.Printf("Failed to deliver event %d to webhook %s after %d attempts: %v",
+		event.ID, hook.ID, attempts, err)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.deadLetters) == d.config.DeadLetters {
+		d.deadLetters = d.deadLetters[1:]
+	}
+	d.deadLetters = append(d.deadLetters, &DeadLetter{
+		ID:       newRequestID(),
+		Webhook:  hook.ID,
+		URL:      hook.URL,
+		Event:    event,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Time:     time.Now().UTC(),
+		owner:    hook.owner.ID,
+	})
+}
+
+// stop stops delivering Events, recording the deliveries waiting to be retried as dead
+// letters, and waits for the deliveries in progress to finish or the context to be
+// done.
+func (d *webhookDispatcher) stop(ctx context.Context) error {
+	d.cancel()
+	finished := make(chan struct{})
+	go func() {
+		<-d.done
+		d.deliveries.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// webhooksResourceHandler is the ResourceHandler with which webhooks are registered.
+type webhooksResourceHandler struct {
+	BaseResourceHandler
+	dispatcher   *webhookDispatcher
+	authenticate func(*http.Request) error
+}
+
+// ResourceName returns "webhooks".
+func (w webhooksResourceHandler) ResourceName() string {
+	return webhooksResource
+}
+
+// CreateResource registers a webhook owned by the request's Principal with the url,
+// resources, events and optionally secret of the payload, returning it with its
+// secret.
+func (w webhooksResourceHandler) CreateResource(ctx RequestContext, data Payload,
+	version string) (Resource, error) {
+
+	hook, err := w.webhookFromRequest(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if hook.Secret == "" {
+		hook.Secret = newRequestID()
+	}
+	hook.ID = newRequestID()
+	hook.Created = time.Now().UTC()
+
+	w.dispatcher.mu.Lock()
+	defer w.dispatcher.mu.Unlock()
+	w.dispatcher.hooks[hook.ID] = hook
+	created := *hook
+	return &created, nil
+}
+
+// ReadResourceList returns the webhooks of the request's Principal in the order they
+// were registered, without their secrets.
+func (w webhooksResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	w.dispatcher.mu.RLock()
+	hooks := make([]*Webhook, 0, len(w.dispatcher.hooks))
+	for _, hook := range w.dispatcher.hooks {
+		if hook.owner.ID == owner.ID {
+			hooks = append(hooks, hook)
+		}
+	}
+	w.dispatcher.mu.RUnlock()
+	sort.Slice(hooks, func(i, j int) bool {
+		if hooks[i].Created.Equal(hooks[j].Created) {
+			return hooks[i].ID < hooks[j].ID
+		}
+		return hooks[i].Created.Before(hooks[j].Created)
+	})
+
+	resources := make([]Resource, len(hooks))
+	for i, hook := range hooks {
+		resources[i] = redactWebhook(hook)
+	}
+	return paginate(resources, limit, cursor)
+}
+
+// ReadResource returns the request Principal's webhook with the ID, without its
+// secret.
+func (w webhooksResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.dispatcher.mu.RLock()
+	defer w.dispatcher.mu.RUnlock()
+	hook, ok := w.dispatcher.hook(id, owner.ID)
+	if !ok {
+		return nil, ResourceNotFound(fmt.Sprintf("No webhook with id %s", id))
+	}
+	return redactWebhook(hook), nil
+}
+
+// UpdateResource replaces the url, resources and events of the request Principal's
+// webhook with the ID, and its secret if one is given.
+func (w webhooksResourceHandler) UpdateResource(ctx RequestContext, id string,
+	data Payload, version string) (Resource, error) {
+
+	hook, err := w.webhookFromRequest(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	w.dispatcher.mu.Lock()
+	defer w.dispatcher.mu.Unlock()
+	existing, ok := w.dispatcher.hook(id, hook.owner.ID)
+	if !ok {
+		return nil, ResourceNotFound(fmt.Sprintf("No webhook with id %s", id))
+	}
+	if hook.Secret == "" {
+		hook.Secret = existing.Secret
+	}
+	hook.ID = id
+	hook.Created = existing.Created
+	w.dispatcher.hooks[id] = hook
+	return redactWebhook(hook), nil
+}
+
+// DeleteResource unregisters the request Principal's webhook with the ID. Deliveries
+// in progress are still attempted.
+func (w webhooksResourceHandler) DeleteResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.dispatcher.mu.Lock()
+	defer w.dispatcher.mu.Unlock()
+	hook, ok := w.dispatcher.hook(id, owner.ID)
+	if !ok {
+		return nil, ResourceNotFound(fmt.Sprintf("No webhook with id %s", id))
+	}
+	delete(w.dispatcher.hooks, id)
+	return redactWebhook(hook), nil
+}
+
+// Authenticate authenticates requests with the Configuration's Authenticate function.
+func (w webhooksResourceHandler) Authenticate(r *http.Request) error {
+	return w.authenticate(r)
+}
+
+// webhookFromRequest returns the webhook described by the payload, owned by the
+// request's Principal. The request must pass the Authenticate method of the
+// ResourceHandler of each of the webhook's resources and its URL must resolve to
+// allowed addresses.
+func (w webhooksResourceHandler) webhookFromRequest(ctx RequestContext,
+	data Payload) (*Webhook, error) {
+
+	hook, err := webhookFromPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	req, ok := ctx.Request()
+	if !ok {
+		return nil, UnauthorizedRequest("Webhooks require an authenticated request")
+	}
+	for _, resource := range hook.Resources {
+		handler := w.dispatcher.handler.resourceHandler(resource)
+		if handler == nil || resource == webhooksResource || resource == deadLettersResource {
+			return nil, UnprocessableRequest(fmt.Sprintf("Invalid resource: %s", resource))
+		}
+		if err := handler.Authenticate(req); err != nil {
+			return nil, UnauthorizedRequest(err.Error())
+		}
+	}
+	if hook.owner, err = webhookOwner(ctx); err != nil {
+		return nil, err
+	}
+	if err := w.dispatcher.checkDestination(ctx, hook.URL); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// deadLettersResourceHandler is the ResourceHandler serving the dead letters of the
+// webhook deliveries, which can be deleted once handled.
+type deadLettersResourceHandler struct {
+	BaseResourceHandler
+	dispatcher   *webhookDispatcher
+	authenticate func(*http.Request) error
+}
+
+// ResourceName returns "webhook_dead_letters".
+func (d deadLettersResourceHandler) ResourceName() string {
+	return deadLettersResource
+}
+
+// ReadResourceList returns the dead letters of the request Principal's webhooks,
+// oldest first.
+func (d deadLettersResourceHandler) ReadResourceList(ctx RequestContext, limit int,
+	cursor string, version string) ([]Resource, string, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	d.dispatcher.mu.RLock()
+	resources := []Resource{}
+	for _, letter := range d.dispatcher.deadLetters {
+		if letter.owner == owner.ID {
+			resources = append(resources, letter)
+		}
+	}
+	d.dispatcher.mu.RUnlock()
+	return paginate(resources, limit, cursor)
+}
+
+// ReadResource returns the dead letter of the request Principal's webhooks with the
+// ID.
+func (d deadLettersResourceHandler) ReadResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.dispatcher.mu.RLock()
+	defer d.dispatcher.mu.RUnlock()
+	for _, letter := range d.dispatcher.deadLetters {
+		if letter.ID == id && letter.owner == owner.ID {
+			return letter, nil
+		}
+	}
+	return nil, ResourceNotFound(fmt.Sprintf("No dead letter with id %s", id))
+}
+
+// DeleteResource discards the dead letter of the request Principal's webhooks with the
+// ID.
+func (d deadLettersResourceHandler) DeleteResource(ctx RequestContext, id string,
+	version string) (Resource, error) {
+
+	owner, err := webhookOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.dispatcher.mu.Lock()
+	defer d.dispatcher.mu.Unlock()
+	for i, letter := range d.dispatcher.deadLetters {
+		if letter.ID == id && letter.owner == owner.ID {
+			d.dispatcher.deadLetters = append(d.dispatcher.deadLetters[:i:i],
+				d.dispatcher.deadLetters[i+1:]...)
+			return letter, nil
+		}
+	}
+	return nil, ResourceNotFound(fmt.Sprintf("No dead letter with id %s", id))
+}
+
+// Authenticate authenticates requests with the Configuration's Authenticate function.
+func (d deadLettersResourceHandler) Authenticate(r *http.Request) error {
+	return d.authenticate(r)
+}
+
+// hook returns the webhook with the ID if it's owned by the Principal with the ID. The
+// dispatcher must be locked.
+func (d *webhookDispatcher) hook(id, owner string) (*Webhook, bool) {
+	hook, ok := d.hooks[id]
+	return hook, ok && hook.owner.ID == owner
+}
+
+// webhookOwner returns the request's Principal, which owns the webhooks it manages.
+func webhookOwner(ctx RequestContext) (*Principal, error) {
+	owner, ok := ctx.Principal()
+	if !ok {
+		return nil, UnauthorizedRequest("Webhooks require an authenticated Principal")
+	}
+	return owner, nil
+}
+
+// webhookFromPayload returns the webhook described by the payload, validating its URL.
+func webhookFromPayload(data Payload) (*Webhook, error) {
+	hook := &Webhook{}
+	rawURL, err := data.GetString("url")
+	if err != nil {
+		return nil, UnprocessableRequest("Webhooks require a url")
+	}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return nil, UnprocessableRequest(fmt.Sprintf("Invalid webhook url: %s", rawURL))
+	}
+	hook.URL = rawURL
+
+	if _, ok := data["resources"]; !ok {
+		return nil, UnprocessableRequest("Webhooks require resources")
+	}
+	if hook.Resources, err = stringSlice(data, "resources"); err != nil {
+		return nil, err
+	}
+	if len(hook.Resources) == 0 {
+		return nil, UnprocessableRequest("Webhooks require resources")
+	}
+	if _, ok := data["events"]; ok {
+		events, err := stringSlice(data, "events")
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			kind := EventType(event)
+			if kind != EventCreate && kind != EventUpdate && kind != EventDelete {
+				return nil, UnprocessableRequest(fmt.Sprintf("Invalid event type: %s", event))
+			}
+			hook.Events = append(hook.Events, kind)
+		}
+	}
+	if _, ok := data["secret"]; ok {
+		if hook.Secret, err = data.GetString("secret"); err != nil {
+			return nil, UnprocessableRequest("Webhook secrets must be strings")
+		}
+	}
+	return hook, nil
+}
+
+// stringSlice returns the list of strings of the payload's key.
+func stringSlice(data Payload, key string) ([]string, error) {
+	values, err := data.GetSlice(key)
+	if err != nil {
+		return nil, UnprocessableRequest(fmt.Sprintf("%s must be a list of strings", key))
+	}
+	strs := make([]string, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, UnprocessableRequest(fmt.Sprintf("%s must be a list of strings", key))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// redactWebhook returns a copy of the webhook without its secret.
+func redactWebhook(hook *Webhook) *Webhook {
+	redacted := *hook
+	redacted.Secret = ""
+	return &redacted
+}
+
+// paginate returns the page of resources after the offset given by the cursor, of at
+// most limit resources, and the cursor of the next page, if any.
+func paginate(resources []Resource, limit int, cursor string) ([]Resource, string, error) {
+	offset := 0
+	if cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(cursor); err != nil || offset < 0 {
+			return nil, "", BadRequest(fmt.Sprintf("Invalid cursor: %s", cursor))
+		}
+	}
+	if offset > len(resources) {
+		offset = len(resources)
+	}
+	resources = resources[offset:]
+	if limit > 0 && len(resources) > limit {
+		return resources[:limit], strconv.Itoa(offset + limit), nil
+	}
+	return resources, "", nil
+}
+
+// containsEventType returns true if the EventTypes contain the kind.
+func containsEventType(kinds []EventType, kind EventType) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// registerWebhooks registers the webhooks and dead letters resources and starts
+// delivering Events to the webhooks until the API is shut down. They aren't registered
+// unless the Configuration has an Authenticate function, without which webhooks
+// couldn't be attributed to their owners.
+func (r *muxAPI) registerWebhooks(config WebhookConfig) {
+	if r.config.Authenticate == nil {
+		log.Printf("Webhooks aren't registered without the Configuration's Authenticate function")
+		return
+	}
+	dispatcher := newWebhookDispatcher(config, r.handler)
+	r.handler.mute(webhooksResource, deadLettersResource)
+	r.RegisterResourceHandler(webhooksResourceHandler{
+		dispatcher: dispatcher, authenticate: r.config.Authenticate})
+	r.RegisterResourceHandler(deadLettersResourceHandler{
+		dispatcher: dispatcher, authenticate: r.config.Authenticate})
+	r.OnShutdown(dispatcher.stop)
+}
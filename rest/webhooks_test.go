@@ -0,0 +1,348 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookDelivery is a delivery received by a test webhook.
+type webhookDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// startWebhookReceiver returns a server responding to deliveries with the status and
+// a channel receiving them.
+func startWebhookReceiver(status int) (*httptest.Server, chan webhookDelivery) {
+	deliveries := make(chan webhookDelivery, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		deliveries <- webhookDelivery{r.Header, body}
+		w.WriteHeader(status)
+	}))
+	return server, deliveries
+}
+
+// serveJSON serves the request with the JSON body and returns the decoded envelope.
+func serveJSON(api API, method, uri, body string) (int, map[string]interface{}) {
+	return serveJSONAs(api, "", method, uri, body)
+}
+
+// serveJSONAs serves the request with the JSON body made by the caller, if any, and
+// returns the decoded envelope.
+func serveJSONAs(api API, caller, method, uri, body string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, uri, strings.NewReader(body))
+	if caller != "" {
+		req.Header.Set("X-Caller", caller)
+	}
+	api.ServeHTTP(w, req)
+	return w.Code, decodeEnvelope(w)
+}
+
+// authenticateCaller authenticates requests with the caller of their X-Caller header
+// as their Principal.
+func authenticateCaller(r *http.Request) error {
+	caller := r.Header.Get("X-Caller")
+	if caller == "" {
+		return errors.New("No caller")
+	}
+	SetRequestPrincipal(r, &Principal{ID: caller})
+	return nil
+}
+
+// allowDestination allows deliveries to any address, including the test receivers.
+func allowDestination(net.IP) bool {
+	return true
+}
+
+// newWebhooksAPI returns an API authenticating callers with webhooks configured by the
+// WebhookConfig, delivering to any address, and the widgets resource.
+func newWebhooksAPI(config *WebhookConfig) API {
+	config.AllowDestination = allowDestination
+	api := NewAPI(&Configuration{
+		Events:       &EventConfig{Webhooks: config},
+		Authenticate: authenticateCaller,
+	})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	return api
+}
+
+// gadgetsResourceHandler is a widgetsResourceHandler for gadgets which only
+// authenticates alice and doesn't authorize the Events of gadget 7.
+type gadgetsResourceHandler struct {
+	widgetsResourceHandler
+}
+
+func (g gadgetsResourceHandler) ResourceName() string {
+	return "gadgets"
+}
+
+func (g gadgetsResourceHandler) Authenticate(r *http.Request) error {
+	if r.Header.Get("X-Caller") != "alice" {
+		return errors.New("Gadgets are alice's")
+	}
+	return nil
+}
+
+func (g gadgetsResourceHandler) AuthorizeEvent(r *http.Request, event Event) bool {
+	p, ok := RequestPrincipal(r)
+	return ok && p.ID == "alice" && event.ResourceID != "7"
+}
+
+// Ensures that Events accepted by the filters of webhooks are delivered to them,
+// signed with their secrets.
+func TestWebhookDelivery(t *testing.T) {
+	assert := assert.New(t)
+	api := newWebhooksAPI(&WebhookConfig{})
+	defer api.Shutdown(context.Background())
+	receiver, deliveries := startWebhookReceiver(http.StatusNoContent)
+	defer receiver.Close()
+
+	code, envelope := serveJSONAs(api, "alice", "POST", "/api/v1/webhooks",
+		`{"url": "`+receiver.URL+`", "resources": ["widgets"], "events": ["update"], "secret": "s3cret"}`)
+	if !assert.Equal(http.StatusCreated, code) {
+		return
+	}
+	hook := envelope["result"].(map[string]interface{})
+	assert.Equal("s3cret", hook["secret"])
+	id := hook["id"].(string)
+
+	code, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/webhooks/"+id, "")
+	assert.Equal(http.StatusOK, code)
+	assert.Nil(envelope["result"].(map[string]interface{})["secret"])
+	code, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/webhooks", "")
+	assert.Equal(http.StatusOK, code)
+	assert.Len(envelope["results"], 1)
+
+	serveJSON(api, "POST", "/api/v1/widgets", `{"name": "sprocket"}`)
+	serveJSON(api, "PUT", "/api/v1/widgets/42", `{"name": "cog"}`)
+
+	select {
+	case delivery := <-deliveries:
+		var event Event
+		assert.Nil(json.Unmarshal(delivery.body, &event))
+		assert.Equal(EventUpdate, event.Type)
+		assert.Equal("42", event.ResourceID)
+		assert.Equal(id, delivery.header.Get("X-Webhook-ID"))
+		assert.Equal("2", delivery.header.Get("X-Webhook-Event-ID"))
+		assert.True(VerifyWebhookSignature("s3cret", delivery.header.Get("X-Webhook-Timestamp"),
+			delivery.body, delivery.header.Get("X-Webhook-Signature")))
+		assert.False(VerifyWebhookSignature("secret", delivery.header.Get("X-Webhook-Timestamp"),
+			delivery.body, delivery.header.Get("X-Webhook-Signature")))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event wasn't delivered")
+	}
+	select {
+	case <-deliveries:
+		t.Fatal("Event not matching the filters was delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	code, _ = serveJSONAs(api, "alice", "DELETE", "/api/v1/webhooks/"+id, "")
+	assert.Equal(http.StatusOK, code)
+	code, _ = serveJSONAs(api, "alice", "GET", "/api/v1/webhooks/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+}
+
+// Ensures that failed deliveries are retried and recorded as dead letters once the
+// attempts are exhausted.
+func TestWebhookDeadLetters(t *testing.T) {
+	assert := assert.New(t)
+	api := newWebhooksAPI(&WebhookConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+	defer api.Shutdown(context.Background())
+	receiver, deliveries := startWebhookReceiver(http.StatusInternalServerError)
+	defer receiver.Close()
+
+	serveJSONAs(api, "alice", "POST", "/api/v1/webhooks",
+		`{"url": "`+receiver.URL+`", "resources": ["widgets"]}`)
+	serveJSON(api, "DELETE", "/api/v1/widgets/7", "")
+	for i := 0; i < 3; i++ {
+		select {
+		case <-deliveries:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Delivery wasn't retried")
+		}
+	}
+
+	var letters []interface{}
+	for start := time.Now(); len(letters) == 0 && time.Since(start) < 5*time.Second; {
+		_, envelope := serveJSONAs(api, "alice", "GET", "/api/v1/webhook_dead_letters", "")
+		letters, _ = envelope["results"].([]interface{})
+		time.Sleep(time.Millisecond)
+	}
+	if !assert.Len(letters, 1) {
+		return
+	}
+	letter := letters[0].(map[string]interface{})
+	assert.Equal(float64(3), letter["attempts"])
+	assert.Equal("Webhook responded 500 Internal Server Error", letter["error"])
+	assert.Equal("delete", letter["event"].(map[string]interface{})["type"])
+
+	_, envelope := serveJSONAs(api, "bob", "GET", "/api/v1/webhook_dead_letters", "")
+	assert.Len(envelope["results"], 0)
+	uri := "/api/v1/webhook_dead_letters/" + letter["id"].(string)
+	code, _ := serveJSONAs(api, "bob", "GET", uri, "")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "bob", "DELETE", uri, "")
+	assert.Equal(http.StatusNotFound, code)
+
+	code, _ = serveJSONAs(api, "alice", "DELETE", uri, "")
+	assert.Equal(http.StatusOK, code)
+	_, envelope = serveJSONAs(api, "alice", "GET", "/api/v1/webhook_dead_letters", "")
+	assert.Len(envelope["results"], 0)
+}
+
+// Ensures that invalid webhooks, including those without resources or delivering to
+// non-public addresses, are rejected and that the mutations of webhooks aren't
+// published as Events, which would disclose their secrets.
+func TestWebhookValidation(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{
+		Events:       &EventConfig{Webhooks: &WebhookConfig{}},
+		Authenticate: authenticateCaller,
+	})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	defer api.Shutdown(context.Background())
+
+	for _, body := range []string{
+		`{}`,
+		`{"url": "ftp://example.com", "resources": ["widgets"]}`,
+		`{"url": "https://example.com", "resources": ["widgets"], "events": ["read"]}`,
+		`{"url": "https://example.com", "resources": "widgets"}`,
+		`{"url": "https://203.0.113.10"}`,
+		`{"url": "https://203.0.113.10", "resources": []}`,
+		`{"url": "https://203.0.113.10", "resources": ["gizmos"]}`,
+		`{"url": "https://203.0.113.10", "resources": ["webhooks"]}`,
+		`{"url": "http://127.0.0.1:8080", "resources": ["widgets"]}`,
+		`{"url": "http://169.254.169.254/latest/meta-data", "resources": ["widgets"]}`,
+		`{"url": "http://10.0.0.1", "resources": ["widgets"]}`,
+		`{"url": "http://[::1]", "resources": ["widgets"]}`,
+		`{"url": "http://0.0.0.0", "resources": ["widgets"]}`,
+	} {
+		code, _ := serveJSONAs(api, "alice", "POST", "/api/v1/webhooks", body)
+		assert.Equal(http.StatusUnprocessableEntity, code, body)
+	}
+
+	code, _ := serveJSONAs(api, "alice", "POST", "/api/v1/webhooks",
+		`{"url": "https://203.0.113.10", "resources": ["widgets"]}`)
+	assert.Equal(http.StatusCreated, code)
+	assert.Equal(uint64(0), api.(*muxAPI).handler.events.latest())
+}
+
+// Ensures that the default delivery client refuses to connect to non-public addresses,
+// even if a webhook's host resolved to a public address when it was registered.
+func TestWebhookClient(t *testing.T) {
+	assert := assert.New(t)
+	receiver, deliveries := startWebhookReceiver(http.StatusNoContent)
+	defer receiver.Close()
+
+	_, err := newWebhookClient(publicAddress).Post(receiver.URL, "application/json", nil)
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "isn't allowed")
+	}
+	assert.Len(deliveries, 0)
+
+	resp, err := newWebhookClient(allowDestination).Post(receiver.URL, "application/json", nil)
+	if assert.Nil(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusNoContent, resp.StatusCode)
+	}
+}
+
+// Ensures that webhooks are registered for resources whose ResourceHandlers
+// authenticate the request, are only visible to the Principals which registered them,
+// and only receive the Events authorized for them.
+func TestWebhookAuthorization(t *testing.T) {
+	assert := assert.New(t)
+	api := newWebhooksAPI(&WebhookConfig{})
+	api.RegisterResourceHandler(gadgetsResourceHandler{})
+	defer api.Shutdown(context.Background())
+	receiver, deliveries := startWebhookReceiver(http.StatusNoContent)
+	defer receiver.Close()
+
+	gadgets := `{"url": "` + receiver.URL + `", "resources": ["gadgets"]}`
+	code, _ := serveJSON(api, "POST", "/api/v1/webhooks", gadgets)
+	assert.Equal(http.StatusUnauthorized, code)
+	code, _ = serveJSONAs(api, "bob", "POST", "/api/v1/webhooks", gadgets)
+	assert.Equal(http.StatusUnauthorized, code)
+	code, envelope := serveJSONAs(api, "alice", "POST", "/api/v1/webhooks", gadgets)
+	if !assert.Equal(http.StatusCreated, code) {
+		return
+	}
+	id := envelope["result"].(map[string]interface{})["id"].(string)
+
+	code, envelope = serveJSONAs(api, "bob", "POST", "/api/v1/webhooks",
+		`{"url": "`+receiver.URL+`", "resources": ["widgets"]}`)
+	if !assert.Equal(http.StatusCreated, code) {
+		return
+	}
+	bobs := envelope["result"].(map[string]interface{})["id"].(string)
+	_, envelope = serveJSONAs(api, "bob", "GET", "/api/v1/webhooks", "")
+	if results, _ := envelope["results"].([]interface{}); assert.Len(results, 1) {
+		assert.Equal(bobs, results[0].(map[string]interface{})["id"])
+	}
+	code, _ = serveJSONAs(api, "bob", "GET", "/api/v1/webhooks/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "bob", "PUT", "/api/v1/webhooks/"+id,
+		`{"url": "`+receiver.URL+`", "resources": ["widgets"]}`)
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSONAs(api, "bob", "DELETE", "/api/v1/webhooks/"+id, "")
+	assert.Equal(http.StatusNotFound, code)
+
+	serveJSONAs(api, "alice", "PUT", "/api/v1/gadgets/7", `{"name": "cog"}`)
+	serveJSONAs(api, "alice", "PUT", "/api/v1/gadgets/42", `{"name": "cog"}`)
+	select {
+	case delivery := <-deliveries:
+		var event Event
+		assert.Nil(json.Unmarshal(delivery.body, &event))
+		assert.Equal("42", event.ResourceID)
+		assert.Equal(id, delivery.header.Get("X-Webhook-ID"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event wasn't delivered")
+	}
+	select {
+	case <-deliveries:
+		t.Fatal("Unauthorized Event was delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Ensures that the webhooks resources aren't registered without the Configuration's
+// Authenticate function.
+func TestWebhooksWithoutAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{Webhooks: &WebhookConfig{}}})
+	defer api.Shutdown(context.Background())
+
+	code, _ := serveJSON(api, "POST", "/api/v1/webhooks", `{"url": "https://203.0.113.10"}`)
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = serveJSON(api, "GET", "/api/v1/webhook_dead_letters", "")
+	assert.Equal(http.StatusNotFound, code)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// webSocketWriteTimeout is how long writes to a WebSocket may take before the client is
+// considered gone.
+const webSocketWriteTimeout = 10 * time.Second
+
+// webSocketRequest is a message sent by a client over an Event WebSocket.
+type webSocketRequest struct {
+	// Action is "subscribe" or "unsubscribe".
+	Action string `json:"action"`
+
+	// Resource is the name of the resource whose Events are (un)subscribed to.
+	Resource string `json:"resource"`
+
+	// ResourceID, if set, restricts the subscription to the Events of the resource
+	// with the ID.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Fields, if set, restricts the subscription to the Events whose payloads have
+	// the fields with the values.
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// LastEventID, if set, is the ID of the last Event received before reconnecting.
+	// The recorded Events of the subscription after it are sent before the live ones.
+	LastEventID *uint64 `json:"last_event_id,omitempty"`
+}
+
+// webSocketMessage is a message sent to a client over an Event WebSocket.
+type webSocketMessage struct {
+	// Type is "event" for Events, "subscribed" or "unsubscribed" to acknowledge
+	// requests, "reset" if Events after the LastEventID of a subscription are no
+	// longer recorded, or "error".
+	Type string `json:"type"`
+
+	Resource   string `json:"resource,omitempty"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Event      *Event `json:"event,omitempty"`
+}
+
+// webSocketSession is the state of an Event WebSocket connection.
+type webSocketSession struct {
+	h    requestHandler
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	subscriber *eventSubscriber
+}
+
+// handleEventSocket returns a Handler upgrading requests to WebSockets over which
+// clients subscribe to the Events of resources, of individual resources or of those
+// with given field values. Requests with an Origin header are only accepted from the
+// API's own origin, so other sites can't subscribe with the credentials of their
+// visitors. Subscriptions to a resource must be authenticated by its ResourceHandler,
+// which may also authorize each Event. Clients which don't keep up with the Events are
+// disconnected.
+func (h requestHandler) handleEventSocket() http.Handler {
+	return websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return nil
+			}
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				return websocket.ErrBadWebSocketOrigin
+			}
+			config.Origin = u
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			session := &webSocketSession{h: h, conn: conn,
+				subscriber: h.newEventSubscriber(conn.Request())}
+			session.serve()
+		},
+	}
+}
+
+// serve relays the Events subscribed to until the client disconnects or falls behind,
+// or the API shuts down.
+func (s *webSocketSession) serve() {
+	defer s.conn.Close()
+	sub := s.h.events.subscribe(s.subscriber.accepts)
+	defer s.h.events.unsubscribe(sub)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-s.h.events.done():
+			s.conn.Close()
+		case <-done:
+		}
+	}()
+
+	go func() {
+		for event := range sub.events {
+			event := event
+			if s.send(webSocketMessage{Type: "event", Event: &event}) != nil {
+				s.conn.Close()
+				return
+			}
+		}
+		select {
+		case <-done:
+		default:
+			// The subscription was closed because the client fell behind.
+			s.send(webSocketMessage{Type: "error", Message: "Too many pending events"})
+			s.conn.Close()
+		}
+	}()
+
+	for {
+		var req webSocketRequest
+		err := websocket.JSON.Receive(s.conn, &req)
+		// The write lock is held while the request is handled so the Events replayed
+		// for a subscription are sent before the live ones.
+		s.writeMu.Lock()
+		var replies []webSocketMessage
+		switch err.(type) {
+		case nil:
+			replies = s.handle(req)
+		case *json.SyntaxError, *json.UnmarshalTypeError:
+			replies = []webSocketMessage{{Type: "error", Message: "Invalid request: " + err.Error()}}
+		default:
+			s.writeMu.Unlock()
+			return
+		}
+		err = s.write(replies...)
+		s.writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle applies the client's request, returning the acknowledgement or error sent
+// back, followed by the Events replayed for a subscription with a LastEventID. The
+// write lock must be held.
+func (s *webSocketSession) handle(req webSocketRequest) []webSocketMessage {
+	filter := EventFilter{Resource: req.Resource, ResourceID: req.ResourceID, Fields: req.Fields}
+	switch req.Action {
+	case "subscribe":
+		handler := s.h.resourceHandler(req.Resource)
+		if handler == nil {
+			return []webSocketMessage{{Type: "error", Resource: req.Resource,
+				Message: "Unknown resource"}}
+		}
+		if err := handler.Authenticate(s.conn.Request()); err != nil {
+			return []webSocketMessage{{Type: "error", Resource: req.Resource,
+				Message: err.Error()}}
+		}
+		replies := []webSocketMessage{{Type: "subscribed", Resource: req.Resource,
+			ResourceID: req.ResourceID}}
+		if req.LastEventID == nil {
+			s.subscriber.add(filter)
+			return replies
+		}
+		missed, complete := s.h.events.replay(*req.LastEventID, func(event Event) bool {
+			return filter.Matches(event) && s.subscriber.authorized(event)
+		}, func() { s.subscriber.add(filter) })
+		if !complete {
+			replies = append(replies, webSocketMessage{Type: resetEvent, Resource: req.Resource,
+				ResourceID: req.ResourceID})
+		}
+		for i := range missed {
+			replies = append(replies, webSocketMessage{Type: "event", Event: &missed[i]})
+		}
+		return replies
+	case "unsubscribe":
+		s.subscriber.remove(filter)
+		return []webSocketMessage{{Type: "unsubscribed", Resource: req.Resource,
+			ResourceID: req.ResourceID}}
+	}
+	return []webSocketMessage{{Type: "error", Message: "Unknown action: " + req.Action}}
+}
+
+// send writes the message to the client.
+func (s *webSocketSession) send(message webSocketMessage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.write(message)
+}
+
+// write writes the messages to the client. The write lock must be held.
+func (s *webSocketSession) write(messages ...webSocketMessage) error {
+	for _, message := range messages {
+		s.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+		if err := websocket.JSON.Send(s.conn, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2014 - 2015 Workiva, LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// secretsResourceHandler is a ResourceHandler rejecting all requests.
+type secretsResourceHandler struct {
+	BaseResourceHandler
+}
+
+func (s secretsResourceHandler) ResourceName() string {
+	return "secrets"
+}
+
+func (s secretsResourceHandler) Authenticate(r *http.Request) error {
+	return Unauthorized("Not allowed")
+}
+
+// receiveMessage receives the next message of the WebSocket.
+func receiveMessage(t *testing.T, conn *websocket.Conn) webSocketMessage {
+	var message webSocketMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := websocket.JSON.Receive(conn, &message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// Ensures that clients can subscribe to the Events of resources and individual
+// resources over a WebSocket, subject to the authentication of their ResourceHandlers.
+func TestEventSocket(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{WebSocketPath: "/api/events"}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	api.RegisterResourceHandler(secretsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/events"
+
+	conn, err := websocket.Dial(wsURL, "", ts.URL)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close()
+
+	send := func(req webSocketRequest) webSocketMessage {
+		if err := websocket.JSON.Send(conn, req); err != nil {
+			t.Fatal(err)
+		}
+		return receiveMessage(t, conn)
+	}
+	assert.Equal(webSocketMessage{Type: "subscribed", Resource: "widgets", ResourceID: "42"},
+		send(webSocketRequest{Action: "subscribe", Resource: "widgets", ResourceID: "42"}))
+	assert.Equal("error", send(webSocketRequest{Action: "subscribe", Resource: "gadgets"}).Type)
+	assert.Equal(webSocketMessage{Type: "error", Resource: "secrets", Message: "Not allowed"},
+		send(webSocketRequest{Action: "subscribe", Resource: "secrets"}))
+	assert.Equal("error", send(webSocketRequest{Action: "publish"}).Type)
+	websocket.Message.Send(conn, "not json")
+	assert.Equal("error", receiveMessage(t, conn).Type)
+
+	mutate := func(method, path, body string) {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if resp, err := http.DefaultClient.Do(req); assert.Nil(err) {
+			resp.Body.Close()
+		}
+	}
+	mutate("POST", "/api/v1/widgets", `{"name": "sprocket"}`)
+	mutate("PUT", "/api/v1/widgets/7", `{"name": "gear"}`)
+	mutate("PUT", "/api/v1/widgets/42", `{"name": "cog"}`)
+	message := receiveMessage(t, conn)
+	if assert.Equal("event", message.Type) {
+		assert.Equal(EventUpdate, message.Event.Type)
+		assert.Equal("42", message.Event.ResourceID)
+	}
+
+	assert.Equal("subscribed", send(webSocketRequest{Action: "subscribe", Resource: "widgets"}).Type)
+	assert.Equal("unsubscribed",
+		send(webSocketRequest{Action: "unsubscribe", Resource: "widgets", ResourceID: "42"}).Type)
+	mutate("DELETE", "/api/v1/widgets/9", "")
+	message = receiveMessage(t, conn)
+	if assert.Equal("event", message.Type) {
+		assert.Equal(EventDelete, message.Event.Type)
+		assert.Equal("9", message.Event.ResourceID)
+	}
+}
+
+// Ensures that WebSockets can be opened when Instrumentation records the requests of
+// each route.
+func TestEventSocketInstrumentation(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{
+		Instrumentation: true,
+		Events:          &EventConfig{WebSocketPath: "/api/events"},
+	})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/events", "", ts.URL)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close()
+
+	if err := websocket.JSON.Send(conn, webSocketRequest{Action: "subscribe", Resource: "widgets"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(webSocketMessage{Type: "subscribed", Resource: "widgets"}, receiveMessage(t, conn))
+}
+
+// Ensures that Shutdown closes the WebSockets of connected clients, which the servers
+// don't track once they're hijacked.
+func TestEventSocketShutdown(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{WebSocketPath: "/api/events"}})
+	api.RegisterResourceHandler(widgetsResourceHandler{})
+	addr, done := startTestAPI(t, api)
+
+	conn, err := websocket.Dial("ws://"+addr+"/api/events", "", "http://"+addr)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close()
+	if err := websocket.JSON.Send(conn, webSocketRequest{Action: "subscribe", Resource: "widgets"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(webSocketMessage{Type: "subscribed", Resource: "widgets"}, receiveMessage(t, conn))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+	assert.Nil(<-done)
+
+	var message webSocketMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	err = websocket.JSON.Receive(conn, &message)
+	if assert.NotNil(err) {
+		netErr, ok := err.(net.Error)
+		assert.False(ok && netErr.Timeout(), "WebSocket wasn't closed")
+	}
+}
+
+// Ensures that WebSockets are only opened for requests from the API's own origin.
+func TestEventSocketOrigin(t *testing.T) {
+	assert := assert.New(t)
+	api := NewAPI(&Configuration{Events: &EventConfig{WebSocketPath: "/api/events"}})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	_, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/events", "",
+		"http://evil.example.com")
+	assert.Error(err)
+}